@@ -0,0 +1,81 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Names of the labels added to each series of the exported alerts metric.
+const (
+	alertStateLabel = "alertstate"
+	silencedLabel   = "silenced"
+	inhibitedLabel  = "inhibited"
+)
+
+// alertsMetricFamily returns a metric family with one series of value 1 for
+// each firing alert. Series are labeled with the alert's labels, its state,
+// and whether it is silenced or inhibited, similar to the ALERTS metric of
+// Prometheus.
+func alertsMetricFamily(alerts []*types.Alert, marker types.Marker) *dto.MetricFamily {
+	mf := &dto.MetricFamily{
+		Name: proto.String("ALERTS"),
+		Help: proto.String("Alerts currently firing in the Alertmanager."),
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+
+	for _, a := range alerts {
+		if a.Resolved() {
+			continue
+		}
+		fp := a.Fingerprint()
+		_, silenced := marker.Silenced(fp)
+
+		lset := a.Labels.Clone()
+		lset[alertStateLabel] = "firing"
+		lset[silencedLabel] = model.LabelValue(strconv.FormatBool(silenced))
+		lset[inhibitedLabel] = model.LabelValue(strconv.FormatBool(marker.Inhibited(fp)))
+
+		mf.Metric = append(mf.Metric, &dto.Metric{
+			Label: labelPairs(lset),
+			Gauge: &dto.Gauge{Value: proto.Float64(1)},
+		})
+	}
+	return mf
+}
+
+// labelPairs returns the label pairs of the label set sorted by name.
+func labelPairs(lset model.LabelSet) []*dto.LabelPair {
+	names := make(model.LabelNames, 0, len(lset))
+	for ln := range lset {
+		names = append(names, ln)
+	}
+	sort.Sort(names)
+
+	res := make([]*dto.LabelPair, 0, len(names))
+	for _, ln := range names {
+		res = append(res, &dto.LabelPair{
+			Name:  proto.String(string(ln)),
+			Value: proto.String(string(lset[ln])),
+		})
+	}
+	return res
+}
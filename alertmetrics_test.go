@@ -0,0 +1,67 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestAlertsMetricFamily(t *testing.T) {
+	now := time.Now()
+
+	alerts := []*types.Alert{
+		{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "A", "instance": "i1"},
+				StartsAt: now.Add(-time.Hour),
+			},
+		}, {
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "B"},
+				StartsAt: now.Add(-time.Hour),
+			},
+		}, {
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "C"},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(-time.Minute),
+			},
+		},
+	}
+
+	marker := types.NewMarker()
+	marker.SetSilenced(alerts[0].Fingerprint(), 1)
+	marker.SetInhibited(alerts[1].Fingerprint(), &types.InhibitSource{})
+
+	var buf bytes.Buffer
+	if _, err := expfmt.MetricFamilyToText(&buf, alertsMetricFamily(alerts, marker)); err != nil {
+		t.Fatalf("Encoding failed: %s", err)
+	}
+
+	expected := `# HELP ALERTS Alerts currently firing in the Alertmanager.
+# TYPE ALERTS gauge
+ALERTS{alertname="A",alertstate="firing",inhibited="false",instance="i1",silenced="true"} 1
+ALERTS{alertname="B",alertstate="firing",inhibited="true",silenced="false"} 1
+`
+	if buf.String() != expected {
+		t.Fatalf("Unexpected output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// AlertAnalytics summarizes the volume of alerts and notifications over a
+// time range. Alerts count alerts starting to fire, notifications count
+// alerts notified about through an integration of a receiver.
+type AlertAnalytics struct {
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	Alerts        int       `json:"alerts"`
+	Notifications int       `json:"notifications"`
+
+	Hours     []*HourVolume                      `json:"hours"`
+	Receivers []*ReceiverVolume                  `json:"receivers"`
+	Labels    map[model.LabelName][]*LabelVolume `json:"labels"`
+}
+
+// HourVolume is the volume within the hour starting at Time.
+type HourVolume struct {
+	Time          time.Time `json:"time"`
+	Alerts        int       `json:"alerts"`
+	Notifications int       `json:"notifications"`
+}
+
+// ReceiverVolume is the volume of notifications sent to a receiver and the
+// number of distinct alerts they were about.
+type ReceiverVolume struct {
+	Receiver      string `json:"receiver"`
+	Notifications int    `json:"notifications"`
+	Alerts        int    `json:"alerts"`
+}
+
+// LabelVolume is the volume of alerts with a label value.
+type LabelVolume struct {
+	Value         model.LabelValue `json:"value"`
+	Alerts        int              `json:"alerts"`
+	Notifications int              `json:"notifications"`
+}
+
+// analyzeAlerts computes the volume of alerts and notifications recorded in
+// the history within [from, to). For each of the label names, the top label
+// values by alerts are included. If m is not nil, only alerts it matches
+// are counted.
+func analyzeAlerts(h provider.AlertHistory, from, to time.Time, names []model.LabelName, top int, m *types.Matcher) (*AlertAnalytics, error) {
+	entries, err := h.Query(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &AlertAnalytics{
+		From:      from,
+		To:        to,
+		Hours:     []*HourVolume{},
+		Receivers: []*ReceiverVolume{},
+		Labels:    map[model.LabelName][]*LabelVolume{},
+	}
+
+	hours := map[time.Time]*HourVolume{}
+	for t := from.Truncate(time.Hour); t.Before(to); t = t.Add(time.Hour) {
+		hv := &HourVolume{Time: t}
+		hours[t] = hv
+		res.Hours = append(res.Hours, hv)
+	}
+
+	var (
+		labels    = map[model.Fingerprint]model.LabelSet{}
+		receivers = map[string]*ReceiverVolume{}
+		notified  = map[string]map[model.Fingerprint]struct{}{}
+		values    = map[model.LabelName]map[model.LabelValue]*LabelVolume{}
+	)
+	for _, ln := range names {
+		values[ln] = map[model.LabelValue]*LabelVolume{}
+	}
+
+	// alertLabels returns the labels of the alert, which are recorded with
+	// its creation. Alerts created before the time range are looked up.
+	alertLabels := func(fp model.Fingerprint) (model.LabelSet, error) {
+		if lset, ok := labels[fp]; ok {
+			return lset, nil
+		}
+		all, err := h.Get(fp)
+		if err != nil {
+			return nil, err
+		}
+		var lset model.LabelSet
+		for _, e := range all {
+			if e.State == types.AlertCreated {
+				lset = e.Labels
+			}
+		}
+		labels[fp] = lset
+		return lset, nil
+	}
+
+	for _, e := range entries {
+		if e.State == types.AlertCreated {
+			labels[e.Alert] = e.Labels
+		}
+		if e.State != types.AlertFiring && e.State != types.AlertNotified {
+			continue
+		}
+		lset, err := alertLabels(e.Alert)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil && !m.Match(lset) {
+			continue
+		}
+		hv := hours[e.Time.Truncate(time.Hour)]
+
+		var lvs []*LabelVolume
+		for _, ln := range names {
+			v, ok := lset[ln]
+			if !ok {
+				continue
+			}
+			lv, ok := values[ln][v]
+			if !ok {
+				lv = &LabelVolume{Value: v}
+				values[ln][v] = lv
+			}
+			lvs = append(lvs, lv)
+		}
+
+		if e.State == types.AlertFiring {
+			res.Alerts++
+			if hv != nil {
+				hv.Alerts++
+			}
+			for _, lv := range lvs {
+				lv.Alerts++
+			}
+			continue
+		}
+
+		res.Notifications++
+		if hv != nil {
+			hv.Notifications++
+		}
+		for _, lv := range lvs {
+			lv.Notifications++
+		}
+		rv, ok := receivers[e.Receiver]
+		if !ok {
+			rv = &ReceiverVolume{Receiver: e.Receiver}
+			receivers[e.Receiver] = rv
+			notified[e.Receiver] = map[model.Fingerprint]struct{}{}
+			res.Receivers = append(res.Receivers, rv)
+		}
+		rv.Notifications++
+		notified[e.Receiver][e.Alert] = struct{}{}
+	}
+
+	for _, rv := range res.Receivers {
+		rv.Alerts = len(notified[rv.Receiver])
+	}
+	sort.Sort(receiverVolumes(res.Receivers))
+
+	for ln, vs := range values {
+		lvs := make([]*LabelVolume, 0, len(vs))
+		for _, lv := range vs {
+			lvs = append(lvs, lv)
+		}
+		sort.Sort(labelVolumes(lvs))
+		if top > 0 && len(lvs) > top {
+			lvs = lvs[:top]
+		}
+		res.Labels[ln] = lvs
+	}
+
+	return res, nil
+}
+
+// receiverVolumes sorts by descending notifications.
+type receiverVolumes []*ReceiverVolume
+
+func (rv receiverVolumes) Len() int      { return len(rv) }
+func (rv receiverVolumes) Swap(i, j int) { rv[i], rv[j] = rv[j], rv[i] }
+func (rv receiverVolumes) Less(i, j int) bool {
+	if rv[i].Notifications != rv[j].Notifications {
+		return rv[i].Notifications > rv[j].Notifications
+	}
+	return rv[i].Receiver < rv[j].Receiver
+}
+
+// labelVolumes sorts by descending alerts and notifications.
+type labelVolumes []*LabelVolume
+
+func (lv labelVolumes) Len() int      { return len(lv) }
+func (lv labelVolumes) Swap(i, j int) { lv[i], lv[j] = lv[j], lv[i] }
+func (lv labelVolumes) Less(i, j int) bool {
+	if lv[i].Alerts != lv[j].Alerts {
+		return lv[i].Alerts > lv[j].Alerts
+	}
+	if lv[i].Notifications != lv[j].Notifications {
+		return lv[i].Notifications > lv[j].Notifications
+	}
+	return lv[i].Value < lv[j].Value
+}
@@ -0,0 +1,162 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider/boltmem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestAnalyzeAlerts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "analytics_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h, err := boltmem.NewAlertHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	var (
+		to   = time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+		from = to.Add(-3 * time.Hour)
+
+		a1 = model.LabelSet{"alertname": "HighLatency", "tenant": "a"}
+		a2 = model.LabelSet{"alertname": "HighLatency", "tenant": "b"}
+		a3 = model.LabelSet{"alertname": "DiskFull", "tenant": "a"}
+	)
+	err = h.Add(
+		// Created before the time range.
+		&types.AlertHistoryEntry{Alert: a1.Fingerprint(), Time: from.Add(-time.Hour), State: types.AlertCreated, Labels: a1},
+		&types.AlertHistoryEntry{Alert: a1.Fingerprint(), Time: from.Add(-time.Hour), State: types.AlertFiring},
+		&types.AlertHistoryEntry{Alert: a1.Fingerprint(), Time: from.Add(10 * time.Minute), State: types.AlertNotified, Receiver: "team-X"},
+		&types.AlertHistoryEntry{Alert: a2.Fingerprint(), Time: from.Add(70 * time.Minute), State: types.AlertCreated, Labels: a2},
+		&types.AlertHistoryEntry{Alert: a2.Fingerprint(), Time: from.Add(70 * time.Minute), State: types.AlertFiring},
+		&types.AlertHistoryEntry{Alert: a2.Fingerprint(), Time: from.Add(80 * time.Minute), State: types.AlertNotified, Receiver: "team-Y"},
+		&types.AlertHistoryEntry{Alert: a2.Fingerprint(), Time: from.Add(90 * time.Minute), State: types.AlertNotified, Receiver: "team-Y"},
+		&types.AlertHistoryEntry{Alert: a3.Fingerprint(), Time: from.Add(150 * time.Minute), State: types.AlertCreated, Labels: a3},
+		&types.AlertHistoryEntry{Alert: a3.Fingerprint(), Time: from.Add(150 * time.Minute), State: types.AlertFiring},
+		&types.AlertHistoryEntry{Alert: a3.Fingerprint(), Time: from.Add(160 * time.Minute), State: types.AlertResolved},
+		// After the time range.
+		&types.AlertHistoryEntry{Alert: a3.Fingerprint(), Time: to, State: types.AlertNotified, Receiver: "team-X"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := analyzeAlerts(h, from, to, []model.LabelName{"alertname", "tenant"}, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Alerts != 2 || res.Notifications != 3 {
+		t.Errorf("expected 2 alerts and 3 notifications but got %d and %d", res.Alerts, res.Notifications)
+	}
+
+	expHours := []HourVolume{
+		{Time: from, Alerts: 0, Notifications: 1},
+		{Time: from.Add(time.Hour), Alerts: 1, Notifications: 2},
+		{Time: from.Add(2 * time.Hour), Alerts: 1, Notifications: 0},
+	}
+	if len(res.Hours) != len(expHours) {
+		t.Fatalf("expected %d hours but got %d", len(expHours), len(res.Hours))
+	}
+	for i, hv := range res.Hours {
+		if *hv != expHours[i] {
+			t.Errorf("%d. expected hour volume %+v but got %+v", i, expHours[i], *hv)
+		}
+	}
+
+	expRcvs := []ReceiverVolume{
+		{Receiver: "team-Y", Notifications: 2, Alerts: 1},
+		{Receiver: "team-X", Notifications: 1, Alerts: 1},
+	}
+	if len(res.Receivers) != len(expRcvs) {
+		t.Fatalf("expected %d receivers but got %d", len(expRcvs), len(res.Receivers))
+	}
+	for i, rv := range res.Receivers {
+		if *rv != expRcvs[i] {
+			t.Errorf("%d. expected receiver volume %+v but got %+v", i, expRcvs[i], *rv)
+		}
+	}
+
+	if lvs := res.Labels["alertname"]; len(lvs) != 1 || *lvs[0] != (LabelVolume{Value: "HighLatency", Alerts: 1, Notifications: 3}) {
+		t.Errorf("expected top alertname HighLatency but got %v", lvs)
+	}
+	if lvs := res.Labels["tenant"]; len(lvs) != 1 || *lvs[0] != (LabelVolume{Value: "b", Alerts: 1, Notifications: 2}) {
+		t.Errorf("expected top tenant b but got %v", lvs)
+	}
+
+	// Scoped to a tenant.
+	res, err = analyzeAlerts(h, from, to, []model.LabelName{"alertname"}, 10, types.NewMatcher("tenant", "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Alerts != 1 || res.Notifications != 1 || len(res.Labels["alertname"]) != 2 {
+		t.Errorf("expected the volume of tenant a only but got %+v", res)
+	}
+}
+
+func TestRecordNotifications(t *testing.T) {
+	dir, err := ioutil.TempDir("", "analytics_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h, err := boltmem.NewAlertHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	var (
+		fail bool
+		n    = recordNotifications(h, notify.NotifierFunc(func(ctx context.Context, alerts ...*types.Alert) error {
+			if fail {
+				return context.DeadlineExceeded
+			}
+			return nil
+		}))
+		a   = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "HighLatency"}}}
+		ctx = notify.WithReceiver(context.Background(), "team-X/email/0")
+	)
+
+	if err := n.Notify(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	if err := n.Notify(ctx, a); err == nil {
+		t.Fatalf("expected notification error to be returned")
+	}
+
+	entries, err := h.Get(a.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].State != types.AlertNotified || entries[0].Receiver != "team-X" {
+		t.Errorf("expected a single notified entry for team-X but got %v", entries)
+	}
+}
@@ -14,21 +14,38 @@
 package main
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/common/version"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/ingest"
+	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/tracing"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -44,11 +61,18 @@ var (
 		Name:      "alerts_invalid_total",
 		Help:      "The total number of received alerts that were invalid.",
 	})
+
+	numLateAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "alerts_late_received_total",
+		Help:      "The total number of received alerts that started longer than the late alert threshold ago.",
+	}, []string{"action"})
 )
 
 func init() {
 	prometheus.Register(numReceivedAlerts)
 	prometheus.Register(numInvalidAlerts)
+	prometheus.Register(numLateAlerts)
 }
 
 // API provides registration of handlers for API routes.
@@ -56,135 +80,2082 @@ type API struct {
 	alerts         provider.Alerts
 	silences       provider.Silences
 	events         provider.Events
+	acks           provider.Acks
+	notifies       provider.Notifies
+	costs          *notify.CostAccount
+	checker        *notify.Checker
+	deadLetters    provider.DeadLetters
+	supervisor     *Supervisor
+	snapshotFile   string
 	config         string
 	resolveTimeout time.Duration
 	uptime         time.Time
 
-	groups func() AlertOverview
+	lateAlertThreshold time.Duration
+	lateAlertPolicy    config.LateAlertPolicy
+
+	// Receivers and templates of the current configuration used to
+	// render notification previews.
+	receivers map[string]*config.Receiver
+	tmpl      *template.Template
+	// The running configuration posted candidates are compared against.
+	runningConfig *config.Config
+
+	// Records calls of mutating endpoints if set.
+	auditor *Auditor
+	// Checks access to all endpoints if set.
+	authenticator *Authenticator
+	// Scopes requests to the alerts of their tenant if set.
+	tenants *Tenants
+	// Sent transitions of events between statuses if set.
+	eventHooks *EventHooks
+	// Holds the state changes of alerts if set.
+	alertHistory provider.AlertHistory
+	// Holds the notifications recorded in dry-run mode if set.
+	dryRuns *notify.DryRuns
+	// Holds planned maintenance windows if set.
+	maintenance provider.MaintenanceWindows
+	// Holds the dependencies between services if set.
+	dependencies provider.Dependencies
+	// The currently running inhibitor, which is replaced on configuration
+	// reloads.
+	inhibitor *Inhibitor
+	// Holds files attached to events if set, which are limited in size
+	// and to the given content types.
+	attachments            provider.EventAttachments
+	attachmentMaxSize      int64
+	attachmentContentTypes map[string]struct{}
+	// Verifies PagerDuty webhook requests if set.
+	pagerDutyWebhookConf *config.PagerdutyWebhook
+	// Verifies Slack action requests if set.
+	slackActionsConf *config.SlackActions
+
+	// Databases included in online backups by file name.
+	backups map[string]provider.Backuper
+
+	// peer is nil if not running as a cluster.
+	peer *cluster.Peer
+
+	// dispatcher returns the currently active dispatcher, which is
+	// replaced on configuration reloads.
+	dispatcher func() *Dispatcher
 
 	// context is an indirection for testing.
 	context func(r *http.Request) context.Context
 	mtx     sync.RWMutex
 }
 
-// NewAPI returns a new API.
-func NewAPI(alerts provider.Alerts, silences provider.Silences, events provider.Events, gf func() AlertOverview) *API {
-	return &API{
-		context:  route.Context,
-		alerts:   alerts,
-		silences: silences,
-		events:   events,
-		groups:   gf,
-		uptime:   time.Now(),
+// NewAPI returns a new API.
+func NewAPI(alerts provider.Alerts, silences provider.Silences, events provider.Events, acks provider.Acks, costs *notify.CostAccount, checker *notify.Checker, deadLetters provider.DeadLetters, sup *Supervisor, snapshotFile string, df func() *Dispatcher) *API {
+	return &API{
+		context:      route.Context,
+		alerts:       alerts,
+		silences:     silences,
+		events:       events,
+		acks:         acks,
+		costs:        costs,
+		checker:      checker,
+		deadLetters:  deadLetters,
+		supervisor:   sup,
+		snapshotFile: snapshotFile,
+		dispatcher:   df,
+		uptime:       time.Now(),
+	}
+}
+
+// Register registers the API handlers under their correct routes
+// in the given router.
+func (api *API) Register(r *route.Router) {
+	ihf := func(name string, h http.HandlerFunc) http.HandlerFunc {
+		return prometheus.InstrumentHandlerFunc(name, tracing.InstrumentHandlerFunc(name, api.authorized(name, h)))
+	}
+
+	// Register legacy forwarder for alert pushing.
+	r.Post("/alerts", ihf("legacy_add_alerts", api.legacyAddAlerts))
+
+	// Register versioned alert ingestion.
+	r.WithPrefix("/v2").Post("/alerts", ihf("add_alerts_v2", api.addAlertsV2))
+
+	// Register actual API. Calls of endpoints changing state are recorded
+	// in the audit log, except for the high-volume alert ingestion and
+	// internal cluster traffic.
+	r = r.WithPrefix("/v1")
+
+	r.Get("/status", ihf("status", api.status))
+	r.Get("/status/subsystems", ihf("subsystems_status", api.subsystemsStatus))
+	r.Get("/status/snapshot", ihf("get_snapshot", api.getSnapshot))
+	r.Post("/status/snapshot", ihf("write_snapshot", api.audited("write_snapshot", "", api.writeSnapshot)))
+	r.Get("/stats/costs", ihf("notification_costs", api.notificationCosts))
+	r.Get("/routes/cardinality", ihf("route_cardinality", api.routeCardinality))
+	r.Post("/routes/cardinality", ihf("analyze_route_cardinality", api.routeCardinality))
+	r.Post("/routes/test", ihf("test_routes", api.testRoutes))
+	r.Post("/routes/groups/preview", ihf("preview_route_groups", api.previewRouteGroups))
+	r.Post("/inhibit/test", ihf("test_inhibition", api.testInhibition))
+	r.Post("/config/validate", ihf("validate_config", api.validateConfig))
+	r.Get("/receivers/status", ihf("receivers_status", api.receiversStatus))
+	r.Get("/receivers/health", ihf("receivers_health", api.receiversHealth))
+	r.Get("/schedule", ihf("schedule", api.schedule))
+	r.Get("/admin/state", ihf("export_state", api.exportState))
+	r.Post("/admin/state", ihf("import_state", api.audited("import_state", "", api.importState)))
+	r.Get("/admin/backup", ihf("backup", api.backup))
+	r.Get("/export", ihf("export_bundle", api.exportBundle))
+	r.Post("/import", ihf("import_bundle", api.audited("import_bundle", "", api.importBundle)))
+	r.Get("/admin/audit", ihf("audit_log", api.auditLog))
+	r.Get("/cluster/status", ihf("cluster_status", api.clusterStatus))
+	r.Post("/cluster/gossip", ihf("cluster_gossip", api.clusterGossip))
+	r.Get("/notifications/dead_letters", ihf("dead_letters", api.listDeadLetters))
+	r.Get("/notifications/dead_letter/:id", ihf("get_dead_letter", api.getDeadLetter))
+	r.Del("/notifications/dead_letter/:id", ihf("del_dead_letter", api.audited("del_dead_letter", "id", api.delDeadLetter)))
+	r.Post("/notifications/dead_letter/:id/redrive", ihf("redrive_dead_letter", api.audited("redrive_dead_letter", "id", api.redriveDeadLetter)))
+	r.Get("/notifications/dry_run", ihf("dry_run_notifications", api.listDryRuns))
+	r.Get("/alerts/metrics", ihf("alerts_metrics", api.alertsMetrics))
+	r.Get("/overview", ihf("overview", api.overview))
+	r.Get("/alerts/groups", ihf("alert_groups", api.alertGroups))
+	r.Post("/alerts/groups/:fp/pause", ihf("pause_alert_group", api.audited("pause_alert_group", "fp", api.pauseAlertGroup)))
+	r.Del("/alerts/groups/:fp/pause", ihf("resume_alert_group", api.audited("resume_alert_group", "fp", api.resumeAlertGroup)))
+	r.Get("/alerts/groups/:fp/render", ihf("render_alert_group", api.renderAlertGroup))
+	r.Post("/alerts/groups/:fp/reassign", ihf("reassign_alert_group", api.audited("reassign_alert_group", "fp", api.reassignAlertGroup)))
+	r.Post("/alerts/groups/:fp/silence", ihf("silence_alert_group", api.audited("silence_alert_group", "fp", api.silenceAlertGroup)))
+
+	r.Get("/alerts", ihf("list_alerts", api.listAlerts))
+	r.Post("/alerts", ihf("add_alerts", api.addAlerts))
+	r.Post("/alerts/write", ihf("write_alerts", api.writeAlerts))
+	r.Post("/webhooks/pagerduty", ihf("pagerduty_webhook", api.audited("pagerduty_webhook", "", api.pagerDutyWebhook)))
+	r.Post("/webhooks/slack", ihf("slack_action", api.audited("slack_action", "", api.slackAction)))
+
+	r.Post("/alert/:fp/ack", ihf("ack_alert", api.audited("ack_alert", "fp", api.ackAlert)))
+	r.Del("/alert/:fp/ack", ihf("del_alert_ack", api.audited("del_alert_ack", "fp", api.delAlertAck)))
+	r.Get("/alert/:fp/history", ihf("alert_history", api.alertHistoryTimeline))
+	r.Get("/analytics/alerts", ihf("alert_analytics", api.alertAnalytics))
+
+	r.Get("/silences", ihf("list_silences", api.listSilences))
+	r.Post("/silences", ihf("add_silence", api.audited("add_silence", "", api.addSilence)))
+	r.Post("/silences/preview", ihf("preview_silence", api.previewSilence))
+	r.Get("/silence/:sid", ihf("get_silence", api.getSilence))
+	r.Del("/silence/:sid", ihf("del_silence", api.audited("del_silence", "sid", api.delSilence)))
+
+	r.Get("/maintenance", ihf("list_maintenance_windows", api.listMaintenanceWindows))
+	r.Post("/maintenance", ihf("add_maintenance_window", api.audited("add_maintenance_window", "", api.addMaintenanceWindow)))
+	r.Get("/maintenance/:id", ihf("get_maintenance_window", api.getMaintenanceWindow))
+	r.Put("/maintenance/:id", ihf("update_maintenance_window", api.audited("update_maintenance_window", "id", api.updateMaintenanceWindow)))
+	r.Del("/maintenance/:id", ihf("del_maintenance_window", api.audited("del_maintenance_window", "id", api.delMaintenanceWindow)))
+
+	r.Get("/dependencies", ihf("list_dependencies", api.listDependencies))
+	r.Post("/dependencies", ihf("add_dependency", api.audited("add_dependency", "", api.addDependency)))
+	r.Get("/dependencies/:id", ihf("get_dependency", api.getDependency))
+	r.Del("/dependencies/:id", ihf("del_dependency", api.audited("del_dependency", "id", api.delDependency)))
+
+	r.Post("/templates/render", ihf("render_template", api.renderTemplate))
+
+	r.Get("/events", ihf("list_events", api.listEvents))
+	r.Get("/events/search", ihf("search_events", api.searchEvents))
+	r.Post("/events", ihf("add_event", api.audited("add_event", "", api.addEvent)))
+	r.Put("/event/:eid", ihf("update_event", api.audited("update_event", "eid", api.updateEvent)))
+	r.Del("/event/:eid", ihf("del_event", api.audited("del_event", "eid", api.delEvent)))
+	r.Get("/event/:eid", ihf("get_event", api.getEvent))
+	r.Get("/event/:eid/alerts", ihf("list_event_alerts", api.listEventAlerts))
+	r.Get("/event/:eid/timeline", ihf("event_timeline", api.eventTimeline))
+	r.Post("/event/:eid/ack", ihf("ack_event", api.audited("ack_event", "eid", api.ackEvent)))
+	r.Post("/event/:eid/transition", ihf("transition_event", api.audited("transition_event", "eid", api.transitionEvent)))
+	r.Get("/event/:eid/children", ihf("list_event_children", api.listEventChildren))
+	r.Post("/event/:eid/children/:cid", ihf("attach_event_child", api.audited("attach_event_child", "cid", api.attachEventChild)))
+	r.Del("/event/:eid/children/:cid", ihf("detach_event_child", api.audited("detach_event_child", "cid", api.detachEventChild)))
+	r.Get("/event/:eid/attachments", ihf("list_event_attachments", api.listEventAttachments))
+	r.Post("/event/:eid/attachments", ihf("add_event_attachment", api.audited("add_event_attachment", "eid", api.addEventAttachment)))
+	r.Get("/event/:eid/attachments/:aid", ihf("get_event_attachment", api.getEventAttachment))
+	r.Del("/event/:eid/attachments/:aid", ihf("del_event_attachment", api.audited("del_event_attachment", "aid", api.delEventAttachment)))
+}
+
+// Update sets the configuration string to a new value.
+func (api *API) Update(config string, resolveTimeout time.Duration) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.config = config
+	api.resolveTimeout = resolveTimeout
+}
+
+// SetRunningConfig sets the configuration currently in effect.
+func (api *API) SetRunningConfig(c *config.Config) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.runningConfig = c
+}
+
+// SetLateAlerts sets the age of an alert's start time at which it is
+// considered late and the policy for handling such alerts.
+func (api *API) SetLateAlerts(threshold time.Duration, policy config.LateAlertPolicy) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.lateAlertThreshold = threshold
+	api.lateAlertPolicy = policy
+}
+
+// SetReceivers sets the receivers and templates notification previews
+// are rendered with.
+func (api *API) SetReceivers(rcvs []*config.Receiver, tmpl *template.Template) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.receivers = make(map[string]*config.Receiver, len(rcvs))
+	for _, rcv := range rcvs {
+		api.receivers[rcv.Name] = rcv
+	}
+	api.tmpl = tmpl
+}
+
+// SetPeer sets the peer through which the instance is part of a cluster.
+func (api *API) SetPeer(p *cluster.Peer) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.peer = p
+}
+
+// SetNotifies sets the notification log included in state exports.
+func (api *API) SetNotifies(n provider.Notifies) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.notifies = n
+}
+
+// SetBackups sets the databases included in online backups by the file
+// name they are archived under.
+func (api *API) SetBackups(b map[string]provider.Backuper) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.backups = b
+}
+
+// SetAuditor sets the auditor recording calls of mutating endpoints.
+func (api *API) SetAuditor(au *Auditor) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.auditor = au
+}
+
+// SetPagerdutyWebhook sets the configuration of the PagerDuty webhook
+// endpoint. If it is nil, the endpoint is disabled.
+func (api *API) SetPagerdutyWebhook(conf *config.PagerdutyWebhook) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.pagerDutyWebhookConf = conf
+}
+
+// SetSlackActions sets the configuration of the endpoint for Slack
+// actions. If it is nil, the endpoint is disabled.
+func (api *API) SetSlackActions(conf *config.SlackActions) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.slackActionsConf = conf
+}
+
+// SetAuthenticator sets the authenticator checking access to all
+// endpoints.
+func (api *API) SetAuthenticator(a *Authenticator) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.authenticator = a
+}
+
+// SetTenants sets the tenants requests are scoped to.
+func (api *API) SetTenants(t *Tenants) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.tenants = t
+}
+
+// SetAlertHistory sets the history of alert state changes.
+func (api *API) SetAlertHistory(h provider.AlertHistory) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.alertHistory = h
+}
+
+// SetDryRuns sets the notifications recorded in dry-run mode.
+func (api *API) SetDryRuns(d *notify.DryRuns) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.dryRuns = d
+}
+
+// SetMaintenance sets the storage of maintenance windows.
+func (api *API) SetMaintenance(m provider.MaintenanceWindows) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.maintenance = m
+}
+
+// SetDependencies sets the storage of dependencies between services.
+func (api *API) SetDependencies(d provider.Dependencies) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.dependencies = d
+}
+
+// SetInhibitor sets the inhibitor inhibition rules are tested against.
+func (api *API) SetInhibitor(ih *Inhibitor) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.inhibitor = ih
+}
+
+// SetAttachments sets the storage of files attached to events. Uploads
+// larger than maxSize bytes or of content types other than the given ones
+// are rejected.
+func (api *API) SetAttachments(a provider.EventAttachments, maxSize int64, contentTypes []string) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.attachments = a
+	api.attachmentMaxSize = maxSize
+	api.attachmentContentTypes = map[string]struct{}{}
+	for _, ct := range contentTypes {
+		api.attachmentContentTypes[ct] = struct{}{}
+	}
+}
+
+// SetEventHooks sets the hooks transitions of events are sent to.
+func (api *API) SetEventHooks(h *EventHooks) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.eventHooks = h
+}
+
+// authorized calls the handler of the named endpoint only for requests
+// granted access by the authenticator and within their tenant's rate limit.
+func (api *API) authorized(name string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		api.mtx.RLock()
+		a, t := api.authenticator, api.tenants
+		api.mtx.RUnlock()
+
+		a.Handler(name, t.Handler(name, h))(w, r)
+	}
+}
+
+// tenantMatcher returns a matcher selecting the alerts of the request's
+// tenant. It is nil if the request is not scoped to a tenant.
+func (api *API) tenantMatcher(r *http.Request) *types.Matcher {
+	api.mtx.RLock()
+	t := api.tenants
+	api.mtx.RUnlock()
+
+	return t.Matcher(r)
+}
+
+// audited records calls of the handler in the audit log under the
+// given action. The route parameter param identifies the target.
+func (api *API) audited(action, param string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		api.mtx.RLock()
+		au := api.auditor
+		api.mtx.RUnlock()
+
+		au.Handler(action, param, h)(w, r)
+	}
+}
+
+type errorType string
+
+const (
+	errorNone            errorType = ""
+	errorInternal                  = "server_error"
+	errorBadData                   = "bad_data"
+	errorNotFound                  = "not_found"
+	errorConflict                  = "conflict"
+	errorUnauthorized              = "unauthorized"
+	errorForbidden                 = "forbidden"
+	errorTooLarge                  = "too_large"
+	errorTooManyRequests           = "too_many_requests"
+)
+
+type apiError struct {
+	typ errorType
+	err error
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.typ, e.err)
+}
+
+func (api *API) status(w http.ResponseWriter, req *http.Request) {
+	api.mtx.RLock()
+
+	var status = struct {
+		Config      string            `json:"config"`
+		VersionInfo map[string]string `json:"versionInfo"`
+		Uptime      time.Time         `json:"uptime"`
+	}{
+		Config: api.config,
+		VersionInfo: map[string]string{
+			"version":   version.Version,
+			"revision":  version.Revision,
+			"branch":    version.Branch,
+			"buildUser": version.BuildUser,
+			"buildDate": version.BuildDate,
+			"goVersion": version.GoVersion,
+		},
+		Uptime: api.uptime,
+	}
+
+	api.mtx.RUnlock()
+
+	respond(w, status)
+}
+
+func (api *API) subsystemsStatus(w http.ResponseWriter, req *http.Request) {
+	respond(w, api.supervisor.Status())
+}
+
+func (api *API) clusterPeer(w http.ResponseWriter) (*cluster.Peer, bool) {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	if api.peer == nil {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("not running as a cluster"),
+		}, nil)
+		return nil, false
+	}
+	return api.peer, true
+}
+
+func (api *API) clusterStatus(w http.ResponseWriter, req *http.Request) {
+	if p, ok := api.clusterPeer(w); ok {
+		respond(w, p.Status())
+	}
+}
+
+func (api *API) clusterGossip(w http.ResponseWriter, req *http.Request) {
+	p, ok := api.clusterPeer(w)
+	if !ok {
+		return
+	}
+
+	var msg cluster.Message
+	if err := receive(req, &msg); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := p.Merge(&msg); err != nil {
+		log.With("peer", msg.From).Errorf("Merging gossip failed: %s", err)
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
+
+func (api *API) getSnapshot(w http.ResponseWriter, req *http.Request) {
+	respond(w, api.dispatcher().Snapshot())
+}
+
+func (api *API) writeSnapshot(w http.ResponseWriter, req *http.Request) {
+	snap := api.dispatcher().Snapshot()
+
+	if err := snap.WriteFile(api.snapshotFile); err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, struct {
+		Groups int `json:"groups"`
+	}{
+		Groups: len(snap.Groups),
+	})
+}
+
+func (api *API) listDeadLetters(w http.ResponseWriter, req *http.Request) {
+	letters, err := api.deadLetters.All()
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if m := api.tenantMatcher(req); m != nil {
+		res := []*types.DeadLetter{}
+		for _, l := range letters {
+			if ownsAlerts(m, l.Alerts) {
+				res = append(res, l)
+			}
+		}
+		letters = res
+	}
+	respond(w, letters)
+}
+
+// listDryRuns lists the notifications recorded instead of being sent in
+// dry-run mode, most recent first.
+func (api *API) listDryRuns(w http.ResponseWriter, r *http.Request) {
+	api.mtx.RLock()
+	d := api.dryRuns
+	api.mtx.RUnlock()
+
+	if d == nil {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("dry-run notifications not recorded"),
+		}, nil)
+		return
+	}
+	respond(w, d.All())
+}
+
+func (api *API) getDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if l, ok := api.deadLetter(w, r); ok {
+		respond(w, l)
+	}
+}
+
+func (api *API) delDeadLetter(w http.ResponseWriter, r *http.Request) {
+	l, ok := api.deadLetter(w, r)
+	if !ok {
+		return
+	}
+
+	if err := api.deadLetters.Del(l.ID); err == provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: err,
+		}, nil)
+		return
+	} else if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
+
+// redriveDeadLetter sends the notification of a dead letter again and
+// removes the dead letter if it succeeds.
+func (api *API) redriveDeadLetter(w http.ResponseWriter, r *http.Request) {
+	l, ok := api.deadLetter(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notify.MinTimeout)
+	defer cancel()
+
+	if err := api.dispatcher().Redrive(ctx, l); err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: fmt.Errorf("redriving dead letter failed: %s", err),
+		}, nil)
+		return
+	}
+	if err := api.deadLetters.Del(l.ID); err != nil && err != provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
+
+// deadLetter returns the dead letter referenced by the request's id
+// parameter. If it cannot be retrieved, an error is sent and false is
+// returned. Dead letters with alerts of other tenants than the request's
+// are not retrieved.
+func (api *API) deadLetter(w http.ResponseWriter, r *http.Request) (*types.DeadLetter, bool) {
+	id, err := strconv.ParseUint(route.Param(api.context(r), "id"), 10, 64)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return nil, false
+	}
+
+	l, err := api.deadLetters.Get(id)
+	if err == provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("dead letter %d not found", id),
+		}, nil)
+		return nil, false
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return nil, false
+	}
+	if m := api.tenantMatcher(r); m != nil && !tenantAlerts(w, m, l.Alerts, fmt.Errorf("dead letter %d not found", id)) {
+		return nil, false
+	}
+	return l, true
+}
+
+func (api *API) notificationCosts(w http.ResponseWriter, req *http.Request) {
+	respond(w, api.costs.Totals())
+}
+
+func (api *API) receiversStatus(w http.ResponseWriter, req *http.Request) {
+	respond(w, api.checker.Statuses())
+}
+
+func (api *API) receiversHealth(w http.ResponseWriter, req *http.Request) {
+	statuses := api.checker.Statuses()
+
+	healthy := true
+	for _, rs := range statuses {
+		healthy = healthy && rs.Healthy
+	}
+	respond(w, struct {
+		Healthy   bool                     `json:"healthy"`
+		Receivers []*notify.ReceiverStatus `json:"receivers"`
+	}{
+		Healthy:   healthy,
+		Receivers: statuses,
+	})
+}
+
+func (api *API) schedule(w http.ResponseWriter, req *http.Request) {
+	var ms types.Matchers
+	if m := api.tenantMatcher(req); m != nil {
+		ms = append(ms, m)
+	}
+	respond(w, api.dispatcher().Schedule(time.Now(), ms))
+}
+
+func (api *API) alertGroups(w http.ResponseWriter, req *http.Request) {
+	opts, err := parseGroupsOptions(req.URL.Query())
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if m := api.tenantMatcher(req); m != nil {
+		opts.Matchers = append(opts.Matchers, m)
+	}
+	respond(w, api.dispatcher().GroupsFiltered(opts))
+}
+
+// parseGroupsOptions parses the query parameters receiver, filter, sort,
+// reverse, offset and limit. Filters are of the form name=value or
+// name=~regex and may be given multiple times.
+func parseGroupsOptions(q url.Values) (GroupsOptions, error) {
+	opts := GroupsOptions{
+		Receiver: q.Get("receiver"),
+		SortBy:   q.Get("sort"),
+	}
+	for _, f := range q["filter"] {
+		m, err := parseMatcher(f)
+		if err != nil {
+			return opts, err
+		}
+		opts.Matchers = append(opts.Matchers, m)
+	}
+
+	switch opts.SortBy {
+	case "", SortByLabels, SortByAlerts:
+	default:
+		return opts, fmt.Errorf("unknown sort order %q", opts.SortBy)
+	}
+
+	var err error
+	if v := q.Get("reverse"); v != "" {
+		if opts.Reverse, err = strconv.ParseBool(v); err != nil {
+			return opts, fmt.Errorf("invalid reverse parameter: %s", err)
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if opts.Offset, err = strconv.Atoi(v); err != nil || opts.Offset < 0 {
+			return opts, fmt.Errorf("invalid offset %q", v)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit < 0 {
+			return opts, fmt.Errorf("invalid limit %q", v)
+		}
+	}
+	return opts, nil
+}
+
+// parseMatcher parses a matcher of the form name=value or name=~regex.
+func parseMatcher(s string) (*types.Matcher, error) {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid matcher %q", s)
+	}
+	name := model.LabelName(s[:i])
+	if !name.IsValid() {
+		return nil, fmt.Errorf("invalid label name %q", name)
+	}
+	value := s[i+1:]
+
+	if !strings.HasPrefix(value, "~") {
+		return types.NewMatcher(name, value), nil
+	}
+	re, err := regexp.Compile("^(?:" + value[1:] + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return types.NewRegexMatcher(name, re), nil
+}
+
+// parseSelector parses a label selector of the form
+// {name="value", name=~"regex"} into a list of matchers.
+func parseSelector(s string) (types.Matchers, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return nil, fmt.Errorf("selector %q must be enclosed in braces", s)
+	}
+	s = strings.TrimSpace(s[1 : len(s)-1])
+
+	var ms types.Matchers
+	for s != "" {
+		i := strings.Index(s, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("missing operator in %q", s)
+		}
+		name := model.LabelName(strings.TrimSpace(s[:i]))
+		if !name.IsValid() {
+			return nil, fmt.Errorf("invalid label name %q", name)
+		}
+		s = s[i+1:]
+
+		isRegex := strings.HasPrefix(s, "~")
+		if isRegex {
+			s = s[1:]
+		}
+		s = strings.TrimSpace(s)
+
+		// Find the closing quote of the value, skipping escaped ones.
+		if !strings.HasPrefix(s, `"`) {
+			return nil, fmt.Errorf("value for label %q must be quoted", name)
+		}
+		end := -1
+		for j := 1; j < len(s); j++ {
+			if s[j] == '\\' {
+				j++
+			} else if s[j] == '"' {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated value for label %q", name)
+		}
+		value, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for label %q: %s", name, err)
+		}
+		s = strings.TrimSpace(s[end+1:])
+
+		if isRegex {
+			re, err := regexp.Compile("^(?:" + value + ")$")
+			if err != nil {
+				return nil, err
+			}
+			ms = append(ms, types.NewRegexMatcher(name, re))
+		} else {
+			ms = append(ms, types.NewMatcher(name, value))
+		}
+
+		if s == "" {
+			break
+		}
+		if !strings.HasPrefix(s, ",") {
+			return nil, fmt.Errorf("expected comma after matcher for label %q", name)
+		}
+		s = strings.TrimSpace(s[1:])
+	}
+	return ms, nil
+}
+
+func (api *API) pauseAlertGroup(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.FingerprintFromString(route.Param(api.context(r), "fp"))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var pause struct {
+		Until   time.Time `json:"until"`
+		Inherit bool      `json:"inherit"`
+	}
+	if err := receive(r, &pause); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if !pause.Until.After(time.Now()) {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("pause must end in the future"),
+		}, nil)
+		return
+	}
+	if !api.tenantGroup(w, r, fp) {
+		return
+	}
+
+	api.setGroupPause(w, fp, pause.Until, pause.Inherit)
+}
+
+// renderAlertGroup renders the templates of a receiver against the alerts
+// of the next notification of an aggregation group. The receiver defaults
+// to the one the group notifies next.
+func (api *API) renderAlertGroup(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.FingerprintFromString(route.Param(api.context(r), "fp"))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	name := r.URL.Query().Get("receiver")
+
+	if !api.tenantGroup(w, r, fp) {
+		return
+	}
+	ctx, alerts, err := api.dispatcher().NextNotification(fp, name)
+	if err == provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("alert group %s not found", fp),
+		}, nil)
+		return
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if name == "" {
+		name, _ = notify.Receiver(ctx)
+	} else {
+		ctx = notify.WithReceiver(ctx, name)
+	}
+
+	api.mtx.RLock()
+	rcv, ok := api.receivers[name]
+	tmpl := api.tmpl
+	api.mtx.RUnlock()
+
+	if !ok {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("unknown receiver %q", name),
+		}, nil)
+		return
+	}
+
+	respond(w, struct {
+		Receiver     string              `json:"receiver"`
+		Alerts       []*types.Alert      `json:"alerts"`
+		Integrations []*notify.Rendering `json:"integrations"`
+	}{
+		Receiver:     name,
+		Alerts:       alerts,
+		Integrations: notify.Render(ctx, rcv, tmpl, alerts...),
+	})
+}
+
+// renderTemplate renders a template against sample alerts or the next
+// notification of an aggregation group, without notifying anyone. The
+// template is rendered on its own and may define templates used by the
+// integrations of the receivers, which are rendered as well.
+func (api *API) renderTemplate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Template    string         `json:"template"`
+		Alerts      []*types.Alert `json:"alerts"`
+		GroupLabels model.LabelSet `json:"groupLabels"`
+		GroupKey    string         `json:"groupKey"`
+		Receiver    string         `json:"receiver"`
+	}
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.mtx.RLock()
+	receivers := api.receivers
+	tmpl := api.tmpl
+	api.mtx.RUnlock()
+
+	if tmpl == nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: fmt.Errorf("templates not loaded"),
+		}, nil)
+		return
+	}
+	tmpl, err := tmpl.Extend(req.Template)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var (
+		ctx    context.Context
+		alerts []*types.Alert
+	)
+	if req.GroupKey != "" {
+		ctx, alerts, err = api.dispatcher().NextNotificationByKey(req.GroupKey)
+		if m := api.tenantMatcher(r); err == nil && m != nil && !ownsAlerts(m, alerts) {
+			err = provider.ErrNotFound
+		}
+		if err == provider.ErrNotFound {
+			respondError(w, apiError{
+				typ: errorNotFound,
+				err: fmt.Errorf("alert group %s not found", req.GroupKey),
+			}, nil)
+			return
+		}
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+	} else {
+		if len(req.Alerts) == 0 {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("either alerts or a group key must be given"),
+			}, nil)
+			return
+		}
+		now := time.Now()
+		for _, a := range req.Alerts {
+			if a.StartsAt.IsZero() {
+				a.StartsAt = now
+			}
+			if err := a.Validate(); err != nil {
+				respondError(w, apiError{
+					typ: errorBadData,
+					err: err,
+				}, nil)
+				return
+			}
+		}
+		alerts = req.Alerts
+
+		ctx = notify.WithNow(context.Background(), now)
+		ctx = notify.WithGroupLabels(ctx, req.GroupLabels)
+	}
+
+	var names []string
+	if req.Receiver != "" {
+		if _, ok := receivers[req.Receiver]; !ok {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("unknown receiver %q", req.Receiver),
+			}, nil)
+			return
+		}
+		names = append(names, req.Receiver)
+	} else {
+		for name := range receivers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	type rendering struct {
+		Receiver     string              `json:"receiver"`
+		Text         string              `json:"text"`
+		Error        string              `json:"error,omitempty"`
+		Integrations []*notify.Rendering `json:"integrations"`
+	}
+	res := make([]*rendering, 0, len(names))
+
+	for _, name := range names {
+		rctx := notify.WithReceiver(ctx, name)
+
+		rr := &rendering{
+			Receiver:     name,
+			Integrations: notify.Render(rctx, receivers[name], tmpl, alerts...),
+		}
+		if rr.Text, err = notify.RenderText(rctx, tmpl, req.Template, alerts...); err != nil {
+			rr.Error = err.Error()
+		}
+		res = append(res, rr)
+	}
+
+	respond(w, struct {
+		Alerts    []*types.Alert `json:"alerts"`
+		Receivers []*rendering   `json:"receivers"`
+	}{
+		Alerts:    alerts,
+		Receivers: res,
+	})
+}
+
+// reassignAlertGroup hands an aggregation group over to another receiver
+// until the group is removed.
+func (api *API) reassignAlertGroup(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.FingerprintFromString(route.Param(api.context(r), "fp"))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	name := r.URL.Query().Get("receiver")
+
+	api.mtx.RLock()
+	_, ok := api.receivers[name]
+	api.mtx.RUnlock()
+
+	if !ok {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("unknown receiver %q", name),
+		}, nil)
+		return
+	}
+	if !api.tenantGroup(w, r, fp) {
+		return
+	}
+
+	err = api.dispatcher().ReassignGroup(fp, name)
+	if err == provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("alert group %s not found", fp),
+		}, nil)
+		return
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
+
+// silenceAlertGroup creates a silence matching exactly the labels of an
+// aggregation group for the requested duration.
+func (api *API) silenceAlertGroup(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.FingerprintFromString(route.Param(api.context(r), "fp"))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var req struct {
+		Duration  string `json:"duration"`
+		CreatedBy string `json:"createdBy"`
+		Comment   string `json:"comment"`
+	}
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	d, err := model.ParseDuration(req.Duration)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("duration must be positive")
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid duration %q: %s", req.Duration, err),
+		}, nil)
+		return
+	}
+
+	labels, err := api.dispatcher().GroupLabels(fp)
+	if err == provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("alert group %s not found", fp),
+		}, nil)
+		return
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	// A silence without matchers would mute all alerts.
+	if len(labels) == 0 {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("alert groups without grouping labels cannot be silenced"),
+		}, nil)
+		return
+	}
+	if m := api.tenantMatcher(r); m != nil {
+		if v, ok := labels[m.Name]; ok && string(v) != m.Value {
+			respondError(w, apiError{
+				typ: errorForbidden,
+				err: fmt.Errorf("alert group belongs to another tenant"),
+			}, nil)
+			return
+		}
+		labels = labels.Clone()
+		labels[m.Name] = model.LabelValue(m.Value)
+	}
+
+	sid, err := api.silenceGroup(labels, time.Duration(d), req.CreatedBy, req.Comment, time.Now())
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	respond(w, struct {
+		SilenceID uint64 `json:"silenceId"`
+	}{
+		SilenceID: sid,
+	})
+}
+
+func (api *API) resumeAlertGroup(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.FingerprintFromString(route.Param(api.context(r), "fp"))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if !api.tenantGroup(w, r, fp) {
+		return
+	}
+
+	api.setGroupPause(w, fp, time.Time{}, false)
+}
+
+// tenantGroup returns true if the aggregation groups with the fingerprint
+// only hold alerts of the request's tenant. Otherwise an error is sent and
+// false is returned. Groups without alerts of the tenant are not found.
+func (api *API) tenantGroup(w http.ResponseWriter, r *http.Request, fp model.Fingerprint) bool {
+	m := api.tenantMatcher(r)
+	if m == nil {
+		return true
+	}
+	alerts, err := api.dispatcher().GroupAlerts(fp)
+	if err != nil && err != provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return false
+	}
+	return tenantAlerts(w, m, alerts, fmt.Errorf("alert group %s not found", fp))
+}
+
+// tenantAlerts returns true if all alerts are of the tenant selected by the
+// matcher. Otherwise an error is sent and false is returned. If none of the
+// alerts is of the tenant, the error is the given not found one.
+func tenantAlerts(w http.ResponseWriter, m *types.Matcher, alerts []*types.Alert, notFound error) bool {
+	if ownsAlerts(m, alerts) {
+		return true
+	}
+	for _, a := range alerts {
+		if m.Match(a.Labels) {
+			respondError(w, apiError{
+				typ: errorForbidden,
+				err: fmt.Errorf("alerts of other tenants affected"),
+			}, nil)
+			return false
+		}
+	}
+	respondError(w, apiError{
+		typ: errorNotFound,
+		err: notFound,
+	}, nil)
+	return false
+}
+
+func (api *API) setGroupPause(w http.ResponseWriter, fp model.Fingerprint, until time.Time, inherit bool) {
+	err := api.dispatcher().PauseGroup(fp, until, inherit)
+	if err == provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("alert group %s not found", fp),
+		}, nil)
+		return
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
+
+// stores returns the providers holding the operational state or false if
+// the notification log is not set.
+func (api *API) stores() (*Stores, bool) {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	if api.notifies == nil {
+		return nil, false
+	}
+	return &Stores{
+		Alerts:   api.alerts,
+		Silences: api.silences,
+		Events:   api.events,
+		Notifies: api.notifies,
+		Acks:     api.acks,
+	}, true
+}
+
+// exportState responds with an archive of the complete operational state,
+// which can be imported through importState or the import-state command.
+func (api *API) exportState(w http.ResponseWriter, r *http.Request) {
+	stores, ok := api.stores()
+	if !ok {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: fmt.Errorf("notification log not available"),
+		}, nil)
+		return
+	}
+	st, err := stores.Export(api.tenantMatcher(r))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="alertmanager-state.json"`)
+
+	if err := st.Write(w); err != nil {
+		log.Errorf("Error writing state archive: %s", err)
+	}
+}
+
+// backup streams a tar archive of consistent snapshots of the databases.
+// The files can be copied into the data directory to restore them.
+func (api *API) backup(w http.ResponseWriter, r *http.Request) {
+	// The databases hold the state of all tenants.
+	if api.tenantMatcher(r) != nil {
+		respondError(w, apiError{
+			typ: errorForbidden,
+			err: fmt.Errorf("backups cannot be made by tenants"),
+		}, nil)
+		return
+	}
+	api.mtx.RLock()
+	backups := api.backups
+	api.mtx.RUnlock()
+
+	if len(backups) == 0 {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("no databases to back up"),
+		}, nil)
+		return
+	}
+	names := make([]string, 0, len(backups))
+	for name := range backups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="alertmanager-backup-%s.tar"`, now.UTC().Format("20060102T150405Z")))
+
+	tw := tar.NewWriter(w)
+
+	for _, name := range names {
+		err := backups[name].Backup(func(size int64, snapshot io.WriterTo) error {
+			err := tw.WriteHeader(&tar.Header{
+				Name:    name,
+				Mode:    0644,
+				Size:    size,
+				ModTime: now,
+			})
+			if err != nil {
+				return err
+			}
+			_, err = snapshot.WriteTo(tw)
+			return err
+		})
+		if err != nil {
+			// The response is already partially written and the truncated
+			// archive is detected by the client.
+			log.Errorf("Error writing backup of %s: %s", name, err)
+			return
+		}
+	}
+	if err := tw.Close(); err != nil {
+		log.Errorf("Error writing backup: %s", err)
+	}
+}
+
+// auditLog lists the audit log entries, newest first. The since and
+// limit parameters restrict the returned entries.
+func (api *API) auditLog(w http.ResponseWriter, r *http.Request) {
+	// Audit entries are not attributed to tenants.
+	if api.tenantMatcher(r) != nil {
+		respondError(w, apiError{
+			typ: errorForbidden,
+			err: fmt.Errorf("the audit log cannot be read by tenants"),
+		}, nil)
+		return
+	}
+	api.mtx.RLock()
+	au := api.auditor
+	api.mtx.RUnlock()
+
+	if au == nil {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("audit log not enabled"),
+		}, nil)
+		return
+	}
+
+	var (
+		q     = r.URL.Query()
+		since time.Time
+		limit int
+		err   error
+	)
+	if v := q.Get("since"); v != "" {
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid since parameter: %s", err),
+			}, nil)
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid limit %q", v),
+			}, nil)
+			return
+		}
+	}
+
+	entries, err := au.audit.Query(since, limit)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if entries == nil {
+		entries = []*types.AuditEntry{}
+	}
+	respond(w, entries)
+}
+
+func (api *API) importState(w http.ResponseWriter, r *http.Request) {
+	stores, ok := api.stores()
+	if !ok {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: fmt.Errorf("notification log not available"),
+		}, nil)
+		return
+	}
+	st, err := ReadState(r.Body)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := stores.Import(st); err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
+
+func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
+	var (
+		res []*types.Alert
+		err error
+	)
+	if f := r.URL.Query().Get("filter"); f != "" {
+		ms, perr := parseSelector(f)
+		if perr != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: perr,
+			}, nil)
+			return
+		}
+		res, err = api.alerts.Query(ms)
+	} else {
+		res, err = api.pendingAlerts()
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if m := api.tenantMatcher(r); m != nil {
+		var scoped []*types.Alert
+		for _, a := range res {
+			if m.Match(a.Labels) {
+				scoped = append(scoped, a)
+			}
+		}
+		res = scoped
+	}
+	if wantsCSV(r) {
+		respondAlertsCSV(w, res)
+		return
+	}
+	respond(w, types.Alerts(res...))
+}
+
+// alertsMetrics exposes the currently firing alerts in the Prometheus
+// exposition format so they can be scraped.
+func (api *API) alertsMetrics(w http.ResponseWriter, r *http.Request) {
+	alerts, err := api.pendingAlerts()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if m := api.tenantMatcher(r); m != nil {
+		var res []*types.Alert
+		for _, a := range alerts {
+			if m.Match(a.Labels) {
+				res = append(res, a)
+			}
+		}
+		alerts = res
+	}
+	mf := alertsMetricFamily(alerts, api.dispatcher().marker)
+
+	format := expfmt.Negotiate(r.Header)
+	w.Header().Set("Content-Type", string(format))
+
+	if err := expfmt.NewEncoder(w, format).Encode(mf); err != nil {
+		log.Errorf("Error encoding alerts metrics: %s", err)
+	}
+}
+
+// pendingAlerts returns all alerts with pending notifications.
+func (api *API) pendingAlerts() ([]*types.Alert, error) {
+	alerts := api.alerts.GetPending()
+	defer alerts.Close()
+
+	var res []*types.Alert
+
+	// TODO(fabxc): enforce a sensible timeout.
+	for a := range alerts.Next() {
+		if err := alerts.Err(); err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, nil
+}
+
+// routeCardinality estimates the aggregation groups the current alerts
+// create for each route. If a configuration is posted, its routing tree is
+// analyzed instead of the loaded one.
+func (api *API) routeCardinality(w http.ResponseWriter, r *http.Request) {
+	threshold := DefaultCardinalityThreshold
+
+	if v := r.URL.Query().Get("threshold"); v != "" {
+		var err error
+		if threshold, err = strconv.Atoi(v); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid threshold %q", v),
+			}, nil)
+			return
+		}
+	}
+
+	root := api.dispatcher().Route()
+
+	if r.Method == "POST" {
+		b, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+		conf, err := config.Load(string(b))
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+		root = NewRoute(conf.Route, nil)
+	}
+
+	alerts, err := api.pendingAlerts()
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, AnalyzeCardinality(root, alerts, threshold))
+}
+
+// previewRouteGroups responds with the aggregation groups the current
+// alerts would be grouped into by the routing tree of the posted
+// configuration, along with the number of groups of the loaded one.
+func (api *API) previewRouteGroups(w http.ResponseWriter, r *http.Request) {
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	conf, err := config.Load(string(b))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	alerts, err := api.pendingAlerts()
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if m := api.tenantMatcher(r); m != nil {
+		var res []*types.Alert
+		for _, a := range alerts {
+			if m.Match(a.Labels) {
+				res = append(res, a)
+			}
+		}
+		alerts = res
+	}
+
+	groups := PreviewGroups(NewRoute(conf.Route, nil), alerts)
+	respond(w, struct {
+		Groups        []*GroupPreview `json:"groups"`
+		CurrentGroups int             `json:"currentGroups"`
+	}{
+		Groups:        groups,
+		CurrentGroups: len(PreviewGroups(api.dispatcher().Route(), alerts)),
+	})
+}
+
+// validateConfig checks the posted configuration and compares it to the
+// running one.
+func (api *API) validateConfig(w http.ResponseWriter, r *http.Request) {
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	res := &ConfigValidation{}
+
+	conf, err := config.Load(string(b))
+	if err == nil {
+		_, err = template.FromGlobs(conf.Templates...)
+	}
+	if err != nil {
+		res.Errors = append(res.Errors, err.Error())
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid configuration: %s", err),
+		}, res)
+		return
+	}
+
+	res.Valid = true
+	res.Warnings = ConfigWarnings(conf)
+
+	api.mtx.RLock()
+	running := api.runningConfig
+	api.mtx.RUnlock()
+
+	if running != nil {
+		res.Diff = DiffConfig(running, conf)
+	}
+	respond(w, res)
+}
+
+// testRoutes returns the routes an alert with the posted labels would be
+// routed to.
+func (api *API) testRoutes(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Labels model.LabelSet `json:"labels"`
+	}
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if len(req.Labels) == 0 {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("no labels given"),
+		}, nil)
+		return
+	}
+	if err := req.Labels.Validate(); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	respond(w, api.dispatcher().Route().MatchDetails(req.Labels))
+}
+
+// testInhibition returns the inhibition rules that would mute an alert with
+// the posted labels along with the firing source alerts causing it.
+func (api *API) testInhibition(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Labels model.LabelSet `json:"labels"`
+	}
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if len(req.Labels) == 0 {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("no labels given"),
+		}, nil)
+		return
+	}
+	if err := req.Labels.Validate(); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.mtx.RLock()
+	ih := api.inhibitor
+	api.mtx.RUnlock()
+
+	if ih == nil {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("inhibitor not running"),
+		}, nil)
+		return
+	}
+
+	res := struct {
+		Inhibited bool                `json:"inhibited"`
+		Rules     []*InhibitRuleMatch `json:"rules"`
+	}{
+		Rules: []*InhibitRuleMatch{},
+	}
+	tm := api.tenantMatcher(r)
+	for _, m := range ih.Test(req.Labels) {
+		res.Inhibited = true
+		// Source alerts of other tenants are not disclosed.
+		if tm != nil {
+			srcs := []*types.Alert{}
+			for _, a := range m.Sources {
+				if tm.Match(a.Labels) {
+					srcs = append(srcs, a)
+				}
+			}
+			m.Sources = srcs
+		}
+		res.Rules = append(res.Rules, m)
+	}
+	respond(w, res)
+}
+
+// alertHistoryTimeline lists the recorded state changes of an alert in
+// chronological order.
+func (api *API) alertHistoryTimeline(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.FingerprintFromString(route.Param(api.context(r), "fp"))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.mtx.RLock()
+	h := api.alertHistory
+	api.mtx.RUnlock()
+
+	if h == nil {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("alert history not enabled"),
+		}, nil)
+		return
+	}
+
+	entries, err := h.Get(fp)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	// The history of alerts of other tenants is not found. The labels of
+	// alerts are recorded with their creation.
+	if m := api.tenantMatcher(r); m != nil {
+		var lset model.LabelSet
+		for _, e := range entries {
+			if e.State == types.AlertCreated {
+				lset = e.Labels
+			}
+		}
+		if !m.Match(lset) {
+			entries = nil
+		}
+	}
+	if len(entries) == 0 {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("no history of alert %s", fp),
+		}, nil)
+		return
+	}
+	respond(w, entries)
+}
+
+// alertAnalytics summarizes the volume of alerts and notifications recorded
+// in the alert history over a time range, which defaults to the last day.
+func (api *API) alertAnalytics(w http.ResponseWriter, r *http.Request) {
+	api.mtx.RLock()
+	h := api.alertHistory
+	api.mtx.RUnlock()
+
+	if h == nil {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("alert history not enabled"),
+		}, nil)
+		return
+	}
+
+	var (
+		q     = r.URL.Query()
+		to    = time.Now()
+		from  time.Time
+		top   = 10
+		names []model.LabelName
+		err   error
+	)
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid to parameter: %s", err),
+			}, nil)
+			return
+		}
+	}
+	from = to.Add(-24 * time.Hour)
+	if v := q.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid from parameter: %s", err),
+			}, nil)
+			return
+		}
+	}
+	if !from.Before(to) {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("from must be before to"),
+		}, nil)
+		return
+	}
+	if v := q.Get("top"); v != "" {
+		if top, err = strconv.Atoi(v); err != nil || top < 0 {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid top %q", v),
+			}, nil)
+			return
+		}
+	}
+	for _, v := range q["label"] {
+		ln := model.LabelName(v)
+		if !ln.IsValid() {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid label name %q", v),
+			}, nil)
+			return
+		}
+		names = append(names, ln)
+	}
+	if names == nil {
+		names = []model.LabelName{model.AlertNameLabel}
+	}
+
+	res, err := analyzeAlerts(h, from, to, names, top, api.tenantMatcher(r))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
 	}
+	respond(w, res)
 }
 
-// Register registers the API handlers under their correct routes
-// in the given router.
-func (api *API) Register(r *route.Router) {
-	ihf := prometheus.InstrumentHandlerFunc
-
-	// Register legacy forwarder for alert pushing.
-	r.Post("/alerts", ihf("legacy_add_alerts", api.legacyAddAlerts))
-
-	// Register actual API.
-	r = r.WithPrefix("/v1")
+func (api *API) ackAlert(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.FingerprintFromString(route.Param(api.context(r), "fp"))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
 
-	r.Get("/status", ihf("status", api.status))
-	r.Get("/alerts/groups", ihf("alert_groups", api.alertGroups))
+	var ack types.Ack
+	if err := receive(r, &ack); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	ack.Alert = fp
+	ack.CreatedAt = time.Now()
 
-	r.Get("/alerts", ihf("list_alerts", api.listAlerts))
-	r.Post("/alerts", ihf("add_alerts", api.addAlerts))
+	if err := ack.Validate(); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
 
-	r.Get("/silences", ihf("list_silences", api.listSilences))
-	r.Post("/silences", ihf("add_silence", api.addSilence))
-	r.Get("/silence/:sid", ihf("get_silence", api.getSilence))
-	r.Del("/silence/:sid", ihf("del_silence", api.delSilence))
+	alert, ok := api.alert(w, r, fp)
+	if !ok {
+		return
+	}
+	if alert.Resolved() {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("alert %s is resolved", fp),
+		}, nil)
+		return
+	}
 
-	r.Get("/events", ihf("list_events", api.listEvents))
-	r.Post("/events", ihf("add_event", api.addEvent))
-	r.Get("/event/:eid/alerts", ihf("list_event_alerts", api.listEventAlerts))
+	if err := api.acks.Set(&ack); err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, &ack)
 }
 
-// Update sets the configuration string to a new value.
-func (api *API) Update(config string, resolveTimeout time.Duration) {
-	api.mtx.Lock()
-	defer api.mtx.Unlock()
-
-	api.config = config
-	api.resolveTimeout = resolveTimeout
+// alert returns the alert with the fingerprint or responds with an error.
+// Alerts of other tenants than the request's are not found.
+func (api *API) alert(w http.ResponseWriter, r *http.Request, fp model.Fingerprint) (*types.Alert, bool) {
+	alert, err := api.alerts.Get(fp)
+	if m := api.tenantMatcher(r); err == nil && m != nil && !m.Match(alert.Labels) {
+		err = provider.ErrNotFound
+	}
+	if err == provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("alert %s not found", fp),
+		}, nil)
+		return nil, false
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return nil, false
+	}
+	return alert, true
 }
 
-type errorType string
-
-const (
-	errorNone     errorType = ""
-	errorInternal           = "server_error"
-	errorBadData            = "bad_data"
-)
-
-type apiError struct {
-	typ errorType
-	err error
+// firingAlerts returns the alerts that are not resolved.
+func firingAlerts(alerts []*types.Alert) []*types.Alert {
+	var firing []*types.Alert
+	for _, a := range alerts {
+		if !a.Resolved() {
+			firing = append(firing, a)
+		}
+	}
+	return firing
 }
 
-func (e *apiError) Error() string {
-	return fmt.Sprintf("%s: %s", e.typ, e.err)
+// ackAlerts acknowledges the alerts on behalf of the creator until they
+// resolve and returns their fingerprints.
+func (api *API) ackAlerts(alerts []*types.Alert, createdBy, comment string, now time.Time) ([]model.Fingerprint, error) {
+	var fps []model.Fingerprint
+	for _, a := range alerts {
+		ack := &types.Ack{
+			Alert:     a.Fingerprint(),
+			CreatedBy: createdBy,
+			Comment:   comment,
+			CreatedAt: now,
+		}
+		if err := api.acks.Set(ack); err != nil {
+			return fps, err
+		}
+		fps = append(fps, ack.Alert)
+	}
+	return fps, nil
 }
 
-func (api *API) status(w http.ResponseWriter, req *http.Request) {
-	api.mtx.RLock()
-
-	var status = struct {
-		Config      string            `json:"config"`
-		VersionInfo map[string]string `json:"versionInfo"`
-		Uptime      time.Time         `json:"uptime"`
-	}{
-		Config: api.config,
-		VersionInfo: map[string]string{
-			"version":   version.Version,
-			"revision":  version.Revision,
-			"branch":    version.Branch,
-			"buildUser": version.BuildUser,
-			"buildDate": version.BuildDate,
-			"goVersion": version.GoVersion,
-		},
-		Uptime: api.uptime,
+// silenceGroup silences all alerts with the given group labels for the
+// given duration on behalf of the creator and returns the silence's ID.
+func (api *API) silenceGroup(labels model.LabelSet, d time.Duration, createdBy, comment string, now time.Time) (uint64, error) {
+	names := make(model.LabelNames, 0, len(labels))
+	for ln := range labels {
+		names = append(names, ln)
 	}
+	sort.Sort(names)
 
-	api.mtx.RUnlock()
-
-	respond(w, status)
-}
+	ms := make([]*model.Matcher, 0, len(names))
+	for _, ln := range names {
+		ms = append(ms, &model.Matcher{Name: ln, Value: string(labels[ln])})
+	}
 
-func (api *API) alertGroups(w http.ResponseWriter, req *http.Request) {
-	respond(w, api.groups())
+	sil := types.NewSilence(&model.Silence{
+		Matchers:  ms,
+		StartsAt:  now,
+		EndsAt:    now.Add(d),
+		CreatedAt: now,
+		CreatedBy: createdBy,
+		Comment:   comment,
+	})
+	if err := sil.Validate(); err != nil {
+		return 0, err
+	}
+	return api.silences.Set(sil)
 }
 
-func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
-	alerts := api.alerts.GetPending()
-	defer alerts.Close()
-
-	var (
-		err error
-		res []*types.Alert
-	)
-	// TODO(fabxc): enforce a sensible timeout.
-	for a := range alerts.Next() {
-		if err = alerts.Err(); err != nil {
-			break
+func (api *API) delAlertAck(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.FingerprintFromString(route.Param(api.context(r), "fp"))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if api.tenantMatcher(r) != nil {
+		if _, ok := api.alert(w, r, fp); !ok {
+			return
 		}
-		res = append(res, a)
 	}
 
-	if err != nil {
+	if err := api.acks.Del(fp); err != nil {
 		respondError(w, apiError{
 			typ: errorInternal,
 			err: err,
 		}, nil)
 		return
 	}
-	respond(w, types.Alerts(res...))
+	respond(w, nil)
 }
 
 func (api *API) legacyAddAlerts(w http.ResponseWriter, r *http.Request) {
@@ -234,7 +2205,85 @@ func (api *API) addAlerts(w http.ResponseWriter, r *http.Request) {
 	api.insertAlerts(w, r, alerts...)
 }
 
+// writeAlerts receives a snappy-compressed protobuf batch of alerts as
+// defined in the ingest package.
+func (api *API) writeAlerts(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if enc := r.Header.Get("Content-Encoding"); enc != "snappy" {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("unsupported content encoding %q", enc),
+		}, nil)
+		return
+	}
+	alerts, err := ingest.Decode(r.Body)
+	if err != nil {
+		log.Debugf("Decoding alert batch failed: %v", err)
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.insertAlerts(w, r, alerts...)
+}
+
 func (api *API) insertAlerts(w http.ResponseWriter, r *http.Request, alerts ...*types.Alert) {
+	api.setSource(r, alerts...)
+
+	validationErrs, err := api.storeAlerts(alerts...)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	if validationErrs.Len() > 0 {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: validationErrs,
+		}, nil)
+		return
+	}
+
+	respond(w, nil)
+}
+
+// setSource records the request's authenticated user and address as the
+// source of the alerts, replacing any source sent with them.
+func (api *API) setSource(r *http.Request, alerts ...*types.Alert) {
+	api.mtx.RLock()
+	auth := api.authenticator
+	api.mtx.RUnlock()
+
+	client, _ := auth.User(r)
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	for _, a := range alerts {
+		a.Source = &types.AlertSource{
+			Client:  client,
+			Address: addr,
+		}
+		if u, err := url.Parse(a.GeneratorURL); err == nil {
+			host := u.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			a.Source.Domain = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		}
+	}
+}
+
+// storeAlerts makes a best effort to store all valid alerts. The returned
+// multi error holds the validation errors of the invalid ones. A non-nil
+// error is only returned if storing the alerts failed.
+func (api *API) storeAlerts(alerts ...*types.Alert) (*types.MultiError, error) {
 	now := time.Now()
 
 	for _, alert := range alerts {
@@ -256,7 +2305,6 @@ func (api *API) insertAlerts(w http.ResponseWriter, r *http.Request, alerts ...*
 		}
 	}
 
-	// Make a best effort to insert all alerts that are valid.
 	var (
 		validAlerts    = make([]*types.Alert, 0, len(alerts))
 		validationErrs = &types.MultiError{}
@@ -267,25 +2315,40 @@ func (api *API) insertAlerts(w http.ResponseWriter, r *http.Request, alerts ...*
 			numInvalidAlerts.Inc()
 			continue
 		}
+		if api.suppressLate(a, now) {
+			continue
+		}
 		validAlerts = append(validAlerts, a)
 	}
 	if err := api.alerts.Put(validAlerts...); err != nil {
-		respondError(w, apiError{
-			typ: errorInternal,
-			err: err,
-		}, nil)
-		return
+		return nil, err
 	}
+	return validationErrs, nil
+}
 
-	if validationErrs.Len() > 0 {
-		respondError(w, apiError{
-			typ: errorBadData,
-			err: validationErrs,
-		}, nil)
-		return
+// suppressLate returns true iff the alert arrived late and must be dropped
+// according to the late alert policy. Only resolved alerts that were never
+// received while firing are dropped.
+func (api *API) suppressLate(a *types.Alert, now time.Time) bool {
+	api.mtx.RLock()
+	threshold, policy := api.lateAlertThreshold, api.lateAlertPolicy
+	api.mtx.RUnlock()
+
+	if threshold <= 0 || !a.StartsAt.Before(now.Add(-threshold)) {
+		return false
+	}
+	if policy != config.LateAlertSuppressResolved || !a.Resolved() {
+		numLateAlerts.WithLabelValues("notify").Inc()
+		return false
+	}
+	if _, err := api.alerts.Get(a.Fingerprint()); err != provider.ErrNotFound {
+		numLateAlerts.WithLabelValues("notify").Inc()
+		return false
 	}
+	numLateAlerts.WithLabelValues("suppress").Inc()
 
-	respond(w, nil)
+	log.With("alert", a).Debugf("Dropping late resolved alert that started at %s", a.StartsAt)
+	return true
 }
 
 func (api *API) addSilence(w http.ResponseWriter, r *http.Request) {
@@ -301,6 +2364,15 @@ func (api *API) addSilence(w http.ResponseWriter, r *http.Request) {
 	if sil.CreatedAt.IsZero() {
 		sil.CreatedAt = time.Now()
 	}
+	if m := api.tenantMatcher(r); m != nil {
+		if err := scopeSilence(m, &sil); err != nil {
+			respondError(w, apiError{
+				typ: errorForbidden,
+				err: err,
+			}, nil)
+			return
+		}
+	}
 
 	if err := sil.Validate(); err != nil {
 		respondError(w, apiError{
@@ -326,6 +2398,45 @@ func (api *API) addSilence(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// previewSilence responds with the firing alerts and aggregation groups
+// the silence in the request would mute if it was created. The silence is
+// validated as on creation.
+func (api *API) previewSilence(w http.ResponseWriter, r *http.Request) {
+	var sil types.Silence
+	if err := receive(r, &sil); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	now := time.Now()
+	if sil.CreatedAt.IsZero() {
+		sil.CreatedAt = now
+	}
+	if m := api.tenantMatcher(r); m != nil {
+		if err := scopeSilence(m, &sil); err != nil {
+			respondError(w, apiError{
+				typ: errorForbidden,
+				err: err,
+			}, nil)
+			return
+		}
+	}
+
+	if err := sil.Validate(); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	s := types.RestoreSilence(&sil)
+	respond(w, api.dispatcher().PreviewSilence(s, now))
+}
+
 func (api *API) getSilence(w http.ResponseWriter, r *http.Request) {
 	sids := route.Param(api.context(r), "sid")
 	sid, err := strconv.ParseUint(sids, 10, 64)
@@ -338,6 +2449,9 @@ func (api *API) getSilence(w http.ResponseWriter, r *http.Request) {
 	}
 
 	sil, err := api.silences.Get(sid)
+	if m := api.tenantMatcher(r); err == nil && m != nil && !ownsSilence(m, sil) {
+		err = provider.ErrNotFound
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprint("Error getting silence: ", err), http.StatusNotFound)
 		return
@@ -356,6 +2470,19 @@ func (api *API) delSilence(w http.ResponseWriter, r *http.Request) {
 		}, nil)
 		return
 	}
+	if m := api.tenantMatcher(r); m != nil {
+		sil, err := api.silences.Get(sid)
+		if err == nil && !ownsSilence(m, sil) {
+			err = provider.ErrNotFound
+		}
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorNotFound,
+				err: fmt.Errorf("silence %d not found", sid),
+			}, nil)
+			return
+		}
+	}
 
 	if err := api.silences.Del(sid); err != nil {
 		respondError(w, apiError{
@@ -376,6 +2503,15 @@ func (api *API) listSilences(w http.ResponseWriter, r *http.Request) {
 		}, nil)
 		return
 	}
+	if m := api.tenantMatcher(r); m != nil {
+		var scoped []*types.Silence
+		for _, sil := range sils {
+			if ownsSilence(m, sil) {
+				scoped = append(scoped, sil)
+			}
+		}
+		sils = scoped
+	}
 	respond(w, sils)
 }
 
@@ -415,6 +2551,18 @@ func respondError(w http.ResponseWriter, apiErr apiError, data interface{}) {
 		w.WriteHeader(http.StatusBadRequest)
 	case errorInternal:
 		w.WriteHeader(http.StatusInternalServerError)
+	case errorNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case errorConflict:
+		w.WriteHeader(http.StatusConflict)
+	case errorUnauthorized:
+		w.WriteHeader(http.StatusUnauthorized)
+	case errorForbidden:
+		w.WriteHeader(http.StatusForbidden)
+	case errorTooLarge:
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	case errorTooManyRequests:
+		w.WriteHeader(http.StatusTooManyRequests)
 	default:
 		panic(fmt.Sprintf("unknown error type %q", apiErr))
 	}
@@ -433,13 +2581,40 @@ func respondError(w http.ResponseWriter, apiErr apiError, data interface{}) {
 	w.Write(b)
 }
 
+// receive decodes the request body into v. Bodies may be gzip compressed
+// and encoded as JSON or msgpack as indicated by the request headers.
 func receive(r *http.Request, v interface{}) error {
-	dec := json.NewDecoder(r.Body)
 	defer r.Body.Close()
 
-	err := dec.Decode(v)
+	err := decodeBody(r, v)
 	if err != nil {
 		log.Debugf("Decoding request failed: %v", err)
 	}
 	return err
 }
+
+func decodeBody(r *http.Request, v interface{}) error {
+	var body io.Reader = r.Body
+
+	switch enc := r.Header.Get("Content-Encoding"); enc {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		body = gz
+	default:
+		return fmt.Errorf("unsupported content encoding %q", enc)
+	}
+
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mt {
+	case "application/msgpack", "application/x-msgpack":
+		return decodeMsgpack(body, v)
+	default:
+		return json.NewDecoder(body).Decode(v)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+// bundleStores returns the stores of the state held in bundles.
+func (api *API) bundleStores() *Stores {
+	return &Stores{
+		Alerts:   api.alerts,
+		Silences: api.silences,
+		Events:   api.events,
+		Acks:     api.acks,
+	}
+}
+
+// exportBundle responds with a bundle of the silences, acknowledgements,
+// and open events of the request's tenant, which can be imported into
+// another instance through importBundle.
+func (api *API) exportBundle(w http.ResponseWriter, r *http.Request) {
+	b, err := api.bundleStores().ExportBundle(time.Now(), api.tenantMatcher(r))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="alertmanager-bundle.json"`)
+
+	if err := b.Write(w); err != nil {
+		log.Errorf("Error writing bundle: %s", err)
+	}
+}
+
+// importBundle adds the silences, acknowledgements, and events of the
+// bundle in the request body.
+func (api *API) importBundle(w http.ResponseWriter, r *http.Request) {
+	// Bundles are not scoped to tenants as the alerts acknowledgements
+	// refer to may not exist yet.
+	if api.tenantMatcher(r) != nil {
+		respondError(w, apiError{
+			typ: errorForbidden,
+			err: fmt.Errorf("bundles cannot be imported by tenants"),
+		}, nil)
+		return
+	}
+	b, err := ReadBundle(r.Body)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	counts, err := api.bundleStores().ImportBundle(b)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, counts)
+}
@@ -0,0 +1,253 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// dependencyCycle returns the services forming a cycle if the dependency
+// were added to the given ones, starting and ending with the service of
+// the dependency.
+func dependencyCycle(deps []*types.Dependency, d *types.Dependency) []string {
+	dependsOn := map[string][]string{}
+	for _, dep := range deps {
+		dependsOn[dep.Service] = append(dependsOn[dep.Service], dep.DependsOn)
+	}
+
+	visited := map[string]bool{}
+	var path func(from string) []string
+	// path returns the services on a path from the given service to the
+	// service of the dependency.
+	path = func(from string) []string {
+		if from == d.Service {
+			return []string{from}
+		}
+		if visited[from] {
+			return nil
+		}
+		visited[from] = true
+
+		for _, to := range dependsOn[from] {
+			if p := path(to); p != nil {
+				return append([]string{from}, p...)
+			}
+		}
+		return nil
+	}
+	if p := path(d.DependsOn); p != nil {
+		return append([]string{d.Service}, p...)
+	}
+	return nil
+}
+
+// dependencyStore returns the dependencies or responds with an error if
+// they are not stored.
+func (api *API) dependencyStore(w http.ResponseWriter) (provider.Dependencies, bool) {
+	api.mtx.RLock()
+	d := api.dependencies
+	api.mtx.RUnlock()
+
+	if d == nil {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("dependencies not stored"),
+		}, nil)
+		return nil, false
+	}
+	return d, true
+}
+
+// dependency returns the dependency of the request's id parameter.
+func (api *API) dependency(w http.ResponseWriter, r *http.Request) (*types.Dependency, bool) {
+	d, ok := api.dependencyStore(w)
+	if !ok {
+		return nil, false
+	}
+	id, err := strconv.ParseUint(route.Param(api.context(r), "id"), 10, 64)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return nil, false
+	}
+
+	dep, err := d.Get(id)
+	if err == provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("dependency %d not found", id),
+		}, nil)
+		return nil, false
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return nil, false
+	}
+	return dep, true
+}
+
+// forbidDependencyChange responds with an error if the request is scoped to a
+// tenant. Dependencies are shared by all tenants, so only unscoped
+// requests may change them.
+func (api *API) forbidDependencyChange(w http.ResponseWriter, r *http.Request) bool {
+	if api.tenantMatcher(r) == nil {
+		return false
+	}
+	respondError(w, apiError{
+		typ: errorForbidden,
+		err: fmt.Errorf("dependencies cannot be changed by tenants"),
+	}, nil)
+	return true
+}
+
+func (api *API) listDependencies(w http.ResponseWriter, r *http.Request) {
+	d, ok := api.dependencyStore(w)
+	if !ok {
+		return
+	}
+	deps, err := d.All()
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if deps == nil {
+		deps = []*types.Dependency{}
+	}
+	respond(w, deps)
+}
+
+func (api *API) getDependency(w http.ResponseWriter, r *http.Request) {
+	if dep, ok := api.dependency(w, r); ok {
+		respond(w, dep)
+	}
+}
+
+func (api *API) addDependency(w http.ResponseWriter, r *http.Request) {
+	d, ok := api.dependencyStore(w)
+	if !ok || api.forbidDependencyChange(w, r) {
+		return
+	}
+	var dep types.Dependency
+	if err := receive(r, &dep); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := dep.Validate(); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	deps, err := d.All()
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	for _, old := range deps {
+		if old.Service == dep.Service && old.DependsOn == dep.DependsOn {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("dependency %d already declares that %q depends on %q", old.ID, dep.Service, dep.DependsOn),
+			}, nil)
+			return
+		}
+	}
+	// Services depending on each other would inhibit each other's alerts
+	// while all of them fire.
+	if c := dependencyCycle(deps, &dep); c != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("dependency creates the cycle %s", strings.Join(c, " -> ")),
+		}, nil)
+		return
+	}
+	dep.ID = 0
+	dep.CreatedAt = time.Now()
+
+	id, err := d.Set(&dep)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	api.reloadDependencies()
+	respond(w, struct {
+		DependencyID uint64 `json:"dependencyId"`
+	}{
+		DependencyID: id,
+	})
+}
+
+func (api *API) delDependency(w http.ResponseWriter, r *http.Request) {
+	if api.forbidDependencyChange(w, r) {
+		return
+	}
+	dep, ok := api.dependency(w, r)
+	if !ok {
+		return
+	}
+	d, _ := api.dependencyStore(w)
+	if err := d.Del(dep.ID); err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	api.reloadDependencies()
+	respond(w, nil)
+}
+
+// reloadDependencies makes the running inhibitor load the changed
+// dependencies.
+func (api *API) reloadDependencies() {
+	api.mtx.RLock()
+	ih := api.inhibitor
+	api.mtx.RUnlock()
+
+	if ih == nil {
+		return
+	}
+	if err := ih.ReloadDependencies(); err != nil {
+		log.Errorf("Reloading dependencies failed: %s", err)
+	}
+}
@@ -1,18 +1,80 @@
 package main
 
 import (
-	"time"
-	"strconv"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/route"
 
+	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/types"
 )
 
 func (api *API) listEvents(w http.ResponseWriter, r *http.Request) {
-	events, err := api.events.All()
+	var (
+		q        = r.URL.Query()
+		from, to time.Time
+		limit    int
+		err      error
+	)
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{
+		{"from", &from},
+		{"to", &to},
+	} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		if *p.t, err = time.Parse(time.RFC3339, v); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid %s parameter: %s", p.name, err),
+			}, nil)
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid limit %q", v),
+			}, nil)
+			return
+		}
+	}
+	statuses := map[types.EventStatus]bool{}
+	for _, v := range q["status"] {
+		s := types.EventStatus(v)
+		if err := s.Validate(); err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+		statuses[s] = true
+	}
+
+	var events []*types.Event
+	if from.IsZero() && to.IsZero() && limit == 0 {
+		events, err = api.events.All()
+	} else {
+		events, err = api.events.ListByTimeRange(from, to, limit)
+	}
 	if err != nil {
 		respondError(w, apiError{
 			typ: errorInternal,
@@ -20,9 +82,349 @@ func (api *API) listEvents(w http.ResponseWriter, r *http.Request) {
 		}, nil)
 		return
 	}
+	events = api.tenantEvents(r, events)
+	if len(statuses) > 0 {
+		var res []*types.Event
+		for _, e := range events {
+			if statuses[e.CurrentStatus()] {
+				res = append(res, e)
+			}
+		}
+		events = res
+	}
+	if wantsCSV(r) {
+		respondEventsCSV(w, events)
+		return
+	}
 	respond(w, events)
 }
 
+func (api *API) searchEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("missing search query"),
+		}, nil)
+		return
+	}
+
+	events, err := api.events.Search(q)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	events = api.tenantEvents(r, events)
+	if wantsCSV(r) {
+		respondEventsCSV(w, events)
+		return
+	}
+	respond(w, events)
+}
+
+func (api *API) getEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := api.event(w, r)
+	if !ok {
+		return
+	}
+	respond(w, event)
+}
+
+// event returns the event referenced by the request's parameters or
+// responds with an error.
+func (api *API) event(w http.ResponseWriter, r *http.Request) (*types.Event, bool) {
+	eids := route.Param(api.context(r), "eid")
+	eid, err := strconv.ParseUint(eids, 10, 64)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return nil, false
+	}
+
+	event, err := api.events.Get(eid)
+	if m := api.tenantMatcher(r); err == nil && m != nil && !m.Match(event.Labels) {
+		err = provider.ErrNotFound
+	}
+	if err != nil {
+		respondEventError(w, eid, err)
+		return nil, false
+	}
+	return event, true
+}
+
+// scopeEvent labels the event with the request's tenant.
+func (api *API) scopeEvent(r *http.Request, event *types.Event) {
+	m := api.tenantMatcher(r)
+	if m == nil {
+		return
+	}
+	if event.Labels == nil {
+		event.Labels = model.LabelSet{}
+	}
+	event.Labels[m.Name] = model.LabelValue(m.Value)
+}
+
+// tenantEvents returns the events labeled with the request's tenant.
+func (api *API) tenantEvents(r *http.Request, events []*types.Event) []*types.Event {
+	m := api.tenantMatcher(r)
+	if m == nil {
+		return events
+	}
+	var res []*types.Event
+	for _, e := range events {
+		if m.Match(e.Labels) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// eventAlerts returns the alerts of the event that still exist.
+func (api *API) eventAlerts(event *types.Event) ([]*types.Alert, error) {
+	var alerts []*types.Alert
+	for _, ids := range event.Alerts {
+		id, err := strconv.ParseUint(ids, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		a, err := api.alerts.Get(model.Fingerprint(id))
+		if err == provider.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+// Kinds of entries in an event's timeline.
+const (
+	timelineCreated       = "created"
+	timelineUpdated       = "updated"
+	timelineClosed        = "closed"
+	timelineAlertStarted  = "alert_started"
+	timelineAlertResolved = "alert_resolved"
+	timelineAcknowledged  = "acknowledged"
+	timelineStatusChanged = "status_changed"
+)
+
+// EventTimelineEntry is something that happened during an event.
+type EventTimelineEntry struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	// Alert is set for entries about one of the event's alerts.
+	Alert  *types.Alert `json:"alert,omitempty"`
+	Ack    *types.Ack   `json:"ack,omitempty"`
+	Author string       `json:"author,omitempty"`
+	// Status is set for entries about the event entering a status.
+	Status types.EventStatus `json:"status,omitempty"`
+}
+
+type eventTimeline []*EventTimelineEntry
+
+func (t eventTimeline) Len() int           { return len(t) }
+func (t eventTimeline) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t eventTimeline) Less(i, j int) bool { return t[i].Time.Before(t[j].Time) }
+
+func (api *API) eventTimeline(w http.ResponseWriter, r *http.Request) {
+	event, ok := api.event(w, r)
+	if !ok {
+		return
+	}
+	alerts, err := api.eventAlerts(event)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	timeline := eventTimeline{{
+		Time:   event.CreatedAt,
+		Kind:   timelineCreated,
+		Author: event.Creator,
+	}}
+	if !event.UpdatedAt.IsZero() {
+		timeline = append(timeline, &EventTimelineEntry{
+			Time: event.UpdatedAt,
+			Kind: timelineUpdated,
+		})
+	}
+	if !event.ClosedAt.IsZero() {
+		timeline = append(timeline, &EventTimelineEntry{
+			Time: event.ClosedAt,
+			Kind: timelineClosed,
+		})
+	}
+	for _, s := range []types.EventStatus{
+		types.EventOpen,
+		types.EventAcknowledged,
+		types.EventMitigated,
+		types.EventResolved,
+	} {
+		if t, ok := event.StatusTimes[s]; ok {
+			timeline = append(timeline, &EventTimelineEntry{
+				Time:   t,
+				Kind:   timelineStatusChanged,
+				Status: s,
+			})
+		}
+	}
+
+	for _, a := range alerts {
+		timeline = append(timeline, &EventTimelineEntry{
+			Time:  a.StartsAt,
+			Kind:  timelineAlertStarted,
+			Alert: a,
+		})
+		if a.Resolved() {
+			timeline = append(timeline, &EventTimelineEntry{
+				Time:  a.EndsAt,
+				Kind:  timelineAlertResolved,
+				Alert: a,
+			})
+		}
+
+		ack, err := api.acks.Get(a.Fingerprint())
+		if err == provider.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+		timeline = append(timeline, &EventTimelineEntry{
+			Time:   ack.CreatedAt,
+			Kind:   timelineAcknowledged,
+			Alert:  a,
+			Ack:    ack,
+			Author: ack.CreatedBy,
+		})
+	}
+	sort.Stable(timeline)
+
+	respond(w, timeline)
+}
+
+// ackEvent acknowledges all firing alerts of the event.
+func (api *API) ackEvent(w http.ResponseWriter, r *http.Request) {
+	var ack types.Ack
+	if err := receive(r, &ack); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	ack.CreatedAt = time.Now()
+
+	if err := ack.Validate(); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	event, ok := api.event(w, r)
+	if !ok {
+		return
+	}
+	alerts, err := api.eventAlerts(event)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	acks := []*types.Ack{}
+	for _, a := range alerts {
+		if a.Resolved() {
+			continue
+		}
+		ack := ack
+		ack.Alert = a.Fingerprint()
+
+		if err := api.acks.Set(&ack); err != nil {
+			respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+		acks = append(acks, &ack)
+	}
+	respond(w, acks)
+}
+
+// transitionEvent moves an event into another status of its lifecycle.
+func (api *API) transitionEvent(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Status  types.EventStatus `json:"status"`
+		Author  string            `json:"author,omitempty"`
+		Comment string            `json:"comment,omitempty"`
+	}
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := req.Status.Validate(); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	event, ok := api.event(w, r)
+	if !ok {
+		return
+	}
+	t := &EventTransition{
+		Event:   event,
+		From:    event.CurrentStatus(),
+		To:      req.Status,
+		Author:  req.Author,
+		Comment: req.Comment,
+		Time:    time.Now(),
+	}
+	if err := event.Transition(t.To, t.Time); err != nil {
+		respondError(w, apiError{
+			typ: errorConflict,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := api.events.Update(event); err != nil {
+		respondEventError(w, event.ID, err)
+		return
+	}
+
+	api.mtx.RLock()
+	hooks := api.eventHooks
+	api.mtx.RUnlock()
+
+	hooks.Notify(t)
+
+	respond(w, event)
+}
+
 func (api *API) listEventAlerts(w http.ResponseWriter, r *http.Request) {
 	eids := route.Param(api.context(r), "eid")
 	eid, err := strconv.ParseUint(eids, 10, 64)
@@ -42,6 +444,10 @@ func (api *API) listEventAlerts(w http.ResponseWriter, r *http.Request) {
 		}, nil)
 		return
 	}
+	if m := api.tenantMatcher(r); m != nil && !m.Match(event.Labels) {
+		respondEventError(w, eid, provider.ErrNotFound)
+		return
+	}
 
 	var alerts []*types.Alert
 	for _, ids := range event.Alerts {
@@ -65,6 +471,10 @@ func (api *API) listEventAlerts(w http.ResponseWriter, r *http.Request) {
 		alerts = append(alerts, a)
 	}
 
+	if wantsCSV(r) {
+		respondAlertsCSV(w, alerts)
+		return
+	}
 	respond(w, alerts)
 }
 
@@ -81,6 +491,24 @@ func (api *API) addEvent(w http.ResponseWriter, r *http.Request) {
 	if event.CreatedAt.IsZero() {
 		event.CreatedAt = time.Now()
 	}
+	api.scopeEvent(r, &event)
+	if event.Status == "" {
+		event.Status = types.EventOpen
+		event.StatusTimes = map[types.EventStatus]time.Time{
+			types.EventOpen: event.CreatedAt,
+		}
+	}
+	if err := event.Validate(); err != nil {
+		respondEventValidationError(w, err)
+		return
+	}
+	if err := api.checkEventParent(0, event.ParentID); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
 
 	sid, err := api.events.Set(&event)
 	if err != nil {
@@ -97,3 +525,529 @@ func (api *API) addEvent(w http.ResponseWriter, r *http.Request) {
 		EventID: sid,
 	})
 }
+
+func (api *API) updateEvent(w http.ResponseWriter, r *http.Request) {
+	if api.tenantMatcher(r) != nil {
+		if _, ok := api.event(w, r); !ok {
+			return
+		}
+	}
+	eids := route.Param(api.context(r), "eid")
+	eid, err := strconv.ParseUint(eids, 10, 64)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var event types.Event
+	if err := receive(r, &event); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	event.ID = eid
+	event.UpdatedAt = time.Now()
+	api.scopeEvent(r, &event)
+
+	if err := event.Validate(); err != nil {
+		respondEventValidationError(w, err)
+		return
+	}
+
+	if err := api.checkEventParent(eid, event.ParentID); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := api.events.Update(&event); err != nil {
+		respondEventError(w, eid, err)
+		return
+	}
+
+	respond(w, struct {
+		EventID uint64 `json:"eventId"`
+		Version uint64 `json:"version"`
+	}{
+		EventID: eid,
+		Version: event.Version,
+	})
+}
+
+func (api *API) delEvent(w http.ResponseWriter, r *http.Request) {
+	if api.tenantMatcher(r) != nil {
+		if _, ok := api.event(w, r); !ok {
+			return
+		}
+	}
+	eids := route.Param(api.context(r), "eid")
+	eid, err := strconv.ParseUint(eids, 10, 64)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	if err := api.events.Delete(eid); err != nil {
+		respondEventError(w, eid, err)
+		return
+	}
+
+	// Children of the deleted event become top-level events.
+	children, err := api.events.Children(eid)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	for _, c := range children {
+		c.ParentID = 0
+		c.UpdatedAt = time.Now()
+
+		if err := api.events.Update(c); err != nil {
+			respondEventError(w, c.ID, err)
+			return
+		}
+	}
+
+	if att, _, _ := api.attachmentsConf(); att != nil {
+		if err := att.DeleteAll(eid); err != nil {
+			respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+	}
+	respond(w, nil)
+}
+
+// checkEventParent returns an error if the parent of the event with the
+// given ID does not exist or if the event would become its own ancestor.
+// The ID is zero for events that do not exist yet.
+func (api *API) checkEventParent(id, parentID uint64) error {
+	seen := map[uint64]struct{}{}
+
+	for pid := parentID; pid != 0; {
+		if pid == id {
+			return fmt.Errorf("event %d cannot be a descendant of itself", id)
+		}
+		if _, ok := seen[pid]; ok {
+			return fmt.Errorf("cyclic parents of event %d", parentID)
+		}
+		seen[pid] = struct{}{}
+
+		p, err := api.events.Get(pid)
+		if err == provider.ErrNotFound {
+			return fmt.Errorf("parent event %d not found", pid)
+		}
+		if err != nil {
+			return err
+		}
+		pid = p.ParentID
+	}
+	return nil
+}
+
+func (api *API) listEventChildren(w http.ResponseWriter, r *http.Request) {
+	event, ok := api.event(w, r)
+	if !ok {
+		return
+	}
+	children, err := api.events.Children(event.ID)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if children == nil {
+		children = []*types.Event{}
+	}
+	respond(w, children)
+}
+
+// eventChild returns the parent and child event referenced by the
+// request's parameters or responds with an error.
+func (api *API) eventChild(w http.ResponseWriter, r *http.Request) (*types.Event, *types.Event, bool) {
+	parent, ok := api.event(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	cids := route.Param(api.context(r), "cid")
+	cid, err := strconv.ParseUint(cids, 10, 64)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return nil, nil, false
+	}
+
+	child, err := api.events.Get(cid)
+	if m := api.tenantMatcher(r); err == nil && m != nil && !m.Match(child.Labels) {
+		err = provider.ErrNotFound
+	}
+	if err != nil {
+		respondEventError(w, cid, err)
+		return nil, nil, false
+	}
+	child.ID = cid
+
+	return parent, child, true
+}
+
+// attachEventChild makes an event a child of another one.
+func (api *API) attachEventChild(w http.ResponseWriter, r *http.Request) {
+	parent, child, ok := api.eventChild(w, r)
+	if !ok {
+		return
+	}
+	if err := api.checkEventParent(child.ID, parent.ID); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	child.ParentID = parent.ID
+	child.UpdatedAt = time.Now()
+
+	if err := api.events.Update(child); err != nil {
+		respondEventError(w, child.ID, err)
+		return
+	}
+	respond(w, child)
+}
+
+// detachEventChild makes a child event a top-level event.
+func (api *API) detachEventChild(w http.ResponseWriter, r *http.Request) {
+	parent, child, ok := api.eventChild(w, r)
+	if !ok {
+		return
+	}
+	if child.ParentID != parent.ID {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("event %d is not a child of event %d", child.ID, parent.ID),
+		}, nil)
+		return
+	}
+	child.ParentID = 0
+	child.UpdatedAt = time.Now()
+
+	if err := api.events.Update(child); err != nil {
+		respondEventError(w, child.ID, err)
+		return
+	}
+	respond(w, child)
+}
+
+func respondEventError(w http.ResponseWriter, eid uint64, err error) {
+	switch err {
+	case provider.ErrNotFound:
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("event %d not found", eid),
+		}, nil)
+	case provider.ErrConflict:
+		respondError(w, apiError{
+			typ: errorConflict,
+			err: fmt.Errorf("event %d was modified concurrently", eid),
+		}, nil)
+	default:
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+	}
+}
+
+// respondEventValidationError responds with the error of validating an
+// event. The problems of its fields are returned as data.
+func respondEventValidationError(w http.ResponseWriter, err error) {
+	var data interface{}
+	if verr, ok := err.(types.ValidationError); ok {
+		data = verr
+	}
+	respondError(w, apiError{
+		typ: errorBadData,
+		err: err,
+	}, data)
+}
+
+// attachmentsConf returns the storage of event attachments, which is nil if
+// attachments are disabled, and the limits of uploads.
+func (api *API) attachmentsConf() (provider.EventAttachments, int64, map[string]struct{}) {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	return api.attachments, api.attachmentMaxSize, api.attachmentContentTypes
+}
+
+// eventAttachments returns the storage of event attachments and the event
+// referenced by the request's parameters or responds with an error.
+func (api *API) eventAttachments(w http.ResponseWriter, r *http.Request) (provider.EventAttachments, *types.Event, bool) {
+	att, _, _ := api.attachmentsConf()
+	if att == nil {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("event attachments not enabled"),
+		}, nil)
+		return nil, nil, false
+	}
+	event, ok := api.event(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	return att, event, true
+}
+
+// attachmentID returns the attachment ID of the request's parameters or
+// responds with an error.
+func (api *API) attachmentID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	aid, err := strconv.ParseUint(route.Param(api.context(r), "aid"), 10, 64)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return 0, false
+	}
+	return aid, true
+}
+
+func respondAttachmentError(w http.ResponseWriter, eid, aid uint64, err error) {
+	if err == provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("attachment %d of event %d not found", aid, eid),
+		}, nil)
+		return
+	}
+	respondError(w, apiError{
+		typ: errorInternal,
+		err: err,
+	}, nil)
+}
+
+func (api *API) listEventAttachments(w http.ResponseWriter, r *http.Request) {
+	att, event, ok := api.eventAttachments(w, r)
+	if !ok {
+		return
+	}
+	res, err := att.List(event.ID)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if res == nil {
+		res = []*types.Attachment{}
+	}
+	respond(w, res)
+}
+
+// addEventAttachment stores the file of the multipart form field "file".
+// The optional field "createdBy" names the uploader.
+func (api *API) addEventAttachment(w http.ResponseWriter, r *http.Request) {
+	att, event, ok := api.eventAttachments(w, r)
+	if !ok {
+		return
+	}
+	_, maxSize, contentTypes := api.attachmentsConf()
+
+	// Leave room for the other form fields and the multipart encoding.
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var (
+		a = &types.Attachment{
+			EventID:   event.ID,
+			CreatedAt: time.Now(),
+		}
+		content  []byte
+		declared string
+	)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+
+		switch p.FormName() {
+		case "createdBy":
+			b, err := ioutil.ReadAll(io.LimitReader(p, 256))
+			if err != nil {
+				respondError(w, apiError{
+					typ: errorBadData,
+					err: err,
+				}, nil)
+				return
+			}
+			a.CreatedBy = string(b)
+
+		case "file":
+			content, err = ioutil.ReadAll(io.LimitReader(p, maxSize+1))
+			if err != nil {
+				respondError(w, apiError{
+					typ: errorBadData,
+					err: err,
+				}, nil)
+				return
+			}
+			if int64(len(content)) > maxSize {
+				respondError(w, apiError{
+					typ: errorTooLarge,
+					err: fmt.Errorf("attachment larger than %d bytes", maxSize),
+				}, nil)
+				return
+			}
+			// Some clients send the full path of the file.
+			a.Filename = path.Base(strings.Replace(p.FileName(), "\\", "/", -1))
+			declared = p.Header.Get("Content-Type")
+		}
+	}
+	if content == nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("missing file"),
+		}, nil)
+		return
+	}
+	if a.Filename == "" || a.Filename == "." || a.Filename == "/" {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("missing file name"),
+		}, nil)
+		return
+	}
+
+	ct, err := attachmentContentType(declared, content, contentTypes)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	sum := sha256.Sum256(content)
+
+	a.ContentType = ct
+	a.Size = int64(len(content))
+	a.SHA256 = hex.EncodeToString(sum[:])
+
+	if _, err := att.Add(a, content); err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, a)
+}
+
+// attachmentContentType returns the media type of the uploaded content. It
+// fails if it is not one of the allowed types or if the content does not
+// match the declared type, which defaults to the detected one.
+func attachmentContentType(declared string, content []byte, allowed map[string]struct{}) (string, error) {
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(content))
+	if err != nil {
+		return "", err
+	}
+	ct := detected
+	if declared != "" && declared != "application/octet-stream" {
+		if ct, _, err = mime.ParseMediaType(declared); err != nil {
+			return "", fmt.Errorf("invalid content type %q: %s", declared, err)
+		}
+	}
+	if _, ok := allowed[ct]; !ok {
+		return "", fmt.Errorf("content type %q not allowed", ct)
+	}
+
+	// Text cannot be told apart by its content, while all other allowed
+	// types are expected to be detected as such.
+	if strings.HasPrefix(ct, "text/") {
+		if detected != "text/plain" {
+			return "", fmt.Errorf("content of type %q is not text", detected)
+		}
+	} else if detected != ct {
+		return "", fmt.Errorf("content of type %q does not match content type %q", detected, ct)
+	}
+	return ct, nil
+}
+
+func (api *API) getEventAttachment(w http.ResponseWriter, r *http.Request) {
+	att, event, ok := api.eventAttachments(w, r)
+	if !ok {
+		return
+	}
+	aid, ok := api.attachmentID(w, r)
+	if !ok {
+		return
+	}
+	a, content, err := att.Get(event.ID, aid)
+	if err != nil {
+		respondAttachmentError(w, event.ID, aid, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(int64(len(content)), 10))
+	// Browsers must neither render the content inline nor guess another
+	// content type.
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})
+	if disposition == "" {
+		// The file name cannot be encoded.
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	w.Write(content)
+}
+
+func (api *API) delEventAttachment(w http.ResponseWriter, r *http.Request) {
+	att, event, ok := api.eventAttachments(w, r)
+	if !ok {
+		return
+	}
+	aid, ok := api.attachmentID(w, r)
+	if !ok {
+		return
+	}
+	if err := att.Delete(event.ID, aid); err != nil {
+		respondAttachmentError(w, event.ID, aid, err)
+		return
+	}
+	respond(w, nil)
+}
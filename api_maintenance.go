@@ -0,0 +1,231 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// maintenanceWindows sorts by start time.
+type maintenanceWindows []*types.MaintenanceWindow
+
+func (mws maintenanceWindows) Len() int      { return len(mws) }
+func (mws maintenanceWindows) Swap(i, j int) { mws[i], mws[j] = mws[j], mws[i] }
+func (mws maintenanceWindows) Less(i, j int) bool {
+	if !mws[i].StartsAt.Equal(mws[j].StartsAt) {
+		return mws[i].StartsAt.Before(mws[j].StartsAt)
+	}
+	return mws[i].ID < mws[j].ID
+}
+
+// maintenanceStore returns the maintenance windows or responds with an
+// error if they are not stored.
+func (api *API) maintenanceStore(w http.ResponseWriter) (provider.MaintenanceWindows, bool) {
+	api.mtx.RLock()
+	m := api.maintenance
+	api.mtx.RUnlock()
+
+	if m == nil {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("maintenance windows not stored"),
+		}, nil)
+		return nil, false
+	}
+	return m, true
+}
+
+// maintenanceWindow returns the maintenance window of the request's id
+// parameter. Windows of other tenants are not found.
+func (api *API) maintenanceWindow(w http.ResponseWriter, r *http.Request) (*types.MaintenanceWindow, bool) {
+	m, ok := api.maintenanceStore(w)
+	if !ok {
+		return nil, false
+	}
+	id, err := strconv.ParseUint(route.Param(api.context(r), "id"), 10, 64)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return nil, false
+	}
+
+	mw, err := m.Get(id)
+	if tm := api.tenantMatcher(r); err == nil && tm != nil && !ownsMatchers(tm, mw.Matchers) {
+		err = provider.ErrNotFound
+	}
+	if err == provider.ErrNotFound {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("maintenance window %d not found", id),
+		}, nil)
+		return nil, false
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return nil, false
+	}
+	return mw, true
+}
+
+// receiveMaintenanceWindow decodes and validates the maintenance window in
+// the request body, which is restricted to the request's tenant.
+func (api *API) receiveMaintenanceWindow(w http.ResponseWriter, r *http.Request) (*types.MaintenanceWindow, bool) {
+	var mw types.MaintenanceWindow
+	if err := receive(r, &mw); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return nil, false
+	}
+	if tm := api.tenantMatcher(r); tm != nil {
+		ms, err := scopeMatchers(tm, mw.Matchers)
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorForbidden,
+				err: fmt.Errorf("maintenance window %s", err),
+			}, nil)
+			return nil, false
+		}
+		mw.Matchers = ms
+	}
+
+	err := mw.Validate()
+	if err == nil && mw.TicketURL != "" {
+		if u, perr := url.Parse(mw.TicketURL); perr != nil || !u.IsAbs() {
+			err = fmt.Errorf("ticket URL must be absolute")
+		}
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return nil, false
+	}
+	return &mw, true
+}
+
+func (api *API) listMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	m, ok := api.maintenanceStore(w)
+	if !ok {
+		return
+	}
+	mws, err := m.All()
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	res := make([]*types.MaintenanceWindow, 0, len(mws))
+	tm := api.tenantMatcher(r)
+	for _, mw := range mws {
+		if tm == nil || ownsMatchers(tm, mw.Matchers) {
+			res = append(res, mw)
+		}
+	}
+	sort.Sort(maintenanceWindows(res))
+
+	respond(w, res)
+}
+
+func (api *API) getMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	if mw, ok := api.maintenanceWindow(w, r); ok {
+		respond(w, mw)
+	}
+}
+
+func (api *API) addMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	m, ok := api.maintenanceStore(w)
+	if !ok {
+		return
+	}
+	mw, ok := api.receiveMaintenanceWindow(w, r)
+	if !ok {
+		return
+	}
+	mw.ID = 0
+	mw.CreatedAt = time.Now()
+
+	id, err := m.Set(mw)
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, struct {
+		MaintenanceWindowID uint64 `json:"maintenanceWindowId"`
+	}{
+		MaintenanceWindowID: id,
+	})
+}
+
+func (api *API) updateMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	old, ok := api.maintenanceWindow(w, r)
+	if !ok {
+		return
+	}
+	mw, ok := api.receiveMaintenanceWindow(w, r)
+	if !ok {
+		return
+	}
+	mw.ID = old.ID
+	mw.CreatedAt = old.CreatedAt
+
+	m, _ := api.maintenanceStore(w)
+	if _, err := m.Set(mw); err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, mw)
+}
+
+func (api *API) delMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	mw, ok := api.maintenanceWindow(w, r)
+	if !ok {
+		return
+	}
+	m, _ := api.maintenanceStore(w)
+	if err := m.Del(mw.ID); err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, nil)
+}
@@ -0,0 +1,111 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// overviewSnapshot is a snapshot of the alert groups along with the silences
+// muting their alerts, the open events, and the health of the receivers.
+type overviewSnapshot struct {
+	Time      time.Time                `json:"time"`
+	Groups    AlertOverview            `json:"groups"`
+	Silences  []*types.Silence         `json:"silences"`
+	Events    []*types.Event           `json:"events"`
+	Receivers []*notify.ReceiverStatus `json:"receivers"`
+}
+
+// overview responds with the alert groups, filtered as by alertGroups, the
+// active silences muting at least one of their alerts, the events that are
+// not resolved, and the receiver health. They are read while no alerts are
+// dispatched, so that the groups do not change in the meantime.
+func (api *API) overview(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseGroupsOptions(r.URL.Query())
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	tm := api.tenantMatcher(r)
+	if tm != nil {
+		opts.Matchers = append(opts.Matchers, tm)
+	}
+
+	res := &overviewSnapshot{
+		Time:     time.Now(),
+		Silences: []*types.Silence{},
+		Events:   []*types.Event{},
+	}
+	err = api.dispatcher().GroupsView(opts, func(groups AlertOverview) error {
+		res.Groups = groups
+
+		sils, err := api.silences.All()
+		if err != nil {
+			return err
+		}
+		for _, sil := range sils {
+			if tm != nil && !ownsSilence(tm, sil) {
+				continue
+			}
+			if sil.Recurrence != nil || res.Time.Before(sil.StartsAt) || res.Time.After(sil.EndsAt) {
+				continue
+			}
+			if mutesAny(sil, groups) {
+				res.Silences = append(res.Silences, sil)
+			}
+		}
+
+		events, err := api.events.All()
+		if err != nil {
+			return err
+		}
+		for _, e := range api.tenantEvents(r, events) {
+			if e.CurrentStatus() != types.EventResolved {
+				res.Events = append(res.Events, e)
+			}
+		}
+
+		res.Receivers = api.checker.Statuses()
+		return nil
+	})
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, res)
+}
+
+// mutesAny returns true iff the silence matches an alert of the groups.
+func mutesAny(sil *types.Silence, groups AlertOverview) bool {
+	for _, g := range groups {
+		for _, b := range g.Blocks {
+			for _, a := range b.Alerts {
+				if sil.Matches(a.Labels) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
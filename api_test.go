@@ -0,0 +1,1473 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/provider/boltmem"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestAPISuppressLate(t *testing.T) {
+	now := time.Now()
+
+	newAlert := func(name string, startsAt, endsAt time.Time) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": model.LabelValue(name)},
+				StartsAt: startsAt,
+				EndsAt:   endsAt,
+			},
+		}
+	}
+	var (
+		known    = newAlert("known", now.Add(-3*time.Hour), now.Add(-time.Hour))
+		unknown  = newAlert("unknown", now.Add(-3*time.Hour), now.Add(-time.Hour))
+		firing   = newAlert("firing", now.Add(-3*time.Hour), now.Add(time.Hour))
+		resolved = newAlert("recent", now.Add(-time.Minute), now.Add(-time.Second))
+	)
+
+	alerts := provider.NewMemAlerts(provider.NewMemData())
+	if err := alerts.Put(newAlert("known", now.Add(-3*time.Hour), now.Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	api := NewAPI(alerts, nil, nil, nil, nil, nil, nil, nil, "", nil)
+
+	cases := []struct {
+		threshold time.Duration
+		policy    config.LateAlertPolicy
+		alert     *types.Alert
+		suppress  bool
+	}{
+		{
+			threshold: 0,
+			policy:    config.LateAlertSuppressResolved,
+			alert:     unknown,
+			suppress:  false,
+		},
+		{
+			threshold: time.Hour,
+			policy:    config.LateAlertNotify,
+			alert:     unknown,
+			suppress:  false,
+		},
+		{
+			threshold: time.Hour,
+			policy:    config.LateAlertSuppressResolved,
+			alert:     unknown,
+			suppress:  true,
+		},
+		{
+			// Alerts received while firing are resolved as usual.
+			threshold: time.Hour,
+			policy:    config.LateAlertSuppressResolved,
+			alert:     known,
+			suppress:  false,
+		},
+		{
+			threshold: time.Hour,
+			policy:    config.LateAlertSuppressResolved,
+			alert:     firing,
+			suppress:  false,
+		},
+		{
+			threshold: time.Hour,
+			policy:    config.LateAlertSuppressResolved,
+			alert:     resolved,
+			suppress:  false,
+		},
+	}
+
+	for i, c := range cases {
+		api.SetLateAlerts(c.threshold, c.policy)
+
+		if have := api.suppressLate(c.alert, now); have != c.suppress {
+			t.Errorf("%d: expected suppress %v but got %v", i, c.suppress, have)
+		}
+	}
+}
+
+func TestAddAlertsSource(t *testing.T) {
+	alerts := provider.NewMemAlerts(provider.NewMemData())
+	api := NewAPI(alerts, nil, nil, nil, nil, nil, nil, nil, "", nil)
+
+	// A source sent with the alert is replaced.
+	body := `[{"labels": {"alertname": "A"}, "generatorURL": "http://prometheus.example.com:9090/graph", "source": {"client": "admin"}}]`
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	api.addAlerts(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d but got %d", http.StatusOK, w.Code)
+	}
+	a, err := alerts.Get(model.LabelSet{"alertname": "A"}.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &types.AlertSource{Address: "10.0.0.1", Domain: "prometheus.example.com"}
+	if !reflect.DeepEqual(a.Source, expected) {
+		t.Errorf("expected source %+v but got %+v", expected, a.Source)
+	}
+
+	// IPv6 hosts are recorded without brackets.
+	body = `[{"labels": {"alertname": "B"}, "generatorURL": "http://[fd00::1]/graph"}]`
+	r = httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.RemoteAddr = "10.0.0.1:1234"
+	api.addAlerts(httptest.NewRecorder(), r)
+
+	a, err = alerts.Get(model.LabelSet{"alertname": "B"}.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Source.Domain != "fd00::1" {
+		t.Errorf("expected domain %q but got %q", "fd00::1", a.Source.Domain)
+	}
+}
+
+func TestEventTimelineAndAck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	acks, err := boltmem.NewAcks(dir, types.NewMarker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer acks.Close()
+
+	var (
+		now    = time.Now()
+		alerts = provider.NewMemAlerts(provider.NewMemData())
+		events = provider.NewMemEvents()
+	)
+	var (
+		firing = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "firing"},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(time.Hour),
+			},
+		}
+		resolved = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "resolved"},
+				StartsAt: now.Add(-2 * time.Hour),
+				EndsAt:   now.Add(-30 * time.Minute),
+			},
+		}
+	)
+	if err := alerts.Put(firing, resolved); err != nil {
+		t.Fatal(err)
+	}
+	event := &types.Event{
+		Title:     "Outage",
+		Creator:   "user",
+		CreatedAt: now.Add(-50 * time.Minute),
+		Alerts: []string{
+			strconv.FormatUint(uint64(firing.Fingerprint()), 10),
+			strconv.FormatUint(uint64(resolved.Fingerprint()), 10),
+			// Alerts that no longer exist are skipped.
+			"1",
+		},
+	}
+	id, err := events.Set(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := NewAPI(alerts, nil, events, acks, nil, nil, nil, nil, "", nil)
+	api.context = func(r *http.Request) context.Context {
+		return route.WithParam(context.Background(), "eid", strconv.FormatUint(id, 10))
+	}
+
+	var res struct {
+		Data json.RawMessage `json:"data"`
+	}
+	call := func(h http.HandlerFunc, method, body string, v interface{}) {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(method, "/", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Unexpected status code %d: %s", w.Code, w.Body)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(res.Data, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var created []*types.Ack
+	call(api.ackEvent, "POST", `{"createdBy": "oncall", "comment": "on it"}`, &created)
+	if len(created) != 1 || created[0].Alert != firing.Fingerprint() {
+		t.Fatalf("expected only the firing alert to be acknowledged but got %v", created)
+	}
+
+	var timeline []*EventTimelineEntry
+	call(api.eventTimeline, "GET", "", &timeline)
+
+	expected := []string{
+		timelineAlertStarted,
+		timelineAlertStarted,
+		timelineCreated,
+		timelineAlertResolved,
+		timelineAcknowledged,
+	}
+	if len(timeline) != len(expected) {
+		t.Fatalf("expected %d timeline entries but got %d", len(expected), len(timeline))
+	}
+	for i, e := range timeline {
+		if e.Kind != expected[i] {
+			t.Errorf("%d: expected entry of kind %q but got %q", i, expected[i], e.Kind)
+		}
+	}
+	if timeline[4].Author != "oncall" {
+		t.Errorf("expected acknowledgement by %q but got %q", "oncall", timeline[4].Author)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected string
+		err      bool
+	}{
+		{in: `{}`, expected: `{}`},
+		{in: `{job="db",severity=~"crit.*"}`, expected: `{job="db", severity=~"crit.*"}`},
+		{in: ` { job = "a,b" , path=~"/\"x\"" } `, expected: `{job="a,b", path=~"/\"x\""}`},
+		{in: `job="db"`, err: true},
+		{in: `{job=db}`, err: true},
+		{in: `{job="db" severity="crit"}`, err: true},
+		{in: `{job="db}`, err: true},
+		{in: `{0job="db"}`, err: true},
+		{in: `{job=~"("}`, err: true},
+	}
+
+	for _, c := range cases {
+		ms, err := parseSelector(c.in)
+		if c.err {
+			if err == nil {
+				t.Errorf("expected error for %q but got none", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.in, err)
+			continue
+		}
+		if ms.String() != c.expected {
+			t.Errorf("expected %s for %q but got %s", c.expected, c.in, ms.String())
+		}
+	}
+}
+
+func TestBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	silences, err := boltmem.NewSilences(dir, types.NewMarker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silences.Close()
+
+	events, err := boltmem.NewEvents(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Close()
+
+	sil := types.NewSilence(&model.Silence{
+		Matchers:  []*model.Matcher{{Name: "job", Value: "db"}},
+		StartsAt:  time.Now(),
+		EndsAt:    time.Now().Add(time.Hour),
+		CreatedBy: "user",
+		Comment:   "maintenance",
+	})
+	sid, err := silences.Set(sil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := NewAPI(nil, nil, nil, nil, nil, nil, nil, nil, "", nil)
+
+	rec := httptest.NewRecorder()
+	api.backup(rec, httptest.NewRequest("GET", "/admin/backup", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d without databases but got %d", http.StatusNotFound, rec.Code)
+	}
+
+	api.SetBackups(map[string]provider.Backuper{
+		"silences.db": silences,
+		"events.db":   events,
+	})
+	rec = httptest.NewRecorder()
+	api.backup(rec, httptest.NewRequest("GET", "/admin/backup", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d but got %d", http.StatusOK, rec.Code)
+	}
+
+	restoreDir, err := ioutil.TempDir("", "api_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(restoreDir)
+
+	var names []string
+	tr := tar.NewReader(rec.Body)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(restoreDir, hdr.Name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp := []string{"events.db", "silences.db"}; !reflect.DeepEqual(names, exp) {
+		t.Fatalf("expected archived files %v but got %v", exp, names)
+	}
+
+	// The restored database contains the silence.
+	restored, err := boltmem.NewSilences(restoreDir, types.NewMarker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+
+	res, err := restored.Get(sid)
+	if err != nil {
+		t.Fatalf("retrieving restored silence failed: %s", err)
+	}
+	if res.Comment != "maintenance" {
+		t.Errorf("expected restored silence comment %q but got %q", "maintenance", res.Comment)
+	}
+}
+
+func TestEventChildren(t *testing.T) {
+	events := provider.NewMemEvents()
+
+	var ids []uint64
+	for _, title := range []string{"Major incident", "Database outage", "Network outage"} {
+		id, err := events.Set(&types.Event{Title: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	api := NewAPI(nil, nil, events, nil, nil, nil, nil, nil, "", nil)
+
+	call := func(h http.HandlerFunc, eid, cid uint64) *httptest.ResponseRecorder {
+		api.context = func(r *http.Request) context.Context {
+			ctx := route.WithParam(context.Background(), "eid", strconv.FormatUint(eid, 10))
+			return route.WithParam(ctx, "cid", strconv.FormatUint(cid, 10))
+		}
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest("GET", "/", nil))
+		return w
+	}
+	children := func(eid uint64) []*types.Event {
+		var res struct {
+			Data []*types.Event `json:"data"`
+		}
+		w := call(api.listEventChildren, eid, 0)
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		return res.Data
+	}
+
+	for _, cid := range ids[1:] {
+		if w := call(api.attachEventChild, ids[0], cid); w.Code != http.StatusOK {
+			t.Fatalf("Attaching child failed with status %d: %s", w.Code, w.Body)
+		}
+	}
+	if cs := children(ids[0]); len(cs) != 2 || cs[0].ID != ids[1] || cs[1].ID != ids[2] {
+		t.Fatalf("expected 2 children but got %v", cs)
+	}
+
+	// Events cannot become their own ancestors.
+	if w := call(api.attachEventChild, ids[1], ids[0]); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected attaching a parent to its child to fail but got status %d", w.Code)
+	}
+	if w := call(api.attachEventChild, ids[1], ids[1]); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected attaching an event to itself to fail but got status %d", w.Code)
+	}
+
+	if w := call(api.detachEventChild, ids[1], ids[2]); w.Code != http.StatusNotFound {
+		t.Fatalf("expected detaching a non-child to fail but got status %d", w.Code)
+	}
+	if w := call(api.detachEventChild, ids[0], ids[2]); w.Code != http.StatusOK {
+		t.Fatalf("Detaching child failed with status %d: %s", w.Code, w.Body)
+	}
+	if cs := children(ids[0]); len(cs) != 1 || cs[0].ID != ids[1] {
+		t.Fatalf("expected 1 child but got %v", cs)
+	}
+
+	// Children of deleted events become top-level events.
+	if w := call(api.delEvent, ids[0], 0); w.Code != http.StatusOK {
+		t.Fatalf("Deleting event failed with status %d: %s", w.Code, w.Body)
+	}
+	e, err := events.Get(ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ParentID != 0 {
+		t.Fatalf("expected orphaned child to be top-level but got parent %d", e.ParentID)
+	}
+}
+
+func TestEventAttachments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "attachments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	attachments, err := boltmem.NewAttachments(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer attachments.Close()
+
+	events := provider.NewMemEvents()
+	eid, err := events.Set(&types.Event{Title: "Database outage"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := NewAPI(nil, nil, events, nil, nil, nil, nil, nil, "", nil)
+	api.SetAttachments(attachments, 16, []string{"image/png", "text/plain"})
+
+	call := func(h http.HandlerFunc, aid uint64, req *http.Request) *httptest.ResponseRecorder {
+		api.context = func(r *http.Request) context.Context {
+			ctx := route.WithParam(context.Background(), "eid", strconv.FormatUint(eid, 10))
+			return route.WithParam(ctx, "aid", strconv.FormatUint(aid, 10))
+		}
+		w := httptest.NewRecorder()
+		h(w, req)
+		return w
+	}
+	upload := func(filename, contentType, content string) *httptest.ResponseRecorder {
+		var b bytes.Buffer
+		mw := multipart.NewWriter(&b)
+		mw.WriteField("createdBy", "alice")
+
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+		if contentType != "" {
+			h.Set("Content-Type", contentType)
+		}
+		pw, err := mw.CreatePart(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(pw, content)
+		mw.Close()
+
+		req := httptest.NewRequest("POST", "/", &b)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return call(api.addEventAttachment, 0, req)
+	}
+
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+	w := upload("screenshot.png", "image/png", png)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Uploading attachment failed with status %d: %s", w.Code, w.Body)
+	}
+	var res struct {
+		Data *types.Attachment `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	a := res.Data
+	if a.EventID != eid || a.Filename != "screenshot.png" || a.ContentType != "image/png" || a.Size != int64(len(png)) || a.CreatedBy != "alice" {
+		t.Fatalf("unexpected attachment %+v", a)
+	}
+
+	// Content types are detected if not declared.
+	if w := upload(`C:\Users\alice\notes.txt`, "", "postmortem"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"filename":"notes.txt"`) {
+		t.Fatalf("expected text file to be accepted but got status %d: %s", w.Code, w.Body)
+	}
+
+	for _, c := range []struct {
+		contentType, content string
+		code                 int
+	}{
+		// Disallowed content type.
+		{"application/pdf", "%PDF-1.4", http.StatusBadRequest},
+		// Content not matching the declared content type.
+		{"image/png", "<html>", http.StatusBadRequest},
+		{"text/plain", png, http.StatusBadRequest},
+		// Content exceeding the size limit.
+		{"text/plain", strings.Repeat("a", 17), http.StatusRequestEntityTooLarge},
+	} {
+		if w := upload("file", c.contentType, c.content); w.Code != c.code {
+			t.Errorf("expected upload of %q as %s to fail with status %d but got %d", c.content, c.contentType, c.code, w.Code)
+		}
+	}
+
+	w = call(api.getEventAttachment, a.ID, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != png {
+		t.Fatalf("expected attachment content but got status %d: %q", w.Code, w.Body)
+	}
+	if ct, cd := w.Header().Get("Content-Type"), w.Header().Get("Content-Disposition"); ct != "image/png" || cd != `attachment; filename=screenshot.png` {
+		t.Fatalf("unexpected headers %q and %q", ct, cd)
+	}
+
+	if w := call(api.delEventAttachment, a.ID, httptest.NewRequest("DELETE", "/", nil)); w.Code != http.StatusOK {
+		t.Fatalf("Deleting attachment failed with status %d: %s", w.Code, w.Body)
+	}
+	if w := call(api.getEventAttachment, a.ID, httptest.NewRequest("GET", "/", nil)); w.Code != http.StatusNotFound {
+		t.Fatalf("expected deleted attachment not to be found but got status %d", w.Code)
+	}
+
+	// Attachments are removed along with their event.
+	if w := call(api.delEvent, 0, httptest.NewRequest("DELETE", "/", nil)); w.Code != http.StatusOK {
+		t.Fatalf("Deleting event failed with status %d: %s", w.Code, w.Body)
+	}
+	if res, err := attachments.List(eid); err != nil || len(res) != 0 {
+		t.Fatalf("expected attachments of deleted event to be removed but got %v, %v", res, err)
+	}
+}
+
+func TestTransitionEvent(t *testing.T) {
+	received := make(chan *EventTransition, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var et EventTransition
+		if err := json.NewDecoder(r.Body).Decode(&et); err != nil {
+			t.Errorf("Decoding hook request failed: %s", err)
+		}
+		received <- &et
+	}))
+	defer srv.Close()
+
+	events := provider.NewMemEvents()
+	id, err := events.Set(&types.Event{Title: "Database outage"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hooks := NewEventHooks()
+	hooks.SetConfig([]*config.EventHook{{
+		URL:      srv.URL,
+		Statuses: []string{"mitigated"},
+		Timeout:  model.Duration(time.Second),
+	}})
+
+	api := NewAPI(nil, nil, events, nil, nil, nil, nil, nil, "", nil)
+	api.SetEventHooks(hooks)
+	api.context = func(r *http.Request) context.Context {
+		return route.WithParam(context.Background(), "eid", strconv.FormatUint(id, 10))
+	}
+
+	transition := func(body string) int {
+		w := httptest.NewRecorder()
+		api.transitionEvent(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return w.Code
+	}
+
+	if code := transition(`{"status": "acknowledged", "author": "oncall"}`); code != http.StatusOK {
+		t.Fatalf("expected acknowledging to succeed but got status %d", code)
+	}
+	if code := transition(`{"status": "open"}`); code != http.StatusConflict {
+		t.Fatalf("expected invalid transition to conflict but got status %d", code)
+	}
+	if code := transition(`{"status": "unknown"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected unknown status to be rejected but got status %d", code)
+	}
+	if code := transition(`{"status": "mitigated", "author": "oncall", "comment": "failed over"}`); code != http.StatusOK {
+		t.Fatalf("expected mitigating to succeed but got status %d", code)
+	}
+
+	select {
+	case et := <-received:
+		if et.From != types.EventAcknowledged || et.To != types.EventMitigated || et.Comment != "failed over" {
+			t.Fatalf("unexpected transition sent to hook: %+v", et)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected transition to be sent to hook")
+	}
+	// Only transitions into the hook's statuses are sent.
+	select {
+	case et := <-received:
+		t.Fatalf("unexpected transition sent to hook: %+v", et)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	e, err := events.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Status != types.EventMitigated || len(e.StatusTimes) != 2 {
+		t.Fatalf("expected mitigated event with 2 status times but got %q, %v", e.Status, e.StatusTimes)
+	}
+}
+
+func TestAddEventValidation(t *testing.T) {
+	events := provider.NewMemEvents()
+	api := NewAPI(nil, nil, events, nil, nil, nil, nil, nil, "", nil)
+
+	add := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		api.addEvent(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return w
+	}
+
+	w := add(`{"title": "", "severity": "fatal", "alerts": ["1", ""]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected malformed event to be rejected but got status %d", w.Code)
+	}
+	var res struct {
+		Data []types.FieldError `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	fields := []string{}
+	for _, fe := range res.Data {
+		fields = append(fields, fe.Field)
+	}
+	if expected := []string{"title", "severity", "alerts[1]"}; !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected errors of fields %q but got %q", expected, fields)
+	}
+
+	w = add(`{"title": "Database outage", "severity": "critical", "assignee": "oncall", "labels": {"team": "db"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected valid event to be added but got status %d: %s", w.Code, w.Body)
+	}
+	all, err := events.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].Severity != types.EventCritical || all[0].Assignee != "oncall" {
+		t.Errorf("unexpected events %+v", all)
+	}
+}
+
+func TestListEventsTimeRange(t *testing.T) {
+	var (
+		events = provider.NewMemEvents()
+		now    = time.Now().UTC().Truncate(time.Second)
+	)
+	for i := 0; i < 3; i++ {
+		e := &types.Event{Title: fmt.Sprintf("Outage %d", i), CreatedAt: now.Add(time.Duration(i) * time.Hour)}
+		if _, err := events.Set(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	api := NewAPI(nil, nil, events, nil, nil, nil, nil, nil, "", nil)
+
+	list := func(query string) (int, []string) {
+		w := httptest.NewRecorder()
+		api.listEvents(w, httptest.NewRequest("GET", "/?"+query, nil))
+
+		var res struct {
+			Data []*types.Event `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		titles := []string{}
+		for _, e := range res.Data {
+			titles = append(titles, e.Title)
+		}
+		return w.Code, titles
+	}
+
+	q := url.Values{
+		"from":  {now.Add(time.Hour).Format(time.RFC3339)},
+		"limit": {"1"},
+	}
+	if code, titles := list(q.Encode()); code != http.StatusOK || !reflect.DeepEqual(titles, []string{"Outage 1"}) {
+		t.Errorf("expected event %q but got status %d and events %q", "Outage 1", code, titles)
+	}
+	if code, _ := list("to=yesterday"); code != http.StatusBadRequest {
+		t.Errorf("expected invalid time to be rejected but got status %d", code)
+	}
+}
+
+func TestListEventsStatus(t *testing.T) {
+	var (
+		events = provider.NewMemEvents()
+		now    = time.Now()
+	)
+	for i, s := range []types.EventStatus{types.EventOpen, types.EventAcknowledged, types.EventResolved} {
+		e := &types.Event{Title: fmt.Sprintf("Outage %d", i), CreatedAt: now.Add(time.Duration(i) * time.Second)}
+		if s != types.EventOpen {
+			if err := e.Transition(s, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := events.Set(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	api := NewAPI(nil, nil, events, nil, nil, nil, nil, nil, "", nil)
+
+	w := httptest.NewRecorder()
+	api.listEvents(w, httptest.NewRequest("GET", "/?status=open&status=resolved", nil))
+
+	var res struct {
+		Data []*types.Event `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	titles := map[string]bool{}
+	for _, e := range res.Data {
+		titles[e.Title] = true
+	}
+	if expected := map[string]bool{"Outage 0": true, "Outage 2": true}; !reflect.DeepEqual(titles, expected) {
+		t.Errorf("expected events %v but got %v", expected, titles)
+	}
+
+	w = httptest.NewRecorder()
+	api.listEvents(w, httptest.NewRequest("GET", "/?status=closed", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected unknown status to be rejected but got status %d", w.Code)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	api := NewAPI(nil, nil, nil, nil, nil, nil, nil, nil, "", nil)
+	api.SetReceivers([]*config.Receiver{
+		{
+			Name: "team-x",
+			SlackConfigs: []*config.SlackConfig{
+				{Title: `{{ template "custom.title" . }}`},
+			},
+		},
+		{Name: "team-y"},
+	}, tmpl)
+
+	render := func(body string) (int, []byte) {
+		w := httptest.NewRecorder()
+		api.renderTemplate(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return w.Code, w.Body.Bytes()
+	}
+
+	code, b := render(`{
+		"template": "{{ define \"custom.title\" }}[{{ .Status }}] {{ .GroupLabels.alertname }}{{ end }}{{ template \"custom.title\" . }} for {{ .Receiver }}",
+		"alerts": [{"labels": {"alertname": "HighLatency"}}],
+		"groupLabels": {"alertname": "HighLatency"}
+	}`)
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status code %d: %s", code, b)
+	}
+	var res struct {
+		Data struct {
+			Receivers []struct {
+				Receiver     string              `json:"receiver"`
+				Text         string              `json:"text"`
+				Error        string              `json:"error"`
+				Integrations []*notify.Rendering `json:"integrations"`
+			} `json:"receivers"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	rcvs := res.Data.Receivers
+	if len(rcvs) != 2 || rcvs[0].Receiver != "team-x" || rcvs[1].Receiver != "team-y" {
+		t.Fatalf("expected renderings for team-x and team-y but got %+v", rcvs)
+	}
+	if exp := "[firing] HighLatency for team-x"; rcvs[0].Text != exp {
+		t.Errorf("expected text %q but got %q (%s)", exp, rcvs[0].Text, rcvs[0].Error)
+	}
+	if len(rcvs[0].Integrations) != 1 {
+		t.Fatalf("expected one integration but got %d", len(rcvs[0].Integrations))
+	}
+	if exp, got := "[firing] HighLatency", rcvs[0].Integrations[0].Fields["title"]; got != exp {
+		t.Errorf("expected title %q but got %q", exp, got)
+	}
+
+	for _, body := range []string{
+		`{"template": "{{ .Foo", "alerts": [{"labels": {"alertname": "HighLatency"}}]}`,
+		`{"template": "{{ .Status }}"}`,
+		`{"template": "{{ .Status }}", "alerts": [{"labels": {}}]}`,
+		`{"template": "{{ .Status }}", "alerts": [{"labels": {"alertname": "HighLatency"}}], "receiver": "unknown"}`,
+	} {
+		if code, b := render(body); code != http.StatusBadRequest {
+			t.Errorf("expected status code %d for %s but got %d: %s", http.StatusBadRequest, body, code, b)
+		}
+	}
+}
+
+func TestSilenceAlertGroup(t *testing.T) {
+	r := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "team-X",
+			GroupBy:        map[model.LabelName]struct{}{"alertname": struct{}{}, "service": struct{}{}},
+			GroupWait:      time.Hour,
+			GroupInterval:  time.Hour,
+			RepeatInterval: time.Hour,
+		},
+	}
+	d := NewDispatcher(nil, r, nil, nil)
+	defer d.cancel()
+
+	d.processAlert(&types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "HighLatency", "service": "db", "instance": "db-1"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}, r)
+	fp := model.LabelSet{"alertname": "HighLatency", "service": "db"}.Fingerprint()
+
+	silences := provider.NewMemSilences()
+	api := NewAPI(nil, silences, nil, nil, nil, nil, nil, nil, "", func() *Dispatcher {
+		return d
+	})
+
+	silence := func(fp model.Fingerprint, body string) (int, uint64) {
+		api.context = func(r *http.Request) context.Context {
+			return route.WithParam(context.Background(), "fp", fp.String())
+		}
+		w := httptest.NewRecorder()
+		api.silenceAlertGroup(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+
+		var res struct {
+			Data struct {
+				SilenceID uint64 `json:"silenceId"`
+			} `json:"data"`
+		}
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, res.Data.SilenceID
+	}
+
+	if code, _ := silence(fp, `{"duration": "-1h"}`); code != http.StatusBadRequest {
+		t.Errorf("expected negative duration to be rejected but got status %d", code)
+	}
+	if code, _ := silence(model.Fingerprint(1), `{"duration": "2h"}`); code != http.StatusNotFound {
+		t.Errorf("expected unknown group to be not found but got status %d", code)
+	}
+
+	code, sid := silence(fp, `{"duration": "2h", "createdBy": "jane", "comment": "Maintenance"}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected status %d but got %d", http.StatusOK, code)
+	}
+	sil, err := silences.Get(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*model.Matcher{
+		{Name: "alertname", Value: "HighLatency"},
+		{Name: "service", Value: "db"},
+	}
+	if !reflect.DeepEqual(sil.Silence.Matchers, expected) {
+		t.Errorf("expected matchers %v but got %v", expected, sil.Silence.Matchers)
+	}
+	if d := sil.EndsAt.Sub(sil.StartsAt); d != 2*time.Hour || sil.CreatedBy != "jane" {
+		t.Errorf("expected 2h silence created by jane but got %s by %q", d, sil.CreatedBy)
+	}
+}
+
+func TestMaintenanceWindowsAPI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := boltmem.NewMaintenanceWindows(dir, types.NewMarker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	api := NewAPI(nil, nil, nil, nil, nil, nil, nil, nil, "", nil)
+	api.SetMaintenance(m)
+
+	call := func(h http.HandlerFunc, id uint64, body string) *httptest.ResponseRecorder {
+		api.context = func(r *http.Request) context.Context {
+			return route.WithParam(context.Background(), "id", strconv.FormatUint(id, 10))
+		}
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return w
+	}
+
+	if w := call(api.addMaintenanceWindow, 0, `{
+		"matchers": [{"name": "service", "value": "db"}],
+		"startsAt": "2016-10-01T14:00:00Z",
+		"endsAt": "2016-10-01T12:00:00Z",
+		"owner": "jane"
+	}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected window ending before its start to be rejected but got status %d", w.Code)
+	}
+	if w := call(api.addMaintenanceWindow, 0, `{
+		"matchers": [{"name": "service", "value": "db"}],
+		"startsAt": "2016-10-01T12:00:00Z",
+		"endsAt": "2016-10-01T14:00:00Z",
+		"owner": "jane",
+		"ticketURL": "CHG-1"
+	}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected relative ticket URL to be rejected but got status %d", w.Code)
+	}
+
+	w := call(api.addMaintenanceWindow, 0, `{
+		"matchers": [{"name": "service", "value": "db"}],
+		"startsAt": "2016-10-01T12:00:00Z",
+		"endsAt": "2016-10-01T14:00:00Z",
+		"owner": "jane",
+		"ticketURL": "https://tickets.example.com/CHG-1"
+	}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d but got %d", http.StatusOK, w.Code)
+	}
+	var res struct {
+		Data struct {
+			ID uint64 `json:"maintenanceWindowId"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	id := res.Data.ID
+
+	if w := call(api.updateMaintenanceWindow, id, `{
+		"matchers": [{"name": "service", "value": "db"}],
+		"startsAt": "2016-10-01T12:00:00Z",
+		"endsAt": "2016-10-01T16:00:00Z",
+		"owner": "jane"
+	}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d but got %d", http.StatusOK, w.Code)
+	}
+	mw, err := m.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mw.EndsAt.Hour() != 16 || mw.CreatedAt.IsZero() || mw.TicketURL != "" {
+		t.Errorf("expected updated maintenance window but got %+v", mw)
+	}
+	if w := call(api.updateMaintenanceWindow, id+1, `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected update of unknown window to fail with status %d but got %d", http.StatusNotFound, w.Code)
+	}
+
+	if w := call(api.delMaintenanceWindow, id, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d but got %d", http.StatusOK, w.Code)
+	}
+	if w := call(api.getMaintenanceWindow, id, ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected deleted window not to be found but got status %d", w.Code)
+	}
+}
+
+func TestDependenciesAPI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d, err := boltmem.NewDependencies(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	api := NewAPI(nil, nil, nil, nil, nil, nil, nil, nil, "", nil)
+	api.SetDependencies(d)
+
+	ih := NewInhibitor(nil, nil, types.NewMarker())
+	ih.SetDependencies(d, "service")
+	api.SetInhibitor(ih)
+
+	call := func(h http.HandlerFunc, id uint64, body string) *httptest.ResponseRecorder {
+		api.context = func(r *http.Request) context.Context {
+			return route.WithParam(context.Background(), "id", strconv.FormatUint(id, 10))
+		}
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return w
+	}
+
+	for _, body := range []string{
+		`{"service": "app", "dependsOn": "db", "createdBy": "jane"}`,
+		`{"service": "db", "dependsOn": "storage", "createdBy": "jane"}`,
+	} {
+		if w := call(api.addDependency, 0, body); w.Code != http.StatusOK {
+			t.Fatalf("expected status %d but got %d", http.StatusOK, w.Code)
+		}
+	}
+	// The inhibitor loads the added dependencies.
+	if len(ih.dbyService["app"]) != 1 || len(ih.dbyService["db"]) != 1 {
+		t.Fatalf("expected inhibitor to load the added dependencies but got %v", ih.dbyService)
+	}
+
+	for _, body := range []string{
+		// Incomplete dependencies.
+		`{"service": "app", "createdBy": "jane"}`,
+		`{"service": "app", "dependsOn": "app", "createdBy": "jane"}`,
+		`{"service": "app", "dependsOn": "db", "equal": ["0cluster"], "createdBy": "jane"}`,
+		// Duplicate dependencies.
+		`{"service": "app", "dependsOn": "db", "createdBy": "john"}`,
+		// Dependencies creating a cycle.
+		`{"service": "storage", "dependsOn": "app", "createdBy": "jane"}`,
+	} {
+		if w := call(api.addDependency, 0, body); w.Code != http.StatusBadRequest {
+			t.Errorf("expected dependency %s to be rejected but got status %d", body, w.Code)
+		}
+	}
+
+	deps, err := d.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps) != 2 || deps[0].CreatedAt.IsZero() {
+		t.Fatalf("expected 2 dependencies but got %v", deps)
+	}
+
+	if w := call(api.delDependency, deps[0].ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d but got %d", http.StatusOK, w.Code)
+	}
+	if w := call(api.getDependency, deps[0].ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected deleted dependency not to be found but got status %d", w.Code)
+	}
+	if len(ih.dbyService["app"]) != 0 {
+		t.Errorf("expected inhibitor to drop the deleted dependency but got %v", ih.dbyService)
+	}
+	// Without the dependency of app on db, storage may depend on app.
+	if w := call(api.addDependency, 0, `{"service": "storage", "dependsOn": "app", "createdBy": "jane"}`); w.Code != http.StatusOK {
+		t.Errorf("expected status %d but got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestOverview(t *testing.T) {
+	r := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "team-X",
+			GroupBy:        map[model.LabelName]struct{}{"alertname": struct{}{}},
+			GroupWait:      time.Hour,
+			GroupInterval:  time.Hour,
+			RepeatInterval: time.Hour,
+		},
+	}
+	d := NewDispatcher(nil, r, nil, types.NewMarker())
+	defer d.cancel()
+
+	now := time.Now()
+	d.processAlert(&types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "HighLatency", "service": "db"},
+			StartsAt: now,
+			EndsAt:   now.Add(time.Hour),
+		},
+	}, r)
+
+	var (
+		silences = provider.NewMemSilences()
+		events   = provider.NewMemEvents()
+	)
+	for _, sil := range []*types.Silence{
+		// Muting the alert.
+		types.NewSilence(&model.Silence{
+			Matchers: []*model.Matcher{{Name: "service", Value: "db"}},
+			StartsAt: now.Add(-time.Hour),
+			EndsAt:   now.Add(time.Hour),
+		}),
+		// Matching no alert.
+		types.NewSilence(&model.Silence{
+			Matchers: []*model.Matcher{{Name: "service", Value: "api"}},
+			StartsAt: now.Add(-time.Hour),
+			EndsAt:   now.Add(time.Hour),
+		}),
+		// Expired.
+		types.NewSilence(&model.Silence{
+			Matchers: []*model.Matcher{{Name: "service", Value: "db"}},
+			StartsAt: now.Add(-2 * time.Hour),
+			EndsAt:   now.Add(-time.Hour),
+		}),
+	} {
+		if _, err := silences.Set(sil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, e := range []*types.Event{
+		{Title: "Database outage", Status: types.EventMitigated},
+		{Title: "Network outage", Status: types.EventResolved},
+	} {
+		if _, err := events.Set(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	api := NewAPI(nil, silences, events, nil, nil, notify.NewChecker(time.Second), nil, nil, "", func() *Dispatcher {
+		return d
+	})
+
+	w := httptest.NewRecorder()
+	api.overview(w, httptest.NewRequest("GET", "/?receiver=team-X", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	var res struct {
+		Data struct {
+			Groups []struct {
+				Labels model.LabelSet    `json:"labels"`
+				Blocks []json.RawMessage `json:"blocks"`
+			} `json:"groups"`
+			Silences []*types.Silence `json:"silences"`
+			Events   []*types.Event   `json:"events"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Data.Groups) != 1 || len(res.Data.Groups[0].Blocks) != 1 {
+		t.Fatalf("expected one alert group but got %v", res.Data.Groups)
+	}
+	if len(res.Data.Silences) != 1 || res.Data.Silences[0].EndsAt.Before(now) || res.Data.Silences[0].Silence.Matchers[0].Value != "db" {
+		t.Errorf("expected only the silence muting the alert but got %v", res.Data.Silences)
+	}
+	if len(res.Data.Events) != 1 || res.Data.Events[0].Title != "Database outage" {
+		t.Errorf("expected only the unresolved event but got %v", res.Data.Events)
+	}
+}
+
+func TestAPITenantScoping(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	newAlert := func(name, tenant model.LabelValue) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": name, "tenant": tenant},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(time.Hour),
+			},
+			UpdatedAt: now,
+		}
+	}
+	var (
+		alertA = newAlert("A", "a")
+		alertB = newAlert("B", "b")
+		mixedA = newAlert("Mixed", "a")
+		mixedB = newAlert("Mixed", "b")
+	)
+
+	alerts := provider.NewMemAlerts(provider.NewMemData())
+	if err := alerts.Put(alertA, alertB, mixedA, mixedB); err != nil {
+		t.Fatal(err)
+	}
+	acks, err := boltmem.NewAcks(dir, types.NewMarker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer acks.Close()
+	for _, a := range []*types.Alert{alertA, alertB} {
+		if err := acks.Set(&types.Ack{Alert: a.Fingerprint(), CreatedBy: "user", CreatedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history, err := boltmem.NewAlertHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	deadLetters := provider.NewMemDeadLetters(10)
+	for _, a := range []*types.Alert{alertA, alertB} {
+		if err := history.Add(&types.AlertHistoryEntry{Alert: a.Fingerprint(), Time: now, State: types.AlertCreated, Labels: a.Labels}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := deadLetters.Add(&types.DeadLetter{Receiver: "team", Alerts: []*types.Alert{a}, Time: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "team",
+			GroupBy:        map[model.LabelName]struct{}{"alertname": struct{}{}},
+			GroupWait:      time.Hour,
+			GroupInterval:  time.Hour,
+			RepeatInterval: time.Hour,
+		},
+	}
+	d := NewDispatcher(alerts, r, nil, types.NewMarker())
+	groups := map[model.LabelValue]*aggrGroup{}
+	for _, a := range []*types.Alert{alertA, alertB, mixedA, mixedB} {
+		name := a.Labels["alertname"]
+		ag, ok := groups[name]
+		if !ok {
+			ag = newAggrGroup(context.Background(), model.LabelSet{"alertname": name}, r)
+			groups[name] = ag
+			d.aggrGroups.set(r, ag)
+		}
+		ag.insert(a)
+	}
+
+	tenants := NewTenants(nil)
+	tenants.SetConfig(&config.Tenancy{Label: "tenant", Header: "X-Tenant"})
+
+	api := NewAPI(alerts, provider.NewMemSilences(), provider.NewMemEvents(), acks, nil, nil, deadLetters, nil, "", func() *Dispatcher { return d })
+	api.SetTenants(tenants)
+	api.SetNotifies(provider.NewMemNotifies(provider.NewMemData()))
+	api.SetAlertHistory(history)
+	api.SetBackups(map[string]provider.Backuper{"alert_history.db": history})
+	api.SetAuditor(NewAuditor(&testAudit{}, "", nil))
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.SetReceivers([]*config.Receiver{{Name: "team"}}, tmpl)
+
+	var (
+		groupA = groups["A"].fingerprint().String()
+		groupB = groups["B"].fingerprint().String()
+		mixed  = groups["Mixed"].fingerprint().String()
+		until  = fmt.Sprintf(`{"until": %q}`, now.Add(time.Hour).Format(time.RFC3339))
+	)
+	// Requests are made for tenant b. Responses must not disclose alerts
+	// of tenant a and requests for them must not change anything.
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		url     string
+		param   string
+		value   string
+		body    string
+		status  int
+		absent  string
+		present string
+	}{
+		{
+			name:    "alerts metrics",
+			handler: api.alertsMetrics,
+			status:  http.StatusOK,
+			absent:  `alertname="A"`,
+			present: `alertname="B"`,
+		},
+		{
+			name:    "alert history of other tenant",
+			handler: api.alertHistoryTimeline,
+			param:   "fp",
+			value:   alertA.Fingerprint().String(),
+			status:  http.StatusNotFound,
+		},
+		{
+			name:    "alert history",
+			handler: api.alertHistoryTimeline,
+			param:   "fp",
+			value:   alertB.Fingerprint().String(),
+			status:  http.StatusOK,
+		},
+		{
+			name:    "schedule",
+			handler: api.schedule,
+			status:  http.StatusOK,
+			absent:  `"alertname":"A"`,
+			present: `"alertname":"B"`,
+		},
+		{
+			name:    "render group of other tenant",
+			handler: api.renderAlertGroup,
+			param:   "fp",
+			value:   groupA,
+			status:  http.StatusNotFound,
+		},
+		{
+			name:    "render template of group of other tenant",
+			handler: api.renderTemplate,
+			method:  "POST",
+			body:    fmt.Sprintf(`{"template": "{{ define \"x\" }}{{ end }}", "groupKey": %q}`, groups["A"].groupKey()),
+			status:  http.StatusNotFound,
+		},
+		{
+			name:    "pause group of other tenant",
+			handler: api.pauseAlertGroup,
+			method:  "POST",
+			param:   "fp",
+			value:   groupA,
+			body:    until,
+			status:  http.StatusNotFound,
+		},
+		{
+			name:    "pause group with alerts of other tenant",
+			handler: api.pauseAlertGroup,
+			method:  "POST",
+			param:   "fp",
+			value:   mixed,
+			body:    until,
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "pause group",
+			handler: api.pauseAlertGroup,
+			method:  "POST",
+			param:   "fp",
+			value:   groupB,
+			body:    until,
+			status:  http.StatusOK,
+		},
+		{
+			name:    "resume group of other tenant",
+			handler: api.resumeAlertGroup,
+			method:  "DELETE",
+			param:   "fp",
+			value:   groupA,
+			status:  http.StatusNotFound,
+		},
+		{
+			name:    "reassign group of other tenant",
+			handler: api.reassignAlertGroup,
+			method:  "POST",
+			url:     "/?receiver=team",
+			param:   "fp",
+			value:   groupA,
+			status:  http.StatusNotFound,
+		},
+		{
+			name:    "dead letters",
+			handler: api.listDeadLetters,
+			status:  http.StatusOK,
+			absent:  `"alertname":"A"`,
+			present: `"alertname":"B"`,
+		},
+		{
+			name:    "dead letter of other tenant",
+			handler: api.getDeadLetter,
+			param:   "id",
+			value:   "1",
+			status:  http.StatusNotFound,
+		},
+		{
+			name:    "delete dead letter of other tenant",
+			handler: api.delDeadLetter,
+			method:  "DELETE",
+			param:   "id",
+			value:   "1",
+			status:  http.StatusNotFound,
+		},
+		{
+			name:    "backup",
+			handler: api.backup,
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "audit log",
+			handler: api.auditLog,
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "export state",
+			handler: api.exportState,
+			status:  http.StatusOK,
+			absent:  `"alertname":"A"`,
+			present: `"alertname":"B"`,
+		},
+	}
+
+	for _, c := range cases {
+		api.context = func(r *http.Request) context.Context {
+			return route.WithParam(context.Background(), c.param, c.value)
+		}
+		method, url := c.method, c.url
+		if method == "" {
+			method = "GET"
+		}
+		if url == "" {
+			url = "/"
+		}
+		req := httptest.NewRequest(method, url, strings.NewReader(c.body))
+		req.Header.Set("X-Tenant", "b")
+
+		w := httptest.NewRecorder()
+		c.handler(w, req)
+
+		if w.Code != c.status {
+			t.Errorf("%s: expected status %d but got %d: %s", c.name, c.status, w.Code, w.Body)
+			continue
+		}
+		if c.absent != "" && strings.Contains(w.Body.String(), c.absent) {
+			t.Errorf("%s: expected response without %s but got %s", c.name, c.absent, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), c.present) {
+			t.Errorf("%s: expected response with %s but got %s", c.name, c.present, w.Body)
+		}
+	}
+
+	if _, paused := groups["A"].pausedUntil(now); paused {
+		t.Errorf("expected group of other tenant not to be paused")
+	}
+	if groups["A"].reassignedTo != "" {
+		t.Errorf("expected group of other tenant not to be reassigned")
+	}
+	if _, err := deadLetters.Get(1); err != nil {
+		t.Errorf("expected dead letter of other tenant to be kept but got %v", err)
+	}
+}
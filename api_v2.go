@@ -0,0 +1,224 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+const (
+	// alertsSchemaVersion is the version of the alert payload accepted at
+	// /api/v2/alerts.
+	alertsSchemaVersion = 2
+
+	// Limits of the labels of alerts received at /api/v2/alerts. The size
+	// of a label is the length of its name and value in bytes.
+	maxAlertLabels    = 64
+	maxAlertLabelSize = 1024
+)
+
+// alertsV2 is the payload received at /api/v2/alerts. Labels and times are
+// decoded as plain strings so that each alert is validated on its own.
+type alertsV2 struct {
+	Version int        `json:"version"`
+	Alerts  []*alertV2 `json:"alerts"`
+}
+
+type alertV2 struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     string            `json:"startsAt"`
+	EndsAt       string            `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+}
+
+// rejectedAlert lists the problems of an alert that was not accepted. The
+// index is the alert's position in the request.
+type rejectedAlert struct {
+	Index  int                `json:"index"`
+	Errors []types.FieldError `json:"errors"`
+}
+
+// alertsV2Result reports the outcome of receiving alerts at /api/v2/alerts.
+type alertsV2Result struct {
+	Accepted int              `json:"accepted"`
+	Rejected []*rejectedAlert `json:"rejected"`
+}
+
+// alert converts the received alert into an alert. It returns the problems
+// of the fields if the alert is malformed.
+func (a *alertV2) alert() (*types.Alert, types.ValidationError) {
+	var (
+		verr types.ValidationError
+		res  = &types.Alert{Alert: model.Alert{
+			Labels:       model.LabelSet{},
+			Annotations:  model.LabelSet{},
+			GeneratorURL: a.GeneratorURL,
+		}}
+		add = func(field, format string, args ...interface{}) {
+			verr = append(verr, types.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+		}
+	)
+
+	if len(a.Labels) == 0 {
+		add("labels", "at least one label required")
+	}
+	if len(a.Labels) > maxAlertLabels {
+		add("labels", "more than %d labels", maxAlertLabels)
+	}
+	for _, f := range []struct {
+		name   string
+		values map[string]string
+		lset   model.LabelSet
+		limit  bool
+	}{
+		{"labels", a.Labels, res.Labels, true},
+		{"annotations", a.Annotations, res.Annotations, false},
+	} {
+		names := make([]string, 0, len(f.values))
+		for name := range f.values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			var (
+				ln = model.LabelName(name)
+				lv = model.LabelValue(f.values[name])
+			)
+			switch {
+			case !ln.IsValid():
+				add(f.name, "invalid name %q", name)
+			case !lv.IsValid():
+				add(f.name+"."+name, "value is not valid UTF-8")
+			case f.limit && len(name)+len(lv) > maxAlertLabelSize:
+				add(f.name+"."+name, "larger than %d bytes", maxAlertLabelSize)
+			default:
+				f.lset[ln] = lv
+			}
+		}
+	}
+
+	for _, f := range []struct {
+		name  string
+		value string
+		t     *time.Time
+	}{
+		{"startsAt", a.StartsAt, &res.StartsAt},
+		{"endsAt", a.EndsAt, &res.EndsAt},
+	} {
+		if f.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, f.value)
+		if err != nil {
+			add(f.name, "must be an RFC 3339 timestamp")
+			continue
+		}
+		if _, offset := t.Zone(); offset != 0 {
+			add(f.name, "must be in UTC")
+			continue
+		}
+		*f.t = t
+	}
+	if !res.EndsAt.IsZero() {
+		if a.StartsAt == "" {
+			add("startsAt", "required if endsAt is set")
+		} else if !res.StartsAt.IsZero() && res.EndsAt.Before(res.StartsAt) {
+			add("endsAt", "must not be before startsAt")
+		}
+	}
+
+	if a.GeneratorURL != "" {
+		if u, err := url.Parse(a.GeneratorURL); err != nil || !u.IsAbs() {
+			add("generatorURL", "must be an absolute URL")
+		}
+	}
+
+	if len(verr) > 0 {
+		return nil, verr
+	}
+	return res, nil
+}
+
+// addAlertsV2 receives alerts in the versioned payload format. Valid alerts
+// are stored even if others are rejected, which are listed with their
+// problems. The request fails only if no alert was accepted.
+func (api *API) addAlertsV2(w http.ResponseWriter, r *http.Request) {
+	var req alertsV2
+	if err := receive(r, &req); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if req.Version != alertsSchemaVersion {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("unsupported schema version %d, expected %d", req.Version, alertsSchemaVersion),
+		}, nil)
+		return
+	}
+
+	var (
+		res    = &alertsV2Result{Rejected: []*rejectedAlert{}}
+		alerts = make([]*types.Alert, 0, len(req.Alerts))
+	)
+	for i, a := range req.Alerts {
+		if a == nil {
+			a = &alertV2{}
+		}
+		alert, verr := a.alert()
+		if verr != nil {
+			numInvalidAlerts.Inc()
+			res.Rejected = append(res.Rejected, &rejectedAlert{
+				Index:  i,
+				Errors: verr,
+			})
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+
+	api.setSource(r, alerts...)
+
+	// The alerts were validated above and are consistent with the defaults
+	// applied when storing them, which yields no further validation errors.
+	if _, err := api.storeAlerts(alerts...); err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	res.Accepted = len(alerts)
+
+	if res.Accepted == 0 && len(res.Rejected) > 0 {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("all alerts rejected"),
+		}, res)
+		return
+	}
+	respond(w, res)
+}
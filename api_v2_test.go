@@ -0,0 +1,83 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestAddAlertsV2(t *testing.T) {
+	alerts := provider.NewMemAlerts(provider.NewMemData())
+	api := NewAPI(alerts, nil, nil, nil, nil, nil, nil, nil, "", nil)
+
+	post := func(body string) (int, *alertsV2Result) {
+		w := httptest.NewRecorder()
+		api.addAlertsV2(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+
+		var res struct {
+			Data *alertsV2Result `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, res.Data
+	}
+
+	if code, _ := post(`{"alerts": [{"labels": {"alertname": "HighLatency"}}]}`); code != http.StatusBadRequest {
+		t.Errorf("expected payload without version to be rejected but got status %d", code)
+	}
+
+	code, res := post(fmt.Sprintf(`{"version": 2, "alerts": [
+		{"labels": {"alertname": "HighLatency"}, "startsAt": "2016-10-01T12:00:00Z"},
+		{"labels": {"1alertname": "HighLatency", "service": %q}},
+		{"labels": {"alertname": "DiskFull"}, "startsAt": "2016-10-01T14:00:00+02:00"},
+		{"labels": {"alertname": "DiskFull"}, "endsAt": "2016-10-01T12:00:00Z"}
+	]}`, strings.Repeat("x", maxAlertLabelSize)))
+	if code != http.StatusOK {
+		t.Fatalf("expected status %d but got %d", http.StatusOK, code)
+	}
+	if res.Accepted != 1 {
+		t.Errorf("expected 1 accepted alert but got %d", res.Accepted)
+	}
+	expected := []*rejectedAlert{
+		{Index: 1, Errors: []types.FieldError{
+			{Field: "labels", Message: `invalid name "1alertname"`},
+			{Field: "labels.service", Message: fmt.Sprintf("larger than %d bytes", maxAlertLabelSize)},
+		}},
+		{Index: 2, Errors: []types.FieldError{{Field: "startsAt", Message: "must be in UTC"}}},
+		{Index: 3, Errors: []types.FieldError{{Field: "startsAt", Message: "required if endsAt is set"}}},
+	}
+	if !reflect.DeepEqual(res.Rejected, expected) {
+		t.Errorf("expected rejected alerts %+v but got %+v", expected, res.Rejected)
+	}
+	if _, err := alerts.Get(model.LabelSet{"alertname": "HighLatency"}.Fingerprint()); err != nil {
+		t.Errorf("expected valid alert to be stored but got %v", err)
+	}
+
+	code, res = post(`{"version": 2, "alerts": [{"labels": {}}]}`)
+	if code != http.StatusBadRequest || len(res.Rejected) != 1 {
+		t.Errorf("expected all alerts to be rejected but got status %d and %+v", code, res)
+	}
+}
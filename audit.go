@@ -0,0 +1,109 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// An Auditor records calls of mutating API endpoints in the audit log.
+type Auditor struct {
+	audit provider.Audit
+	// Header set to the authenticated user by a reverse proxy.
+	userHeader string
+	auth       *Authenticator
+}
+
+// NewAuditor returns a new Auditor writing to the given audit log. If
+// userHeader is set, the principal is read from the request header of
+// that name. Otherwise users authenticated by the Authenticator are
+// recorded if it is not nil.
+func NewAuditor(a provider.Audit, userHeader string, auth *Authenticator) *Auditor {
+	return &Auditor{audit: a, userHeader: userHeader, auth: auth}
+}
+
+// principal returns the authenticated user of the request if known and
+// the client's address otherwise.
+func (au *Auditor) principal(r *http.Request) string {
+	if au.userHeader != "" {
+		if u := r.Header.Get(au.userHeader); u != "" {
+			return u
+		}
+	}
+	if u, ok := au.auth.User(r); ok {
+		return u
+	}
+	if u, _, ok := r.BasicAuth(); ok && u != "" {
+		return u
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// Record adds an entry for the request to the audit log.
+func (au *Auditor) Record(r *http.Request, action, target string, status int) {
+	e := &types.AuditEntry{
+		Time:       time.Now(),
+		Principal:  au.principal(r),
+		RemoteAddr: r.RemoteAddr,
+		Action:     action,
+		Target:     target,
+		Status:     status,
+	}
+	if _, err := au.audit.Add(e); err != nil {
+		log.With("action", action).Errorf("Error writing audit log: %s", err)
+	}
+}
+
+// Handler returns a handler recording each call of h under the given
+// action. If param is set, the route parameter of that name is recorded
+// as the target. A nil Auditor records nothing.
+func (au *Auditor) Handler(action, param string, h http.HandlerFunc) http.HandlerFunc {
+	if au == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h(sw, r)
+
+		var target string
+		if param != "" {
+			if ctx := route.Context(r); ctx != nil {
+				target = route.Param(ctx, param)
+			}
+		}
+		au.Record(r, action, target, sw.status)
+	}
+}
+
+// statusWriter captures the status code written to a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
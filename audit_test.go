@@ -0,0 +1,100 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+type testAudit struct {
+	entries []*types.AuditEntry
+}
+
+func (a *testAudit) Add(e *types.AuditEntry) (uint64, error) {
+	a.entries = append(a.entries, e)
+	e.ID = uint64(len(a.entries))
+	return e.ID, nil
+}
+
+func (a *testAudit) Query(since time.Time, limit int) ([]*types.AuditEntry, error) {
+	return a.entries, nil
+}
+
+func TestAuditorHandler(t *testing.T) {
+	var (
+		audit = &testAudit{}
+		au    = NewAuditor(audit, "X-Forwarded-User", nil)
+	)
+	h := au.Handler("add_silence", "", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	cases := []struct {
+		prepare   func(r *http.Request)
+		principal string
+	}{
+		{
+			prepare:   func(r *http.Request) {},
+			principal: "10.0.0.1",
+		},
+		{
+			prepare:   func(r *http.Request) { r.SetBasicAuth("bob", "secret") },
+			principal: "bob",
+		},
+		{
+			prepare: func(r *http.Request) {
+				r.SetBasicAuth("bob", "secret")
+				r.Header.Set("X-Forwarded-User", "alice")
+			},
+			principal: "alice",
+		},
+	}
+
+	for i, c := range cases {
+		r, err := http.NewRequest("POST", "http://localhost/api/v1/silences", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = "10.0.0.1:1234"
+		c.prepare(r)
+
+		h(httptest.NewRecorder(), r)
+
+		if len(audit.entries) != i+1 {
+			t.Fatalf("%d. expected %d audit entries but got %d", i, i+1, len(audit.entries))
+		}
+		e := audit.entries[i]
+		if e.Principal != c.principal {
+			t.Fatalf("%d. expected principal %q but got %q", i, c.principal, e.Principal)
+		}
+		if e.Action != "add_silence" || e.Status != http.StatusBadRequest || e.RemoteAddr != "10.0.0.1:1234" {
+			t.Fatalf("%d. unexpected audit entry %+v", i, e)
+		}
+	}
+
+	// A nil auditor passes calls through.
+	var nilAuditor *Auditor
+	called := false
+	nilAuditor.Handler("add_silence", "", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})(httptest.NewRecorder(), &http.Request{})
+	if !called {
+		t.Fatalf("expected handler to be called")
+	}
+}
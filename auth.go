@@ -0,0 +1,216 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// endpointRoles are the roles required by endpoints regardless of the
+// request method. Other endpoints require the read role for GET and HEAD
+// requests outside of /admin/ and the admin role otherwise.
+var endpointRoles = map[string]config.APIRole{
+	// Profiles expose the process' memory.
+	"debug": config.APIRoleAdmin,
+	// Backups and exports hold the complete state.
+	"backup":       config.APIRoleAdmin,
+	"export_state": config.APIRoleAdmin,
+	"audit_log":    config.APIRoleAdmin,
+
+	"preview_silence":     config.APIRoleRead,
+	"render_template":     config.APIRoleRead,
+	"add_silence":         config.APIRoleSilence,
+	"del_silence":         config.APIRoleSilence,
+	"silence_alert_group": config.APIRoleSilence,
+	"ack_alert":           config.APIRoleSilence,
+	"del_alert_ack":       config.APIRoleSilence,
+	"ack_event":           config.APIRoleSilence,
+
+	"add_maintenance_window":    config.APIRoleSilence,
+	"update_maintenance_window": config.APIRoleSilence,
+	"del_maintenance_window":    config.APIRoleSilence,
+}
+
+// signedEndpoints verify the signatures of requests themselves and are
+// exempt from authentication.
+var signedEndpoints = map[string]bool{
+	"pagerduty_webhook": true,
+	"slack_action":      true,
+}
+
+// An Authenticator authenticates requests and checks whether the
+// authenticated user's role grants access to the requested endpoint.
+// All methods are goroutine-safe.
+type Authenticator struct {
+	mtx  sync.RWMutex
+	conf *config.APIAuth
+}
+
+// NewAuthenticator returns a new Authenticator. Until it is configured,
+// all requests are allowed.
+func NewAuthenticator() *Authenticator {
+	return &Authenticator{}
+}
+
+// SetConfig sets the users and roles requests are checked against. If
+// the configuration is nil, all requests are allowed.
+func (a *Authenticator) SetConfig(conf *config.APIAuth) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.conf = conf
+}
+
+// authenticate returns the user identified by the request's credentials.
+// The user is nil if the request has no credentials.
+func (a *Authenticator) authenticate(conf *config.APIAuth, r *http.Request) (*config.APIUser, error) {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token := []byte(strings.TrimPrefix(h, "Bearer "))
+		for _, u := range conf.Users {
+			if u.BearerToken != "" && subtle.ConstantTimeCompare(token, []byte(u.BearerToken)) == 1 {
+				return u, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid bearer token")
+	}
+	if name, password, ok := r.BasicAuth(); ok {
+		for _, u := range conf.Users {
+			if u.Name != name || u.Password == "" {
+				continue
+			}
+			if subtle.ConstantTimeCompare([]byte(password), []byte(u.Password)) == 1 {
+				return u, nil
+			}
+			break
+		}
+		return nil, fmt.Errorf("invalid user or password")
+	}
+	// Only certificates verified against the client CAs identify users.
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for _, u := range conf.Users {
+			if u.ClientCertCN != "" && u.ClientCertCN == cn {
+				return u, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown client certificate %q", cn)
+	}
+	return nil, nil
+}
+
+// identify returns the user authenticated by the request and the role
+// granted to the request. If authentication is not configured, ok is
+// false.
+func (a *Authenticator) identify(r *http.Request) (u *config.APIUser, role config.APIRole, ok bool) {
+	if a == nil {
+		return nil, "", false
+	}
+	a.mtx.RLock()
+	conf := a.conf
+	a.mtx.RUnlock()
+
+	if conf == nil {
+		return nil, "", false
+	}
+	u, err := a.authenticate(conf, r)
+	if err != nil {
+		return nil, "", true
+	}
+	if u == nil {
+		return nil, conf.AnonymousRole, true
+	}
+	return u, u.Role, true
+}
+
+// User returns the name of the user authenticated by the request.
+func (a *Authenticator) User(r *http.Request) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	a.mtx.RLock()
+	conf := a.conf
+	a.mtx.RUnlock()
+
+	if conf == nil {
+		return "", false
+	}
+	u, err := a.authenticate(conf, r)
+	if err != nil || u == nil {
+		return "", false
+	}
+	return u.Name, true
+}
+
+// Handler returns a handler calling h for requests whose user is granted
+// the role required by the named endpoint and rejecting all others. A
+// nil Authenticator allows all requests.
+func (a *Authenticator) Handler(name string, h http.HandlerFunc) http.HandlerFunc {
+	if a == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.mtx.RLock()
+		conf := a.conf
+		a.mtx.RUnlock()
+
+		if conf == nil || signedEndpoints[name] {
+			h(w, r)
+			return
+		}
+
+		role, ok := endpointRoles[name]
+		if !ok {
+			role = config.APIRoleAdmin
+			if (r.Method == "GET" || r.Method == "HEAD") && !strings.Contains(r.URL.Path, "/admin/") {
+				role = config.APIRoleRead
+			}
+		}
+
+		u, err := a.authenticate(conf, r)
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+		granted := conf.AnonymousRole
+		if u != nil {
+			granted = u.Role
+		}
+		if granted == "" {
+			unauthorized(w, fmt.Errorf("authentication required"))
+			return
+		}
+		if !granted.Grants(role) {
+			respondError(w, apiError{
+				typ: errorForbidden,
+				err: fmt.Errorf("role %q required", role),
+			}, nil)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="Alertmanager"`)
+	respondError(w, apiError{
+		typ: errorUnauthorized,
+		err: err,
+	}, nil)
+}
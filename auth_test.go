@@ -0,0 +1,238 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+func TestAuthenticatorHandler(t *testing.T) {
+	a := NewAuthenticator()
+	a.SetConfig(&config.APIAuth{
+		Users: []*config.APIUser{
+			{Name: "oncall", Role: config.APIRoleSilence, Password: "secret"},
+			{Name: "automation", Role: config.APIRoleAdmin, BearerToken: "token"},
+			{Name: "peer", Role: config.APIRoleAdmin, ClientCertCN: "am-2"},
+		},
+	})
+
+	withCert := func(cn string) func(r *http.Request) {
+		return func(r *http.Request) {
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}
+	}
+
+	cases := []struct {
+		name    string
+		method  string
+		prepare func(r *http.Request)
+		status  int
+	}{
+		{
+			name:    "alerts",
+			method:  "GET",
+			prepare: func(r *http.Request) {},
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name:    "alerts",
+			method:  "GET",
+			prepare: func(r *http.Request) { r.SetBasicAuth("oncall", "secret") },
+			status:  http.StatusOK,
+		},
+		{
+			name:    "add_silence",
+			method:  "POST",
+			prepare: func(r *http.Request) { r.SetBasicAuth("oncall", "secret") },
+			status:  http.StatusOK,
+		},
+		{
+			name:    "add_silence",
+			method:  "POST",
+			prepare: func(r *http.Request) { r.SetBasicAuth("oncall", "wrong") },
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name:    "add_event",
+			method:  "POST",
+			prepare: func(r *http.Request) { r.SetBasicAuth("oncall", "secret") },
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "add_event",
+			method:  "POST",
+			prepare: func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") },
+			status:  http.StatusOK,
+		},
+		{
+			name:    "add_event",
+			method:  "POST",
+			prepare: func(r *http.Request) { r.Header.Set("Authorization", "Bearer other") },
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name:    "cluster_gossip",
+			method:  "POST",
+			prepare: withCert("am-2"),
+			status:  http.StatusOK,
+		},
+		{
+			name:    "cluster_gossip",
+			method:  "POST",
+			prepare: withCert("am-3"),
+			status:  http.StatusUnauthorized,
+		},
+	}
+
+	for i, c := range cases {
+		r, err := http.NewRequest(c.method, "http://localhost/api/v1/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.prepare(r)
+
+		called := false
+		w := httptest.NewRecorder()
+		a.Handler(c.name, func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		})(w, r)
+
+		if w.Code != c.status {
+			t.Fatalf("%d. expected status %d but got %d", i, c.status, w.Code)
+		}
+		if called != (c.status == http.StatusOK) {
+			t.Fatalf("%d. expected handler called %v but got %v", i, !called, called)
+		}
+		if c.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("%d. expected authentication challenge", i)
+		}
+	}
+
+	// Requests without credentials get the anonymous role.
+	a.SetConfig(&config.APIAuth{AnonymousRole: config.APIRoleRead})
+
+	for _, c := range []struct {
+		name   string
+		method string
+		status int
+	}{
+		{"alerts", "GET", http.StatusOK},
+		{"del_silence", "DELETE", http.StatusForbidden},
+	} {
+		r, err := http.NewRequest(c.method, "http://localhost/api/v1/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		a.Handler(c.name, func(w http.ResponseWriter, r *http.Request) {})(w, r)
+
+		if w.Code != c.status {
+			t.Fatalf("%s: expected status %d but got %d", c.name, c.status, w.Code)
+		}
+	}
+}
+
+func TestAuthenticatorHandlerAdminEndpoints(t *testing.T) {
+	a := NewAuthenticator()
+	a.SetConfig(&config.APIAuth{
+		Users: []*config.APIUser{
+			{Name: "viewer", Role: config.APIRoleRead, Password: "secret"},
+			{Name: "automation", Role: config.APIRoleAdmin, BearerToken: "token"},
+		},
+	})
+
+	for _, c := range []struct {
+		name string
+		path string
+	}{
+		{"backup", "/api/v1/admin/backup"},
+		{"export_state", "/api/v1/admin/state"},
+		{"audit_log", "/api/v1/admin/audit"},
+		// Endpoints below /admin/ require the admin role by default.
+		{"unknown", "/api/v1/admin/unknown"},
+	} {
+		for _, user := range []string{"viewer", "automation"} {
+			r, err := http.NewRequest("GET", "http://localhost"+c.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			status := http.StatusForbidden
+			if user == "viewer" {
+				r.SetBasicAuth("viewer", "secret")
+			} else {
+				r.Header.Set("Authorization", "Bearer token")
+				status = http.StatusOK
+			}
+			w := httptest.NewRecorder()
+			a.Handler(c.name, func(w http.ResponseWriter, r *http.Request) {})(w, r)
+
+			if w.Code != status {
+				t.Errorf("%s as %q: expected status %d but got %d", c.name, user, status, w.Code)
+			}
+		}
+	}
+}
+
+func TestRegisterWebAuthentication(t *testing.T) {
+	a := NewAuthenticator()
+	a.SetConfig(&config.APIAuth{
+		Users: []*config.APIUser{
+			{Name: "oncall", Role: config.APIRoleSilence, Password: "secret"},
+			{Name: "automation", Role: config.APIRoleAdmin, BearerToken: "token"},
+		},
+	})
+	r := route.New()
+	RegisterWeb(r, make(chan struct{}, 1), nil, a)
+
+	for _, c := range []struct {
+		path   string
+		user   string
+		status int
+	}{
+		{"/", "", http.StatusUnauthorized},
+		{"/app/js/app.js", "", http.StatusUnauthorized},
+		{"/lib/bootstrap.min.css", "", http.StatusUnauthorized},
+		{"/debug/pprof/", "", http.StatusUnauthorized},
+		{"/debug/pprof/", "oncall", http.StatusForbidden},
+		{"/debug/pprof/", "automation", http.StatusOK},
+		{"/metrics", "", http.StatusOK},
+	} {
+		req, err := http.NewRequest("GET", "http://localhost"+c.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch c.user {
+		case "oncall":
+			req.SetBasicAuth("oncall", "secret")
+		case "automation":
+			req.Header.Set("Authorization", "Bearer token")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.status {
+			t.Errorf("%s as %q: expected status %d but got %d", c.path, c.user, c.status, w.Code)
+		}
+	}
+}
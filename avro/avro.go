@@ -0,0 +1,131 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package avro writes data in the Avro binary encoding. Records are
+// encoded field by field by their callers following their schema.
+//
+// https://avro.apache.org/docs/1.11.1/specification/
+package avro
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// An Encoder appends values in the binary encoding to its buffer.
+type Encoder struct {
+	buf []byte
+}
+
+// NewSingleObjectEncoder returns an encoder whose buffer starts with the
+// header of the single object encoding of values of the schema. The schema
+// must be given in its parsing canonical form.
+func NewSingleObjectEncoder(canonicalSchema string) *Encoder {
+	e := &Encoder{buf: []byte{0xc3, 0x01}}
+	var fp [8]byte
+	binary.LittleEndian.PutUint64(fp[:], Fingerprint(canonicalSchema))
+	e.buf = append(e.buf, fp[:]...)
+	return e
+}
+
+// Bytes returns the encoded data.
+func (e *Encoder) Bytes() []byte { return e.buf }
+
+// Boolean encodes a boolean.
+func (e *Encoder) Boolean(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+// Long encodes a long or an int.
+func (e *Encoder) Long(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	e.buf = append(e.buf, b[:binary.PutVarint(b[:], v)]...)
+}
+
+// String encodes a string.
+func (e *Encoder) String(s string) {
+	e.Long(int64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// Union encodes the index of the branch of a union the following value
+// belongs to.
+func (e *Encoder) Union(branch int) { e.Long(int64(branch)) }
+
+// StringArray encodes an array of strings in a single block.
+func (e *Encoder) StringArray(ss []string) {
+	if len(ss) > 0 {
+		e.Long(int64(len(ss)))
+		for _, s := range ss {
+			e.String(s)
+		}
+	}
+	e.Long(0)
+}
+
+// StringMap encodes a map of strings in a single block with its keys in
+// sorted order.
+func (e *Encoder) StringMap(m map[string]string) {
+	if len(m) > 0 {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		e.Long(int64(len(m)))
+		for _, k := range keys {
+			e.String(k)
+			e.String(m[k])
+		}
+	}
+	e.Long(0)
+}
+
+// OptionalString encodes a string as union of null and string, with the
+// empty string encoded as null.
+func (e *Encoder) OptionalString(s string) {
+	if s == "" {
+		e.Union(0)
+		return
+	}
+	e.Union(1)
+	e.String(s)
+}
+
+const emptyFingerprint = 0xc15d213aa4d7a795
+
+var fingerprintTable = func() (t [256]uint64) {
+	for i := range t {
+		fp := uint64(i)
+		for j := 0; j < 8; j++ {
+			fp = (fp >> 1) ^ (emptyFingerprint & -(fp & 1))
+		}
+		t[i] = fp
+	}
+	return t
+}()
+
+// Fingerprint returns the CRC-64-AVRO fingerprint of the schema given in
+// its parsing canonical form.
+func Fingerprint(canonicalSchema string) uint64 {
+	fp := uint64(emptyFingerprint)
+	for i := 0; i < len(canonicalSchema); i++ {
+		fp = (fp >> 8) ^ fingerprintTable[byte(fp)^canonicalSchema[i]]
+	}
+	return fp
+}
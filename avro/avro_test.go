@@ -0,0 +1,58 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avro
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	cases := []struct {
+		schema   string
+		expected uint64
+	}{
+		{schema: `"null"`, expected: 0x63dd24e7cc258f8a},
+		{schema: `"string"`, expected: 0x8f014872634503c7},
+		{schema: `{"name":"r","type":"record","fields":[{"name":"a","type":"long"}]}`, expected: 0x54f6d3744485515f},
+	}
+	for _, c := range cases {
+		if got := Fingerprint(c.schema); got != c.expected {
+			t.Errorf("expected fingerprint of %s to be %x but got %x", c.schema, c.expected, got)
+		}
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	e := NewSingleObjectEncoder(`{"name":"r","type":"record","fields":[{"name":"a","type":"long"}]}`)
+	e.Long(0)
+	e.Long(-1)
+	e.Long(64)
+	e.Boolean(true)
+	e.String("ab")
+	e.OptionalString("")
+	e.OptionalString("c")
+	e.StringArray([]string{"x"})
+	e.StringArray(nil)
+	e.StringMap(map[string]string{"b": "2", "a": "1"})
+
+	expected := "c301" + "5f51854474d3f654" +
+		"00" + "01" + "8001" + "01" + "046162" +
+		"00" + "020263" +
+		"02027800" + "00" +
+		"04" + "02610231" + "02620232" + "00"
+	if got := hex.EncodeToString(e.Bytes()); got != expected {
+		t.Errorf("expected encoding %s but got %s", expected, got)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// bundleVersion is the version of the bundle format.
+const bundleVersion = 1
+
+// Bundle holds the state created by users of an Alertmanager: silences,
+// acknowledgements, and open events. Unlike a State, it holds no alerts or
+// notification log, which the receiving instance builds up on its own, so
+// it can be moved between instances and environments.
+type Bundle struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Silences  []*types.Silence `json:"silences"`
+	Acks      []*types.Ack     `json:"acks"`
+	Events    []*types.Event   `json:"events"`
+}
+
+// BundleCounts is the number of silences, acknowledgements, and events
+// imported from a bundle.
+type BundleCounts struct {
+	Silences int `json:"silences"`
+	Acks     int `json:"acks"`
+	Events   int `json:"events"`
+}
+
+// ReadBundle reads and validates a bundle.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	var b Bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, err
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Write writes the bundle.
+func (b *Bundle) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(b)
+}
+
+// Validate returns an error if the bundle has an unsupported version or
+// holds invalid silences, acknowledgements, or events.
+func (b *Bundle) Validate() error {
+	if b.Version != bundleVersion {
+		return fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	for _, sil := range b.Silences {
+		if err := types.RestoreSilence(sil).Validate(); err != nil {
+			return fmt.Errorf("invalid silence: %s", err)
+		}
+	}
+	for _, ack := range b.Acks {
+		if err := ack.Validate(); err != nil {
+			return fmt.Errorf("invalid acknowledgement of alert %s: %s", ack.Alert, err)
+		}
+	}
+	for _, e := range b.Events {
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("invalid event %d: %s", e.ID, err)
+		}
+	}
+	return nil
+}
+
+// ExportBundle returns a bundle of the silences that have not ended, the
+// acknowledgements that have not expired, and the events that are not
+// resolved at the given time. If the matcher is not nil, the bundle only
+// holds silences and events of its tenant and acknowledgements of its
+// alerts.
+func (s *Stores) ExportBundle(now time.Time, m *types.Matcher) (*Bundle, error) {
+	b := &Bundle{
+		Version:   bundleVersion,
+		CreatedAt: now,
+		Silences:  []*types.Silence{},
+		Acks:      []*types.Ack{},
+		Events:    []*types.Event{},
+	}
+
+	sils, err := s.Silences.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, sil := range sils {
+		if !sil.EndsAt.After(now) || (m != nil && !ownsSilence(m, sil)) {
+			continue
+		}
+		b.Silences = append(b.Silences, sil)
+	}
+
+	acks, err := s.Acks.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, ack := range acks {
+		if !ack.ExpiresAt.IsZero() && !ack.ExpiresAt.After(now) {
+			continue
+		}
+		if m != nil {
+			a, err := s.Alerts.Get(ack.Alert)
+			if err != nil || !m.Match(a.Labels) {
+				continue
+			}
+		}
+		b.Acks = append(b.Acks, ack)
+	}
+
+	events, err := s.Events.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		if e.CurrentStatus() == types.EventResolved || (m != nil && !m.Match(e.Labels)) {
+			continue
+		}
+		b.Events = append(b.Events, e)
+	}
+	return b, nil
+}
+
+// ImportBundle adds the silences, acknowledgements, and events of the
+// bundle to the stores. Silences and events are assigned new IDs, so
+// importing a bundle twice duplicates them.
+func (s *Stores) ImportBundle(b *Bundle) (*BundleCounts, error) {
+	// Nothing is imported unless the whole bundle is valid.
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	for _, sil := range b.Silences {
+		sil = types.RestoreSilence(sil)
+		sil.ID = 0
+
+		if _, err := s.Silences.Set(sil); err != nil {
+			return nil, fmt.Errorf("importing silence failed: %s", err)
+		}
+	}
+	for _, ack := range b.Acks {
+		if err := s.Acks.Set(ack); err != nil {
+			return nil, fmt.Errorf("importing acknowledgement failed: %s", err)
+		}
+	}
+	if err := importEvents(s.Events, b.Events); err != nil {
+		return nil, err
+	}
+	return &BundleCounts{
+		Silences: len(b.Silences),
+		Acks:     len(b.Acks),
+		Events:   len(b.Events),
+	}, nil
+}
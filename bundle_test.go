@@ -0,0 +1,153 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider/boltmem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestBundleExportImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(dir+"/from", 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(dir+"/to", 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		from = newTestStores(t, dir+"/from")
+		to   = newTestStores(t, dir+"/to")
+		now  = time.Now().UTC().Truncate(time.Second)
+	)
+	defer from.Acks.(*boltmem.Acks).Close()
+	defer to.Acks.(*boltmem.Acks).Close()
+
+	alerts := map[string]*types.Alert{}
+	for _, team := range []string{"a", "b"} {
+		alerts[team] = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "test", "team": model.LabelValue(team)},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(time.Hour),
+			},
+			UpdatedAt: now,
+		}
+		if err := from.Alerts.Put(alerts[team]); err != nil {
+			t.Fatal(err)
+		}
+		ack := &types.Ack{Alert: alerts[team].Fingerprint(), CreatedBy: "user", CreatedAt: now}
+		if err := from.Acks.Set(ack); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ends := range []time.Time{now.Add(time.Hour), now.Add(-time.Minute)} {
+		sil := types.NewSilence(&model.Silence{
+			Matchers:  []*model.Matcher{{Name: "team", Value: "a"}},
+			StartsAt:  now.Add(-time.Hour),
+			EndsAt:    ends,
+			CreatedAt: now.Add(-time.Hour),
+			CreatedBy: "user",
+			Comment:   "maintenance",
+		})
+		if _, err := from.Silences.Set(sil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events := []*types.Event{
+		{Title: "open", Labels: model.LabelSet{"team": "a"}, CreatedAt: now},
+		{Title: "child", Labels: model.LabelSet{"team": "b"}, CreatedAt: now},
+		{Title: "resolved", Labels: model.LabelSet{"team": "a"}, CreatedAt: now, ClosedAt: now},
+	}
+	for _, e := range events {
+		if _, err := from.Events.Set(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := from.Events.Update(&types.Event{ID: 2, Title: "child", Labels: model.LabelSet{"team": "b"}, ParentID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ended silences and resolved events are left out.
+	b, err := from.ExportBundle(now, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Silences) != 1 || len(b.Acks) != 2 || len(b.Events) != 2 {
+		t.Fatalf("expected 1 silence, 2 acks, and 2 events but got %d, %d, and %d", len(b.Silences), len(b.Acks), len(b.Events))
+	}
+
+	// Tenants only export their own state.
+	tb, err := from.ExportBundle(now, types.NewMatcher("team", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tb.Silences) != 0 || len(tb.Acks) != 1 || len(tb.Events) != 1 || tb.Acks[0].Alert != alerts["b"].Fingerprint() {
+		t.Fatalf("expected only the ack and event of team b but got %v, %v, and %v", tb.Silences, tb.Acks, tb.Events)
+	}
+
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = ReadBundle(&buf); err != nil {
+		t.Fatal(err)
+	}
+	counts, err := to.ImportBundle(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *counts != (BundleCounts{Silences: 1, Acks: 2, Events: 2}) {
+		t.Errorf("expected counts of imported state but got %+v", counts)
+	}
+
+	sils, err := to.Silences.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sils) != 1 || !sils[0].EndsAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected active silence to be imported but got %v", sils)
+	}
+	if a, err := to.Acks.Get(alerts["b"].Fingerprint()); err != nil || a.CreatedBy != "user" {
+		t.Errorf("expected acknowledgement to be imported but got %v, %v", a, err)
+	}
+	if children, err := to.Events.Children(1); err != nil || len(children) != 1 || children[0].Title != "child" {
+		t.Errorf("expected parent of event to be imported but got %v, %v", children, err)
+	}
+
+	// Invalid bundles are rejected as a whole.
+	for _, in := range []string{
+		`{"version": 2}`,
+		`{"version": 1, "acks": [{"alert": "1"}]}`,
+		`{"version": 1, "events": [{"title": ""}]}`,
+	} {
+		if _, err := ReadBundle(bytes.NewBufferString(in)); err == nil {
+			t.Errorf("expected bundle %s to be rejected", in)
+		}
+	}
+}
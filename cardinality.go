@@ -0,0 +1,183 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// DefaultCardinalityThreshold is the number of aggregation groups per route
+// above which a route's grouping is considered to be of high cardinality.
+const DefaultCardinalityThreshold = 100
+
+// RouteCardinality is an estimate of the aggregation groups a route creates
+// for a set of alerts.
+type RouteCardinality struct {
+	Route    string           `json:"route"`
+	Receiver string           `json:"receiver"`
+	GroupBy  model.LabelNames `json:"groupBy"`
+
+	// Number of alerts routed to the route.
+	Alerts int `json:"alerts"`
+	// Number of aggregation groups the alerts are grouped into.
+	Groups int `json:"groups"`
+	// Number of distinct values of each grouping label.
+	LabelValues map[model.LabelName]int `json:"labelValues"`
+
+	// Whether the number of groups exceeds the threshold and the
+	// grouping labels responsible for it.
+	HighCardinality bool             `json:"highCardinality"`
+	Offending       model.LabelNames `json:"offending,omitempty"`
+}
+
+// AnalyzeCardinality estimates for each route in the tree how many
+// aggregation groups the given alerts would create. Routes creating more
+// groups than the threshold are flagged along with grouping labels that
+// have more distinct values than the threshold on their own.
+func AnalyzeCardinality(root *Route, alerts []*types.Alert, threshold int) []*RouteCardinality {
+	var (
+		res     []*RouteCardinality
+		byRoute = map[*Route]*RouteCardinality{}
+		groups  = map[*Route]map[model.Fingerprint]struct{}{}
+		values  = map[*Route]map[model.LabelName]map[model.LabelValue]struct{}{}
+	)
+	root.Walk(func(r *Route) {
+		rc := &RouteCardinality{
+			Route:       r.Key(),
+			Receiver:    r.RouteOpts.Receiver,
+			GroupBy:     model.LabelNames{},
+			LabelValues: map[model.LabelName]int{},
+		}
+		values[r] = map[model.LabelName]map[model.LabelValue]struct{}{}
+
+		if r.RouteOpts.GroupByAll {
+			rc.GroupBy = append(rc.GroupBy, groupByAllToken)
+		} else {
+			for ln := range r.RouteOpts.GroupBy {
+				rc.GroupBy = append(rc.GroupBy, ln)
+				values[r][ln] = map[model.LabelValue]struct{}{}
+			}
+			sort.Sort(rc.GroupBy)
+		}
+
+		byRoute[r] = rc
+		groups[r] = map[model.Fingerprint]struct{}{}
+		res = append(res, rc)
+	})
+
+	for _, a := range alerts {
+		if a.Resolved() {
+			continue
+		}
+		for _, r := range root.Match(a.RoutingLabels()) {
+			if r.RouteOpts.GroupByAll {
+				// Every alert has its own group.
+				groups[r][a.Fingerprint()] = struct{}{}
+				byRoute[r].Alerts++
+				continue
+			}
+			group := model.LabelSet{}
+
+			for ln := range r.RouteOpts.GroupBy {
+				lv := a.Labels[ln]
+				group[ln] = lv
+				values[r][ln][lv] = struct{}{}
+			}
+			groups[r][group.Fingerprint()] = struct{}{}
+			byRoute[r].Alerts++
+		}
+	}
+
+	for r, rc := range byRoute {
+		rc.Groups = len(groups[r])
+		rc.HighCardinality = rc.Groups > threshold
+
+		if r.RouteOpts.GroupByAll {
+			continue
+		}
+		for _, ln := range rc.GroupBy {
+			n := len(values[r][ln])
+			rc.LabelValues[ln] = n
+
+			if n > threshold {
+				rc.Offending = append(rc.Offending, ln)
+			}
+		}
+	}
+	return res
+}
+
+// GroupPreview is an aggregation group alerts are grouped into by a
+// routing tree.
+type GroupPreview struct {
+	Route    string         `json:"route"`
+	Receiver string         `json:"receiver"`
+	Labels   model.LabelSet `json:"labels"`
+	Alerts   []*types.Alert `json:"alerts"`
+}
+
+// groupPreviews sorts by route and labels.
+type groupPreviews []*GroupPreview
+
+func (gs groupPreviews) Len() int      { return len(gs) }
+func (gs groupPreviews) Swap(i, j int) { gs[i], gs[j] = gs[j], gs[i] }
+func (gs groupPreviews) Less(i, j int) bool {
+	if gs[i].Route != gs[j].Route {
+		return gs[i].Route < gs[j].Route
+	}
+	return gs[i].Labels.Before(gs[j].Labels)
+}
+
+// PreviewGroups returns the aggregation groups the given firing alerts are
+// grouped into by the routing tree, ordered by route and labels.
+func PreviewGroups(root *Route, alerts []*types.Alert) []*GroupPreview {
+	var (
+		res    = []*GroupPreview{}
+		groups = map[*Route]map[model.Fingerprint]*GroupPreview{}
+	)
+	for _, a := range alerts {
+		if a.Resolved() {
+			continue
+		}
+		for _, r := range root.Match(a.RoutingLabels()) {
+			labels := r.RouteOpts.GroupLabels(a.Labels)
+			fp := labels.Fingerprint()
+
+			if groups[r] == nil {
+				groups[r] = map[model.Fingerprint]*GroupPreview{}
+			}
+			g, ok := groups[r][fp]
+			if !ok {
+				g = &GroupPreview{
+					Route:    r.Key(),
+					Receiver: r.RouteOpts.Receiver,
+					Labels:   labels,
+				}
+				groups[r][fp] = g
+				res = append(res, g)
+			}
+			g.Alerts = append(g.Alerts, a)
+		}
+	}
+	for _, g := range res {
+		sort.Sort(alertsByFingerprint(g.Alerts))
+	}
+	sort.Sort(groupPreviews(res))
+
+	return res
+}
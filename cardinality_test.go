@@ -0,0 +1,176 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestAnalyzeCardinality(t *testing.T) {
+	in := `
+receiver: 'default'
+group_by: ['alertname']
+
+routes:
+- match:
+    team: 'infra'
+  receiver: 'infra'
+  group_by: ['alertname', 'pod']
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	var alerts []*types.Alert
+	for i := 0; i < 5; i++ {
+		alerts = append(alerts, &types.Alert{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					"alertname": "PodCrashLooping",
+					"team":      "infra",
+					"pod":       model.LabelValue(fmt.Sprintf("pod-%d", i)),
+				},
+				StartsAt: time.Now().Add(-time.Minute),
+			},
+		})
+	}
+	alerts = append(alerts, &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "Other"},
+			StartsAt: time.Now().Add(-time.Minute),
+		},
+	}, &types.Alert{
+		// Resolved alerts are not taken into account.
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "Resolved"},
+			StartsAt: time.Now().Add(-time.Hour),
+			EndsAt:   time.Now().Add(-time.Minute),
+		},
+	})
+
+	res := AnalyzeCardinality(tree, alerts, 3)
+
+	exp := []*RouteCardinality{
+		{
+			Route:       "{}",
+			Receiver:    "default",
+			GroupBy:     model.LabelNames{"alertname"},
+			Alerts:      1,
+			Groups:      1,
+			LabelValues: map[model.LabelName]int{"alertname": 1},
+		},
+		{
+			Route:           `{}/{team="infra"}`,
+			Receiver:        "infra",
+			GroupBy:         model.LabelNames{"alertname", "pod"},
+			Alerts:          5,
+			Groups:          5,
+			LabelValues:     map[model.LabelName]int{"alertname": 1, "pod": 5},
+			HighCardinality: true,
+			Offending:       model.LabelNames{"pod"},
+		},
+	}
+	if !reflect.DeepEqual(res, exp) {
+		for i := range res {
+			t.Logf("%d: %+v", i, res[i])
+		}
+		t.Fatalf("unexpected cardinality analysis")
+	}
+}
+
+func TestPreviewGroups(t *testing.T) {
+	in := `
+receiver: 'default'
+group_by: ['alertname']
+
+routes:
+- match:
+    team: 'infra'
+  receiver: 'infra'
+  group_by: ['...']
+  continue: true
+- match:
+    team: 'infra'
+  receiver: 'infra-digest'
+  group_by: ['team']
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	var alerts []*types.Alert
+	for i := 0; i < 3; i++ {
+		alerts = append(alerts, &types.Alert{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					"alertname": "PodCrashLooping",
+					"team":      "infra",
+					"pod":       model.LabelValue(fmt.Sprintf("pod-%d", i)),
+				},
+				StartsAt: time.Now().Add(-time.Minute),
+			},
+		})
+	}
+	alerts = append(alerts, &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "Other"},
+			StartsAt: time.Now().Add(-time.Minute),
+		},
+	}, &types.Alert{
+		// Resolved alerts are not grouped.
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "Other", "instance": "resolved"},
+			StartsAt: time.Now().Add(-time.Hour),
+			EndsAt:   time.Now().Add(-time.Minute),
+		},
+	})
+
+	res := PreviewGroups(tree, alerts)
+
+	type group struct {
+		route  string
+		labels model.LabelSet
+		alerts int
+	}
+	exp := []group{
+		{"{}", model.LabelSet{"alertname": "Other"}, 1},
+		{`{}/{team="infra"}`, model.LabelSet{"team": "infra"}, 3},
+	}
+	for _, a := range alerts[:3] {
+		exp = append(exp, group{`{}/{team="infra"}`, a.Labels, 1})
+	}
+	var have []group
+	for _, g := range res {
+		have = append(have, group{g.Route, g.Labels, len(g.Alerts)})
+	}
+	if !reflect.DeepEqual(have, exp) {
+		t.Fatalf("expected groups\n%v\nbut got\n%v", exp, have)
+	}
+	if res[1].Receiver != "infra-digest" {
+		t.Errorf("expected receiver infra-digest but got %q", res[1].Receiver)
+	}
+}
@@ -0,0 +1,474 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cluster replicates state between Alertmanager instances running
+// as a cluster. Changes of replicated state are broadcast to all peers over
+// HTTP and peers deduplicate notifications by waiting according to their
+// position in the cluster before notifying.
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// GossipPath is the path relative to a peer's URL under which it accepts
+// gossip messages.
+const GossipPath = "/api/v1/cluster/gossip"
+
+// maxPending is the maximum number of deltas queued for a single peer.
+// The oldest deltas are dropped if a peer is unreachable for too long, and
+// the complete state is sent to it instead.
+const maxPending = 10000
+
+var (
+	deltasSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "cluster_deltas_sent_total",
+		Help:      "The total number of state deltas sent to peers.",
+	}, []string{"peer"})
+	deltasDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "cluster_deltas_dropped_total",
+		Help:      "The total number of state deltas dropped as a peer was unreachable.",
+	}, []string{"peer"})
+	gossipFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "cluster_gossip_failures_total",
+		Help:      "The total number of failed attempts to send gossip messages to peers.",
+	}, []string{"peer"})
+	fullStatesSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "cluster_full_states_sent_total",
+		Help:      "The total number of times the complete state was sent to peers.",
+	}, []string{"peer"})
+)
+
+func init() {
+	prometheus.MustRegister(deltasSent)
+	prometheus.MustRegister(deltasDropped)
+	prometheus.MustRegister(gossipFailed)
+	prometheus.MustRegister(fullStatesSent)
+}
+
+// A State is replicated across the cluster by broadcasting deltas whenever
+// it changes locally. Its complete state is sent to peers that joined the
+// cluster or missed deltas.
+type State interface {
+	// Merge applies a delta broadcast by a peer. It must not broadcast
+	// the delta again.
+	Merge(from string, b []byte) error
+	// Full returns the complete state.
+	Full() (interface{}, error)
+	// MergeFull applies the complete state of a peer. It must not
+	// broadcast the changes.
+	MergeFull(from string, b []byte) error
+}
+
+// Delta is a change of a named state.
+type Delta struct {
+	State string          `json:"state"`
+	Data  json.RawMessage `json:"data"`
+	// Seq orders the deltas broadcast by a peer. It is zero for
+	// complete states.
+	Seq uint64 `json:"seq,omitempty"`
+	// Full is true if the data is the complete state.
+	Full bool `json:"full,omitempty"`
+}
+
+// Message is a batch of deltas sent from one peer to another.
+type Message struct {
+	From   string   `json:"from"`
+	Deltas []*Delta `json:"deltas"`
+	// Pull asks the receiving peer to send its complete state.
+	Pull bool `json:"pull,omitempty"`
+}
+
+// PeerStatus describes the health of the connection to a peer.
+type PeerStatus struct {
+	Name string `json:"name"`
+	// Healthy is false if the last attempt to send gossip to the peer
+	// failed.
+	Healthy  bool      `json:"healthy"`
+	LastSent time.Time `json:"lastSent,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Pending  int       `json:"pending"`
+}
+
+// Status describes the cluster as seen by a peer.
+type Status struct {
+	Name     string        `json:"name"`
+	Position int           `json:"position"`
+	Peers    []*PeerStatus `json:"peers"`
+}
+
+type peerState struct {
+	status  PeerStatus
+	pending []*Delta
+	// push is true if the complete state is to be sent to the peer and
+	// pull is true if the peer is to be asked for its complete state.
+	push, pull bool
+}
+
+// A Peer is a member of a cluster of Alertmanagers. Peers are identified
+// by the URL under which they are reachable. All methods are goroutine-safe.
+type Peer struct {
+	name     string
+	interval time.Duration
+	timeout  time.Duration
+	client   *http.Client
+	// Token authenticating gossip at the peers' APIs.
+	bearerToken string
+
+	mtx    sync.RWMutex
+	seq    uint64
+	states map[string]State
+	peers  map[string]*peerState
+
+	stopc chan struct{}
+	done  chan struct{}
+}
+
+// NewPeer returns a new peer of the given name that sends gossip to the
+// other peers in the given interval. Peers wait the peer timeout for each
+// peer preceding them before notifying. As it joins the cluster, the peer
+// exchanges its complete state with the other peers.
+func NewPeer(name string, peers []string, interval, timeout time.Duration) *Peer {
+	p := &Peer{
+		name:     normalize(name),
+		interval: interval,
+		timeout:  timeout,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		states:   map[string]State{},
+		peers:    map[string]*peerState{},
+		stopc:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, name := range peers {
+		name = normalize(name)
+		if name == p.name {
+			continue
+		}
+		p.peers[name] = &peerState{
+			status: PeerStatus{Name: name, Healthy: true},
+			push:   true,
+			pull:   true,
+		}
+	}
+	return p
+}
+
+func normalize(name string) string {
+	return strings.TrimRight(name, "/")
+}
+
+// Name returns the name of the peer.
+func (p *Peer) Name() string {
+	return p.name
+}
+
+// SetBearerToken sets the token gossip is authenticated with at the
+// other peers. It must be set before the peer is run.
+func (p *Peer) SetBearerToken(token string) {
+	p.bearerToken = token
+}
+
+// AddState registers a state under the given name. Deltas received for
+// the name are merged into it.
+func (p *Peer) AddState(name string, s State) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.states[name] = s
+}
+
+// Broadcast queues a delta of the named state to be sent to all peers.
+func (p *Peer) Broadcast(state string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.seq++
+	d := &Delta{State: state, Data: b, Seq: p.seq}
+
+	for name, ps := range p.peers {
+		ps.pending = append(ps.pending, d)
+
+		if n := len(ps.pending) - maxPending; n > 0 {
+			ps.pending = ps.pending[n:]
+			ps.push = true
+			deltasDropped.WithLabelValues(name).Add(float64(n))
+		}
+	}
+	return nil
+}
+
+// Merge applies the deltas of a message received from a peer to their
+// states.
+func (p *Peer) Merge(msg *Message) error {
+	if msg.Pull {
+		p.mtx.Lock()
+		if ps, ok := p.peers[normalize(msg.From)]; ok {
+			ps.push = true
+		}
+		p.mtx.Unlock()
+	}
+
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	for _, d := range msg.Deltas {
+		s, ok := p.states[d.State]
+		if !ok {
+			return fmt.Errorf("unknown state %q", d.State)
+		}
+		merge := s.Merge
+		if d.Full {
+			merge = s.MergeFull
+		}
+		if err := merge(msg.From, d.Data); err != nil {
+			return fmt.Errorf("merging %s delta failed: %s", d.State, err)
+		}
+	}
+	return nil
+}
+
+// full returns deltas holding the complete states. The caller must hold
+// the peer's lock.
+func (p *Peer) full() ([]*Delta, error) {
+	var res []*Delta
+	for name, s := range p.states {
+		v, err := s.Full()
+		if err != nil {
+			return nil, fmt.Errorf("getting complete %s state failed: %s", name, err)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &Delta{State: name, Data: b, Full: true})
+	}
+	return res, nil
+}
+
+// Position returns the position of the peer in the list of all healthy
+// peers ordered by name.
+func (p *Peer) Position() int {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	pos := 0
+	for name, ps := range p.peers {
+		if ps.status.Healthy && name < p.name {
+			pos++
+		}
+	}
+	return pos
+}
+
+// Wait returns how long the peer waits before notifying so that peers
+// preceding it can notify first and replicate their notifications.
+func (p *Peer) Wait() time.Duration {
+	return time.Duration(p.Position()) * p.timeout
+}
+
+// Status returns the current status of the peer and its connections.
+func (p *Peer) Status() *Status {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	s := &Status{
+		Name:  p.name,
+		Peers: []*PeerStatus{},
+	}
+	for _, ps := range p.peers {
+		status := ps.status
+		status.Pending = len(ps.pending)
+		s.Peers = append(s.Peers, &status)
+
+		if status.Healthy && status.Name < p.name {
+			s.Position++
+		}
+	}
+	sort.Sort(peerStatuses(s.Peers))
+
+	return s
+}
+
+type peerStatuses []*PeerStatus
+
+func (ps peerStatuses) Len() int           { return len(ps) }
+func (ps peerStatuses) Swap(i, j int)      { ps[i], ps[j] = ps[j], ps[i] }
+func (ps peerStatuses) Less(i, j int) bool { return ps[i].Name < ps[j].Name }
+
+// Run sends gossip to the other peers until the peer is stopped.
+func (p *Peer) Run() {
+	defer close(p.done)
+
+	tick := time.NewTicker(p.interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-p.stopc:
+			return
+		case <-tick.C:
+			p.gossip()
+		}
+	}
+}
+
+// gossip sends all pending deltas to the peers concurrently, preceded by
+// the complete state for peers that joined or missed deltas.
+func (p *Peer) gossip() {
+	var (
+		wg   sync.WaitGroup
+		full []*Delta
+		err  error
+	)
+
+	p.mtx.Lock()
+	for name, ps := range p.peers {
+		if len(ps.pending) == 0 && !ps.push && !ps.pull {
+			continue
+		}
+		msg := &Message{
+			From: p.name,
+			Pull: ps.pull,
+		}
+		if ps.push && full == nil && err == nil {
+			if full, err = p.full(); err != nil {
+				log.Errorf("Getting complete state failed: %s", err)
+			}
+		}
+		if ps.push && err == nil {
+			msg.Deltas = append(msg.Deltas, full...)
+		}
+		msg.Deltas = append(msg.Deltas, ps.pending...)
+
+		// The flags are restored if sending fails.
+		ps.pull = false
+		ps.push = ps.push && err != nil
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			p.sent(name, msg, p.send(name, msg))
+		}(name)
+	}
+	p.mtx.Unlock()
+
+	wg.Wait()
+}
+
+func (p *Peer) send(name string, msg *Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", name+GossipPath, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.bearerToken)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// sent updates the state of a peer after the message was sent to it.
+func (p *Peer) sent(name string, msg *Message, err error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	ps := p.peers[name]
+
+	var (
+		full bool
+		seq  uint64
+	)
+	for _, d := range msg.Deltas {
+		if d.Full {
+			full = true
+		} else {
+			seq = d.Seq
+		}
+	}
+
+	if err != nil {
+		if ps.status.Healthy {
+			log.With("peer", name).Errorf("Sending gossip failed: %s", err)
+		}
+		gossipFailed.WithLabelValues(name).Inc()
+		ps.status.Healthy = false
+		ps.status.Error = err.Error()
+		ps.push = ps.push || full
+		ps.pull = ps.pull || msg.Pull
+		return
+	}
+	if !ps.status.Healthy {
+		log.With("peer", name).Infof("Sending gossip succeeded again")
+	}
+	ps.status.Healthy = true
+	ps.status.Error = ""
+	ps.status.LastSent = time.Now()
+
+	// Deltas broadcast while sending were appended after the sent ones
+	// and the oldest ones may have been dropped in the meantime, so the
+	// sent deltas are told apart by their sequence numbers.
+	n := 0
+	for n < len(ps.pending) && ps.pending[n].Seq <= seq {
+		n++
+	}
+	ps.pending = ps.pending[n:]
+	deltasSent.WithLabelValues(name).Add(float64(len(msg.Deltas)))
+	if full {
+		fullStatesSent.WithLabelValues(name).Inc()
+	}
+}
+
+// Stop the peer's background processing.
+func (p *Peer) Stop() {
+	if p == nil {
+		return
+	}
+	select {
+	case <-p.stopc:
+	default:
+		close(p.stopc)
+	}
+	<-p.done
+}
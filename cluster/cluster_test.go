@@ -0,0 +1,400 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestPeerPosition(t *testing.T) {
+	peers := []string{"http://am1:9093/", "http://am2:9093", "http://am3:9093"}
+
+	for i, name := range peers {
+		p := NewPeer(name, peers, time.Second, 15*time.Second)
+
+		if pos := p.Position(); pos != i {
+			t.Errorf("%s: expected position %d but got %d", name, i, pos)
+		}
+		if w := p.Wait(); w != time.Duration(i)*15*time.Second {
+			t.Errorf("%s: expected wait %s but got %s", name, time.Duration(i)*15*time.Second, w)
+		}
+	}
+
+	// Unhealthy peers are skipped.
+	p := NewPeer(peers[2], peers, time.Second, 15*time.Second)
+	p.sent("http://am1:9093", &Message{}, errors.New("connection refused"))
+
+	if pos := p.Position(); pos != 1 {
+		t.Errorf("expected position 1 with an unhealthy peer but got %d", pos)
+	}
+}
+
+func TestPeerSentDropped(t *testing.T) {
+	p := NewPeer("http://am1:9093", []string{"http://am2:9093"}, time.Second, time.Second)
+	peer := "http://am2:9093"
+
+	for i := 0; i < 3; i++ {
+		if err := p.Broadcast("silences", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	msg := &Message{From: p.Name(), Deltas: p.peers[peer].pending}
+
+	// The sent deltas are dropped while sending.
+	for i := 0; i < maxPending; i++ {
+		if err := p.Broadcast("silences", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.sent(peer, msg, nil)
+
+	if n := p.Status().Peers[0].Pending; n != maxPending {
+		t.Fatalf("expected %d pending deltas but got %d", maxPending, n)
+	}
+	if seq := p.peers[peer].pending[0].Seq; seq != 4 {
+		t.Errorf("expected first pending delta %d but got %d", 4, seq)
+	}
+	if !p.peers[peer].push {
+		t.Errorf("expected complete state to be sent after dropping deltas")
+	}
+}
+
+// newTestPeers returns two peers sending gossip to each other through
+// test servers.
+func newTestPeers(t *testing.T) (*Peer, *Peer, func()) {
+	var a, b *Peer
+
+	handler := func(p **Peer) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != GossipPath {
+				http.NotFound(w, r)
+				return
+			}
+			var msg Message
+			if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+				t.Fatal(err)
+			}
+			if err := (*p).Merge(&msg); err != nil {
+				t.Errorf("Merging failed: %s", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		})
+	}
+	sa := httptest.NewServer(handler(&a))
+	sb := httptest.NewServer(handler(&b))
+
+	peers := []string{sa.URL, sb.URL}
+	a = NewPeer(sa.URL, peers, time.Second, time.Second)
+	b = NewPeer(sb.URL, peers, time.Second, time.Second)
+
+	return a, b, func() {
+		sa.Close()
+		sb.Close()
+	}
+}
+
+func newSilences(t *testing.T, s provider.Silences, p *Peer) *Silences {
+	sils, err := NewSilences(s, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sils
+}
+
+func newEvents(t *testing.T, e provider.Events, p *Peer) *Events {
+	evs, err := NewEvents(e, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return evs
+}
+
+func TestSilencesReplication(t *testing.T) {
+	a, b, stop := newTestPeers(t)
+	defer stop()
+
+	var (
+		sa = newSilences(t, provider.NewMemSilences(), a)
+		sb = newSilences(t, provider.NewMemSilences(), b)
+		t0 = time.Now()
+	)
+
+	sil := types.NewSilence(&model.Silence{
+		Matchers: []*model.Matcher{
+			{Name: "key", Value: "val"},
+		},
+		StartsAt:  t0,
+		EndsAt:    t0.Add(time.Hour),
+		CreatedAt: t0,
+		CreatedBy: "user",
+		Comment:   "replicated",
+	}, &model.Matcher{Name: "severity", Value: "critical"})
+
+	// Create an unrelated silence first so that IDs differ between peers.
+	if _, err := sb.Set(types.NewSilence(&model.Silence{CreatedBy: "other"})); err != nil {
+		t.Fatal(err)
+	}
+	id, err := sa.Set(sil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.gossip()
+	b.gossip()
+
+	sils, err := sb.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sils) != 2 {
+		t.Fatalf("expected 2 silences but got %d", len(sils))
+	}
+	var replica *types.Silence
+	for _, s := range sils {
+		if s.Comment == "replicated" {
+			replica = s
+		}
+	}
+	if replica == nil {
+		t.Fatalf("silence was not replicated")
+	}
+	if !replica.Mutes(model.LabelSet{"key": "val"}) {
+		t.Errorf("expected replicated silence to mute")
+	}
+	if replica.Mutes(model.LabelSet{"key": "val", "severity": "critical"}) {
+		t.Errorf("expected exclude matchers to be replicated")
+	}
+
+	// Deleting the replica deletes the original, also after a restart
+	// as the key of the replica is stored with it.
+	sb = newSilences(t, sb.Silences, b)
+	if err := sb.Del(replica.ID); err != nil {
+		t.Fatal(err)
+	}
+	b.gossip()
+
+	if _, err := sa.Get(id); err != provider.ErrNotFound {
+		t.Fatalf("expected silence to be deleted but got error %v", err)
+	}
+	if s := a.Status(); s.Peers[0].Pending != 0 || !s.Peers[0].Healthy {
+		t.Fatalf("expected gossip to be sent but got status %+v", s.Peers[0])
+	}
+}
+
+func TestNotifiesMerge(t *testing.T) {
+	a, b, stop := newTestPeers(t)
+	defer stop()
+
+	var (
+		na = NewNotifies(provider.NewMemNotifies(provider.NewMemData()), a)
+		nb = NewNotifies(provider.NewMemNotifies(provider.NewMemData()), b)
+		t0 = time.Now()
+	)
+
+	newer := &types.NotifyInfo{Alert: 1, Receiver: "team", Timestamp: t0.Add(time.Minute)}
+	if err := nb.Set(newer); err != nil {
+		t.Fatal(err)
+	}
+	err := na.Set(
+		&types.NotifyInfo{Alert: 1, Receiver: "team", Timestamp: t0},
+		&types.NotifyInfo{Alert: 2, Receiver: "team", Timestamp: t0},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.gossip()
+
+	ns, err := nb.Get("team", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ns[0].Timestamp.Equal(newer.Timestamp) {
+		t.Errorf("expected newer notification to be kept but got %v", ns[0])
+	}
+	if ns[1] == nil || !ns[1].Timestamp.Equal(t0) {
+		t.Errorf("expected notification to be replicated but got %v", ns[1])
+	}
+}
+
+func TestEventsReplication(t *testing.T) {
+	a, b, stop := newTestPeers(t)
+	defer stop()
+
+	var (
+		ea = newEvents(t, provider.NewMemEvents(), a)
+		eb = newEvents(t, provider.NewMemEvents(), b)
+	)
+
+	event := &types.Event{Title: "Database outage", CreatedAt: time.Now()}
+	if _, err := ea.Set(event); err != nil {
+		t.Fatal(err)
+	}
+	a.gossip()
+
+	all, err := eb.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].Title != event.Title {
+		t.Fatalf("expected event to be replicated but got %v", all)
+	}
+
+	// Updates of the replica are replicated to the original, also after
+	// a restart as the key of the replica is stored with it.
+	eb = newEvents(t, eb.Events, b)
+	replica := all[0]
+	replica.Alerts = []string{"1"}
+	if err := eb.Update(replica); err != nil {
+		t.Fatal(err)
+	}
+	b.gossip()
+
+	got, err := ea.Get(event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Alerts) != 1 || got.Alerts[0] != "1" {
+		t.Fatalf("expected update to be replicated but got %v", got)
+	}
+
+	if err := ea.Delete(event.ID); err != nil {
+		t.Fatal(err)
+	}
+	a.gossip()
+
+	if all, err := eb.All(); err != nil || len(all) != 0 {
+		t.Fatalf("expected event to be deleted but got %v, %v", all, err)
+	}
+}
+
+func TestEventsParentReplication(t *testing.T) {
+	a, b, stop := newTestPeers(t)
+	defer stop()
+
+	var (
+		ea = newEvents(t, provider.NewMemEvents(), a)
+		eb = newEvents(t, provider.NewMemEvents(), b)
+	)
+	// Offset the local IDs of the peers.
+	if _, err := eb.Events.Set(&types.Event{Title: "Local"}); err != nil {
+		t.Fatal(err)
+	}
+
+	parent := &types.Event{Title: "Major incident"}
+	if _, err := ea.Set(parent); err != nil {
+		t.Fatal(err)
+	}
+	child := &types.Event{Title: "Database outage", ParentID: parent.ID}
+	if _, err := ea.Set(child); err != nil {
+		t.Fatal(err)
+	}
+	a.gossip()
+
+	all, err := eb.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 events but got %d", len(all))
+	}
+	replParent, replChild := all[1], all[2]
+
+	if replChild.ParentID != replParent.ID {
+		t.Fatalf("expected parent ID %d but got %d", replParent.ID, replChild.ParentID)
+	}
+	children, err := eb.Children(replParent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(children) != 1 || children[0].ID != replChild.ID {
+		t.Fatalf("expected replicated child but got %v", children)
+	}
+}
+
+func TestFullStateSync(t *testing.T) {
+	a, b, stop := newTestPeers(t)
+	defer stop()
+
+	var (
+		sa = newSilences(t, provider.NewMemSilences(), a)
+		sb = newSilences(t, provider.NewMemSilences(), b)
+		ea = newEvents(t, provider.NewMemEvents(), a)
+		eb = newEvents(t, provider.NewMemEvents(), b)
+	)
+
+	// State changed before the peers joined is exchanged as they join.
+	sid, err := sa.Silences.Set(types.NewSilence(&model.Silence{CreatedBy: "user"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eid, err := ea.Events.Set(&types.Event{Title: "Outage"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.gossip()
+
+	if sils, err := sb.All(); err != nil || len(sils) != 1 {
+		t.Fatalf("expected silence to be synced but got %v, %v", sils, err)
+	}
+	if events, err := eb.All(); err != nil || len(events) != 1 || events[0].Title != "Outage" {
+		t.Fatalf("expected event to be synced but got %v, %v", events, err)
+	}
+	b.mtx.RLock()
+	pushed := b.peers[a.Name()].push
+	b.mtx.RUnlock()
+	if !pushed {
+		t.Fatalf("expected peer to be asked for its complete state")
+	}
+
+	// Changes whose deltas were dropped are synced with the complete
+	// state, including deletions.
+	if err := sa.Silences.Del(sid); err != nil {
+		t.Fatal(err)
+	}
+	event, err := ea.Events.Get(eid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.Title = "Major outage"
+	event.UpdatedAt = time.Now()
+	if err := ea.Events.Update(event); err != nil {
+		t.Fatal(err)
+	}
+	a.mtx.Lock()
+	a.peers[b.Name()].push = true
+	a.mtx.Unlock()
+	a.gossip()
+
+	if sils, err := sb.All(); err != nil || len(sils) != 0 {
+		t.Fatalf("expected silence to be deleted but got %v, %v", sils, err)
+	}
+	if events, err := eb.All(); err != nil || len(events) != 1 || events[0].Title != "Major outage" {
+		t.Fatalf("expected event to be updated but got %v, %v", events, err)
+	}
+
+	// The complete state does not override later local changes.
+	b.gossip()
+	if event, err := ea.Events.Get(eid); err != nil || event.Title != "Major outage" {
+		t.Fatalf("expected event to be kept but got %v, %v", event, err)
+	}
+}
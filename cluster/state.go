@@ -0,0 +1,540 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// keyMap maps the cluster-wide keys of items to their local IDs. The keys
+// of replicated items are stored with them, from which the map is loaded.
+type keyMap struct {
+	self string
+
+	mtx  sync.RWMutex
+	ids  map[types.ReplicaKey]uint64
+	keys map[uint64]types.ReplicaKey
+}
+
+func newKeyMap(self string) *keyMap {
+	return &keyMap{
+		self: self,
+		ids:  map[types.ReplicaKey]uint64{},
+		keys: map[uint64]types.ReplicaKey{},
+	}
+}
+
+// key returns the cluster-wide key of a local ID. IDs of items not
+// received from peers are keyed by the local peer.
+func (m *keyMap) key(id uint64) types.ReplicaKey {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if k, ok := m.keys[id]; ok {
+		return k
+	}
+	return types.ReplicaKey{Origin: m.self, ID: id}
+}
+
+// replica returns the key of the local ID if the item was received from a
+// peer and nil otherwise.
+func (m *keyMap) replica(id uint64) *types.ReplicaKey {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if k, ok := m.keys[id]; ok {
+		return &k
+	}
+	return nil
+}
+
+// id returns the local ID of the key.
+func (m *keyMap) id(k types.ReplicaKey) (uint64, bool) {
+	if k.Origin == m.self {
+		return k.ID, true
+	}
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	id, ok := m.ids[k]
+	return id, ok
+}
+
+func (m *keyMap) set(k types.ReplicaKey, id uint64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.ids[k] = id
+	m.keys[id] = k
+}
+
+func (m *keyMap) del(id uint64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if k, ok := m.keys[id]; ok {
+		delete(m.ids, k)
+		delete(m.keys, id)
+	}
+}
+
+// silenceDelta is the replicated change of a silence.
+type silenceDelta struct {
+	Key     types.ReplicaKey `json:"key"`
+	Silence *types.Silence   `json:"silence,omitempty"`
+	Deleted bool             `json:"deleted,omitempty"`
+}
+
+// Silences is a Silences provider whose changes are replicated across the
+// cluster.
+type Silences struct {
+	provider.Silences
+
+	peer *Peer
+	keys *keyMap
+}
+
+// NewSilences returns Silences replicating changes of s through the peer.
+func NewSilences(s provider.Silences, p *Peer) (*Silences, error) {
+	sils := &Silences{
+		Silences: s,
+		peer:     p,
+		keys:     newKeyMap(p.Name()),
+	}
+	all, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, sil := range all {
+		if sil.Replica != nil {
+			sils.keys.set(*sil.Replica, sil.ID)
+		}
+	}
+	p.AddState("silences", sils)
+	return sils, nil
+}
+
+// Set implements the Silences interface.
+func (s *Silences) Set(sil *types.Silence) (uint64, error) {
+	// Silences set locally are keyed by the local peer.
+	sil.Replica = nil
+
+	id, err := s.Silences.Set(sil)
+	if err != nil {
+		return id, err
+	}
+	return id, s.peer.Broadcast("silences", &silenceDelta{
+		Key:     s.keys.key(id),
+		Silence: sil,
+	})
+}
+
+// Del implements the Silences interface.
+func (s *Silences) Del(id uint64) error {
+	if err := s.Silences.Del(id); err != nil {
+		return err
+	}
+	k := s.keys.key(id)
+	s.keys.del(id)
+
+	return s.peer.Broadcast("silences", &silenceDelta{
+		Key:     k,
+		Deleted: true,
+	})
+}
+
+// Merge implements the State interface.
+func (s *Silences) Merge(from string, b []byte) error {
+	var d silenceDelta
+	if err := json.Unmarshal(b, &d); err != nil {
+		return err
+	}
+	return s.merge(&d)
+}
+
+// Full implements the State interface.
+func (s *Silences) Full() (interface{}, error) {
+	sils, err := s.Silences.All()
+	if err != nil {
+		return nil, err
+	}
+	ds := make([]*silenceDelta, 0, len(sils))
+	for _, sil := range sils {
+		ds = append(ds, &silenceDelta{
+			Key:     s.keys.key(sil.ID),
+			Silence: sil,
+		})
+	}
+	return ds, nil
+}
+
+// MergeFull implements the State interface. Silences created by the peer
+// that it no longer has are deleted.
+func (s *Silences) MergeFull(from string, b []byte) error {
+	var ds []*silenceDelta
+	if err := json.Unmarshal(b, &ds); err != nil {
+		return err
+	}
+	keys := map[types.ReplicaKey]struct{}{}
+	for _, d := range ds {
+		keys[d.Key] = struct{}{}
+		if err := s.merge(d); err != nil {
+			return err
+		}
+	}
+
+	sils, err := s.Silences.All()
+	if err != nil {
+		return err
+	}
+	for _, sil := range sils {
+		k := s.keys.key(sil.ID)
+		if _, ok := keys[k]; ok || k.Origin != from {
+			continue
+		}
+		s.keys.del(sil.ID)
+		if err := s.Silences.Del(sil.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Silences) merge(d *silenceDelta) error {
+	id, ok := s.keys.id(d.Key)
+
+	if d.Deleted {
+		if !ok {
+			return nil
+		}
+		s.keys.del(id)
+		return s.Silences.Del(id)
+	}
+	// Silences are immutable and only need to be created once.
+	if ok || d.Silence == nil {
+		return nil
+	}
+	sil := types.RestoreSilence(d.Silence)
+	sil.ID = 0
+	sil.Replica = &d.Key
+
+	id, err := s.Silences.Set(sil)
+	if err != nil {
+		return err
+	}
+	s.keys.set(d.Key, id)
+	return nil
+}
+
+// Notifies is a Notifies provider whose notification log is replicated
+// across the cluster.
+type Notifies struct {
+	provider.Notifies
+
+	peer *Peer
+}
+
+// NewNotifies returns Notifies replicating changes of n through the peer.
+func NewNotifies(n provider.Notifies, p *Peer) *Notifies {
+	ns := &Notifies{
+		Notifies: n,
+		peer:     p,
+	}
+	p.AddState("notifies", ns)
+	return ns
+}
+
+// Set implements the Notifies interface.
+func (n *Notifies) Set(ns ...*types.NotifyInfo) error {
+	if err := n.Notifies.Set(ns...); err != nil {
+		return err
+	}
+	return n.peer.Broadcast("notifies", ns)
+}
+
+// Merge implements the State interface. Notifications older than the
+// locally known ones are ignored.
+func (n *Notifies) Merge(from string, b []byte) error {
+	var ns []*types.NotifyInfo
+	if err := json.Unmarshal(b, &ns); err != nil {
+		return err
+	}
+
+	byReceiver := map[string][]*types.NotifyInfo{}
+	for _, ni := range ns {
+		byReceiver[ni.Receiver] = append(byReceiver[ni.Receiver], ni)
+	}
+
+	var newer []*types.NotifyInfo
+
+	for rcv, ns := range byReceiver {
+		fps := make([]model.Fingerprint, 0, len(ns))
+		for _, ni := range ns {
+			fps = append(fps, ni.Alert)
+		}
+		last, err := n.Notifies.Get(rcv, fps...)
+		if err != nil {
+			return err
+		}
+		for i, ni := range ns {
+			if last[i] == nil || ni.Timestamp.After(last[i].Timestamp) {
+				newer = append(newer, ni)
+			}
+		}
+	}
+	if len(newer) == 0 {
+		return nil
+	}
+	return n.Notifies.Set(newer...)
+}
+
+// Full implements the State interface.
+func (n *Notifies) Full() (interface{}, error) {
+	return n.Notifies.All()
+}
+
+// MergeFull implements the State interface.
+func (n *Notifies) MergeFull(from string, b []byte) error {
+	return n.Merge(from, b)
+}
+
+// eventDelta is the replicated change of an event.
+type eventDelta struct {
+	Key     types.ReplicaKey `json:"key"`
+	Event   *types.Event     `json:"event,omitempty"`
+	Deleted bool             `json:"deleted,omitempty"`
+	// Parent is the key of the event's parent, whose local ID differs
+	// across peers.
+	Parent *types.ReplicaKey `json:"parent,omitempty"`
+}
+
+// Events is an Events provider whose changes are replicated across the
+// cluster.
+type Events struct {
+	provider.Events
+
+	peer *Peer
+	keys *keyMap
+}
+
+// NewEvents returns Events replicating changes of e through the peer.
+func NewEvents(e provider.Events, p *Peer) (*Events, error) {
+	evs := &Events{
+		Events: e,
+		peer:   p,
+		keys:   newKeyMap(p.Name()),
+	}
+	all, err := e.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range all {
+		if event.Replica != nil {
+			evs.keys.set(*event.Replica, event.ID)
+		}
+	}
+	p.AddState("events", evs)
+	return evs, nil
+}
+
+// Set implements the Events interface.
+func (e *Events) Set(event *types.Event) (uint64, error) {
+	// Events created locally are keyed by the local peer.
+	event.Replica = nil
+
+	id, err := e.Events.Set(event)
+	if err != nil {
+		return id, err
+	}
+	return id, e.peer.Broadcast("events", e.delta(event))
+}
+
+// Update implements the Events interface.
+func (e *Events) Update(event *types.Event) error {
+	event.Replica = e.keys.replica(event.ID)
+
+	if err := e.Events.Update(event); err != nil {
+		return err
+	}
+	return e.peer.Broadcast("events", e.delta(event))
+}
+
+// delta returns the replicated change of the event.
+func (e *Events) delta(event *types.Event) *eventDelta {
+	d := &eventDelta{
+		Key:   e.keys.key(event.ID),
+		Event: event,
+	}
+	if event.ParentID != 0 {
+		k := e.keys.key(event.ParentID)
+		d.Parent = &k
+	}
+	return d
+}
+
+// Delete implements the Events interface.
+func (e *Events) Delete(id uint64) error {
+	if err := e.Events.Delete(id); err != nil {
+		return err
+	}
+	k := e.keys.key(id)
+	e.keys.del(id)
+
+	return e.peer.Broadcast("events", &eventDelta{
+		Key:     k,
+		Deleted: true,
+	})
+}
+
+// Merge implements the State interface. Versions are local to each peer,
+// so concurrent updates of an event on different peers are resolved by
+// applying them in the order they are received.
+func (e *Events) Merge(from string, b []byte) error {
+	var d eventDelta
+	if err := json.Unmarshal(b, &d); err != nil {
+		return err
+	}
+	return e.merge(&d)
+}
+
+// Full implements the State interface.
+func (e *Events) Full() (interface{}, error) {
+	events, err := e.Events.All()
+	if err != nil {
+		return nil, err
+	}
+	ds := make([]*eventDelta, 0, len(events))
+	for _, event := range events {
+		ds = append(ds, e.delta(event))
+	}
+	return ds, nil
+}
+
+// MergeFull implements the State interface. Events known locally are only
+// updated if the peer's version was updated later, and events created by
+// the peer that it no longer has are deleted.
+func (e *Events) MergeFull(from string, b []byte) error {
+	var ds []*eventDelta
+	if err := json.Unmarshal(b, &ds); err != nil {
+		return err
+	}
+	var (
+		keys    = map[types.ReplicaKey]struct{}{}
+		orphans []*eventDelta
+	)
+	for _, d := range ds {
+		keys[d.Key] = struct{}{}
+		if d.Event == nil {
+			continue
+		}
+		if id, ok := e.keys.id(d.Key); ok {
+			cur, err := e.Events.Get(id)
+			if err != nil && err != provider.ErrNotFound {
+				return err
+			}
+			if err == nil && !d.Event.UpdatedAt.After(cur.UpdatedAt) {
+				continue
+			}
+		}
+		// Parents may follow their children.
+		if d.Parent != nil {
+			if _, ok := e.keys.id(*d.Parent); !ok {
+				orphans = append(orphans, d)
+			}
+		}
+		if err := e.merge(d); err != nil {
+			return err
+		}
+	}
+	for _, d := range orphans {
+		if err := e.merge(d); err != nil {
+			return err
+		}
+	}
+
+	events, err := e.Events.All()
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		k := e.keys.key(event.ID)
+		if _, ok := keys[k]; ok || k.Origin != from {
+			continue
+		}
+		e.keys.del(event.ID)
+		if err := e.Events.Delete(event.ID); err != nil && err != provider.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Events) merge(d *eventDelta) error {
+	id, ok := e.keys.id(d.Key)
+
+	if d.Deleted {
+		if !ok {
+			return nil
+		}
+		e.keys.del(id)
+		if err := e.Events.Delete(id); err != provider.ErrNotFound {
+			return err
+		}
+		return nil
+	}
+	if d.Event == nil {
+		return nil
+	}
+	event := *d.Event
+
+	// Parents that are unknown locally are dropped.
+	event.ParentID = 0
+	if d.Parent != nil {
+		if pid, ok := e.keys.id(*d.Parent); ok {
+			event.ParentID = pid
+		}
+	}
+
+	if ok {
+		cur, err := e.Events.Get(id)
+		if err == nil {
+			event.ID = id
+			event.Version = cur.Version
+			event.Replica = e.keys.replica(id)
+			return e.Events.Update(&event)
+		}
+		if err != provider.ErrNotFound {
+			return err
+		}
+		log.With("event", id).Debugf("Replicated event was deleted locally, recreating it")
+	}
+
+	event.ID = 0
+	event.Version = 0
+	event.Replica = &d.Key
+
+	id, err := e.Events.Set(&event)
+	if err != nil {
+		return err
+	}
+	e.keys.set(d.Key, id)
+	return nil
+}
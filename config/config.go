@@ -17,6 +17,8 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net/url"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -26,7 +28,7 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-var patAuthLine = regexp.MustCompile(`((?:api_key|service_key|api_url|token|user_key|password|secret):\s+)(".+"|'.+'|[^\s]+)`)
+var patAuthLine = regexp.MustCompile(`((?:api_key|service_key|api_url|webhook_url|token|user_key|password|secret):\s+)(".+"|'.+'|[^\s]+)`)
 
 // Secret is a string that must not be revealed on marshaling.
 type Secret string
@@ -82,15 +84,32 @@ func resolveFilepaths(baseDir string, cfg *Config) {
 	for i, tf := range cfg.Templates {
 		cfg.Templates[i] = join(tf)
 	}
+	for _, rcv := range cfg.Receivers {
+		if hc := rcv.HTTPConfig; hc != nil {
+			hc.TLSConfig.CAFile = join(hc.TLSConfig.CAFile)
+			hc.TLSConfig.CertFile = join(hc.TLSConfig.CertFile)
+			hc.TLSConfig.KeyFile = join(hc.TLSConfig.KeyFile)
+		}
+	}
 }
 
 // Config is the top-level configuration for Alertmanager's config files.
 type Config struct {
-	Global       *GlobalConfig  `yaml:"global,omitempty"`
-	Route        *Route         `yaml:"route,omitempty"`
-	InhibitRules []*InhibitRule `yaml:"inhibit_rules,omitempty"`
-	Receivers    []*Receiver    `yaml:"receivers,omitempty"`
-	Templates    []string       `yaml:"templates"`
+	Global           *GlobalConfig      `yaml:"global,omitempty"`
+	Route            *Route             `yaml:"route,omitempty"`
+	InhibitRules     []*InhibitRule     `yaml:"inhibit_rules,omitempty"`
+	DuplicateRules   []*DuplicateRule   `yaml:"duplicate_rules,omitempty"`
+	CorrelationRules []*CorrelationRule `yaml:"correlation_rules,omitempty"`
+	EventTemplates   []*EventTemplate   `yaml:"event_templates,omitempty"`
+	EnrichmentRules  []*EnrichmentRule  `yaml:"enrichment_rules,omitempty"`
+	FlapDetection    *FlapDetection     `yaml:"flap_detection,omitempty"`
+	APIAuth          *APIAuth           `yaml:"api_auth,omitempty"`
+	Tenancy          *Tenancy           `yaml:"tenancy,omitempty"`
+	EventHooks       []*EventHook       `yaml:"event_hooks,omitempty"`
+	PagerdutyWebhook *PagerdutyWebhook  `yaml:"pagerduty_webhook,omitempty"`
+	SlackActions     *SlackActions      `yaml:"slack_actions,omitempty"`
+	Receivers        []*Receiver        `yaml:"receivers,omitempty"`
+	Templates        []string           `yaml:"templates"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline"`
@@ -180,6 +199,9 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 				}
 				sc.APIURL = c.Global.SlackAPIURL
 			}
+			if sc.Actions && c.SlackActions == nil {
+				return fmt.Errorf("Slack actions require the slack_actions section")
+			}
 		}
 		for _, hc := range rcv.HipchatConfigs {
 			if hc.APIURL == "" {
@@ -198,6 +220,11 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 				hc.AuthToken = c.Global.HipchatAuthToken
 			}
 		}
+		for _, wc := range rcv.WebhookConfigs {
+			if wc.GroupKeyFormat == "" {
+				wc.GroupKeyFormat = c.Global.GroupKeyFormat
+			}
+		}
 		for _, pdc := range rcv.PagerdutyConfigs {
 			if pdc.URL == "" {
 				if c.Global.PagerdutyURL == "" {
@@ -205,6 +232,9 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 				}
 				pdc.URL = c.Global.PagerdutyURL
 			}
+			if pdc.GroupKeyFormat == "" {
+				pdc.GroupKeyFormat = c.Global.GroupKeyFormat
+			}
 		}
 		for _, ogc := range rcv.OpsGenieConfigs {
 			if ogc.APIHost == "" {
@@ -216,6 +246,9 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 			if !strings.HasSuffix(ogc.APIHost, "/") {
 				ogc.APIHost += "/"
 			}
+			if ogc.GroupKeyFormat == "" {
+				ogc.GroupKeyFormat = c.Global.GroupKeyFormat
+			}
 		}
 		names[rcv.Name] = struct{}{}
 	}
@@ -225,7 +258,7 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if c.Route == nil {
 		return fmt.Errorf("No routes provided")
 	}
-	if len(c.Route.Match) > 0 || len(c.Route.MatchRE) > 0 {
+	if len(c.Route.Match) > 0 || len(c.Route.MatchRE) > 0 || len(c.Route.MatchIn) > 0 || len(c.Route.MatchNotRE) > 0 {
 		return fmt.Errorf("Root route must not have any matchers")
 	}
 
@@ -233,6 +266,9 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := checkReceiver(c.Route, names); err != nil {
 		return err
 	}
+	if err := checkFallbacks(c.Receivers); err != nil {
+		return err
+	}
 
 	return checkOverflow(c.XXX, "config")
 }
@@ -243,6 +279,11 @@ func checkReceiver(r *Route, receivers map[string]struct{}) error {
 	if _, ok := receivers[r.Receiver]; !ok {
 		return fmt.Errorf("Undefined receiver %q used in route", r.Receiver)
 	}
+	for _, es := range r.Escalation {
+		if _, ok := receivers[es.Receiver]; !ok {
+			return fmt.Errorf("Undefined receiver %q used in escalation", es.Receiver)
+		}
+	}
 	for _, sr := range r.Routes {
 		if err := checkReceiver(sr, receivers); err != nil {
 			return err
@@ -251,9 +292,34 @@ func checkReceiver(r *Route, receivers map[string]struct{}) error {
 	return nil
 }
 
+// checkFallbacks returns an error if a receiver falls back to an undefined
+// receiver or if a chain of fallbacks leads back to a receiver in it.
+func checkFallbacks(rcvs []*Receiver) error {
+	fallbacks := map[string]string{}
+	for _, rcv := range rcvs {
+		fallbacks[rcv.Name] = rcv.Fallback
+	}
+	for _, rcv := range rcvs {
+		seen := map[string]struct{}{rcv.Name: struct{}{}}
+
+		for name := rcv.Fallback; name != ""; name = fallbacks[name] {
+			if _, ok := fallbacks[name]; !ok {
+				return fmt.Errorf("Undefined receiver %q used as fallback", name)
+			}
+			if _, ok := seen[name]; ok {
+				return fmt.Errorf("Fallbacks of receiver %q form a cycle", rcv.Name)
+			}
+			seen[name] = struct{}{}
+		}
+	}
+	return nil
+}
+
 // DefaultGlobalConfig provides global default values.
 var DefaultGlobalConfig = GlobalConfig{
-	ResolveTimeout: model.Duration(5 * time.Minute),
+	ResolveTimeout:  model.Duration(5 * time.Minute),
+	LateAlertPolicy: LateAlertNotify,
+	GroupKeyFormat:  GroupKeyLegacy,
 
 	PagerdutyURL:    "https://events.pagerduty.com/generic/2010-04-15/create_event.json",
 	HipchatURL:      "https://api.hipchat.com/",
@@ -266,6 +332,21 @@ type GlobalConfig struct {
 	// ResolveTimeout is the time after which an alert is declared resolved
 	// if it has not been updated.
 	ResolveTimeout model.Duration `yaml:"resolve_timeout"`
+	// EnforceResolveTimeout makes the dispatcher resolve alerts whose end
+	// time was set by the resolve timeout and that have not been updated
+	// within it.
+	EnforceResolveTimeout bool `yaml:"enforce_resolve_timeout,omitempty"`
+
+	// LateAlertThreshold is the age of an alert's start time at which the
+	// alert is considered to arrive late, e.g. after a network partition
+	// healed. Zero disables the detection of late alerts.
+	LateAlertThreshold model.Duration `yaml:"late_alert_threshold"`
+	// LateAlertPolicy defines how late alerts are handled.
+	LateAlertPolicy LateAlertPolicy `yaml:"late_alert_policy"`
+
+	// GroupKeyFormat is the default group key format of webhook,
+	// PagerDuty, and OpsGenie notifications.
+	GroupKeyFormat GroupKeyFormat `yaml:"group_key_format"`
 
 	SMTPFrom         string `yaml:"smtp_from"`
 	SMTPSmarthost    string `yaml:"smtp_smarthost"`
@@ -278,28 +359,138 @@ type GlobalConfig struct {
 	HipchatURL       string `yaml:"hipchat_url"`
 	HipchatAuthToken Secret `yaml:"hipchat_auth_token"`
 	OpsGenieAPIHost  string `yaml:"opsgenie_api_host"`
+
+	// Severities maps severity label values to the color, emoji, and
+	// priority notification templates present them with.
+	Severities map[string]*SeverityStyle `yaml:"severities,omitempty"`
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
 func (c *GlobalConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	*c = DefaultGlobalConfig
 	type plain GlobalConfig
-	return unmarshal((*plain)(c))
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	switch c.LateAlertPolicy {
+	case LateAlertNotify, LateAlertSuppressResolved:
+	default:
+		return fmt.Errorf("unknown late_alert_policy %q", c.LateAlertPolicy)
+	}
+	if err := checkGroupKeyFormat(c.GroupKeyFormat); err != nil {
+		return err
+	}
+	for sev, st := range c.Severities {
+		if st == nil {
+			return fmt.Errorf("missing style of severity %q", sev)
+		}
+	}
+	return nil
+}
+
+// SeverityStyle defines how notifications present alerts of a severity.
+// It is exposed to all notification templates through the severityColor,
+// severityEmoji, and severityPriority functions.
+type SeverityStyle struct {
+	Color    string `yaml:"color,omitempty"`
+	Emoji    string `yaml:"emoji,omitempty"`
+	Priority int    `yaml:"priority,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (s *SeverityStyle) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SeverityStyle
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	return checkOverflow(s.XXX, "severity style")
+}
+
+// LateAlertPolicy defines how to handle alerts whose start time lies
+// further in the past than the late alert threshold.
+type LateAlertPolicy string
+
+// Possible late alert policies.
+const (
+	// Notify about late alerts as usual.
+	LateAlertNotify LateAlertPolicy = "notify"
+	// Drop late alerts that are already resolved and were never received
+	// while firing.
+	LateAlertSuppressResolved LateAlertPolicy = "suppress_resolved"
+)
+
+// GroupKeyFormat defines how notifications identify their aggregation
+// group to webhooks, PagerDuty, and OpsGenie.
+type GroupKeyFormat string
+
+// Possible group key formats.
+const (
+	// Identify groups by the fingerprint of their labels as earlier
+	// versions did. Groups with the same labels on different routes share
+	// it. Webhooks receive version 3 of the payload.
+	GroupKeyLegacy GroupKeyFormat = "legacy"
+	// Identify groups by their string group key, which is unique across
+	// routes. Webhooks receive version 4 of the payload, PagerDuty and
+	// OpsGenie the SHA-256 digest of the key.
+	GroupKeyString GroupKeyFormat = "string"
+)
+
+func checkGroupKeyFormat(f GroupKeyFormat) error {
+	switch f {
+	case "", GroupKeyLegacy, GroupKeyString:
+		return nil
+	}
+	return fmt.Errorf("unknown group_key_format %q", f)
 }
 
 // A Route is a node that contains definitions of how to handle alerts.
 type Route struct {
-	Receiver string            `yaml:"receiver,omitempty"`
-	GroupBy  []model.LabelName `yaml:"group_by,omitempty"`
+	Receiver   string   `yaml:"receiver,omitempty"`
+	GroupByStr []string `yaml:"group_by,omitempty"`
+
+	// GroupBy holds the labels of group_by unless it is the special
+	// value ['...'], which groups by all labels and sets GroupByAll.
+	GroupBy    []model.LabelName `yaml:"-"`
+	GroupByAll bool              `yaml:"-"`
 
 	Match    map[string]string `yaml:"match,omitempty"`
 	MatchRE  map[string]Regexp `yaml:"match_re,omitempty"`
 	Continue bool              `yaml:"continue,omitempty"`
 	Routes   []*Route          `yaml:"routes,omitempty"`
 
+	// MatchIn defines labels whose value has to be one of the given values.
+	MatchIn map[string][]string `yaml:"match_in,omitempty"`
+	// MatchNotRE defines labels whose value must not match the given
+	// regular expressions.
+	MatchNotRE map[string]Regexp `yaml:"match_not_re,omitempty"`
+
 	GroupWait      *model.Duration `yaml:"group_wait,omitempty"`
 	GroupInterval  *model.Duration `yaml:"group_interval,omitempty"`
 	RepeatInterval *model.Duration `yaml:"repeat_interval,omitempty"`
+	// Maximum random delay added to the group wait and group interval of
+	// each alert group so that groups do not flush at the same time.
+	GroupJitter *model.Duration `yaml:"group_jitter,omitempty"`
+	// If set, resolved notifications are only sent for alerts that were
+	// notified about as firing and stayed resolved for the given duration.
+	SendResolvedAfter *model.Duration `yaml:"send_resolved_after,omitempty"`
+
+	// Time intervals during which notifications for the route are held back.
+	MuteTimeIntervals []*TimeInterval `yaml:"mute_time_intervals,omitempty"`
+
+	// Receivers to switch to if alert groups of the route keep firing.
+	Escalation []*EscalationStep `yaml:"escalation,omitempty"`
+
+	// Template of the HTML body of email notifications for alert groups
+	// of the route, replacing the one of the email configs.
+	EmailHTML string `yaml:"email_html,omitempty"`
+
+	// Priority of the notifications of alert groups of the route. Groups
+	// of routes with a higher priority are notified first when all notify
+	// workers are busy.
+	Priority *int `yaml:"priority,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline"`
@@ -324,18 +515,250 @@ func (r *Route) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		}
 	}
 
+	for k, vs := range r.MatchIn {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+		if len(vs) == 0 {
+			return fmt.Errorf("no values given in match_in for label %q", k)
+		}
+	}
+
+	for k := range r.MatchNotRE {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	if r.GroupJitter != nil && *r.GroupJitter < 0 {
+		return fmt.Errorf("group_jitter must not be negative")
+	}
+	if r.SendResolvedAfter != nil && *r.SendResolvedAfter < 0 {
+		return fmt.Errorf("send_resolved_after must not be negative")
+	}
+
 	groupBy := map[model.LabelName]struct{}{}
 
-	for _, ln := range r.GroupBy {
+	r.GroupBy, r.GroupByAll = nil, false
+	if r.GroupByStr != nil {
+		r.GroupBy = make([]model.LabelName, 0, len(r.GroupByStr))
+	}
+	for _, l := range r.GroupByStr {
+		if l == "..." {
+			r.GroupByAll = true
+			continue
+		}
+		ln := model.LabelName(l)
+		if !ln.IsValid() {
+			return fmt.Errorf("invalid label name %q in group_by", l)
+		}
 		if _, ok := groupBy[ln]; ok {
 			return fmt.Errorf("duplicated label %q in group_by", ln)
 		}
 		groupBy[ln] = struct{}{}
+		r.GroupBy = append(r.GroupBy, ln)
+	}
+	if r.GroupByAll && len(r.GroupByStr) > 1 {
+		return fmt.Errorf("cannot have wildcard group_by (`...`) and other labels at the same time")
+	}
+
+	for i := 1; i < len(r.Escalation); i++ {
+		if r.Escalation[i].AfterRepeatIntervals <= r.Escalation[i-1].AfterRepeatIntervals {
+			return fmt.Errorf("escalation steps must be in increasing order of after_repeat_intervals")
+		}
 	}
 
 	return checkOverflow(r.XXX, "route")
 }
 
+// EscalationStep switches notifications of an alert group to another
+// receiver once the group has been firing and unacknowledged for the given
+// number of repeat intervals.
+type EscalationStep struct {
+	Receiver             string `yaml:"receiver" json:"receiver"`
+	AfterRepeatIntervals int    `yaml:"after_repeat_intervals" json:"afterRepeatIntervals"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (es *EscalationStep) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain EscalationStep
+	if err := unmarshal((*plain)(es)); err != nil {
+		return err
+	}
+	if es.Receiver == "" {
+		return fmt.Errorf("missing receiver in escalation step")
+	}
+	if es.AfterRepeatIntervals <= 0 {
+		return fmt.Errorf("after_repeat_intervals of escalation step must be positive")
+	}
+	return checkOverflow(es.XXX, "escalation step")
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// TimeInterval describes a recurring period of time by a set of weekdays
+// and times of the day in a given location.
+type TimeInterval struct {
+	// Weekdays are single days like 'monday' or inclusive ranges like
+	// 'monday:friday'. If empty, all days match.
+	Weekdays []string `yaml:"weekdays,omitempty" json:"weekdays,omitempty"`
+	// Times are ranges of the day. If empty, the whole day matches.
+	Times []TimeRange `yaml:"times,omitempty" json:"times,omitempty"`
+	// Location is the name of the time zone the interval is evaluated in.
+	// Defaults to UTC.
+	Location string `yaml:"location,omitempty" json:"location,omitempty"`
+
+	days     map[time.Weekday]struct{}
+	location *time.Location
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (ti *TimeInterval) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain TimeInterval
+	if err := unmarshal((*plain)(ti)); err != nil {
+		return err
+	}
+
+	ti.days = map[time.Weekday]struct{}{}
+
+	for _, wd := range ti.Weekdays {
+		rng := strings.SplitN(strings.ToLower(wd), ":", 2)
+
+		start, ok := weekdays[rng[0]]
+		if !ok {
+			return fmt.Errorf("invalid weekday %q", rng[0])
+		}
+		end := start
+		if len(rng) == 2 {
+			if end, ok = weekdays[rng[1]]; !ok {
+				return fmt.Errorf("invalid weekday %q", rng[1])
+			}
+		}
+		for d := start; ; d = (d + 1) % 7 {
+			ti.days[d] = struct{}{}
+			if d == end {
+				break
+			}
+		}
+	}
+
+	ti.location = time.UTC
+	if ti.Location != "" {
+		loc, err := time.LoadLocation(ti.Location)
+		if err != nil {
+			return fmt.Errorf("invalid location %q: %s", ti.Location, err)
+		}
+		ti.location = loc
+	}
+
+	return checkOverflow(ti.XXX, "time interval")
+}
+
+// Contains returns true iff the given time lies within the interval.
+func (ti *TimeInterval) Contains(t time.Time) bool {
+	if ti.location != nil {
+		t = t.In(ti.location)
+	}
+	if len(ti.Times) == 0 {
+		return ti.onDay(t.Weekday())
+	}
+	for _, tr := range ti.Times {
+		if day, ok := tr.contains(t); ok && ti.onDay(day) {
+			return true
+		}
+	}
+	return false
+}
+
+// onDay returns true iff the interval applies to the given weekday.
+func (ti *TimeInterval) onDay(day time.Weekday) bool {
+	if len(ti.days) == 0 {
+		return true
+	}
+	_, ok := ti.days[day]
+	return ok
+}
+
+// TimeRange is a range of the day in the format HH:MM. The start time
+// is inclusive and the end time exclusive. If the end lies before the
+// start, the range wraps around midnight and the part after midnight
+// belongs to the weekday the range started on.
+type TimeRange struct {
+	StartTime string `yaml:"start_time" json:"startTime"`
+	EndTime   string `yaml:"end_time" json:"endTime"`
+
+	start, end int
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (tr *TimeRange) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain TimeRange
+	if err := unmarshal((*plain)(tr)); err != nil {
+		return err
+	}
+
+	var err error
+	if tr.start, err = parseTimeOfDay(tr.StartTime, false); err != nil {
+		return err
+	}
+	if tr.end, err = parseTimeOfDay(tr.EndTime, true); err != nil {
+		return err
+	}
+	if tr.start == tr.end {
+		return fmt.Errorf("time range %s-%s is empty", tr.StartTime, tr.EndTime)
+	}
+	return checkOverflow(tr.XXX, "time range")
+}
+
+// contains returns whether the given time lies within the range and the
+// weekday the range started on.
+func (tr *TimeRange) contains(t time.Time) (time.Weekday, bool) {
+	var (
+		m   = t.Hour()*60 + t.Minute()
+		day = t.Weekday()
+	)
+	if tr.start < tr.end {
+		return day, m >= tr.start && m < tr.end
+	}
+	if m < tr.end {
+		return (day + 6) % 7, true
+	}
+	return day, m >= tr.start
+}
+
+// parseTimeOfDay parses a HH:MM string into minutes since midnight.
+// End times may be given as 24:00 for the end of the day.
+func parseTimeOfDay(s string, end bool) (int, error) {
+	if s == "24:00" {
+		if !end {
+			return 0, fmt.Errorf("invalid start time %q, use 00:00 instead", s)
+		}
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
 // InhibitRule defines an inhibition rule that mutes alerts that match the
 // target labels if an alert matching the source labels exists.
 // Both alerts have to have a set of labels being equal.
@@ -394,6 +817,517 @@ func (r *InhibitRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return checkOverflow(r.XXX, "inhibit rule")
 }
 
+// DuplicateRule defines a rule that duplicates alerts matching a set of
+// labels into a new alert with modified labels. This allows for escalation
+// to be expressed through the routing tree.
+type DuplicateRule struct {
+	// Match defines a set of labels that have to equal the given
+	// value for alerts to be duplicated.
+	Match map[string]string `yaml:"match,omitempty"`
+	// MatchRE defines pairs like Match but does regular expression
+	// matching.
+	MatchRE map[string]Regexp `yaml:"match_re,omitempty"`
+	// After is the duration a matching alert has to be firing before
+	// it is duplicated.
+	After model.Duration `yaml:"after,omitempty"`
+	// Labels are set on the duplicate and overwrite existing labels
+	// of the same name.
+	Labels model.LabelSet `yaml:"labels"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *DuplicateRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain DuplicateRule
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+
+	for k := range r.Match {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	for k := range r.MatchRE {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	if len(r.Labels) == 0 {
+		return fmt.Errorf("missing labels in duplicate rule")
+	}
+	if err := r.Labels.Validate(); err != nil {
+		return err
+	}
+
+	return checkOverflow(r.XXX, "duplicate rule")
+}
+
+// EnrichmentRule defines annotations that are added to alerts matching a
+// set of labels before they are notified about.
+type EnrichmentRule struct {
+	// Match defines a set of labels that have to equal the given
+	// value for alerts to be enriched.
+	Match map[string]string `yaml:"match,omitempty"`
+	// MatchRE defines pairs like Match but does regular expression
+	// matching.
+	MatchRE map[string]Regexp `yaml:"match_re,omitempty"`
+	// Annotations are templates executed with the alert whose results are
+	// added as annotations of the same name.
+	Annotations map[string]string `yaml:"annotations"`
+	// If true, annotations the alert already has are replaced.
+	Overwrite bool `yaml:"overwrite,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *EnrichmentRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain EnrichmentRule
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+
+	for k := range r.Match {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	for k := range r.MatchRE {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	if len(r.Annotations) == 0 {
+		return fmt.Errorf("missing annotations in enrichment rule")
+	}
+	for k := range r.Annotations {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid annotation name %q", k)
+		}
+	}
+
+	return checkOverflow(r.XXX, "enrichment rule")
+}
+
+// CorrelationRule defines a rule that attaches alerts matching a set of
+// labels to open events matching another set of labels.
+type CorrelationRule struct {
+	// EventMatch defines a set of labels that have to equal the given
+	// value for events.
+	EventMatch map[string]string `yaml:"event_match,omitempty"`
+	// EventMatchRE defines pairs like EventMatch but does regular expression
+	// matching.
+	EventMatchRE map[string]Regexp `yaml:"event_match_re,omitempty"`
+	// AlertMatch defines a set of labels that have to equal the given
+	// value for alerts.
+	AlertMatch map[string]string `yaml:"alert_match,omitempty"`
+	// AlertMatchRE defines pairs like AlertMatch but does regular expression
+	// matching.
+	AlertMatchRE map[string]Regexp `yaml:"alert_match_re,omitempty"`
+	// A set of labels that must be equal between the event and the alert
+	// for them to be correlated.
+	Equal model.LabelNames `yaml:"equal,omitempty"`
+	// Window is the maximum duration between the creation of the event and
+	// the start of the alert. If unset, alerts are correlated as long as
+	// the event is open.
+	Window model.Duration `yaml:"window,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *CorrelationRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain CorrelationRule
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+
+	for k := range r.EventMatch {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	for k := range r.EventMatchRE {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	for k := range r.AlertMatch {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	for k := range r.AlertMatchRE {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	return checkOverflow(r.XXX, "correlation rule")
+}
+
+// EventTemplate defines an event that is created when an alert group
+// containing a matching alert fires for the first time. Its title,
+// description, and label values are templated.
+type EventTemplate struct {
+	// Match defines a set of labels that have to equal the given value
+	// for an alert of the group.
+	Match map[string]string `yaml:"match,omitempty"`
+	// MatchRE defines pairs like Match but does regular expression
+	// matching.
+	MatchRE map[string]Regexp `yaml:"match_re,omitempty"`
+
+	Title       string            `yaml:"title"`
+	Description string            `yaml:"description,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (t *EventTemplate) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain EventTemplate
+	if err := unmarshal((*plain)(t)); err != nil {
+		return err
+	}
+	if t.Title == "" {
+		return fmt.Errorf("missing title in event template")
+	}
+
+	for k := range t.Match {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	for k := range t.MatchRE {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	for k := range t.Labels {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	return checkOverflow(t.XXX, "event template")
+}
+
+// FlapDetection configures the dampening of alerts that repeatedly
+// change between firing and resolved.
+type FlapDetection struct {
+	// An alert is flapping if it changed its state more than Threshold
+	// times within Window.
+	Threshold int            `yaml:"threshold"`
+	Window    model.Duration `yaml:"window"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (f *FlapDetection) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain FlapDetection
+	if err := unmarshal((*plain)(f)); err != nil {
+		return err
+	}
+	if f.Threshold <= 0 {
+		return fmt.Errorf("flap detection threshold must be positive")
+	}
+	if f.Window <= 0 {
+		return fmt.Errorf("flap detection window must be positive")
+	}
+	return checkOverflow(f.XXX, "flap detection config")
+}
+
+// eventStatuses are the statuses of an event's lifecycle.
+var eventStatuses = map[string]struct{}{
+	"open":         {},
+	"acknowledged": {},
+	"mitigated":    {},
+	"resolved":     {},
+}
+
+// DefaultEventHook provides the default values of event hooks.
+var DefaultEventHook = EventHook{
+	Timeout: model.Duration(10 * time.Second),
+}
+
+// EventHook is an HTTP endpoint that is sent a POST request for each
+// transition of an event between statuses.
+type EventHook struct {
+	URL string `yaml:"url"`
+	// Statuses restricts the requests to transitions into the given
+	// statuses. If empty, all transitions are sent.
+	Statuses []string `yaml:"statuses,omitempty"`
+	// Timeout of a single request.
+	Timeout model.Duration `yaml:"timeout,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (h *EventHook) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*h = DefaultEventHook
+	type plain EventHook
+	if err := unmarshal((*plain)(h)); err != nil {
+		return err
+	}
+	if h.URL == "" {
+		return fmt.Errorf("missing URL in event hook")
+	}
+	if _, err := url.Parse(h.URL); err != nil {
+		return fmt.Errorf("invalid event hook URL %q: %s", h.URL, err)
+	}
+	for _, s := range h.Statuses {
+		if _, ok := eventStatuses[s]; !ok {
+			return fmt.Errorf("unknown event status %q in event hook", s)
+		}
+	}
+	if h.Timeout <= 0 {
+		return fmt.Errorf("event hook timeout must be positive")
+	}
+	return checkOverflow(h.XXX, "event hook")
+}
+
+// DefaultPagerdutyWebhook provides the default values of the PagerDuty
+// webhook.
+var DefaultPagerdutyWebhook = PagerdutyWebhook{
+	SilenceDuration: model.Duration(4 * time.Hour),
+}
+
+// PagerdutyWebhook configures the endpoint receiving PagerDuty V3 webhooks.
+// Incidents acknowledged in PagerDuty acknowledge the alerts of the group
+// they were triggered for. Incidents resolved in PagerDuty while alerts of
+// the group are still firing silence the group.
+type PagerdutyWebhook struct {
+	// Secret of the webhook subscription signing the requests.
+	Secret Secret `yaml:"secret"`
+	// SilenceDuration is how long groups of resolved incidents are silenced.
+	SilenceDuration model.Duration `yaml:"silence_duration,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (w *PagerdutyWebhook) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*w = DefaultPagerdutyWebhook
+	type plain PagerdutyWebhook
+	if err := unmarshal((*plain)(w)); err != nil {
+		return err
+	}
+	if w.Secret == "" {
+		return fmt.Errorf("missing secret in PagerDuty webhook")
+	}
+	if w.SilenceDuration <= 0 {
+		return fmt.Errorf("PagerDuty webhook silence duration must be positive")
+	}
+	return checkOverflow(w.XXX, "pagerduty webhook")
+}
+
+// SlackActions configures the endpoint receiving the callbacks of the
+// buttons in Slack notifications.
+type SlackActions struct {
+	// Signing secret of the Slack app the buttons belong to.
+	SigningSecret Secret `yaml:"signing_secret"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (a *SlackActions) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SlackActions
+	if err := unmarshal((*plain)(a)); err != nil {
+		return err
+	}
+	if a.SigningSecret == "" {
+		return fmt.Errorf("missing signing secret in Slack actions")
+	}
+	return checkOverflow(a.XXX, "slack actions")
+}
+
+// APIRole is a role granting access to the API. Each role grants the
+// access of the roles ranked lower.
+type APIRole string
+
+// The roles in increasing rank.
+const (
+	// APIRoleRead grants read-only access.
+	APIRoleRead APIRole = "read"
+	// APIRoleSilence additionally grants creating and deleting silences
+	// and acknowledging alerts and events.
+	APIRoleSilence APIRole = "silence"
+	// APIRoleAdmin grants access to all endpoints.
+	APIRoleAdmin APIRole = "admin"
+)
+
+var apiRoleRanks = map[APIRole]int{
+	APIRoleRead:    1,
+	APIRoleSilence: 2,
+	APIRoleAdmin:   3,
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *APIRole) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	if _, ok := apiRoleRanks[APIRole(s)]; !ok {
+		return fmt.Errorf("unknown API role %q", s)
+	}
+	*r = APIRole(s)
+	return nil
+}
+
+// Grants returns true iff the role grants the access of the other role.
+func (r APIRole) Grants(o APIRole) bool {
+	return apiRoleRanks[r] != 0 && apiRoleRanks[r] >= apiRoleRanks[o]
+}
+
+// APIAuth configures the authentication and authorization of API
+// requests.
+type APIAuth struct {
+	// The role of requests without credentials. If empty, such requests
+	// are rejected.
+	AnonymousRole APIRole `yaml:"anonymous_role,omitempty"`
+
+	Users []*APIUser `yaml:"users,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (a *APIAuth) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain APIAuth
+	if err := unmarshal((*plain)(a)); err != nil {
+		return err
+	}
+	var (
+		names  = map[string]struct{}{}
+		tokens = map[Secret]struct{}{}
+		cns    = map[string]struct{}{}
+	)
+	for _, u := range a.Users {
+		if _, ok := names[u.Name]; ok {
+			return fmt.Errorf("API user %q is not unique", u.Name)
+		}
+		names[u.Name] = struct{}{}
+
+		if u.BearerToken != "" {
+			if _, ok := tokens[u.BearerToken]; ok {
+				return fmt.Errorf("bearer token of API user %q is not unique", u.Name)
+			}
+			tokens[u.BearerToken] = struct{}{}
+		}
+		if u.ClientCertCN != "" {
+			if _, ok := cns[u.ClientCertCN]; ok {
+				return fmt.Errorf("client certificate common name of API user %q is not unique", u.Name)
+			}
+			cns[u.ClientCertCN] = struct{}{}
+		}
+	}
+	return checkOverflow(a.XXX, "API auth config")
+}
+
+// APIUser is a user of the API identified by any of its credentials.
+type APIUser struct {
+	Name string  `yaml:"name"`
+	Role APIRole `yaml:"role"`
+
+	// Password for basic authentication with the user's name.
+	Password Secret `yaml:"password,omitempty"`
+	// Token for bearer authentication.
+	BearerToken Secret `yaml:"bearer_token,omitempty"`
+	// Common name of the subject of the user's TLS client certificate.
+	ClientCertCN string `yaml:"client_cert_cn,omitempty"`
+
+	// Tenant the user's requests are scoped to.
+	Tenant string `yaml:"tenant,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (u *APIUser) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain APIUser
+	if err := unmarshal((*plain)(u)); err != nil {
+		return err
+	}
+	if u.Name == "" {
+		return fmt.Errorf("missing name of API user")
+	}
+	if u.Role == "" {
+		return fmt.Errorf("missing role of API user %q", u.Name)
+	}
+	if u.Password == "" && u.BearerToken == "" && u.ClientCertCN == "" {
+		return fmt.Errorf("API user %q has no credentials", u.Name)
+	}
+	return checkOverflow(u.XXX, "API user config")
+}
+
+// Tenancy configures the scoping of API requests to the alerts of a
+// tenant.
+type Tenancy struct {
+	// Label whose value identifies the tenant of alerts.
+	Label model.LabelName `yaml:"label"`
+	// Header holding the tenant of requests of users without a tenant,
+	// e.g. set by an authenticating reverse proxy.
+	Header string `yaml:"header,omitempty"`
+
+	// Requests per second allowed for each tenant with bursts of the
+	// given size. A rate of zero disables the limit.
+	RateLimit float64 `yaml:"rate_limit,omitempty"`
+	RateBurst int     `yaml:"rate_burst,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (t *Tenancy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Tenancy
+	if err := unmarshal((*plain)(t)); err != nil {
+		return err
+	}
+	if t.Label == "" {
+		return fmt.Errorf("missing tenant label")
+	}
+	if !t.Label.IsValid() {
+		return fmt.Errorf("invalid tenant label %q", t.Label)
+	}
+	if t.RateLimit < 0 {
+		return fmt.Errorf("rate_limit must not be negative")
+	}
+	if t.RateBurst < 0 {
+		return fmt.Errorf("rate_burst must not be negative")
+	}
+	if t.RateBurst == 0 {
+		t.RateBurst = int(math.Ceil(t.RateLimit))
+	}
+	return checkOverflow(t.XXX, "tenancy config")
+}
+
 // Receiver configuration provides configuration on how to contact a receiver.
 type Receiver struct {
 	// A unique identifier for this receiver.
@@ -406,6 +1340,40 @@ type Receiver struct {
 	WebhookConfigs   []*WebhookConfig   `yaml:"webhook_configs,omitempty"`
 	OpsGenieConfigs  []*OpsGenieConfig  `yaml:"opsgenie_configs,omitempty"`
 	PushoverConfigs  []*PushoverConfig  `yaml:"pushover_configs,omitempty"`
+	TeamsConfigs     []*TeamsConfig     `yaml:"teams_configs,omitempty"`
+	TelegramConfigs  []*TelegramConfig  `yaml:"telegram_configs,omitempty"`
+	JiraConfigs      []*JiraConfig      `yaml:"jira_configs,omitempty"`
+	SNMPConfigs      []*SNMPConfig      `yaml:"snmp_configs,omitempty"`
+
+	AlertmanagerConfigs []*AlertmanagerConfig `yaml:"alertmanager_configs,omitempty"`
+	PluginConfigs       []*PluginConfig       `yaml:"plugin_configs,omitempty"`
+
+	// How to proceed if notifying the receiver does not finish before
+	// the next group interval.
+	DeadlineExceeded DeadlinePolicy `yaml:"deadline_exceeded,omitempty"`
+
+	// Maximum number of notifications sent to the receiver at once by the
+	// dispatcher's notify workers. Unlimited if zero.
+	MaxConcurrentNotifications int `yaml:"max_concurrent_notifications,omitempty"`
+
+	// If set, notifications are accumulated and sent to the receiver as a
+	// single digest once per interval.
+	Digest *DigestConfig `yaml:"digest,omitempty"`
+
+	// How failed notifications to the receiver's integrations are retried.
+	Retry *RetryConfig `yaml:"retry,omitempty"`
+
+	// If set, overrides whether notifications to the receiver are only
+	// recorded instead of being sent, as set by the -notify.dry-run flag.
+	DryRun *bool `yaml:"dry_run,omitempty"`
+
+	// Receiver notifications are delivered to instead once an integration
+	// of this receiver exhausted its retry budget.
+	Fallback string `yaml:"fallback,omitempty"`
+
+	// How the receiver's integrations sending notifications over HTTP
+	// connect to their endpoints.
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline"`
@@ -420,9 +1388,174 @@ func (c *Receiver) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if c.Name == "" {
 		return fmt.Errorf("missing name in receiver")
 	}
+	switch c.DeadlineExceeded {
+	case "":
+		c.DeadlineExceeded = DeadlineRetryNextInterval
+	case DeadlineRetryNextInterval, DeadlineRetryImmediately, DeadlineDeadLetter:
+	default:
+		return fmt.Errorf("unknown deadline_exceeded policy %q", c.DeadlineExceeded)
+	}
+	if c.MaxConcurrentNotifications < 0 {
+		return fmt.Errorf("max_concurrent_notifications must not be negative")
+	}
+	if c.Fallback != "" && (c.Retry == nil || c.Retry.MaxAttempts == 0) {
+		return fmt.Errorf("fallback of receiver %q requires a retry budget with max_attempts", c.Name)
+	}
 	return checkOverflow(c.XXX, "receiver config")
 }
 
+// HTTPClientConfig configures the HTTP client integrations send
+// notifications with.
+type HTTPClientConfig struct {
+	// URL of the proxy requests are sent through. If empty, the proxy
+	// configured by the environment is used.
+	ProxyURL string `yaml:"proxy_url,omitempty"`
+	// TLS settings of connections to HTTPS endpoints and proxies.
+	TLSConfig TLSConfig `yaml:"tls_config,omitempty"`
+	// Timeout of a single request. Zero means no timeout.
+	Timeout model.Duration `yaml:"timeout,omitempty"`
+	// Timeout of establishing a connection. Zero means no timeout.
+	ConnectTimeout model.Duration `yaml:"connect_timeout,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *HTTPClientConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain HTTPClientConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.ProxyURL != "" {
+		u, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy_url: %s", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("unsupported scheme %q of proxy_url", u.Scheme)
+		}
+		if u.Host == "" {
+			return fmt.Errorf("missing host in proxy_url")
+		}
+	}
+	if c.Timeout < 0 || c.ConnectTimeout < 0 {
+		return fmt.Errorf("HTTP client timeouts must not be negative")
+	}
+	return checkOverflow(c.XXX, "http config")
+}
+
+// TLSConfig configures the TLS connections of an HTTP client.
+type TLSConfig struct {
+	// CA certificates server certificates are verified against instead
+	// of the system's certificate pool.
+	CAFile string `yaml:"ca_file,omitempty"`
+	// Certificate and key presented to servers requesting one.
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+	// Name server certificates are verified against instead of the
+	// host of the URL.
+	ServerName string `yaml:"server_name,omitempty"`
+	// Disables the verification of server certificates.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *TLSConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain TLSConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
+	}
+	return checkOverflow(c.XXX, "tls config")
+}
+
+// DigestConfig configures the digest notifications of a receiver.
+type DigestConfig struct {
+	// How often a digest of the accumulated notifications is sent.
+	Interval model.Duration `yaml:"interval"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *DigestConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain DigestConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("digest interval must be positive")
+	}
+	return checkOverflow(c.XXX, "digest config")
+}
+
+// DefaultRetryConfig provides the default values of the retry
+// configuration of receivers.
+var DefaultRetryConfig = RetryConfig{
+	InitialInterval: model.Duration(500 * time.Millisecond),
+	MaxInterval:     model.Duration(time.Minute),
+}
+
+// RetryConfig configures how failed notifications are retried. The
+// interval between attempts grows exponentially with random jitter.
+type RetryConfig struct {
+	// Interval before the first retry.
+	InitialInterval model.Duration `yaml:"initial_interval,omitempty"`
+	// Upper bound of the interval between retries.
+	MaxInterval model.Duration `yaml:"max_interval,omitempty"`
+	// Maximum number of attempts per notification after which it is
+	// given up on. Unlimited if zero, in which case attempts continue
+	// until the notification's deadline.
+	MaxAttempts int `yaml:"max_attempts,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *RetryConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultRetryConfig
+	type plain RetryConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.InitialInterval <= 0 {
+		return fmt.Errorf("retry initial_interval must be positive")
+	}
+	if c.MaxInterval < c.InitialInterval {
+		return fmt.Errorf("retry max_interval must not be less than initial_interval")
+	}
+	if c.MaxAttempts < 0 {
+		return fmt.Errorf("retry max_attempts must not be negative")
+	}
+	return checkOverflow(c.XXX, "retry config")
+}
+
+// DeadlinePolicy defines how to handle notifications that exceeded
+// their deadline.
+type DeadlinePolicy string
+
+// Possible deadline policies.
+const (
+	// Retry at the next group interval.
+	DeadlineRetryNextInterval DeadlinePolicy = "next_interval"
+	// Retry soon with exponential backoff, at the next group interval
+	// after five consecutive retries.
+	DeadlineRetryImmediately DeadlinePolicy = "retry"
+	// Do not retry and record the notification as a dead letter. Firing
+	// alerts are not notified about again until they fire anew.
+	DeadlineDeadLetter DeadlinePolicy = "dead_letter"
+)
+
 // Regexp encapsulates a regexp.Regexp and makes it YAML marshalable.
 type Regexp struct {
 	*regexp.Regexp
@@ -14,9 +14,15 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
+
+	"github.com/prometheus/common/model"
 )
 
 var (
@@ -25,6 +31,8 @@ var (
 		NotifierConfig: NotifierConfig{
 			VSendResolved: true,
 		},
+		Method:       "POST",
+		RetryBackoff: duration(1 * time.Second),
 	}
 
 	// DefaultEmailConfig defines default values for Email configurations.
@@ -70,6 +78,48 @@ var (
 		Fallback:  `{{ template "slack.default.fallback" . }}`,
 	}
 
+	// DefaultTeamsConfig defines default values for Microsoft Teams configurations.
+	DefaultTeamsConfig = TeamsConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		Title:      `{{ template "teams.default.title" . }}`,
+		Text:       `{{ template "teams.default.text" . }}`,
+		AlertTitle: `{{ template "teams.default.alert.title" . }}`,
+		AlertText:  `{{ template "teams.default.alert.text" . }}`,
+		MaxAlerts:  10,
+	}
+
+	// DefaultTelegramConfig defines default values for Telegram configurations.
+	DefaultTelegramConfig = TelegramConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		APIURL:    "https://api.telegram.org/",
+		Message:   `{{ template "telegram.default.message" . }}`,
+		ParseMode: "MarkdownV2",
+	}
+
+	// DefaultJiraConfig defines default values for Jira configurations.
+	DefaultJiraConfig = JiraConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		IssueType:         "Bug",
+		Summary:           `{{ template "jira.default.summary" . }}`,
+		Description:       `{{ template "jira.default.description" . }}`,
+		ResolveTransition: "Done",
+	}
+
+	// DefaultSNMPConfig defines default values for SNMP trap configurations.
+	DefaultSNMPConfig = SNMPConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		Version:   "v2c",
+		Community: "public",
+	}
+
 	// DefaultHipchatConfig defines default values for Hipchat configurations.
 	DefaultHipchatConfig = HipchatConfig{
 		NotifierConfig: NotifierConfig{
@@ -87,11 +137,21 @@ var (
 		NotifierConfig: NotifierConfig{
 			VSendResolved: true,
 		},
-		Description: `{{ template "opsgenie.default.description" . }}`,
-		Source:      `{{ template "opsgenie.default.source" . }}`,
+		Description:   `{{ template "opsgenie.default.description" . }}`,
+		Source:        `{{ template "opsgenie.default.source" . }}`,
+		SeverityLabel: "severity",
 		// TODO: Add a details field with all the alerts.
 	}
 
+	// DefaultOpsGenieSeverityPriorities maps common severity label values
+	// to OpsGenie priorities if no mapping is configured.
+	DefaultOpsGenieSeverityPriorities = map[string]string{
+		"critical": "P1",
+		"error":    "P2",
+		"warning":  "P3",
+		"info":     "P5",
+	}
+
 	// DefaultPushoverConfig defines default values for Pushover configurations.
 	DefaultPushoverConfig = PushoverConfig{
 		NotifierConfig: NotifierConfig{
@@ -104,17 +164,41 @@ var (
 		Retry:    duration(1 * time.Minute),
 		Expire:   duration(1 * time.Hour),
 	}
+
+	// DefaultAlertmanagerConfig defines default values for forwarding to
+	// other Alertmanagers.
+	DefaultAlertmanagerConfig = AlertmanagerConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		MaxHops: 3,
+	}
+
+	// DefaultPluginConfig defines default values for plugin configurations.
+	DefaultPluginConfig = PluginConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+	}
 )
 
 // NotifierConfig contains base options common across all notifier configurations.
 type NotifierConfig struct {
 	VSendResolved bool `yaml:"send_resolved"`
+
+	// The approximate cost of a single notification, e.g. for SMS gateways.
+	VCost float64 `yaml:"cost,omitempty"`
 }
 
 func (nc *NotifierConfig) SendResolved() bool {
 	return nc.VSendResolved
 }
 
+// Cost returns the approximate cost of a single notification.
+func (nc *NotifierConfig) Cost() float64 {
+	return nc.VCost
+}
+
 // EmailConfig configures notifications via mail.
 type EmailConfig struct {
 	NotifierConfig `yaml:",inline"`
@@ -131,10 +215,51 @@ type EmailConfig struct {
 	HTML         string            `yaml:"html"`
 	RequireTLS   bool              `yaml:"require_tls"`
 
+	// Images are fetched when notifying and embedded into the email. The
+	// HTML body references them as cid:<name>.
+	Images []*EmailImage `yaml:"images,omitempty"`
+	// If AttachCSV is true, a CSV file listing the alerts of the
+	// notification is attached to the email.
+	AttachCSV bool `yaml:"attach_csv,omitempty"`
+	// MaxIdleConns is the number of connections to the smarthost kept
+	// open for subsequent emails. If 0, a connection is opened per email.
+	MaxIdleConns int `yaml:"max_idle_conns,omitempty"`
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline"`
 }
 
+// emailImageNameRE matches names of email images that are valid content
+// IDs.
+var emailImageNameRE = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// EmailImage is an image embedded into emails, such as a chart of the
+// alerting metric.
+type EmailImage struct {
+	Name string `yaml:"name"`
+	// URL the image is fetched from. It is a template executed with the
+	// notification data.
+	URL string `yaml:"url"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *EmailImage) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain EmailImage
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if !emailImageNameRE.MatchString(c.Name) {
+		return fmt.Errorf("invalid email image name %q", c.Name)
+	}
+	if c.URL == "" {
+		return fmt.Errorf("missing URL of email image %q", c.Name)
+	}
+	return checkOverflow(c.XXX, "email image")
+}
+
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
 func (c *EmailConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	*c = DefaultEmailConfig
@@ -156,6 +281,17 @@ func (c *EmailConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 	c.Headers = normalizedHeaders
 
+	images := map[string]struct{}{}
+	for _, img := range c.Images {
+		if _, ok := images[img.Name]; ok {
+			return fmt.Errorf("duplicate image %q in email config", img.Name)
+		}
+		images[img.Name] = struct{}{}
+	}
+	if c.MaxIdleConns < 0 {
+		return fmt.Errorf("max_idle_conns must not be negative in email config")
+	}
+
 	return checkOverflow(c.XXX, "email config")
 }
 
@@ -170,6 +306,10 @@ type PagerdutyConfig struct {
 	Description string            `yaml:"description"`
 	Details     map[string]string `yaml:"details"`
 
+	// GroupKeyFormat defines the incident key. It defaults to the global
+	// group key format.
+	GroupKeyFormat GroupKeyFormat `yaml:"group_key_format,omitempty"`
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline"`
 }
@@ -184,6 +324,9 @@ func (c *PagerdutyConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	if c.ServiceKey == "" {
 		return fmt.Errorf("missing service key in PagerDuty config")
 	}
+	if err := checkGroupKeyFormat(c.GroupKeyFormat); err != nil {
+		return err
+	}
 	return checkOverflow(c.XXX, "pagerduty config")
 }
 
@@ -193,6 +336,17 @@ type SlackConfig struct {
 
 	APIURL Secret `yaml:"api_url"`
 
+	// If Thread is true, notifications are sent through the chat.postMessage
+	// method of the Slack Web API at APIURL using APIToken. Follow-up
+	// notifications of an alert group are posted as replies to the first one.
+	Thread   bool   `yaml:"thread,omitempty"`
+	APIToken Secret `yaml:"api_token,omitempty"`
+
+	// If Actions is true, notifications about firing alerts carry buttons
+	// to silence the alert group for an hour, acknowledge its alerts, and
+	// view its event.
+	Actions bool `yaml:"actions,omitempty"`
+
 	// Slack channel override, (like #other-channel or @username).
 	Channel  string `yaml:"channel"`
 	Username string `yaml:"username"`
@@ -219,9 +373,274 @@ func (c *SlackConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if c.Channel == "" {
 		return fmt.Errorf("missing channel in Slack config")
 	}
+	if c.Thread && c.APIToken == "" {
+		return fmt.Errorf("missing API token for threading in Slack config")
+	}
 	return checkOverflow(c.XXX, "slack config")
 }
 
+// TeamsConfig configures notifications via Microsoft Teams incoming webhooks.
+type TeamsConfig struct {
+	NotifierConfig `yaml:",inline"`
+
+	WebhookURL Secret `yaml:"webhook_url"`
+
+	// Title and Text are executed with the notification data and make up
+	// the head of the card.
+	Title string `yaml:"title"`
+	Text  string `yaml:"text"`
+	// AlertTitle and AlertText are executed with each alert and make up
+	// its section of the card.
+	AlertTitle string `yaml:"alert_title"`
+	AlertText  string `yaml:"alert_text"`
+	// MaxAlerts limits the number of alert sections in a single card.
+	MaxAlerts int `yaml:"max_alerts"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *TeamsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultTeamsConfig
+	type plain TeamsConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.WebhookURL == "" {
+		return fmt.Errorf("missing webhook URL in Teams config")
+	}
+	if c.MaxAlerts <= 0 {
+		return fmt.Errorf("max_alerts must be positive in Teams config")
+	}
+	return checkOverflow(c.XXX, "teams config")
+}
+
+// TelegramConfig configures notifications via a Telegram bot.
+type TelegramConfig struct {
+	NotifierConfig `yaml:",inline"`
+
+	APIURL   string `yaml:"api_url"`
+	BotToken Secret `yaml:"bot_token"`
+	ChatID   int64  `yaml:"chat_id"`
+
+	Message string `yaml:"message"`
+	// ParseMode is one of MarkdownV2, HTML, or empty for plain text.
+	ParseMode           string `yaml:"parse_mode"`
+	DisableNotification bool   `yaml:"disable_notification"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *TelegramConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultTelegramConfig
+	type plain TelegramConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.BotToken == "" {
+		return fmt.Errorf("missing bot token in Telegram config")
+	}
+	if c.ChatID == 0 {
+		return fmt.Errorf("missing chat_id in Telegram config")
+	}
+	switch c.ParseMode {
+	case "", "MarkdownV2", "HTML":
+	default:
+		return fmt.Errorf("unknown parse_mode %q in Telegram config", c.ParseMode)
+	}
+	return checkOverflow(c.XXX, "telegram config")
+}
+
+// JiraConfig configures the creation of Jira issues for alert groups.
+type JiraConfig struct {
+	NotifierConfig `yaml:",inline"`
+
+	// APIURL is the base URL of the Jira instance, e.g.
+	// https://example.atlassian.net/.
+	APIURL string `yaml:"api_url"`
+	// User and APIToken authenticate with basic authentication. Without a
+	// user, the token is sent as a bearer token instead.
+	User     string `yaml:"user"`
+	APIToken Secret `yaml:"api_token"`
+
+	Project   string `yaml:"project"`
+	IssueType string `yaml:"issue_type"`
+
+	Summary     string `yaml:"summary"`
+	Description string `yaml:"description"`
+	// Priority is executed with the notification data and sets the issue's
+	// priority by name if not empty.
+	Priority string   `yaml:"priority"`
+	Labels   []string `yaml:"labels"`
+
+	// ResolveTransition is the name of the workflow transition applied to
+	// the issue once its alert group resolved.
+	ResolveTransition string `yaml:"resolve_transition"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *JiraConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultJiraConfig
+	type plain JiraConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.APIURL == "" {
+		return fmt.Errorf("missing api_url in Jira config")
+	}
+	if !strings.HasSuffix(c.APIURL, "/") {
+		c.APIURL += "/"
+	}
+	if c.Project == "" {
+		return fmt.Errorf("missing project in Jira config")
+	}
+	if c.IssueType == "" {
+		return fmt.Errorf("missing issue_type in Jira config")
+	}
+	if c.User != "" && c.APIToken == "" {
+		return fmt.Errorf("missing api_token for user %q in Jira config", c.User)
+	}
+	for _, l := range c.Labels {
+		// Jira rejects labels containing spaces.
+		if l == "" || strings.ContainsAny(l, " \t\n") {
+			return fmt.Errorf("invalid label %q in Jira config", l)
+		}
+	}
+	return checkOverflow(c.XXX, "jira config")
+}
+
+// oidRE matches object identifiers in dotted notation.
+var oidRE = regexp.MustCompile(`^\.?[0-2](\.[0-9]+)+$`)
+
+// SNMPConfig configures SNMP traps sent for each alert of a notification.
+type SNMPConfig struct {
+	NotifierConfig `yaml:",inline"`
+
+	// Target is the host:port of the trap receiver. The port defaults to
+	// 162.
+	Target string `yaml:"target"`
+	// Version is either v2c or v3.
+	Version   string `yaml:"version"`
+	Community Secret `yaml:"community"`
+
+	// The parameters of the SNMPv3 user-based security model. The engine
+	// ID is hex-encoded.
+	EngineID     string `yaml:"engine_id"`
+	User         string `yaml:"user"`
+	AuthProtocol string `yaml:"auth_protocol"`
+	AuthPassword Secret `yaml:"auth_password"`
+	PrivProtocol string `yaml:"priv_protocol"`
+	PrivPassword Secret `yaml:"priv_password"`
+
+	// TrapOID identifies the traps of firing alerts and, unless
+	// ResolvedTrapOID is set, those of resolved alerts.
+	TrapOID         string         `yaml:"trap_oid"`
+	ResolvedTrapOID string         `yaml:"resolved_trap_oid"`
+	VarBinds        []*SNMPVarBind `yaml:"varbinds"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SNMPConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultSNMPConfig
+	type plain SNMPConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Target == "" {
+		return fmt.Errorf("missing target in SNMP config")
+	}
+	if _, _, err := net.SplitHostPort(c.Target); err != nil {
+		c.Target = net.JoinHostPort(c.Target, "162")
+	}
+	if !oidRE.MatchString(c.TrapOID) {
+		return fmt.Errorf("invalid trap_oid %q in SNMP config", c.TrapOID)
+	}
+	if c.ResolvedTrapOID != "" && !oidRE.MatchString(c.ResolvedTrapOID) {
+		return fmt.Errorf("invalid resolved_trap_oid %q in SNMP config", c.ResolvedTrapOID)
+	}
+
+	switch c.Version {
+	case "v2c":
+		if c.Community == "" {
+			return fmt.Errorf("missing community in SNMP config")
+		}
+	case "v3":
+		if c.User == "" {
+			return fmt.Errorf("missing user for SNMPv3 in SNMP config")
+		}
+		if c.EngineID != "" {
+			b, err := hex.DecodeString(strings.TrimPrefix(c.EngineID, "0x"))
+			if err != nil || len(b) < 5 || len(b) > 32 {
+				return fmt.Errorf("engine_id must be 5 to 32 hex-encoded bytes in SNMP config")
+			}
+		}
+		switch c.AuthProtocol {
+		case "":
+			if c.PrivProtocol != "" {
+				return fmt.Errorf("priv_protocol requires auth_protocol in SNMP config")
+			}
+		case "MD5", "SHA":
+			if len(c.AuthPassword) < 8 {
+				return fmt.Errorf("auth_password must have at least 8 characters in SNMP config")
+			}
+		default:
+			return fmt.Errorf("unknown auth_protocol %q in SNMP config", c.AuthProtocol)
+		}
+		switch c.PrivProtocol {
+		case "":
+		case "DES", "AES":
+			if len(c.PrivPassword) < 8 {
+				return fmt.Errorf("priv_password must have at least 8 characters in SNMP config")
+			}
+		default:
+			return fmt.Errorf("unknown priv_protocol %q in SNMP config", c.PrivProtocol)
+		}
+	default:
+		return fmt.Errorf("unknown version %q in SNMP config", c.Version)
+	}
+	return checkOverflow(c.XXX, "snmp config")
+}
+
+// SNMPVarBind maps the result of a template executed with each alert to
+// an OID of the alert's trap.
+type SNMPVarBind struct {
+	OID   string `yaml:"oid"`
+	Value string `yaml:"value"`
+	// Type is the type the value is sent as, either string or integer.
+	Type string `yaml:"type"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (v *SNMPVarBind) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SNMPVarBind
+	if err := unmarshal((*plain)(v)); err != nil {
+		return err
+	}
+	if !oidRE.MatchString(v.OID) {
+		return fmt.Errorf("invalid varbind OID %q in SNMP config", v.OID)
+	}
+	switch v.Type {
+	case "":
+		v.Type = "string"
+	case "string", "integer":
+	default:
+		return fmt.Errorf("unknown type %q of varbind %s in SNMP config", v.Type, v.OID)
+	}
+	return checkOverflow(v.XXX, "snmp varbind")
+}
+
 // HipchatConfig configures notifications via Hipchat.
 type HipchatConfig struct {
 	NotifierConfig `yaml:",inline"`
@@ -257,8 +676,24 @@ func (c *HipchatConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 type WebhookConfig struct {
 	NotifierConfig `yaml:",inline"`
 
-	// URL to send POST request to.
+	// URL to send the request to.
 	URL string `yaml:"url"`
+	// HTTP method of the request.
+	Method string `yaml:"method"`
+	// Headers of the request. Values are templated.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Template of the request body. If empty, the JSON representation
+	// of the notification is sent.
+	Body string `yaml:"body,omitempty"`
+	// Timeout of a single request. Zero means no timeout.
+	Timeout duration `yaml:"timeout,omitempty"`
+	// Number of times a failed request is retried with exponential backoff
+	// starting at the given initial backoff.
+	MaxRetries   int      `yaml:"max_retries,omitempty"`
+	RetryBackoff duration `yaml:"retry_backoff"`
+	// GroupKeyFormat defines the group key and version of the payload. It
+	// defaults to the global group key format.
+	GroupKeyFormat GroupKeyFormat `yaml:"group_key_format,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline"`
@@ -274,9 +709,22 @@ func (c *WebhookConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if c.URL == "" {
 		return fmt.Errorf("missing URL in webhook config")
 	}
-	return checkOverflow(c.XXX, "slack config")
+	c.Method = strings.ToUpper(c.Method)
+	if c.Method == "" {
+		return fmt.Errorf("missing method in webhook config")
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("negative max_retries in webhook config")
+	}
+	if err := checkGroupKeyFormat(c.GroupKeyFormat); err != nil {
+		return err
+	}
+	return checkOverflow(c.XXX, "webhook config")
 }
 
+// opsGeniePriorityRE matches the priorities accepted by OpsGenie.
+var opsGeniePriorityRE = regexp.MustCompile(`^P[1-5]$`)
+
 // OpsGenieConfig configures notifications via OpsGenie.
 type OpsGenieConfig struct {
 	NotifierConfig `yaml:",inline"`
@@ -289,6 +737,18 @@ type OpsGenieConfig struct {
 	Teams       string            `yaml:"teams"`
 	Tags        string            `yaml:"tags"`
 
+	// Priority is a template for the alert priority. If it is empty the
+	// priority is derived from the severity label of the alerts.
+	Priority           string            `yaml:"priority"`
+	SeverityLabel      model.LabelName   `yaml:"severity_label"`
+	SeverityPriorities map[string]string `yaml:"severity_priorities"`
+	// TagLabels lists labels that are added as name:value tags if all
+	// alerts of the notification share them.
+	TagLabels []model.LabelName `yaml:"tag_labels"`
+	// GroupKeyFormat defines the alert alias. It defaults to the global
+	// group key format.
+	GroupKeyFormat GroupKeyFormat `yaml:"group_key_format,omitempty"`
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline"`
 }
@@ -303,6 +763,28 @@ func (c *OpsGenieConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	if c.APIKey == "" {
 		return fmt.Errorf("missing API key in OpsGenie config")
 	}
+	if c.SeverityPriorities == nil {
+		c.SeverityPriorities = make(map[string]string, len(DefaultOpsGenieSeverityPriorities))
+		for k, v := range DefaultOpsGenieSeverityPriorities {
+			c.SeverityPriorities[k] = v
+		}
+	}
+	for k, v := range c.SeverityPriorities {
+		if !opsGeniePriorityRE.MatchString(v) {
+			return fmt.Errorf("invalid OpsGenie priority %q for severity %q", v, k)
+		}
+	}
+	if !c.SeverityLabel.IsValid() {
+		return fmt.Errorf("invalid severity label %q in OpsGenie config", c.SeverityLabel)
+	}
+	if err := checkGroupKeyFormat(c.GroupKeyFormat); err != nil {
+		return err
+	}
+	for _, ln := range c.TagLabels {
+		if !ln.IsValid() {
+			return fmt.Errorf("invalid tag label %q in OpsGenie config", ln)
+		}
+	}
 	return checkOverflow(c.XXX, "opsgenie config")
 }
 
@@ -351,3 +833,66 @@ func (c *PushoverConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	}
 	return checkOverflow(c.XXX, "pushover config")
 }
+
+// AlertmanagerConfig configures forwarding of alerts to another
+// Alertmanager.
+type AlertmanagerConfig struct {
+	NotifierConfig `yaml:",inline"`
+
+	// Base URL of the Alertmanager alerts are forwarded to.
+	URL string `yaml:"url"`
+	// Maximum number of Alertmanagers an alert is forwarded through.
+	// Alerts that reached it are dropped to break forwarding loops.
+	MaxHops int `yaml:"max_hops"`
+	// Timeout of a single request. Zero means no timeout.
+	Timeout duration `yaml:"timeout,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *AlertmanagerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultAlertmanagerConfig
+	type plain AlertmanagerConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return fmt.Errorf("missing URL in alertmanager config")
+	}
+	if c.MaxHops <= 0 {
+		return fmt.Errorf("max_hops must be positive in alertmanager config")
+	}
+	return checkOverflow(c.XXX, "alertmanager config")
+}
+
+// PluginConfig configures notifications through an executable in the
+// plugins directory.
+type PluginConfig struct {
+	NotifierConfig `yaml:",inline"`
+
+	// File name of the plugin's executable.
+	Name string `yaml:"name"`
+	// Options passed to the plugin with each notification.
+	Options map[string]string `yaml:"options,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *PluginConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultPluginConfig
+	type plain PluginConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return fmt.Errorf("missing name in plugin config")
+	}
+	if c.Name != filepath.Base(c.Name) || strings.HasPrefix(c.Name, ".") {
+		return fmt.Errorf("invalid plugin name %q, must be a file name in the plugins directory", c.Name)
+	}
+	return checkOverflow(c.XXX, "plugin config")
+}
@@ -0,0 +1,179 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// ConfigValidation is the result of validating a candidate configuration.
+type ConfigValidation struct {
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	// Changes compared to the running configuration.
+	Diff *ConfigDiff `json:"diff,omitempty"`
+}
+
+// ConfigDiff lists the differences between two configurations.
+type ConfigDiff struct {
+	// Top-level sections other than the routing tree and the receivers
+	// that changed, by their name in the configuration file.
+	Sections  []string   `json:"sections,omitempty"`
+	Routes    *ItemsDiff `json:"routes"`
+	Receivers *ItemsDiff `json:"receivers"`
+}
+
+// ItemsDiff lists the names of added, removed, and changed items.
+type ItemsDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// ConfigWarnings returns problems of a valid configuration that are likely
+// mistakes, such as routes no alert can reach and unused receivers.
+func ConfigWarnings(conf *config.Config) []string {
+	var (
+		warnings []string
+		used     = map[string]struct{}{}
+		root     = NewRoute(conf.Route, nil)
+	)
+	root.Walk(func(r *Route) {
+		used[r.RouteOpts.Receiver] = struct{}{}
+		for _, es := range r.RouteOpts.Escalation {
+			used[es.Receiver] = struct{}{}
+		}
+
+		var catchAll *Route
+		for _, cr := range r.Routes {
+			if catchAll != nil {
+				warnings = append(warnings, fmt.Sprintf("Route %s is unreachable as the preceding route %s matches all alerts", cr.Key(), catchAll.Key()))
+			} else if m, pm := conflictingMatcher(cr); m != nil {
+				warnings = append(warnings, fmt.Sprintf("Route %s is unreachable as its matcher %s=%q conflicts with %s=%q of a parent route", cr.Key(), m.Name, m.Value, pm.Name, pm.Value))
+			}
+			if len(cr.Matchers) == 0 && !cr.Continue && catchAll == nil {
+				catchAll = cr
+			}
+		}
+	})
+
+	for _, rcv := range conf.Receivers {
+		if _, ok := used[rcv.Name]; !ok {
+			warnings = append(warnings, fmt.Sprintf("Receiver %q is not used by any route", rcv.Name))
+		}
+	}
+	return warnings
+}
+
+// conflictingMatcher returns an equality matcher of the route and one of
+// its parents' that require different values of the same label.
+func conflictingMatcher(r *Route) (*types.Matcher, *types.Matcher) {
+	for _, m := range r.Matchers {
+		if m.IsRegex() {
+			continue
+		}
+		for _, pm := range r.parent.SquashMatchers() {
+			if !pm.IsRegex() && pm.Name == m.Name && pm.Value != m.Value {
+				return m, pm
+			}
+		}
+	}
+	return nil, nil
+}
+
+// DiffConfig returns the differences between the old and the new
+// configuration. Routes are identified by their key and compared by their
+// resolved routing options.
+func DiffConfig(old, new *config.Config) *ConfigDiff {
+	d := &ConfigDiff{
+		Routes:    diffItems(routesByKey(old.Route), routesByKey(new.Route)),
+		Receivers: diffItems(receiversByName(old.Receivers), receiversByName(new.Receivers)),
+	}
+
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	for i := 0; i < ov.NumField(); i++ {
+		f := ov.Type().Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+
+		switch name {
+		case "", "route", "receivers":
+			continue
+		}
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			d.Sections = append(d.Sections, name)
+		}
+	}
+	return d
+}
+
+// routeItem holds the properties of a route that are compared between
+// configurations.
+type routeItem struct {
+	opts RouteOpts
+	cont bool
+}
+
+// routesByKey returns the routes of the tree by their key. Routes with
+// the same key are told apart by their position among them.
+func routesByKey(cr *config.Route) map[string]interface{} {
+	res := map[string]interface{}{}
+	NewRoute(cr, nil).Walk(func(r *Route) {
+		key := r.Key()
+		for i := 2; ; i++ {
+			if _, ok := res[key]; !ok {
+				break
+			}
+			key = fmt.Sprintf("%s#%d", r.Key(), i)
+		}
+		res[key] = routeItem{opts: r.RouteOpts, cont: r.Continue}
+	})
+	return res
+}
+
+func receiversByName(rcvs []*config.Receiver) map[string]interface{} {
+	res := map[string]interface{}{}
+	for _, rcv := range rcvs {
+		res[rcv.Name] = rcv
+	}
+	return res
+}
+
+func diffItems(old, new map[string]interface{}) *ItemsDiff {
+	d := &ItemsDiff{}
+	for name, o := range old {
+		n, ok := new[name]
+		if !ok {
+			d.Removed = append(d.Removed, name)
+		} else if !reflect.DeepEqual(o, n) {
+			d.Changed = append(d.Changed, name)
+		}
+	}
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			d.Added = append(d.Added, name)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+
+	return d
+}
@@ -0,0 +1,132 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+func TestConfigWarnings(t *testing.T) {
+	conf, err := config.Load(`
+route:
+  receiver: team-X
+  routes:
+  - match:
+      owner: team-X
+    receiver: team-X
+    routes:
+    - match:
+        owner: team-Y
+      receiver: team-Y
+  - receiver: team-Y
+  - match:
+      service: db
+    receiver: team-X
+receivers:
+- name: team-X
+- name: team-Y
+- name: team-Z
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`Route {}/{service="db"} is unreachable as the preceding route {}/{} matches all alerts`,
+		`Route {}/{owner="team-X"}/{owner="team-Y"} is unreachable as its matcher owner="team-Y" conflicts with owner="team-X" of a parent route`,
+		`Receiver "team-Z" is not used by any route`,
+	}
+	if got := ConfigWarnings(conf); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected warnings %q but got %q", expected, got)
+	}
+}
+
+func TestDiffConfig(t *testing.T) {
+	old, err := config.LoadFile("doc/examples/simple.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, err := config.LoadFile("doc/examples/simple.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ConfigDiff{Routes: &ItemsDiff{}, Receivers: &ItemsDiff{}}
+	if d := DiffConfig(old, same); !reflect.DeepEqual(d, expected) {
+		t.Errorf("expected no differences but got %+v", d)
+	}
+
+	old, err = config.Load(`
+global:
+  resolve_timeout: 5m
+route:
+  receiver: team-X
+  routes:
+  - match:
+      service: db
+    receiver: team-X
+  - match:
+      service: files
+    receiver: team-Y
+receivers:
+- name: team-X
+- name: team-Y
+  webhook_configs:
+  - url: http://example.com/a
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur, err := config.Load(`
+global:
+  resolve_timeout: 10m
+route:
+  receiver: team-X
+  routes:
+  - match:
+      service: db
+    receiver: team-X
+    group_wait: 1m
+  - match:
+      service: api
+    receiver: team-Z
+receivers:
+- name: team-X
+- name: team-Y
+  webhook_configs:
+  - url: http://example.com/b
+- name: team-Z
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = &ConfigDiff{
+		Sections: []string{"global"},
+		Routes: &ItemsDiff{
+			Added:   []string{`{}/{service="api"}`},
+			Removed: []string{`{}/{service="files"}`},
+			Changed: []string{`{}/{service="db"}`},
+		},
+		Receivers: &ItemsDiff{
+			Added:   []string{"team-Z"},
+			Changed: []string{"team-Y"},
+		},
+	}
+	if d := DiffConfig(old, cur); !reflect.DeepEqual(d, expected) {
+		t.Errorf("expected differences %+v but got %+v", expected, d)
+	}
+}
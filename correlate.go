@@ -0,0 +1,332 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// maxEventUpdateAttempts bounds how often updating an event that is
+// modified concurrently is attempted.
+const maxEventUpdateAttempts = 3
+
+// A Correlator attaches alerts to open events based on a set of
+// correlation rules and closes events once all their alerts resolved.
+type Correlator struct {
+	alerts provider.Alerts
+	events provider.Events
+	rules  []*CorrelationRule
+	hooks  *EventHooks
+}
+
+// NewCorrelator returns a new Correlator. Events it resolves are sent to
+// the hooks if they are not nil.
+func NewCorrelator(ap provider.Alerts, ev provider.Events, rs []*config.CorrelationRule, hooks *EventHooks) *Correlator {
+	c := &Correlator{
+		alerts: ap,
+		events: ev,
+		hooks:  hooks,
+	}
+	for _, cr := range rs {
+		c.rules = append(c.rules, NewCorrelationRule(cr))
+	}
+	return c
+}
+
+// Correlate adds the fingerprint of a firing alert to all open events it
+// correlates with by any rule.
+func (c *Correlator) Correlate(a *types.Alert) {
+	if a.Resolved() {
+		return
+	}
+	var rules []*CorrelationRule
+	for _, r := range c.rules {
+		if r.AlertMatchers.Match(a.Labels) {
+			rules = append(rules, r)
+		}
+	}
+	// Avoid loading events for alerts no rule applies to.
+	if len(rules) == 0 {
+		return
+	}
+
+	events, err := c.events.All()
+	if err != nil {
+		log.Errorf("Loading events for correlation failed: %s", err)
+		return
+	}
+
+	id := eventAlertID(a.Fingerprint())
+
+	for _, e := range events {
+		if !e.ClosedAt.IsZero() || hasEventAlert(e, id) {
+			continue
+		}
+		for _, r := range rules {
+			if !r.correlates(e, a) {
+				continue
+			}
+			err := retryEventUpdate(c.events, e, func(e *types.Event) (bool, error) {
+				if !e.ClosedAt.IsZero() || hasEventAlert(e, id) {
+					return false, nil
+				}
+				e.Alerts = append(e.Alerts, id)
+				e.UpdatedAt = time.Now()
+				return true, nil
+			})
+			if err != nil {
+				log.Errorf("Adding alert %s to event %d failed: %s", a, e.ID, err)
+			}
+			break
+		}
+	}
+}
+
+// CloseResolved closes all open events whose alerts are all resolved.
+// Events without alerts are left open.
+func (c *Correlator) CloseResolved(now time.Time) {
+	events, err := c.events.All()
+	if err != nil {
+		log.Errorf("Loading events for correlation failed: %s", err)
+		return
+	}
+
+	for _, e := range events {
+		if !e.ClosedAt.IsZero() || len(e.Alerts) == 0 {
+			continue
+		}
+		var t *EventTransition
+
+		err := retryEventUpdate(c.events, e, func(e *types.Event) (bool, error) {
+			if !e.ClosedAt.IsZero() {
+				return false, nil
+			}
+			ok, err := c.resolved(e)
+			if err != nil || !ok {
+				return false, err
+			}
+			t = &EventTransition{
+				Event:   e,
+				From:    e.CurrentStatus(),
+				To:      types.EventResolved,
+				Comment: "All alerts resolved",
+				Time:    now,
+			}
+			return true, e.Transition(types.EventResolved, now)
+		})
+		if err != nil {
+			log.Errorf("Closing event %d failed: %s", e.ID, err)
+			continue
+		}
+		if t != nil {
+			c.hooks.Notify(t)
+		}
+	}
+}
+
+// resolved returns true iff the event has alerts and none of them is
+// firing. Alerts that no longer exist are considered resolved.
+func (c *Correlator) resolved(e *types.Event) (bool, error) {
+	if len(e.Alerts) == 0 {
+		return false, nil
+	}
+	for _, ids := range e.Alerts {
+		id, err := strconv.ParseUint(ids, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		a, err := c.alerts.Get(model.Fingerprint(id))
+		if err == provider.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if !a.Resolved() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// retryEventUpdate applies f to the event and stores the result if f returns
+// true. If the event was modified concurrently, it is reloaded and f is
+// applied again.
+func retryEventUpdate(events provider.Events, e *types.Event, f func(*types.Event) (bool, error)) error {
+	for i := 1; ; i++ {
+		ok, err := f(e)
+		if err != nil || !ok {
+			return err
+		}
+		err = events.Update(e)
+		if err != provider.ErrConflict || i == maxEventUpdateAttempts {
+			return err
+		}
+		if e, err = events.Get(e.ID); err != nil {
+			return err
+		}
+	}
+}
+
+// EventIssueLinker links issues created by ticketing integrations to the
+// open events containing the issue's alerts. The issue key and URL are
+// stored in the event annotations <integration>_issue and
+// <integration>_issue_url.
+type EventIssueLinker struct {
+	events provider.Events
+}
+
+// NewEventIssueLinker returns a new EventIssueLinker.
+func NewEventIssueLinker(ev provider.Events) *EventIssueLinker {
+	return &EventIssueLinker{events: ev}
+}
+
+// LinkIssue implements the notify.IssueLinker interface.
+func (l *EventIssueLinker) LinkIssue(integration, key, url string, alerts ...*types.Alert) error {
+	events, err := l.events.All()
+	if err != nil {
+		return err
+	}
+	var (
+		keyName = model.LabelName(integration + "_issue")
+		urlName = model.LabelName(integration + "_issue_url")
+	)
+	for _, e := range events {
+		if !e.ClosedAt.IsZero() || !hasAnyEventAlert(e, alerts) {
+			continue
+		}
+		err := retryEventUpdate(l.events, e, func(e *types.Event) (bool, error) {
+			if !e.ClosedAt.IsZero() || e.Annotations[keyName] == model.LabelValue(key) {
+				return false, nil
+			}
+			if e.Annotations == nil {
+				e.Annotations = model.LabelSet{}
+			}
+			e.Annotations[keyName] = model.LabelValue(key)
+			e.Annotations[urlName] = model.LabelValue(url)
+			e.UpdatedAt = time.Now()
+			return true, nil
+		})
+		if err != nil {
+			return fmt.Errorf("updating event %d: %s", e.ID, err)
+		}
+	}
+	return nil
+}
+
+// eventAlertID returns the representation of an alert fingerprint in the
+// alerts of an event.
+func eventAlertID(fp model.Fingerprint) string {
+	return strconv.FormatUint(uint64(fp), 10)
+}
+
+func hasEventAlert(e *types.Event, id string) bool {
+	for _, ids := range e.Alerts {
+		if ids == id {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyEventAlert(e *types.Event, alerts []*types.Alert) bool {
+	for _, a := range alerts {
+		if hasEventAlert(e, eventAlertID(a.Fingerprint())) {
+			return true
+		}
+	}
+	return false
+}
+
+// A CorrelationRule specifies that alerts matching a set of labels belong
+// to open events matching another set of labels if all specified labels are
+// equal between the event and the alert and the alert started within a
+// window around the event's creation.
+type CorrelationRule struct {
+	// The set of Filters which define the events alerts are added to.
+	EventMatchers types.Matchers
+	// The set of Filters which define the alerts that are added to events.
+	AlertMatchers types.Matchers
+	// A set of label names whose label values need to be identical in the
+	// event and the alert.
+	Equal map[model.LabelName]struct{}
+	// Maximum duration between the event's creation and the alert's start.
+	// Zero means no restriction.
+	Window time.Duration
+}
+
+// NewCorrelationRule returns a new CorrelationRule based on a configuration definition.
+func NewCorrelationRule(cr *config.CorrelationRule) *CorrelationRule {
+	var (
+		eventm types.Matchers
+		alertm types.Matchers
+	)
+
+	for ln, lv := range cr.EventMatch {
+		eventm = append(eventm, types.NewMatcher(model.LabelName(ln), lv))
+	}
+	for ln, lv := range cr.EventMatchRE {
+		eventm = append(eventm, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
+	}
+
+	for ln, lv := range cr.AlertMatch {
+		alertm = append(alertm, types.NewMatcher(model.LabelName(ln), lv))
+	}
+	for ln, lv := range cr.AlertMatchRE {
+		alertm = append(alertm, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
+	}
+
+	equal := map[model.LabelName]struct{}{}
+	for _, ln := range cr.Equal {
+		equal[ln] = struct{}{}
+	}
+
+	return &CorrelationRule{
+		EventMatchers: eventm,
+		AlertMatchers: alertm,
+		Equal:         equal,
+		Window:        time.Duration(cr.Window),
+	}
+}
+
+// correlates returns true iff the rule attaches the alert to the event.
+func (r *CorrelationRule) correlates(e *types.Event, a *types.Alert) bool {
+	if !r.AlertMatchers.Match(a.Labels) || !r.EventMatchers.Match(e.Labels) {
+		return false
+	}
+	for ln := range r.Equal {
+		if e.Labels[ln] != a.Labels[ln] {
+			return false
+		}
+	}
+	if r.Window > 0 {
+		d := a.StartsAt.Sub(e.CreatedAt)
+		if d < 0 {
+			d = -d
+		}
+		if d > r.Window {
+			return false
+		}
+	}
+	return true
+}
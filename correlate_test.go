@@ -0,0 +1,191 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestCorrelator(t *testing.T) {
+	var (
+		alerts = provider.NewMemAlerts(provider.NewMemData())
+		events = provider.NewMemEvents()
+		now    = time.Now()
+	)
+
+	c := NewCorrelator(alerts, events, []*config.CorrelationRule{
+		{
+			EventMatch: map[string]string{"kind": "outage"},
+			AlertMatch: map[string]string{"severity": "critical"},
+			Equal:      model.LabelNames{"service"},
+			Window:     model.Duration(time.Hour),
+		},
+	}, nil)
+
+	outage := &types.Event{
+		Title:     "Database outage",
+		Labels:    model.LabelSet{"kind": "outage", "service": "db"},
+		CreatedAt: now,
+	}
+	other := &types.Event{
+		Title:     "Deployment",
+		Labels:    model.LabelSet{"kind": "deploy", "service": "db"},
+		CreatedAt: now,
+	}
+	for _, e := range []*types.Event{outage, other} {
+		if _, err := events.Set(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newAlert := func(labels model.LabelSet, start time.Time) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   labels,
+				StartsAt: start,
+				EndsAt:   now.Add(time.Hour),
+			},
+			Timeout: true,
+		}
+	}
+	var (
+		a1 = newAlert(model.LabelSet{"alertname": "a1", "severity": "critical", "service": "db"}, now.Add(-time.Minute))
+		// Different service.
+		a2 = newAlert(model.LabelSet{"alertname": "a2", "severity": "critical", "service": "web"}, now)
+		// Not matching the alert matchers.
+		a3 = newAlert(model.LabelSet{"alertname": "a3", "severity": "warning", "service": "db"}, now)
+		// Outside of the window.
+		a4 = newAlert(model.LabelSet{"alertname": "a4", "severity": "critical", "service": "db"}, now.Add(2*time.Hour))
+	)
+	if err := alerts.Put(a1, a2, a3, a4); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []*types.Alert{a1, a2, a3, a4, a1} {
+		c.Correlate(a)
+	}
+
+	members := func(id uint64) []string {
+		e, err := events.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e.Alerts
+	}
+	if exp, got := []string{eventAlertID(a1.Fingerprint())}, members(outage.ID); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected alerts %v but got %v", exp, got)
+	}
+	if got := members(other.ID); len(got) != 0 {
+		t.Fatalf("expected no alerts but got %v", got)
+	}
+
+	closed := func(id uint64) bool {
+		e, err := events.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return !e.ClosedAt.IsZero()
+	}
+
+	c.CloseResolved(now)
+	if closed(outage.ID) {
+		t.Fatalf("expected event with firing alerts to be open")
+	}
+
+	resolved := *a1
+	resolved.EndsAt = now.Add(-time.Second)
+	resolved.UpdatedAt = now
+	resolved.Timeout = false
+	if err := alerts.Put(&resolved); err != nil {
+		t.Fatal(err)
+	}
+
+	c.CloseResolved(now)
+	if !closed(outage.ID) {
+		t.Fatalf("expected event with resolved alerts to be closed")
+	}
+	if closed(other.ID) {
+		t.Fatalf("expected event without alerts to be open")
+	}
+
+	// Closed events are not correlated anymore.
+	a5 := newAlert(model.LabelSet{"alertname": "a5", "severity": "critical", "service": "db"}, now)
+	c.Correlate(a5)
+	if got := members(outage.ID); len(got) != 1 {
+		t.Fatalf("expected closed event to keep its alerts but got %v", got)
+	}
+}
+
+func TestEventIssueLinker(t *testing.T) {
+	var (
+		events = provider.NewMemEvents()
+		now    = time.Now()
+		a1     = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a1"}}}
+		a2     = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a2"}}}
+	)
+	open := &types.Event{
+		Title:     "Disk full",
+		Alerts:    []string{eventAlertID(a1.Fingerprint())},
+		CreatedAt: now,
+	}
+	closed := &types.Event{
+		Title:     "Disk full before",
+		Alerts:    []string{eventAlertID(a1.Fingerprint())},
+		CreatedAt: now.Add(-time.Hour),
+		ClosedAt:  now.Add(-time.Minute),
+	}
+	other := &types.Event{
+		Title:     "Network down",
+		Alerts:    []string{eventAlertID(a2.Fingerprint())},
+		CreatedAt: now,
+	}
+	for _, e := range []*types.Event{open, closed, other} {
+		if _, err := events.Set(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := NewEventIssueLinker(events)
+	if err := l.LinkIssue("jira", "OPS-1", "https://jira.example.com/browse/OPS-1", a1); err != nil {
+		t.Fatal(err)
+	}
+
+	annotations := func(id uint64) model.LabelSet {
+		e, err := events.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e.Annotations
+	}
+	exp := model.LabelSet{
+		"jira_issue":     "OPS-1",
+		"jira_issue_url": "https://jira.example.com/browse/OPS-1",
+	}
+	if got := annotations(open.ID); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected annotations %v but got %v", exp, got)
+	}
+	// Closed events and events of other alerts are left unchanged.
+	for _, id := range []uint64{closed.ID, other.ID} {
+		if got := annotations(id); len(got) != 0 {
+			t.Errorf("expected event %d to have no annotations but got %v", id, got)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+const csvContentType = "text/csv"
+
+// wantsCSV returns true if the request asks for a CSV response either
+// through the format parameter or its Accept header.
+func wantsCSV(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "csv"
+	}
+	for _, v := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mt, _, err := mime.ParseMediaType(strings.TrimSpace(v)); err == nil && mt == csvContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// respondCSV streams n rows as a CSV file attachment of the given name.
+func respondCSV(w http.ResponseWriter, name string, header []string, n int, row func(i int) []string) {
+	w.Header().Set("Content-Type", csvContentType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".csv"))
+	w.WriteHeader(200)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		log.Errorf("Error writing CSV response: %s", err)
+		return
+	}
+	for i := 0; i < n; i++ {
+		if err := cw.Write(row(i)); err != nil {
+			log.Errorf("Error writing CSV response: %s", err)
+			return
+		}
+	}
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		log.Errorf("Error writing CSV response: %s", err)
+	}
+}
+
+var eventsCSVHeader = []string{
+	"id", "title", "kind", "level", "is_safe", "creator", "labels",
+	"annotations", "alerts", "created_at", "updated_at", "closed_at", "version",
+}
+
+func respondEventsCSV(w http.ResponseWriter, events []*types.Event) {
+	respondCSV(w, "events", eventsCSVHeader, len(events), func(i int) []string {
+		e := events[i]
+		return []string{
+			strconv.FormatUint(e.ID, 10),
+			e.Title,
+			e.Kind,
+			e.Level,
+			e.IsSafe,
+			e.Creator,
+			csvLabels(e.Labels),
+			csvLabels(e.Annotations),
+			strings.Join(e.Alerts, " "),
+			csvTime(e.CreatedAt),
+			csvTime(e.UpdatedAt),
+			csvTime(e.ClosedAt),
+			strconv.FormatUint(e.Version, 10),
+		}
+	})
+}
+
+var alertsCSVHeader = []string{
+	"fingerprint", "labels", "annotations", "starts_at", "ends_at", "generator_url",
+}
+
+func respondAlertsCSV(w http.ResponseWriter, alerts []*types.Alert) {
+	// Hide expected end timestamps of alerts as in the JSON representation.
+	as := types.Alerts(alerts...)
+
+	respondCSV(w, "alerts", alertsCSVHeader, len(as), func(i int) []string {
+		a := as[i]
+		return []string{
+			a.Fingerprint().String(),
+			csvLabels(a.Labels),
+			csvLabels(a.Annotations),
+			csvTime(a.StartsAt),
+			csvTime(a.EndsAt),
+			a.GeneratorURL,
+		}
+	})
+}
+
+func csvLabels(ls model.LabelSet) string {
+	if len(ls) == 0 {
+		return ""
+	}
+	return ls.String()
+}
+
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
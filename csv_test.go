@@ -0,0 +1,78 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestWantsCSV(t *testing.T) {
+	for _, c := range []struct {
+		url, accept string
+		csv         bool
+	}{
+		{url: "/api/v1/events", csv: false},
+		{url: "/api/v1/events?format=csv", csv: true},
+		{url: "/api/v1/events?format=json", accept: "text/csv", csv: false},
+		{url: "/api/v1/events", accept: "application/json, text/csv;q=0.9", csv: true},
+		{url: "/api/v1/events", accept: "application/json", csv: false},
+	} {
+		r := httptest.NewRequest("GET", c.url, nil)
+		r.Header.Set("Accept", c.accept)
+
+		if res := wantsCSV(r); res != c.csv {
+			t.Errorf("%s (Accept: %q): expected %v but got %v", c.url, c.accept, c.csv, res)
+		}
+	}
+}
+
+func TestRespondEventsCSV(t *testing.T) {
+	created := time.Date(2016, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	w := httptest.NewRecorder()
+	respondEventsCSV(w, []*types.Event{
+		{
+			ID:        1,
+			Title:     "Outage, datacenter \"A\"",
+			Creator:   "user",
+			Alerts:    []string{"1", "2"},
+			Labels:    model.LabelSet{"team": "db"},
+			CreatedAt: created,
+			Version:   2,
+		},
+	})
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		eventsCSVHeader,
+		{"1", "Outage, datacenter \"A\"", "", "", "", "user", `{team="db"}`, "", "1 2", "2016-06-01T12:00:00Z", "", "", "2"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected rows %q but got %q", expected, rows)
+	}
+}
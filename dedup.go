@@ -0,0 +1,93 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+var numDuplicatesDropped = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "dispatcher_duplicate_alerts_dropped_total",
+	Help:      "The total number of alert updates dropped by the dispatcher because they only extended the end time of the alert.",
+})
+
+func init() {
+	prometheus.MustRegister(numDuplicatesDropped)
+}
+
+// dedupEntry is the last alert update passed on by a Deduper.
+type dedupEntry struct {
+	alert *types.Alert
+	at    time.Time
+}
+
+// A Deduper drops updates of firing alerts that change nothing but their
+// end time within a window after the last update passed on. It must only
+// be used by a single goroutine.
+type Deduper struct {
+	window time.Duration
+	seen   map[model.Fingerprint]*dedupEntry
+}
+
+// NewDeduper returns a new Deduper with the given window.
+func NewDeduper(window time.Duration) *Deduper {
+	return &Deduper{
+		window: window,
+		seen:   map[model.Fingerprint]*dedupEntry{},
+	}
+}
+
+// Duplicate returns true iff the alert update is to be dropped. Updates are
+// only dropped while the end time of the last update passed on keeps the
+// alert firing beyond the window so that alerts do not appear resolved to
+// the aggregation groups in between.
+func (d *Deduper) Duplicate(a *types.Alert, now time.Time) bool {
+	fp := a.Fingerprint()
+
+	if a.ResolvedAt(now) {
+		delete(d.seen, fp)
+		return false
+	}
+	if e, ok := d.seen[fp]; ok && now.Sub(e.at) < d.window && e.alert.EndsAt.After(now.Add(d.window)) && sameButEndsAt(e.alert, a) {
+		numDuplicatesDropped.Inc()
+		return true
+	}
+	d.seen[fp] = &dedupEntry{alert: a, at: now}
+	return false
+}
+
+// gc removes the updates passed on before the window.
+func (d *Deduper) gc(now time.Time) {
+	for fp, e := range d.seen {
+		if now.Sub(e.at) >= d.window {
+			delete(d.seen, fp)
+		}
+	}
+}
+
+// sameButEndsAt returns true iff the alerts differ at most in their end
+// and update times.
+func sameButEndsAt(a, b *types.Alert) bool {
+	return a.Labels.Equal(b.Labels) &&
+		a.Annotations.Equal(b.Annotations) &&
+		a.StartsAt.Equal(b.StartsAt) &&
+		a.GeneratorURL == b.GeneratorURL &&
+		a.Timeout == b.Timeout
+}
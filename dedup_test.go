@@ -0,0 +1,96 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestDeduper(t *testing.T) {
+	var (
+		now   = time.Now()
+		start = now.Add(-time.Hour)
+	)
+	newAlert := func(endsAt time.Time, summary model.LabelValue) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "HighLatency"},
+				Annotations: model.LabelSet{"summary": summary},
+				StartsAt:    start,
+				EndsAt:      endsAt,
+			},
+		}
+	}
+
+	d := NewDeduper(time.Minute)
+
+	cases := []struct {
+		alert *types.Alert
+		at    time.Time
+		dup   bool
+	}{
+		{
+			alert: newAlert(now.Add(3*time.Minute), "slow"),
+			at:    now,
+			dup:   false,
+		}, {
+			// Only the end time changed.
+			alert: newAlert(now.Add(3*time.Minute+10*time.Second), "slow"),
+			at:    now.Add(10 * time.Second),
+			dup:   true,
+		}, {
+			// The annotations changed.
+			alert: newAlert(now.Add(3*time.Minute+20*time.Second), "very slow"),
+			at:    now.Add(20 * time.Second),
+			dup:   false,
+		}, {
+			alert: newAlert(now.Add(3*time.Minute+30*time.Second), "very slow"),
+			at:    now.Add(30 * time.Second),
+			dup:   true,
+		}, {
+			// The window since the last update passed on elapsed.
+			alert: newAlert(now.Add(4*time.Minute+20*time.Second), "very slow"),
+			at:    now.Add(80 * time.Second),
+			dup:   false,
+		}, {
+			// Resolving updates are always passed on.
+			alert: newAlert(now.Add(85*time.Second), "very slow"),
+			at:    now.Add(90 * time.Second),
+			dup:   false,
+		},
+	}
+	for i, c := range cases {
+		if dup := d.Duplicate(c.alert, c.at); dup != c.dup {
+			t.Errorf("case %d: expected duplicate %v but got %v", i, c.dup, dup)
+		}
+	}
+
+	// Updates are passed on if the last end time would make the alert
+	// resolve within the window.
+	d = NewDeduper(time.Minute)
+	d.Duplicate(newAlert(now.Add(30*time.Second), "slow"), now)
+	if d.Duplicate(newAlert(now.Add(40*time.Second), "slow"), now.Add(10*time.Second)) {
+		t.Errorf("expected update extending an alert about to resolve to be passed on")
+	}
+
+	d.gc(now.Add(2 * time.Minute))
+	if len(d.seen) != 0 {
+		t.Errorf("expected all entries to be removed but got %d", len(d.seen))
+	}
+}
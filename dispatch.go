@@ -1,85 +1,298 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"runtime"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/model"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/tracing"
 	"github.com/prometheus/alertmanager/types"
 )
 
+var (
+	numDeadlineExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notifications_deadline_exceeded_total",
+		Help:      "The total number of notifications that exceeded their deadline.",
+	}, []string{"receiver", "policy"})
+
+	numExpiredAlerts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "dispatcher_alerts_expired_total",
+		Help:      "The total number of alerts without end time resolved because they were not updated within the resolve timeout.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(numDeadlineExceeded)
+	prometheus.MustRegister(numExpiredAlerts)
+}
+
 // Dispatcher sorts incoming alerts into aggregation groups and
 // assigns the correct notifiers to each.
 type Dispatcher struct {
 	route    *Route
 	alerts   provider.Alerts
 	notifier notify.Notifier
+	// Protects the notifier, which is replaced on configuration reloads.
+	notifierMtx sync.RWMutex
 
 	marker types.Marker
 
-	aggrGroups map[*Route]map[model.Fingerprint]*aggrGroup
-	mtx        sync.RWMutex
+	aggrGroups *groupMap
+	// Protects the routing tree and collaborators of the aggregation groups.
+	// Replacing the aggregation groups requires the write lock, accessing
+	// them a read lock.
+	mtx sync.RWMutex
+
+	// snapshot is restored when the dispatcher is started.
+	snapshot *DispatcherSnapshot
+
+	// Policies for notifications exceeding their deadline by receiver.
+	deadlinePolicies map[string]config.DeadlinePolicy
+	deadLetters      provider.DeadLetters
+
+	// Attaches incoming alerts to events if set.
+	correlator *Correlator
+	// Creates events for groups firing for the first time if set.
+	eventCreator *EventCreator
+	// Mutes alerts of services under maintenance if set.
+	maintenance types.Muter
+	// Alerts without end time not updated for this long are resolved.
+	// Zero disables expiring alerts.
+	resolveTimeout time.Duration
+	// Returns how long to wait before notifying to let preceding peers
+	// of the cluster notify first. Nil if not clustered.
+	peerWait func() time.Duration
+	// Drops alert updates only extending the alert's end time if set.
+	deduper *Deduper
+	// Sends notifications through a bounded number of workers in order of
+	// route priority if set. Otherwise each group notifies on its own.
+	queue *NotifyQueue
 
 	done   chan struct{}
 	ctx    context.Context
 	cancel func()
 
+	// Whether Run and Stop were called. Stop only waits for Run to
+	// finish if it was started, and Run does nothing once stopped.
+	runMtx           sync.Mutex
+	started, stopped bool
+
 	log log.Logger
 }
 
 // NewDispatcher returns a new Dispatcher.
 func NewDispatcher(ap provider.Alerts, r *Route, n notify.Notifier, mk types.Marker) *Dispatcher {
 	disp := &Dispatcher{
-		alerts:   ap,
-		notifier: n,
-		route:    r,
-		marker:   mk,
-		log:      log.With("component", "dispatcher"),
+		alerts:     ap,
+		notifier:   n,
+		route:      r,
+		marker:     mk,
+		aggrGroups: newGroupMap(),
+		done:       make(chan struct{}),
+		log:        log.With("component", "dispatcher"),
 	}
+	disp.ctx, disp.cancel = context.WithCancel(context.Background())
+
 	return disp
 }
 
-// Run starts dispatching alerts incoming via the updates channel.
-func (d *Dispatcher) Run() {
-	d.done = make(chan struct{})
+// SetDeadlinePolicies sets how notifications to the given receivers that
+// exceed their deadline are handled. Dead letters are added to dl.
+func (d *Dispatcher) SetDeadlinePolicies(rcvs []*config.Receiver, dl provider.DeadLetters) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
 
+	d.deadlinePolicies = map[string]config.DeadlinePolicy{}
+	for _, rcv := range rcvs {
+		d.deadlinePolicies[rcv.Name] = rcv.DeadlineExceeded
+	}
+	d.deadLetters = dl
+}
+
+// SetCorrelator sets the correlator through which incoming alerts are
+// attached to events and events of resolved alerts are closed.
+func (d *Dispatcher) SetCorrelator(c *Correlator) {
 	d.mtx.Lock()
-	d.aggrGroups = map[*Route]map[model.Fingerprint]*aggrGroup{}
-	d.mtx.Unlock()
+	defer d.mtx.Unlock()
+
+	d.correlator = c
+}
+
+// SetEventCreator sets the creator of events for aggregation groups firing
+// for the first time.
+func (d *Dispatcher) SetEventCreator(c *EventCreator) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.eventCreator = c
+}
+
+// SetMaintenance sets the maintenance windows muting the alerts of
+// aggregation groups when they flush.
+func (d *Dispatcher) SetMaintenance(m types.Muter) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.maintenance = m
+}
+
+// SetResolveTimeout sets the time after which alerts whose end time was set
+// by the resolve timeout are resolved if they have not been updated. Zero
+// disables it.
+func (d *Dispatcher) SetResolveTimeout(timeout time.Duration) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.resolveTimeout = timeout
+}
+
+// SetPeerWait sets the function returning how long aggregation groups wait
+// before notifying so that peers of the cluster preceding this instance can
+// notify first.
+func (d *Dispatcher) SetPeerWait(f func() time.Duration) {
+	d.peerWait = f
+}
+
+// SetDeduper sets the deduper dropping incoming alert updates that only
+// extend the alert's end time before they are routed. It must be set
+// before the dispatcher is run.
+func (d *Dispatcher) SetDeduper(dd *Deduper) {
+	d.deduper = dd
+}
+
+// SetNotifyQueue sets the queue through which aggregation groups send their
+// notifications. It must be set before the dispatcher is run, which starts
+// and stops the queue's workers.
+func (d *Dispatcher) SetNotifyQueue(q *NotifyQueue) {
+	d.queue = q
+}
+
+// SetConcurrencyLimits sets the limits of notifications sent to each of
+// the receivers at once. They only apply if the dispatcher has a notify
+// queue.
+func (d *Dispatcher) SetConcurrencyLimits(rcvs []*config.Receiver) {
+	if d.queue != nil {
+		d.queue.SetReceivers(rcvs)
+	}
+}
 
-	d.ctx, d.cancel = context.WithCancel(context.Background())
+// Route returns the root of the dispatcher's routing tree.
+func (d *Dispatcher) Route() *Route {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	return d.route
+}
+
+// SetNotifier sets the notifier through which aggregation groups send
+// their notifications.
+func (d *Dispatcher) SetNotifier(n notify.Notifier) {
+	d.notifierMtx.Lock()
+	defer d.notifierMtx.Unlock()
+
+	d.notifier = n
+}
+
+// deadlinePolicy returns the deadline policy of the receiver. The caller
+// must hold the dispatcher's lock.
+func (d *Dispatcher) deadlinePolicy(receiver string) config.DeadlinePolicy {
+	if p, ok := d.deadlinePolicies[receiver]; ok && p != "" {
+		return p
+	}
+	return config.DeadlineRetryNextInterval
+}
+
+// Run starts dispatching alerts incoming via the updates channel.
+// A dispatcher can only be run once and does not run once stopped.
+func (d *Dispatcher) Run() {
+	d.runMtx.Lock()
+	if d.stopped {
+		d.runMtx.Unlock()
+		return
+	}
+	d.started = true
+	d.runMtx.Unlock()
+
+	if d.queue != nil {
+		d.queue.Run()
+	}
+	d.restore()
 
 	d.run(d.alerts.Subscribe())
+
+	if d.queue != nil {
+		d.queue.Stop()
+	}
 	close(d.done)
 }
 
 // AlertBlock contains a list of alerts associated with a set of
 // routing options.
 type AlertBlock struct {
-	RouteOpts *RouteOpts  `json:"routeOpts"`
-	Alerts    []*APIAlert `json:"alerts"`
+	GroupKey    string      `json:"groupKey"`
+	RouteOpts   *RouteOpts  `json:"routeOpts"`
+	Alerts      []*APIAlert `json:"alerts"`
+	PausedUntil *time.Time  `json:"pausedUntil,omitempty"`
+
+	// State of the aggregation group's notifications.
+	HasSent   bool      `json:"hasSent"`
+	NextFlush time.Time `json:"nextFlush"`
+	// LastFlush is the last time notifications were attempted. It is nil
+	// if the group never notified.
+	LastFlush        *time.Time   `json:"lastFlush,omitempty"`
+	LastNotifyStatus NotifyStatus `json:"lastNotifyStatus,omitempty"`
+	// ReassignedTo is the receiver the group was handed over to, if any.
+	ReassignedTo string `json:"reassignedTo,omitempty"`
 }
 
+// NotifyStatus is the outcome of a group's notification attempt.
+type NotifyStatus string
+
+// Outcomes of notification attempts.
+const (
+	NotifySuccess NotifyStatus = "success"
+	NotifyFailed  NotifyStatus = "failed"
+)
+
 // APIAlert is the API representation of an alert, which is a regular alert
 // annotated with silencing and inhibition info.
 type APIAlert struct {
 	*types.Alert
 
-	Inhibited bool   `json:"inhibited"`
-	Silenced  uint64 `json:"silenced,omitempty"`
+	Inhibited   bool                 `json:"inhibited"`
+	InhibitedBy *types.InhibitSource `json:"inhibitedBy,omitempty"`
+	Silenced    uint64               `json:"silenced,omitempty"`
+	Acked       *types.Ack           `json:"acked,omitempty"`
+	Flapping    *types.FlapState     `json:"flapping,omitempty"`
+	// Maintenance is the ID of the maintenance window muting the alert.
+	Maintenance uint64 `json:"maintenance,omitempty"`
+	// SourceTrust is the trust level of the alert's source.
+	SourceTrust string `json:"sourceTrust,omitempty"`
 }
 
 // AlertGroup is a list of alert blocks grouped by the same label set.
 type AlertGroup struct {
-	Labels model.LabelSet `json:"labels"`
-	Blocks []*AlertBlock  `json:"blocks"`
+	Labels      model.LabelSet `json:"labels"`
+	Fingerprint string         `json:"fingerprint"`
+	Blocks      []*AlertBlock  `json:"blocks"`
 }
 
 // AlertOverview is a representation of all active alerts in the system.
@@ -89,55 +302,702 @@ func (ao AlertOverview) Swap(i, j int)      { ao[i], ao[j] = ao[j], ao[i] }
 func (ao AlertOverview) Less(i, j int) bool { return ao[i].Labels.Before(ao[j].Labels) }
 func (ao AlertOverview) Len() int           { return len(ao) }
 
+// Sort orders of an AlertOverview.
+const (
+	SortByLabels = "labels"
+	SortByAlerts = "alerts"
+)
+
+// GroupsOptions restricts and orders the AlertOverview returned by
+// GroupsFiltered.
+type GroupsOptions struct {
+	// Only include blocks of routes notifying the given receiver.
+	Receiver string
+	// Only include alerts matching all matchers.
+	Matchers types.Matchers
+	// Order of the groups. Defaults to SortByLabels.
+	SortBy  string
+	Reverse bool
+	// Pagination of the sorted groups. A limit of zero returns all
+	// groups after the offset.
+	Offset int
+	Limit  int
+}
+
 // Groups populates an AlertOverview from the dispatcher's internal state.
 func (d *Dispatcher) Groups() AlertOverview {
-	var overview AlertOverview
+	return d.GroupsFiltered(GroupsOptions{})
+}
 
+// GroupsFiltered populates an AlertOverview from the dispatcher's internal
+// state restricted by the given options.
+func (d *Dispatcher) GroupsFiltered(opts GroupsOptions) AlertOverview {
 	d.mtx.RLock()
 	defer d.mtx.RUnlock()
 
+	return d.groupsFiltered(opts)
+}
+
+// GroupsView calls the function with the AlertOverview restricted by the
+// given options. No alerts are dispatched to aggregation groups until the
+// function returns, so it should not block.
+func (d *Dispatcher) GroupsView(opts GroupsOptions, fn func(AlertOverview) error) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	return fn(d.groupsFiltered(opts))
+}
+
+func (d *Dispatcher) groupsFiltered(opts GroupsOptions) AlertOverview {
+	var overview AlertOverview
+
 	seen := map[model.Fingerprint]*AlertGroup{}
+	now := time.Now()
 
-	for route, ags := range d.aggrGroups {
+	for route, ags := range d.aggrGroups.byRoute() {
+		if opts.Receiver != "" && route.RouteOpts.Receiver != opts.Receiver {
+			continue
+		}
 		for _, ag := range ags {
+			// Alerts of groups by all labels have the group's labels, so
+			// groups not matching can be skipped without listing alerts.
+			if route.RouteOpts.GroupByAll && !opts.Matchers.Match(ag.labels) {
+				continue
+			}
+			var apiAlerts []*APIAlert
+			for _, a := range ag.alertSlice() {
+				if !a.EndsAt.IsZero() && a.EndsAt.Before(now) {
+					continue
+				}
+				if !opts.Matchers.Match(a.Labels) {
+					continue
+				}
+
+				sid, _ := d.marker.Silenced(a.Fingerprint())
+				src, inhibited := d.marker.InhibitedBy(a.Fingerprint())
+
+				apiAlert := &APIAlert{
+					Alert:       a,
+					Inhibited:   inhibited,
+					InhibitedBy: src,
+					Silenced:    sid,
+					SourceTrust: a.Source.Trust(),
+				}
+				if ack, ok := d.marker.Acked(a.Fingerprint()); ok && ack.Suppresses(a, now) {
+					apiAlert.Acked = ack
+				}
+				if f, ok := d.marker.Flapping(a.Fingerprint()); ok {
+					apiAlert.Flapping = f
+				}
+				if id, ok := d.marker.Maintenance(a.Fingerprint()); ok {
+					apiAlert.Maintenance = id
+				}
+				apiAlerts = append(apiAlerts, apiAlert)
+			}
+			if len(apiAlerts) == 0 {
+				continue
+			}
+
 			alertGroup, ok := seen[ag.fingerprint()]
 			if !ok {
-				alertGroup = &AlertGroup{Labels: ag.labels}
+				alertGroup = &AlertGroup{
+					Labels:      ag.labels,
+					Fingerprint: ag.fingerprint().String(),
+				}
 
 				seen[ag.fingerprint()] = alertGroup
 				overview = append(overview, alertGroup)
 			}
 
-			now := time.Now()
+			block := &AlertBlock{
+				GroupKey:  ag.groupKey(),
+				RouteOpts: &route.RouteOpts,
+				Alerts:    apiAlerts,
+			}
+			if until, ok := ag.pausedUntil(now); ok {
+				block.PausedUntil = &until
+			}
+			ag.setState(block)
+			alertGroup.Blocks = append(alertGroup.Blocks, block)
+		}
+	}
+
+	var s sort.Interface = overview
+	if opts.SortBy == SortByAlerts {
+		s = byAlerts{overview}
+	}
+	if opts.Reverse {
+		s = sort.Reverse(s)
+	}
+	sort.Sort(s)
+
+	if opts.Offset > len(overview) {
+		opts.Offset = len(overview)
+	}
+	overview = overview[opts.Offset:]
+
+	if opts.Limit > 0 && opts.Limit < len(overview) {
+		overview = overview[:opts.Limit]
+	}
+	return overview
+}
+
+// SilencePreview holds the firing alerts and aggregation groups a silence
+// would mute.
+type SilencePreview struct {
+	// Active is true if the silence's time range or, for recurring
+	// silences, one of its windows includes the time of the preview.
+	Active bool                   `json:"active"`
+	Alerts []*APIAlert            `json:"alerts"`
+	Groups []*SilencePreviewGroup `json:"groups"`
+}
+
+// SilencePreviewGroup is an aggregation group with firing alerts muted by
+// a silence.
+type SilencePreviewGroup struct {
+	GroupKey string         `json:"groupKey"`
+	Labels   model.LabelSet `json:"labels"`
+	Receiver string         `json:"receiver"`
+	// Number of the group's firing alerts muted by the silence and in total.
+	Muted  int `json:"muted"`
+	Firing int `json:"firing"`
+}
+
+// PreviewSilence returns the alerts firing at the given time and the
+// aggregation groups that the silence matches, regardless of its time range.
+func (d *Dispatcher) PreviewSilence(sil *types.Silence, now time.Time) *SilencePreview {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	var (
+		alerts = map[model.Fingerprint]*types.Alert{}
+		groups = map[string]*SilencePreviewGroup{}
+	)
+	for route, ags := range d.aggrGroups.byRoute() {
+		for _, ag := range ags {
+			g := &SilencePreviewGroup{
+				GroupKey: ag.groupKey(),
+				Labels:   ag.labels,
+				Receiver: route.RouteOpts.Receiver,
+			}
+			for _, a := range ag.alertSlice() {
+				if a.ResolvedAt(now) {
+					continue
+				}
+				g.Firing++
+
+				if sil.Matches(a.Labels) {
+					g.Muted++
+					alerts[a.Fingerprint()] = a
+				}
+			}
+			if g.Muted > 0 {
+				groups[g.GroupKey] = g
+			}
+		}
+	}
+
+	active := !now.Before(sil.StartsAt) && !now.After(sil.EndsAt)
+	if sil.Recurrence != nil {
+		_, _, active = sil.Window(now)
+	}
+	p := &SilencePreview{
+		Active: active,
+		Alerts: make([]*APIAlert, 0, len(alerts)),
+		Groups: make([]*SilencePreviewGroup, 0, len(groups)),
+	}
+
+	fps := make(model.Fingerprints, 0, len(alerts))
+	for fp := range alerts {
+		fps = append(fps, fp)
+	}
+	sort.Sort(fps)
+
+	for _, fp := range fps {
+		sid, _ := d.marker.Silenced(fp)
+		src, inhibited := d.marker.InhibitedBy(fp)
+
+		p.Alerts = append(p.Alerts, &APIAlert{
+			Alert:       alerts[fp],
+			Inhibited:   inhibited,
+			InhibitedBy: src,
+			Silenced:    sid,
+			SourceTrust: alerts[fp].Source.Trust(),
+		})
+	}
+
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p.Groups = append(p.Groups, groups[k])
+	}
+	return p
+}
+
+// byAlerts orders an AlertOverview by the number of alerts in each group
+// in descending order. Groups of equal size are ordered by their labels.
+type byAlerts struct {
+	AlertOverview
+}
+
+func (ao byAlerts) Less(i, j int) bool {
+	ni, nj := ao.AlertOverview[i].numAlerts(), ao.AlertOverview[j].numAlerts()
+	if ni != nj {
+		return ni > nj
+	}
+	return ao.AlertOverview.Less(i, j)
+}
+
+func (ag *AlertGroup) numAlerts() int {
+	n := 0
+	for _, b := range ag.Blocks {
+		n += len(b.Alerts)
+	}
+	return n
+}
+
+// PauseGroup holds back notifications of all aggregation groups with the
+// given fingerprint until the given time. If inherit is true, alerts joining
+// a group while it is paused are held back as well. Otherwise only alerts that
+// were part of the group at the time of pausing are suppressed.
+// A zero time resumes the groups immediately.
+func (d *Dispatcher) PauseGroup(fp model.Fingerprint, until time.Time, inherit bool) error {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	groups := d.aggrGroups.byFingerprint(fp)
+	if len(groups) == 0 {
+		return provider.ErrNotFound
+	}
+	for _, ag := range groups {
+		ag.pause(until, inherit)
+	}
+	return nil
+}
+
+// ReassignGroup hands all aggregation groups with the given fingerprint
+// over to the given receiver, which is notified instead of the routes'
+// receivers and their escalations for as long as the groups exist.
+func (d *Dispatcher) ReassignGroup(fp model.Fingerprint, receiver string) error {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	groups := d.aggrGroups.byFingerprint(fp)
+	if len(groups) == 0 {
+		return provider.ErrNotFound
+	}
+	for _, ag := range groups {
+		ag.reassign(receiver)
+	}
+	return nil
+}
+
+// GroupLabels returns the labels of the aggregation groups with the given
+// fingerprint. It returns provider.ErrNotFound if no such group exists.
+func (d *Dispatcher) GroupLabels(fp model.Fingerprint) (model.LabelSet, error) {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	for _, ag := range d.aggrGroups.byFingerprint(fp) {
+		return ag.labels, nil
+	}
+	return nil, provider.ErrNotFound
+}
+
+// GroupAlerts returns the alerts of the aggregation groups with the given
+// fingerprint. It returns provider.ErrNotFound if no such group exists.
+func (d *Dispatcher) GroupAlerts(fp model.Fingerprint) ([]*types.Alert, error) {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	groups := d.aggrGroups.byFingerprint(fp)
+	if len(groups) == 0 {
+		return nil, provider.ErrNotFound
+	}
+	var alerts []*types.Alert
+	for _, ag := range groups {
+		alerts = append(alerts, ag.alertSlice()...)
+	}
+	return alerts, nil
+}
+
+// GroupByHash returns the labels and alerts of the aggregation group whose
+// hashed group key as sent in notifications is the given hash. The legacy
+// group key, the decimal fingerprint of the group labels, is accepted as
+// well. It returns provider.ErrNotFound if no such group exists.
+func (d *Dispatcher) GroupByHash(hash string) (model.LabelSet, []*types.Alert, error) {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	legacy, err := strconv.ParseUint(hash, 10, 64)
+
+	var res *aggrGroup
+	d.aggrGroups.each(func(_ *Route, ag *aggrGroup) {
+		if notify.HashGroupKey(ag.groupKey()) == hash {
+			res = ag
+		} else if res == nil && err == nil && uint64(ag.labels.Fingerprint()) == legacy {
+			res = ag
+		}
+	})
+	if res == nil {
+		return nil, nil, provider.ErrNotFound
+	}
+	return res.labels, res.alertSlice(), nil
+}
+
+// NextNotification returns the alerts the next notification of the
+// aggregation group with the given fingerprint would contain along with
+// the context it would be sent with. If groups of several routes have the
+// fingerprint, a group whose route notifies the given receiver is
+// preferred.
+func (d *Dispatcher) NextNotification(fp model.Fingerprint, receiver string) (context.Context, []*types.Alert, error) {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	var ag *aggrGroup
+	for route, g := range d.aggrGroups.byFingerprint(fp) {
+		if ag == nil || route.RouteOpts.Receiver == receiver {
+			ag = g
+		}
+	}
+	if ag == nil {
+		return nil, nil, provider.ErrNotFound
+	}
+	ctx, alerts := ag.nextNotification(time.Now())
+	return ctx, alerts, nil
+}
+
+// NextNotificationByKey is like NextNotification for the aggregation group
+// with the given group key.
+func (d *Dispatcher) NextNotificationByKey(key string) (context.Context, []*types.Alert, error) {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	var ag *aggrGroup
+	d.aggrGroups.each(func(_ *Route, g *aggrGroup) {
+		if g.groupKey() == key {
+			ag = g
+		}
+	})
+	if ag == nil {
+		return nil, nil, provider.ErrNotFound
+	}
+	ctx, alerts := ag.nextNotification(time.Now())
+	return ctx, alerts, nil
+}
+
+// snapshotVersion is the version of the DispatcherSnapshot format.
+const snapshotVersion = 1
+
+// DispatcherSnapshot holds the state of all aggregation groups of a
+// dispatcher so it can be restored after a restart.
+type DispatcherSnapshot struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Groups    []*GroupSnapshot `json:"groups"`
+}
+
+// GroupSnapshot holds the state of a single aggregation group.
+type GroupSnapshot struct {
+	// Route is the key of the route the group belongs to, which is told
+	// apart from routes with the same key by its fingerprint.
+	Route            string            `json:"route"`
+	RouteFingerprint model.Fingerprint `json:"routeFingerprint"`
+	Labels           model.LabelSet    `json:"labels"`
+	Alerts           []*types.Alert    `json:"alerts"`
+	HasSent          bool              `json:"hasSent"`
+	NextFlush        time.Time         `json:"nextFlush"`
+	// Start of the group's current firing streak used for escalations.
+	FiringSince time.Time `json:"firingSince"`
+	// Receiver the group was handed over to, if any.
+	ReassignedTo string `json:"reassignedTo,omitempty"`
+	// Whether an event was created for the group.
+	EventCreated bool `json:"eventCreated,omitempty"`
+	// Alerts that were notified about as firing.
+	NotifiedFiring []model.Fingerprint `json:"notifiedFiring,omitempty"`
+	// Firing alerts whose notification was recorded as a dead letter.
+	DeadLettered []model.Fingerprint `json:"deadLettered,omitempty"`
+}
+
+// LoadSnapshotFile reads a dispatcher snapshot from the given file.
+func LoadSnapshotFile(filename string) (*DispatcherSnapshot, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var s DispatcherSnapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	return &s, nil
+}
+
+// WriteFile atomically writes the snapshot to the given file.
+func (s *DispatcherSnapshot) WriteFile(filename string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// Snapshot returns the current state of all aggregation groups.
+func (d *Dispatcher) Snapshot() *DispatcherSnapshot {
+	s := &DispatcherSnapshot{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now(),
+		Groups:    []*GroupSnapshot{},
+	}
+
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	d.aggrGroups.each(func(_ *Route, ag *aggrGroup) {
+		s.Groups = append(s.Groups, ag.snapshot())
+	})
+	return s
+}
+
+// Restore sets a snapshot from which the aggregation groups are restored
+// once the dispatcher is started. Groups of routes that no longer exist
+// are dropped.
+func (d *Dispatcher) Restore(s *DispatcherSnapshot) {
+	d.snapshot = s
+}
+
+// restore recreates the aggregation groups of the pending snapshot.
+func (d *Dispatcher) restore() {
+	if d.snapshot == nil {
+		return
+	}
+	routes := map[routeID]*Route{}
+	d.route.Walk(func(r *Route) {
+		id := routeID{key: r.Key(), fp: r.Fingerprint()}
+		if _, ok := routes[id]; !ok {
+			routes[id] = r
+		}
+	})
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	now := time.Now()
+
+	for _, gs := range d.snapshot.Groups {
+		route, ok := routes[routeID{key: gs.Route, fp: gs.RouteFingerprint}]
+		if !ok {
+			d.log.With("route", gs.Route).Debug("Dropping snapshot of group for unknown route")
+			continue
+		}
+		// The timers of restored groups start as those of groups of newly
+		// received alerts, so restarts neither skip nor move their group
+		// wait and interval.
+		gs.NextFlush = now.Add(route.RouteOpts.GroupWait + route.RouteOpts.Jitter())
+		for _, a := range gs.Alerts {
+			if !gs.HasSent && a.StartsAt.Add(route.RouteOpts.GroupWait).Before(now) {
+				gs.NextFlush = now
+				break
+			}
+		}
+		d.aggrGroups.set(route, d.restoreGroup(route, gs, now))
+	}
+	d.snapshot = nil
+}
+
+// restoreGroup starts a new aggregation group for the route with the state
+// of the group snapshot. The caller must hold the dispatcher's lock.
+func (d *Dispatcher) restoreGroup(route *Route, gs *GroupSnapshot, now time.Time) *aggrGroup {
+	ag := d.newGroup(gs.Labels, route)
+	for _, a := range gs.Alerts {
+		ag.alerts[a.Fingerprint()] = a
+	}
+	ag.hasSent = gs.HasSent
+	ag.firingSince = gs.FiringSince
+	ag.reassignedTo = gs.ReassignedTo
+	ag.eventCreated = gs.EventCreated
+	for _, fp := range gs.NotifiedFiring {
+		ag.notifiedFiring[fp] = struct{}{}
+	}
+	for _, fp := range gs.DeadLettered {
+		if a, ok := ag.alerts[fp]; ok {
+			ag.deadLettered[fp] = a.StartsAt
+		}
+	}
+
+	wait := gs.NextFlush.Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	ag.resetTimer(wait)
+
+	go ag.run(d.notifyFunc(route.RouteOpts.Priority))
+	return ag
+}
+
+// routeID identifies equivalent routes across routing trees by the path
+// of matchers leading to them and their grouping labels.
+type routeID struct {
+	key string
+	fp  model.Fingerprint
+}
+
+// Reload replaces the routing tree of a running dispatcher. Aggregation
+// groups of routes whose matchers and grouping labels are unchanged are
+// moved to the equivalent route of the new tree and keep their alerts,
+// notification state and timers. Groups whose other routing options
+// changed are restarted with their state carried over, which aborts
+// notifications in progress. Groups of routes whose grouping labels
+// changed are merged into the groups their alerts now belong to, which
+// keep the notification state of the groups they were merged from. Groups
+// of routes that no longer exist are stopped.
+func (d *Dispatcher) Reload(r *Route) {
+	var (
+		routes = map[routeID]*Route{}
+		byKey  = map[string]*Route{}
+	)
+	r.Walk(func(r *Route) {
+		id := routeID{key: r.Key(), fp: r.Fingerprint()}
+		if _, ok := routes[id]; !ok {
+			routes[id] = r
+		}
+		if _, ok := byKey[id.key]; !ok {
+			byKey[id.key] = r
+		}
+	})
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	var (
+		now        = time.Now()
+		aggrGroups = newGroupMap()
+		oldGroups  = d.aggrGroups.byRoute()
+		// Groups of routes whose grouping labels changed by the
+		// route they are merged into.
+		regrouped = map[*Route][]*GroupSnapshot{}
+		kept      = map[*Route]bool{}
+	)
+	for old := range oldGroups {
+		if route, ok := routes[routeID{key: old.Key(), fp: old.Fingerprint()}]; ok {
+			kept[route] = true
+		}
+	}
+	for old, groups := range oldGroups {
+		route, ok := routes[routeID{key: old.Key(), fp: old.Fingerprint()}]
+		if !ok {
+			if route, ok := byKey[old.Key()]; ok && !kept[route] {
+				d.log.With("route", old.Key()).Debug("Regrouping groups of route with changed grouping labels")
+				for _, ag := range groups {
+					ag.stop()
+					regrouped[route] = append(regrouped[route], ag.snapshot())
+				}
+				continue
+			}
+			d.log.With("route", old.Key()).Debug("Stopping groups of removed route")
+			for _, ag := range groups {
+				ag.stop()
+			}
+			continue
+		}
+		for _, ag := range groups {
+			// Routes with the same key and grouping may occur more than once
+			// in the old tree. Only the first of their groups is kept.
+			if _, ok := aggrGroups.get(route, ag.fingerprint()); ok {
+				ag.stop()
+				continue
+			}
+			if reflect.DeepEqual(old.RouteOpts, route.RouteOpts) {
+				ag.mtx.Lock()
+				ag.deadlinePolicy = d.deadlinePolicy(route.RouteOpts.Receiver)
+				ag.eventCreator = d.eventCreator
+				ag.mtx.Unlock()
+
+				aggrGroups.set(route, ag)
+				continue
+			}
+			ag.stop()
+			aggrGroups.set(route, d.restoreGroup(route, ag.snapshot(), now))
+		}
+	}
+	for route, gss := range regrouped {
+		for _, gs := range regroup(route, gss) {
+			aggrGroups.set(route, d.restoreGroup(route, gs, now))
+		}
+	}
+	d.route = r
+	d.aggrGroups = aggrGroups
+}
+
+// regroup distributes the alerts of the group snapshots to the groups of
+// the route by its grouping labels. A group has sent notifications, was
+// notified about alerts, recorded them as dead letters, and created an
+// event if any of the groups its alerts came from did. It is flushed when
+// the earliest of them would have been and keeps their receiver only if
+// they agree on it.
+func regroup(route *Route, gss []*GroupSnapshot) []*GroupSnapshot {
+	var (
+		res  []*GroupSnapshot
+		byFp = map[model.Fingerprint]*GroupSnapshot{}
+	)
+	for _, old := range gss {
+		notified := map[model.Fingerprint]struct{}{}
+		for _, fp := range old.NotifiedFiring {
+			notified[fp] = struct{}{}
+		}
+		deadLettered := map[model.Fingerprint]struct{}{}
+		for _, fp := range old.DeadLettered {
+			deadLettered[fp] = struct{}{}
+		}
+
+		for _, a := range old.Alerts {
+			labels := route.RouteOpts.GroupLabels(a.Labels)
 
-			var apiAlerts []*APIAlert
-			for _, a := range ag.alertSlice() {
-				if !a.EndsAt.IsZero() && a.EndsAt.Before(now) {
-					continue
+			gs, ok := byFp[labels.Fingerprint()]
+			if !ok {
+				gs = &GroupSnapshot{
+					Route:            route.Key(),
+					RouteFingerprint: route.Fingerprint(),
+					Labels:           labels,
+					NextFlush:        old.NextFlush,
+					FiringSince:      old.FiringSince,
+					ReassignedTo:     old.ReassignedTo,
 				}
+				byFp[labels.Fingerprint()] = gs
+				res = append(res, gs)
+			}
+			gs.Alerts = append(gs.Alerts, a)
+			gs.HasSent = gs.HasSent || old.HasSent
+			gs.EventCreated = gs.EventCreated || old.EventCreated
 
-				sid, _ := d.marker.Silenced(a.Fingerprint())
-
-				apiAlerts = append(apiAlerts, &APIAlert{
-					Alert:     a,
-					Inhibited: d.marker.Inhibited(a.Fingerprint()),
-					Silenced:  sid,
-				})
+			if old.NextFlush.Before(gs.NextFlush) {
+				gs.NextFlush = old.NextFlush
 			}
-			if len(apiAlerts) == 0 {
-				continue
+			if !old.FiringSince.IsZero() && (gs.FiringSince.IsZero() || old.FiringSince.Before(gs.FiringSince)) {
+				gs.FiringSince = old.FiringSince
+			}
+			if old.ReassignedTo != gs.ReassignedTo {
+				gs.ReassignedTo = ""
+			}
+			if _, ok := notified[a.Fingerprint()]; ok {
+				gs.NotifiedFiring = append(gs.NotifiedFiring, a.Fingerprint())
+			}
+			if _, ok := deadLettered[a.Fingerprint()]; ok {
+				gs.DeadLettered = append(gs.DeadLettered, a.Fingerprint())
 			}
-
-			alertGroup.Blocks = append(alertGroup.Blocks, &AlertBlock{
-				RouteOpts: &route.RouteOpts,
-				Alerts:    apiAlerts,
-			})
 		}
 	}
-
-	sort.Sort(overview)
-
-	return overview
+	return res
 }
 
 func (d *Dispatcher) run(it provider.AlertIterator) {
@@ -146,6 +1006,28 @@ func (d *Dispatcher) run(it provider.AlertIterator) {
 
 	defer it.Close()
 
+	// Alerts are routed and inserted into their aggregation groups by
+	// several workers. Updates of the same alert are handled by the same
+	// worker to retain their order.
+	var (
+		wg      sync.WaitGroup
+		workers = make([]chan *types.Alert, runtime.GOMAXPROCS(0))
+	)
+	for i := range workers {
+		workers[i] = make(chan *types.Alert, 64)
+		wg.Add(1)
+		go func(alerts <-chan *types.Alert) {
+			defer wg.Done()
+			d.routeAlerts(alerts)
+		}(workers[i])
+	}
+	defer func() {
+		for _, w := range workers {
+			close(w)
+		}
+		wg.Wait()
+	}()
+
 	for {
 		select {
 		case alert, ok := <-it.Next():
@@ -165,23 +1047,41 @@ func (d *Dispatcher) run(it provider.AlertIterator) {
 				continue
 			}
 
-			for _, r := range d.route.Match(alert.Labels) {
-				d.processAlert(alert, r)
+			if d.deduper != nil && d.deduper.Duplicate(alert, time.Now()) {
+				continue
+			}
+
+			d.mtx.RLock()
+			correlator := d.correlator
+			d.mtx.RUnlock()
+
+			if correlator != nil {
+				correlator.Correlate(alert)
 			}
 
+			workers[uint64(alert.Fingerprint())%uint64(len(workers))] <- alert
+
 		case <-cleanup.C:
-			d.mtx.Lock()
+			d.mtx.RLock()
+			var (
+				empty      = d.aggrGroups.removeEmpty()
+				correlator = d.correlator
+			)
+			d.mtx.RUnlock()
+
+			for _, ag := range empty {
+				ag.stop()
+			}
 
-			for _, groups := range d.aggrGroups {
-				for _, ag := range groups {
-					if ag.empty() {
-						ag.stop()
-						delete(groups, ag.fingerprint())
-					}
-				}
+			if d.deduper != nil {
+				d.deduper.gc(time.Now())
 			}
 
-			d.mtx.Unlock()
+			d.expireStale(time.Now())
+
+			if correlator != nil {
+				correlator.CloseResolved(time.Now())
+			}
 
 		case <-d.ctx.Done():
 			return
@@ -189,86 +1089,248 @@ func (d *Dispatcher) run(it provider.AlertIterator) {
 	}
 }
 
-// Stop the dispatcher.
+// expireStale resolves the alerts in the aggregation groups whose end time
+// was only set by the resolve timeout on receipt and that were not updated
+// within the resolve timeout. The resolved alerts are stored to be handled
+// like any other update, which records their resolution in the alert history
+// and the event bus.
+func (d *Dispatcher) expireStale(now time.Time) {
+	d.mtx.RLock()
+	timeout := d.resolveTimeout
+	if timeout <= 0 {
+		d.mtx.RUnlock()
+		return
+	}
+
+	var (
+		expired []*types.Alert
+		seen    = map[model.Fingerprint]struct{}{}
+	)
+	d.aggrGroups.each(func(_ *Route, ag *aggrGroup) {
+		for _, a := range ag.alertSlice() {
+			if !a.Timeout || now.Sub(a.UpdatedAt) < timeout {
+				continue
+			}
+			fp := a.Fingerprint()
+			if _, ok := seen[fp]; ok {
+				continue
+			}
+			seen[fp] = struct{}{}
+
+			e := *a
+			if end := a.UpdatedAt.Add(timeout); end.Before(e.EndsAt) {
+				e.EndsAt = end
+			}
+			e.UpdatedAt = now
+			// The end time is no longer the expected one of a
+			// timeout, so the alert is not expired again.
+			e.Timeout = false
+			expired = append(expired, &e)
+		}
+	})
+	d.mtx.RUnlock()
+
+	if len(expired) == 0 {
+		return
+	}
+	for _, a := range expired {
+		d.log.With("alert", a).Infof("Resolving alert not updated within %s", timeout)
+	}
+	numExpiredAlerts.Add(float64(len(expired)))
+
+	if err := d.alerts.Put(expired...); err != nil {
+		d.log.Errorf("Error storing expired alerts: %s", err)
+	}
+}
+
+// routeAlerts inserts the received alerts into the aggregation groups of
+// the routes they match.
+func (d *Dispatcher) routeAlerts(alerts <-chan *types.Alert) {
+	for alert := range alerts {
+		d.mtx.RLock()
+		routes := d.route.Match(alert.RoutingLabels())
+		d.mtx.RUnlock()
+
+		for _, r := range routes {
+			d.processAlert(alert, r)
+		}
+	}
+}
+
+// Stop the dispatcher. If it is running, Stop waits for Run to return.
 func (d *Dispatcher) Stop() {
-	if d == nil || d.cancel == nil {
+	if d == nil {
 		return
 	}
+	d.runMtx.Lock()
+	d.stopped = true
+	started := d.started
+	d.runMtx.Unlock()
+
 	d.cancel()
-	d.cancel = nil
 
-	<-d.done
+	if started {
+		<-d.done
+	}
 }
 
 // notifyFunc is a function that performs notifcation for the alert
 // with the given fingerprint. It aborts on context cancelation.
-// Returns false iff notifying failed.
-type notifyFunc func(context.Context, ...*types.Alert) bool
+type notifyFunc func(context.Context, ...*types.Alert) error
 
 // processAlert determines in which aggregation group the alert falls
 // and insert it.
 func (d *Dispatcher) processAlert(alert *types.Alert, route *Route) {
-	group := model.LabelSet{}
+	group := route.RouteOpts.GroupLabels(alert.Labels)
+	fp := group.Fingerprint()
 
-	for ln, lv := range alert.Labels {
-		if _, ok := route.RouteOpts.GroupBy[ln]; ok {
-			group[ln] = lv
-		}
-	}
+	_, span := tracing.Start(context.Background(), "dispatcher.process_alert")
+	defer span.End()
 
-	fp := group.Fingerprint()
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
 
-	d.mtx.Lock()
-	groups, ok := d.aggrGroups[route]
-	if !ok {
-		groups = map[model.Fingerprint]*aggrGroup{}
-		d.aggrGroups[route] = groups
+	ag := d.aggrGroups.insert(route, fp, alert, func() *aggrGroup {
+		ag := d.newGroup(group, route)
+		go ag.run(d.notifyFunc(route.RouteOpts.Priority))
+		return ag
+	})
+	// Formatting the attributes is skipped for every alert if tracing is
+	// disabled or the span is not sampled.
+	if span.IsRecording() {
+		span.SetAttributes(
+			tracing.String("alert", alert.Fingerprint().String()),
+			tracing.String("group_key", ag.groupKey()),
+			tracing.String("receiver", route.RouteOpts.Receiver),
+		)
 	}
-	d.mtx.Unlock()
+}
 
-	// If the group does not exist, create it.
-	ag, ok := groups[fp]
-	if !ok {
-		ag = newAggrGroup(d.ctx, group, &route.RouteOpts)
-		groups[fp] = ag
+// newGroup returns a new aggregation group for the route set up with the
+// dispatcher's collaborators. The caller must hold the dispatcher's lock.
+func (d *Dispatcher) newGroup(labels model.LabelSet, route *Route) *aggrGroup {
+	ag := newAggrGroup(d.ctx, labels, route)
+	ag.deadlinePolicy = d.deadlinePolicy(route.RouteOpts.Receiver)
+	ag.deadLetters = d.deadLetters
+	ag.marker = d.marker
+	ag.maintenance = d.maintenance
+	ag.peerWait = d.peerWait
+	ag.eventCreator = d.eventCreator
 
-		go ag.run(func(ctx context.Context, alerts ...*types.Alert) bool {
-			err := d.notifier.Notify(ctx, alerts...)
-			if err != nil {
-				log.Errorf("Notify for %d alerts failed: %s", len(alerts), err)
-			}
-			return err == nil
-		})
+	return ag
+}
+
+// notifyFunc returns the function through which aggregation groups of a
+// route with the given priority send their notifications.
+func (d *Dispatcher) notifyFunc(priority int) notifyFunc {
+	nf := func(ctx context.Context, alerts ...*types.Alert) error {
+		d.notifierMtx.RLock()
+		n := d.notifier
+		d.notifierMtx.RUnlock()
+
+		err := n.Notify(ctx, alerts...)
+		if err != nil {
+			log.Errorf("Notify for %d alerts failed: %s", len(alerts), err)
+		}
+		return err
+	}
+	if d.queue == nil {
+		return nf
+	}
+	return func(ctx context.Context, alerts ...*types.Alert) error {
+		return d.queue.Notify(ctx, priority, nf, alerts...)
 	}
+}
+
+// Redrive sends the notification of a dead letter again through the
+// notification pipeline.
+func (d *Dispatcher) Redrive(ctx context.Context, l *types.DeadLetter) error {
+	ctx = notify.WithNow(ctx, time.Now())
+	ctx = notify.WithGroupKey(ctx, l.GroupKey)
+	ctx = notify.WithGroupLabels(ctx, l.GroupLabels)
+	ctx = notify.WithReceiver(ctx, l.Receiver)
+	ctx = notify.WithRepeatInterval(ctx, l.RepeatInterval)
 
-	ag.insert(alert)
+	d.notifierMtx.RLock()
+	n := d.notifier
+	d.notifierMtx.RUnlock()
+
+	return n.Notify(ctx, l.Alerts...)
 }
 
 // aggrGroup aggregates alert fingerprints into groups to which a
 // common set of routing options applies.
 // It emits notifications in the specified intervals.
 type aggrGroup struct {
-	labels  model.LabelSet
-	opts    *RouteOpts
-	routeFP model.Fingerprint
-	log     log.Logger
+	labels   model.LabelSet
+	opts     *RouteOpts
+	routeKey string
+	routeFP  model.Fingerprint
+	log      log.Logger
 
 	ctx    context.Context
 	cancel func()
 	done   chan struct{}
 	next   *time.Timer
 
-	mtx     sync.RWMutex
-	alerts  map[model.Fingerprint]*types.Alert
-	hasSent bool
+	mtx       sync.RWMutex
+	alerts    map[model.Fingerprint]*types.Alert
+	hasSent   bool
+	nextFlush time.Time
+	// Time of the last successful notification.
+	lastNotified time.Time
+	// Alerts that were successfully notified about as firing.
+	notifiedFiring map[model.Fingerprint]struct{}
+	// Start times of firing alerts whose notification was recorded as a
+	// dead letter. They are not notified about until they fire anew.
+	deadLettered map[model.Fingerprint]time.Time
+	// Number of consecutive immediate retries after exceeded deadlines.
+	deadlineRetries int
+	// Time and outcome of the last notification attempt.
+	lastFlush        time.Time
+	lastNotifyStatus NotifyStatus
+
+	// Start of the streak of consecutive flushes containing firing,
+	// unacknowledged alerts. Zero if the last flush had none.
+	firingSince time.Time
+
+	// Notifications are held back until pauseEnd. If pauseInherit is false,
+	// this only applies to the alerts in pausedAlerts.
+	pauseEnd     time.Time
+	pauseInherit bool
+	pausedAlerts map[model.Fingerprint]struct{}
+
+	// Receiver notified instead of the route's receiver if the group was
+	// handed over.
+	reassignedTo string
+
+	deadlinePolicy config.DeadlinePolicy
+	deadLetters    provider.DeadLetters
+
+	// Alerts acknowledged in the marker are not notified about.
+	marker types.Marker
+	// Alerts muted by a maintenance window are not notified about.
+	maintenance types.Muter
+
+	peerWait func() time.Duration
+
+	// Creates an event once the group fires for the first time if set.
+	eventCreator *EventCreator
+	eventCreated bool
 }
 
 // newAggrGroup returns a new aggregation group.
-func newAggrGroup(ctx context.Context, labels model.LabelSet, opts *RouteOpts) *aggrGroup {
+func newAggrGroup(ctx context.Context, labels model.LabelSet, r *Route) *aggrGroup {
 	ag := &aggrGroup{
-		labels: labels,
-		opts:   opts,
-		alerts: map[model.Fingerprint]*types.Alert{},
+		labels:   labels,
+		opts:     &r.RouteOpts,
+		routeKey: r.Key(),
+		routeFP:  r.Fingerprint(),
+		alerts:   map[model.Fingerprint]*types.Alert{},
+
+		notifiedFiring: map[model.Fingerprint]struct{}{},
+		deadLettered:   map[model.Fingerprint]time.Time{},
+		done:           make(chan struct{}),
 	}
 	ag.ctx, ag.cancel = context.WithCancel(ctx)
 
@@ -276,7 +1338,10 @@ func newAggrGroup(ctx context.Context, labels model.LabelSet, opts *RouteOpts) *
 
 	// Set an initial one-time wait before flushing
 	// the first batch of notifications.
-	ag.next = time.NewTimer(ag.opts.GroupWait)
+	wait := ag.opts.GroupWait + ag.opts.Jitter()
+
+	ag.next = time.NewTimer(wait)
+	ag.nextFlush = time.Now().Add(wait)
 
 	return ag
 }
@@ -296,9 +1361,20 @@ func (ag *aggrGroup) alertSlice() []*types.Alert {
 	return alerts
 }
 
-func (ag *aggrGroup) run(nf notifyFunc) {
-	ag.done = make(chan struct{})
+// holds returns true if the group holds an alert matched by the matchers.
+func (ag *aggrGroup) holds(ms types.Matchers) bool {
+	ag.mtx.RLock()
+	defer ag.mtx.RUnlock()
+
+	for _, a := range ag.alerts {
+		if ms.Match(a.Labels) {
+			return true
+		}
+	}
+	return false
+}
 
+func (ag *aggrGroup) run(nf notifyFunc) {
 	defer close(ag.done)
 	defer ag.next.Stop()
 
@@ -311,39 +1387,230 @@ func (ag *aggrGroup) run(nf notifyFunc) {
 	for {
 		select {
 		case now := <-ag.next.C:
+			var wait time.Duration
+			if ag.peerWait != nil {
+				wait = ag.peerWait()
+			}
 			// Give the notifcations time until the next flush to
 			// finish before terminating them.
-			ctx, cancel := context.WithTimeout(ag.ctx, timeout)
+			ctx, cancel := context.WithTimeout(ag.ctx, timeout+wait)
 
 			// The now time we retrieve from the ticker is the only reliable
 			// point of time reference for the subsequent notification pipeline.
 			// Calculating the current time directly is prone to flaky behavior,
 			// which usually only becomes apparent in tests.
 			ctx = notify.WithNow(ctx, now)
-
-			// Populate context with information needed along the pipeline.
-			ctx = notify.WithGroupKey(ctx, ag.labels.Fingerprint()^ag.routeFP)
-			ctx = notify.WithGroupLabels(ctx, ag.labels)
-			ctx = notify.WithReceiver(ctx, ag.opts.Receiver)
-			ctx = notify.WithRepeatInterval(ctx, ag.opts.RepeatInterval)
+			ctx = ag.notifyContext(ctx, now)
 
 			// Wait the configured interval before calling flush again.
 			ag.mtx.Lock()
-			ag.next.Reset(ag.opts.GroupInterval)
+			ag.resetTimer(ag.opts.GroupInterval + ag.opts.Jitter())
 			ag.mtx.Unlock()
 
+			var (
+				exceeded, exhausted []*types.Alert
+				err                 error
+			)
 			ag.flush(func(alerts ...*types.Alert) bool {
-				return nf(ctx, alerts...)
+				receiver, _ := notify.Receiver(ctx)
+				ctx, span := tracing.Start(ctx, "aggregation_group.flush",
+					tracing.String("group_key", ag.groupKey()),
+					tracing.String("receiver", receiver),
+					tracing.Int("alerts", len(alerts)),
+				)
+				defer span.End()
+
+				// Peers preceding this instance in the cluster notify
+				// first and replicate their notification log, through
+				// which the notifications are deduplicated after waiting.
+				if wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-ctx.Done():
+						return false
+					}
+				}
+				err = nf(ctx, alerts...)
+				span.SetError(err)
+
+				if err == nil {
+					return true
+				}
+				if ctx.Err() == context.DeadlineExceeded {
+					exceeded = alerts
+				} else if notify.RetryBudgetExhausted(err) {
+					exhausted = alerts
+				}
+				return false
 			})
 
 			cancel()
 
+			if exceeded != nil {
+				ag.deadlineExceeded(ctx, exceeded, err)
+			} else if exhausted != nil {
+				ag.retryBudgetExhausted(ctx, exhausted, err)
+			}
+
 		case <-ag.ctx.Done():
 			return
 		}
 	}
 }
 
+// notifyContext populates the context with the information about the
+// group needed along the notification pipeline.
+func (ag *aggrGroup) notifyContext(ctx context.Context, now time.Time) context.Context {
+	receiver, level := ag.escalation(now)
+
+	ctx = notify.WithGroupKey(ctx, ag.groupKey())
+	ctx = notify.WithGroupLabels(ctx, ag.labels)
+	ctx = notify.WithReceiver(ctx, receiver)
+	ctx = notify.WithEscalationLevel(ctx, level)
+	ctx = notify.WithRepeatInterval(ctx, ag.opts.RepeatInterval)
+	ctx = notify.WithRouteInfo(ctx, ag.routeInfo())
+	if ag.opts.EmailHTML != "" {
+		ctx = notify.WithEmailHTML(ctx, ag.opts.EmailHTML)
+	}
+
+	return ctx
+}
+
+// nextNotification returns the alerts the group would notify about at the
+// given time and the context it would notify them with.
+func (ag *aggrGroup) nextNotification(now time.Time) (context.Context, []*types.Alert) {
+	ag.mtx.RLock()
+	alerts := ag.notifiable(now)
+	ag.mtx.RUnlock()
+
+	ctx := notify.WithNow(context.Background(), now)
+	ctx = ag.notifyContext(ctx, now)
+
+	return ctx, alerts
+}
+
+// escalation returns the receiver to notify and the escalation level
+// based on how long the group has been firing at the given time.
+func (ag *aggrGroup) escalation(now time.Time) (string, int) {
+	ag.mtx.RLock()
+	defer ag.mtx.RUnlock()
+
+	// Groups handed over to another receiver are no longer escalated.
+	if ag.reassignedTo != "" {
+		return ag.reassignedTo, 0
+	}
+	if ag.firingSince.IsZero() {
+		return ag.opts.Receiver, 0
+	}
+	return ag.opts.Escalated(now.Sub(ag.firingSince))
+}
+
+// routeInfo returns the template representation of the route the
+// aggregation group belongs to.
+func (ag *aggrGroup) routeInfo() template.Route {
+	ri := template.Route{
+		Path:           ag.routeKey,
+		GroupWait:      model.Duration(ag.opts.GroupWait).String(),
+		GroupInterval:  model.Duration(ag.opts.GroupInterval).String(),
+		RepeatInterval: model.Duration(ag.opts.RepeatInterval).String(),
+	}
+	for _, ln := range ag.opts.groupByLabels() {
+		ri.GroupBy = append(ri.GroupBy, string(ln))
+	}
+
+	return ri
+}
+
+const (
+	// deadlineRetryBackoff is the wait before the first immediate retry of
+	// a notification that exceeded its deadline. It doubles with every
+	// consecutive retry.
+	deadlineRetryBackoff = 10 * time.Second
+	// maxDeadlineRetries is the number of consecutive immediate retries
+	// after which notifications are retried at the group interval again.
+	maxDeadlineRetries = 5
+)
+
+// deadlineExceeded applies the group's deadline policy to alerts whose
+// notification under the given context did not finish in time.
+func (ag *aggrGroup) deadlineExceeded(ctx context.Context, alerts []*types.Alert, err error) {
+	ag.mtx.RLock()
+	policy := ag.deadlinePolicy
+	ag.mtx.RUnlock()
+
+	if policy == "" {
+		policy = config.DeadlineRetryNextInterval
+	}
+	numDeadlineExceeded.WithLabelValues(ag.opts.Receiver, string(policy)).Inc()
+
+	ag.log.Warnf("Notification deadline exceeded, handling with policy %q", policy)
+
+	switch policy {
+	case config.DeadlineRetryImmediately:
+		ag.mtx.Lock()
+		defer ag.mtx.Unlock()
+
+		// Retries back off exponentially. Once they are used up or the
+		// next flush is due earlier, the group interval applies.
+		if ag.deadlineRetries >= maxDeadlineRetries {
+			return
+		}
+		backoff := deadlineRetryBackoff << uint(ag.deadlineRetries)
+		if time.Now().Add(backoff).Before(ag.nextFlush) {
+			ag.resetTimer(backoff)
+		}
+		ag.deadlineRetries++
+
+	case config.DeadlineDeadLetter:
+		// Retry as usual if the dead letter cannot be stored so the
+		// notification is not lost.
+		if err := ag.addDeadLetter(ctx, alerts, err); err != nil {
+			ag.log.Errorf("Storing dead letter failed: %s", err)
+			return
+		}
+		ag.mtx.Lock()
+		defer ag.mtx.Unlock()
+
+		for _, a := range alerts {
+			if !a.Resolved() {
+				ag.deadLettered[a.Fingerprint()] = a.StartsAt
+			}
+		}
+		ag.removeResolved(alerts)
+	}
+}
+
+// retryBudgetExhausted records a notification given up on by an integration
+// after its maximum number of attempts as a dead letter. The alerts are
+// kept so the notification is attempted again at the next group interval.
+func (ag *aggrGroup) retryBudgetExhausted(ctx context.Context, alerts []*types.Alert, err error) {
+	ag.log.Warnf("Notification retry budget exhausted: %s", err)
+
+	if ag.deadLetters == nil {
+		return
+	}
+	if err := ag.addDeadLetter(ctx, alerts, err); err != nil {
+		ag.log.Errorf("Storing dead letter failed: %s", err)
+	}
+}
+
+// addDeadLetter stores the notification of the alerts under the given
+// context that failed with the given error as a dead letter.
+func (ag *aggrGroup) addDeadLetter(ctx context.Context, alerts []*types.Alert, err error) error {
+	receiver, _ := notify.Receiver(ctx)
+
+	_, err = ag.deadLetters.Add(&types.DeadLetter{
+		Receiver:       receiver,
+		GroupKey:       ag.groupKey(),
+		GroupLabels:    ag.labels,
+		RepeatInterval: ag.opts.RepeatInterval,
+		Alerts:         alerts,
+		Reason:         err.Error(),
+		Time:           time.Now(),
+	})
+	return err
+}
+
 func (ag *aggrGroup) stop() {
 	// Calling cancel will terminate all in-process notifications
 	// and the run() loop.
@@ -351,10 +1618,45 @@ func (ag *aggrGroup) stop() {
 	<-ag.done
 }
 
+// snapshot returns the state of the aggregation group.
+func (ag *aggrGroup) snapshot() *GroupSnapshot {
+	ag.mtx.RLock()
+	defer ag.mtx.RUnlock()
+
+	gs := &GroupSnapshot{
+		Route:            ag.routeKey,
+		RouteFingerprint: ag.routeFP,
+		Labels:           ag.labels,
+		HasSent:          ag.hasSent,
+		NextFlush:        ag.nextFlush,
+		FiringSince:      ag.firingSince,
+		ReassignedTo:     ag.reassignedTo,
+		EventCreated:     ag.eventCreated,
+	}
+	for _, a := range ag.alerts {
+		gs.Alerts = append(gs.Alerts, a)
+	}
+	for fp := range ag.notifiedFiring {
+		gs.NotifiedFiring = append(gs.NotifiedFiring, fp)
+	}
+	for fp := range ag.deadLettered {
+		gs.DeadLettered = append(gs.DeadLettered, fp)
+	}
+	return gs
+}
+
 func (ag *aggrGroup) fingerprint() model.Fingerprint {
 	return ag.labels.Fingerprint()
 }
 
+// groupKey returns a human-readable identifier of the aggregation group
+// made up of its receiver, the matchers leading to its route, and its
+// grouping labels. It remains stable across restarts and configuration
+// reloads as long as the group's route is unchanged.
+func (ag *aggrGroup) groupKey() string {
+	return fmt.Sprintf("%s/%s:%s", ag.opts.Receiver, ag.routeKey, ag.labels)
+}
+
 // insert inserts the alert into the aggregation group. If the aggregation group
 // is empty afterwards, it returns true.
 func (ag *aggrGroup) insert(alert *types.Alert) {
@@ -366,8 +1668,98 @@ func (ag *aggrGroup) insert(alert *types.Alert) {
 	// Immediately trigger a flush if the wait duration for this
 	// alert is already over.
 	if !ag.hasSent && alert.StartsAt.Add(ag.opts.GroupWait).Before(time.Now()) {
-		ag.next.Reset(0)
+		ag.resetTimer(0)
+	}
+}
+
+// resetTimer schedules the next flush after the given duration. The caller
+// must hold the group's lock.
+func (ag *aggrGroup) resetTimer(d time.Duration) {
+	ag.next.Reset(d)
+	ag.nextFlush = time.Now().Add(d)
+}
+
+// reassign hands the group over to the given receiver.
+func (ag *aggrGroup) reassign(receiver string) {
+	ag.mtx.Lock()
+	defer ag.mtx.Unlock()
+
+	ag.reassignedTo = receiver
+}
+
+// pause holds back notifications for the group's alerts until the given time.
+func (ag *aggrGroup) pause(until time.Time, inherit bool) {
+	ag.mtx.Lock()
+	defer ag.mtx.Unlock()
+
+	ag.pauseEnd = until
+	ag.pauseInherit = inherit
+	ag.pausedAlerts = make(map[model.Fingerprint]struct{}, len(ag.alerts))
+
+	for fp := range ag.alerts {
+		ag.pausedAlerts[fp] = struct{}{}
+	}
+}
+
+// pausedUntil returns the end of the group's pause and whether it is
+// still paused at the given time.
+func (ag *aggrGroup) pausedUntil(now time.Time) (time.Time, bool) {
+	ag.mtx.RLock()
+	defer ag.mtx.RUnlock()
+
+	return ag.pauseEnd, now.Before(ag.pauseEnd)
+}
+
+// paused returns whether notifications for the alert with the given
+// fingerprint are held back at the given time. The caller must hold
+// the group's lock.
+func (ag *aggrGroup) paused(fp model.Fingerprint, now time.Time) bool {
+	if !now.Before(ag.pauseEnd) {
+		return false
+	}
+	if ag.pauseInherit {
+		return true
+	}
+	_, ok := ag.pausedAlerts[fp]
+	return ok
+}
+
+// acked returns true iff notifications about the alert are suppressed by
+// an acknowledgement.
+func (ag *aggrGroup) acked(a *types.Alert, now time.Time) bool {
+	if ag.marker == nil {
+		return false
+	}
+	ack, ok := ag.marker.Acked(a.Fingerprint())
+	return ok && ack.Suppresses(a, now)
+}
+
+// inMaintenance returns true iff the alert is muted by an active
+// maintenance window, which is marked in the marker.
+func (ag *aggrGroup) inMaintenance(a *types.Alert) bool {
+	return ag.maintenance != nil && ag.maintenance.Mutes(a.Labels)
+}
+
+// inDeadLetter returns true iff the alert still fires since its
+// notification was recorded as a dead letter. The caller must hold the
+// group's lock.
+func (ag *aggrGroup) inDeadLetter(a *types.Alert) bool {
+	startsAt, ok := ag.deadLettered[a.Fingerprint()]
+	return ok && !a.Resolved() && a.StartsAt.Equal(startsAt)
+}
+
+// notifiable returns the alerts of the group that are not held back at the
+// given time. The caller must hold the group's lock.
+func (ag *aggrGroup) notifiable(now time.Time) []*types.Alert {
+	alerts := make([]*types.Alert, 0, len(ag.alerts))
+
+	for fp, alert := range ag.alerts {
+		if ag.paused(fp, now) || ag.acked(alert, now) || ag.inMaintenance(alert) || ag.inDeadLetter(alert) {
+			continue
+		}
+		alerts = append(alerts, alert)
 	}
+	return alerts
 }
 
 func (ag *aggrGroup) empty() bool {
@@ -382,32 +1774,135 @@ func (ag *aggrGroup) flush(notify func(...*types.Alert) bool) {
 	if ag.empty() {
 		return
 	}
+
+	now := time.Now()
+
+	// Keep aggregating during mute time intervals but do not notify.
+	if ag.opts.Muted(now) {
+		ag.log.Debugln("flush muted by time interval")
+		return
+	}
+
 	ag.mtx.Lock()
 
-	var (
-		alerts      = make(map[model.Fingerprint]*types.Alert, len(ag.alerts))
-		alertsSlice = make([]*types.Alert, 0, len(ag.alerts))
-	)
-	for fp, alert := range ag.alerts {
-		alerts[fp] = alert
-		alertsSlice = append(alertsSlice, alert)
+	alertsSlice := ag.filterResolved(ag.notifiable(now), now)
+
+	// A flush without firing alerts ends the firing streak and thereby
+	// any escalation.
+	firing := false
+	for _, a := range alertsSlice {
+		if !a.Resolved() {
+			firing = true
+			break
+		}
+	}
+	if !firing {
+		ag.firingSince = time.Time{}
+	} else if ag.firingSince.IsZero() {
+		ag.firingSince = now
+	}
+
+	eventCreator := ag.eventCreator
+	createEvent := firing && !ag.eventCreated && eventCreator != nil
+	if createEvent {
+		ag.eventCreated = true
 	}
 
 	ag.mtx.Unlock()
 
+	if createEvent {
+		if id, ok := eventCreator.Create(ag.opts.Receiver, ag.labels, alertsSlice...); ok {
+			ag.log.With("event", id).Debugln("created event for group")
+		}
+	}
+
+	if len(alertsSlice) == 0 {
+		return
+	}
+
 	ag.log.Debugln("flushing", alertsSlice)
 
-	if notify(alertsSlice...) {
-		ag.mtx.Lock()
-		for fp, a := range alerts {
-			// Only delete if the fingerprint has not been inserted
-			// again since we notified about it.
-			if a.Resolved() && ag.alerts[fp] == a {
-				delete(ag.alerts, fp)
-			}
+	ok := notify(alertsSlice...)
+
+	ag.mtx.Lock()
+	defer ag.mtx.Unlock()
+
+	ag.lastFlush = now
+	if !ok {
+		ag.lastNotifyStatus = NotifyFailed
+		return
+	}
+	ag.lastNotifyStatus = NotifySuccess
+	ag.deadlineRetries = 0
+	for _, a := range alertsSlice {
+		if !a.Resolved() {
+			ag.notifiedFiring[a.Fingerprint()] = struct{}{}
+		}
+	}
+	ag.removeResolved(alertsSlice)
+	ag.hasSent = true
+	ag.lastNotified = now
+}
+
+// setState populates the notification state of the group's alert block.
+func (ag *aggrGroup) setState(b *AlertBlock) {
+	ag.mtx.RLock()
+	defer ag.mtx.RUnlock()
+
+	b.HasSent = ag.hasSent
+	b.NextFlush = ag.nextFlush
+	b.LastNotifyStatus = ag.lastNotifyStatus
+	b.ReassignedTo = ag.reassignedTo
+
+	if !ag.lastFlush.IsZero() {
+		lf := ag.lastFlush
+		b.LastFlush = &lf
+	}
+}
+
+// removeResolved deletes the given alerts from the group if they are
+// resolved. The group's lock must be held.
+func (ag *aggrGroup) removeResolved(alerts []*types.Alert) {
+	for _, a := range alerts {
+		fp := a.Fingerprint()
+		// Only delete if the fingerprint has not been inserted
+		// again since we notified about it.
+		if a.Resolved() && ag.alerts[fp] == a {
+			delete(ag.alerts, fp)
+			delete(ag.notifiedFiring, fp)
+			delete(ag.deadLettered, fp)
 		}
+	}
+}
+
+// filterResolved removes the resolved alerts that must not be notified
+// about yet according to the route's send_resolved_after option. Resolved
+// alerts that were never notified about as firing are dropped from the
+// group. The group's lock must be held.
+func (ag *aggrGroup) filterResolved(alerts []*types.Alert, now time.Time) []*types.Alert {
+	if ag.opts.SendResolvedAfter <= 0 {
+		return alerts
+	}
+	res := alerts[:0]
 
-		ag.hasSent = true
-		ag.mtx.Unlock()
+	for _, a := range alerts {
+		if !a.Resolved() {
+			res = append(res, a)
+			continue
+		}
+		fp := a.Fingerprint()
+
+		if _, ok := ag.notifiedFiring[fp]; !ok {
+			delete(ag.alerts, fp)
+			delete(ag.deadLettered, fp)
+			continue
+		}
+		// Hold back the resolved notification until the grace period
+		// passed. If the alert fires again in the meantime, none is sent.
+		if now.Sub(a.EndsAt) < ag.opts.SendResolvedAfter {
+			continue
+		}
+		res = append(res, a)
 	}
+	return res
 }
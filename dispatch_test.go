@@ -1,15 +1,24 @@
 package main
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/prometheus/common/model"
 	"golang.org/x/net/context"
+	"gopkg.in/yaml.v2"
 
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -18,13 +27,16 @@ func TestAggrGroup(t *testing.T) {
 		"a": "v1",
 		"b": "v2",
 	}
-	opts := &RouteOpts{
-		Receiver:       "n1",
-		GroupBy:        map[model.LabelName]struct{}{},
-		GroupWait:      1 * time.Second,
-		GroupInterval:  300 * time.Millisecond,
-		RepeatInterval: 1 * time.Hour,
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{},
+			GroupWait:      1 * time.Second,
+			GroupInterval:  300 * time.Millisecond,
+			RepeatInterval: 1 * time.Hour,
+		},
 	}
+	opts := &route.RouteOpts
 
 	var (
 		a1 = &types.Alert{
@@ -71,13 +83,13 @@ func TestAggrGroup(t *testing.T) {
 		alertsCh = make(chan types.AlertSlice)
 	)
 
-	ntfy := func(ctx context.Context, alerts ...*types.Alert) bool {
+	ntfy := func(ctx context.Context, alerts ...*types.Alert) error {
 		// Validate that the context is properly populated.
 		if _, ok := notify.Now(ctx); !ok {
 			t.Errorf("now missing")
 		}
-		if _, ok := notify.GroupKey(ctx); !ok {
-			t.Errorf("group key missing")
+		if key, ok := notify.GroupKey(ctx); !ok || key != `n1/{}:{a="v1", b="v2"}` {
+			t.Errorf("wrong group key: %q", key)
 		}
 		if lbls, ok := notify.GroupLabels(ctx); !ok || !reflect.DeepEqual(lbls, lset) {
 			t.Errorf("wrong group labels: %q", lbls)
@@ -85,20 +97,26 @@ func TestAggrGroup(t *testing.T) {
 		if rcv, ok := notify.Receiver(ctx); !ok || rcv != opts.Receiver {
 			t.Errorf("wrong receiver: %q", rcv)
 		}
+		if l, ok := notify.EscalationLevel(ctx); !ok || l != 0 {
+			t.Errorf("wrong escalation level: %d", l)
+		}
 		if ri, ok := notify.RepeatInterval(ctx); !ok || ri != opts.RepeatInterval {
 			t.Errorf("wrong repeat interval: %q", ri)
 		}
+		if ri, ok := notify.RouteInfo(ctx); !ok || ri.Path != route.Key() {
+			t.Errorf("wrong route info: %v", ri)
+		}
 
 		last = current
 		current = time.Now()
 
 		alertsCh <- types.AlertSlice(alerts)
 
-		return true
+		return nil
 	}
 
 	// Test regular situation where we wait for group_wait to send out alerts.
-	ag := newAggrGroup(context.Background(), lset, opts)
+	ag := newAggrGroup(context.Background(), lset, route)
 	go ag.run(ntfy)
 
 	ag.insert(a1)
@@ -146,7 +164,7 @@ func TestAggrGroup(t *testing.T) {
 	// immediate flushing.
 	// Finally, set all alerts to be resolved. After successful notify the aggregation group
 	// should empty itself.
-	ag = newAggrGroup(context.Background(), lset, opts)
+	ag = newAggrGroup(context.Background(), lset, route)
 	go ag.run(ntfy)
 
 	ag.insert(a1)
@@ -214,3 +232,1248 @@ func TestAggrGroup(t *testing.T) {
 
 	ag.stop()
 }
+
+func TestAggrGroupPause(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{},
+			GroupWait:      1 * time.Second,
+			GroupInterval:  300 * time.Millisecond,
+			RepeatInterval: 1 * time.Hour,
+		},
+	}
+	var (
+		a1 = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"a": "v1"},
+				StartsAt: time.Now().Add(-time.Minute),
+				EndsAt:   time.Now().Add(time.Hour),
+			},
+		}
+		a2 = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"a": "v2"},
+				StartsAt: time.Now().Add(-time.Minute),
+				EndsAt:   time.Now().Add(time.Hour),
+			},
+		}
+	)
+
+	var notified types.AlertSlice
+	ntfy := func(alerts ...*types.Alert) bool {
+		notified = alerts
+		return true
+	}
+
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route)
+	ag.insert(a1)
+
+	// Without inheritance, alerts joining after the pause are notified about.
+	ag.pause(time.Now().Add(time.Hour), false)
+	ag.insert(a2)
+
+	notified = nil
+	ag.flush(ntfy)
+
+	if exp := (types.AlertSlice{a2}); !reflect.DeepEqual(notified, exp) {
+		t.Fatalf("expected alerts %v but got %v", exp, notified)
+	}
+
+	// With inheritance, all alerts are held back.
+	ag.pause(time.Now().Add(time.Hour), true)
+
+	notified = nil
+	ag.flush(ntfy)
+
+	if notified != nil {
+		t.Fatalf("expected no notification but got %v", notified)
+	}
+
+	// Resuming the group releases all alerts again.
+	ag.pause(time.Time{}, false)
+
+	notified = nil
+	ag.flush(ntfy)
+	sort.Sort(notified)
+
+	if exp := (types.AlertSlice{a1, a2}); len(notified) != len(exp) {
+		t.Fatalf("expected alerts %v but got %v", exp, notified)
+	}
+}
+
+func TestDispatcherStopWithoutRun(t *testing.T) {
+	d := NewDispatcher(nil, &Route{}, nil, types.NewMarker())
+
+	stopped := make(chan struct{})
+	go func() {
+		d.Stop()
+		// Running a stopped dispatcher returns without subscribing to
+		// the nil alerts provider.
+		d.Run()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("expected Stop without Run to return")
+	}
+}
+
+func TestDispatcherSnapshotRestore(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{"a": struct{}{}},
+			GroupWait:      1 * time.Hour,
+			GroupInterval:  1 * time.Hour,
+			RepeatInterval: 1 * time.Hour,
+		},
+	}
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"a": "v1"},
+			StartsAt: time.Now().Add(-time.Minute).UTC(),
+			EndsAt:   time.Now().Add(time.Hour).UTC(),
+		},
+	}
+
+	d := NewDispatcher(nil, route, nil, nil)
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	defer d.cancel()
+
+	ag := newAggrGroup(d.ctx, model.LabelSet{"a": "v1"}, route)
+	ag.insert(alert)
+	ag.hasSent = true
+
+	d.aggrGroups.set(route, ag)
+
+	snap := d.Snapshot()
+	if len(snap.Groups) != 1 {
+		t.Fatalf("expected 1 group in snapshot but got %d", len(snap.Groups))
+	}
+	// Groups of routes that are gone must be dropped on restore.
+	snap.Groups = append(snap.Groups, &GroupSnapshot{
+		Route:  "{}/{b=\"v\"}",
+		Labels: model.LabelSet{"b": "v"},
+	})
+
+	dir, err := ioutil.TempDir("", "dispatcher_snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "snapshot")
+	if err := snap.WriteFile(filename); err != nil {
+		t.Fatalf("writing snapshot failed: %s", err)
+	}
+	loaded, err := LoadSnapshotFile(filename)
+	if err != nil {
+		t.Fatalf("loading snapshot failed: %s", err)
+	}
+
+	d2 := NewDispatcher(nil, route, nil, nil)
+	d2.ctx, d2.cancel = context.WithCancel(context.Background())
+	defer d2.cancel()
+
+	d2.Restore(loaded)
+	d2.restore()
+
+	if n := d2.aggrGroups.len(); n != 1 {
+		t.Fatalf("expected 1 restored group but got %d", n)
+	}
+	restored, _ := d2.aggrGroups.get(route, ag.fingerprint())
+	if restored == nil {
+		t.Fatalf("group %v was not restored", ag.fingerprint())
+	}
+
+	restored.mtx.RLock()
+	defer restored.mtx.RUnlock()
+
+	if !restored.hasSent {
+		t.Fatalf("expected restored group to have sent notifications")
+	}
+	if len(restored.alerts) != 1 {
+		t.Fatalf("expected 1 restored alert but got %d", len(restored.alerts))
+	}
+	if a := restored.alerts[alert.Fingerprint()]; a == nil || !a.StartsAt.Equal(alert.StartsAt) {
+		t.Fatalf("expected alert %v but got %v", alert, a)
+	}
+	if d := restored.nextFlush.Sub(ag.nextFlush); d < -time.Second || d > time.Second {
+		t.Fatalf("expected next flush at %v but got %v", ag.nextFlush, restored.nextFlush)
+	}
+}
+
+func TestDispatcherRestoreRoutes(t *testing.T) {
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(`
+receiver: 'default'
+group_wait: 1m
+group_interval: 1m
+routes:
+- match:
+    team: 'a'
+  group_by: ['alertname']
+  continue: true
+- match:
+    team: 'a'
+  group_by: ['instance']
+`), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+	var (
+		byName     = tree.Routes[0]
+		byInstance = tree.Routes[1]
+		now        = time.Now()
+	)
+	if byName.Key() != byInstance.Key() {
+		t.Fatalf("expected routes to have the same key")
+	}
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "test", "instance": "i1", "team": "a"},
+			StartsAt: now.Add(-time.Hour),
+		},
+	}
+	snap := &DispatcherSnapshot{Version: snapshotVersion}
+	for _, r := range []*Route{byName, byInstance} {
+		snap.Groups = append(snap.Groups, &GroupSnapshot{
+			Route:            r.Key(),
+			RouteFingerprint: r.Fingerprint(),
+			Labels:           r.RouteOpts.GroupLabels(alert.Labels),
+			Alerts:           []*types.Alert{alert},
+			// Flushes planned before the restart are not carried over.
+			NextFlush: now.Add(-time.Second),
+			HasSent:   r == byName,
+		})
+	}
+
+	d := NewDispatcher(nil, tree, notify.NotifierFunc(func(context.Context, ...*types.Alert) error {
+		return nil
+	}), types.NewMarker())
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	defer d.cancel()
+
+	d.Restore(snap)
+	d.restore()
+
+	// Groups that have sent notifications wait for the group wait, while
+	// the first notification of the others is sent right away as their
+	// alerts' group wait has passed.
+	for r, exp := range map[*Route]time.Duration{byName: time.Minute, byInstance: 0} {
+		ag, ok := d.aggrGroups.get(r, r.RouteOpts.GroupLabels(alert.Labels).Fingerprint())
+		if !ok {
+			t.Fatalf("expected group of route %v to be restored", r.RouteOpts.GroupBy)
+		}
+		ag.mtx.RLock()
+		wait := ag.nextFlush.Sub(now)
+		ag.mtx.RUnlock()
+		if wait < exp || wait > exp+time.Second {
+			t.Errorf("expected group of route %v to flush in %v but got %v", r.RouteOpts.GroupBy, exp, wait)
+		}
+	}
+}
+
+func TestDispatcherReload(t *testing.T) {
+	newTree := func(in string) *Route {
+		var ctree config.Route
+		if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+			t.Fatal(err)
+		}
+		return NewRoute(&ctree, nil)
+	}
+	oldTree := newTree(`
+receiver: 'default'
+group_wait: 1h
+group_interval: 1h
+routes:
+- match: {owner: 'a'}
+  receiver: 'a'
+  group_by: ['job']
+- match: {owner: 'b'}
+  receiver: 'b'
+  group_by: ['job']
+- match: {owner: 'c'}
+  receiver: 'c'
+`)
+	newTreeRoutes := newTree(`
+receiver: 'default'
+group_wait: 1h
+group_interval: 1h
+routes:
+- match: {owner: 'a'}
+  receiver: 'a'
+  group_by: ['job']
+- match: {owner: 'b'}
+  receiver: 'b2'
+  group_by: ['job']
+- match: {owner: 'd'}
+  receiver: 'd'
+`)
+
+	d := NewDispatcher(nil, oldTree, nil, nil)
+	defer d.cancel()
+
+	groupOf := func(owner model.LabelValue) *aggrGroup {
+		var res *aggrGroup
+		d.aggrGroups.each(func(_ *Route, ag *aggrGroup) {
+			for _, a := range ag.alerts {
+				if a.Labels["owner"] == owner {
+					res = ag
+				}
+			}
+		})
+		return res
+	}
+
+	for i, owner := range []model.LabelValue{"a", "b", "c"} {
+		a := &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"owner": owner, "job": "j"},
+				StartsAt: time.Now(),
+				EndsAt:   time.Now().Add(time.Hour),
+			},
+		}
+		d.processAlert(a, oldTree.Routes[i])
+	}
+	agA, agB, agC := groupOf("a"), groupOf("b"), groupOf("c")
+
+	agB.mtx.Lock()
+	agB.hasSent = true
+	agB.mtx.Unlock()
+
+	d.Reload(newTreeRoutes)
+
+	if d.Route() != newTreeRoutes {
+		t.Fatalf("expected routing tree to be replaced")
+	}
+	if n := len(d.aggrGroups.byRoute()); n != 2 {
+		t.Fatalf("expected groups of 2 routes but got %d", n)
+	}
+
+	// Groups of unchanged routes keep running on the new route.
+	if ag, _ := d.aggrGroups.get(newTreeRoutes.Routes[0], agA.fingerprint()); ag != agA {
+		t.Fatalf("expected group of unchanged route to be kept but got %v", ag)
+	}
+
+	// Groups of routes with changed options are restarted with their state.
+	ag, _ := d.aggrGroups.get(newTreeRoutes.Routes[1], agB.fingerprint())
+	if ag == nil || ag == agB {
+		t.Fatalf("expected group of changed route to be replaced but got %v", ag)
+	}
+	select {
+	case <-agB.done:
+	default:
+		t.Fatalf("expected replaced group to be stopped")
+	}
+	ag.mtx.RLock()
+	if !ag.hasSent {
+		t.Fatalf("expected replaced group to keep its notification state")
+	}
+	if len(ag.alerts) != 1 {
+		t.Fatalf("expected 1 alert in replaced group but got %d", len(ag.alerts))
+	}
+	if d := ag.nextFlush.Sub(agB.nextFlush); d < -time.Second || d > time.Second {
+		t.Fatalf("expected next flush at %v but got %v", agB.nextFlush, ag.nextFlush)
+	}
+	ag.mtx.RUnlock()
+
+	if ag.opts.Receiver != "b2" {
+		t.Fatalf("expected receiver %q but got %q", "b2", ag.opts.Receiver)
+	}
+
+	// Groups of removed routes are stopped.
+	select {
+	case <-agC.done:
+	default:
+		t.Fatalf("expected group of removed route to be stopped")
+	}
+}
+
+func TestDispatcherReloadRegroup(t *testing.T) {
+	newTree := func(groupBy string) *Route {
+		var ctree config.Route
+		in := "receiver: 'default'\ngroup_wait: 1h\ngroup_interval: 1h\ngroup_by: [" + groupBy + "]\n"
+		if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+			t.Fatal(err)
+		}
+		return NewRoute(&ctree, nil)
+	}
+	oldTree := newTree("job")
+
+	d := NewDispatcher(nil, oldTree, nil, nil)
+	defer d.cancel()
+
+	var alerts []*types.Alert
+	for _, ls := range []model.LabelSet{
+		{"job": "j", "instance": "1"},
+		{"job": "j", "instance": "2"},
+		{"job": "k", "instance": "1"},
+	} {
+		a := &types.Alert{
+			Alert: model.Alert{
+				Labels:   ls,
+				StartsAt: time.Now(),
+				EndsAt:   time.Now().Add(time.Hour),
+			},
+		}
+		alerts = append(alerts, a)
+		d.processAlert(a, oldTree)
+	}
+	agJ, _ := d.aggrGroups.get(oldTree, model.LabelSet{"job": "j"}.Fingerprint())
+	agK, _ := d.aggrGroups.get(oldTree, model.LabelSet{"job": "k"}.Fingerprint())
+
+	agJ.mtx.Lock()
+	agJ.hasSent = true
+	agJ.notifiedFiring[alerts[0].Fingerprint()] = struct{}{}
+	agJ.mtx.Unlock()
+
+	newTreeRoute := newTree("instance")
+	d.Reload(newTreeRoute)
+
+	for _, ag := range []*aggrGroup{agJ, agK} {
+		select {
+		case <-ag.done:
+		default:
+			t.Fatalf("expected regrouped group %v to be stopped", ag)
+		}
+	}
+	if n := d.aggrGroups.len(); n != 2 {
+		t.Fatalf("expected 2 groups but got %d", n)
+	}
+
+	ag1, _ := d.aggrGroups.get(newTreeRoute, model.LabelSet{"instance": "1"}.Fingerprint())
+	ag2, _ := d.aggrGroups.get(newTreeRoute, model.LabelSet{"instance": "2"}.Fingerprint())
+	if ag1 == nil || ag2 == nil {
+		t.Fatalf("expected groups by instance but got %v and %v", ag1, ag2)
+	}
+
+	ag1.mtx.RLock()
+	defer ag1.mtx.RUnlock()
+	if len(ag1.alerts) != 2 {
+		t.Errorf("expected 2 alerts in merged group but got %d", len(ag1.alerts))
+	}
+	if !ag1.hasSent {
+		t.Errorf("expected merged group to keep the notification state")
+	}
+	if _, ok := ag1.notifiedFiring[alerts[0].Fingerprint()]; !ok || len(ag1.notifiedFiring) != 1 {
+		t.Errorf("expected notified alert to be carried over but got %v", ag1.notifiedFiring)
+	}
+
+	ag2.mtx.RLock()
+	defer ag2.mtx.RUnlock()
+	if len(ag2.alerts) != 1 || !ag2.hasSent {
+		t.Errorf("expected group with 1 alert and notification state but got %d alerts, sent: %v", len(ag2.alerts), ag2.hasSent)
+	}
+}
+
+func TestDispatcherGroupsFiltered(t *testing.T) {
+	var (
+		r1 = &Route{RouteOpts: RouteOpts{Receiver: "r1"}}
+		r2 = &Route{RouteOpts: RouteOpts{Receiver: "r2"}}
+	)
+	newAlert := func(labels model.LabelSet) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   labels,
+				StartsAt: time.Now().Add(-time.Minute),
+				EndsAt:   time.Now().Add(time.Hour),
+			},
+		}
+	}
+	newGroup := func(r *Route, labels model.LabelSet, alerts ...*types.Alert) *aggrGroup {
+		ag := newAggrGroup(context.Background(), labels, r)
+		for _, a := range alerts {
+			ag.insert(a)
+		}
+		return ag
+	}
+
+	var (
+		ag1 = newGroup(r1, model.LabelSet{"g": "1"},
+			newAlert(model.LabelSet{"g": "1", "env": "prod"}),
+		)
+		ag2 = newGroup(r1, model.LabelSet{"g": "2"},
+			newAlert(model.LabelSet{"g": "2", "env": "prod", "i": "1"}),
+			newAlert(model.LabelSet{"g": "2", "env": "prod", "i": "2"}),
+			newAlert(model.LabelSet{"g": "2", "env": "dev", "i": "3"}),
+		)
+		ag3 = newGroup(r2, model.LabelSet{"g": "3"},
+			newAlert(model.LabelSet{"g": "3", "env": "dev"}),
+			newAlert(model.LabelSet{"g": "3", "env": "dev", "i": "1"}),
+		)
+	)
+
+	d := NewDispatcher(nil, r1, nil, types.NewMarker())
+	d.aggrGroups.set(r1, ag1)
+	d.aggrGroups.set(r1, ag2)
+	d.aggrGroups.set(r2, ag3)
+
+	groupLabels := func(ao AlertOverview) []model.LabelValue {
+		var res []model.LabelValue
+		for _, ag := range ao {
+			res = append(res, ag.Labels["g"])
+		}
+		return res
+	}
+
+	cases := []struct {
+		opts GroupsOptions
+		exp  []model.LabelValue
+	}{
+		{
+			opts: GroupsOptions{},
+			exp:  []model.LabelValue{"1", "2", "3"},
+		},
+		{
+			opts: GroupsOptions{Receiver: "r2"},
+			exp:  []model.LabelValue{"3"},
+		},
+		{
+			opts: GroupsOptions{Matchers: types.Matchers{types.NewMatcher("env", "prod")}},
+			exp:  []model.LabelValue{"1", "2"},
+		},
+		{
+			opts: GroupsOptions{SortBy: SortByAlerts},
+			exp:  []model.LabelValue{"2", "3", "1"},
+		},
+		{
+			opts: GroupsOptions{Reverse: true, Offset: 1, Limit: 1},
+			exp:  []model.LabelValue{"2"},
+		},
+		{
+			opts: GroupsOptions{Offset: 5},
+			exp:  nil,
+		},
+	}
+
+	for i, c := range cases {
+		if res := groupLabels(d.GroupsFiltered(c.opts)); !reflect.DeepEqual(res, c.exp) {
+			t.Errorf("case %d: expected groups %v but got %v", i, c.exp, res)
+		}
+	}
+
+	// Blocks only contain the alerts matching the filter.
+	ao := d.GroupsFiltered(GroupsOptions{Matchers: types.Matchers{types.NewMatcher("env", "dev")}})
+	if n := ao[0].numAlerts(); ao[0].Labels["g"] != "2" || n != 1 {
+		t.Errorf("expected 1 filtered alert in group 2 but got %d in %v", n, ao[0].Labels)
+	}
+}
+
+func TestDispatcherExpireStale(t *testing.T) {
+	var (
+		now = time.Now()
+		r1  = &Route{RouteOpts: RouteOpts{Receiver: "r1"}}
+		r2  = &Route{RouteOpts: RouteOpts{Receiver: "r2"}}
+	)
+	newAlert := func(name model.LabelValue, updated, end time.Time, timeout bool) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": name},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   end,
+			},
+			UpdatedAt: updated,
+			Timeout:   timeout,
+		}
+	}
+	var (
+		stale   = newAlert("Stale", now.Add(-10*time.Minute), now.Add(50*time.Minute), true)
+		fresh   = newAlert("Fresh", now.Add(-time.Minute), now.Add(59*time.Minute), true)
+		withEnd = newAlert("WithEnd", now.Add(-10*time.Minute), now.Add(time.Hour), false)
+	)
+
+	alerts := provider.NewMemAlerts(provider.NewMemData())
+	if err := alerts.Put(stale, fresh, withEnd); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDispatcher(alerts, r1, nil, types.NewMarker())
+	for _, r := range []*Route{r1, r2} {
+		ag := newAggrGroup(context.Background(), model.LabelSet{}, r)
+		for _, a := range []*types.Alert{stale, fresh, withEnd} {
+			ag.insert(a)
+		}
+		d.aggrGroups.set(r, ag)
+	}
+
+	// Alerts are not expired unless enforced.
+	d.expireStale(now)
+	if a, _ := alerts.Get(stale.Fingerprint()); !a.EndsAt.Equal(stale.EndsAt) {
+		t.Fatalf("expected alert not to be expired without resolve timeout")
+	}
+
+	d.SetResolveTimeout(5 * time.Minute)
+	d.expireStale(now)
+
+	a, err := alerts.Get(stale.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Timeout || !a.EndsAt.Equal(stale.UpdatedAt.Add(5*time.Minute)) || !a.Resolved() {
+		t.Errorf("expected stale alert to be resolved at the resolve timeout but got %v", a)
+	}
+	for _, o := range []*types.Alert{fresh, withEnd} {
+		a, err := alerts.Get(o.Fingerprint())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !a.EndsAt.Equal(o.EndsAt) {
+			t.Errorf("expected alert %s not to be expired but got %v", o.Labels, a)
+		}
+	}
+}
+
+func TestDispatcherExpireStaleReceived(t *testing.T) {
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(`
+receiver: 'default'
+group_wait: 1h
+group_interval: 1h
+`), &ctree); err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := provider.NewMemAlerts(provider.NewMemData())
+	api := NewAPI(alerts, nil, nil, nil, nil, nil, nil, nil, "", nil)
+	api.Update("", 5*time.Minute)
+
+	d := NewDispatcher(alerts, NewRoute(&ctree, nil), notify.NotifierFunc(func(context.Context, ...*types.Alert) error {
+		return nil
+	}), types.NewMarker())
+	go d.Run()
+	defer d.Stop()
+
+	alert := &types.Alert{
+		Alert: model.Alert{Labels: model.LabelSet{"alertname": "test"}},
+	}
+	if _, err := api.storeAlerts(alert); err != nil {
+		t.Fatal(err)
+	}
+	received, err := alerts.Get(alert.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the alert to be routed.
+	for i := 0; len(d.Groups()) == 0; i++ {
+		if i == 100 {
+			t.Fatalf("expected alert to be routed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Received alerts get their end time from the resolve timeout, which
+	// they keep if it is lowered later on.
+	d.SetResolveTimeout(time.Minute)
+	d.expireStale(received.UpdatedAt.Add(2 * time.Minute))
+
+	a, err := alerts.Get(alert.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := received.UpdatedAt.Add(time.Minute); !a.EndsAt.Equal(exp) || a.Timeout {
+		t.Errorf("expected received alert to be resolved at %v but got %v", exp, a)
+	}
+}
+
+func TestDispatcherPreviewSilence(t *testing.T) {
+	r := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "r1",
+			GroupBy:        map[model.LabelName]struct{}{"g": struct{}{}},
+			GroupWait:      time.Hour,
+			GroupInterval:  time.Hour,
+			RepeatInterval: time.Hour,
+		},
+	}
+
+	newAlert := func(labels model.LabelSet, endsAt time.Time) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   labels,
+				StartsAt: time.Now().Add(-2 * time.Minute),
+				EndsAt:   endsAt,
+			},
+		}
+	}
+	var (
+		now = time.Now()
+
+		a1 = newAlert(model.LabelSet{"g": "1", "env": "prod"}, now.Add(time.Hour))
+		a2 = newAlert(model.LabelSet{"g": "2", "env": "prod", "i": "1"}, now.Add(time.Hour))
+		a3 = newAlert(model.LabelSet{"g": "2", "env": "dev", "i": "2"}, now.Add(time.Hour))
+		// Resolved alerts are not muted.
+		a4 = newAlert(model.LabelSet{"g": "3", "env": "prod"}, now.Add(-time.Minute))
+	)
+
+	d := NewDispatcher(nil, r, nil, types.NewMarker())
+	defer d.cancel()
+
+	for _, a := range []*types.Alert{a1, a2, a3, a4} {
+		d.processAlert(a, r)
+	}
+	d.marker.SetSilenced(a2.Fingerprint(), 7)
+
+	sil := types.NewSilence(&model.Silence{
+		Matchers: []*model.Matcher{{Name: "env", Value: "prod"}},
+		StartsAt: now.Add(time.Hour),
+		EndsAt:   now.Add(2 * time.Hour),
+	})
+	p := d.PreviewSilence(sil, now)
+
+	if p.Active {
+		t.Errorf("expected future silence to be inactive")
+	}
+
+	var fps []model.Fingerprint
+	for _, a := range p.Alerts {
+		fps = append(fps, a.Fingerprint())
+	}
+	exp := model.Fingerprints{a1.Fingerprint(), a2.Fingerprint()}
+	sort.Sort(exp)
+	if !reflect.DeepEqual(fps, []model.Fingerprint(exp)) {
+		t.Fatalf("expected muted alerts %v but got %v", exp, fps)
+	}
+	for _, a := range p.Alerts {
+		if a.Fingerprint() == a2.Fingerprint() && a.Silenced != 7 {
+			t.Errorf("expected alert to be reported as silenced by 7 but got %d", a.Silenced)
+		}
+	}
+
+	if len(p.Groups) != 2 {
+		t.Fatalf("expected 2 muted groups but got %d", len(p.Groups))
+	}
+	for _, g := range p.Groups {
+		var muted, firing int
+		switch g.Labels["g"] {
+		case "1":
+			muted, firing = 1, 1
+		case "2":
+			muted, firing = 1, 2
+		default:
+			t.Fatalf("unexpected muted group %v", g.Labels)
+		}
+		if g.Muted != muted || g.Firing != firing || g.Receiver != "r1" {
+			t.Errorf("expected %d of %d alerts of group %v muted but got %+v", muted, firing, g.Labels, g)
+		}
+	}
+}
+
+func TestAggrGroupDeadlineExceeded(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{},
+			GroupWait:      1 * time.Second,
+			GroupInterval:  1 * time.Hour,
+			RepeatInterval: 1 * time.Hour,
+		},
+	}
+	var (
+		firing = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"a": "v1"},
+				StartsAt: time.Now().Add(-time.Minute),
+				EndsAt:   time.Now().Add(time.Hour),
+			},
+		}
+		resolved = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"a": "v2"},
+				StartsAt: time.Now().Add(-time.Minute),
+				EndsAt:   time.Now().Add(-time.Second),
+			},
+		}
+	)
+
+	// Immediate retries schedule the next flush with a backoff until they
+	// are used up.
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route)
+	ag.deadlinePolicy = config.DeadlineRetryImmediately
+
+	ctx := notify.WithReceiver(context.Background(), "n1-escalated")
+	err := fmt.Errorf("context deadline exceeded")
+
+	for i := 0; i <= maxDeadlineRetries; i++ {
+		start := time.Now()
+		ag.resetTimer(time.Hour)
+		ag.deadlineExceeded(ctx, []*types.Alert{firing}, err)
+
+		if i == maxDeadlineRetries {
+			if ag.nextFlush.Before(start.Add(time.Hour)) {
+				t.Fatalf("expected no retry after %d retries but next flush is at %v", i, ag.nextFlush)
+			}
+			break
+		}
+		backoff := deadlineRetryBackoff << uint(i)
+		if ag.nextFlush.Before(start.Add(backoff)) || ag.nextFlush.After(time.Now().Add(backoff)) {
+			t.Fatalf("expected retry %d after %s but next flush is at %v", i, backoff, ag.nextFlush)
+		}
+	}
+
+	// Dead letters are recorded and resolved alerts are not retried.
+	dl := provider.NewMemDeadLetters(10)
+
+	ag = newAggrGroup(context.Background(), model.LabelSet{}, route)
+	ag.deadlinePolicy = config.DeadlineDeadLetter
+	ag.deadLetters = dl
+	ag.insert(firing)
+	ag.insert(resolved)
+
+	ag.deadlineExceeded(ctx, []*types.Alert{firing, resolved}, err)
+
+	letters, _ := dl.All()
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter but got %v", letters)
+	}
+	// The dead letter is addressed to the receiver that was notified.
+	if l := letters[0]; l.Receiver != "n1-escalated" || l.GroupKey != ag.groupKey() || l.Reason != err.Error() || len(l.Alerts) != 2 {
+		t.Fatalf("unexpected dead letter %+v", l)
+	}
+	if len(ag.alerts) != 1 || ag.alerts[firing.Fingerprint()] == nil {
+		t.Fatalf("expected only the firing alert to remain but got %v", ag.alerts)
+	}
+
+	// The firing alert is not notified about again until it fires anew.
+	var notified []*types.Alert
+	ntfy := func(alerts ...*types.Alert) bool {
+		notified = alerts
+		return true
+	}
+	ag.flush(ntfy)
+	if notified != nil {
+		t.Fatalf("expected dead-lettered alert not to be notified but got %v", notified)
+	}
+
+	refiring := *firing
+	refiring.StartsAt = time.Now()
+	ag.insert(&refiring)
+	ag.flush(ntfy)
+	if len(notified) != 1 || notified[0] != &refiring {
+		t.Fatalf("expected alert firing anew to be notified but got %v", notified)
+	}
+}
+
+func TestAggrGroupAcked(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{},
+			GroupWait:      1 * time.Second,
+			GroupInterval:  300 * time.Millisecond,
+			RepeatInterval: 1 * time.Hour,
+		},
+	}
+	var (
+		now = time.Now()
+		a1  = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"a": "v1"},
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   now.Add(time.Hour),
+			},
+		}
+		a2 = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"a": "v2"},
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   now.Add(time.Hour),
+			},
+		}
+	)
+
+	var notified types.AlertSlice
+	ntfy := func(alerts ...*types.Alert) bool {
+		notified = alerts
+		return true
+	}
+
+	marker := types.NewMarker()
+	marker.SetAcked(a1.Fingerprint(), &types.Ack{Alert: a1.Fingerprint(), CreatedAt: now})
+
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route)
+	ag.marker = marker
+	ag.insert(a1)
+	ag.insert(a2)
+
+	ag.flush(ntfy)
+
+	if exp := (types.AlertSlice{a2}); !reflect.DeepEqual(notified, exp) {
+		t.Fatalf("expected alerts %v but got %v", exp, notified)
+	}
+
+	// Once resolved, the acknowledged alert is notified about again.
+	resolved := *a1
+	resolved.EndsAt = now.Add(-time.Second)
+	ag.insert(&resolved)
+
+	notified = nil
+	ag.flush(ntfy)
+
+	if len(notified) != 2 {
+		t.Fatalf("expected 2 alerts but got %v", notified)
+	}
+
+	// Acknowledgements do not apply after they expired.
+	marker.SetAcked(a2.Fingerprint(), &types.Ack{
+		Alert:     a2.Fingerprint(),
+		CreatedAt: now.Add(-time.Hour),
+		ExpiresAt: now.Add(-time.Minute),
+	})
+
+	notified = nil
+	ag.flush(ntfy)
+
+	if exp := (types.AlertSlice{a2}); !reflect.DeepEqual(notified, exp) {
+		t.Fatalf("expected alerts %v but got %v", exp, notified)
+	}
+}
+
+func TestAggrGroupMaintenance(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{},
+			GroupWait:      1 * time.Second,
+			GroupInterval:  300 * time.Millisecond,
+			RepeatInterval: 1 * time.Hour,
+		},
+	}
+	var (
+		now = time.Now()
+		a1  = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"service": "db"},
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   now.Add(time.Hour),
+			},
+		}
+		a2 = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"service": "web"},
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   now.Add(time.Hour),
+			},
+		}
+	)
+
+	var notified types.AlertSlice
+	ntfy := func(alerts ...*types.Alert) bool {
+		notified = alerts
+		return true
+	}
+
+	maintenance := true
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route)
+	ag.maintenance = types.MuteFunc(func(lset model.LabelSet) bool {
+		return maintenance && lset["service"] == "db"
+	})
+	ag.insert(a1)
+	ag.insert(a2)
+
+	ag.flush(ntfy)
+
+	if exp := (types.AlertSlice{a2}); !reflect.DeepEqual(notified, exp) {
+		t.Fatalf("expected alerts %v but got %v", exp, notified)
+	}
+
+	// Alerts are notified about once the maintenance ended.
+	maintenance = false
+	notified = nil
+	ag.flush(ntfy)
+
+	if len(notified) != 2 {
+		t.Fatalf("expected 2 alerts but got %v", notified)
+	}
+}
+
+func TestAggrGroupState(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{},
+			GroupWait:      1 * time.Second,
+			GroupInterval:  300 * time.Millisecond,
+			RepeatInterval: 1 * time.Hour,
+		},
+	}
+	a := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"a": "v1"},
+			StartsAt: time.Now().Add(-time.Minute),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route)
+	ag.insert(a)
+
+	var b AlertBlock
+	ag.setState(&b)
+
+	if b.HasSent || b.LastFlush != nil || b.LastNotifyStatus != "" {
+		t.Fatalf("expected pending group but got %+v", b)
+	}
+	if b.NextFlush.IsZero() {
+		t.Fatalf("expected next flush to be set")
+	}
+
+	ag.flush(func(...*types.Alert) bool { return false })
+	ag.setState(&b)
+
+	if b.HasSent || b.LastFlush == nil || b.LastNotifyStatus != NotifyFailed {
+		t.Fatalf("expected failed notification but got %+v", b)
+	}
+
+	ag.flush(func(...*types.Alert) bool { return true })
+	ag.setState(&b)
+
+	if !b.HasSent || b.LastNotifyStatus != NotifySuccess {
+		t.Fatalf("expected successful notification but got %+v", b)
+	}
+}
+
+func TestAggrGroupSendResolvedAfter(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:          "n1",
+			GroupBy:           map[model.LabelName]struct{}{},
+			GroupWait:         1 * time.Second,
+			GroupInterval:     300 * time.Millisecond,
+			RepeatInterval:    1 * time.Hour,
+			SendResolvedAfter: 10 * time.Minute,
+		},
+	}
+	var (
+		a1 = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"a": "v1"},
+				StartsAt: time.Now().Add(-time.Hour),
+				EndsAt:   time.Now().Add(time.Hour),
+			},
+		}
+		// Resolved before it was ever notified about.
+		a2 = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"a": "v2"},
+				StartsAt: time.Now().Add(-time.Hour),
+				EndsAt:   time.Now().Add(-time.Hour),
+			},
+		}
+		notified []*types.Alert
+		notify   = func(alerts ...*types.Alert) bool {
+			notified = alerts
+			return true
+		}
+	)
+
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route)
+	ag.insert(a1)
+	ag.insert(a2)
+
+	ag.flush(notify)
+
+	if len(notified) != 1 || notified[0] != a1 {
+		t.Fatalf("expected only the firing alert to be notified but got %v", notified)
+	}
+	if _, ok := ag.alerts[a2.Fingerprint()]; ok {
+		t.Fatalf("expected resolved alert that never fired to be dropped")
+	}
+
+	// The alert resolves but the grace period has not passed yet.
+	resolved := *a1
+	resolved.EndsAt = time.Now().Add(-time.Minute)
+	ag.insert(&resolved)
+
+	notified = nil
+	ag.flush(notify)
+
+	if notified != nil {
+		t.Fatalf("expected resolved notification to be held back but got %v", notified)
+	}
+	if ag.empty() {
+		t.Fatalf("expected held back alert to remain in the group")
+	}
+
+	resolved.EndsAt = time.Now().Add(-20 * time.Minute)
+	ag.flush(notify)
+
+	if len(notified) != 1 || notified[0] != &resolved {
+		t.Fatalf("expected resolved alert to be notified but got %v", notified)
+	}
+	if !ag.empty() {
+		t.Fatalf("expected group to be empty after resolved notification")
+	}
+}
+
+func TestAggrGroupEscalation(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{},
+			GroupWait:      1 * time.Second,
+			GroupInterval:  300 * time.Millisecond,
+			RepeatInterval: 1 * time.Hour,
+			Escalation: []*config.EscalationStep{
+				{Receiver: "lead", AfterRepeatIntervals: 1},
+				{Receiver: "manager", AfterRepeatIntervals: 3},
+			},
+		},
+	}
+	var (
+		now = time.Now()
+		a1  = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"a": "v1"},
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   now.Add(time.Hour),
+			},
+		}
+	)
+	ntfy := func(alerts ...*types.Alert) bool { return true }
+
+	marker := types.NewMarker()
+
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route)
+	ag.marker = marker
+	ag.insert(a1)
+
+	checkEscalation := func(at time.Time, receiver string, level int) {
+		if r, l := ag.escalation(at); r != receiver || l != level {
+			t.Fatalf("expected receiver %q at level %d but got %q at level %d", receiver, level, r, l)
+		}
+	}
+
+	checkEscalation(now, "n1", 0)
+
+	ag.flush(ntfy)
+
+	firingSince := ag.firingSince
+	if firingSince.IsZero() {
+		t.Fatalf("expected firing streak to be started")
+	}
+	// Subsequent firing flushes continue the streak.
+	ag.flush(ntfy)
+
+	if !ag.firingSince.Equal(firingSince) {
+		t.Fatalf("expected firing streak to start at %v but got %v", firingSince, ag.firingSince)
+	}
+
+	checkEscalation(firingSince.Add(59*time.Minute), "n1", 0)
+	checkEscalation(firingSince.Add(time.Hour), "lead", 1)
+	checkEscalation(firingSince.Add(3*time.Hour), "manager", 2)
+	checkEscalation(firingSince.Add(10*time.Hour), "manager", 2)
+
+	// Acknowledging the alert ends the escalation.
+	marker.SetAcked(a1.Fingerprint(), &types.Ack{Alert: a1.Fingerprint(), CreatedAt: now})
+	ag.flush(ntfy)
+
+	checkEscalation(firingSince.Add(3*time.Hour), "n1", 0)
+
+	// Resolving the alert ends the escalation as well.
+	marker.SetAcked(a1.Fingerprint())
+	ag.flush(ntfy)
+
+	resolved := *a1
+	resolved.EndsAt = now.Add(-time.Second)
+	ag.insert(&resolved)
+	ag.flush(ntfy)
+
+	checkEscalation(firingSince.Add(3*time.Hour), "n1", 0)
+
+	// Groups handed over to another receiver are not escalated.
+	ag.insert(a1)
+	ag.flush(ntfy)
+	ag.reassign("other")
+
+	checkEscalation(ag.firingSince.Add(10*time.Hour), "other", 0)
+
+	var b AlertBlock
+	ag.setState(&b)
+
+	if b.ReassignedTo != "other" {
+		t.Errorf("expected group to be reassigned to %q but got %q", "other", b.ReassignedTo)
+	}
+}
+
+// BenchmarkDispatcherProcessAlertSerial is the single-goroutine baseline of
+// BenchmarkDispatcherProcessAlert.
+func BenchmarkDispatcherProcessAlertSerial(b *testing.B) {
+	benchmarkDispatcherProcessAlert(b, false)
+}
+
+// BenchmarkDispatcherProcessAlert measures inserting 50k distinct alerts
+// into 5k aggregation groups from concurrent workers while the groups are
+// listed through the API.
+func BenchmarkDispatcherProcessAlert(b *testing.B) {
+	benchmarkDispatcherProcessAlert(b, true)
+}
+
+func benchmarkDispatcherProcessAlert(b *testing.B, parallel bool) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "r",
+			GroupBy:        map[model.LabelName]struct{}{"g": struct{}{}},
+			GroupWait:      time.Hour,
+			GroupInterval:  time.Hour,
+			RepeatInterval: time.Hour,
+		},
+	}
+	d := NewDispatcher(nil, route, nil, types.NewMarker())
+	defer d.cancel()
+
+	now := time.Now()
+	alerts := make([]*types.Alert, 50000)
+	for i := range alerts {
+		alerts[i] = &types.Alert{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					"g": model.LabelValue(strconv.Itoa(i % 5000)),
+					"i": model.LabelValue(strconv.Itoa(i)),
+				},
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+			},
+		}
+	}
+	for _, a := range alerts {
+		d.processAlert(a, route)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		t := time.NewTicker(10 * time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				d.GroupByHash("")
+			}
+		}
+	}()
+
+	b.ResetTimer()
+
+	if !parallel {
+		for i := 0; i < b.N; i++ {
+			d.processAlert(alerts[i%len(alerts)], route)
+		}
+		return
+	}
+
+	var n uint64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := atomic.AddUint64(&n, 1)
+			d.processAlert(alerts[i%uint64(len(alerts))], route)
+		}
+	})
+}
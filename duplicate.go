@@ -0,0 +1,320 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// A Duplicator inserts copies of alerts with modified labels based on
+// a set of duplication rules.
+type Duplicator struct {
+	alerts provider.Alerts
+	rules  []*DuplicateRule
+
+	// Interval in which pending duplicates are checked.
+	interval time.Duration
+	// Duration for which a duplicate is remembered after it was last
+	// inserted or seen.
+	retention time.Duration
+	// Fingerprints of all created duplicates by the time they are
+	// forgotten.
+	created map[model.Fingerprint]time.Time
+
+	mtx   sync.RWMutex
+	stopc chan struct{}
+}
+
+// NewDuplicator returns a new Duplicator.
+func NewDuplicator(ap provider.Alerts, rs []*config.DuplicateRule) *Duplicator {
+	d := &Duplicator{
+		alerts:    ap,
+		interval:  10 * time.Second,
+		retention: time.Hour,
+		created:   map[model.Fingerprint]time.Time{},
+		stopc:     make(chan struct{}),
+	}
+	for _, cr := range rs {
+		d.rules = append(d.rules, NewDuplicateRule(cr))
+	}
+	return d
+}
+
+// Run the Duplicator's background processing.
+func (d *Duplicator) Run() {
+	// Skip all work if there are no rules.
+	if len(d.rules) == 0 {
+		return
+	}
+
+	tick := time.NewTicker(d.interval)
+	defer tick.Stop()
+
+	it := d.alerts.Subscribe()
+	defer it.Close()
+
+	for {
+		select {
+		case <-d.stopc:
+			return
+
+		case now := <-tick.C:
+			d.expire(now)
+
+			var dups []*types.Alert
+			for _, r := range d.rules {
+				dups = append(dups, r.due(now)...)
+			}
+			d.put(dups)
+
+		case a := <-it.Next():
+			if err := it.Err(); err != nil {
+				log.Errorf("Error iterating alerts: %s", err)
+				continue
+			}
+			// Duplicates are not duplicated any further. Resolved ones are
+			// remembered as well so that later updates of them are not
+			// taken for source alerts.
+			if d.seen(a.Fingerprint(), time.Now()) {
+				continue
+			}
+			var dups []*types.Alert
+			for _, r := range d.rules {
+				dups = append(dups, r.observe(a, time.Now())...)
+			}
+			d.put(dups)
+		}
+	}
+}
+
+// seen returns true iff the fingerprint belongs to a created duplicate,
+// which is then remembered for another retention period.
+func (d *Duplicator) seen(fp model.Fingerprint, now time.Time) bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if _, ok := d.created[fp]; !ok {
+		return false
+	}
+	d.created[fp] = now.Add(d.retention)
+	return true
+}
+
+// expire forgets all duplicates that have not been inserted or seen
+// within the retention.
+func (d *Duplicator) expire(now time.Time) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	for fp, until := range d.created {
+		if now.After(until) {
+			delete(d.created, fp)
+		}
+	}
+}
+
+// put inserts the duplicates asynchronously as their insertion is
+// propagated back to the Duplicator's own subscription. Duplicates whose
+// insertion failed are forgotten again.
+func (d *Duplicator) put(alerts []*types.Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	until := time.Now().Add(d.retention)
+
+	d.mtx.Lock()
+	for _, a := range alerts {
+		d.created[a.Fingerprint()] = until
+	}
+	d.mtx.Unlock()
+
+	go func() {
+		if err := d.alerts.Put(alerts...); err != nil {
+			log.Errorf("Inserting %d duplicated alerts failed: %s", len(alerts), err)
+
+			d.mtx.Lock()
+			defer d.mtx.Unlock()
+
+			for _, a := range alerts {
+				// Keep duplicates that were inserted again in the meantime.
+				if fp := a.Fingerprint(); d.created[fp].Equal(until) {
+					delete(d.created, fp)
+				}
+			}
+		}
+	}()
+}
+
+// Stop the Duplicator's background processing.
+func (d *Duplicator) Stop() {
+	if d == nil {
+		return
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	select {
+	case <-d.stopc:
+	default:
+		close(d.stopc)
+	}
+}
+
+// A DuplicateRule specifies that alerts matching a set of labels are
+// duplicated with a set of modified labels once they have been firing for
+// a given duration. Duplicates are updated along with their source alert.
+type DuplicateRule struct {
+	// The set of Filters which define the group of alerts to duplicate.
+	Matchers types.Matchers
+	// Duration the source alert has to be firing before being duplicated.
+	After time.Duration
+	// Labels that are set on the duplicate.
+	Labels model.LabelSet
+
+	mtx sync.Mutex
+	// Source alerts that match the rule by their fingerprint.
+	sources map[model.Fingerprint]*types.Alert
+	// Duplicates that were created by their source fingerprint.
+	dups map[model.Fingerprint]*types.Alert
+}
+
+// NewDuplicateRule returns a new DuplicateRule based on a configuration definition.
+func NewDuplicateRule(cr *config.DuplicateRule) *DuplicateRule {
+	var matchers types.Matchers
+
+	for ln, lv := range cr.Match {
+		matchers = append(matchers, types.NewMatcher(model.LabelName(ln), lv))
+	}
+	for ln, lv := range cr.MatchRE {
+		matchers = append(matchers, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
+	}
+
+	return &DuplicateRule{
+		Matchers: matchers,
+		After:    time.Duration(cr.After),
+		Labels:   cr.Labels,
+		sources:  map[model.Fingerprint]*types.Alert{},
+		dups:     map[model.Fingerprint]*types.Alert{},
+	}
+}
+
+// applies returns true iff the rule applies to the given label set and
+// duplicating would result in a different label set.
+func (r *DuplicateRule) applies(lset model.LabelSet) bool {
+	if !r.Matchers.Match(lset) {
+		return false
+	}
+	for ln, lv := range r.Labels {
+		if v, ok := lset[ln]; !ok || v != lv {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicate returns a copy of the alert with the rule's labels applied.
+func (r *DuplicateRule) duplicate(a *types.Alert, now time.Time) *types.Alert {
+	labels := a.Labels.Clone()
+	for ln, lv := range r.Labels {
+		labels[ln] = lv
+	}
+	return &types.Alert{
+		Alert: model.Alert{
+			Labels:       labels,
+			Annotations:  a.Annotations.Clone(),
+			StartsAt:     a.StartsAt.Add(r.After),
+			EndsAt:       a.EndsAt,
+			GeneratorURL: a.GeneratorURL,
+		},
+		UpdatedAt: now,
+		Timeout:   a.Timeout,
+	}
+}
+
+// observe processes an update of an alert and returns the duplicates
+// that have to be inserted as a consequence.
+func (r *DuplicateRule) observe(a *types.Alert, now time.Time) []*types.Alert {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	fp := a.Fingerprint()
+
+	if !r.applies(a.Labels) {
+		return nil
+	}
+	// Duplicates already created are kept in sync with their source.
+	if _, ok := r.dups[fp]; ok {
+		dup := r.duplicate(a, now)
+		if a.Resolved() {
+			delete(r.sources, fp)
+			delete(r.dups, fp)
+		} else {
+			r.sources[fp] = a
+			r.dups[fp] = dup
+		}
+		return []*types.Alert{dup}
+	}
+	if a.Resolved() {
+		delete(r.sources, fp)
+		return nil
+	}
+	r.sources[fp] = a
+
+	return r.dueLocked(now)
+}
+
+// due returns the duplicates of all source alerts which have been
+// firing long enough.
+func (r *DuplicateRule) due(now time.Time) []*types.Alert {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.dueLocked(now)
+}
+
+func (r *DuplicateRule) dueLocked(now time.Time) []*types.Alert {
+	var res []*types.Alert
+
+	for fp, a := range r.sources {
+		if _, ok := r.dups[fp]; ok {
+			// As alerts can time out without an update, resolved sources
+			// and their duplicates are cleaned up here.
+			if a.Resolved() {
+				delete(r.sources, fp)
+				delete(r.dups, fp)
+			}
+			continue
+		}
+		if a.Resolved() {
+			delete(r.sources, fp)
+			continue
+		}
+		if now.Before(a.StartsAt.Add(r.After)) {
+			continue
+		}
+		dup := r.duplicate(a, now)
+
+		r.dups[fp] = dup
+		res = append(res, dup)
+	}
+	return res
+}
@@ -0,0 +1,152 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestDuplicateRule(t *testing.T) {
+	r := NewDuplicateRule(&config.DuplicateRule{
+		Match:  map[string]string{"severity": "critical"},
+		After:  model.Duration(time.Hour),
+		Labels: model.LabelSet{"escalation": "vp"},
+	})
+
+	now := time.Now()
+
+	source := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a", "severity": "critical"},
+			StartsAt: now.Add(-30 * time.Minute),
+			EndsAt:   now.Add(time.Hour),
+		},
+	}
+	other := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a", "severity": "warning"},
+			StartsAt: now.Add(-2 * time.Hour),
+			EndsAt:   now.Add(time.Hour),
+		},
+	}
+
+	if dups := r.observe(other, now); len(dups) != 0 {
+		t.Fatalf("expected non-matching alert not to be duplicated but got %v", dups)
+	}
+	if dups := r.observe(source, now); len(dups) != 0 {
+		t.Fatalf("expected no duplicate before %s but got %v", r.After, dups)
+	}
+
+	dups := r.due(now.Add(31 * time.Minute))
+	if len(dups) != 1 {
+		t.Fatalf("expected one duplicate but got %v", dups)
+	}
+	dup := dups[0]
+
+	exp := model.LabelSet{"alertname": "a", "severity": "critical", "escalation": "vp"}
+	if !dup.Labels.Equal(exp) {
+		t.Fatalf("expected labels %v but got %v", exp, dup.Labels)
+	}
+	if !dup.StartsAt.Equal(source.StartsAt.Add(time.Hour)) {
+		t.Fatalf("expected duplicate to start at %v but got %v", source.StartsAt.Add(time.Hour), dup.StartsAt)
+	}
+
+	// The duplicate itself does not yield another duplicate.
+	if dups := r.observe(dup, now); len(dups) != 0 {
+		t.Fatalf("expected duplicate not to be duplicated but got %v", dups)
+	}
+	if dups := r.due(now.Add(time.Hour)); len(dups) != 0 {
+		t.Fatalf("expected no further duplicates but got %v", dups)
+	}
+
+	// Resolving the source resolves the duplicate.
+	resolved := &types.Alert{
+		Alert: model.Alert{
+			Labels:   source.Labels,
+			StartsAt: source.StartsAt,
+			EndsAt:   now.Add(-time.Second),
+		},
+	}
+	dups = r.observe(resolved, now)
+	if len(dups) != 1 || !dups[0].Resolved() || dups[0].Fingerprint() != dup.Fingerprint() {
+		t.Fatalf("expected resolved duplicate but got %v", dups)
+	}
+	if len(r.sources) != 0 || len(r.dups) != 0 {
+		t.Fatalf("expected rule state to be cleared but got %v, %v", r.sources, r.dups)
+	}
+}
+
+type failingAlerts struct {
+	provider.Alerts
+	putc chan struct{}
+}
+
+func (a *failingAlerts) Put(...*types.Alert) error {
+	defer close(a.putc)
+	return errors.New("put failed")
+}
+
+func TestDuplicatorCreated(t *testing.T) {
+	d := NewDuplicator(nil, nil)
+
+	now := time.Now()
+	fp := model.Fingerprint(1)
+	d.created[fp] = now.Add(d.retention)
+
+	// Seeing a duplicate, resolved or not, keeps it from expiring.
+	if !d.seen(fp, now.Add(d.retention/2)) {
+		t.Fatalf("expected duplicate %s to be seen", fp)
+	}
+	d.expire(now.Add(d.retention + time.Minute))
+	if !d.seen(fp, now.Add(d.retention+time.Minute)) {
+		t.Fatalf("expected duplicate %s to be kept after being seen", fp)
+	}
+
+	d.expire(now.Add(3 * d.retention))
+	if d.seen(fp, now.Add(3*d.retention)) {
+		t.Fatalf("expected duplicate %s to be expired but got %v", fp, d.created)
+	}
+
+	// Duplicates whose insertion fails are forgotten.
+	alerts := &failingAlerts{putc: make(chan struct{})}
+	d.alerts = alerts
+
+	dup := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "a", "escalation": "vp"},
+		},
+	}
+	d.put([]*types.Alert{dup})
+	<-alerts.putc
+
+	// The failed insertion is handled after Put returned.
+	for i := 0; i < 100; i++ {
+		d.mtx.RLock()
+		n := len(d.created)
+		d.mtx.RUnlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected failed duplicate to be forgotten but got %v", d.created)
+}
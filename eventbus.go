@@ -0,0 +1,461 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/avro"
+	"github.com/prometheus/alertmanager/kafka"
+	"github.com/prometheus/alertmanager/nats"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var (
+	busMessagesPublished = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "eventbus_messages_published_total",
+		Help:      "The total number of messages published to the event bus.",
+	})
+	busMessagesDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "eventbus_messages_dropped_total",
+		Help:      "The total number of messages dropped because the event bus queue was full or they could not be encoded.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(busMessagesPublished)
+	prometheus.MustRegister(busMessagesDropped)
+}
+
+// Types of messages published to the event bus.
+const (
+	busAlertState      = "alert_state"
+	busNotification    = "notification"
+	busEventTransition = "event_transition"
+)
+
+// Formats of messages published to the event bus.
+const (
+	busFormatJSON = "json"
+	busFormatAvro = "avro"
+)
+
+const (
+	busQueueSize    = 10000
+	busBatchSize    = 100
+	busRetryBackoff = 5 * time.Second
+)
+
+// BusMessage is a message published to the event bus. The payload matching
+// its type is set.
+type BusMessage struct {
+	Type            string                   `json:"type"`
+	Time            time.Time                `json:"time"`
+	AlertState      *types.AlertHistoryEntry `json:"alertState,omitempty"`
+	Notification    *NotificationResult      `json:"notification,omitempty"`
+	EventTransition *EventTransition         `json:"eventTransition,omitempty"`
+}
+
+// key returns the key of the message. Messages with the same key are
+// published to the same Kafka partition and thus retain their order.
+func (m *BusMessage) key() string {
+	switch {
+	case m.AlertState != nil:
+		return m.AlertState.Alert.String()
+	case m.Notification != nil:
+		return m.Notification.GroupKey
+	case m.EventTransition != nil:
+		return strconv.FormatUint(m.EventTransition.Event.ID, 10)
+	}
+	return ""
+}
+
+// NotificationResult is the outcome of notifying an alert group through
+// an integration of a receiver after all retries.
+type NotificationResult struct {
+	Receiver    string `json:"receiver"`
+	Integration string `json:"integration"`
+	GroupKey    string `json:"groupKey"`
+	// Alerts are the fingerprints of the notified alerts.
+	Alerts  []string `json:"alerts"`
+	Success bool     `json:"success"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// busSink publishes encoded messages to a message bus.
+type busSink interface {
+	publish(keys []string, values [][]byte) error
+	close() error
+}
+
+// EventBus publishes alert state changes, notification results, and event
+// transitions to a message bus in the background. Messages are dropped if
+// the bus cannot keep up with them.
+type EventBus struct {
+	sink   busSink
+	format string
+
+	queue chan *BusMessage
+	stopc chan struct{}
+	done  chan struct{}
+}
+
+// NewEventBus returns an EventBus publishing messages in the format to the
+// sink.
+func NewEventBus(sink busSink, format string) *EventBus {
+	return &EventBus{
+		sink:   sink,
+		format: format,
+		queue:  make(chan *BusMessage, busQueueSize),
+		stopc:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Publish queues the message for publishing. Nil EventBuses publish
+// nothing.
+func (b *EventBus) Publish(m *BusMessage) {
+	if b == nil {
+		return
+	}
+	select {
+	case b.queue <- m:
+	default:
+		busMessagesDropped.Inc()
+	}
+}
+
+// Run publishes queued messages until the EventBus is stopped.
+func (b *EventBus) Run() {
+	defer close(b.done)
+	defer b.sink.close()
+
+	for {
+		select {
+		case <-b.stopc:
+			b.shutdown(nil)
+			return
+		case m := <-b.queue:
+			batch := b.batch(m)
+			for !b.publish(batch) {
+				select {
+				case <-b.stopc:
+					b.shutdown(batch)
+					return
+				case <-time.After(busRetryBackoff):
+				}
+			}
+		}
+	}
+}
+
+// shutdown gives the pending batch and all queued messages a single
+// attempt to be published.
+func (b *EventBus) shutdown(batch []*BusMessage) {
+	for {
+		if batch = append(batch, b.batch(nil)...); len(batch) == 0 {
+			return
+		}
+		if !b.publish(batch) {
+			busMessagesDropped.Add(float64(len(batch) + len(b.queue)))
+			return
+		}
+		batch = nil
+	}
+}
+
+// batch returns the message followed by further queued messages.
+func (b *EventBus) batch(m *BusMessage) []*BusMessage {
+	var batch []*BusMessage
+	if m != nil {
+		batch = append(batch, m)
+	}
+	for len(batch) < busBatchSize {
+		select {
+		case m := <-b.queue:
+			batch = append(batch, m)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// publish returns false if publishing the batch failed and it should be
+// retried.
+func (b *EventBus) publish(batch []*BusMessage) bool {
+	var (
+		keys   = make([]string, 0, len(batch))
+		values = make([][]byte, 0, len(batch))
+	)
+	for _, m := range batch {
+		v, err := encodeBusMessage(b.format, m)
+		if err != nil {
+			log.With("type", m.Type).Errorf("Encoding event bus message failed: %s", err)
+			busMessagesDropped.Inc()
+			continue
+		}
+		keys = append(keys, m.key())
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return true
+	}
+	if err := b.sink.publish(keys, values); err != nil {
+		log.Errorf("Publishing %d messages to the event bus failed: %s", len(values), err)
+		return false
+	}
+	busMessagesPublished.Add(float64(len(values)))
+	return true
+}
+
+// Stop publishing and close the connection to the message bus.
+func (b *EventBus) Stop() {
+	close(b.stopc)
+	<-b.done
+}
+
+// kafkaSink produces messages to a Kafka topic.
+type kafkaSink struct {
+	producer *kafka.Producer
+}
+
+func newKafkaSink(cfg kafka.Config) *kafkaSink {
+	return &kafkaSink{producer: kafka.NewProducer(cfg)}
+}
+
+func (s *kafkaSink) publish(keys []string, values [][]byte) error {
+	var (
+		now  = time.Now()
+		msgs = make([]*kafka.Message, 0, len(values))
+	)
+	for i, v := range values {
+		msgs = append(msgs, &kafka.Message{Key: []byte(keys[i]), Value: v, Timestamp: now})
+	}
+	return s.producer.Produce(msgs...)
+}
+
+func (s *kafkaSink) close() error { return s.producer.Close() }
+
+// natsSink publishes messages to a NATS subject. It reconnects to the
+// server on the next messages after failures.
+type natsSink struct {
+	url     string
+	subject string
+	timeout time.Duration
+
+	conn *nats.Conn
+}
+
+func newNATSSink(url, subject string, timeout time.Duration) *natsSink {
+	return &natsSink{url: url, subject: subject, timeout: timeout}
+}
+
+func (s *natsSink) publish(keys []string, values [][]byte) (err error) {
+	if s.conn == nil {
+		if s.conn, err = nats.Dial(s.url, "alertmanager", s.timeout); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		if err != nil {
+			s.close()
+		}
+	}()
+	for _, v := range values {
+		if err := s.conn.Publish(s.subject, v); err != nil {
+			return err
+		}
+	}
+	return s.conn.Flush()
+}
+
+func (s *natsSink) close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// busHistory is an AlertHistory publishing the recorded state changes of
+// alerts to the event bus.
+type busHistory struct {
+	provider.AlertHistory
+	bus *EventBus
+}
+
+// NewBusHistory returns an AlertHistory wrapping h that publishes added
+// entries to the event bus.
+func NewBusHistory(h provider.AlertHistory, bus *EventBus) provider.AlertHistory {
+	return &busHistory{AlertHistory: h, bus: bus}
+}
+
+// Add implements the provider.AlertHistory interface.
+func (h *busHistory) Add(entries ...*types.AlertHistoryEntry) error {
+	if err := h.AlertHistory.Add(entries...); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		h.bus.Publish(&BusMessage{Type: busAlertState, Time: e.Time, AlertState: e})
+	}
+	return nil
+}
+
+// publishResults returns a notifier publishing the result of notifying
+// through n to the event bus.
+func publishResults(bus *EventBus, n notify.Notifier) notify.Notifier {
+	return notify.NotifierFunc(func(ctx context.Context, alerts ...*types.Alert) error {
+		err := n.Notify(ctx, alerts...)
+
+		res := &NotificationResult{Success: err == nil}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		rcv, _ := notify.Receiver(ctx)
+		res.Receiver, res.Integration = splitReceiver(rcv)
+		res.GroupKey, _ = notify.GroupKey(ctx)
+		for _, a := range alerts {
+			res.Alerts = append(res.Alerts, a.Fingerprint().String())
+		}
+		now, ok := notify.Now(ctx)
+		if !ok {
+			now = time.Now()
+		}
+		bus.Publish(&BusMessage{Type: busNotification, Time: now, Notification: res})
+
+		return err
+	})
+}
+
+// splitReceiver splits the receiver of a notification through a single
+// integration into the name of the receiver and the integration, by which
+// it is suffixed along with the integration's index.
+func splitReceiver(rcv string) (receiver, integration string) {
+	if i := strings.LastIndex(rcv, "/"); i > 0 {
+		if j := strings.LastIndex(rcv[:i], "/"); j > 0 {
+			return rcv[:j], rcv[j+1:]
+		}
+	}
+	return rcv, ""
+}
+
+// busAvroSchema is the Avro schema of bus messages in parsing canonical
+// form. Times are in milliseconds since the epoch.
+const busAvroSchema = `{"name":"io.prometheus.alertmanager.BusMessage","type":"record","fields":[` +
+	`{"name":"type","type":"string"},` +
+	`{"name":"time","type":"long"},` +
+	`{"name":"alertState","type":["null",{"name":"io.prometheus.alertmanager.AlertState","type":"record","fields":[` +
+	`{"name":"alert","type":"string"},` +
+	`{"name":"state","type":"string"},` +
+	`{"name":"labels","type":{"type":"map","values":"string"}},` +
+	`{"name":"silence","type":"long"},` +
+	`{"name":"inhibitedBy","type":["null","string"]}]}]},` +
+	`{"name":"notification","type":["null",{"name":"io.prometheus.alertmanager.NotificationResult","type":"record","fields":[` +
+	`{"name":"receiver","type":"string"},` +
+	`{"name":"integration","type":"string"},` +
+	`{"name":"groupKey","type":"string"},` +
+	`{"name":"alerts","type":{"type":"array","items":"string"}},` +
+	`{"name":"success","type":"boolean"},` +
+	`{"name":"error","type":["null","string"]}]}]},` +
+	`{"name":"eventTransition","type":["null",{"name":"io.prometheus.alertmanager.EventTransition","type":"record","fields":[` +
+	`{"name":"eventId","type":"long"},` +
+	`{"name":"title","type":"string"},` +
+	`{"name":"labels","type":{"type":"map","values":"string"}},` +
+	`{"name":"from","type":"string"},` +
+	`{"name":"to","type":"string"},` +
+	`{"name":"author","type":["null","string"]},` +
+	`{"name":"comment","type":["null","string"]}]}]}]}`
+
+// encodeBusMessage encodes the message in the format. Avro messages use
+// the single object encoding.
+func encodeBusMessage(format string, m *BusMessage) ([]byte, error) {
+	switch format {
+	case busFormatJSON:
+		return json.Marshal(m)
+	case busFormatAvro:
+		return encodeBusAvro(m), nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func encodeBusAvro(m *BusMessage) []byte {
+	e := avro.NewSingleObjectEncoder(busAvroSchema)
+	e.String(m.Type)
+	e.Long(m.Time.UnixNano() / int64(time.Millisecond))
+
+	if s := m.AlertState; s == nil {
+		e.Union(0)
+	} else {
+		e.Union(1)
+		e.String(s.Alert.String())
+		e.String(string(s.State))
+		e.StringMap(labelMap(s.Labels))
+		e.Long(int64(s.Silence))
+		if s.InhibitedBy == nil {
+			e.Union(0)
+		} else {
+			e.Union(1)
+			e.String(s.InhibitedBy.Fingerprint.String())
+		}
+	}
+
+	if n := m.Notification; n == nil {
+		e.Union(0)
+	} else {
+		e.Union(1)
+		e.String(n.Receiver)
+		e.String(n.Integration)
+		e.String(n.GroupKey)
+		e.StringArray(n.Alerts)
+		e.Boolean(n.Success)
+		e.OptionalString(n.Error)
+	}
+
+	if t := m.EventTransition; t == nil {
+		e.Union(0)
+	} else {
+		e.Union(1)
+		e.Long(int64(t.Event.ID))
+		e.String(t.Event.Title)
+		e.StringMap(labelMap(t.Event.Labels))
+		e.String(string(t.From))
+		e.String(string(t.To))
+		e.OptionalString(t.Author)
+		e.OptionalString(t.Comment)
+	}
+	return e.Bytes()
+}
+
+func labelMap(lset model.LabelSet) map[string]string {
+	m := make(map[string]string, len(lset))
+	for k, v := range lset {
+		m[string(k)] = string(v)
+	}
+	return m
+}
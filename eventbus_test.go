@@ -0,0 +1,169 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/avro"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider/boltmem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// testBusSink records published messages. Publishing fails while err is
+// set.
+type testBusSink struct {
+	mtx    sync.Mutex
+	keys   []string
+	values [][]byte
+	err    error
+}
+
+func (s *testBusSink) publish(keys []string, values [][]byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.keys = append(s.keys, keys...)
+	s.values = append(s.values, values...)
+	return nil
+}
+
+func (s *testBusSink) close() error { return nil }
+
+func TestEventBus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventbus_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h, err := boltmem.NewAlertHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	var (
+		sink  = &testBusSink{}
+		bus   = NewEventBus(sink, busFormatJSON)
+		hooks = NewEventHooks()
+		now   = time.Now()
+	)
+	hooks.SetEventBus(bus)
+
+	err = NewBusHistory(h, bus).Add(&types.AlertHistoryEntry{Alert: 1, Time: now, State: types.AlertFiring})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hooks.Notify(&EventTransition{
+		Event: &types.Event{ID: 3},
+		From:  types.EventStatus("open"),
+		To:    types.EventStatus("acknowledged"),
+		Time:  now,
+	})
+
+	n := publishResults(bus, notify.NotifierFunc(func(context.Context, ...*types.Alert) error {
+		return fmt.Errorf("boom")
+	}))
+	ctx := notify.WithReceiver(context.Background(), "team/a/webhook/0")
+	ctx = notify.WithGroupKey(ctx, "{}:{alertname=\"test\"}")
+	alert := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "test"}}}
+	if err := n.Notify(ctx, alert); err == nil {
+		t.Fatalf("expected notification error to be returned")
+	}
+
+	// Publishing is retried on failure.
+	sink.err = fmt.Errorf("unavailable")
+	go bus.Run()
+	time.Sleep(10 * time.Millisecond)
+	sink.mtx.Lock()
+	sink.err = nil
+	sink.mtx.Unlock()
+	// Messages queued on shutdown are published.
+	bus.Stop()
+
+	if expected := []string{model.Fingerprint(1).String(), "3", "{}:{alertname=\"test\"}"}; !reflect.DeepEqual(sink.keys, expected) {
+		t.Fatalf("expected keys %v but got %v", expected, sink.keys)
+	}
+	var msgs []BusMessage
+	for _, v := range sink.values {
+		var m BusMessage
+		if err := json.Unmarshal(v, &m); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, m)
+	}
+	if msgs[0].Type != busAlertState || msgs[0].AlertState.State != types.AlertFiring {
+		t.Errorf("unexpected alert state message %+v", msgs[0])
+	}
+	if msgs[1].Type != busEventTransition || msgs[1].EventTransition.To != types.EventStatus("acknowledged") {
+		t.Errorf("unexpected event transition message %+v", msgs[1])
+	}
+	expected := &NotificationResult{
+		Receiver:    "team/a",
+		Integration: "webhook/0",
+		GroupKey:    "{}:{alertname=\"test\"}",
+		Alerts:      []string{alert.Fingerprint().String()},
+		Error:       "boom",
+	}
+	if msgs[2].Type != busNotification || !reflect.DeepEqual(msgs[2].Notification, expected) {
+		t.Errorf("expected notification result %+v but got %+v", expected, msgs[2].Notification)
+	}
+}
+
+func TestEncodeBusAvro(t *testing.T) {
+	b := encodeBusAvro(&BusMessage{
+		Type:         busNotification,
+		Time:         time.Unix(1, 0),
+		Notification: &NotificationResult{Receiver: "r", Success: true},
+	})
+	if b[0] != 0xc3 || b[1] != 0x01 {
+		t.Fatalf("expected single object encoding marker but got %x", b[:2])
+	}
+	if fp := binary.LittleEndian.Uint64(b[2:]); fp != avro.Fingerprint(busAvroSchema) {
+		t.Errorf("expected schema fingerprint %x but got %x", avro.Fingerprint(busAvroSchema), fp)
+	}
+
+	e := &avro.Encoder{}
+	e.String(busNotification)
+	e.Long(1000)
+	e.Union(0) // No alert state.
+	e.Union(1)
+	e.String("r")
+	e.String("")
+	e.String("")
+	e.StringArray(nil)
+	e.Boolean(true)
+	e.OptionalString("")
+	e.Union(0) // No event transition.
+
+	if !reflect.DeepEqual(b[10:], e.Bytes()) {
+		t.Errorf("expected body %x but got %x", e.Bytes(), b[10:])
+	}
+}
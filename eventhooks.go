@@ -0,0 +1,127 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/tracing"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// EventTransition is a change of an event's status. It is the body of
+// requests sent to event hooks.
+type EventTransition struct {
+	Event   *types.Event      `json:"event"`
+	From    types.EventStatus `json:"from"`
+	To      types.EventStatus `json:"to"`
+	Author  string            `json:"author,omitempty"`
+	Comment string            `json:"comment,omitempty"`
+	Time    time.Time         `json:"time"`
+}
+
+// EventHooks sends event transitions to the configured hooks. All methods
+// are goroutine-safe.
+type EventHooks struct {
+	mtx   sync.RWMutex
+	hooks []*config.EventHook
+	bus   *EventBus
+}
+
+// NewEventHooks returns new EventHooks without any hooks.
+func NewEventHooks() *EventHooks {
+	return &EventHooks{}
+}
+
+// SetConfig sets the hooks transitions are sent to.
+func (h *EventHooks) SetConfig(hooks []*config.EventHook) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.hooks = hooks
+}
+
+// SetEventBus sets the event bus all transitions are published to.
+func (h *EventHooks) SetEventBus(bus *EventBus) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.bus = bus
+}
+
+// Notify sends the transition to all hooks interested in it and publishes
+// it to the event bus in the background. Nil EventHooks send nothing.
+func (h *EventHooks) Notify(t *EventTransition) {
+	if h == nil {
+		return
+	}
+	h.mtx.RLock()
+	hooks, bus := h.hooks, h.bus
+	h.mtx.RUnlock()
+
+	bus.Publish(&BusMessage{Type: busEventTransition, Time: t.Time, EventTransition: t})
+
+	for _, hook := range hooks {
+		if !hookWants(hook, t.To) {
+			continue
+		}
+		go func(hook *config.EventHook) {
+			if err := sendEventHook(hook, t); err != nil {
+				log.With("event", t.Event.ID).With("url", hook.URL).Errorf("Sending event transition failed: %s", err)
+			}
+		}(hook)
+	}
+}
+
+// hookWants returns true iff the hook is sent transitions into the status.
+func hookWants(hook *config.EventHook, to types.EventStatus) bool {
+	if len(hook.Statuses) == 0 {
+		return true
+	}
+	for _, s := range hook.Statuses {
+		if types.EventStatus(s) == to {
+			return true
+		}
+	}
+	return false
+}
+
+func sendEventHook(hook *config.EventHook, t *EventTransition) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(hook.Timeout))
+	defer cancel()
+
+	resp, err := tracing.Post(ctx, http.DefaultClient, hook.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// eventCreator is the creator of events created from templates.
+const eventCreator = "alertmanager"
+
+// An EventCreator creates events for alert groups firing for the first
+// time based on a set of event templates.
+type EventCreator struct {
+	events    provider.Events
+	tmpl      *template.Template
+	templates []*EventTemplate
+}
+
+// NewEventCreator returns a new EventCreator.
+func NewEventCreator(ev provider.Events, tmpl *template.Template, ts []*config.EventTemplate) *EventCreator {
+	c := &EventCreator{
+		events: ev,
+		tmpl:   tmpl,
+	}
+	for _, et := range ts {
+		c.templates = append(c.templates, NewEventTemplate(et))
+	}
+	return c
+}
+
+// Create creates an event from the first template matching any of the
+// group's firing alerts. It returns the ID of the created event or false
+// if no template matches.
+func (c *EventCreator) Create(receiver string, groupLabels model.LabelSet, alerts ...*types.Alert) (uint64, bool) {
+	var firing []*types.Alert
+	for _, a := range alerts {
+		if !a.Resolved() {
+			firing = append(firing, a)
+		}
+	}
+
+	for _, t := range c.templates {
+		if !t.matches(firing) {
+			continue
+		}
+		e, err := t.event(c.tmpl, c.tmpl.Data(receiver, groupLabels, firing...))
+		if err != nil {
+			log.Errorf("Executing event template failed: %s", err)
+			return 0, false
+		}
+		e.Labels = groupLabels.Merge(e.Labels)
+		e.CreatedAt = time.Now()
+		e.Creator = eventCreator
+
+		for _, a := range firing {
+			e.Alerts = append(e.Alerts, eventAlertID(a.Fingerprint()))
+		}
+
+		id, err := c.events.Set(e)
+		if err != nil {
+			log.Errorf("Creating event for group %s failed: %s", groupLabels, err)
+			return 0, false
+		}
+		return id, true
+	}
+	return 0, false
+}
+
+// An EventTemplate defines the event created for alert groups containing
+// an alert matching a set of matchers.
+type EventTemplate struct {
+	// The set of Filters which define the alerts the template applies to.
+	Matchers types.Matchers
+
+	Title       string
+	Description string
+	Labels      map[model.LabelName]string
+}
+
+// NewEventTemplate returns a new EventTemplate based on a configuration definition.
+func NewEventTemplate(et *config.EventTemplate) *EventTemplate {
+	var matchers types.Matchers
+
+	for ln, lv := range et.Match {
+		matchers = append(matchers, types.NewMatcher(model.LabelName(ln), lv))
+	}
+	for ln, lv := range et.MatchRE {
+		matchers = append(matchers, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
+	}
+
+	labels := map[model.LabelName]string{}
+	for ln, lv := range et.Labels {
+		labels[model.LabelName(ln)] = lv
+	}
+
+	return &EventTemplate{
+		Matchers:    matchers,
+		Title:       et.Title,
+		Description: et.Description,
+		Labels:      labels,
+	}
+}
+
+// matches returns true iff any of the alerts matches the template.
+func (t *EventTemplate) matches(alerts []*types.Alert) bool {
+	for _, a := range alerts {
+		if t.Matchers.Match(a.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// event executes the template against the given data. Labels rendering
+// to an empty value are omitted.
+func (t *EventTemplate) event(tmpl *template.Template, data *template.Data) (*types.Event, error) {
+	var (
+		e    = &types.Event{Labels: model.LabelSet{}}
+		err  error
+		text = func(s string) string {
+			if err != nil {
+				return ""
+			}
+			var res string
+			res, err = tmpl.ExecuteTextString(s, data)
+			return res
+		}
+	)
+
+	e.Title = text(t.Title)
+
+	if t.Description != "" {
+		e.Annotations = model.LabelSet{"description": model.LabelValue(text(t.Description))}
+	}
+	for ln, lv := range t.Labels {
+		if v := text(lv); v != "" {
+			e.Labels[ln] = model.LabelValue(v)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.Title == "" {
+		return nil, fmt.Errorf("empty event title")
+	}
+	return e, nil
+}
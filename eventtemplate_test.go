@@ -0,0 +1,115 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestAggrGroupEventCreation(t *testing.T) {
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+	events := provider.NewMemEvents()
+
+	ec := NewEventCreator(events, tmpl, []*config.EventTemplate{
+		{
+			Match:       map[string]string{"severity": "critical"},
+			Title:       "{{ .CommonLabels.alertname }} in {{ .GroupLabels.service }}",
+			Description: "{{ .Alerts.Firing | len }} alerts firing",
+			Labels:      map[string]string{"kind": "outage", "empty": ""},
+		},
+	})
+
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{"service": struct{}{}},
+			GroupWait:      1 * time.Second,
+			GroupInterval:  300 * time.Millisecond,
+			RepeatInterval: 1 * time.Hour,
+		},
+	}
+	var (
+		now = time.Now()
+		a1  = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "DBDown", "service": "db", "severity": "critical"},
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   now.Add(time.Hour),
+			},
+		}
+		a2 = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "DBDown", "service": "db", "severity": "warning"},
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   now.Add(time.Hour),
+			},
+		}
+	)
+	ntfy := func(alerts ...*types.Alert) bool { return true }
+
+	// Groups without matching alerts do not create events.
+	ag := newAggrGroup(context.Background(), model.LabelSet{"service": "db"}, route)
+	ag.eventCreator = ec
+	ag.insert(a2)
+	ag.flush(ntfy)
+
+	if es, _ := events.All(); len(es) != 0 {
+		t.Fatalf("expected no events but got %v", es)
+	}
+
+	ag = newAggrGroup(context.Background(), model.LabelSet{"service": "db"}, route)
+	ag.eventCreator = ec
+	ag.insert(a1)
+	ag.insert(a2)
+	ag.flush(ntfy)
+	// Events are only created once per group.
+	ag.flush(ntfy)
+
+	es, err := events.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 {
+		t.Fatalf("expected 1 event but got %d", len(es))
+	}
+	e := es[0]
+
+	if e.Title != "DBDown in db" {
+		t.Errorf("expected title %q but got %q", "DBDown in db", e.Title)
+	}
+	if d := e.Annotations["description"]; d != "2 alerts firing" {
+		t.Errorf("expected description %q but got %q", "2 alerts firing", d)
+	}
+	if exp := (model.LabelSet{"service": "db", "kind": "outage"}); !reflect.DeepEqual(e.Labels, exp) {
+		t.Errorf("expected labels %v but got %v", exp, e.Labels)
+	}
+	if e.Creator != eventCreator || len(e.Alerts) != 2 {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// FlapHistory stores the recent transitions between firing and resolved
+// of alerts by fingerprint. It is kept across configuration reloads.
+type FlapHistory struct {
+	mtx    sync.Mutex
+	alerts map[model.Fingerprint]*flapRecord
+}
+
+type flapRecord struct {
+	resolved    bool
+	endsAt      time.Time
+	transitions []time.Time
+}
+
+// NewFlapHistory returns a new empty FlapHistory.
+func NewFlapHistory() *FlapHistory {
+	return &FlapHistory{
+		alerts: map[model.Fingerprint]*flapRecord{},
+	}
+}
+
+// observe records the state of an updated alert.
+func (h *FlapHistory) observe(a *types.Alert, now time.Time) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	var (
+		fp       = a.Fingerprint()
+		resolved = a.ResolvedAt(now)
+	)
+	r, ok := h.alerts[fp]
+	if !ok {
+		h.alerts[fp] = &flapRecord{resolved: resolved, endsAt: a.EndsAt}
+		return
+	}
+
+	if r.resolved != resolved {
+		r.transitions = append(r.transitions, now)
+	} else if !resolved && !r.endsAt.IsZero() && r.endsAt.Before(a.StartsAt) {
+		// The alert timed out without an update and fired again.
+		r.transitions = append(r.transitions, r.endsAt, now)
+	}
+	r.resolved = resolved
+	r.endsAt = a.EndsAt
+}
+
+// transitions returns the number of transitions of the alert at or after
+// the given time. Older transitions are dropped.
+func (h *FlapHistory) transitions(fp model.Fingerprint, since time.Time) int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	r, ok := h.alerts[fp]
+	if !ok {
+		return 0
+	}
+	r.prune(since)
+
+	return len(r.transitions)
+}
+
+// gc removes the records of resolved alerts without transitions at or
+// after the given time.
+func (h *FlapHistory) gc(since time.Time) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	for fp, r := range h.alerts {
+		r.prune(since)
+		if r.resolved && len(r.transitions) == 0 {
+			delete(h.alerts, fp)
+		}
+	}
+}
+
+func (r *flapRecord) prune(since time.Time) {
+	i := 0
+	for i < len(r.transitions) && r.transitions[i].Before(since) {
+		i++
+	}
+	r.transitions = r.transitions[i:]
+}
+
+// A FlapDetector determines whether alerts are flapping based on their
+// transition history and holds back notifications for them.
+type FlapDetector struct {
+	alerts  provider.Alerts
+	conf    *config.FlapDetection
+	history *FlapHistory
+	marker  types.Marker
+
+	mtx   sync.RWMutex
+	stopc chan struct{}
+}
+
+// NewFlapDetector returns a new FlapDetector. If the configuration is nil,
+// no alert is considered flapping.
+func NewFlapDetector(ap provider.Alerts, conf *config.FlapDetection, h *FlapHistory, mk types.Marker) *FlapDetector {
+	return &FlapDetector{
+		alerts:  ap,
+		conf:    conf,
+		history: h,
+		marker:  mk,
+		stopc:   make(chan struct{}),
+	}
+}
+
+func (fd *FlapDetector) runGC() {
+	for {
+		select {
+		case <-time.After(15 * time.Minute):
+			fd.history.gc(time.Now().Add(-time.Duration(fd.conf.Window)))
+		case <-fd.stopc:
+			return
+		}
+	}
+}
+
+// Run the FlapDetector's background processing.
+func (fd *FlapDetector) Run() {
+	if fd.conf == nil {
+		return
+	}
+	go fd.runGC()
+
+	it := fd.alerts.Subscribe()
+	defer it.Close()
+
+	for {
+		select {
+		case <-fd.stopc:
+			return
+		case a := <-it.Next():
+			if err := it.Err(); err != nil {
+				log.Errorf("Error iterating alerts: %s", err)
+				continue
+			}
+			fd.history.observe(a, time.Now())
+		}
+	}
+}
+
+// Stop the FlapDetector's background processing.
+func (fd *FlapDetector) Stop() {
+	if fd == nil {
+		return
+	}
+	fd.mtx.Lock()
+	defer fd.mtx.Unlock()
+
+	select {
+	case <-fd.stopc:
+	default:
+		close(fd.stopc)
+	}
+}
+
+// Mutes returns true iff the alert with the given label set is flapping.
+func (fd *FlapDetector) Mutes(lset model.LabelSet) bool {
+	fp := lset.Fingerprint()
+
+	if fd.conf == nil {
+		fd.marker.SetFlapping(fp)
+		return false
+	}
+	now := time.Now()
+
+	n := fd.history.transitions(fp, now.Add(-time.Duration(fd.conf.Window)))
+	if n <= fd.conf.Threshold {
+		fd.marker.SetFlapping(fp)
+		return false
+	}
+
+	since := now
+	if f, ok := fd.marker.Flapping(fp); ok {
+		since = f.Since
+	}
+	fd.marker.SetFlapping(fp, &types.FlapState{Since: since, Transitions: n})
+
+	return true
+}
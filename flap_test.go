@@ -0,0 +1,112 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestFlapHistory(t *testing.T) {
+	var (
+		h    = NewFlapHistory()
+		now  = time.Now()
+		lset = model.LabelSet{"alertname": "a"}
+		fp   = lset.Fingerprint()
+	)
+	update := func(start, end time.Time, at time.Time) {
+		h.observe(&types.Alert{
+			Alert: model.Alert{Labels: lset, StartsAt: start, EndsAt: end},
+		}, at)
+	}
+
+	t0 := now.Add(-time.Hour)
+
+	update(t0, t0.Add(5*time.Minute), t0)
+	// Updates of a firing alert are no transitions.
+	update(t0, t0.Add(10*time.Minute), t0.Add(time.Minute))
+	// Resolved.
+	update(t0, t0.Add(2*time.Minute), t0.Add(2*time.Minute))
+	// Fires again.
+	update(t0.Add(3*time.Minute), t0.Add(20*time.Minute), t0.Add(3*time.Minute))
+	// Timed out without update and fired again.
+	update(t0.Add(30*time.Minute), t0.Add(40*time.Minute), t0.Add(30*time.Minute))
+
+	if n := h.transitions(fp, t0); n != 4 {
+		t.Fatalf("expected 4 transitions but got %d", n)
+	}
+	if n := h.transitions(fp, t0.Add(10*time.Minute)); n != 2 {
+		t.Fatalf("expected 2 transitions within the window but got %d", n)
+	}
+
+	h.gc(now)
+	if _, ok := h.alerts[fp]; !ok {
+		t.Fatalf("expected history of firing alert to be kept")
+	}
+	update(t0.Add(30*time.Minute), now.Add(-time.Minute), now)
+	h.gc(now.Add(time.Second))
+	if _, ok := h.alerts[fp]; ok {
+		t.Fatalf("expected history of resolved alert without transitions to be removed")
+	}
+}
+
+func TestFlapDetectorMutes(t *testing.T) {
+	var (
+		h      = NewFlapHistory()
+		marker = types.NewMarker()
+		now    = time.Now()
+		lset   = model.LabelSet{"alertname": "a"}
+		fp     = lset.Fingerprint()
+	)
+	fd := NewFlapDetector(nil, &config.FlapDetection{
+		Threshold: 2,
+		Window:    model.Duration(time.Hour),
+	}, h, marker)
+
+	h.observe(&types.Alert{Alert: model.Alert{Labels: lset, StartsAt: now}}, now)
+
+	for i := 1; i <= 3; i++ {
+		a := &types.Alert{Alert: model.Alert{Labels: lset, StartsAt: now}}
+		if i%2 == 1 {
+			a.EndsAt = now
+		}
+		h.observe(a, now.Add(time.Duration(i)*time.Second))
+
+		flapping := i > 2
+		if fd.Mutes(lset) != flapping {
+			t.Fatalf("%d. expected flapping %v but got %v", i, flapping, !flapping)
+		}
+		f, ok := marker.Flapping(fp)
+		if ok != flapping {
+			t.Fatalf("%d. expected marker flapping %v but got %v", i, flapping, ok)
+		}
+		if ok && f.Transitions != i {
+			t.Fatalf("%d. expected %d transitions but got %d", i, i, f.Transitions)
+		}
+	}
+
+	// Without configuration no alert is flapping.
+	fd = NewFlapDetector(nil, nil, h, marker)
+	if fd.Mutes(lset) {
+		t.Fatalf("expected disabled flap detection not to mute")
+	}
+	if _, ok := marker.Flapping(fp); ok {
+		t.Fatalf("expected flapping marker to be removed")
+	}
+}
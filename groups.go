@@ -0,0 +1,141 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// groupMap is a concurrent map of aggregation groups by route and
+// fingerprint.
+type groupMap struct {
+	mtx    sync.RWMutex
+	groups map[model.Fingerprint]map[*Route]*aggrGroup
+}
+
+func newGroupMap() *groupMap {
+	return &groupMap{
+		groups: map[model.Fingerprint]map[*Route]*aggrGroup{},
+	}
+}
+
+// get returns the group of the route with the given fingerprint.
+func (m *groupMap) get(route *Route, fp model.Fingerprint) (*aggrGroup, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	ag, ok := m.groups[fp][route]
+	return ag, ok
+}
+
+// set adds the group of the route, replacing an existing one.
+func (m *groupMap) set(route *Route, ag *aggrGroup) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.setLocked(route, ag.fingerprint(), ag)
+}
+
+func (m *groupMap) setLocked(route *Route, fp model.Fingerprint, ag *aggrGroup) {
+	groups, ok := m.groups[fp]
+	if !ok {
+		groups = map[*Route]*aggrGroup{}
+		m.groups[fp] = groups
+	}
+	groups[route] = ag
+}
+
+// insert adds the alert to the group of the route with the given
+// fingerprint and returns the group. If the group does not exist, it is
+// created by calling create.
+func (m *groupMap) insert(route *Route, fp model.Fingerprint, alert *types.Alert, create func() *aggrGroup) *aggrGroup {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	ag, ok := m.groups[fp][route]
+	if !ok {
+		ag = create()
+		m.setLocked(route, fp, ag)
+	}
+	// Inserting while holding the lock prevents the group from being
+	// removed as empty in between.
+	ag.insert(alert)
+
+	return ag
+}
+
+// byFingerprint returns the groups with the given fingerprint by route.
+func (m *groupMap) byFingerprint(fp model.Fingerprint) map[*Route]*aggrGroup {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	res := make(map[*Route]*aggrGroup, len(m.groups[fp]))
+	for r, ag := range m.groups[fp] {
+		res[r] = ag
+	}
+	return res
+}
+
+// byRoute returns all groups by route.
+func (m *groupMap) byRoute() map[*Route][]*aggrGroup {
+	res := map[*Route][]*aggrGroup{}
+	m.each(func(r *Route, ag *aggrGroup) {
+		res[r] = append(res[r], ag)
+	})
+	return res
+}
+
+// each calls f for all groups while holding the read lock. f must not
+// change the map.
+func (m *groupMap) each(f func(*Route, *aggrGroup)) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	for _, groups := range m.groups {
+		for r, ag := range groups {
+			f(r, ag)
+		}
+	}
+}
+
+// len returns the number of groups.
+func (m *groupMap) len() int {
+	n := 0
+	m.each(func(*Route, *aggrGroup) { n++ })
+	return n
+}
+
+// removeEmpty removes all groups without alerts and returns them.
+func (m *groupMap) removeEmpty() []*aggrGroup {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var res []*aggrGroup
+	for fp, groups := range m.groups {
+		for r, ag := range groups {
+			if ag.empty() {
+				res = append(res, ag)
+				delete(groups, r)
+			}
+		}
+		if len(groups) == 0 {
+			delete(m.groups, fp)
+		}
+	}
+	return res
+}
@@ -0,0 +1,90 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestGroupMap(t *testing.T) {
+	var (
+		r1 = &Route{RouteOpts: RouteOpts{Receiver: "r1"}}
+		r2 = &Route{RouteOpts: RouteOpts{Receiver: "r2"}}
+		m  = newGroupMap()
+		wg sync.WaitGroup
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Alerts of the same groups are inserted concurrently for two routes.
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				labels := model.LabelSet{"g": model.LabelValue(strconv.Itoa(i))}
+				alert := &types.Alert{
+					Alert: model.Alert{
+						Labels:   model.LabelSet{"g": labels["g"], "w": model.LabelValue(strconv.Itoa(w))},
+						StartsAt: time.Now(),
+					},
+				}
+				for _, r := range []*Route{r1, r2} {
+					r := r
+					m.insert(r, labels.Fingerprint(), alert, func() *aggrGroup {
+						return newAggrGroup(ctx, labels, r)
+					})
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if n := m.len(); n != 200 {
+		t.Fatalf("expected 200 groups but got %d", n)
+	}
+	for r, groups := range m.byRoute() {
+		if len(groups) != 100 {
+			t.Errorf("expected 100 groups of route %s but got %d", r.RouteOpts.Receiver, len(groups))
+		}
+		for _, ag := range groups {
+			if n := len(ag.alertSlice()); n != 4 {
+				t.Errorf("expected 4 alerts in group %v but got %d", ag.labels, n)
+			}
+		}
+	}
+
+	fp := model.LabelSet{"g": "7"}.Fingerprint()
+	if groups := m.byFingerprint(fp); len(groups) != 2 || groups[r1] == nil || groups[r2] == nil {
+		t.Fatalf("expected groups of both routes with fingerprint %v but got %v", fp, groups)
+	}
+
+	empty := newAggrGroup(ctx, model.LabelSet{"g": "empty"}, r1)
+	m.set(r1, empty)
+
+	if removed := m.removeEmpty(); len(removed) != 1 || removed[0] != empty {
+		t.Fatalf("expected empty group to be removed but got %v", removed)
+	}
+	if _, ok := m.get(r1, empty.fingerprint()); ok {
+		t.Fatalf("expected empty group to be gone")
+	}
+}
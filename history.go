@@ -0,0 +1,288 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// historyState is the last recorded state of an alert.
+type historyState struct {
+	resolved bool
+	startsAt time.Time
+	endsAt   time.Time
+}
+
+// A HistoryRecorder records alerts being created, firing, and resolving
+// in the alert history and removes entries older than the retention.
+type HistoryRecorder struct {
+	alerts    provider.Alerts
+	history   provider.AlertHistory
+	retention time.Duration
+
+	mtx    sync.Mutex
+	states map[model.Fingerprint]*historyState
+	stopc  chan struct{}
+}
+
+// NewHistoryRecorder returns a new HistoryRecorder.
+func NewHistoryRecorder(ap provider.Alerts, h provider.AlertHistory, retention time.Duration) *HistoryRecorder {
+	return &HistoryRecorder{
+		alerts:    ap,
+		history:   h,
+		retention: retention,
+		states:    map[model.Fingerprint]*historyState{},
+		stopc:     make(chan struct{}),
+	}
+}
+
+// Run the HistoryRecorder's background processing.
+func (r *HistoryRecorder) Run() {
+	it := r.alerts.Subscribe()
+	defer it.Close()
+
+	var (
+		sweep = time.NewTicker(time.Minute)
+		gc    = time.NewTicker(time.Hour)
+	)
+	defer sweep.Stop()
+	defer gc.Stop()
+
+	for {
+		select {
+		case <-r.stopc:
+			return
+		case a := <-it.Next():
+			if err := it.Err(); err != nil {
+				log.Errorf("Error iterating alerts: %s", err)
+				continue
+			}
+			r.observe(a, time.Now())
+		case <-sweep.C:
+			r.sweep(time.Now())
+		case <-gc.C:
+			if err := r.history.GC(time.Now().Add(-r.retention)); err != nil {
+				log.Errorf("Alert history GC failed: %s", err)
+			}
+		}
+	}
+}
+
+// Stop the HistoryRecorder's background processing.
+func (r *HistoryRecorder) Stop() {
+	close(r.stopc)
+}
+
+// state returns the last recorded state of the alert. For alerts not
+// seen since startup it is restored from the history.
+func (r *HistoryRecorder) state(fp model.Fingerprint) (*historyState, error) {
+	if s, ok := r.states[fp]; ok {
+		return s, nil
+	}
+	entries, err := r.history.Get(fp)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		switch e := entries[i]; e.State {
+		case types.AlertFiring:
+			return &historyState{startsAt: e.Time}, nil
+		case types.AlertResolved:
+			return &historyState{resolved: true, endsAt: e.Time}, nil
+		}
+	}
+	return nil, nil
+}
+
+// observe records the changes of state of an updated alert.
+func (r *HistoryRecorder) observe(a *types.Alert, now time.Time) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	fp := a.Fingerprint()
+
+	prev, err := r.state(fp)
+	if err != nil {
+		log.With("alert", fp).Errorf("Loading alert history failed: %s", err)
+		return
+	}
+	var (
+		resolved = a.ResolvedAt(now)
+		entries  []*types.AlertHistoryEntry
+	)
+	entry := func(s types.AlertHistoryState, t time.Time) {
+		entries = append(entries, &types.AlertHistoryEntry{Alert: fp, Time: t, State: s})
+	}
+
+	switch {
+	case prev == nil:
+		entries = append(entries, &types.AlertHistoryEntry{
+			Alert:  fp,
+			Time:   now,
+			State:  types.AlertCreated,
+			Labels: a.Labels,
+		})
+		entry(types.AlertFiring, a.StartsAt)
+		if resolved {
+			entry(types.AlertResolved, a.EndsAt)
+		}
+	case prev.resolved && !resolved:
+		entry(types.AlertFiring, a.StartsAt)
+	case !prev.resolved && resolved:
+		entry(types.AlertResolved, a.EndsAt)
+	case !prev.resolved && !prev.endsAt.IsZero() && prev.endsAt.Before(a.StartsAt):
+		// The alert timed out without an update and fired again.
+		entry(types.AlertResolved, prev.endsAt)
+		entry(types.AlertFiring, a.StartsAt)
+	}
+
+	if len(entries) > 0 {
+		if err := r.history.Add(entries...); err != nil {
+			log.With("alert", fp).Errorf("Recording alert history failed: %s", err)
+			return
+		}
+	}
+	r.states[fp] = &historyState{resolved: resolved, startsAt: a.StartsAt, endsAt: a.EndsAt}
+}
+
+// sweep records firing alerts that timed out without an update as
+// resolved and forgets resolved alerts.
+func (r *HistoryRecorder) sweep(now time.Time) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for fp, s := range r.states {
+		if s.resolved {
+			delete(r.states, fp)
+			continue
+		}
+		if s.endsAt.IsZero() || s.endsAt.After(now) {
+			continue
+		}
+		err := r.history.Add(&types.AlertHistoryEntry{
+			Alert: fp,
+			Time:  s.endsAt,
+			State: types.AlertResolved,
+		})
+		if err != nil {
+			log.With("alert", fp).Errorf("Recording alert history failed: %s", err)
+			continue
+		}
+		delete(r.states, fp)
+	}
+}
+
+// historyMarker is a Marker recording alerts being silenced and inhibited
+// in the alert history.
+type historyMarker struct {
+	types.Marker
+	history provider.AlertHistory
+
+	// Serializes comparing and setting the state of alerts.
+	mtx sync.Mutex
+}
+
+// NewHistoryMarker returns a Marker wrapping m that records changes of
+// the silenced and inhibited state of alerts in the history.
+func NewHistoryMarker(m types.Marker, h provider.AlertHistory) types.Marker {
+	return &historyMarker{Marker: m, history: h}
+}
+
+// SetSilenced implements the types.Marker interface.
+func (m *historyMarker) SetSilenced(alert model.Fingerprint, sil ...uint64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	prev, wasSilenced := m.Marker.Silenced(alert)
+	m.Marker.SetSilenced(alert, sil...)
+
+	e := &types.AlertHistoryEntry{Alert: alert, Time: time.Now()}
+	switch {
+	case len(sil) > 0 && (!wasSilenced || prev != sil[0]):
+		e.State = types.AlertSilenced
+		e.Silence = sil[0]
+	case len(sil) == 0 && wasSilenced:
+		e.State = types.AlertUnsilenced
+	default:
+		return
+	}
+	m.record(e)
+}
+
+// SetInhibited implements the types.Marker interface.
+func (m *historyMarker) SetInhibited(alert model.Fingerprint, src ...*types.InhibitSource) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	wasInhibited := m.Marker.Inhibited(alert)
+	m.Marker.SetInhibited(alert, src...)
+
+	e := &types.AlertHistoryEntry{Alert: alert, Time: time.Now()}
+	switch {
+	case len(src) > 0 && !wasInhibited:
+		e.State = types.AlertInhibited
+		e.InhibitedBy = src[0]
+	case len(src) == 0 && wasInhibited:
+		e.State = types.AlertUninhibited
+	default:
+		return
+	}
+	m.record(e)
+}
+
+func (m *historyMarker) record(e *types.AlertHistoryEntry) {
+	if err := m.history.Add(e); err != nil {
+		log.With("alert", e.Alert).Errorf("Recording alert history failed: %s", err)
+	}
+}
+
+// recordNotifications returns a notifier recording the alerts successfully
+// notified about through n in the alert history. n notifies a single
+// integration of a receiver.
+func recordNotifications(h provider.AlertHistory, n notify.Notifier) notify.Notifier {
+	return notify.NotifierFunc(func(ctx context.Context, alerts ...*types.Alert) error {
+		if err := n.Notify(ctx, alerts...); err != nil {
+			return err
+		}
+		rcv, _ := notify.Receiver(ctx)
+		rcv, _ = splitReceiver(rcv)
+
+		now, ok := notify.Now(ctx)
+		if !ok {
+			now = time.Now()
+		}
+		entries := make([]*types.AlertHistoryEntry, 0, len(alerts))
+		for _, a := range alerts {
+			entries = append(entries, &types.AlertHistoryEntry{
+				Alert:    a.Fingerprint(),
+				Time:     now,
+				State:    types.AlertNotified,
+				Receiver: rcv,
+			})
+		}
+		if err := h.Add(entries...); err != nil {
+			log.With("receiver", rcv).Errorf("Recording notifications in alert history failed: %s", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,149 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider/boltmem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func historyStates(t *testing.T, h *boltmem.AlertHistory, fp model.Fingerprint) []types.AlertHistoryState {
+	entries, err := h.Get(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var states []types.AlertHistoryState
+	for _, e := range entries {
+		states = append(states, e.State)
+	}
+	return states
+}
+
+func expectStates(t *testing.T, got []types.AlertHistoryState, exp ...types.AlertHistoryState) {
+	if len(got) != len(exp) {
+		t.Fatalf("expected states %v but got %v", exp, got)
+	}
+	for i := range exp {
+		if got[i] != exp[i] {
+			t.Fatalf("expected states %v but got %v", exp, got)
+		}
+	}
+}
+
+func TestHistoryRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h, err := boltmem.NewAlertHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	var (
+		r    = NewHistoryRecorder(nil, h, time.Hour)
+		now  = time.Now()
+		lset = model.LabelSet{"alertname": "a"}
+		fp   = lset.Fingerprint()
+	)
+	update := func(start, end time.Time, at time.Time) {
+		r.observe(&types.Alert{
+			Alert: model.Alert{Labels: lset, StartsAt: start, EndsAt: end},
+		}, at)
+	}
+
+	t0 := now.Add(-time.Hour)
+
+	update(t0, t0.Add(5*time.Minute), t0)
+	// Updates of a firing alert are not recorded.
+	update(t0, t0.Add(10*time.Minute), t0.Add(time.Minute))
+	// Resolved.
+	update(t0, t0.Add(2*time.Minute), t0.Add(2*time.Minute))
+	// Fires again.
+	update(t0.Add(3*time.Minute), t0.Add(20*time.Minute), t0.Add(3*time.Minute))
+	// Timed out without update and fired again.
+	update(t0.Add(30*time.Minute), t0.Add(40*time.Minute), t0.Add(30*time.Minute))
+
+	expectStates(t, historyStates(t, h, fp),
+		types.AlertCreated,
+		types.AlertFiring,
+		types.AlertResolved,
+		types.AlertFiring,
+		types.AlertResolved,
+		types.AlertFiring,
+	)
+
+	// Firing alerts that timed out are recorded as resolved by the sweep.
+	r.sweep(now)
+	states := historyStates(t, h, fp)
+	if states[len(states)-1] != types.AlertResolved {
+		t.Fatalf("expected timed out alert to be resolved but got %v", states)
+	}
+
+	// The state is restored from the history after a restart.
+	r = NewHistoryRecorder(nil, h, time.Hour)
+	update(t0.Add(50*time.Minute), now.Add(time.Hour), now)
+
+	states = historyStates(t, h, fp)
+	expectStates(t, states[len(states)-2:], types.AlertResolved, types.AlertFiring)
+}
+
+func TestHistoryMarker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h, err := boltmem.NewAlertHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	var (
+		m  = NewHistoryMarker(types.NewMarker(), h)
+		fp = model.Fingerprint(1)
+	)
+	m.SetSilenced(fp, 1)
+	// Unchanged states are not recorded.
+	m.SetSilenced(fp, 1)
+	m.SetSilenced(fp, 2)
+	m.SetSilenced(fp)
+	m.SetSilenced(fp)
+	m.SetInhibited(fp, &types.InhibitSource{Fingerprint: 2})
+	m.SetInhibited(fp, &types.InhibitSource{Fingerprint: 2})
+	m.SetInhibited(fp)
+
+	expectStates(t, historyStates(t, h, fp),
+		types.AlertSilenced,
+		types.AlertSilenced,
+		types.AlertUnsilenced,
+		types.AlertInhibited,
+		types.AlertUninhibited,
+	)
+	if sil, ok := m.Silenced(fp); ok {
+		t.Fatalf("expected alert not to be silenced but got silence %d", sil)
+	}
+}
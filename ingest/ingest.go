@@ -0,0 +1,117 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ingest decodes snappy-compressed protobuf batches of alerts as
+// defined in ingest.proto. It is a compact alternative to the JSON
+// representation for high-volume senders.
+package ingest
+
+import (
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// MaxBatchSize is the maximum size of a compressed or decompressed batch.
+const MaxBatchSize = 32 << 20
+
+// LabelPair is a single label or annotation.
+type LabelPair struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *LabelPair) Reset()         { *m = LabelPair{} }
+func (m *LabelPair) String() string { return proto.CompactTextString(m) }
+func (*LabelPair) ProtoMessage()    {}
+
+// Alert is the protobuf representation of an alert. Timestamps are in
+// milliseconds since the epoch and zero if unset.
+type Alert struct {
+	Labels       []*LabelPair `protobuf:"bytes,1,rep,name=labels" json:"labels,omitempty"`
+	Annotations  []*LabelPair `protobuf:"bytes,2,rep,name=annotations" json:"annotations,omitempty"`
+	StartsAtMs   int64        `protobuf:"varint,3,opt,name=starts_at_ms,proto3" json:"starts_at_ms,omitempty"`
+	EndsAtMs     int64        `protobuf:"varint,4,opt,name=ends_at_ms,proto3" json:"ends_at_ms,omitempty"`
+	GeneratorURL string       `protobuf:"bytes,5,opt,name=generator_url,proto3" json:"generator_url,omitempty"`
+}
+
+func (m *Alert) Reset()         { *m = Alert{} }
+func (m *Alert) String() string { return proto.CompactTextString(m) }
+func (*Alert) ProtoMessage()    {}
+
+// AlertBatch is a batch of alerts sent in a single request.
+type AlertBatch struct {
+	Alerts []*Alert `protobuf:"bytes,1,rep,name=alerts" json:"alerts,omitempty"`
+}
+
+func (m *AlertBatch) Reset()         { *m = AlertBatch{} }
+func (m *AlertBatch) String() string { return proto.CompactTextString(m) }
+func (*AlertBatch) ProtoMessage()    {}
+
+// Decode reads a snappy-compressed AlertBatch from r and returns its alerts.
+func Decode(r io.Reader) ([]*types.Alert, error) {
+	compressed, err := ioutil.ReadAll(io.LimitReader(r, MaxBatchSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(compressed) > MaxBatchSize {
+		return nil, errTooLarge
+	}
+	b, err := snappyDecode(compressed)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(b)
+}
+
+// Unmarshal returns the alerts of the uncompressed AlertBatch b.
+func Unmarshal(b []byte) ([]*types.Alert, error) {
+	var batch AlertBatch
+	if err := proto.Unmarshal(b, &batch); err != nil {
+		return nil, err
+	}
+
+	res := make([]*types.Alert, 0, len(batch.Alerts))
+	for _, a := range batch.Alerts {
+		res = append(res, &types.Alert{
+			Alert: model.Alert{
+				Labels:       labelSet(a.Labels),
+				Annotations:  labelSet(a.Annotations),
+				StartsAt:     fromMillis(a.StartsAtMs),
+				EndsAt:       fromMillis(a.EndsAtMs),
+				GeneratorURL: a.GeneratorURL,
+			},
+		})
+	}
+	return res, nil
+}
+
+func labelSet(lps []*LabelPair) model.LabelSet {
+	lset := make(model.LabelSet, len(lps))
+	for _, lp := range lps {
+		lset[model.LabelName(lp.Name)] = model.LabelValue(lp.Value)
+	}
+	return lset
+}
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))
+}
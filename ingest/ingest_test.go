@@ -0,0 +1,165 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/common/model"
+)
+
+// snappyLiteral encodes b as a snappy block consisting of a single literal.
+func snappyLiteral(b []byte) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	buf = buf[:binary.PutUvarint(buf, uint64(len(b)))]
+
+	switch n := len(b) - 1; {
+	case n < 60:
+		buf = append(buf, byte(n)<<2)
+	case n < 1<<8:
+		buf = append(buf, 60<<2, byte(n))
+	default:
+		buf = append(buf, 61<<2, byte(n), byte(n>>8))
+	}
+	return append(buf, b...)
+}
+
+func TestSnappyDecode(t *testing.T) {
+	cases := []struct {
+		in  []byte
+		out []byte
+		err bool
+	}{
+		{
+			in:  []byte{0x00},
+			out: []byte{},
+		},
+		{
+			in:  snappyLiteral([]byte("abc")),
+			out: []byte("abc"),
+		},
+		{
+			in:  snappyLiteral(bytes.Repeat([]byte("x"), 300)),
+			out: bytes.Repeat([]byte("x"), 300),
+		},
+		{
+			// Literal "ab" followed by an overlapping 1-byte offset copy
+			// of length 6 and a 2-byte offset copy of length 2.
+			in:  []byte{10, 0x04, 'a', 'b', 0x09, 0x02, 0x06, 0x02, 0x00},
+			out: []byte("ababababab"),
+		},
+		{
+			// Copy before any output.
+			in:  []byte{4, 0x01, 0x01},
+			err: true,
+		},
+		{
+			// Truncated literal.
+			in:  []byte{3, 0x08, 'a'},
+			err: true,
+		},
+		{
+			// Decoded length mismatch.
+			in:  []byte{4, 0x08, 'a', 'b', 'c'},
+			err: true,
+		},
+		{
+			in:  []byte{},
+			err: true,
+		},
+	}
+
+	for i, c := range cases {
+		out, err := snappyDecode(c.in)
+		if c.err {
+			if err == nil {
+				t.Errorf("%d: expected error but got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		if !bytes.Equal(out, c.out) {
+			t.Errorf("%d: expected %q but got %q", i, c.out, out)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	batch := &AlertBatch{
+		Alerts: []*Alert{
+			{
+				Labels: []*LabelPair{
+					{Name: "alertname", Value: "InstanceDown"},
+					{Name: "instance", Value: "host:9100"},
+				},
+				Annotations: []*LabelPair{
+					{Name: "summary", Value: "instance down"},
+				},
+				StartsAtMs:   1451606400123,
+				GeneratorURL: "http://prometheus/graph",
+			},
+			{
+				Labels: []*LabelPair{
+					{Name: "alertname", Value: "HighLatency"},
+				},
+				StartsAtMs: 1451606400000,
+				EndsAtMs:   1451606460000,
+			},
+		},
+	}
+	b, err := proto.Marshal(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, err := Decode(bytes.NewReader(snappyLiteral(b)))
+	if err != nil {
+		t.Fatalf("Decoding failed: %s", err)
+	}
+
+	expected := []model.Alert{
+		{
+			Labels:       model.LabelSet{"alertname": "InstanceDown", "instance": "host:9100"},
+			Annotations:  model.LabelSet{"summary": "instance down"},
+			StartsAt:     time.Unix(1451606400, 123*int64(time.Millisecond)),
+			GeneratorURL: "http://prometheus/graph",
+		},
+		{
+			Labels:      model.LabelSet{"alertname": "HighLatency"},
+			Annotations: model.LabelSet{},
+			StartsAt:    time.Unix(1451606400, 0),
+			EndsAt:      time.Unix(1451606460, 0),
+		},
+	}
+	if len(alerts) != len(expected) {
+		t.Fatalf("expected %d alerts but got %d", len(expected), len(alerts))
+	}
+	for i, a := range alerts {
+		if !reflect.DeepEqual(a.Alert, expected[i]) {
+			t.Errorf("%d: expected alert\n%v\nbut got\n%v", i, expected[i], a.Alert)
+		}
+	}
+
+	if _, err := Decode(bytes.NewReader(b)); err == nil {
+		t.Fatalf("expected error for uncompressed input")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	errCorrupt  = errors.New("snappy: corrupt input")
+	errTooLarge = errors.New("batch too large")
+)
+
+// Tags of the elements in the snappy block format.
+const (
+	tagLiteral = 0x00
+	tagCopy1   = 0x01
+	tagCopy2   = 0x02
+	tagCopy4   = 0x03
+)
+
+// SnappyDecode decodes a block in the snappy block format of at most
+// MaxBatchSize bytes.
+func SnappyDecode(src []byte) ([]byte, error) {
+	return snappyDecode(src)
+}
+
+// snappyDecode decodes a block in the snappy block format as used by
+// Prometheus remote write. The framing format is not supported.
+func snappyDecode(src []byte) ([]byte, error) {
+	n, k := binary.Uvarint(src)
+	if k <= 0 {
+		return nil, errCorrupt
+	}
+	if n > MaxBatchSize {
+		return nil, errTooLarge
+	}
+	src = src[k:]
+	dst := make([]byte, 0, n)
+
+	for len(src) > 0 {
+		var length, offset int
+
+		switch src[0] & 0x03 {
+		case tagLiteral:
+			x := int(src[0] >> 2)
+			src = src[1:]
+			// Values from 60 on denote that the length is stored in the
+			// following 1-4 bytes.
+			if x >= 60 {
+				b := x - 59
+				if len(src) < b {
+					return nil, errCorrupt
+				}
+				x = 0
+				for i := b - 1; i >= 0; i-- {
+					x = x<<8 | int(src[i])
+				}
+				src = src[b:]
+			}
+			length = x + 1
+			if length <= 0 || length > len(src) || uint64(len(dst)+length) > n {
+				return nil, errCorrupt
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+
+		case tagCopy1:
+			if len(src) < 2 {
+				return nil, errCorrupt
+			}
+			length = 4 + int(src[0]>>2)&0x7
+			offset = int(src[0]&0xe0)<<3 | int(src[1])
+			src = src[2:]
+
+		case tagCopy2:
+			if len(src) < 3 {
+				return nil, errCorrupt
+			}
+			length = 1 + int(src[0]>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:3]))
+			src = src[3:]
+
+		case tagCopy4:
+			if len(src) < 5 {
+				return nil, errCorrupt
+			}
+			length = 1 + int(src[0]>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:5]))
+			src = src[5:]
+		}
+
+		if offset <= 0 || offset > len(dst) || uint64(len(dst)+length) > n {
+			return nil, errCorrupt
+		}
+		// Copies may overlap with the bytes they produce, so they have to
+		// be done byte by byte.
+		for pos := len(dst) - offset; length > 0; length-- {
+			dst = append(dst, dst[pos])
+			pos++
+		}
+	}
+
+	if uint64(len(dst)) != n {
+		return nil, errCorrupt
+	}
+	return dst, nil
+}
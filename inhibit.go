@@ -14,6 +14,7 @@
 package main
 
 import (
+	"sort"
 	"sync"
 	"time"
 
@@ -32,6 +33,18 @@ type Inhibitor struct {
 	rules  []*InhibitRule
 	marker types.Marker
 
+	// Dependencies between the services identified by the service label
+	// if set.
+	deps         provider.Dependencies
+	serviceLabel model.LabelName
+
+	dmtx sync.RWMutex
+	// Cache of alerts with the service label.
+	dcache map[model.Fingerprint]*types.Alert
+	// Dependencies by the service depending on them as last loaded from
+	// deps.
+	dbyService map[string][]*types.Dependency
+
 	mtx   sync.RWMutex
 	stopc chan struct{}
 }
@@ -41,6 +54,8 @@ func NewInhibitor(ap provider.Alerts, rs []*config.InhibitRule, mk types.Marker)
 	ih := &Inhibitor{
 		alerts: ap,
 		marker: mk,
+		dcache: map[model.Fingerprint]*types.Alert{},
+		stopc:  make(chan struct{}),
 	}
 	for _, cr := range rs {
 		r := NewInhibitRule(cr)
@@ -49,6 +64,41 @@ func NewInhibitor(ap provider.Alerts, rs []*config.InhibitRule, mk types.Marker)
 	return ih
 }
 
+// SetDependencies sets the dependencies between services, which are
+// identified by the value of the given label. Alerts of a service are
+// inhibited while a service it depends on has firing alerts. It must be
+// called before Run.
+func (ih *Inhibitor) SetDependencies(deps provider.Dependencies, serviceLabel model.LabelName) {
+	ih.deps = deps
+	ih.serviceLabel = serviceLabel
+
+	if err := ih.ReloadDependencies(); err != nil {
+		log.Errorf("Loading dependencies failed: %s", err)
+	}
+}
+
+// ReloadDependencies loads the dependencies anew. It must be called after
+// they changed. If they cannot be loaded, the previous ones are kept.
+func (ih *Inhibitor) ReloadDependencies() error {
+	if ih.deps == nil {
+		return nil
+	}
+	deps, err := ih.deps.All()
+	if err != nil {
+		return err
+	}
+	byService := map[string][]*types.Dependency{}
+	for _, d := range deps {
+		byService[d.Service] = append(byService[d.Service], d)
+	}
+
+	ih.dmtx.Lock()
+	defer ih.dmtx.Unlock()
+
+	ih.dbyService = byService
+	return nil
+}
+
 func (ih *Inhibitor) runGC() {
 	for {
 		select {
@@ -56,6 +106,7 @@ func (ih *Inhibitor) runGC() {
 			for _, r := range ih.rules {
 				r.gc()
 			}
+			ih.gcDependencies()
 		case <-ih.stopc:
 			return
 		}
@@ -64,10 +115,6 @@ func (ih *Inhibitor) runGC() {
 
 // Run the Inihibitor's background processing.
 func (ih *Inhibitor) Run() {
-	ih.mtx.Lock()
-	ih.stopc = make(chan struct{})
-	ih.mtx.Unlock()
-
 	go ih.runGC()
 
 	it := ih.alerts.Subscribe()
@@ -93,6 +140,11 @@ func (ih *Inhibitor) Run() {
 					r.set(a)
 				}
 			}
+			if _, ok := a.Labels[ih.serviceLabel]; ok && ih.deps != nil {
+				ih.dmtx.Lock()
+				ih.dcache[a.Fingerprint()] = a
+				ih.dmtx.Unlock()
+			}
 		}
 	}
 }
@@ -105,9 +157,10 @@ func (ih *Inhibitor) Stop() {
 	ih.mtx.Lock()
 	defer ih.mtx.Unlock()
 
-	if ih.stopc != nil {
+	select {
+	case <-ih.stopc:
+	default:
 		close(ih.stopc)
-		ih.stopc = nil
 	}
 }
 
@@ -116,14 +169,114 @@ func (ih *Inhibitor) Mutes(lset model.LabelSet) bool {
 	fp := lset.Fingerprint()
 
 	for _, r := range ih.rules {
-		if r.TargetMatchers.Match(lset) && r.hasEqual(lset) {
-			ih.marker.SetInhibited(fp, true)
+		if !r.TargetMatchers.Match(lset) {
+			continue
+		}
+		if src, ok := r.hasEqual(lset); ok {
+			ih.marker.SetInhibited(fp, &types.InhibitSource{
+				Fingerprint: src.Fingerprint(),
+				Labels:      src.Labels,
+			})
 			return true
 		}
 	}
-	ih.marker.SetInhibited(fp, false)
+	if src, dep, ok := ih.dependencySource(lset); ok {
+		ih.marker.SetInhibited(fp, &types.InhibitSource{
+			Fingerprint: src.Fingerprint(),
+			Labels:      src.Labels,
+			Dependency:  dep,
+		})
+		return true
+	}
+	ih.marker.SetInhibited(fp)
 	return false
+}
 
+// dependencySource returns a firing alert of a service the service of the
+// label set depends on along with the dependency. Dependencies are not
+// followed transitively.
+func (ih *Inhibitor) dependencySource(lset model.LabelSet) (*types.Alert, *types.Dependency, bool) {
+	if ih.deps == nil {
+		return nil, nil, false
+	}
+	svc, ok := lset[ih.serviceLabel]
+	if !ok {
+		return nil, nil, false
+	}
+
+	ih.dmtx.RLock()
+	defer ih.dmtx.RUnlock()
+
+	for _, d := range ih.dbyService[string(svc)] {
+	Outer:
+		for _, a := range ih.dcache {
+			// The cache might be stale and contain resolved alerts.
+			if a.Resolved() || string(a.Labels[ih.serviceLabel]) != d.DependsOn {
+				continue
+			}
+			for _, ln := range d.Equal {
+				if a.Labels[ln] != lset[ln] {
+					continue Outer
+				}
+			}
+			return a, d, true
+		}
+	}
+	return nil, nil, false
+}
+
+// gcDependencies clears out resolved alerts from the cache of alerts with
+// the service label.
+func (ih *Inhibitor) gcDependencies() {
+	ih.dmtx.Lock()
+	defer ih.dmtx.Unlock()
+
+	for fp, a := range ih.dcache {
+		if a.Resolved() {
+			delete(ih.dcache, fp)
+		}
+	}
+}
+
+// InhibitRuleMatch is an inhibition rule muting a label set along with the
+// firing source alerts causing it.
+type InhibitRuleMatch struct {
+	// The position of the rule in the configuration.
+	Rule           int              `json:"rule"`
+	SourceMatchers types.Matchers   `json:"sourceMatchers"`
+	TargetMatchers types.Matchers   `json:"targetMatchers"`
+	Equal          model.LabelNames `json:"equal"`
+	Sources        []*types.Alert   `json:"sources"`
+}
+
+// Test returns the rules that mute the given label set with all source
+// alerts inhibiting it. Unlike Mutes, it does not mark the label set.
+func (ih *Inhibitor) Test(lset model.LabelSet) []*InhibitRuleMatch {
+	var res []*InhibitRuleMatch
+
+	for i, r := range ih.rules {
+		if !r.TargetMatchers.Match(lset) {
+			continue
+		}
+		srcs := r.equalSources(lset)
+		if len(srcs) == 0 {
+			continue
+		}
+		equal := make(model.LabelNames, 0, len(r.Equal))
+		for ln := range r.Equal {
+			equal = append(equal, ln)
+		}
+		sort.Sort(equal)
+
+		res = append(res, &InhibitRuleMatch{
+			Rule:           i,
+			SourceMatchers: r.SourceMatchers,
+			TargetMatchers: r.TargetMatchers,
+			Equal:          equal,
+			Sources:        srcs,
+		})
+	}
+	return res
 }
 
 // An InhibitRule specifies that a class of (source) alerts should inhibit
@@ -190,8 +343,8 @@ func (r *InhibitRule) set(a *types.Alert) {
 }
 
 // hasEqual checks whether the source cache contains alerts matching
-// the equal labels for the given label set.
-func (r *InhibitRule) hasEqual(lset model.LabelSet) bool {
+// the equal labels for the given label set and returns the first one found.
+func (r *InhibitRule) hasEqual(lset model.LabelSet) (*types.Alert, bool) {
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
 
@@ -206,11 +359,41 @@ Outer:
 				continue Outer
 			}
 		}
-		return true
+		return a, true
 	}
-	return false
+	return nil, false
+}
+
+// equalSources returns all firing alerts in the source cache matching the
+// equal labels for the given label set, ordered by fingerprint.
+func (r *InhibitRule) equalSources(lset model.LabelSet) []*types.Alert {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	var res []*types.Alert
+Outer:
+	for _, a := range r.scache {
+		if a.Resolved() {
+			continue
+		}
+		for n := range r.Equal {
+			if a.Labels[n] != lset[n] {
+				continue Outer
+			}
+		}
+		res = append(res, a)
+	}
+	sort.Sort(alertsByFingerprint(res))
+	return res
 }
 
+// alertsByFingerprint sorts alerts by their fingerprint.
+type alertsByFingerprint []*types.Alert
+
+func (as alertsByFingerprint) Len() int           { return len(as) }
+func (as alertsByFingerprint) Swap(i, j int)      { as[i], as[j] = as[j], as[i] }
+func (as alertsByFingerprint) Less(i, j int) bool { return as[i].Fingerprint() < as[j].Fingerprint() }
+
 // gc clears out resolved alerts from the source cache.
 func (r *InhibitRule) gc() {
 	r.mtx.Lock()
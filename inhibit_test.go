@@ -14,11 +14,14 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/kylelemons/godebug/pretty"
+	"github.com/prometheus/alertmanager/provider/boltmem"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/common/model"
 )
@@ -123,7 +126,7 @@ func TestInhibitRuleHasEqual(t *testing.T) {
 			r.scache[k] = v
 		}
 
-		if have := r.hasEqual(c.input); have != c.result {
+		if _, have := r.hasEqual(c.input); have != c.result {
 			t.Errorf("Unexpected result %q, expected %q", have, c.result)
 		}
 		if !reflect.DeepEqual(r.scache, c.initial) {
@@ -166,3 +169,201 @@ func TestInhibitRuleGC(t *testing.T) {
 		t.Errorf(pretty.Compare(r.scache, after))
 	}
 }
+
+func TestInhibitorMutesSource(t *testing.T) {
+	now := time.Now()
+
+	src := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "NodeDown", "node": "n1"},
+			StartsAt: now.Add(-time.Minute),
+		},
+	}
+	r := &InhibitRule{
+		TargetMatchers: types.Matchers{types.NewMatcher("alertname", "InstanceDown")},
+		Equal:          map[model.LabelName]struct{}{"node": struct{}{}},
+		scache:         map[model.Fingerprint]*types.Alert{src.Fingerprint(): src},
+	}
+	marker := types.NewMarker()
+	ih := &Inhibitor{rules: []*InhibitRule{r}, marker: marker}
+
+	target := model.LabelSet{"alertname": "InstanceDown", "node": "n1"}
+
+	if !ih.Mutes(target) {
+		t.Fatalf("Expected target to be muted")
+	}
+	expected := &types.InhibitSource{
+		Fingerprint: src.Fingerprint(),
+		Labels:      src.Labels,
+	}
+	if have, ok := marker.InhibitedBy(target.Fingerprint()); !ok || !reflect.DeepEqual(have, expected) {
+		t.Fatalf("Unexpected inhibition source %v, expected %v", have, expected)
+	}
+
+	target["node"] = "n2"
+
+	if ih.Mutes(target) {
+		t.Fatalf("Expected target not to be muted")
+	}
+	if _, ok := marker.InhibitedBy(target.Fingerprint()); ok {
+		t.Fatalf("Expected no inhibition source")
+	}
+}
+
+func TestInhibitorDependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inhibit_dependencies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	deps, err := boltmem.NewDependencies(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deps.Close()
+
+	dep := &types.Dependency{Service: "app", DependsOn: "db", Equal: model.LabelNames{"cluster"}, CreatedBy: "user"}
+	if _, err := deps.Set(dep); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := deps.Set(&types.Dependency{Service: "db", DependsOn: "storage", CreatedBy: "user"}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	newAlert := func(lset model.LabelSet, end time.Duration) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   lset,
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(end),
+			},
+		}
+	}
+	var (
+		db      = newAlert(model.LabelSet{"alertname": "DBDown", "service": "db", "cluster": "a"}, time.Hour)
+		storage = newAlert(model.LabelSet{"alertname": "DiskFull", "service": "storage", "cluster": "b"}, -time.Minute)
+	)
+
+	marker := types.NewMarker()
+	ih := &Inhibitor{
+		marker: marker,
+		dcache: map[model.Fingerprint]*types.Alert{
+			db.Fingerprint():      db,
+			storage.Fingerprint(): storage,
+		},
+	}
+	ih.SetDependencies(deps, "service")
+
+	app := model.LabelSet{"alertname": "HighLatency", "service": "app", "cluster": "a"}
+	if !ih.Mutes(app) {
+		t.Fatalf("Expected alert of dependent service to be muted")
+	}
+	expected := &types.InhibitSource{
+		Fingerprint: db.Fingerprint(),
+		Labels:      db.Labels,
+		Dependency:  dep,
+	}
+	if have, ok := marker.InhibitedBy(app.Fingerprint()); !ok || !reflect.DeepEqual(have, expected) {
+		t.Fatalf("Unexpected inhibition source %v, expected %v", have, expected)
+	}
+
+	for _, lset := range []model.LabelSet{
+		// The equal labels differ.
+		{"alertname": "HighLatency", "service": "app", "cluster": "b"},
+		// The alert of the service depended on is resolved.
+		{"alertname": "DBDown", "service": "db", "cluster": "a"},
+		// The service depended on is no dependent service.
+		{"alertname": "DiskFull", "service": "storage", "cluster": "b"},
+	} {
+		if ih.Mutes(lset) {
+			t.Errorf("Expected %v not to be muted", lset)
+		}
+	}
+
+	if err := deps.Del(dep.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Dependencies are cached until they are reloaded.
+	if !ih.Mutes(app) {
+		t.Fatalf("Expected alert to be muted until dependencies are reloaded")
+	}
+	if err := ih.ReloadDependencies(); err != nil {
+		t.Fatal(err)
+	}
+	if ih.Mutes(app) {
+		t.Fatalf("Expected alert not to be muted after dependency was deleted")
+	}
+	if _, ok := marker.InhibitedBy(app.Fingerprint()); ok {
+		t.Fatalf("Expected no inhibition source")
+	}
+}
+
+func TestInhibitorTest(t *testing.T) {
+	now := time.Now()
+
+	newAlert := func(lset model.LabelSet, end time.Duration) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   lset,
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(end),
+			},
+		}
+	}
+	var (
+		n1      = newAlert(model.LabelSet{"alertname": "NodeDown", "node": "n1", "dc": "a"}, time.Hour)
+		n1Old   = newAlert(model.LabelSet{"alertname": "NodeDown", "node": "n1", "dc": "b"}, -time.Minute)
+		n2      = newAlert(model.LabelSet{"alertname": "NodeDown", "node": "n2", "dc": "a"}, time.Hour)
+		dcAlert = newAlert(model.LabelSet{"alertname": "DatacenterDown", "dc": "a"}, time.Hour)
+	)
+	cache := func(as ...*types.Alert) map[model.Fingerprint]*types.Alert {
+		res := map[model.Fingerprint]*types.Alert{}
+		for _, a := range as {
+			res[a.Fingerprint()] = a
+		}
+		return res
+	}
+
+	ih := &Inhibitor{
+		rules: []*InhibitRule{
+			{
+				TargetMatchers: types.Matchers{types.NewMatcher("alertname", "InstanceDown")},
+				Equal:          map[model.LabelName]struct{}{"node": struct{}{}},
+				scache:         cache(n1, n1Old, n2),
+			},
+			{
+				TargetMatchers: types.Matchers{types.NewMatcher("alertname", "NodeDown")},
+				Equal:          map[model.LabelName]struct{}{"dc": struct{}{}},
+				scache:         cache(dcAlert),
+			},
+			{
+				TargetMatchers: types.Matchers{types.NewMatcher("alertname", "InstanceDown")},
+				Equal:          map[model.LabelName]struct{}{"dc": struct{}{}, "node": struct{}{}},
+				scache:         cache(dcAlert),
+			},
+		},
+		marker: types.NewMarker(),
+	}
+
+	target := model.LabelSet{"alertname": "InstanceDown", "node": "n1", "dc": "a"}
+	res := ih.Test(target)
+
+	expected := []*InhibitRuleMatch{{
+		Rule:           0,
+		TargetMatchers: ih.rules[0].TargetMatchers,
+		Equal:          model.LabelNames{"node"},
+		Sources:        []*types.Alert{n1},
+	}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("Unexpected rules muting the label set:\n%s", pretty.Compare(res, expected))
+	}
+	if _, ok := ih.marker.InhibitedBy(target.Fingerprint()); ok {
+		t.Fatalf("Expected tested label set not to be marked")
+	}
+
+	if res := ih.Test(model.LabelSet{"alertname": "InstanceDown", "node": "n3"}); len(res) != 0 {
+		t.Fatalf("Expected no rules muting the label set but got %d", len(res))
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxResponseSize bounds the size of responses read from brokers.
+const maxResponseSize = 256 << 20
+
+// broker is a connection to a Kafka broker. Requests are sent one at a
+// time.
+type broker struct {
+	addr     string
+	clientID string
+	timeout  time.Duration
+
+	mtx    sync.Mutex
+	conn   net.Conn
+	corrID int32
+}
+
+func dialBroker(addr, clientID string, timeout time.Duration) (*broker, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &broker{
+		addr:     addr,
+		clientID: clientID,
+		timeout:  timeout,
+		conn:     conn,
+	}, nil
+}
+
+func (b *broker) Close() error {
+	return b.conn.Close()
+}
+
+// request sends the request body for the API and returns the response
+// body. The request may take up to the extra duration in addition to the
+// broker's timeout, e.g. for the maximum wait time of fetches.
+func (b *broker) request(api int16, body []byte, extra time.Duration) (*decoder, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.corrID++
+
+	var e encoder
+	e.int32(0) // Size, set below.
+	e.int16(api)
+	e.int16(apiVersions[api])
+	e.int32(b.corrID)
+	e.nullableString(b.clientID)
+	e.b = append(e.b, body...)
+	binary.BigEndian.PutUint32(e.b, uint32(len(e.b)-4))
+
+	b.conn.SetDeadline(time.Now().Add(b.timeout + extra))
+
+	if _, err := b.conn.Write(e.b); err != nil {
+		return nil, err
+	}
+	var hdr [8]byte
+	if _, err := io.ReadFull(b.conn, hdr[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(hdr[:4]))
+	if size < 4 || size > maxResponseSize {
+		return nil, fmt.Errorf("invalid response size %d", size)
+	}
+	if corrID := int32(binary.BigEndian.Uint32(hdr[4:])); corrID != b.corrID {
+		return nil, fmt.Errorf("unexpected correlation ID %d, expected %d", corrID, b.corrID)
+	}
+	resp := make([]byte, size-4)
+	if _, err := io.ReadFull(b.conn, resp); err != nil {
+		return nil, err
+	}
+	return &decoder{b: resp}, nil
+}
+
+// bootstrap connects to the first reachable of the configured brokers.
+func bootstrap(cfg Config) (*broker, error) {
+	var err error
+	for _, addr := range cfg.Brokers {
+		var b *broker
+		if b, err = dialBroker(addr, cfg.ClientID, cfg.Timeout); err == nil {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("connecting to brokers failed: %s", err)
+}
+
+// cluster holds the addresses of the brokers and the leaders of the
+// topic's partitions.
+type cluster struct {
+	brokers map[int32]string
+	leaders map[int32]int32
+}
+
+// partitions returns the partitions of the topic in ascending order.
+func (cl *cluster) partitions() []int32 {
+	res := make([]int32, 0, len(cl.leaders))
+	for p := range cl.leaders {
+		res = append(res, p)
+	}
+	sort.Sort(int32s(res))
+	return res
+}
+
+type int32s []int32
+
+func (s int32s) Len() int           { return len(s) }
+func (s int32s) Less(i, j int) bool { return s[i] < s[j] }
+func (s int32s) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// metadata returns the brokers of the cluster and the leaders of the
+// topic's partitions.
+func metadata(b *broker, topic string) (*cluster, error) {
+	var e encoder
+	e.arrayLen(1)
+	e.string(topic)
+
+	d, err := b.request(apiMetadata, e.b, 0)
+	if err != nil {
+		return nil, err
+	}
+	cl := &cluster{brokers: map[int32]string{}, leaders: map[int32]int32{}}
+
+	for n := d.arrayLen(); n > 0; n-- {
+		id, host, port := d.int32(), d.string(), d.int32()
+		d.string() // Rack.
+		cl.brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.int32() // Controller ID.
+
+	for n := d.arrayLen(); n > 0; n-- {
+		terr, name := d.error(), d.string()
+		d.bool() // Internal.
+		if d.err == nil && name == topic && terr != errNone {
+			return nil, fmt.Errorf("topic %q: %s", name, terr)
+		}
+		for m := d.arrayLen(); m > 0; m-- {
+			perr, p, leader := d.error(), d.int32(), d.int32()
+			for k := d.arrayLen(); k > 0; k-- {
+				d.int32() // Replicas.
+			}
+			for k := d.arrayLen(); k > 0; k-- {
+				d.int32() // In-sync replicas.
+			}
+			if name != topic {
+				continue
+			}
+			if perr != errNone && perr != errLeaderNotAvailable {
+				return nil, fmt.Errorf("partition %d: %s", p, perr)
+			}
+			if _, ok := cl.brokers[leader]; !ok && d.err == nil {
+				return nil, fmt.Errorf("partition %d: %s", p, errLeaderNotAvailable)
+			}
+			cl.leaders[p] = leader
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(cl.leaders) == 0 {
+		return nil, fmt.Errorf("topic %q has no partitions", topic)
+	}
+	return cl, nil
+}
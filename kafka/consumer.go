@@ -0,0 +1,678 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kafka produces messages to Kafka topics and consumes them as
+// member of a consumer group.
+package kafka
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+const (
+	// protocolType and assignorName identify the partition assignment
+	// protocol of the group. Partitions are assigned in ranges like by
+	// the range assignor of the Java client.
+	protocolType = "consumer"
+	assignorName = "range"
+
+	// Special timestamps of ListOffsets requests.
+	timestampNewest = -1
+	timestampOldest = -2
+
+	fetchMaxBytes          = 16 << 20
+	fetchPartitionMaxBytes = 4 << 20
+)
+
+// Config configures a Consumer or Producer. The group settings only
+// apply to consumers.
+type Config struct {
+	// Brokers are the host:port addresses of the brokers the cluster
+	// metadata is bootstrapped from.
+	Brokers  []string
+	Topic    string
+	Group    string
+	ClientID string
+	// Oldest starts consuming partitions without committed offset at the
+	// oldest instead of the newest message.
+	Oldest bool
+
+	SessionTimeout    time.Duration
+	RebalanceTimeout  time.Duration
+	HeartbeatInterval time.Duration
+	// MaxWait is how long fetches wait for new messages.
+	MaxWait time.Duration
+	// Timeout bounds connection attempts and requests to brokers.
+	Timeout time.Duration
+	// RetryBackoff is the time waited before rejoining the group after
+	// failures.
+	RetryBackoff time.Duration
+}
+
+// DefaultConfig defines default values of consumer configurations.
+var DefaultConfig = Config{
+	ClientID:          "alertmanager",
+	SessionTimeout:    30 * time.Second,
+	RebalanceTimeout:  time.Minute,
+	HeartbeatInterval: 3 * time.Second,
+	MaxWait:           500 * time.Millisecond,
+	Timeout:           10 * time.Second,
+	RetryBackoff:      5 * time.Second,
+}
+
+// A Handler processes the messages fetched from a partition in offset
+// order. Their offsets are only committed after it returned nil. On
+// error, the consumer rejoins its group and the messages are fetched
+// again.
+type Handler func(msgs []*Message) error
+
+// A Consumer consumes the partitions of a topic assigned to it as member
+// of a consumer group.
+type Consumer struct {
+	cfg     Config
+	handler Handler
+
+	// memberID is the ID assigned by the group coordinator. It is kept
+	// across sessions to rejoin the group as the same member.
+	memberID string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewConsumer returns a new consumer passing consumed messages to the
+// handler.
+func NewConsumer(cfg Config, h Handler) *Consumer {
+	return &Consumer{
+		cfg:     cfg,
+		handler: h,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Run consumes the topic until the consumer is stopped. Failed sessions
+// are retried after the configured backoff.
+func (c *Consumer) Run() {
+	defer close(c.done)
+
+	for {
+		err := c.session()
+		if err == nil {
+			return
+		}
+		log.Warnf("Consuming Kafka topic %q failed: %s", c.cfg.Topic, err)
+
+		select {
+		case <-c.stop:
+			return
+		case <-time.After(c.cfg.RetryBackoff):
+		}
+	}
+}
+
+// Stop the consumer after the messages being handled are committed and
+// leave the group.
+func (c *Consumer) Stop() {
+	close(c.stop)
+	<-c.done
+}
+
+// session joins the group and consumes the assigned partitions until the
+// consumer is stopped, which returns nil, or an error occurs.
+func (c *Consumer) session() error {
+	boot, err := bootstrap(c.cfg)
+	if err != nil {
+		return err
+	}
+	cl, err := metadata(boot, c.cfg.Topic)
+	if err != nil {
+		boot.Close()
+		return err
+	}
+	coord, err := c.coordinator(boot)
+	boot.Close()
+	if err != nil {
+		return err
+	}
+	defer coord.Close()
+
+	gen, assigned, err := c.join(coord, cl)
+	if err != nil {
+		return err
+	}
+	log.Infof("Joined Kafka consumer group %q with generation %d, assigned partitions %v of topic %q", c.cfg.Group, gen, assigned, c.cfg.Topic)
+
+	// Heartbeats are sent through the coordinator connection, which is
+	// not blocked by fetches.
+	var (
+		hbErr = make(chan error, 1)
+		quit  = make(chan struct{})
+	)
+	defer close(quit)
+	go c.heartbeat(coord, gen, hbErr, quit)
+
+	leaders := map[int32]*broker{}
+	defer func() {
+		for _, b := range leaders {
+			b.Close()
+		}
+	}()
+	byLeader := map[int32][]int32{}
+	for _, p := range assigned {
+		id := cl.leaders[p]
+		if _, ok := leaders[id]; !ok {
+			b, err := dialBroker(cl.brokers[id], c.cfg.ClientID, c.cfg.Timeout)
+			if err != nil {
+				return err
+			}
+			leaders[id] = b
+		}
+		byLeader[id] = append(byLeader[id], p)
+	}
+
+	offsets, err := c.offsets(coord, assigned)
+	if err != nil {
+		return err
+	}
+	for id, ps := range byLeader {
+		if err := c.resetOffsets(leaders[id], ps, offsets, false); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if len(assigned) == 0 {
+			// Idle members wait for a rebalance.
+			select {
+			case <-c.stop:
+			case err := <-hbErr:
+				return err
+			}
+		}
+		select {
+		case <-c.stop:
+			c.leave(coord)
+			return nil
+		case err := <-hbErr:
+			return err
+		default:
+		}
+
+		for id, ps := range byLeader {
+			if err := c.fetch(leaders[id], coord, gen, ps, offsets); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// coordinator connects to the coordinator of the group.
+func (c *Consumer) coordinator(b *broker) (*broker, error) {
+	var e encoder
+	e.string(c.cfg.Group)
+
+	d, err := b.request(apiFindCoordinator, e.b, 0)
+	if err != nil {
+		return nil, err
+	}
+	ferr := d.error()
+	d.int32() // Node ID.
+	host, port := d.string(), d.int32()
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := ferr.check(); err != nil {
+		return nil, fmt.Errorf("finding coordinator of group %q: %s", c.cfg.Group, err)
+	}
+	return dialBroker(net.JoinHostPort(host, strconv.Itoa(int(port))), c.cfg.ClientID, c.cfg.Timeout)
+}
+
+// join joins the group and returns the generation and the partitions
+// assigned to the consumer.
+func (c *Consumer) join(coord *broker, cl *cluster) (int32, []int32, error) {
+	var sub encoder
+	sub.int16(0) // Version.
+	sub.arrayLen(1)
+	sub.string(c.cfg.Topic)
+	sub.bytes(nil) // User data.
+
+	var e encoder
+	e.string(c.cfg.Group)
+	e.int32(int32(c.cfg.SessionTimeout / time.Millisecond))
+	e.int32(int32(c.cfg.RebalanceTimeout / time.Millisecond))
+	e.string(c.memberID)
+	e.string(protocolType)
+	e.arrayLen(1)
+	e.string(assignorName)
+	e.bytes(sub.b)
+
+	// Joining waits for all members to rejoin during rebalances.
+	d, err := coord.request(apiJoinGroup, e.b, c.cfg.RebalanceTimeout)
+	if err != nil {
+		return 0, nil, err
+	}
+	d.int32() // Throttle time.
+	var (
+		jerr     = d.error()
+		gen      = d.int32()
+		protocol = d.string()
+		leader   = d.string()
+		memberID = d.string()
+		members  = map[string][]string{}
+	)
+	for n := d.arrayLen(); n > 0; n-- {
+		id, meta := d.string(), d.bytes()
+		members[id] = subscribedTopics(meta)
+	}
+	if d.err != nil {
+		return 0, nil, d.err
+	}
+	if jerr == errUnknownMemberID {
+		// The coordinator forgot about the member, e.g. after its session
+		// timed out. It rejoins as a new member.
+		c.memberID = ""
+	}
+	if err := jerr.check(); err != nil {
+		return 0, nil, fmt.Errorf("joining group %q: %s", c.cfg.Group, err)
+	}
+	if protocol != assignorName {
+		return 0, nil, fmt.Errorf("group %q uses unsupported assignor %q", c.cfg.Group, protocol)
+	}
+	c.memberID = memberID
+
+	e = encoder{}
+	e.string(c.cfg.Group)
+	e.int32(gen)
+	e.string(memberID)
+	if leader == memberID {
+		assignments := assign(c.cfg.Topic, cl.partitions(), members)
+		e.arrayLen(len(assignments))
+		for id, ps := range assignments {
+			e.string(id)
+			e.bytes(encodeAssignment(c.cfg.Topic, ps))
+		}
+	} else {
+		e.arrayLen(0)
+	}
+
+	d, err = coord.request(apiSyncGroup, e.b, c.cfg.RebalanceTimeout)
+	if err != nil {
+		return 0, nil, err
+	}
+	serr, a := d.error(), d.bytes()
+	if d.err != nil {
+		return 0, nil, d.err
+	}
+	if err := serr.check(); err != nil {
+		return 0, nil, fmt.Errorf("syncing group %q: %s", c.cfg.Group, err)
+	}
+	assigned, err := decodeAssignment(c.cfg.Topic, a)
+	if err != nil {
+		return 0, nil, err
+	}
+	return gen, assigned, nil
+}
+
+// subscribedTopics returns the topics of a member's subscription
+// metadata.
+func subscribedTopics(meta []byte) []string {
+	d := &decoder{b: meta}
+	d.int16() // Version.
+
+	var res []string
+	for n := d.arrayLen(); n > 0; n-- {
+		res = append(res, d.string())
+	}
+	if d.err != nil {
+		return nil
+	}
+	return res
+}
+
+// assign distributes the partitions of the topic in ranges across the
+// members subscribed to it. Members are ordered by their ID, earlier ones
+// get one partition more if they cannot be distributed evenly.
+func assign(topic string, partitions []int32, members map[string][]string) map[string][]int32 {
+	var ids []string
+	for id, topics := range members {
+		for _, t := range topics {
+			if t == topic {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	sort.Strings(ids)
+
+	res := make(map[string][]int32, len(members))
+	for id := range members {
+		res[id] = nil
+	}
+	if len(ids) == 0 {
+		return res
+	}
+	per, extra := len(partitions)/len(ids), len(partitions)%len(ids)
+	start := 0
+	for i, id := range ids {
+		n := per
+		if i < extra {
+			n++
+		}
+		res[id] = partitions[start : start+n]
+		start += n
+	}
+	return res
+}
+
+func encodeAssignment(topic string, partitions []int32) []byte {
+	var e encoder
+	e.int16(0) // Version.
+	e.arrayLen(1)
+	e.string(topic)
+	e.arrayLen(len(partitions))
+	for _, p := range partitions {
+		e.int32(p)
+	}
+	e.bytes(nil) // User data.
+	return e.b
+}
+
+// decodeAssignment returns the partitions of the topic in the member
+// assignment.
+func decodeAssignment(topic string, b []byte) ([]int32, error) {
+	// Members without partitions may be sent an empty assignment.
+	if len(b) == 0 {
+		return nil, nil
+	}
+	d := &decoder{b: b}
+	d.int16() // Version.
+
+	var res []int32
+	for n := d.arrayLen(); n > 0; n-- {
+		t := d.string()
+		for m := d.arrayLen(); m > 0; m-- {
+			if p := d.int32(); t == topic {
+				res = append(res, p)
+			}
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("decoding assignment: %s", d.err)
+	}
+	return res, nil
+}
+
+// heartbeat sends heartbeats to the coordinator until quit is closed. The
+// first error, e.g. due to a rebalance, is sent to errc.
+func (c *Consumer) heartbeat(coord *broker, gen int32, errc chan<- error, quit <-chan struct{}) {
+	ticker := time.NewTicker(c.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+		}
+		var e encoder
+		e.string(c.cfg.Group)
+		e.int32(gen)
+		e.string(c.memberID)
+
+		d, err := coord.request(apiHeartbeat, e.b, 0)
+		if err == nil {
+			herr := d.error()
+			if err = d.err; err == nil {
+				err = herr.check()
+			}
+		}
+		if err != nil {
+			errc <- fmt.Errorf("heartbeat: %s", err)
+			return
+		}
+	}
+}
+
+// leave leaves the group so that its partitions are reassigned without
+// waiting for the session to time out.
+func (c *Consumer) leave(coord *broker) {
+	var e encoder
+	e.string(c.cfg.Group)
+	e.string(c.memberID)
+
+	d, err := coord.request(apiLeaveGroup, e.b, 0)
+	if err == nil {
+		lerr := d.error()
+		if err = d.err; err == nil {
+			err = lerr.check()
+		}
+	}
+	if err != nil {
+		log.Warnf("Leaving Kafka consumer group %q failed: %s", c.cfg.Group, err)
+	}
+	c.memberID = ""
+}
+
+// offsets returns the committed offsets of the partitions. Partitions
+// without committed offset have offset -1.
+func (c *Consumer) offsets(coord *broker, partitions []int32) (map[int32]int64, error) {
+	var e encoder
+	e.string(c.cfg.Group)
+	e.arrayLen(1)
+	e.string(c.cfg.Topic)
+	e.arrayLen(len(partitions))
+	for _, p := range partitions {
+		e.int32(p)
+	}
+
+	d, err := coord.request(apiOffsetFetch, e.b, 0)
+	if err != nil {
+		return nil, err
+	}
+	res := map[int32]int64{}
+	for n := d.arrayLen(); n > 0; n-- {
+		d.string() // Topic.
+		for m := d.arrayLen(); m > 0; m-- {
+			p, offset := d.int32(), d.int64()
+			d.string() // Metadata.
+			if err := d.error().check(); err != nil && d.err == nil {
+				return nil, fmt.Errorf("fetching offset of partition %d: %s", p, err)
+			}
+			res[p] = offset
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	for _, p := range partitions {
+		if _, ok := res[p]; !ok {
+			res[p] = -1
+		}
+	}
+	return res, nil
+}
+
+// resetOffsets sets the offsets of the partitions to the oldest or newest
+// offset as configured. Unless force is set, only partitions with negative
+// offset are reset.
+func (c *Consumer) resetOffsets(leader *broker, partitions []int32, offsets map[int32]int64, force bool) error {
+	var reset []int32
+	for _, p := range partitions {
+		if force || offsets[p] < 0 {
+			reset = append(reset, p)
+		}
+	}
+	if len(reset) == 0 {
+		return nil
+	}
+	ts := int64(timestampNewest)
+	if c.cfg.Oldest {
+		ts = timestampOldest
+	}
+
+	var e encoder
+	e.int32(-1) // Replica ID.
+	e.arrayLen(1)
+	e.string(c.cfg.Topic)
+	e.arrayLen(len(reset))
+	for _, p := range reset {
+		e.int32(p)
+		e.int64(ts)
+	}
+
+	d, err := leader.request(apiListOffsets, e.b, 0)
+	if err != nil {
+		return err
+	}
+	for n := d.arrayLen(); n > 0; n-- {
+		d.string() // Topic.
+		for m := d.arrayLen(); m > 0; m-- {
+			p, lerr := d.int32(), d.error()
+			d.int64() // Timestamp.
+			offset := d.int64()
+			if err := lerr.check(); err != nil && d.err == nil {
+				return fmt.Errorf("listing offsets of partition %d: %s", p, err)
+			}
+			offsets[p] = offset
+		}
+	}
+	return d.err
+}
+
+// fetch fetches messages of the partitions from their leader, passes them
+// to the handler and commits their offsets.
+func (c *Consumer) fetch(leader, coord *broker, gen int32, partitions []int32, offsets map[int32]int64) error {
+	var e encoder
+	e.int32(-1) // Replica ID.
+	e.int32(int32(c.cfg.MaxWait / time.Millisecond))
+	e.int32(1) // Minimum bytes.
+	e.int32(fetchMaxBytes)
+	e.int8(0) // Read uncommitted.
+	e.arrayLen(1)
+	e.string(c.cfg.Topic)
+	e.arrayLen(len(partitions))
+	for _, p := range partitions {
+		e.int32(p)
+		e.int64(offsets[p])
+		e.int32(fetchPartitionMaxBytes)
+	}
+
+	d, err := leader.request(apiFetch, e.b, c.cfg.MaxWait)
+	if err != nil {
+		return err
+	}
+	d.int32() // Throttle time.
+
+	type result struct {
+		partition int32
+		records   []byte
+	}
+	var (
+		results    []result
+		outOfRange []int32
+	)
+	for n := d.arrayLen(); n > 0; n-- {
+		d.string() // Topic.
+		for m := d.arrayLen(); m > 0; m-- {
+			p, ferr := d.int32(), d.error()
+			d.int64() // High watermark.
+			d.int64() // Last stable offset.
+			for k := d.arrayLen(); k > 0; k-- {
+				d.int64() // Producer ID of aborted transaction.
+				d.int64() // First offset of aborted transaction.
+			}
+			records := d.bytes()
+
+			switch ferr {
+			case errNone:
+				results = append(results, result{partition: p, records: records})
+			case errOffsetOutOfRange:
+				outOfRange = append(outOfRange, p)
+			default:
+				if d.err == nil {
+					return fmt.Errorf("fetching partition %d: %s", p, ferr)
+				}
+			}
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	// Messages may have been deleted by retention before they were
+	// consumed.
+	if len(outOfRange) > 0 {
+		log.Warnf("Offsets of partitions %v of Kafka topic %q out of range, resetting", outOfRange, c.cfg.Topic)
+		if err := c.resetOffsets(leader, outOfRange, offsets, true); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range results {
+		msgs, next, err := decodeRecords(r.partition, r.records, offsets[r.partition])
+		if err != nil {
+			return fmt.Errorf("decoding messages of partition %d: %s", r.partition, err)
+		}
+		if len(msgs) > 0 {
+			if err := c.handler(msgs); err != nil {
+				return fmt.Errorf("handling messages of partition %d: %s", r.partition, err)
+			}
+		}
+		if next <= offsets[r.partition] {
+			continue
+		}
+		if err := c.commit(coord, gen, r.partition, next); err != nil {
+			return err
+		}
+		offsets[r.partition] = next
+	}
+	return nil
+}
+
+// commit commits the offset of the next message to be consumed from the
+// partition.
+func (c *Consumer) commit(coord *broker, gen, partition int32, offset int64) error {
+	var e encoder
+	e.string(c.cfg.Group)
+	e.int32(gen)
+	e.string(c.memberID)
+	e.int64(-1) // Retention time as configured by the broker.
+	e.arrayLen(1)
+	e.string(c.cfg.Topic)
+	e.arrayLen(1)
+	e.int32(partition)
+	e.int64(offset)
+	e.nullableString("") // Metadata.
+
+	d, err := coord.request(apiOffsetCommit, e.b, 0)
+	if err != nil {
+		return err
+	}
+	for n := d.arrayLen(); n > 0; n-- {
+		d.string() // Topic.
+		for m := d.arrayLen(); m > 0; m-- {
+			p, cerr := d.int32(), d.error()
+			if err := cerr.check(); err != nil && d.err == nil {
+				return fmt.Errorf("committing offset of partition %d: %s", p, err)
+			}
+		}
+	}
+	return d.err
+}
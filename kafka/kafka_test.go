@@ -0,0 +1,149 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+// recordBatch returns a record batch of the values starting at the base
+// offset.
+func recordBatch(base int64, codec int, control bool, values ...string) []byte {
+	var records []byte
+	for i, v := range values {
+		var r []byte
+		r = append(r, 0) // Attributes.
+		r = appendVarint(r, int64(i*10))
+		r = appendVarint(r, int64(i))
+		r = appendVarint(r, -1) // Null key.
+		r = appendVarint(r, int64(len(v)))
+		r = append(r, v...)
+		r = appendVarint(r, 0) // Headers.
+
+		records = appendVarint(records, int64(len(r)))
+		records = append(records, r...)
+	}
+	if codec == codecGzip {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(records)
+		w.Close()
+		records = buf.Bytes()
+	}
+	attrs := int16(codec)
+	if control {
+		attrs |= attrControl
+	}
+
+	var e encoder
+	e.int64(base)
+	e.int32(0) // Length, set below.
+	e.int32(0) // Partition leader epoch.
+	e.int8(2)  // Magic.
+	e.int32(0) // CRC, set below.
+	e.int16(attrs)
+	e.int32(int32(len(values) - 1))
+	e.int64(1451606400000) // First timestamp.
+	e.int64(1451606400000) // Max timestamp.
+	e.int64(-1)            // Producer ID.
+	e.int16(-1)            // Producer epoch.
+	e.int32(-1)            // First sequence.
+	e.int32(int32(len(values)))
+	e.b = append(e.b, records...)
+
+	binary.BigEndian.PutUint32(e.b[8:], uint32(len(e.b)-12))
+	binary.BigEndian.PutUint32(e.b[17:], crc32.Checksum(e.b[21:], castagnoli))
+	return e.b
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+func TestDecodeRecords(t *testing.T) {
+	var b []byte
+	b = append(b, recordBatch(0, codecNone, false, "a", "b")...)
+	b = append(b, recordBatch(2, codecGzip, false, "c", "d", "e")...)
+	b = append(b, recordBatch(5, codecNone, true, "commit")...)
+	// Brokers may cut off the last batch.
+	partial := recordBatch(6, codecNone, false, "f")
+	b = append(b, partial[:len(partial)-3]...)
+
+	msgs, next, err := decodeRecords(3, b, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range msgs {
+		if m.Partition != 3 {
+			t.Errorf("expected partition 3 but got %d", m.Partition)
+		}
+		got = append(got, fmt.Sprintf("%d:%s", m.Offset, m.Value))
+	}
+	if expected := []string{"1:b", "2:c", "3:d", "4:e"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected messages %v but got %v", expected, got)
+	}
+	// The control batch advances the next offset without messages.
+	if next != 6 {
+		t.Errorf("expected next offset 6 but got %d", next)
+	}
+	if ts := msgs[1].Timestamp.UnixNano() / 1e6; ts != 1451606400000 {
+		t.Errorf("expected timestamp 1451606400000 but got %d", ts)
+	}
+	if ts := msgs[2].Timestamp.UnixNano() / 1e6; ts != 1451606400010 {
+		t.Errorf("expected timestamp 1451606400010 but got %d", ts)
+	}
+
+	// Corrupted batches are rejected.
+	b = recordBatch(0, codecNone, false, "a")
+	b[len(b)-2] ^= 0xff
+	if _, _, err := decodeRecords(0, b, 0); err == nil {
+		t.Errorf("expected error for corrupt batch")
+	}
+}
+
+func TestAssign(t *testing.T) {
+	members := map[string][]string{
+		"b": {"alerts"},
+		"a": {"alerts"},
+		"c": {"other"},
+		"d": {"other", "alerts"},
+	}
+	got := assign("alerts", []int32{0, 1, 2, 3, 4}, members)
+
+	expected := map[string][]int32{
+		"a": {0, 1},
+		"b": {2, 3},
+		"c": nil,
+		"d": {4},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected assignment %v but got %v", expected, got)
+	}
+
+	ps, err := decodeAssignment("alerts", encodeAssignment("alerts", got["b"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ps, got["b"]) {
+		t.Errorf("expected decoded partitions %v but got %v", got["b"], ps)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// A Producer produces messages to a topic. Messages with the same key are
+// produced to the same partition as long as the number of partitions does
+// not change.
+type Producer struct {
+	cfg Config
+
+	mtx     sync.Mutex
+	cluster *cluster
+	leaders map[int32]*broker
+	next    int32
+}
+
+// NewProducer returns a new producer. Connections to the brokers are
+// established on the first messages produced.
+func NewProducer(cfg Config) *Producer {
+	return &Producer{cfg: cfg, leaders: map[int32]*broker{}}
+}
+
+// Produce the messages and wait for all in-sync replicas to acknowledge
+// them. Only keys, values, and timestamps of the messages are produced.
+func (p *Producer) Produce(msgs ...*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.cluster == nil {
+		if err := p.refresh(); err != nil {
+			return err
+		}
+	}
+
+	byPartition := map[int32][]*Message{}
+	for _, m := range msgs {
+		part := p.partition(m.Key)
+		byPartition[part] = append(byPartition[part], m)
+	}
+	byLeader := map[int32][]int32{}
+	for part := range byPartition {
+		id := p.cluster.leaders[part]
+		byLeader[id] = append(byLeader[id], part)
+	}
+
+	for id, parts := range byLeader {
+		if err := p.produce(id, parts, byPartition); err != nil {
+			// Leaders may have moved. The metadata is refreshed on the
+			// next call.
+			p.reset()
+			return err
+		}
+	}
+	return nil
+}
+
+// partition returns the partition of messages with the key. Messages
+// without key are distributed in turn.
+func (p *Producer) partition(key []byte) int32 {
+	parts := p.cluster.partitions()
+	if key == nil {
+		p.next++
+		return parts[int(p.next)%len(parts)]
+	}
+	h := fnv.New32a()
+	h.Write(key)
+	return parts[h.Sum32()%uint32(len(parts))]
+}
+
+func (p *Producer) produce(id int32, parts []int32, byPartition map[int32][]*Message) error {
+	b, ok := p.leaders[id]
+	if !ok {
+		var err error
+		if b, err = dialBroker(p.cluster.brokers[id], p.cfg.ClientID, p.cfg.Timeout); err != nil {
+			return err
+		}
+		p.leaders[id] = b
+	}
+
+	var e encoder
+	e.nullableString("") // Transactional ID.
+	e.int16(-1)          // Acknowledgements of all in-sync replicas.
+	e.int32(int32(p.cfg.Timeout / time.Millisecond))
+	e.arrayLen(1)
+	e.string(p.cfg.Topic)
+	e.arrayLen(len(parts))
+	for _, part := range parts {
+		e.int32(part)
+		e.bytes(encodeRecords(byPartition[part]))
+	}
+
+	d, err := b.request(apiProduce, e.b, p.cfg.Timeout)
+	if err != nil {
+		return err
+	}
+	for n := d.arrayLen(); n > 0; n-- {
+		d.string() // Topic.
+		for m := d.arrayLen(); m > 0; m-- {
+			part, perr := d.int32(), d.error()
+			d.int64() // Base offset.
+			d.int64() // Log append time.
+			if err := perr.check(); err != nil && d.err == nil {
+				return fmt.Errorf("producing to partition %d: %s", part, err)
+			}
+		}
+	}
+	return d.err
+}
+
+func (p *Producer) refresh() error {
+	b, err := bootstrap(p.cfg)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	cl, err := metadata(b, p.cfg.Topic)
+	if err != nil {
+		return err
+	}
+	p.cluster = cl
+	return nil
+}
+
+// reset closes the connections to the leaders and forgets the metadata.
+func (p *Producer) reset() {
+	for id, b := range p.leaders {
+		b.Close()
+		delete(p.leaders, id)
+	}
+	p.cluster = nil
+}
+
+// Close the connections to the brokers.
+func (p *Producer) Close() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.reset()
+	return nil
+}
@@ -0,0 +1,275 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Keys and versions of the APIs used by the client. The versions are the
+// oldest ones supported by Kafka 4.
+const (
+	apiProduce         = 0
+	apiFetch           = 1
+	apiListOffsets     = 2
+	apiMetadata        = 3
+	apiOffsetCommit    = 8
+	apiOffsetFetch     = 9
+	apiFindCoordinator = 10
+	apiJoinGroup       = 11
+	apiHeartbeat       = 12
+	apiLeaveGroup      = 13
+	apiSyncGroup       = 14
+)
+
+var apiVersions = map[int16]int16{
+	apiProduce:         3,
+	apiFetch:           4,
+	apiListOffsets:     1,
+	apiMetadata:        1,
+	apiOffsetCommit:    2,
+	apiOffsetFetch:     1,
+	apiFindCoordinator: 0,
+	apiJoinGroup:       2,
+	apiHeartbeat:       0,
+	apiLeaveGroup:      0,
+	apiSyncGroup:       0,
+}
+
+// Error is an error code returned by Kafka brokers.
+type Error int16
+
+// Error codes handled by the consumer.
+const (
+	errNone                    Error = 0
+	errOffsetOutOfRange        Error = 1
+	errUnknownTopicOrPartition Error = 3
+	errLeaderNotAvailable      Error = 5
+	errNotLeader               Error = 6
+	errCoordinatorLoading      Error = 14
+	errCoordinatorNotAvailable Error = 15
+	errNotCoordinator          Error = 16
+	errIllegalGeneration       Error = 22
+	errUnknownMemberID         Error = 25
+	errRebalanceInProgress     Error = 27
+)
+
+var errorNames = map[Error]string{
+	errOffsetOutOfRange:        "offset out of range",
+	errUnknownTopicOrPartition: "unknown topic or partition",
+	errLeaderNotAvailable:      "leader not available",
+	errNotLeader:               "not leader for partition",
+	errCoordinatorLoading:      "coordinator load in progress",
+	errCoordinatorNotAvailable: "coordinator not available",
+	errNotCoordinator:          "not coordinator",
+	errIllegalGeneration:       "illegal generation",
+	errUnknownMemberID:         "unknown member ID",
+	errRebalanceInProgress:     "rebalance in progress",
+}
+
+func (e Error) Error() string {
+	if s, ok := errorNames[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("kafka error %d", int16(e))
+}
+
+// check returns the error code as error or nil if it is errNone.
+func (e Error) check() error {
+	if e == errNone {
+		return nil
+	}
+	return e
+}
+
+var errMalformed = errors.New("malformed response")
+
+// encoder builds request bodies.
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) int8(v int8) { e.b = append(e.b, byte(v)) }
+
+func (e *encoder) int16(v int16) {
+	e.b = append(e.b, byte(v>>8), byte(v))
+}
+
+func (e *encoder) int32(v int32) {
+	e.b = append(e.b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (e *encoder) int64(v int64) {
+	e.int32(int32(v >> 32))
+	e.int32(int32(v))
+}
+
+func (e *encoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.b = append(e.b, s...)
+}
+
+// nullableString encodes the empty string as null.
+func (e *encoder) nullableString(s string) {
+	if s == "" {
+		e.int16(-1)
+		return
+	}
+	e.string(s)
+}
+
+// bytes encodes nil as null.
+func (e *encoder) bytes(b []byte) {
+	if b == nil {
+		e.int32(-1)
+		return
+	}
+	e.int32(int32(len(b)))
+	e.b = append(e.b, b...)
+}
+
+// arrayLen encodes the length of an array whose elements follow.
+func (e *encoder) arrayLen(n int) { e.int32(int32(n)) }
+
+// varint encodes a zigzag-encoded variable-length integer.
+func (e *encoder) varint(v int64) {
+	var buf [binary.MaxVarintLen64]byte
+	e.b = append(e.b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+// varbytes encodes bytes prefixed with their varint length. Nil is
+// encoded as null.
+func (e *encoder) varbytes(b []byte) {
+	if b == nil {
+		e.varint(-1)
+		return
+	}
+	e.varint(int64(len(b)))
+	e.b = append(e.b, b...)
+}
+
+// decoder reads response bodies. Reading past the end sets err and
+// returns zero values.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.err = errMalformed
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) int8() int8 {
+	b := d.take(1)
+	if b == nil {
+		return 0
+	}
+	return int8(b[0])
+}
+
+func (d *decoder) int16() int16 {
+	b := d.take(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (d *decoder) int32() int32 {
+	b := d.take(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (d *decoder) int64() int64 {
+	b := d.take(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (d *decoder) bool() bool { return d.int8() != 0 }
+
+func (d *decoder) error() Error { return Error(d.int16()) }
+
+// string decodes a string or nullable string. Null is returned as the
+// empty string.
+func (d *decoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.take(int(n)))
+}
+
+// bytes decodes nullable bytes. Null is returned as nil.
+func (d *decoder) bytes() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return d.take(int(n))
+}
+
+// arrayLen decodes the length of an array. Null arrays have length zero.
+// Lengths exceeding the remaining bytes are rejected as malformed.
+func (d *decoder) arrayLen() int {
+	n := d.int32()
+	if n < 0 {
+		return 0
+	}
+	if int(n) > len(d.b) {
+		d.err = errMalformed
+		return 0
+	}
+	return int(n)
+}
+
+// varint decodes a zigzag-encoded variable-length integer.
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = errMalformed
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+// varbytes decodes bytes prefixed with their varint length. A negative
+// length denotes null.
+func (d *decoder) varbytes() []byte {
+	n := d.varint()
+	if n < 0 {
+		return nil
+	}
+	return d.take(int(n))
+}
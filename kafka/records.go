@@ -0,0 +1,259 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/prometheus/alertmanager/ingest"
+)
+
+// Message is a record consumed from a partition of the topic.
+type Message struct {
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Timestamp time.Time
+}
+
+// Compression codecs of record batches.
+const (
+	codecNone   = 0
+	codecGzip   = 1
+	codecSnappy = 2
+	codecLZ4    = 3
+	codecZstd   = 4
+)
+
+const (
+	// recordBatchMagic is the magic byte of the record batch format
+	// written since Kafka 0.11.
+	recordBatchMagic = 2
+	// Attributes of record batches.
+	attrCodecMask = 0x07
+	attrControl   = 0x20
+	// recordBatchHeaderLen is the length of the batch header up to the
+	// number of records, starting with the partition leader epoch.
+	recordBatchHeaderLen = 49
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// decodeRecords returns the messages of the record batches in b with an
+// offset of at least minOffset and the offset following the last complete
+// batch. A partial batch at the end is ignored.
+func decodeRecords(partition int32, b []byte, minOffset int64) ([]*Message, int64, error) {
+	var (
+		msgs []*Message
+		next = minOffset
+	)
+
+	for len(b) >= 12 {
+		var (
+			baseOffset = int64(binary.BigEndian.Uint64(b))
+			length     = int(int32(binary.BigEndian.Uint32(b[8:])))
+		)
+		if length < recordBatchHeaderLen {
+			return nil, 0, errMalformed
+		}
+		// Brokers may return a partial batch at the end of the response.
+		if len(b)-12 < length {
+			break
+		}
+		batch := b[12 : 12+length]
+		b = b[12+length:]
+
+		if magic := batch[4]; magic != recordBatchMagic {
+			return nil, 0, fmt.Errorf("unsupported message format version %d", magic)
+		}
+		if crc := binary.BigEndian.Uint32(batch[5:]); crc != crc32.Checksum(batch[9:], castagnoli) {
+			return nil, 0, fmt.Errorf("record batch at offset %d is corrupt", baseOffset)
+		}
+
+		d := &decoder{b: batch[9:]}
+		var (
+			attrs          = d.int16()
+			lastOffset     = baseOffset + int64(d.int32())
+			firstTimestamp = d.int64()
+		)
+		d.take(8 + 8 + 2 + 4) // Max timestamp and producer state.
+		n := d.int32()
+
+		if d.err != nil {
+			return nil, 0, d.err
+		}
+		if lastOffset >= next {
+			next = lastOffset + 1
+		}
+		// Control batches mark the ends of transactions. Batches may also
+		// end before the requested offset after compaction.
+		if attrs&attrControl != 0 || lastOffset < minOffset {
+			continue
+		}
+
+		records, err := decompress(int(attrs&attrCodecMask), d.b)
+		if err != nil {
+			return nil, 0, fmt.Errorf("record batch at offset %d: %s", baseOffset, err)
+		}
+		rd := &decoder{b: records}
+
+		for i := int32(0); i < n; i++ {
+			r := &decoder{b: rd.take(int(rd.varint()))}
+			if rd.err != nil {
+				return nil, 0, rd.err
+			}
+			r.int8() // Attributes.
+			var (
+				ts     = firstTimestamp + r.varint()
+				offset = baseOffset + r.varint()
+				key    = r.varbytes()
+				value  = r.varbytes()
+			)
+			// Headers are ignored.
+			if r.err != nil {
+				return nil, 0, r.err
+			}
+			if offset < minOffset {
+				continue
+			}
+			msgs = append(msgs, &Message{
+				Partition: partition,
+				Offset:    offset,
+				Key:       key,
+				Value:     value,
+				Timestamp: time.Unix(ts/1000, (ts%1000)*int64(time.Millisecond)),
+			})
+		}
+	}
+	return msgs, next, nil
+}
+
+// encodeRecords returns an uncompressed record batch of the messages.
+// Offsets are assigned by the broker, the messages' timestamps are kept.
+func encodeRecords(msgs []*Message) []byte {
+	var (
+		first = msgs[0].Timestamp
+		max   = first
+		rs    encoder
+	)
+	for i, m := range msgs {
+		if m.Timestamp.After(max) {
+			max = m.Timestamp
+		}
+		var r encoder
+		r.int8(0) // Attributes.
+		r.varint(millis(m.Timestamp) - millis(first))
+		r.varint(int64(i))
+		r.varbytes(m.Key)
+		r.varbytes(m.Value)
+		r.varint(0) // Headers.
+
+		rs.varint(int64(len(r.b)))
+		rs.b = append(rs.b, r.b...)
+	}
+
+	var e encoder
+	e.int64(0)  // Base offset.
+	e.int32(0)  // Length, set below.
+	e.int32(-1) // Partition leader epoch.
+	e.int8(recordBatchMagic)
+	e.int32(0) // CRC, set below.
+	e.int16(codecNone)
+	e.int32(int32(len(msgs) - 1))
+	e.int64(millis(first))
+	e.int64(millis(max))
+	e.int64(-1) // Producer ID.
+	e.int16(-1) // Producer epoch.
+	e.int32(-1) // Base sequence.
+	e.int32(int32(len(msgs)))
+	e.b = append(e.b, rs.b...)
+
+	binary.BigEndian.PutUint32(e.b[8:], uint32(len(e.b)-12))
+	binary.BigEndian.PutUint32(e.b[17:], crc32.Checksum(e.b[21:], castagnoli))
+	return e.b
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// xerialHeader starts snappy-compressed data in the framing of the Java
+// snappy library.
+var xerialHeader = []byte{0x82, 'S', 'N', 'A', 'P', 'P', 'Y', 0}
+
+func decompress(codec int, b []byte) ([]byte, error) {
+	switch codec {
+	case codecNone:
+		return b, nil
+
+	case codecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		res, err := ioutil.ReadAll(io.LimitReader(r, maxResponseSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(res) > maxResponseSize {
+			return nil, fmt.Errorf("decompressed batch too large")
+		}
+		return res, nil
+
+	case codecSnappy:
+		if !bytes.HasPrefix(b, xerialHeader) {
+			return ingest.SnappyDecode(b)
+		}
+		// The header is followed by a version and a compatible version
+		// and chunks prefixed by their length.
+		if len(b) < len(xerialHeader)+8 {
+			return nil, errMalformed
+		}
+		var res []byte
+		for b = b[len(xerialHeader)+8:]; len(b) > 0; {
+			if len(b) < 4 {
+				return nil, errMalformed
+			}
+			n := int(binary.BigEndian.Uint32(b))
+			if n < 0 || len(b)-4 < n {
+				return nil, errMalformed
+			}
+			chunk, err := ingest.SnappyDecode(b[4 : 4+n])
+			if err != nil {
+				return nil, err
+			}
+			if res = append(res, chunk...); len(res) > maxResponseSize {
+				return nil, fmt.Errorf("decompressed batch too large")
+			}
+			b = b[4+n:]
+		}
+		return res, nil
+
+	case codecLZ4:
+		return nil, fmt.Errorf("unsupported compression codec lz4")
+	case codecZstd:
+		return nil, fmt.Errorf("unsupported compression codec zstd")
+	}
+	return nil, fmt.Errorf("unknown compression codec %d", codec)
+}
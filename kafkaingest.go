@@ -0,0 +1,106 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"github.com/prometheus/alertmanager/ingest"
+	"github.com/prometheus/alertmanager/kafka"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var kafkaMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "kafka_messages_consumed_total",
+	Help:      "The total number of consumed Kafka messages by whether their alerts were stored or they were dropped as undecodable.",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(kafkaMessages)
+}
+
+// Formats of alerts in Kafka messages.
+const (
+	kafkaFormatJSON     = "json"
+	kafkaFormatProtobuf = "protobuf"
+)
+
+// kafkaHandler returns a handler storing the alerts of consumed messages
+// through the API. Messages whose alerts cannot be decoded are dropped.
+// If storing fails, the messages' offsets are not committed and they are
+// consumed again.
+func kafkaHandler(api *API, format string) kafka.Handler {
+	return func(msgs []*kafka.Message) error {
+		var (
+			alerts  []*types.Alert
+			dropped int
+		)
+		for _, m := range msgs {
+			as, err := decodeKafkaMessage(format, m.Value)
+			if err != nil {
+				log.With("partition", m.Partition).With("offset", m.Offset).Warnf("Dropping undecodable Kafka message: %s", err)
+				dropped++
+				continue
+			}
+			alerts = append(alerts, as...)
+		}
+
+		validationErrs, err := api.storeAlerts(alerts...)
+		if err != nil {
+			return err
+		}
+		if validationErrs.Len() > 0 {
+			log.Warnf("Dropping invalid alerts consumed from Kafka: %s", validationErrs)
+		}
+		kafkaMessages.WithLabelValues("stored").Add(float64(len(msgs) - dropped))
+		kafkaMessages.WithLabelValues("dropped").Add(float64(dropped))
+		return nil
+	}
+}
+
+// decodeKafkaMessage returns the alerts of a message. JSON messages hold
+// a single alert or a list of alerts as sent to the API, protobuf messages
+// an uncompressed batch of the ingest package.
+func decodeKafkaMessage(format string, b []byte) ([]*types.Alert, error) {
+	switch format {
+	case kafkaFormatProtobuf:
+		return ingest.Unmarshal(b)
+
+	case kafkaFormatJSON:
+		if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '{' {
+			var a types.Alert
+			if err := json.Unmarshal(b, &a); err != nil {
+				return nil, err
+			}
+			return []*types.Alert{&a}, nil
+		}
+		var as []*types.Alert
+		if err := json.Unmarshal(b, &as); err != nil {
+			return nil, err
+		}
+		for _, a := range as {
+			if a == nil {
+				return nil, fmt.Errorf("null alert")
+			}
+		}
+		return as, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
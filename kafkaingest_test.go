@@ -0,0 +1,70 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/ingest"
+	"github.com/prometheus/alertmanager/kafka"
+	"github.com/prometheus/alertmanager/provider"
+)
+
+func TestKafkaHandler(t *testing.T) {
+	alerts := provider.NewMemAlerts(provider.NewMemData())
+	api := NewAPI(alerts, nil, nil, nil, nil, nil, nil, nil, "", nil)
+	api.Update("", 5*time.Minute)
+
+	h := kafkaHandler(api, kafkaFormatJSON)
+	err := h([]*kafka.Message{
+		{Offset: 0, Value: []byte(`{"labels": {"alertname": "single"}}`)},
+		{Offset: 1, Value: []byte(`[{"labels": {"alertname": "first"}}, {"labels": {"alertname": "second"}, "startsAt": "2016-01-01T00:00:00Z", "endsAt": "2016-01-01T00:05:00Z"}]`)},
+		{Offset: 2, Value: []byte(`not json`)},
+		{Offset: 3, Value: []byte(`[{"labels": {}}]`)},
+	})
+	if err != nil {
+		t.Fatalf("Handling messages failed: %s", err)
+	}
+
+	for _, name := range []string{"single", "first", "second"} {
+		a, err := alerts.Get(model.LabelSet{"alertname": model.LabelValue(name)}.Fingerprint())
+		if err != nil {
+			t.Errorf("expected alert %q to be stored but got %s", name, err)
+			continue
+		}
+		if expected := name == "second"; a.Resolved() != expected {
+			t.Errorf("expected alert %q resolved to be %v but got %v", name, expected, a.Resolved())
+		}
+	}
+
+	b, err := proto.Marshal(&ingest.AlertBatch{
+		Alerts: []*ingest.Alert{{
+			Labels: []*ingest.LabelPair{{Name: "alertname", Value: "proto"}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = kafkaHandler(api, kafkaFormatProtobuf)
+	if err := h([]*kafka.Message{{Value: b}}); err != nil {
+		t.Fatalf("Handling messages failed: %s", err)
+	}
+	if _, err := alerts.Get(model.LabelSet{"alertname": "proto"}.Fingerprint()); err != nil {
+		t.Errorf("expected protobuf alert to be stored but got %s", err)
+	}
+}
@@ -14,27 +14,39 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
 	"path"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/common/version"
 
+	"github.com/prometheus/alertmanager/cluster"
 	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/kafka"
 	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/provider/boltmem"
+	"github.com/prometheus/alertmanager/provider/sqlite"
 	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/tracing"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -44,8 +56,62 @@ var (
 	configFile = flag.String("config.file", "alertmanager.yml", "Alertmanager configuration file name.")
 	dataDir    = flag.String("storage.path", "data/", "Base path for data storage.")
 
+	eventsStorage = flag.String("storage.events", "boltmem", "Storage backend for events. One of boltmem, memory, or sqlite.")
+
+	historyRetention = flag.Duration("storage.alert-history-retention", 7*24*time.Hour, "How long the state changes of alerts are kept in the alert history.")
+
+	gcInterval         = flag.Duration("storage.gc-interval", 15*time.Minute, "Interval in which entries of the notification log and dead letters exceeding their retention are removed.")
+	notifyLogMaxAge    = flag.Duration("storage.notification-log.max-age", 0, "How long notification log entries are kept after the last notification. Should exceed the longest repeat interval. Disabled if zero.")
+	notifyLogMaxSize   = flag.Int64("storage.notification-log.max-size", 0, "Maximum total size of notification log entries in bytes. The least recently written entries are removed first. Disabled if zero.")
+	deadLettersMaxAge  = flag.Duration("storage.dead-letters.max-age", 0, "How long notifications given up on are kept. Disabled if zero.")
+	deadLettersMaxSize = flag.Int64("storage.dead-letters.max-size", 0, "Maximum total size of notifications given up on in bytes. The oldest ones are removed first. Disabled if zero.")
+
+	attachmentsPath         = flag.String("storage.attachments-path", "", "Directory the content of event attachments is stored in. If omitted, it is stored in the attachments database.")
+	attachmentsMaxSize      = flag.Int64("events.attachments.max-size", 10<<20, "Maximum size of event attachments in bytes.")
+	attachmentsContentTypes = flag.String("events.attachments.content-types", "image/png,image/jpeg,image/gif,application/pdf,text/plain,text/markdown,text/csv", "Comma-separated list of content types event attachments may have.")
+
 	externalURL   = flag.String("web.external-url", "", "The URL under which Alertmanager is externally reachable (for example, if Alertmanager is served via a reverse proxy). Used for generating relative and absolute links back to Alertmanager itself. If the URL has a path portion, it will be used to prefix all HTTP endpoints served by Alertmanager. If omitted, relevant URL components will be derived automatically.")
 	listenAddress = flag.String("web.listen-address", ":9093", "Address to listen on for the web interface and API.")
+
+	checkReceivers        = flag.Bool("receivers.check", false, "Verify connectivity to all receiver endpoints on startup and configuration reload.")
+	checkReceiversTimeout = flag.Duration("receivers.check-timeout", 10*time.Second, "Timeout for connectivity checks of a single receiver endpoint.")
+	healthCheckInterval   = flag.Duration("receivers.health-check-interval", 0, "Interval in which all receiver endpoints are checked in the background. Results are exposed at /api/v1/receivers/health and as metrics. Disabled if 0.")
+
+	tlsCertFile     = flag.String("web.tls-cert-file", "", "Certificate file to serve the web interface and API via HTTPS with. Requires -web.tls-key-file.")
+	tlsKeyFile      = flag.String("web.tls-key-file", "", "Private key file of the certificate given by -web.tls-cert-file.")
+	tlsClientCAFile = flag.String("web.tls-client-ca-file", "", "CA certificates file to verify client certificates against. Verified client certificates authenticate API users by their common name.")
+	auditUserHeader = flag.String("web.audit.user-header", "", "HTTP header holding the authenticated user set by a reverse proxy. Used as the principal in the audit log. If omitted, the basic auth user or the client address is recorded.")
+
+	dedupWindow     = flag.Duration("dispatcher.dedup-window", 0, "Window in which updates of firing alerts that change nothing but their end time are dropped before routing. Disabled if zero.")
+	notifyWorkers   = flag.Int("dispatcher.notify-workers", 0, "Number of workers sending the notifications of all alert groups in order of route priority. If zero, each alert group notifies on its own.")
+	notifyQueueSize = flag.Int("dispatcher.notify-queue-size", 1024, "Maximum number of notifications waiting for a notify worker. Flushes of alert groups wait for space once the queue is full.")
+
+	dependencyLabel = flag.String("inhibit.dependency-label", "service", "Label identifying the service of an alert. Alerts of a service are inhibited while a service it depends on, as declared at /api/v1/dependencies, has firing alerts.")
+
+	maxDeadLetters = flag.Int("notify.max-dead-letters", 100, "Maximum number of notifications given up on that are retained for inspection and re-driving.")
+	dryRun         = flag.Bool("notify.dry-run", false, "Record and log notifications instead of sending them, unless overridden by a receiver's dry_run setting. Recorded notifications are listed at /api/v1/notifications/dry_run.")
+	maxDryRuns     = flag.Int("notify.max-dry-runs", 1000, "Maximum number of notifications recorded in dry-run mode that are retained.")
+	pluginsDir     = flag.String("notify.plugins-dir", "", "Directory of the executables implementing the integrations of plugin_configs. It is rescanned on configuration reloads.")
+
+	clusterPeers          = flag.String("cluster.peers", "", "Comma-separated list of the external URLs of other Alertmanagers to run as a cluster with. Each peer is identified by its -web.external-url, which must be equal across all peers' lists.")
+	clusterGossipInterval = flag.Duration("cluster.gossip-interval", time.Second, "Interval in which state changes are sent to cluster peers.")
+	clusterPeerTimeout    = flag.Duration("cluster.peer-timeout", 15*time.Second, "Time to wait for each preceding cluster peer to notify before notifying.")
+	clusterBearerToken    = flag.String("cluster.bearer-token", "", "Bearer token sent with gossip to cluster peers whose API requires authentication. The token's user needs the admin role.")
+
+	kafkaBrokers       = flag.String("kafka.brokers", "", "Comma-separated list of Kafka brokers to consume alerts from. Kafka ingestion is disabled if omitted.")
+	kafkaTopic         = flag.String("kafka.topic", "alerts", "Kafka topic to consume alerts from.")
+	kafkaGroup         = flag.String("kafka.group", "alertmanager", "Kafka consumer group. Alertmanagers in the same group share the topic's partitions.")
+	kafkaFormat        = flag.String("kafka.format", "json", "Format of the alerts in Kafka messages. One of json or protobuf.")
+	kafkaInitialOffset = flag.String("kafka.initial-offset", "newest", "Offset partitions without committed offset are consumed from. One of oldest or newest.")
+
+	eventBusKafkaBrokers = flag.String("eventbus.kafka-brokers", "", "Comma-separated list of Kafka brokers to publish alert state changes, notification results, and event transitions to.")
+	eventBusKafkaTopic   = flag.String("eventbus.kafka-topic", "alertmanager-events", "Kafka topic the event bus publishes to.")
+	eventBusNATSURL      = flag.String("eventbus.nats-url", "", "URL of the NATS server to publish alert state changes, notification results, and event transitions to, for example nats://localhost:4222.")
+	eventBusNATSSubject  = flag.String("eventbus.nats-subject", "alertmanager.events", "NATS subject the event bus publishes to.")
+	eventBusFormat       = flag.String("eventbus.format", "json", "Schema of the messages published to the event bus. One of json or avro.")
+
+	tracingEndpoint    = flag.String("tracing.otlp-endpoint", "", "URL of the OTLP/HTTP traces endpoint of an OpenTelemetry collector to export spans to, for example http://localhost:4318/v1/traces. Tracing is disabled if omitted.")
+	tracingSampleRatio = flag.Float64("tracing.sample-ratio", 1, "Ratio of the traces started by the Alertmanager that are sampled. Traces continued from API clients are sampled as decided by the client.")
 )
 
 var (
@@ -75,83 +141,556 @@ func main() {
 		os.Exit(0)
 	}
 
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+	case "export-state", "import-state":
+		if flag.NArg() != 2 {
+			log.Fatalf("Usage: alertmanager [flags] %s <file>", cmd)
+		}
+		if err := os.MkdirAll(*dataDir, 0777); err != nil {
+			log.Fatal(err)
+		}
+		stores, closers, err := openStores(*dataDir, types.NewMarker())
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = runStateCommand(cmd, stores, flag.Arg(1))
+
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i].Close()
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	default:
+		log.Fatalf("Unknown command %q", cmd)
+	}
+
 	log.Infoln("Starting alertmanager", version.Info())
 	log.Infoln("Build context", version.BuildContext())
 
+	if *tracingEndpoint != "" {
+		tracer := tracing.NewTracer(*tracingEndpoint, "alertmanager", *tracingSampleRatio)
+		tracing.SetTracer(tracer)
+
+		go tracer.Run()
+		defer tracer.Stop()
+	}
+
+	if *notifyWorkers > 0 && *notifyQueueSize < 1 {
+		log.Fatalf("-dispatcher.notify-queue-size must be positive")
+	}
+	if *kafkaFormat != kafkaFormatJSON && *kafkaFormat != kafkaFormatProtobuf {
+		log.Fatalf("Unknown Kafka message format %q", *kafkaFormat)
+	}
+	if *kafkaInitialOffset != "oldest" && *kafkaInitialOffset != "newest" {
+		log.Fatalf("Unknown Kafka initial offset %q", *kafkaInitialOffset)
+	}
+	if *eventBusFormat != busFormatJSON && *eventBusFormat != busFormatAvro {
+		log.Fatalf("Unknown event bus format %q", *eventBusFormat)
+	}
+	if !model.LabelName(*dependencyLabel).IsValid() {
+		log.Fatalf("Invalid dependency label %q", *dependencyLabel)
+	}
+
+	// Alert state changes, notification results, and event transitions
+	// are published to the event bus if one is configured.
+	var bus *EventBus
+	switch {
+	case *eventBusKafkaBrokers != "" && *eventBusNATSURL != "":
+		log.Fatalf("Only one of -eventbus.kafka-brokers and -eventbus.nats-url may be set")
+	case *eventBusKafkaBrokers != "":
+		cfg := kafka.DefaultConfig
+		cfg.Brokers = strings.Split(*eventBusKafkaBrokers, ",")
+		cfg.Topic = *eventBusKafkaTopic
+		bus = NewEventBus(newKafkaSink(cfg), *eventBusFormat)
+	case *eventBusNATSURL != "":
+		bus = NewEventBus(newNATSSink(*eventBusNATSURL, *eventBusNATSSubject, kafka.DefaultConfig.Timeout), *eventBusFormat)
+	}
+
 	err := os.MkdirAll(*dataDir, 0777)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	marker := types.NewMarker()
+	// Changes of the silenced and inhibited state of alerts are recorded
+	// by the marker.
+	alertHistory, err := boltmem.NewAlertHistory(*dataDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var history provider.AlertHistory = alertHistory
+	if bus != nil {
+		history = NewBusHistory(alertHistory, bus)
+	}
+	marker := NewHistoryMarker(types.NewMarker(), history)
+
+	amURL, err := extURL(*externalURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Providers are opened on startup and closed by the storage subsystem
+	// once all subsystems using them are stopped.
+	stores, closers, err := openStores(*dataDir, marker)
+	if err != nil {
+		log.Fatal(err)
+	}
+	closers = append([]io.Closer{alertHistory}, closers...)
+	var (
+		alerts   = stores.Alerts
+		notifies = stores.Notifies
+		silences = stores.Silences
+		events   = stores.Events
+		acks     = stores.Acks
+	)
 
-	alerts, err := boltmem.NewAlerts(*dataDir)
+	// The online backup covers the databases holding user-created state.
+	backups := map[string]provider.Backuper{}
+	for name, s := range map[string]interface{}{
+		"events.db":            events,
+		"silences.db":          silences,
+		"notification_info.db": notifies,
+	} {
+		if b, ok := s.(provider.Backuper); ok {
+			backups[name] = b
+		}
+	}
+
+	deadLetters, err := boltmem.NewDeadLetters(*dataDir, *maxDeadLetters)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer alerts.Close()
+	closers = append(closers, deadLetters)
 
-	notifies, err := boltmem.NewNotificationInfo(*dataDir)
+	maintenance, err := boltmem.NewMaintenanceWindows(*dataDir, marker)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer notifies.Close()
+	closers = append(closers, maintenance)
+	backups["maintenance.db"] = maintenance
 
-	silences, err := boltmem.NewSilences(*dataDir, marker)
+	dependencies, err := boltmem.NewDependencies(*dataDir)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer silences.Close()
+	closers = append(closers, dependencies)
+	backups["dependencies.db"] = dependencies
 
-	events, err := boltmem.NewEvents(*dataDir)
+	threads, err := boltmem.NewThreads(*dataDir)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer events.Close()
+	closers = append(closers, threads)
 
+	audit, err := boltmem.NewAudit(*dataDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	closers = append(closers, audit)
+	backups["audit.db"] = audit
+
+	attachments, err := boltmem.NewAttachments(*dataDir, *attachmentsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	closers = append(closers, attachments)
+	backups["attachments.db"] = attachments
+	backups["alert_history.db"] = alertHistory
+
+	authenticator := NewAuthenticator()
+	tenants := NewTenants(authenticator)
+	eventHooks := NewEventHooks()
+	eventHooks.SetEventBus(bus)
+	auditor := NewAuditor(audit, *auditUserHeader, authenticator)
+
+	// In cluster mode, changes of silences, the notification log, and
+	// events are replicated to the peers.
 	var (
-		inhibitor *Inhibitor
-		tmpl      *template.Template
-		disp      *Dispatcher
+		peer        *cluster.Peer
+		notifyLog   provider.Notifies = notifies
+		allSilences provider.Silences = silences
 	)
-	defer disp.Stop()
+	if *clusterPeers != "" {
+		peer = cluster.NewPeer(amURL.String(), strings.Split(*clusterPeers, ","), *clusterGossipInterval, *clusterPeerTimeout)
+		peer.SetBearerToken(*clusterBearerToken)
+
+		notifyLog = cluster.NewNotifies(notifies, peer)
+		if allSilences, err = cluster.NewSilences(silences, peer); err != nil {
+			log.Fatal(err)
+		}
+		if events, err = cluster.NewEvents(events, peer); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	api := NewAPI(alerts, silences, events, func() AlertOverview {
-		return disp.Groups()
+	costs, err := notify.NewCostAccount(filepath.Join(*dataDir, "costs.json"))
+	if err != nil {
+		log.Fatalf("Loading notification costs failed: %s", err)
+	}
+
+	var (
+		conf            *config.Config
+		inhibitor       *Inhibitor
+		flaps           *FlapDetector
+		historyRecorder *HistoryRecorder
+		storageGC       *StorageGC
+		silenceSched    *SilenceScheduler
+		healthMonitor   *notify.HealthMonitor
+		duplicator      *Duplicator
+		tmpl            *template.Template
+		disp            *Dispatcher
+	)
+
+	var (
+		sup          = NewSupervisor()
+		digests      = notify.NewDigests()
+		dryRuns      = notify.NewDryRuns(*maxDryRuns)
+		checker      = notify.NewChecker(*checkReceiversTimeout)
+		plugins      = notify.NewPlugins(*pluginsDir)
+		httpClients  = notify.NewHTTPClients()
+		flapHistory  = NewFlapHistory()
+		snapshotFile = filepath.Join(*dataDir, "dispatcher.snapshot")
+	)
+
+	api := NewAPI(alerts, allSilences, events, acks, costs, checker, deadLetters, sup, snapshotFile, func() *Dispatcher {
+		return disp
 	})
+	api.SetPeer(peer)
+	api.SetNotifies(notifyLog)
+	api.SetBackups(backups)
+	api.SetAttachments(attachments, *attachmentsMaxSize, strings.Split(*attachmentsContentTypes, ","))
+	api.SetAuditor(auditor)
+	api.SetAuthenticator(authenticator)
+	api.SetTenants(tenants)
+	api.SetEventHooks(eventHooks)
+	api.SetAlertHistory(alertHistory)
+	api.SetMaintenance(maintenance)
+	api.SetDependencies(dependencies)
+	api.SetDryRuns(dryRuns)
 
 	build := func(rcvs []*config.Receiver) notify.Notifier {
 		var (
 			router  = notify.Router{}
-			fanouts = notify.Build(rcvs, tmpl)
+			fanouts = notify.Build(rcvs, tmpl, costs, threads, NewEventIssueLinker(events), plugins, httpClients)
 		)
+		var (
+			retries   = map[string]*config.RetryConfig{}
+			dry       = map[string]bool{}
+			fallbacks = map[string]string{}
+		)
+		for _, rc := range rcvs {
+			retries[rc.Name] = rc.Retry
+			fallbacks[rc.Name] = rc.Fallback
+			dry[rc.Name] = *dryRun
+			if rc.DryRun != nil {
+				dry[rc.Name] = *rc.DryRun
+			}
+		}
 		for name, fo := range fanouts {
 			for i, n := range fo {
-				n = notify.Retry(n)
+				if dry[name] {
+					n = dryRuns.Notifier()
+				}
+				n = notify.Retry(n, retries[name])
+				n = recordNotifications(alertHistory, n)
+				if bus != nil {
+					n = publishResults(bus, n)
+				}
 				n = notify.Log(n, log.With("step", "retry"))
-				n = notify.Dedup(notifies, n)
+				n = notify.Dedup(notifyLog, n)
 				n = notify.Log(n, log.With("step", "dedup"))
+				if fb := fallbacks[name]; fb != "" {
+					// The fallback's integrations are reached through
+					// the router once all receivers were added.
+					n = notify.Failover(notifyLog, n, fb, router)
+				}
 
 				fo[i] = n
 			}
 			router[name] = fo
 		}
+		digests.Wrap(rcvs, router)
+
 		n := notify.Notifier(router)
 
 		n = notify.Log(n, log.With("step", "route"))
-		n = notify.Silence(silences, n, marker)
+		n = notify.Enrich(conf.EnrichmentRules, tmpl, n)
+		n = notify.Log(n, log.With("step", "enrich"))
+		n = notify.Silence(allSilences, n, marker)
 		n = notify.Log(n, log.With("step", "silence"))
 		n = notify.Inhibit(inhibitor, n, marker)
 		n = notify.Log(n, log.With("step", "inhibit"))
+		n = notify.Flap(flaps, n)
+		n = notify.Log(n, log.With("step", "flap"))
 
 		return n
 	}
 
-	amURL, err := extURL(*externalURL)
-	if err != nil {
-		log.Fatal(err)
+	configureDispatcher := func(d *Dispatcher) {
+		d.SetDeadlinePolicies(conf.Receivers, deadLetters)
+		d.SetConcurrencyLimits(conf.Receivers)
+		d.SetCorrelator(NewCorrelator(alerts, events, conf.CorrelationRules, eventHooks))
+		d.SetEventCreator(NewEventCreator(events, tmpl, conf.EventTemplates))
+		d.SetMaintenance(maintenance)
+
+		var resolveTimeout time.Duration
+		if conf.Global.EnforceResolveTimeout {
+			resolveTimeout = time.Duration(conf.Global.ResolveTimeout)
+		}
+		d.SetResolveTimeout(resolveTimeout)
 	}
 
+	router := route.New()
+
+	webReload := make(chan struct{})
+	RegisterWeb(router.WithPrefix(amURL.Path), webReload, auditor, authenticator)
+	api.Register(router.WithPrefix(path.Join(amURL.Path, "/api")))
+
+	var listener net.Listener
+
+	subsystems := []*Subsystem{
+		{
+			Name:  "storage",
+			Start: func() error { return nil },
+			Stop: func() error {
+				var errs types.MultiError
+				for i := len(closers) - 1; i >= 0; i-- {
+					if err := closers[i].Close(); err != nil {
+						errs.Add(err)
+					}
+				}
+				if errs.Len() > 0 {
+					return &errs
+				}
+				return nil
+			},
+		}, {
+			Name: "cluster",
+			Deps: []string{"storage"},
+			Start: func() error {
+				if peer != nil {
+					log.Infof("Running as cluster peer %s", peer.Name())
+					go peer.Run()
+				}
+				return nil
+			},
+			Stop: func() error {
+				peer.Stop()
+				return nil
+			},
+		}, {
+			Name: "inhibitor",
+			Deps: []string{"storage"},
+			Start: func() error {
+				inhibitor = NewInhibitor(alerts, conf.InhibitRules, marker)
+				inhibitor.SetDependencies(dependencies, model.LabelName(*dependencyLabel))
+				go inhibitor.Run()
+				api.SetInhibitor(inhibitor)
+				return nil
+			},
+			Stop: func() error {
+				inhibitor.Stop()
+				return nil
+			},
+		}, {
+			Name: "flapdetector",
+			Deps: []string{"storage"},
+			Start: func() error {
+				flaps = NewFlapDetector(alerts, conf.FlapDetection, flapHistory, marker)
+				go flaps.Run()
+				return nil
+			},
+			Stop: func() error {
+				flaps.Stop()
+				return nil
+			},
+		}, {
+			Name: "history",
+			Deps: []string{"storage"},
+			Start: func() error {
+				historyRecorder = NewHistoryRecorder(alerts, history, *historyRetention)
+				go historyRecorder.Run()
+				return nil
+			},
+			Stop: func() error {
+				historyRecorder.Stop()
+				return nil
+			},
+		}, {
+			Name: "storagegc",
+			Deps: []string{"storage"},
+			Start: func() error {
+				storageGC = NewStorageGC(*gcInterval)
+				if c, ok := notifies.(provider.Collector); ok {
+					storageGC.Add("notification_log", c, provider.Retention{
+						MaxAge:  *notifyLogMaxAge,
+						MaxSize: *notifyLogMaxSize,
+					})
+				}
+				storageGC.Add("dead_letters", deadLetters, provider.Retention{
+					MaxAge:  *deadLettersMaxAge,
+					MaxSize: *deadLettersMaxSize,
+				})
+				go storageGC.Run()
+				return nil
+			},
+			Stop: func() error {
+				storageGC.Stop()
+				return nil
+			},
+		}, {
+			Name: "silencescheduler",
+			Deps: []string{"storage"},
+			Start: func() error {
+				silenceSched = NewSilenceScheduler(allSilences, time.Minute)
+				go silenceSched.Run()
+				return nil
+			},
+			Stop: func() error {
+				silenceSched.Stop()
+				return nil
+			},
+		}, {
+			Name: "duplicator",
+			Deps: []string{"storage"},
+			Start: func() error {
+				duplicator = NewDuplicator(alerts, conf.DuplicateRules)
+				go duplicator.Run()
+				return nil
+			},
+			Stop: func() error {
+				duplicator.Stop()
+				return nil
+			},
+		}, {
+			Name: "dispatcher",
+			// The dispatcher is not restarted along with the inhibitor and
+			// flap detector on reloads but picks up the new configuration
+			// through Reload to retain the state of its aggregation groups.
+			Deps: []string{"storage"},
+			Start: func() error {
+				first := disp == nil
+				disp = NewDispatcher(alerts, NewRoute(conf.Route, nil), build(conf.Receivers), marker)
+				if *notifyWorkers > 0 {
+					disp.SetNotifyQueue(NewNotifyQueue(*notifyWorkers, *notifyQueueSize))
+				}
+				configureDispatcher(disp)
+				if peer != nil {
+					disp.SetPeerWait(peer.Wait)
+				}
+				if *dedupWindow > 0 {
+					disp.SetDeduper(NewDeduper(*dedupWindow))
+				}
+
+				// Restore the aggregation groups persisted on the last shutdown.
+				if first {
+					if snap, err := LoadSnapshotFile(snapshotFile); err == nil {
+						disp.Restore(snap)
+						os.Remove(snapshotFile)
+					} else if !os.IsNotExist(err) {
+						log.Errorf("Loading dispatcher snapshot failed: %s", err)
+					}
+				}
+				go disp.Run()
+				return nil
+			},
+			Stop: func() error {
+				disp.Stop()
+				return nil
+			},
+		}, {
+			Name: "web",
+			Deps: []string{"storage"},
+			Start: func() error {
+				l, err := net.Listen("tcp", *listenAddress)
+				if err != nil {
+					return err
+				}
+				if *tlsCertFile != "" {
+					tlsConfig, err := loadTLSConfig(*tlsCertFile, *tlsKeyFile, *tlsClientCAFile)
+					if err != nil {
+						l.Close()
+						return err
+					}
+					l = tls.NewListener(l, tlsConfig)
+				}
+				listener = l
+
+				log.Infoln("Listening on", *listenAddress)
+				go func() {
+					if err := http.Serve(l, router); err != nil {
+						sup.Fail("web", err)
+					}
+				}()
+				return nil
+			},
+			Stop: func() error {
+				return listener.Close()
+			},
+		},
+	}
+	if *healthCheckInterval > 0 {
+		subsystems = append(subsystems, &Subsystem{
+			Name: "receiverhealth",
+			Start: func() error {
+				healthMonitor = notify.NewHealthMonitor(checker, *healthCheckInterval)
+				go healthMonitor.Run()
+				return nil
+			},
+			Stop: func() error {
+				healthMonitor.Stop()
+				return nil
+			},
+		})
+	}
+	if bus != nil {
+		// The event bus is started first and stopped last to publish the
+		// messages of all other subsystems.
+		subsystems = append([]*Subsystem{{
+			Name: "eventbus",
+			Start: func() error {
+				go bus.Run()
+				return nil
+			},
+			Stop: func() error {
+				bus.Stop()
+				return nil
+			},
+		}}, subsystems...)
+	}
+	if *kafkaBrokers != "" {
+		var consumer *kafka.Consumer
+
+		cfg := kafka.DefaultConfig
+		cfg.Brokers = strings.Split(*kafkaBrokers, ",")
+		cfg.Topic = *kafkaTopic
+		cfg.Group = *kafkaGroup
+		cfg.Oldest = *kafkaInitialOffset == "oldest"
+
+		subsystems = append(subsystems, &Subsystem{
+			Name: "kafka",
+			Deps: []string{"storage"},
+			Start: func() error {
+				consumer = kafka.NewConsumer(cfg, kafkaHandler(api, *kafkaFormat))
+				go consumer.Run()
+				return nil
+			},
+			Stop: func() error {
+				consumer.Stop()
+				return nil
+			},
+		})
+	}
+	for _, ss := range subsystems {
+		if err := sup.Add(ss); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	started := false
+
 	reload := func() (err error) {
 		log.With("file", *configFile).Infof("Loading configuration file")
 		defer func() {
@@ -164,27 +703,53 @@ func main() {
 			}
 		}()
 
-		conf, err := config.LoadFile(*configFile)
+		c, err := config.LoadFile(*configFile)
 		if err != nil {
 			return err
 		}
+		if err := plugins.Discover(c.Receivers); err != nil {
+			return err
+		}
+		log.With("plugins", strings.Join(plugins.Names(), ",")).Debugf("Discovered notifier plugins")
+		if err := httpClients.Load(c.Receivers); err != nil {
+			return err
+		}
 
-		api.Update(conf.String(), time.Duration(conf.Global.ResolveTimeout))
+		api.Update(c.String(), time.Duration(c.Global.ResolveTimeout))
+		api.SetLateAlerts(time.Duration(c.Global.LateAlertThreshold), c.Global.LateAlertPolicy)
+		authenticator.SetConfig(c.APIAuth)
+		tenants.SetConfig(c.Tenancy)
+		eventHooks.SetConfig(c.EventHooks)
+		api.SetPagerdutyWebhook(c.PagerdutyWebhook)
+		api.SetSlackActions(c.SlackActions)
 
-		tmpl, err = template.FromGlobs(conf.Templates...)
+		tmpl, err = template.FromGlobs(c.Templates...)
 		if err != nil {
 			return err
 		}
 		tmpl.ExternalURL = amURL
+		tmpl.SetSeverities(severityStyles(c.Global.Severities))
+		api.SetReceivers(c.Receivers, tmpl)
 
-		inhibitor.Stop()
-		disp.Stop()
+		checker.SetReceivers(c.Receivers)
+		if *checkReceivers {
+			go checker.Check(c.Receivers)
+		}
 
-		inhibitor = NewInhibitor(alerts, conf.InhibitRules, marker)
-		disp = NewDispatcher(alerts, NewRoute(conf.Route, nil), build(conf.Receivers), marker)
+		conf = c
+		api.SetRunningConfig(c)
 
-		go disp.Run()
-		go inhibitor.Run()
+		// On startup, subsystems are started after the initial load.
+		if !started {
+			return nil
+		}
+		if err := sup.Restart("inhibitor", "flapdetector", "duplicator"); err != nil {
+			return err
+		}
+		// Notifications go through the restarted inhibitor and flap detector.
+		disp.SetNotifier(build(conf.Receivers))
+		configureDispatcher(disp)
+		disp.Reload(NewRoute(conf.Route, nil))
 
 		return nil
 	}
@@ -192,15 +757,10 @@ func main() {
 	if err := reload(); err != nil {
 		os.Exit(1)
 	}
-
-	router := route.New()
-
-	webReload := make(chan struct{})
-	RegisterWeb(router.WithPrefix(amURL.Path), webReload)
-	api.Register(router.WithPrefix(path.Join(amURL.Path, "/api")))
-
-	log.Infoln("Listening on", *listenAddress)
-	go listen(router)
+	if err := sup.Start(); err != nil {
+		log.Fatal(err)
+	}
+	started = true
 
 	var (
 		hup      = make(chan os.Signal)
@@ -227,6 +787,86 @@ func main() {
 	<-term
 
 	log.Infoln("Received SIGTERM, exiting gracefully...")
+
+	if err := disp.Snapshot().WriteFile(snapshotFile); err != nil {
+		log.Errorf("Writing dispatcher snapshot failed: %s", err)
+	}
+	sup.Stop()
+	// Send the pending digests once no more notifications are dispatched.
+	digests.Stop()
+}
+
+// openStores opens the providers holding the operational state in the
+// given directory. The returned closers must be closed in reverse order
+// once the providers are no longer used.
+func openStores(dir string, marker types.Marker) (*Stores, []io.Closer, error) {
+	var (
+		stores  = &Stores{}
+		closers []io.Closer
+	)
+	fail := func(err error) (*Stores, []io.Closer, error) {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i].Close()
+		}
+		return nil, nil, err
+	}
+
+	alerts, err := boltmem.NewAlerts(dir)
+	if err != nil {
+		return fail(err)
+	}
+	closers = append(closers, alerts)
+	stores.Alerts = alerts
+
+	notifies, err := boltmem.NewNotificationInfo(dir)
+	if err != nil {
+		return fail(err)
+	}
+	closers = append(closers, notifies)
+	stores.Notifies = notifies
+
+	silences, err := boltmem.NewSilences(dir, marker)
+	if err != nil {
+		return fail(err)
+	}
+	closers = append(closers, silences)
+	stores.Silences = silences
+
+	switch *eventsStorage {
+	case "boltmem":
+		events, err := boltmem.NewEvents(dir)
+		if err != nil {
+			return fail(err)
+		}
+		closers = append(closers, events)
+		stores.Events = events
+
+	case "memory":
+		stores.Events = provider.NewMemEvents()
+
+	case "sqlite":
+		db, err := sql.Open("sqlite3", filepath.Join(dir, "events.sqlite"))
+		if err != nil {
+			return fail(err)
+		}
+		closers = append(closers, db)
+
+		if stores.Events, err = sqlite.NewEvents(db); err != nil {
+			return fail(err)
+		}
+
+	default:
+		return fail(fmt.Errorf("unknown events storage %q", *eventsStorage))
+	}
+
+	acks, err := boltmem.NewAcks(dir, marker)
+	if err != nil {
+		return fail(err)
+	}
+	closers = append(closers, acks)
+	stores.Acks = acks
+
+	return stores, closers, nil
 }
 
 func extURL(s string) (*url.URL, error) {
@@ -257,8 +897,41 @@ func extURL(s string) (*url.URL, error) {
 	return u, nil
 }
 
-func listen(router *route.Router) {
-	if err := http.ListenAndServe(*listenAddress, router); err != nil {
-		log.Fatal(err)
+// loadTLSConfig returns the TLS configuration for serving with the given
+// certificate. If the client CA file is set, client certificates are
+// requested and verified against it.
+func loadTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS certificate failed: %s", err)
+	}
+	c := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if clientCAFile != "" {
+		b, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+		}
+		c.ClientCAs = pool
+		c.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return c, nil
+}
+
+// severityStyles converts the configured severity styles into the ones
+// exposed to notification templates.
+func severityStyles(cs map[string]*config.SeverityStyle) map[string]template.SeverityStyle {
+	styles := make(map[string]template.SeverityStyle, len(cs))
+	for sev, c := range cs {
+		styles[sev] = template.SeverityStyle{
+			Color:    c.Color,
+			Emoji:    c.Emoji,
+			Priority: c.Priority,
+		}
 	}
+	return styles
 }
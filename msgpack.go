@@ -0,0 +1,283 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Limits of the length of strings, arrays, and maps and of the nesting of
+// arrays and maps in msgpack payloads. They protect against oversized
+// allocations and unbounded recursion.
+const (
+	maxMsgpackLen   = 1 << 24
+	maxMsgpackDepth = 100
+)
+
+// decodeMsgpack decodes a msgpack encoded value from r and stores it in
+// the value pointed to by v. The payload is mapped onto the JSON
+// representation of v so that all types can be received in either encoding.
+// Timestamp extensions are converted to RFC3339 strings.
+func decodeMsgpack(r io.Reader, v interface{}) error {
+	d := &msgpackDecoder{r: bufio.NewReader(r)}
+
+	val, err := d.decode()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+type msgpackDecoder struct {
+	r     *bufio.Reader
+	buf   [8]byte
+	depth int
+}
+
+func (d *msgpackDecoder) enter() error {
+	d.depth++
+	if d.depth > maxMsgpackDepth {
+		return fmt.Errorf("msgpack: nesting exceeds depth limit")
+	}
+	return nil
+}
+
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	var b []byte
+	if n <= len(d.buf) {
+		b = d.buf[:n]
+	} else {
+		b = make([]byte, n)
+	}
+	_, err := io.ReadFull(d.r, b)
+	return b, err
+}
+
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *msgpackDecoder) int(n int) (int64, error) {
+	u, err := d.uint(n)
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 1:
+		return int64(int8(u)), nil
+	case 2:
+		return int64(int16(u)), nil
+	case 4:
+		return int64(int32(u)), nil
+	default:
+		return int64(u), nil
+	}
+}
+
+func (d *msgpackDecoder) length(n int) (int, error) {
+	u, err := d.uint(n)
+	if err != nil {
+		return 0, err
+	}
+	if u > maxMsgpackLen {
+		return 0, fmt.Errorf("msgpack: length %d exceeds limit", u)
+	}
+	return int(u), nil
+}
+
+func (d *msgpackDecoder) decode() (interface{}, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c >= 0x80 && c <= 0x8f:
+		return d.decodeMap(int(c & 0x0f))
+	case c >= 0x90 && c <= 0x9f:
+		return d.decodeArray(int(c & 0x0f))
+	case c >= 0xa0 && c <= 0xbf:
+		return d.decodeString(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+
+	// Binary data is treated like strings.
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.length(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.length(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.length(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(n)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(1 << (c - 0xd4))
+
+	case 0xca:
+		u, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return math.Float64frombits(u), err
+
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.uint(1 << (c - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		return d.int(1 << (c - 0xd0))
+
+	case 0xdc, 0xdd:
+		n, err := d.length(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := d.length(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+	}
+	return nil, fmt.Errorf("msgpack: invalid type byte 0x%x", c)
+}
+
+func (d *msgpackDecoder) decodeString(n int) (string, error) {
+	b, err := d.read(n)
+	return string(b), err
+}
+
+func (d *msgpackDecoder) decodeArray(n int) ([]interface{}, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+
+	res := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n int) (map[string]interface{}, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+
+	res := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		res[fmt.Sprint(k)] = v
+	}
+	return res, nil
+}
+
+// decodeExt decodes an extension value of the given length. Only the
+// timestamp extension type is supported.
+func (d *msgpackDecoder) decodeExt(n int) (interface{}, error) {
+	typ, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ) != -1 {
+		return nil, fmt.Errorf("msgpack: unsupported extension type %d", int8(typ))
+	}
+
+	var t time.Time
+
+	switch n {
+	case 4:
+		sec, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		t = time.Unix(int64(sec), 0)
+	case 8:
+		u, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		t = time.Unix(int64(u&(1<<34-1)), int64(u>>34))
+	case 12:
+		nsec, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		sec, err := d.int(8)
+		if err != nil {
+			return nil, err
+		}
+		t = time.Unix(sec, int64(nsec))
+	default:
+		return nil, fmt.Errorf("msgpack: invalid timestamp length %d", n)
+	}
+	return t.UTC().Format(time.RFC3339Nano), nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// alertsMsgpack is the msgpack encoding of
+//
+//	[{"labels": {"alertname": "A"}, "startsAt": <2016-01-02T03:04:05Z>, "generatorURL": "x"}]
+//
+// where startsAt is encoded as a 32-bit timestamp extension.
+var alertsMsgpack = []byte{
+	0x91, 0x83,
+	0xa6, 'l', 'a', 'b', 'e', 'l', 's',
+	0x81, 0xa9, 'a', 'l', 'e', 'r', 't', 'n', 'a', 'm', 'e', 0xa1, 'A',
+	0xa8, 's', 't', 'a', 'r', 't', 's', 'A', 't',
+	0xd6, 0xff, 0x56, 0x87, 0x3e, 0x25,
+	0xac, 'g', 'e', 'n', 'e', 'r', 'a', 't', 'o', 'r', 'U', 'R', 'L',
+	0xc4, 0x01, 'x',
+}
+
+func TestReceive(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write(alertsMsgpack)
+	gz.Close()
+
+	expected := []*types.Alert{
+		{
+			Alert: model.Alert{
+				Labels:       model.LabelSet{"alertname": "A"},
+				StartsAt:     time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC),
+				GeneratorURL: "x",
+			},
+		},
+	}
+
+	cases := []struct {
+		body        []byte
+		contentType string
+		encoding    string
+		fail        bool
+	}{
+		{
+			body:        []byte(`[{"labels":{"alertname":"A"},"startsAt":"2016-01-02T03:04:05Z","generatorURL":"x"}]`),
+			contentType: "application/json",
+		},
+		{
+			body:        alertsMsgpack,
+			contentType: "application/msgpack",
+		},
+		{
+			body:        alertsMsgpack,
+			contentType: "application/x-msgpack; charset=binary",
+		},
+		{
+			body:        gzipped.Bytes(),
+			contentType: "application/msgpack",
+			encoding:    "gzip",
+		},
+		{
+			body:        alertsMsgpack,
+			contentType: "application/msgpack",
+			encoding:    "br",
+			fail:        true,
+		},
+		{
+			body:        alertsMsgpack[:20],
+			contentType: "application/msgpack",
+			fail:        true,
+		},
+	}
+
+	for i, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/alerts", bytes.NewReader(c.body))
+		req.Header.Set("Content-Type", c.contentType)
+		if c.encoding != "" {
+			req.Header.Set("Content-Encoding", c.encoding)
+		}
+
+		var alerts []*types.Alert
+		err := receive(req, &alerts)
+		if c.fail {
+			if err == nil {
+				t.Errorf("%d: expected error but got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		for _, a := range alerts {
+			a.StartsAt = a.StartsAt.UTC()
+		}
+		if !reflect.DeepEqual(alerts, expected) {
+			t.Errorf("%d: unexpected alerts: %v", i, alerts)
+		}
+	}
+}
+
+func TestDecodeMsgpackDepth(t *testing.T) {
+	var v interface{}
+
+	deep := bytes.Repeat([]byte{0x91}, maxMsgpackDepth+1)
+	if err := decodeMsgpack(bytes.NewReader(append(deep, 0xc0)), &v); err == nil {
+		t.Fatalf("expected error for deeply nested payload")
+	}
+}
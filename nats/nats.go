@@ -0,0 +1,257 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nats publishes messages to a NATS server using the plain text
+// client protocol.
+//
+// https://docs.nats.io/reference/reference-protocols/nats-protocol
+package nats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPort is the port of NATS servers if URLs do not contain one.
+const DefaultPort = "4222"
+
+// hostPort returns the host of a URL with the default port added if it
+// has none.
+func hostPort(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return net.JoinHostPort(host, DefaultPort)
+}
+
+var errClosed = errors.New("connection closed")
+
+// info is the subset of the INFO message sent by servers used by the
+// client.
+type info struct {
+	TLSRequired bool `json:"tls_required"`
+	MaxPayload  int  `json:"max_payload"`
+}
+
+// connect holds the options of the CONNECT message.
+type connect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	TLS       bool   `json:"tls_required"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Version   string `json:"version"`
+	Protocol  int    `json:"protocol"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// Conn is a connection to a NATS server. It only publishes messages and
+// does not subscribe to any subjects. All methods are goroutine-safe.
+type Conn struct {
+	conn    net.Conn
+	timeout time.Duration
+	info    info
+
+	// mtx serializes writes to the connection.
+	mtx sync.Mutex
+	w   *bufio.Writer
+
+	// pongs receives a value for every PONG received from the server.
+	pongs chan struct{}
+	// done is closed once reading from the connection failed, with err
+	// holding the reason.
+	done chan struct{}
+	err  error
+}
+
+// Dial connects to the NATS server at the URL of the form
+// nats://[user:password@|token@]host[:port]. The client is identified to
+// the server by the name.
+func Dial(rawurl, name string, timeout time.Duration) (*Conn, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "nats" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	conn, err := net.DialTimeout("tcp", hostPort(u.Host), timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{
+		conn:    conn,
+		timeout: timeout,
+		w:       bufio.NewWriter(conn),
+		pongs:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	r := bufio.NewReader(conn)
+
+	if err := c.handshake(r, u, name); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go c.read(r)
+
+	// Authentication failures are reported before the first PONG.
+	if err := c.Flush(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Conn) handshake(r *bufio.Reader, u *url.URL, name string) error {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected greeting %q", strings.TrimSpace(line))
+	}
+	if err := json.Unmarshal([]byte(line[len("INFO "):]), &c.info); err != nil {
+		return fmt.Errorf("invalid server info: %s", err)
+	}
+	if c.info.TLSRequired {
+		return fmt.Errorf("server requires TLS, which is not supported")
+	}
+
+	opts := connect{
+		Name:     name,
+		Lang:     "go",
+		Version:  "1.0.0",
+		Protocol: 1,
+	}
+	if ui := u.User; ui != nil {
+		if pass, ok := ui.Password(); ok {
+			opts.User, opts.Pass = ui.Username(), pass
+		} else {
+			opts.AuthToken = ui.Username()
+		}
+	}
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	return c.write("CONNECT " + string(b) + "\r\n")
+}
+
+// read handles the messages sent by the server until reading fails.
+func (c *Conn) read(r *bufio.Reader) {
+	var err error
+	defer func() {
+		c.err = err
+		close(c.done)
+	}()
+
+	for {
+		var line string
+		if line, err = r.ReadString('\n'); err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch op := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); op {
+		case "PING":
+			if err = c.write("PONG\r\n"); err != nil {
+				return
+			}
+		case "PONG":
+			select {
+			case c.pongs <- struct{}{}:
+			default:
+			}
+		case "-ERR":
+			err = fmt.Errorf("server error: %s", strings.Trim(strings.TrimPrefix(line, op), " '"))
+			c.conn.Close()
+			return
+		case "+OK", "INFO":
+		default:
+			err = fmt.Errorf("unexpected message %q", line)
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+func (c *Conn) write(s string, data ...[]byte) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+
+	c.w.WriteString(s)
+	for _, d := range data {
+		c.w.Write(d)
+		c.w.WriteString("\r\n")
+	}
+	return c.w.Flush()
+}
+
+// Publish the data to the subject. Messages are not guaranteed to have
+// reached the server before Flush returns.
+func (c *Conn) Publish(subject string, data []byte) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("invalid subject %q", subject)
+	}
+	if c.info.MaxPayload > 0 && len(data) > c.info.MaxPayload {
+		return fmt.Errorf("message of %d bytes exceeds maximum payload of %d bytes", len(data), c.info.MaxPayload)
+	}
+	select {
+	case <-c.done:
+		return c.closedErr()
+	default:
+	}
+	return c.write(fmt.Sprintf("PUB %s %d\r\n", subject, len(data)), data)
+}
+
+// Flush waits for the server to process all messages published before.
+func (c *Conn) Flush() error {
+	if err := c.write("PING\r\n"); err != nil {
+		return err
+	}
+	select {
+	case <-c.pongs:
+		return nil
+	case <-c.done:
+		return c.closedErr()
+	case <-time.After(c.timeout):
+		return fmt.Errorf("flush timed out")
+	}
+}
+
+func (c *Conn) closedErr() error {
+	if c.err != nil {
+		return c.err
+	}
+	return errClosed
+}
+
+// Close the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
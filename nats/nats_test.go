@@ -0,0 +1,142 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testServer accepts a single client, answering PINGs and recording the
+// options and published messages. Clients whose password is not secret
+// are rejected.
+type testServer struct {
+	l        net.Listener
+	opts     chan connect
+	messages chan string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{l: l, opts: make(chan connect, 1), messages: make(chan string, 10)}
+	go s.serve()
+	return s
+}
+
+func (s *testServer) serve() {
+	conn, err := s.l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"max_payload\":16}\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		f := strings.Fields(line)
+		switch f[0] {
+		case "CONNECT":
+			var opts connect
+			json.Unmarshal([]byte(line[len("CONNECT "):]), &opts)
+			s.opts <- opts
+			if opts.Pass != "secret" {
+				fmt.Fprintf(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+			// Clients must answer PINGs of the server.
+			fmt.Fprintf(conn, "PING\r\n")
+		case "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case "PONG":
+			s.messages <- "PONG"
+		case "PUB":
+			n, _ := strconv.Atoi(f[2])
+			b := make([]byte, n+2)
+			io.ReadFull(r, b)
+			s.messages <- f[1] + " " + string(b[:n])
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+	s := newTestServer(t)
+	defer s.l.Close()
+
+	c, err := Dial("nats://am:secret@"+s.l.Addr().String(), "alertmanager", time.Second)
+	if err != nil {
+		t.Fatalf("Dialing failed: %s", err)
+	}
+	defer c.Close()
+
+	opts := <-s.opts
+	if opts.User != "am" || opts.Name != "alertmanager" || opts.Verbose {
+		t.Errorf("unexpected connect options %+v", opts)
+	}
+
+	if err := c.Publish("alertmanager.events", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flushing failed: %s", err)
+	}
+	for _, expected := range []string{"PONG", "alertmanager.events hello"} {
+		if got := <-s.messages; got != expected {
+			t.Errorf("expected server to receive %q but got %q", expected, got)
+		}
+	}
+
+	if err := c.Publish("invalid subject", nil); err == nil {
+		t.Errorf("expected error for invalid subject")
+	}
+	if err := c.Publish("alertmanager.events", make([]byte, 17)); err == nil {
+		t.Errorf("expected error for payload exceeding the maximum")
+	}
+}
+
+func TestDialAuthorizationViolation(t *testing.T) {
+	s := newTestServer(t)
+	defer s.l.Close()
+
+	_, err := Dial("nats://am:wrong@"+s.l.Addr().String(), "alertmanager", time.Second)
+	if err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Errorf("expected authorization violation but got %v", err)
+	}
+}
+
+func TestHostPort(t *testing.T) {
+	for host, expected := range map[string]string{
+		"nats.example.com":      "nats.example.com:4222",
+		"nats.example.com:4223": "nats.example.com:4223",
+		"[::1]":                 "[::1]:4222",
+		"[::1]:4223":            "[::1]:4223",
+	} {
+		if got := hostPort(host); got != expected {
+			t.Errorf("expected address %q of host %q but got %q", expected, host, got)
+		}
+	}
+}
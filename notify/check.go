@@ -0,0 +1,495 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// pushoverAPIURL is the endpoint of the Pushover API.
+const pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+
+var (
+	endpointUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "receiver_endpoint_up",
+		Help:      "Whether the endpoint of a receiver's integration passed the latest health check.",
+	}, []string{"receiver", "integration"})
+
+	endpointCheckFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "receiver_endpoint_check_failures_total",
+		Help:      "The total number of failed health checks of the endpoint of a receiver's integration.",
+	}, []string{"receiver", "integration"})
+)
+
+func init() {
+	prometheus.Register(endpointUp)
+	prometheus.Register(endpointCheckFailures)
+}
+
+// EndpointStatus is the result of a connectivity check against the
+// endpoint of a single integration.
+type EndpointStatus struct {
+	Integration string    `json:"integration"`
+	Endpoint    string    `json:"endpoint"`
+	Healthy     bool      `json:"healthy"`
+	Error       string    `json:"error,omitempty"`
+	CheckedAt   time.Time `json:"checkedAt"`
+	// Time of the latest check the endpoint passed, which may precede
+	// the latest check.
+	LastHealthyAt time.Time `json:"lastHealthyAt,omitempty"`
+	// Number of checks the endpoint failed since it was last healthy.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+}
+
+// ReceiverStatus holds the connectivity check results of all
+// integrations of a receiver.
+type ReceiverStatus struct {
+	Name string `json:"name"`
+	// Healthy is true iff all endpoints of the receiver are healthy.
+	Healthy   bool              `json:"healthy"`
+	Endpoints []*EndpointStatus `json:"endpoints"`
+}
+
+// Checker verifies that the endpoints of configured receivers can be
+// resolved and connected to. It keeps the results of the latest check.
+// All methods are goroutine-safe.
+type Checker struct {
+	timeout time.Duration
+	client  *http.Client
+
+	mtx       sync.RWMutex
+	statuses  []*ReceiverStatus
+	receivers []*config.Receiver
+	// Previous results by receiver and integration, e.g. "team-X/slack/0".
+	byName map[string]*EndpointStatus
+}
+
+// NewChecker returns a new Checker that aborts checks of single endpoints
+// after the given timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		client:  &http.Client{Timeout: timeout},
+		byName:  map[string]*EndpointStatus{},
+	}
+}
+
+// Statuses returns the results of the latest check.
+func (c *Checker) Statuses() []*ReceiverStatus {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.statuses
+}
+
+// SetReceivers sets the receivers checked by a HealthMonitor.
+func (c *Checker) SetReceivers(confs []*config.Receiver) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.receivers = confs
+}
+
+func (c *Checker) currentReceivers() []*config.Receiver {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.receivers
+}
+
+// endpointCheck is a pending check of a single endpoint.
+type endpointCheck struct {
+	receiver    string
+	integration string
+	status      *EndpointStatus
+	check       func() error
+}
+
+// Check verifies DNS resolution, TCP and TLS connectivity and, where
+// possible without sending a notification, authentication for every
+// endpoint of the given receivers. Webhooks are sent a HEAD request and
+// Slack API tokens are verified. SNMP trap receivers are only resolved as
+// traps are sent via UDP. Failures are logged as warnings.
+func (c *Checker) Check(confs []*config.Receiver) []*ReceiverStatus {
+	var (
+		statuses = make([]*ReceiverStatus, 0, len(confs))
+		checks   []*endpointCheck
+		counts   map[string]int
+	)
+	add := func(rs *ReceiverStatus, integration, endpoint string, check func() error) {
+		es := &EndpointStatus{
+			Integration: integration,
+			Endpoint:    endpoint,
+		}
+		rs.Endpoints = append(rs.Endpoints, es)
+		checks = append(checks, &endpointCheck{
+			receiver:    rs.Name,
+			integration: fmt.Sprintf("%s/%d", integration, counts[integration]),
+			status:      es,
+			check:       check,
+		})
+		counts[integration]++
+	}
+	addURL := func(rs *ReceiverStatus, integration, rawurl string) {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			// Do not reveal the URL as it may contain secrets.
+			add(rs, integration, "", func() error { return fmt.Errorf("invalid URL") })
+			return
+		}
+		add(rs, integration, u.Host, func() error { return c.checkURL(u) })
+	}
+
+	for _, nc := range confs {
+		rs := &ReceiverStatus{Name: nc.Name}
+		counts = map[string]int{}
+
+		for _, wc := range nc.WebhookConfigs {
+			u, err := url.Parse(wc.URL)
+			if err != nil {
+				addURL(rs, "webhook", wc.URL)
+				continue
+			}
+			add(rs, "webhook", u.Host, func() error { return c.checkWebhook(u) })
+		}
+		for _, ec := range nc.EmailConfigs {
+			ec := ec
+			add(rs, "email", ec.Smarthost, func() error { return c.checkSMTP(ec) })
+		}
+		for _, pc := range nc.PagerdutyConfigs {
+			addURL(rs, "pagerduty", pc.URL)
+		}
+		for _, oc := range nc.OpsGenieConfigs {
+			addURL(rs, "opsgenie", oc.APIHost)
+		}
+		for _, sc := range nc.SlackConfigs {
+			sc := sc
+			u, err := url.Parse(string(sc.APIURL))
+			if err != nil || sc.APIToken == "" {
+				addURL(rs, "slack", string(sc.APIURL))
+				continue
+			}
+			add(rs, "slack", u.Host, func() error { return c.checkSlack(u, string(sc.APIToken)) })
+		}
+		for _, hc := range nc.HipchatConfigs {
+			addURL(rs, "hipchat", hc.APIURL)
+		}
+		for range nc.PushoverConfigs {
+			addURL(rs, "pushover", pushoverAPIURL)
+		}
+		for _, tc := range nc.TeamsConfigs {
+			addURL(rs, "teams", string(tc.WebhookURL))
+		}
+		for _, tc := range nc.TelegramConfigs {
+			addURL(rs, "telegram", tc.APIURL)
+		}
+		for _, jc := range nc.JiraConfigs {
+			addURL(rs, "jira", jc.APIURL)
+		}
+		for _, sc := range nc.SNMPConfigs {
+			sc := sc
+			add(rs, "snmp", sc.Target, func() error { return c.checkDNS(sc.Target) })
+		}
+		for _, ac := range nc.AlertmanagerConfigs {
+			addURL(rs, "alertmanager", ac.URL)
+		}
+		statuses = append(statuses, rs)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(checks))
+
+	for _, ec := range checks {
+		go func(ec *endpointCheck) {
+			defer wg.Done()
+
+			err := ec.check()
+
+			ec.status.CheckedAt = time.Now()
+			ec.status.Healthy = err == nil
+			if err != nil {
+				ec.status.Error = err.Error()
+			}
+		}(ec)
+	}
+	wg.Wait()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// Carry over the history of endpoints checked before.
+	byName := make(map[string]*EndpointStatus, len(checks))
+	endpointUp.Reset()
+
+	for _, ec := range checks {
+		name := ec.receiver + "/" + ec.integration
+		es := ec.status
+
+		if es.Healthy {
+			es.LastHealthyAt = es.CheckedAt
+			endpointUp.WithLabelValues(ec.receiver, ec.integration).Set(1)
+		} else {
+			if prev, ok := c.byName[name]; ok && prev.Endpoint == es.Endpoint {
+				es.LastHealthyAt = prev.LastHealthyAt
+				es.ConsecutiveFailures = prev.ConsecutiveFailures
+			}
+			es.ConsecutiveFailures++
+
+			endpointUp.WithLabelValues(ec.receiver, ec.integration).Set(0)
+			endpointCheckFailures.WithLabelValues(ec.receiver, ec.integration).Inc()
+
+			log.With("receiver", ec.receiver).With("integration", ec.integration).
+				Warnf("Endpoint %q failed connectivity check: %s", es.Endpoint, es.Error)
+		}
+		byName[name] = es
+	}
+	for _, rs := range statuses {
+		rs.Healthy = true
+		for _, es := range rs.Endpoints {
+			rs.Healthy = rs.Healthy && es.Healthy
+		}
+	}
+
+	c.statuses = statuses
+	c.byName = byName
+
+	return statuses
+}
+
+// HealthMonitor periodically checks the receivers set on a Checker.
+type HealthMonitor struct {
+	checker  *Checker
+	interval time.Duration
+	stopc    chan struct{}
+}
+
+// NewHealthMonitor returns a new HealthMonitor checking the receivers of
+// the checker in the given interval.
+func NewHealthMonitor(c *Checker, interval time.Duration) *HealthMonitor {
+	return &HealthMonitor{
+		checker:  c,
+		interval: interval,
+		stopc:    make(chan struct{}),
+	}
+}
+
+// Run the HealthMonitor's background processing.
+func (m *HealthMonitor) Run() {
+	t := time.NewTicker(m.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-m.stopc:
+			return
+		case <-t.C:
+			if confs := m.checker.currentReceivers(); len(confs) > 0 {
+				m.checker.Check(confs)
+			}
+		}
+	}
+}
+
+// Stop the HealthMonitor's background processing.
+func (m *HealthMonitor) Stop() {
+	close(m.stopc)
+}
+
+// checkWebhook checks the connectivity to the webhook's host and sends it
+// a HEAD request. Client errors count as healthy as webhooks commonly
+// reject requests other than notifications.
+func (c *Checker) checkWebhook(u *url.URL) error {
+	if err := c.checkURL(u); err != nil {
+		return err
+	}
+	req, err := http.NewRequest("HEAD", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// Do not reveal the URL as it may contain secrets.
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return fmt.Errorf("HEAD request failed: %s", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// checkSlack checks the connectivity to the Slack Web API at the URL and
+// verifies the API token through the auth.test method.
+func (c *Checker) checkSlack(u *url.URL, token string) error {
+	if err := c.checkURL(u); err != nil {
+		return err
+	}
+	authTest := u.ResolveReference(&url.URL{Path: "auth.test"})
+
+	req, err := http.NewRequest("POST", authTest.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return fmt.Errorf("auth.test request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	var sresp slackResp
+	if err := json.NewDecoder(resp.Body).Decode(&sresp); err != nil {
+		return err
+	}
+	if !sresp.OK {
+		return fmt.Errorf("auth.test failed: %s", sresp.Error)
+	}
+	return nil
+}
+
+// checkURL resolves the URL's host and establishes a TCP connection to it.
+// For HTTPS URLs a TLS handshake is performed.
+func (c *Checker) checkURL(u *url.URL) error {
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host = u.Host
+		switch u.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		default:
+			return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+		}
+	}
+
+	conn, err := c.dial(host, port)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if u.Scheme == "https" {
+		tconn := tls.Client(conn, &tls.Config{ServerName: host})
+		tconn.SetDeadline(time.Now().Add(c.timeout))
+
+		if err := tconn.Handshake(); err != nil {
+			return fmt.Errorf("TLS handshake failed: %s", err)
+		}
+	}
+	return nil
+}
+
+// checkSMTP connects to the smarthost and authenticates if the server
+// supports it. No mail is sent.
+func (c *Checker) checkSMTP(conf *config.EmailConfig) error {
+	host, port, err := net.SplitHostPort(conf.Smarthost)
+	if err != nil {
+		return fmt.Errorf("invalid address: %s", err)
+	}
+
+	conn, err := c.dial(host, port)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if conf.RequireTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("require_tls: true (default), but %q does not advertise the STARTTLS extension", conf.Smarthost)
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("starttls failed: %s", err)
+		}
+	}
+
+	if ok, mech := client.Extension("AUTH"); ok {
+		auth, err := (&Email{conf: conf}).auth(mech)
+		if err != nil {
+			return err
+		}
+		if auth != nil {
+			if err := client.Auth(auth); err != nil {
+				return fmt.Errorf("%T failed: %s", auth, err)
+			}
+		}
+	}
+	return client.Quit()
+}
+
+// checkDNS resolves the host of the host:port address.
+func (c *Checker) checkDNS(hostport string) error {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return err
+	}
+	addrs, err := net.LookupHost(host)
+	if err != nil {
+		return fmt.Errorf("DNS resolution failed: %s", err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("DNS resolution of %q returned no addresses", host)
+	}
+	return nil
+}
+
+// dial resolves the host and connects to it.
+func (c *Checker) dial(host, port string) (net.Conn, error) {
+	addrs, err := net.LookupHost(host)
+	if err != nil {
+		return nil, fmt.Errorf("DNS resolution failed: %s", err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("DNS resolution of %q returned no addresses", host)
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(addrs[0], port), c.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %s", err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+var notificationCost = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "notification_cost_total",
+	Help:      "The approximate total cost of sent notifications.",
+}, []string{"integration", "receiver"})
+
+func init() {
+	prometheus.Register(notificationCost)
+}
+
+// costMonthFormat is the layout of the month keys of cost totals.
+const costMonthFormat = "2006-01"
+
+// CostAccount accumulates the approximate cost of successfully sent
+// notifications per month and receiver. With a file, the totals are
+// written to it on every change so that they survive restarts. All
+// methods are goroutine-safe.
+type CostAccount struct {
+	mtx    sync.RWMutex
+	totals map[string]map[string]float64
+	file   string
+}
+
+// NewCostAccount returns a new CostAccount persisting its totals to the
+// given file if it is not empty. Totals already in the file are loaded.
+func NewCostAccount(file string) (*CostAccount, error) {
+	ca := &CostAccount{
+		totals: map[string]map[string]float64{},
+		file:   file,
+	}
+	if file == "" {
+		return ca, nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return ca, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &ca.totals); err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
+
+// Add accounts the cost of a notification sent to the receiver at the
+// given time.
+func (ca *CostAccount) Add(receiver string, t time.Time, cost float64) {
+	if ca == nil || cost == 0 {
+		return
+	}
+	ca.mtx.Lock()
+	defer ca.mtx.Unlock()
+
+	month := t.UTC().Format(costMonthFormat)
+
+	rcvs, ok := ca.totals[month]
+	if !ok {
+		rcvs = map[string]float64{}
+		ca.totals[month] = rcvs
+	}
+	rcvs[receiver] += cost
+
+	if ca.file != "" {
+		if err := ca.write(); err != nil {
+			log.Errorf("Writing notification costs failed: %s", err)
+		}
+	}
+}
+
+// write atomically writes the totals to the file. The caller must hold
+// the lock.
+func (ca *CostAccount) write() error {
+	b, err := json.Marshal(ca.totals)
+	if err != nil {
+		return err
+	}
+	tmp := ca.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ca.file)
+}
+
+// Totals returns a copy of the cost totals keyed by month (YYYY-MM)
+// and receiver.
+func (ca *CostAccount) Totals() map[string]map[string]float64 {
+	ca.mtx.RLock()
+	defer ca.mtx.RUnlock()
+
+	res := make(map[string]map[string]float64, len(ca.totals))
+	for month, rcvs := range ca.totals {
+		res[month] = make(map[string]float64, len(rcvs))
+		for rcv, c := range rcvs {
+			res[month][rcv] = c
+		}
+	}
+	return res
+}
@@ -0,0 +1,295 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// DigestGroup is an alert group summarized by a digest notification.
+type DigestGroup struct {
+	// One of the template.Digest* statuses.
+	Status string
+	Labels model.LabelSet
+	Alerts []*types.Alert
+}
+
+// WithDigest populates a context with the groups summarized by a digest
+// notification.
+func WithDigest(ctx context.Context, groups []*DigestGroup) context.Context {
+	return context.WithValue(ctx, keyDigest, groups)
+}
+
+// Digest extracts the groups summarized by a digest notification from the
+// context. Iff none exist, the second argument is false.
+func Digest(ctx context.Context) ([]*DigestGroup, bool) {
+	v, ok := ctx.Value(keyDigest).([]*DigestGroup)
+	return v, ok
+}
+
+// digestTemplateData returns the template data of the digest groups.
+func digestTemplateData(tmpl *template.Template, recv string, groups []*DigestGroup) template.DigestGroups {
+	res := make(template.DigestGroups, 0, len(groups))
+	for _, g := range groups {
+		data := tmpl.Data(recv, g.Labels, g.Alerts...)
+		res = append(res, template.DigestGroup{
+			Status:      g.Status,
+			GroupLabels: data.GroupLabels,
+			Alerts:      data.Alerts,
+		})
+	}
+	return res
+}
+
+// DigestNotifier accumulates the notifications for a receiver and sends
+// them on as a single digest notification once per interval.
+type DigestNotifier struct {
+	receiver string
+
+	mtx      sync.Mutex
+	notifier Notifier
+	interval time.Duration
+	// The latest notification of each group since the last digest by
+	// group key.
+	pending map[string]*DigestGroup
+	// Keys of the groups that were firing in the last digest.
+	firing map[string]struct{}
+
+	done    chan struct{}
+	stopped chan struct{}
+
+	// now is an indirection for testing.
+	now func() time.Time
+}
+
+// NewDigestNotifier returns a new DigestNotifier sending digests for the
+// receiver through n. Digests are only sent once Run is called.
+func NewDigestNotifier(receiver string, interval time.Duration, n Notifier) *DigestNotifier {
+	return &DigestNotifier{
+		receiver: receiver,
+		notifier: n,
+		interval: interval,
+		pending:  map[string]*DigestGroup{},
+		firing:   map[string]struct{}{},
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		now:      time.Now,
+	}
+}
+
+// set replaces the notifier digests are sent through and the interval,
+// which takes effect after the next digest.
+func (n *DigestNotifier) set(interval time.Duration, notifier Notifier) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	n.interval = interval
+	n.notifier = notifier
+}
+
+// Notify implements the Notifier interface. It records the alerts as the
+// latest state of their group, which is notified about with the next
+// digest.
+func (n *DigestNotifier) Notify(ctx context.Context, alerts ...*types.Alert) error {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return fmt.Errorf("group key missing")
+	}
+	lset, _ := GroupLabels(ctx)
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	n.pending[key] = &DigestGroup{Labels: lset, Alerts: alerts}
+	return nil
+}
+
+// Run sends a digest once per interval until Stop is called.
+func (n *DigestNotifier) Run() {
+	defer close(n.stopped)
+
+	for {
+		n.mtx.Lock()
+		interval := n.interval
+		n.mtx.Unlock()
+
+		select {
+		case <-n.done:
+			return
+		case <-time.After(interval):
+			if err := n.Flush(); err != nil {
+				log.With("receiver", n.receiver).Errorf("Error sending digest: %s", err)
+			}
+		}
+	}
+}
+
+// Stop stops sending digests. Pending notifications are sent as a final
+// digest. It must only be called once Run was started.
+func (n *DigestNotifier) Stop() {
+	close(n.done)
+	<-n.stopped
+
+	if err := n.Flush(); err != nil {
+		log.With("receiver", n.receiver).Errorf("Error sending digest: %s", err)
+	}
+}
+
+// Flush sends a digest of the groups notified about since the last one.
+// Nothing is sent if there are none.
+func (n *DigestNotifier) Flush() error {
+	n.mtx.Lock()
+	groups := n.digest()
+	notifier, interval := n.notifier, n.interval
+	n.mtx.Unlock()
+
+	if len(groups) == 0 {
+		return nil
+	}
+
+	var (
+		alerts []*types.Alert
+		seen   = map[model.Fingerprint]struct{}{}
+	)
+	for _, g := range groups {
+		for _, a := range g.Alerts {
+			if _, ok := seen[a.Fingerprint()]; !ok {
+				seen[a.Fingerprint()] = struct{}{}
+				alerts = append(alerts, a)
+			}
+		}
+	}
+
+	timeout := interval
+	if timeout < MinTimeout {
+		timeout = MinTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ctx = WithReceiver(ctx, n.receiver)
+	ctx = WithGroupKey(ctx, "digest/"+n.receiver)
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+	// The digest interval limits how often the receiver is notified.
+	// Still firing alerts are repeated in every digest.
+	ctx = WithRepeatInterval(ctx, 0)
+	ctx = WithNow(ctx, n.now())
+	ctx = WithDigest(ctx, groups)
+
+	return notifier.Notify(ctx, alerts...)
+}
+
+// digest returns the pending groups ordered by their key with their status
+// since the last digest and resets them. Groups whose alerts are all
+// resolved are reported as resolved and forgotten.
+func (n *DigestNotifier) digest() []*DigestGroup {
+	keys := make([]string, 0, len(n.pending))
+	for k := range n.pending {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var (
+		groups = make([]*DigestGroup, 0, len(keys))
+		firing = map[string]struct{}{}
+	)
+	for _, k := range keys {
+		g := n.pending[k]
+
+		if types.Alerts(g.Alerts...).Status() == model.AlertResolved {
+			g.Status = template.DigestResolved
+		} else if _, ok := n.firing[k]; ok {
+			g.Status = template.DigestFiring
+			firing[k] = struct{}{}
+		} else {
+			g.Status = template.DigestNew
+			firing[k] = struct{}{}
+		}
+		groups = append(groups, g)
+	}
+
+	n.pending = map[string]*DigestGroup{}
+	n.firing = firing
+
+	return groups
+}
+
+// Digests holds the digest notifiers of receivers across rebuilds of the
+// notification pipeline so that pending notifications are retained.
+type Digests struct {
+	mtx       sync.Mutex
+	notifiers map[string]*DigestNotifier
+}
+
+// NewDigests returns new Digests.
+func NewDigests() *Digests {
+	return &Digests{notifiers: map[string]*DigestNotifier{}}
+}
+
+// Wrap replaces the notifiers of receivers with a digest configuration in
+// the router by their digest notifiers. The digest notifiers of receivers
+// that no longer have one are stopped after sending a final digest.
+func (d *Digests) Wrap(rcvs []*config.Receiver, router Router) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	notifiers := map[string]*DigestNotifier{}
+
+	for _, rc := range rcvs {
+		if rc.Digest == nil {
+			continue
+		}
+		var (
+			interval = time.Duration(rc.Digest.Interval)
+			dn, ok   = d.notifiers[rc.Name]
+		)
+		if ok {
+			dn.set(interval, router[rc.Name])
+		} else {
+			dn = NewDigestNotifier(rc.Name, interval, router[rc.Name])
+			go dn.Run()
+		}
+		notifiers[rc.Name] = dn
+		router[rc.Name] = dn
+	}
+
+	for name, dn := range d.notifiers {
+		if _, ok := notifiers[name]; !ok {
+			go dn.Stop()
+		}
+	}
+	d.notifiers = notifiers
+}
+
+// Stop stops all digest notifiers after sending their final digests.
+func (d *Digests) Stop() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	for _, dn := range d.notifiers {
+		dn.Stop()
+	}
+	d.notifiers = map[string]*DigestNotifier{}
+}
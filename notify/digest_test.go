@@ -0,0 +1,162 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestDigestNotifier(t *testing.T) {
+	var (
+		now = time.Now()
+		rec = &recordNotifier{}
+		n   = NewDigestNotifier("team-X", time.Hour, rec)
+
+		firing = func(name string) *types.Alert {
+			return &types.Alert{Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": model.LabelValue(name)},
+				StartsAt: now.Add(-time.Hour),
+			}}
+		}
+		resolved = func(name string) *types.Alert {
+			a := firing(name)
+			a.EndsAt = now.Add(-time.Minute)
+			return a
+		}
+		notify = func(name string, alerts ...*types.Alert) {
+			ctx := WithGroupKey(context.Background(), "team-X/{}:{alertname=\""+name+"\"}")
+			ctx = WithGroupLabels(ctx, model.LabelSet{"alertname": model.LabelValue(name)})
+			if err := n.Notify(ctx, alerts...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		statuses = func() map[model.LabelValue]string {
+			groups, ok := Digest(rec.ctx)
+			if !ok {
+				t.Fatalf("expected digest groups in context")
+			}
+			res := map[model.LabelValue]string{}
+			for _, g := range groups {
+				res[g.Labels["alertname"]] = g.Status
+			}
+			return res
+		}
+	)
+	n.now = func() time.Time { return now }
+
+	notify("A", firing("A"))
+	notify("B", firing("B"))
+	// Only the latest notification of a group is kept.
+	notify("B", firing("B"))
+
+	if err := n.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.alerts) != 2 {
+		t.Fatalf("expected 2 alerts in digest but got %d", len(rec.alerts))
+	}
+	expected := map[model.LabelValue]string{"A": template.DigestNew, "B": template.DigestNew}
+	if got := statuses(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected group statuses %v but got %v", expected, got)
+	}
+	if r, _ := Receiver(rec.ctx); r != "team-X" {
+		t.Errorf("expected digest for receiver %q but got %q", "team-X", r)
+	}
+
+	notify("A", firing("A"))
+	notify("B", resolved("B"))
+	notify("C", firing("C"))
+
+	rec.alerts = nil
+	if err := n.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected = map[model.LabelValue]string{"A": template.DigestFiring, "B": template.DigestResolved, "C": template.DigestNew}
+	if got := statuses(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected group statuses %v but got %v", expected, got)
+	}
+
+	// Nothing is sent without notifications since the last digest.
+	rec.alerts = nil
+	rec.ctx = nil
+	if err := n.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if rec.ctx != nil {
+		t.Errorf("expected no digest but got %d alerts", len(rec.alerts))
+	}
+
+	// Resolved groups are forgotten and reported as new when firing again.
+	notify("B", firing("B"))
+	if err := n.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected = map[model.LabelValue]string{"B": template.DigestNew}
+	if got := statuses(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected group statuses %v but got %v", expected, got)
+	}
+}
+
+func TestDigestTemplateData(t *testing.T) {
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+	ctx = WithDigest(ctx, []*DigestGroup{
+		{
+			Status: template.DigestNew,
+			Labels: model.LabelSet{"alertname": "A"},
+			Alerts: []*types.Alert{{Alert: model.Alert{Labels: model.LabelSet{"alertname": "A"}}}},
+		},
+		{
+			Status: template.DigestResolved,
+			Labels: model.LabelSet{"alertname": "B"},
+		},
+	})
+
+	data := tmplData(ctx, tmpl, &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "A"}}})
+	if len(data.Digest) != 2 || data.Digest[0].GroupLabels["alertname"] != "A" || len(data.Digest[0].Alerts) != 1 {
+		t.Fatalf("unexpected digest data %+v", data.Digest)
+	}
+
+	subject, err := tmpl.ExecuteTextString(`{{ template "__subject" . }}`, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[DIGEST] 1 new, 0 firing, 1 resolved alert groups"; subject != expected {
+		t.Errorf("expected subject %q but got %q", expected, subject)
+	}
+
+	html, err := tmpl.ExecuteHTMLString(`{{ template "email.default.html" . }}`, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, "[1] New groups") || !strings.Contains(html, "[1] Resolved groups") {
+		t.Errorf("expected digest groups in email body but got %s", html)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+var numDryRunNotifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "notifications_dry_run_total",
+	Help:      "The total number of notifications recorded instead of being sent in dry-run mode.",
+}, []string{"receiver"})
+
+func init() {
+	prometheus.Register(numDryRunNotifications)
+}
+
+// DryRunNotification is a notification that would have been sent to an
+// integration of a receiver.
+type DryRunNotification struct {
+	Receiver    string         `json:"receiver"`
+	GroupKey    string         `json:"groupKey"`
+	GroupLabels model.LabelSet `json:"groupLabels"`
+	Alerts      []*types.Alert `json:"alerts"`
+	Time        time.Time      `json:"time"`
+}
+
+// DryRuns records the notifications of integrations in dry-run mode. It
+// retains the given number of most recent notifications. All methods are
+// goroutine-safe.
+type DryRuns struct {
+	mtx           sync.RWMutex
+	size          int
+	notifications []*DryRunNotification
+}
+
+// NewDryRuns returns new DryRuns retaining up to size notifications.
+func NewDryRuns(size int) *DryRuns {
+	return &DryRuns{size: size}
+}
+
+// Notifier returns a notifier recording and logging the notifications it
+// is called with instead of sending them.
+func (d *DryRuns) Notifier() Notifier {
+	return NotifierFunc(func(ctx context.Context, alerts ...*types.Alert) error {
+		receiver, _ := Receiver(ctx)
+		groupKey, _ := GroupKey(ctx)
+		groupLabels, _ := GroupLabels(ctx)
+		now, ok := Now(ctx)
+		if !ok {
+			now = time.Now()
+		}
+
+		log.With("receiver", receiver).With("groupKey", groupKey).
+			Infof("Dry run: not sending notification about %d alerts", len(alerts))
+		numDryRunNotifications.WithLabelValues(receiver).Inc()
+
+		d.add(&DryRunNotification{
+			Receiver:    receiver,
+			GroupKey:    groupKey,
+			GroupLabels: groupLabels,
+			Alerts:      alerts,
+			Time:        now,
+		})
+		return nil
+	})
+}
+
+func (d *DryRuns) add(n *DryRunNotification) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.notifications = append(d.notifications, n)
+	if len(d.notifications) > d.size {
+		d.notifications = d.notifications[len(d.notifications)-d.size:]
+	}
+}
+
+// All returns the retained notifications, most recent first.
+func (d *DryRuns) All() []*DryRunNotification {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	res := make([]*DryRunNotification, 0, len(d.notifications))
+	for i := len(d.notifications) - 1; i >= 0; i-- {
+		res = append(res, d.notifications[i])
+	}
+	return res
+}
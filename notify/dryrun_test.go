@@ -0,0 +1,54 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestDryRuns(t *testing.T) {
+	var (
+		d   = NewDryRuns(2)
+		n   = d.Notifier()
+		now = time.Now()
+	)
+	for _, key := range []string{"a", "b", "c"} {
+		ctx := WithReceiver(context.Background(), "team-X/webhook/0")
+		ctx = WithGroupKey(ctx, key)
+		ctx = WithGroupLabels(ctx, model.LabelSet{"alertname": "HighLatency"})
+		ctx = WithNow(ctx, now)
+
+		a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "HighLatency"}}}
+		if err := n.Notify(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all := d.All()
+	if len(all) != 2 {
+		t.Fatalf("expected 2 retained notifications but got %d", len(all))
+	}
+	if all[0].GroupKey != "c" || all[1].GroupKey != "b" {
+		t.Errorf("expected most recent notifications first but got %q and %q", all[0].GroupKey, all[1].GroupKey)
+	}
+	if all[0].Receiver != "team-X/webhook/0" || len(all[0].Alerts) != 1 || !all[0].Time.Equal(now) {
+		t.Errorf("unexpected notification %+v", all[0])
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/tracing"
+)
+
+const (
+	// emailConnIdleTimeout is how long idle SMTP connections are kept
+	// open for reuse.
+	emailConnIdleTimeout = time.Minute
+
+	// maxEmailImageSize is the maximum size of images embedded into
+	// emails.
+	maxEmailImageSize = 4 << 20
+)
+
+// emailConns are the idle SMTP connections of all email notifiers. They
+// outlive the notifiers, which are recreated on configuration reloads.
+var emailConns = &smtpPool{idle: map[string][]idleSMTPConn{}}
+
+type idleSMTPConn struct {
+	c     *smtp.Client
+	since time.Time
+}
+
+// smtpPool keeps idle SMTP connections by the smarthost and credentials
+// they were established with.
+type smtpPool struct {
+	mtx  sync.Mutex
+	idle map[string][]idleSMTPConn
+}
+
+// smtpPoolKey returns the key of connections established with the email
+// config.
+func smtpPoolKey(c *config.EmailConfig) string {
+	h := sha256.New()
+	for _, s := range []string{
+		c.Smarthost,
+		c.AuthUsername,
+		string(c.AuthPassword),
+		string(c.AuthSecret),
+		c.AuthIdentity,
+		strconv.FormatBool(c.RequireTLS),
+	} {
+		io.WriteString(h, s)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the most recently used idle connection with the given key or
+// nil if there is none.
+func (p *smtpPool) get(key string, now time.Time) *smtp.Client {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.expire(now)
+
+	conns := p.idle[key]
+	if len(conns) == 0 {
+		return nil
+	}
+	ic := conns[len(conns)-1]
+	if len(conns) == 1 {
+		delete(p.idle, key)
+	} else {
+		p.idle[key] = conns[:len(conns)-1]
+	}
+	return ic.c
+}
+
+// put adds the connection to the idle connections with the given key
+// unless there are max of them already. It returns false if the
+// connection was not added.
+func (p *smtpPool) put(key string, c *smtp.Client, max int, now time.Time) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.expire(now)
+
+	if len(p.idle[key]) >= max {
+		return false
+	}
+	p.idle[key] = append(p.idle[key], idleSMTPConn{c: c, since: now})
+	return true
+}
+
+// expire closes the connections idle for longer than the idle timeout.
+func (p *smtpPool) expire(now time.Time) {
+	for key, conns := range p.idle {
+		i := 0
+		for ; i < len(conns) && now.Sub(conns[i].since) >= emailConnIdleTimeout; i++ {
+			conns[i].c.Close()
+		}
+		if i == len(conns) {
+			delete(p.idle, key)
+		} else if i > 0 {
+			p.idle[key] = conns[i:]
+		}
+	}
+}
+
+// emailPart is a MIME part of an email with its body already encoded.
+type emailPart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+func htmlPart(html string) (emailPart, error) {
+	var b bytes.Buffer
+	qw := quotedprintable.NewWriter(&b)
+	if _, err := io.WriteString(qw, html); err != nil {
+		return emailPart{}, err
+	}
+	if err := qw.Close(); err != nil {
+		return emailPart{}, err
+	}
+	return emailPart{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {"text/html; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		},
+		body: b.Bytes(),
+	}, nil
+}
+
+func base64Part(contentType, disposition, filename string, b []byte) emailPart {
+	enc := base64.StdEncoding.EncodeToString(b)
+
+	var buf bytes.Buffer
+	for len(enc) > 76 {
+		buf.WriteString(enc[:76])
+		buf.WriteString("\r\n")
+		enc = enc[76:]
+	}
+	buf.WriteString(enc)
+
+	return emailPart{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType(disposition, map[string]string{"filename": filename})},
+		},
+		body: buf.Bytes(),
+	}
+}
+
+// multipartPart returns a part holding the parts as a multipart body of
+// the given subtype.
+func multipartPart(subtype string, parts ...emailPart) (emailPart, error) {
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	for _, p := range parts {
+		pw, err := mw.CreatePart(p.header)
+		if err != nil {
+			return emailPart{}, err
+		}
+		if _, err := pw.Write(p.body); err != nil {
+			return emailPart{}, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return emailPart{}, err
+	}
+	return emailPart{
+		header: textproto.MIMEHeader{
+			"Content-Type": {mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": mw.Boundary()})},
+		},
+		body: b.Bytes(),
+	}, nil
+}
+
+// emailImages fetches the images of the email config as parts to be
+// referenced by the HTML body. Images that cannot be fetched are left out
+// so that the email is sent nonetheless.
+func emailImages(ctx context.Context, tmpl *template.Template, c *config.EmailConfig, data *template.Data) []emailPart {
+	var res []emailPart
+	for _, img := range c.Images {
+		u, err := tmpl.ExecuteTextString(img.URL, data)
+		if err != nil {
+			log.Warnf("Executing URL template of email image %q failed: %s", img.Name, err)
+			continue
+		}
+		b, contentType, err := fetchImage(ctx, u)
+		if err != nil {
+			log.Warnf("Fetching email image %q failed: %s", img.Name, err)
+			continue
+		}
+		p := base64Part(contentType, "inline", img.Name, b)
+		p.header.Set("Content-ID", "<"+img.Name+">")
+
+		res = append(res, p)
+	}
+	return res
+}
+
+// fetchImage returns the image at the URL and its content type.
+func fetchImage(ctx context.Context, url string) ([]byte, string, error) {
+	resp, err := tracing.Get(ctx, http.DefaultClient, url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, "", fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxEmailImageSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(b) > maxEmailImageSize {
+		return nil, "", fmt.Errorf("image larger than %d bytes", maxEmailImageSize)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mt, _, err := mime.ParseMediaType(contentType); err != nil || !strings.HasPrefix(mt, "image/") {
+		contentType = http.DetectContentType(b)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("unexpected content type %q", contentType)
+	}
+	return b, contentType, nil
+}
+
+// alertsCSV returns a CSV file listing the alerts with a column for each
+// of their label and annotation names.
+func alertsCSV(alerts template.Alerts) ([]byte, error) {
+	var (
+		labels      = map[string]struct{}{}
+		annotations = map[string]struct{}{}
+	)
+	for _, a := range alerts {
+		for ln := range a.Labels {
+			labels[ln] = struct{}{}
+		}
+		for an := range a.Annotations {
+			annotations[an] = struct{}{}
+		}
+	}
+	sorted := func(m map[string]struct{}) []string {
+		res := make([]string, 0, len(m))
+		for k := range m {
+			res = append(res, k)
+		}
+		sort.Strings(res)
+		return res
+	}
+	lnames, anames := sorted(labels), sorted(annotations)
+
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+
+	header := []string{"status", "starts_at", "ends_at", "generator_url"}
+	for _, ln := range lnames {
+		header = append(header, "labels."+ln)
+	}
+	for _, an := range anames {
+		header = append(header, "annotations."+an)
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	for _, a := range alerts {
+		rec := []string{a.Status, formatTime(a.StartsAt), formatTime(a.EndsAt), a.GeneratorURL}
+		for _, ln := range lnames {
+			rec = append(rec, a.Labels[ln])
+		}
+		for _, an := range anames {
+			rec = append(rec, a.Annotations[an])
+		}
+		if err := w.Write(rec); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+
+	return b.Bytes(), w.Error()
+}
@@ -0,0 +1,306 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func newEmailTestTemplate(t *testing.T) *template.Template {
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+	return tmpl
+}
+
+func TestEmailMessage(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("alert") != "HighLatency" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(png)
+	}))
+	defer srv.Close()
+
+	n := NewEmail(&config.EmailConfig{
+		To:      "team-x@example.com",
+		From:    "am@example.com",
+		Headers: map[string]string{},
+		HTML:    `config`,
+		Images: []*config.EmailImage{
+			{Name: "chart", URL: srv.URL + `/?alert={{ .GroupLabels.alertname }}`},
+			{Name: "missing", URL: srv.URL + `/missing`},
+		},
+		AttachCSV: true,
+	}, newEmailTestTemplate(t))
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	ctx = WithGroupLabels(ctx, model.LabelSet{"alertname": "HighLatency"})
+	ctx = WithEmailHTML(ctx, `<img src="cid:chart"> {{ len .Alerts }} alerts`)
+
+	alerts := []*types.Alert{
+		{Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "HighLatency", "instance": "a"},
+			StartsAt: time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC),
+		}},
+		{Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "HighLatency", "instance": "b"},
+			Annotations: model.LabelSet{"summary": "slow, very slow"},
+			StartsAt:    time.Date(2024, 1, 6, 3, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	b, err := n.message(ctx, tmplData(ctx, n.tmpl, alerts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to := msg.Header.Get("To"); to != "team-x@example.com" {
+		t.Errorf("expected To header %q but got %q", "team-x@example.com", to)
+	}
+
+	// The message is a mixed multipart of the related multipart holding the
+	// HTML body and the images, and the CSV attachment.
+	parts := readMultipart(t, msg.Header.Get("Content-Type"), msg.Body, "multipart/mixed")
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts but got %d", len(parts))
+	}
+	related := readMultipart(t, parts[0].header.Get("Content-Type"), bytes.NewReader(parts[0].body), "multipart/related")
+	if len(related) != 2 {
+		t.Fatalf("expected HTML body and one image but got %d parts", len(related))
+	}
+
+	if exp := `<img src="cid:chart"> 2 alerts`; string(related[0].body) != exp {
+		t.Errorf("expected HTML body %q but got %q", exp, related[0].body)
+	}
+	if cid := related[1].header.Get("Content-ID"); cid != "<chart>" {
+		t.Errorf("expected image with content ID <chart> but got %q", cid)
+	}
+	if ct := related[1].header.Get("Content-Type"); ct != "image/png" {
+		t.Errorf("expected image of type image/png but got %q", ct)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(parts[1].base64Body(t))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"status", "starts_at", "ends_at", "generator_url", "labels.alertname", "labels.instance", "annotations.summary"},
+		{"firing", "2024-01-06T02:00:00Z", "", "", "HighLatency", "a", ""},
+		{"firing", "2024-01-06T03:00:00Z", "", "", "HighLatency", "b", "slow, very slow"},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected CSV %v but got %v", expected, records)
+	}
+
+	// Without images and attachments, the message only holds the HTML body.
+	n.conf.Images, n.conf.AttachCSV = nil, false
+
+	if b, err = n.message(ctx, tmplData(ctx, n.tmpl, alerts...)); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err = mail.ReadMessage(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	if ct := msg.Header.Get("Content-Type"); ct != "text/html; charset=UTF-8" {
+		t.Errorf("expected HTML body but got content type %q", ct)
+	}
+}
+
+type testPart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// base64Body returns the decoded body of a base64 encoded part.
+func (p testPart) base64Body(t *testing.T) []byte {
+	b, err := base64.StdEncoding.DecodeString(strings.Replace(string(p.body), "\r\n", "", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// readMultipart returns the parts of a multipart body of the expected
+// content type. Quoted-printable bodies are decoded.
+func readMultipart(t *testing.T, contentType string, r io.Reader, expected string) []testPart {
+	mt, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mt != expected {
+		t.Fatalf("expected content type %s but got %s", expected, mt)
+	}
+
+	var res []testPart
+	mr := multipart.NewReader(r, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, testPart{header: p.Header, body: b})
+	}
+	return res
+}
+
+// smtpTestServer is a minimal SMTP server that accepts all messages.
+type smtpTestServer struct {
+	ln net.Listener
+
+	mtx      sync.Mutex
+	conns    int
+	messages []string
+}
+
+func newSMTPTestServer(t *testing.T) *smtpTestServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpTestServer{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mtx.Lock()
+			s.conns++
+			s.mtx.Unlock()
+
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpTestServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	var (
+		r   = textproto.NewReader(bufio.NewReader(conn))
+		w   = textproto.NewWriter(bufio.NewWriter(conn))
+		out = func(l string) { w.PrintfLine("%s", l) }
+	)
+	out("220 localhost")
+	for {
+		l, err := r.ReadLine()
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(strings.SplitN(l, " ", 2)[0]) {
+		case "EHLO", "HELO", "MAIL", "RCPT", "RSET", "NOOP":
+			out("250 OK")
+		case "DATA":
+			out("354 Go ahead")
+			b, err := r.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mtx.Lock()
+			s.messages = append(s.messages, string(b))
+			s.mtx.Unlock()
+			out("250 OK")
+		case "QUIT":
+			out("221 Bye")
+			return
+		default:
+			out("502 Unknown command")
+		}
+	}
+}
+
+func TestEmailConnectionReuse(t *testing.T) {
+	srv := newSMTPTestServer(t)
+	defer srv.ln.Close()
+
+	conf := &config.EmailConfig{
+		To:           "team-x@example.com",
+		From:         "am@example.com",
+		Smarthost:    srv.ln.Addr().String(),
+		Headers:      map[string]string{},
+		HTML:         `{{ len .Alerts }} alerts`,
+		MaxIdleConns: 1,
+	}
+	n := NewEmail(conf, newEmailTestTemplate(t))
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+
+	alert := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "HighLatency"},
+		StartsAt: time.Now(),
+	}}
+	for i := 0; i < 3; i++ {
+		if err := n.Notify(ctx, alert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv.mtx.Lock()
+	conns, messages := srv.conns, len(srv.messages)
+	srv.mtx.Unlock()
+
+	if messages != 3 {
+		t.Fatalf("expected 3 messages but got %d", messages)
+	}
+	if conns != 1 {
+		t.Fatalf("expected a single connection but got %d", conns)
+	}
+
+	// Idle connections are closed after the idle timeout.
+	if c := emailConns.get(n.poolKey, time.Now().Add(emailConnIdleTimeout)); c != nil {
+		t.Fatalf("expected idle connection to expire")
+	}
+}
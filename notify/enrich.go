@@ -0,0 +1,135 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// An EnrichRule adds annotations rendered from templates to the alerts
+// matching its matchers.
+type EnrichRule struct {
+	Matchers    types.Matchers
+	Annotations map[model.LabelName]string
+	Overwrite   bool
+}
+
+// NewEnrichRule returns a new EnrichRule based on a configuration definition.
+func NewEnrichRule(cr *config.EnrichmentRule) *EnrichRule {
+	var matchers types.Matchers
+
+	for ln, lv := range cr.Match {
+		matchers = append(matchers, types.NewMatcher(model.LabelName(ln), lv))
+	}
+	for ln, lv := range cr.MatchRE {
+		matchers = append(matchers, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
+	}
+
+	annotations := map[model.LabelName]string{}
+	for ln, tmpl := range cr.Annotations {
+		annotations[model.LabelName(ln)] = tmpl
+	}
+
+	return &EnrichRule{
+		Matchers:    matchers,
+		Annotations: annotations,
+		Overwrite:   cr.Overwrite,
+	}
+}
+
+// EnrichNotifier adds the annotations of matching enrichment rules to
+// alerts before passing them on to the next Notifier.
+type EnrichNotifier struct {
+	notifier Notifier
+	tmpl     *template.Template
+	rules    []*EnrichRule
+}
+
+// Enrich returns a new EnrichNotifier applying the configured rules.
+func Enrich(crs []*config.EnrichmentRule, tmpl *template.Template, n Notifier) *EnrichNotifier {
+	en := &EnrichNotifier{notifier: n, tmpl: tmpl}
+	for _, cr := range crs {
+		en.rules = append(en.rules, NewEnrichRule(cr))
+	}
+	return en
+}
+
+// Notify implements the Notifier interface.
+func (n *EnrichNotifier) Notify(ctx context.Context, alerts ...*types.Alert) error {
+	if len(n.rules) == 0 {
+		return n.notifier.Notify(ctx, alerts...)
+	}
+	obs := observeStage(ctx, StageEnrich, len(alerts))
+
+	enriched := make([]*types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		enriched = append(enriched, n.enrich(a))
+	}
+	obs.done(len(enriched), nil)
+
+	return n.notifier.Notify(ctx, enriched...)
+}
+
+// enrich returns a copy of the alert with the annotations of all matching
+// rules added. Rules earlier in the configuration take precedence. The
+// alert itself is returned if no annotation is added.
+func (n *EnrichNotifier) enrich(a *types.Alert) *types.Alert {
+	var (
+		annotations model.LabelSet
+		data        *template.Alert
+	)
+	for _, r := range n.rules {
+		if !r.Matchers.Match(a.Labels) {
+			continue
+		}
+		if data == nil {
+			data = &n.tmpl.Data("", a.Labels, a).Alerts[0]
+		}
+		for ln, text := range r.Annotations {
+			if _, ok := annotations[ln]; ok {
+				continue
+			}
+			if _, ok := a.Annotations[ln]; ok && !r.Overwrite {
+				continue
+			}
+			v, err := n.tmpl.ExecuteTextString(text, data)
+			if err != nil {
+				log.With("alert", a).Errorf("Executing template of annotation %q failed: %s", ln, err)
+				continue
+			}
+			if v == "" {
+				continue
+			}
+			if annotations == nil {
+				annotations = model.LabelSet{}
+			}
+			annotations[ln] = model.LabelValue(v)
+		}
+	}
+	if annotations == nil {
+		return a
+	}
+
+	// Alerts are shared with the aggregation group and must not be
+	// modified.
+	res := *a
+	res.Annotations = a.Annotations.Merge(annotations)
+	return &res
+}
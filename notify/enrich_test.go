@@ -0,0 +1,98 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestEnrichNotifier(t *testing.T) {
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.ExternalURL, err = url.Parse("http://am.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		rec = &recordNotifier{}
+		n   = Enrich([]*config.EnrichmentRule{
+			{
+				Match: map[string]string{"team": "infra"},
+				Annotations: map[string]string{
+					"runbook_url":   "https://runbooks.example.com/{{ .Labels.alertname }}",
+					"dashboard_url": "https://grafana.example.com/d/nodes?var-instance={{ .Labels.instance | urlquery }}",
+					"summary":       "{{ .Labels.alertname }} on {{ .Labels.instance }}",
+				},
+			}, {
+				Annotations: map[string]string{
+					"runbook_url": "https://runbooks.example.com/default",
+					"summary":     "replaced",
+					"empty":       "{{ .Labels.missing }}",
+				},
+				Overwrite: true,
+			},
+		}, tmpl, rec)
+
+		infra = &types.Alert{Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "NodeDown", "team": "infra", "instance": "node-1:9100"},
+			Annotations: model.LabelSet{"summary": "custom"},
+		}}
+		other = &types.Alert{Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "HighLatency"},
+		}}
+	)
+	if err := n.Notify(context.Background(), infra, other); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.alerts) != 2 {
+		t.Fatalf("expected 2 alerts but got %d", len(rec.alerts))
+	}
+
+	// Earlier rules take precedence and existing annotations are only
+	// replaced by rules overwriting them.
+	exp := model.LabelSet{
+		"runbook_url":   "https://runbooks.example.com/NodeDown",
+		"dashboard_url": "https://grafana.example.com/d/nodes?var-instance=node-1%3A9100",
+		"summary":       "replaced",
+	}
+	if !reflect.DeepEqual(rec.alerts[0].Annotations, exp) {
+		t.Errorf("expected annotations %v but got %v", exp, rec.alerts[0].Annotations)
+	}
+	exp = model.LabelSet{
+		"runbook_url": "https://runbooks.example.com/default",
+		"summary":     "replaced",
+	}
+	if !reflect.DeepEqual(rec.alerts[1].Annotations, exp) {
+		t.Errorf("expected annotations %v but got %v", exp, rec.alerts[1].Annotations)
+	}
+
+	// The alerts of the aggregation group are not modified.
+	if !reflect.DeepEqual(infra.Annotations, model.LabelSet{"summary": "custom"}) || other.Annotations != nil {
+		t.Errorf("expected original alerts to be unchanged but got %v and %v", infra.Annotations, other.Annotations)
+	}
+	if rec.alerts[0].Fingerprint() != infra.Fingerprint() {
+		t.Errorf("expected enriched alert to keep its fingerprint")
+	}
+}
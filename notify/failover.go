@@ -0,0 +1,101 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var numFailovers = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "notifications_failed_over_total",
+	Help:      "The total number of notifications delivered to a fallback receiver after the retry budget was exhausted.",
+}, []string{"receiver", "fallback"})
+
+func init() {
+	prometheus.Register(numFailovers)
+}
+
+// FailoverNotifier delivers notifications to a fallback receiver if the
+// wrapped notifier gave up on them after exhausting its retry budget.
+type FailoverNotifier struct {
+	notifier Notifier
+	fallback string
+	// Notifies receivers by the name in the context.
+	router   Notifier
+	notifies provider.Notifies
+}
+
+// Failover wraps a notifier in a FailoverNotifier delivering to the named
+// fallback receiver through the router. Successful failovers are recorded
+// in the notification log for the wrapped notifier's receiver.
+func Failover(notifies provider.Notifies, n Notifier, fallback string, router Notifier) *FailoverNotifier {
+	return &FailoverNotifier{
+		notifier: n,
+		fallback: fallback,
+		router:   router,
+		notifies: notifies,
+	}
+}
+
+// Notify implements the Notifier interface.
+func (n *FailoverNotifier) Notify(ctx context.Context, alerts ...*types.Alert) error {
+	err := n.notifier.Notify(ctx, alerts...)
+	if err == nil || !RetryBudgetExhausted(err) {
+		return err
+	}
+
+	receiver, ok := Receiver(ctx)
+	if !ok {
+		return fmt.Errorf("notifier name missing")
+	}
+	now, ok := Now(ctx)
+	if !ok {
+		return fmt.Errorf("now time missing")
+	}
+
+	log.With("receiver", receiver).With("fallback", n.fallback).
+		Warnf("Failing over notification about %d alerts: %s", len(alerts), err)
+
+	if ferr := n.router.Notify(WithReceiver(ctx, n.fallback), alerts...); ferr != nil {
+		// Keep the retry budget error so the notification is handled as
+		// given up on.
+		me := &types.MultiError{}
+		me.Add(err)
+		me.Add(fmt.Errorf("failover to %q failed: %s", n.fallback, ferr))
+		return me
+	}
+	numFailovers.WithLabelValues(receiver, n.fallback).Inc()
+
+	// The notification counts as sent so that it is not repeated before
+	// the repeat interval.
+	infos := make([]*types.NotifyInfo, 0, len(alerts))
+	for _, a := range alerts {
+		infos = append(infos, &types.NotifyInfo{
+			Alert:      a.Fingerprint(),
+			Receiver:   receiver,
+			Resolved:   a.Resolved(),
+			Timestamp:  now,
+			FailoverTo: n.fallback,
+		})
+	}
+	return n.notifies.Set(infos...)
+}
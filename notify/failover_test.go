@@ -0,0 +1,76 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestFailoverNotifier(t *testing.T) {
+	var (
+		now      = time.Now()
+		notifies = provider.NewMemNotifies(provider.NewMemData())
+		fallback = &recordNotifier{}
+		router   = Router{"team-X-mails": fallback}
+		primErr  error
+
+		n = Failover(notifies, NotifierFunc(func(ctx context.Context, alerts ...*types.Alert) error {
+			return primErr
+		}), "team-X-mails", router)
+
+		a   = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "A"}}}
+		ctx = WithReceiver(context.Background(), "team-X-slack/slack/0")
+	)
+	ctx = WithGroupKey(ctx, "team-X-slack/{}:{}")
+	ctx = WithNow(ctx, now)
+
+	// Other errors are not failed over.
+	primErr = fmt.Errorf("some error")
+	if err := n.Notify(ctx, a); err != primErr {
+		t.Fatalf("expected error %v but got %v", primErr, err)
+	}
+	if fallback.ctx != nil {
+		t.Fatalf("expected no notification to the fallback receiver")
+	}
+
+	primErr = &RetryBudgetError{Attempts: 3, Err: fmt.Errorf("some error")}
+	if err := n.Notify(ctx, a); err != nil {
+		t.Fatalf("expected failover to succeed but got %v", err)
+	}
+	if r, _ := Receiver(fallback.ctx); r != "team-X-mails" || len(fallback.alerts) != 1 {
+		t.Fatalf("expected alert to be sent to team-X-mails but got %d alerts to %q", len(fallback.alerts), r)
+	}
+
+	infos, err := notifies.Get("team-X-slack/slack/0", a.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ni := infos[0]; ni == nil || ni.FailoverTo != "team-X-mails" || !ni.Timestamp.Equal(now) {
+		t.Fatalf("expected failover to be recorded in the notification log but got %v", ni)
+	}
+
+	// Failed failovers are still given up on.
+	n.router = Router{}
+	if err := n.Notify(ctx, a); !RetryBudgetExhausted(err) {
+		t.Fatalf("expected retry budget error but got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// NewHTTPClient returns a client sending requests as configured. Without a
+// configuration, http.DefaultClient is returned.
+func NewHTTPClient(c *config.HTTPClientConfig) (*http.Client, error) {
+	if c == nil {
+		return http.DefaultClient, nil
+	}
+	tlsConf, err := newTLSConfig(&c.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transport is set up like http.DefaultTransport. Transports with
+	// a custom TLS configuration speak HTTP/1.1 only.
+	tr := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout:   time.Duration(c.ConnectTimeout),
+			KeepAlive: 30 * time.Second,
+		}).Dial,
+		TLSClientConfig:     tlsConf,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	if c.ProxyURL != "" {
+		u, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		tr.Proxy = http.ProxyURL(u)
+	}
+
+	return &http.Client{
+		Transport: tr,
+		Timeout:   time.Duration(c.Timeout),
+	}, nil
+}
+
+// newTLSConfig returns the TLS configuration loading the configured
+// certificates.
+func newTLSConfig(c *config.TLSConfig) (*tls.Config, error) {
+	tc := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		b, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+		tc.RootCAs = pool
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate failed: %s", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
+
+// HTTPClients holds the HTTP clients of the receivers.
+type HTTPClients struct {
+	mtx     sync.RWMutex
+	clients map[string]*http.Client
+}
+
+// NewHTTPClients returns new HTTPClients without clients.
+func NewHTTPClients() *HTTPClients {
+	return &HTTPClients{clients: map[string]*http.Client{}}
+}
+
+// Load creates the clients of the receivers' HTTP configurations, reading
+// their certificates anew. If one of the clients cannot be created, the
+// previously loaded clients are kept.
+func (hc *HTTPClients) Load(confs []*config.Receiver) error {
+	clients := map[string]*http.Client{}
+	for _, rc := range confs {
+		if rc.HTTPConfig == nil {
+			continue
+		}
+		c, err := NewHTTPClient(rc.HTTPConfig)
+		if err != nil {
+			return fmt.Errorf("creating HTTP client of receiver %q failed: %s", rc.Name, err)
+		}
+		clients[rc.Name] = c
+	}
+
+	hc.mtx.Lock()
+	defer hc.mtx.Unlock()
+
+	hc.clients = clients
+	return nil
+}
+
+// Client returns the HTTP client of the named receiver, which is
+// http.DefaultClient if the receiver has no HTTP configuration.
+func (hc *HTTPClients) Client(rcv string) *http.Client {
+	hc.mtx.RLock()
+	defer hc.mtx.RUnlock()
+
+	if c, ok := hc.clients[rcv]; ok {
+		return c
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+func TestHTTPClients(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpclient_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var proto string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.Proto
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.TLS.Certificates[0].Certificate[0]})
+	if err := ioutil.WriteFile(caFile, ca, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	clients := NewHTTPClients()
+	if err := clients.Load([]*config.Receiver{
+		{Name: "tls", HTTPConfig: &config.HTTPClientConfig{TLSConfig: config.TLSConfig{CAFile: caFile}, ConnectTimeout: model.Duration(time.Second)}},
+		{Name: "proxy", HTTPConfig: &config.HTTPClientConfig{ProxyURL: proxy.URL}},
+		{Name: "default"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Servers are verified against the configured CA.
+	resp, err := clients.Client("tls").Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request of receiver %q failed: %s", "tls", err)
+	}
+	resp.Body.Close()
+	if proto != "HTTP/1.1" {
+		t.Errorf("expected protocol %s but got %s", "HTTP/1.1", proto)
+	}
+	if _, err := clients.Client("default").Get(srv.URL); err == nil {
+		t.Errorf("expected certificate of test server to be rejected by default client")
+	}
+
+	resp, err = clients.Client("proxy").Get("http://receiver.example.com/notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if proxied != "http://receiver.example.com/notify" {
+		t.Errorf("expected request to be sent through proxy but got %q", proxied)
+	}
+
+	// Clients are kept if loading a configuration fails.
+	err = clients.Load([]*config.Receiver{
+		{Name: "tls", HTTPConfig: &config.HTTPClientConfig{TLSConfig: config.TLSConfig{CAFile: filepath.Join(dir, "missing.pem")}}},
+	})
+	if err == nil {
+		t.Fatalf("expected loading of missing CA file to fail")
+	}
+	if c := clients.Client("tls"); c == http.DefaultClient {
+		t.Errorf("expected previous client to be kept")
+	}
+}
@@ -15,10 +15,11 @@ package notify
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"mime"
 	"net"
@@ -26,6 +27,7 @@ import (
 	"net/mail"
 	"net/smtp"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -33,10 +35,11 @@ import (
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/model"
 	"golang.org/x/net/context"
-	"golang.org/x/net/context/ctxhttp"
 
 	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/tracing"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -52,15 +55,23 @@ var (
 		Name:      "notifications_failed_total",
 		Help:      "The total number of failed notifications.",
 	}, []string{"integration"})
+
+	numRetryBudgetExhausted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notifications_retry_budget_exhausted_total",
+		Help:      "The total number of notifications given up on after their maximum number of attempts.",
+	}, []string{"receiver"})
 )
 
 func init() {
 	prometheus.Register(numNotifications)
 	prometheus.Register(numFailedNotifications)
+	prometheus.Register(numRetryBudgetExhausted)
 }
 
 type notifierConfig interface {
 	SendResolved() bool
+	Cost() float64
 }
 
 type NotifierFunc func(context.Context, ...*types.Alert) error
@@ -74,30 +85,38 @@ type integration interface {
 	name() string
 }
 
-// Build creates a fanout notifier for each receiver.
-func Build(confs []*config.Receiver, tmpl *template.Template) map[string]Fanout {
+// Build creates a fanout notifier for each receiver. The cost of successful
+// notifications is accounted in the given CostAccount. Chat integrations
+// persist the IDs of message threads in the given Threads provider, as do
+// ticketing integrations with the keys of their issues. Created issues are
+// linked to their alerts through the IssueLinker if it is not nil. Plugin
+// integrations run the configured one of the plugins. Integrations sending
+// notifications over HTTP use their receiver's client of the HTTPClients.
+func Build(confs []*config.Receiver, tmpl *template.Template, costs *CostAccount, threads provider.Threads, issues IssueLinker, plugins *Plugins, clients *HTTPClients) map[string]Fanout {
 	res := map[string]Fanout{}
 
-	filter := func(n integration, c notifierConfig) Notifier {
+	filter := func(rcv string, n integration, c notifierConfig) Notifier {
 		return NotifierFunc(func(ctx context.Context, alerts ...*types.Alert) error {
-			var res []*types.Alert
-
-			if c.SendResolved() {
-				res = alerts
-			} else {
-				for _, a := range alerts {
-					if a.Status() != model.AlertResolved {
-						res = append(res, a)
-					}
-				}
-			}
+			obs := observeStage(ctx, StageIntegration, len(alerts))
+
+			res := sendable(c, alerts)
 			if len(res) == 0 {
+				obs.done(0, nil)
 				return nil
 			}
 
-			err := n.Notify(ctx, res...)
+			err := n.Notify(obs.ctx, res...)
+			obs.done(len(res), err)
+
 			if err != nil {
 				numFailedNotifications.WithLabelValues(n.name()).Inc()
+			} else if cost := c.Cost(); cost > 0 {
+				now, ok := Now(ctx)
+				if !ok {
+					now = time.Now()
+				}
+				costs.Add(rcv, now, cost)
+				notificationCost.WithLabelValues(n.name(), rcv).Add(cost)
 			}
 			numNotifications.WithLabelValues(n.name()).Inc()
 
@@ -107,37 +126,72 @@ func Build(confs []*config.Receiver, tmpl *template.Template) map[string]Fanout
 
 	for _, nc := range confs {
 		var (
-			fo  = Fanout{}
-			add = func(i int, on integration, n Notifier) { fo[fmt.Sprintf("%s/%d", on.name(), i)] = n }
+			fo     = Fanout{}
+			add    = func(i int, on integration, n Notifier) { fo[fmt.Sprintf("%s/%d", on.name(), i)] = n }
+			client = clients.Client(nc.Name)
 		)
 
 		for i, c := range nc.WebhookConfigs {
 			n := NewWebhook(c, tmpl)
-			add(i, n, filter(n, c))
+			n.client = client
+			add(i, n, filter(nc.Name, n, c))
 		}
 		for i, c := range nc.EmailConfigs {
 			n := NewEmail(c, tmpl)
-			add(i, n, filter(n, c))
+			add(i, n, filter(nc.Name, n, c))
 		}
 		for i, c := range nc.PagerdutyConfigs {
 			n := NewPagerDuty(c, tmpl)
-			add(i, n, filter(n, c))
+			n.client = client
+			add(i, n, filter(nc.Name, n, c))
 		}
 		for i, c := range nc.OpsGenieConfigs {
 			n := NewOpsGenie(c, tmpl)
-			add(i, n, filter(n, c))
+			n.client = client
+			add(i, n, filter(nc.Name, n, c))
 		}
 		for i, c := range nc.SlackConfigs {
-			n := NewSlack(c, tmpl)
-			add(i, n, filter(n, c))
+			n := NewSlack(c, tmpl, threads)
+			n.client = client
+			add(i, n, filter(nc.Name, n, c))
 		}
 		for i, c := range nc.HipchatConfigs {
 			n := NewHipchat(c, tmpl)
-			add(i, n, filter(n, c))
+			n.client = client
+			add(i, n, filter(nc.Name, n, c))
 		}
 		for i, c := range nc.PushoverConfigs {
 			n := NewPushover(c, tmpl)
-			add(i, n, filter(n, c))
+			n.client = client
+			add(i, n, filter(nc.Name, n, c))
+		}
+		for i, c := range nc.TeamsConfigs {
+			n := NewTeams(c, tmpl)
+			n.client = client
+			add(i, n, filter(nc.Name, n, c))
+		}
+		for i, c := range nc.TelegramConfigs {
+			n := NewTelegram(c, tmpl)
+			n.client = client
+			add(i, n, filter(nc.Name, n, c))
+		}
+		for i, c := range nc.JiraConfigs {
+			n := NewJira(c, tmpl, threads, issues)
+			n.client = client
+			add(i, n, filter(nc.Name, n, c))
+		}
+		for i, c := range nc.SNMPConfigs {
+			n := NewSNMP(c, tmpl)
+			add(i, n, filter(nc.Name, n, c))
+		}
+		for i, c := range nc.AlertmanagerConfigs {
+			n := NewAlertmanager(c)
+			n.client = client
+			add(i, n, filter(nc.Name, n, c))
+		}
+		for i, c := range nc.PluginConfigs {
+			n := NewPlugin(c, tmpl, plugins)
+			add(i, n, filter(nc.Name, n, c))
 		}
 
 		res[nc.Name] = fo
@@ -145,18 +199,35 @@ func Build(confs []*config.Receiver, tmpl *template.Template) map[string]Fanout
 	return res
 }
 
+// sendable returns the alerts an integration with the given configuration
+// is notified about.
+func sendable(c notifierConfig, alerts []*types.Alert) []*types.Alert {
+	if c.SendResolved() {
+		return alerts
+	}
+	var res []*types.Alert
+	for _, a := range alerts {
+		if a.Status() != model.AlertResolved {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
 const contentTypeJSON = "application/json"
 
 // Webhook implements a Notifier for generic webhooks.
 type Webhook struct {
 	// The URL to which notifications are sent.
-	URL  string
-	tmpl *template.Template
+	URL    string
+	conf   *config.WebhookConfig
+	tmpl   *template.Template
+	client *http.Client
 }
 
 // NewWebhook returns a new Webhook.
 func NewWebhook(conf *config.WebhookConfig, t *template.Template) *Webhook {
-	return &Webhook{URL: conf.URL, tmpl: t}
+	return &Webhook{URL: conf.URL, conf: conf, tmpl: t, client: http.DefaultClient}
 }
 
 func (*Webhook) name() string { return "webhook" }
@@ -166,47 +237,190 @@ type WebhookMessage struct {
 	*template.Data
 
 	// The protocol version.
-	Version  string `json:"version"`
-	GroupKey uint64 `json:"groupKey"`
+	Version string `json:"version"`
+	// The numeric legacy group key in version 3 and the string group key
+	// in version 4.
+	GroupKey interface{} `json:"groupKey"`
+}
+
+// newWebhookMessage returns the message of a notification in the payload
+// version of the group key format.
+func newWebhookMessage(ctx context.Context, format config.GroupKeyFormat, data *template.Data) *WebhookMessage {
+	if format == config.GroupKeyString {
+		key, ok := GroupKey(ctx)
+		if !ok {
+			log.Errorf("group key missing")
+		}
+		return &WebhookMessage{Version: "4", Data: data, GroupKey: key}
+	}
+	key, ok := legacyGroupKey(ctx)
+	if !ok {
+		log.Errorf("group labels missing")
+	}
+	return &WebhookMessage{Version: "3", Data: data, GroupKey: key}
 }
 
 // Notify implements the Notifier interface.
 func (w *Webhook) Notify(ctx context.Context, alerts ...*types.Alert) error {
-	data := w.tmpl.Data(receiver(ctx), groupLabels(ctx), alerts...)
+	data := tmplData(ctx, w.tmpl, alerts...)
 
-	groupKey, ok := GroupKey(ctx)
-	if !ok {
-		log.Errorf("group key missing")
+	var (
+		err     error
+		tmpl    = tmplText(w.tmpl, data, &err)
+		body    []byte
+		headers = map[string]string{}
+	)
+	if w.conf.Body != "" {
+		body = []byte(tmpl(w.conf.Body))
+	} else {
+		msg := newWebhookMessage(ctx, w.conf.GroupKeyFormat, data)
+		if body, err = json.Marshal(msg); err != nil {
+			return err
+		}
+		headers["Content-Type"] = contentTypeJSON
+	}
+	for k, v := range w.conf.Headers {
+		headers[k] = tmpl(v)
 	}
+	if err != nil {
+		return err
+	}
+
+	backoff := time.Duration(w.conf.RetryBackoff)
 
-	msg := &WebhookMessage{
-		Version:  "3",
-		Data:     data,
-		GroupKey: uint64(groupKey),
+	for i := 0; ; i++ {
+		retry, err := w.send(ctx, body, headers)
+		if err == nil {
+			return nil
+		}
+		if !retry || i >= w.conf.MaxRetries {
+			return err
+		}
+		log.Debugf("Webhook attempt %d to %s failed: %s", i+1, w.URL, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
 	}
+}
 
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+// send performs a single request to the webhook and reports whether it
+// should be retried on failure.
+func (w *Webhook) send(ctx context.Context, body []byte, headers map[string]string) (bool, error) {
+	if w.conf.Timeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(w.conf.Timeout))
+		defer cancel()
+	}
+
+	req, err := http.NewRequest(w.conf.Method, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := tracing.Do(ctx, w.client, req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		// Client errors other than rate limiting will not resolve on retry.
+		retry := resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("unexpected status code %v from %s", resp.StatusCode, w.URL)
+	}
+	return false, nil
+}
+
+// HopsLabel is the label counting the Alertmanagers an alert was forwarded
+// through.
+const HopsLabel = "__alertmanager_hops__"
+
+// Alertmanager implements a Notifier forwarding alerts to the API of
+// another Alertmanager.
+type Alertmanager struct {
+	// The URL of the alerts API alerts are posted to.
+	URL    string
+	conf   *config.AlertmanagerConfig
+	client *http.Client
+}
+
+// NewAlertmanager returns a new Alertmanager notifier.
+func NewAlertmanager(c *config.AlertmanagerConfig) *Alertmanager {
+	return &Alertmanager{
+		URL:    strings.TrimRight(c.URL, "/") + "/api/v1/alerts",
+		conf:   c,
+		client: http.DefaultClient,
+	}
+}
+
+func (*Alertmanager) name() string { return "alertmanager" }
+
+// Notify implements the Notifier interface.
+func (am *Alertmanager) Notify(ctx context.Context, alerts ...*types.Alert) error {
+	fwd := forwardedAlerts(am.conf.MaxHops, alerts...)
+	if len(fwd) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(fwd)
+	if err != nil {
 		return err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, w.URL, contentTypeJSON, &buf)
+	if am.conf.Timeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(am.conf.Timeout))
+		defer cancel()
+	}
+
+	resp, err := tracing.Post(ctx, am.client, am.URL, contentTypeJSON, bytes.NewReader(b))
 	if err != nil {
 		return err
 	}
 	resp.Body.Close()
 
 	if resp.StatusCode/100 != 2 {
-		return fmt.Errorf("unexpected status code %v from %s", resp.StatusCode, w.URL)
+		return fmt.Errorf("unexpected status code %v from %s", resp.StatusCode, am.URL)
 	}
-
 	return nil
 }
 
+// forwardedAlerts returns the alerts as forwarded to another Alertmanager
+// with their hop count incremented. Alerts that were already forwarded
+// through the maximum number of hops are dropped.
+func forwardedAlerts(maxHops int, alerts ...*types.Alert) model.Alerts {
+	var res model.Alerts
+
+	for _, a := range types.Alerts(alerts...) {
+		hops, err := strconv.Atoi(string(a.Labels[HopsLabel]))
+		if err != nil {
+			hops = 0
+		}
+		if hops >= maxHops {
+			log.With("alert", a).Warnf("Dropping alert forwarded through %d Alertmanagers", hops)
+			continue
+		}
+		v := *a
+		v.Labels = a.Labels.Clone()
+		v.Labels[HopsLabel] = model.LabelValue(strconv.Itoa(hops + 1))
+
+		res = append(res, &v)
+	}
+	return res
+}
+
 // Email implements a Notifier for email notifications.
 type Email struct {
 	conf *config.EmailConfig
 	tmpl *template.Template
+	// Key of the connections to the smarthost in the pool.
+	poolKey string
 }
 
 // NewEmail returns a new Email notifier.
@@ -220,7 +434,7 @@ func NewEmail(c *config.EmailConfig, t *template.Template) *Email {
 	if _, ok := c.Headers["From"]; !ok {
 		c.Headers["From"] = c.From
 	}
-	return &Email{conf: c, tmpl: t}
+	return &Email{conf: c, tmpl: t, poolKey: smtpPoolKey(c)}
 }
 
 func (*Email) name() string { return "email" }
@@ -256,45 +470,75 @@ func (n *Email) auth(mechs string) (smtp.Auth, error) {
 	return nil, nil
 }
 
-// Notify implements the Notifier interface.
-func (n *Email) Notify(ctx context.Context, as ...*types.Alert) error {
-	// Connect to the SMTP smarthost.
-	c, err := smtp.Dial(n.conf.Smarthost)
+// dial connects and authenticates to the SMTP smarthost.
+func (n *Email) dial() (*smtp.Client, error) {
+	// We need to know the hostname for both auth and TLS.
+	host, _, err := net.SplitHostPort(n.conf.Smarthost)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("invalid address: %s", err)
 	}
-	defer c.Quit()
 
-	// We need to know the hostname for both auth and TLS.
-	host, _, err := net.SplitHostPort(n.conf.Smarthost)
+	c, err := smtp.Dial(n.conf.Smarthost)
 	if err != nil {
-		return fmt.Errorf("invalid address: %s", err)
+		return nil, err
 	}
 
 	if n.conf.RequireTLS {
 		if ok, _ := c.Extension("STARTTLS"); !ok {
-			return fmt.Errorf("require_tls: true (default), but %q does not advertise the STARTTLS extension", n.conf.Smarthost)
+			c.Close()
+			return nil, fmt.Errorf("require_tls: true (default), but %q does not advertise the STARTTLS extension", n.conf.Smarthost)
 		}
 		tlsConf := &tls.Config{ServerName: host}
 		if err := c.StartTLS(tlsConf); err != nil {
-			return fmt.Errorf("starttls failed: %s", err)
+			c.Close()
+			return nil, fmt.Errorf("starttls failed: %s", err)
 		}
 	}
 
 	if ok, mech := c.Extension("AUTH"); ok {
 		auth, err := n.auth(mech)
 		if err != nil {
-			return err
+			c.Close()
+			return nil, err
 		}
 		if auth != nil {
 			if err := c.Auth(auth); err != nil {
-				return fmt.Errorf("%T failed: %s", auth, err)
+				c.Close()
+				return nil, fmt.Errorf("%T failed: %s", auth, err)
 			}
 		}
 	}
+	return c, nil
+}
+
+// connect returns a connection to the smarthost. Idle connections are
+// reused if pooling is enabled.
+func (n *Email) connect() (*smtp.Client, error) {
+	if n.conf.MaxIdleConns > 0 {
+		for c := emailConns.get(n.poolKey, time.Now()); c != nil; c = emailConns.get(n.poolKey, time.Now()) {
+			// The server may have closed the connection in the meantime.
+			if err := c.Reset(); err == nil {
+				return c, nil
+			}
+			c.Close()
+		}
+	}
+	return n.dial()
+}
 
+// release returns the connection to the pool or closes it.
+func (n *Email) release(c *smtp.Client) {
+	if n.conf.MaxIdleConns > 0 && emailConns.put(n.poolKey, c, n.conf.MaxIdleConns, time.Now()) {
+		return
+	}
+	c.Quit()
+}
+
+// Notify implements the Notifier interface.
+func (n *Email) Notify(ctx context.Context, as ...*types.Alert) error {
 	var (
-		data = n.tmpl.Data(receiver(ctx), groupLabels(ctx), as...)
+		err  error
+		data = tmplData(ctx, n.tmpl, as...)
 		tmpl = tmplText(n.tmpl, data, &err)
 		from = tmpl(n.conf.From)
 		to   = tmpl(n.conf.To)
@@ -303,67 +547,125 @@ func (n *Email) Notify(ctx context.Context, as ...*types.Alert) error {
 		return err
 	}
 
-	addrs, err := mail.ParseAddressList(from)
+	fromAddrs, err := mail.ParseAddressList(from)
 	if err != nil {
 		return fmt.Errorf("parsing from addresses: %s", err)
 	}
-	if len(addrs) != 1 {
+	if len(fromAddrs) != 1 {
 		return fmt.Errorf("must be exactly one from address")
 	}
-	if err := c.Mail(addrs[0].Address); err != nil {
-		return fmt.Errorf("sending mail from: %s", err)
-	}
-	addrs, err = mail.ParseAddressList(to)
+	toAddrs, err := mail.ParseAddressList(to)
 	if err != nil {
 		return fmt.Errorf("parsing to addresses: %s", err)
 	}
-	for _, addr := range addrs {
-		if err := c.Rcpt(addr.Address); err != nil {
-			return fmt.Errorf("sending rcpt to: %s", err)
-		}
+
+	msg, err := n.message(ctx, data)
+	if err != nil {
+		return err
 	}
 
-	// Send the email body.
-	wc, err := c.Data()
+	c, err := n.connect()
 	if err != nil {
 		return err
 	}
-	defer wc.Close()
+	if err := sendMail(c, fromAddrs[0], toAddrs, msg); err != nil {
+		c.Close()
+		return err
+	}
+	n.release(c)
+
+	return nil
+}
+
+// message returns the email with its headers.
+func (n *Email) message(ctx context.Context, data *template.Data) ([]byte, error) {
+	var buf bytes.Buffer
 
 	for header, t := range n.conf.Headers {
 		value, err := n.tmpl.ExecuteTextString(t, data)
 		if err != nil {
-			return fmt.Errorf("executing %q header template: %s", header, err)
+			return nil, fmt.Errorf("executing %q header template: %s", header, err)
 		}
-		fmt.Fprintf(wc, "%s: %s\r\n", header, mime.QEncoding.Encode("utf-8", value))
+		fmt.Fprintf(&buf, "%s: %s\r\n", header, mime.QEncoding.Encode("utf-8", value))
 	}
-
-	fmt.Fprintf(wc, "Content-Type: text/html; charset=UTF-8\r\n")
-	fmt.Fprintf(wc, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
-
-	// TODO: Add some useful headers here, such as URL of the alertmanager
-	// and active/resolved.
-	fmt.Fprintf(wc, "\r\n")
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
 
 	// TODO(fabxc): do a multipart write that considers the plain template.
-	body, err := n.tmpl.ExecuteHTMLString(n.conf.HTML, data)
+	html := n.conf.HTML
+	if h, ok := EmailHTML(ctx); ok {
+		html = h
+	}
+	body, err := n.tmpl.ExecuteHTMLString(html, data)
+	if err != nil {
+		return nil, fmt.Errorf("executing email html template: %s", err)
+	}
+	part, err := htmlPart(body)
 	if err != nil {
-		return fmt.Errorf("executing email html template: %s", err)
+		return nil, err
 	}
-	_, err = io.WriteString(wc, body)
 
-	return err
+	if images := emailImages(ctx, n.tmpl, n.conf, data); len(images) > 0 {
+		if part, err = multipartPart("related", append([]emailPart{part}, images...)...); err != nil {
+			return nil, err
+		}
+	}
+	if n.conf.AttachCSV {
+		b, err := alertsCSV(data.Alerts)
+		if err != nil {
+			return nil, err
+		}
+		attachment := base64Part("text/csv; charset=UTF-8", "attachment", "alerts.csv", b)
+
+		if part, err = multipartPart("mixed", part, attachment); err != nil {
+			return nil, err
+		}
+	}
+
+	for header, values := range part.header {
+		for _, v := range values {
+			fmt.Fprintf(&buf, "%s: %s\r\n", header, v)
+		}
+	}
+	fmt.Fprintf(&buf, "\r\n")
+	buf.Write(part.body)
+
+	return buf.Bytes(), nil
+}
+
+// sendMail sends the message over the connection.
+func sendMail(c *smtp.Client, from *mail.Address, to []*mail.Address, msg []byte) error {
+	if err := c.Mail(from.Address); err != nil {
+		return fmt.Errorf("sending mail from: %s", err)
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr.Address); err != nil {
+			return fmt.Errorf("sending rcpt to: %s", err)
+		}
+	}
+
+	// Send the email body.
+	wc, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(msg); err != nil {
+		wc.Close()
+		return err
+	}
+	return wc.Close()
 }
 
 // PagerDuty implements a Notifier for PagerDuty notifications.
 type PagerDuty struct {
-	conf *config.PagerdutyConfig
-	tmpl *template.Template
+	conf   *config.PagerdutyConfig
+	tmpl   *template.Template
+	client *http.Client
 }
 
 // NewPagerDuty returns a new PagerDuty notifier.
 func NewPagerDuty(c *config.PagerdutyConfig, t *template.Template) *PagerDuty {
-	return &PagerDuty{conf: c, tmpl: t}
+	return &PagerDuty{conf: c, tmpl: t, client: http.DefaultClient}
 }
 
 func (*PagerDuty) name() string { return "pagerduty" }
@@ -375,7 +677,7 @@ const (
 
 type pagerDutyMessage struct {
 	ServiceKey  string            `json:"service_key"`
-	IncidentKey model.Fingerprint `json:"incident_key"`
+	IncidentKey interface{}       `json:"incident_key"`
 	EventType   string            `json:"event_type"`
 	Description string            `json:"description"`
 	Client      string            `json:"client,omitempty"`
@@ -387,15 +689,14 @@ type pagerDutyMessage struct {
 //
 // http://developer.pagerduty.com/documentation/integration/events/trigger
 func (n *PagerDuty) Notify(ctx context.Context, as ...*types.Alert) error {
-	key, ok := GroupKey(ctx)
-	if !ok {
-		return fmt.Errorf("group key missing")
+	key, err := incidentKey(ctx, n.conf.GroupKeyFormat)
+	if err != nil {
+		return err
 	}
 
-	var err error
 	var (
 		alerts    = types.Alerts(as...)
-		data      = n.tmpl.Data(receiver(ctx), groupLabels(ctx), as...)
+		data      = tmplData(ctx, n.tmpl, as...)
 		tmpl      = tmplText(n.tmpl, data, &err)
 		eventType = pagerDutyEventTrigger
 	)
@@ -430,7 +731,7 @@ func (n *PagerDuty) Notify(ctx context.Context, as ...*types.Alert) error {
 		return err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, n.conf.URL, contentTypeJSON, &buf)
+	resp, err := tracing.Post(ctx, n.client, n.conf.URL, contentTypeJSON, &buf)
 	if err != nil {
 		return err
 	}
@@ -444,15 +745,19 @@ func (n *PagerDuty) Notify(ctx context.Context, as ...*types.Alert) error {
 
 // Slack implements a Notifier for Slack notifications.
 type Slack struct {
-	conf *config.SlackConfig
-	tmpl *template.Template
+	conf    *config.SlackConfig
+	tmpl    *template.Template
+	threads provider.Threads
+	client  *http.Client
 }
 
 // NewSlack returns a new Slack notification handler.
-func NewSlack(conf *config.SlackConfig, tmpl *template.Template) *Slack {
+func NewSlack(conf *config.SlackConfig, tmpl *template.Template, threads provider.Threads) *Slack {
 	return &Slack{
-		conf: conf,
-		tmpl: tmpl,
+		conf:    conf,
+		tmpl:    tmpl,
+		threads: threads,
+		client:  http.DefaultClient,
 	}
 }
 
@@ -464,6 +769,14 @@ type slackReq struct {
 	Username    string            `json:"username,omitempty"`
 	IconEmoji   string            `json:"icon_emoji,omitempty"`
 	Attachments []slackAttachment `json:"attachments"`
+	ThreadTS    string            `json:"thread_ts,omitempty"`
+}
+
+// slackResp is the response of the Slack Web API.
+type slackResp struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	TS    string `json:"ts"`
 }
 
 // slackAttachment is used to display a richly-formatted message block.
@@ -476,6 +789,44 @@ type slackAttachment struct {
 
 	Color    string   `json:"color,omitempty"`
 	MrkdwnIn []string `json:"mrkdwn_in,omitempty"`
+
+	CallbackID string        `json:"callback_id,omitempty"`
+	Actions    []slackAction `json:"actions,omitempty"`
+}
+
+// slackAction is a button inside the message attachment.
+type slackAction struct {
+	Name  string `json:"name"`
+	Text  string `json:"text"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Style string `json:"style,omitempty"`
+}
+
+// SlackCallbackID identifies the buttons of alert group notifications in
+// the callbacks of Slack actions.
+const SlackCallbackID = "alertmanager_group"
+
+// Names of the actions of the buttons in Slack notifications. The value
+// of each button is the hashed key of the notified group.
+const (
+	SlackActionSilence   = "silence"
+	SlackActionAck       = "ack"
+	SlackActionViewEvent = "view_event"
+)
+
+// SlackSilenceDuration is how long the silence button silences a group.
+const SlackSilenceDuration = time.Hour
+
+// slackActions returns the buttons for the group with the given key.
+func slackActions(groupKey string) []slackAction {
+	value := HashGroupKey(groupKey)
+
+	return []slackAction{
+		{Name: SlackActionSilence, Text: "Silence 1h", Type: "button", Value: value},
+		{Name: SlackActionAck, Text: "Ack", Type: "button", Value: value, Style: "primary"},
+		{Name: SlackActionViewEvent, Text: "View event", Type: "button", Value: value},
+	}
 }
 
 // slackAttachmentField is displayed in a table inside the message attachment.
@@ -489,7 +840,7 @@ type slackAttachmentField struct {
 func (n *Slack) Notify(ctx context.Context, as ...*types.Alert) error {
 	var err error
 	var (
-		data     = n.tmpl.Data(receiver(ctx), groupLabels(ctx), as...)
+		data     = tmplData(ctx, n.tmpl, as...)
 		tmplText = tmplText(n.tmpl, data, &err)
 	)
 
@@ -502,6 +853,10 @@ func (n *Slack) Notify(ctx context.Context, as ...*types.Alert) error {
 		Color:     tmplText(n.conf.Color),
 		MrkdwnIn:  []string{"fallback", "pretext", "text"},
 	}
+	if key, ok := GroupKey(ctx); ok && n.conf.Actions && data.Status == string(model.AlertFiring) {
+		attachment.CallbackID = SlackCallbackID
+		attachment.Actions = slackActions(key)
+	}
 	req := &slackReq{
 		Channel:     tmplText(n.conf.Channel),
 		Username:    tmplText(n.conf.Username),
@@ -512,12 +867,16 @@ func (n *Slack) Notify(ctx context.Context, as ...*types.Alert) error {
 		return err
 	}
 
+	if n.conf.Thread {
+		return n.notifyThread(ctx, req, data.Status == string(model.AlertResolved))
+	}
+
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(req); err != nil {
 		return err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, string(n.conf.APIURL), contentTypeJSON, &buf)
+	resp, err := tracing.Post(ctx, n.client, string(n.conf.APIURL), contentTypeJSON, &buf)
 	if err != nil {
 		return err
 	}
@@ -531,17 +890,76 @@ func (n *Slack) Notify(ctx context.Context, as ...*types.Alert) error {
 	return nil
 }
 
+// notifyThread posts the message through the Slack Web API. The first
+// message of an alert group starts a thread all further messages are posted
+// to. Once the group is resolved, the next message starts a new thread.
+func (n *Slack) notifyThread(ctx context.Context, req *slackReq, resolved bool) error {
+	rcv, ok := Receiver(ctx)
+	if !ok {
+		return fmt.Errorf("receiver missing")
+	}
+	groupKey, ok := GroupKey(ctx)
+	if !ok {
+		return fmt.Errorf("group key missing")
+	}
+	key := fmt.Sprintf("%s/%s/%s", rcv, req.Channel, groupKey)
+
+	ts, err := n.threads.Get(key)
+	if err != nil && err != provider.ErrNotFound {
+		return err
+	}
+	req.ThreadTS = ts
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(req); err != nil {
+		return err
+	}
+	hreq, err := http.NewRequest("POST", string(n.conf.APIURL), &buf)
+	if err != nil {
+		return err
+	}
+	hreq.Header.Set("Content-Type", contentTypeJSON)
+	hreq.Header.Set("Authorization", "Bearer "+string(n.conf.APIToken))
+
+	resp, err := tracing.Do(ctx, n.client, hreq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	var sresp slackResp
+	if err := json.NewDecoder(resp.Body).Decode(&sresp); err != nil {
+		return err
+	}
+	if !sresp.OK {
+		return fmt.Errorf("slack API error: %s", sresp.Error)
+	}
+
+	switch {
+	case resolved:
+		return n.threads.Del(key)
+	case ts == "":
+		return n.threads.Set(key, sresp.TS)
+	}
+	return nil
+}
+
 // Hipchat implements a Notifier for Hipchat notifications.
 type Hipchat struct {
-	conf *config.HipchatConfig
-	tmpl *template.Template
+	conf   *config.HipchatConfig
+	tmpl   *template.Template
+	client *http.Client
 }
 
 // NewHipchat returns a new Hipchat notification handler.
 func NewHipchat(conf *config.HipchatConfig, tmpl *template.Template) *Hipchat {
 	return &Hipchat{
-		conf: conf,
-		tmpl: tmpl,
+		conf:   conf,
+		tmpl:   tmpl,
+		client: http.DefaultClient,
 	}
 }
 
@@ -560,7 +978,7 @@ func (n *Hipchat) Notify(ctx context.Context, as ...*types.Alert) error {
 	var err error
 	var msg string
 	var (
-		data     = n.tmpl.Data(receiver(ctx), groupLabels(ctx), as...)
+		data     = tmplData(ctx, n.tmpl, as...)
 		tmplText = tmplText(n.tmpl, data, &err)
 		tmplHTML = tmplHTML(n.tmpl, data, &err)
 		url      = fmt.Sprintf("%sv2/room/%s/notification?auth_token=%s", n.conf.APIURL, n.conf.RoomID, n.conf.AuthToken)
@@ -588,7 +1006,7 @@ func (n *Hipchat) Notify(ctx context.Context, as ...*types.Alert) error {
 		return err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, url, contentTypeJSON, &buf)
+	resp, err := tracing.Post(ctx, n.client, url, contentTypeJSON, &buf)
 	if err != nil {
 		return err
 	}
@@ -602,32 +1020,319 @@ func (n *Hipchat) Notify(ctx context.Context, as ...*types.Alert) error {
 	return nil
 }
 
+// Teams implements a Notifier for Microsoft Teams notifications. Each
+// notification is posted as a single Adaptive Card to an incoming webhook.
+type Teams struct {
+	conf   *config.TeamsConfig
+	tmpl   *template.Template
+	client *http.Client
+}
+
+// NewTeams returns a new Teams notification handler.
+func NewTeams(conf *config.TeamsConfig, tmpl *template.Template) *Teams {
+	return &Teams{
+		conf:   conf,
+		tmpl:   tmpl,
+		client: http.DefaultClient,
+	}
+}
+
+func (*Teams) name() string { return "teams" }
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+	Actions []teamsAction  `json:"actions,omitempty"`
+}
+
+type teamsElement struct {
+	Type      string         `json:"type"`
+	Text      string         `json:"text,omitempty"`
+	Size      string         `json:"size,omitempty"`
+	Weight    string         `json:"weight,omitempty"`
+	Color     string         `json:"color,omitempty"`
+	Style     string         `json:"style,omitempty"`
+	Wrap      bool           `json:"wrap,omitempty"`
+	IsSubtle  bool           `json:"isSubtle,omitempty"`
+	Separator bool           `json:"separator,omitempty"`
+	Items     []teamsElement `json:"items,omitempty"`
+}
+
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// teamsColor returns the Adaptive Card color for the given status.
+func teamsColor(status string) string {
+	if status == string(model.AlertFiring) {
+		return "attention"
+	}
+	return "good"
+}
+
+// Notify implements the Notifier interface.
+func (n *Teams) Notify(ctx context.Context, as ...*types.Alert) error {
+	var (
+		err  error
+		data = tmplData(ctx, n.tmpl, as...)
+		tmpl = tmplText(n.tmpl, data, &err)
+		// Alert sections are executed with the single alert as data.
+		tmplAlert = func(text string, a template.Alert) string {
+			if err != nil {
+				return ""
+			}
+			var s string
+			s, err = n.tmpl.ExecuteTextString(text, a)
+			return s
+		}
+	)
+
+	body := []teamsElement{{
+		Type:   "TextBlock",
+		Text:   tmpl(n.conf.Title),
+		Size:   "Medium",
+		Weight: "Bolder",
+		Color:  teamsColor(data.Status),
+		Wrap:   true,
+	}}
+	if text := tmpl(n.conf.Text); text != "" {
+		body = append(body, teamsElement{Type: "TextBlock", Text: text, Wrap: true})
+	}
+
+	for i, a := range data.Alerts {
+		// Keep the card within the size limits of Teams by summarizing
+		// alerts beyond the maximum.
+		if i == n.conf.MaxAlerts {
+			body = append(body, teamsElement{
+				Type:     "TextBlock",
+				Text:     fmt.Sprintf("and %d more alerts", len(data.Alerts)-i),
+				IsSubtle: true,
+				Wrap:     true,
+			})
+			break
+		}
+		body = append(body, teamsElement{
+			Type:      "Container",
+			Style:     teamsColor(a.Status),
+			Separator: true,
+			Items: []teamsElement{
+				{Type: "TextBlock", Text: tmplAlert(n.conf.AlertTitle, a), Weight: "Bolder", Wrap: true},
+				{Type: "TextBlock", Text: tmplAlert(n.conf.AlertText, a), Wrap: true},
+			},
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("templating error: %s", err)
+	}
+
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.2",
+		Body:    body,
+	}
+	if data.ExternalURL != "" {
+		card.Actions = []teamsAction{{
+			Type:  "Action.OpenUrl",
+			Title: "View in Alertmanager",
+			URL:   data.ExternalURL,
+		}}
+	}
+	msg := &teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+		return err
+	}
+
+	resp, err := tracing.Post(ctx, n.client, string(n.conf.WebhookURL), contentTypeJSON, &buf)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// telegramMaxMessageLength is the maximum number of characters of a
+// single Telegram message.
+const telegramMaxMessageLength = 4096
+
+// Telegram implements a Notifier for Telegram bot notifications.
+type Telegram struct {
+	conf   *config.TelegramConfig
+	tmpl   *template.Template
+	client *http.Client
+}
+
+// NewTelegram returns a new Telegram notification handler.
+func NewTelegram(conf *config.TelegramConfig, tmpl *template.Template) *Telegram {
+	return &Telegram{
+		conf:   conf,
+		tmpl:   tmpl,
+		client: http.DefaultClient,
+	}
+}
+
+func (*Telegram) name() string { return "telegram" }
+
+type telegramReq struct {
+	ChatID              int64  `json:"chat_id"`
+	Text                string `json:"text"`
+	ParseMode           string `json:"parse_mode,omitempty"`
+	DisableNotification bool   `json:"disable_notification,omitempty"`
+}
+
+type telegramResp struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+// Notify implements the Notifier interface.
+func (n *Telegram) Notify(ctx context.Context, as ...*types.Alert) error {
+	var (
+		err  error
+		data = tmplData(ctx, n.tmpl, as...)
+		text = tmplText(n.tmpl, data, &err)(n.conf.Message)
+	)
+	if err != nil {
+		return fmt.Errorf("templating error: %s", err)
+	}
+
+	// Messages exceeding the limit are sent in multiple parts.
+	for _, part := range splitMessage(text, telegramMaxMessageLength) {
+		if err := n.send(ctx, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Telegram) send(ctx context.Context, text string) error {
+	req := &telegramReq{
+		ChatID:              n.conf.ChatID,
+		Text:                text,
+		ParseMode:           n.conf.ParseMode,
+		DisableNotification: n.conf.DisableNotification,
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(req); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%sbot%s/sendMessage", n.conf.APIURL, n.conf.BotToken)
+
+	resp, err := tracing.Post(ctx, n.client, url, contentTypeJSON, &buf)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var res telegramResp
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	if !res.OK {
+		return fmt.Errorf("error sending Telegram message: %s", res.Description)
+	}
+	return nil
+}
+
+// splitMessage splits text into parts of at most limit characters. Parts
+// are split at line breaks where possible so formatting within a line is
+// retained. Escaping backslashes are kept with the character they escape.
+func splitMessage(text string, limit int) []string {
+	var (
+		parts []string
+		cur   []rune
+	)
+	flush := func() {
+		if len(cur) > 0 {
+			parts = append(parts, string(cur))
+			cur = nil
+		}
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		l := []rune(line)
+		if len(cur)+len(l) > limit {
+			flush()
+		}
+		for len(l) > limit {
+			i := limit
+			// Do not separate an escape sequence.
+			if l[i-1] == '\\' && !escaped(l[:i-1]) {
+				i--
+			}
+			parts = append(parts, string(l[:i]))
+			l = l[i:]
+		}
+		cur = append(cur, l...)
+	}
+	flush()
+
+	return parts
+}
+
+// escaped returns true if the rune following s is escaped by a backslash.
+func escaped(s []rune) bool {
+	n := 0
+	for i := len(s) - 1; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
 // OpsGenie implements a Notifier for OpsGenie notifications.
 type OpsGenie struct {
-	conf *config.OpsGenieConfig
-	tmpl *template.Template
+	conf   *config.OpsGenieConfig
+	tmpl   *template.Template
+	client *http.Client
 }
 
 // NewOpsGenie returns a new OpsGenie notifier.
 func NewOpsGenie(c *config.OpsGenieConfig, t *template.Template) *OpsGenie {
-	return &OpsGenie{conf: c, tmpl: t}
+	return &OpsGenie{conf: c, tmpl: t, client: http.DefaultClient}
 }
 
 func (*OpsGenie) name() string { return "opsgenie" }
 
 type opsGenieMessage struct {
-	APIKey string            `json:"apiKey"`
-	Alias  model.Fingerprint `json:"alias"`
+	APIKey string      `json:"apiKey"`
+	Alias  interface{} `json:"alias"`
 }
 
 type opsGenieCreateMessage struct {
 	*opsGenieMessage `json:",inline"`
 
-	Message string            `json:"message"`
-	Details map[string]string `json:"details"`
-	Source  string            `json:"source"`
-	Teams   string            `json:"teams,omitempty"`
-	Tags    string            `json:"tags,omitempty"`
+	Message  string            `json:"message"`
+	Details  map[string]string `json:"details"`
+	Source   string            `json:"source"`
+	Teams    string            `json:"teams,omitempty"`
+	Tags     string            `json:"tags,omitempty"`
+	Priority string            `json:"priority,omitempty"`
 }
 
 type opsGenieCloseMessage struct {
@@ -641,15 +1346,14 @@ type opsGenieErrorResponse struct {
 
 // Notify implements the Notifier interface.
 func (n *OpsGenie) Notify(ctx context.Context, as ...*types.Alert) error {
-	key, ok := GroupKey(ctx)
-	if !ok {
-		return fmt.Errorf("group key missing")
+	key, err := incidentKey(ctx, n.conf.GroupKeyFormat)
+	if err != nil {
+		return err
 	}
-	data := n.tmpl.Data(receiver(ctx), groupLabels(ctx), as...)
+	data := tmplData(ctx, n.tmpl, as...)
 
 	log.With("incident", key).Debugln("notifying OpsGenie")
 
-	var err error
 	tmpl := tmplText(n.tmpl, data, &err)
 
 	details := make(map[string]string, len(n.conf.Details))
@@ -679,7 +1383,8 @@ func (n *OpsGenie) Notify(ctx context.Context, as ...*types.Alert) error {
 			Details:         details,
 			Source:          tmpl(n.conf.Source),
 			Teams:           tmpl(n.conf.Teams),
-			Tags:            tmpl(n.conf.Tags),
+			Tags:            n.tags(tmpl(n.conf.Tags), data),
+			Priority:        n.priority(tmpl, alerts),
 		}
 	}
 	if err != nil {
@@ -691,7 +1396,7 @@ func (n *OpsGenie) Notify(ctx context.Context, as ...*types.Alert) error {
 		return err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, apiURL, contentTypeJSON, &buf)
+	resp, err := tracing.Post(ctx, n.client, apiURL, contentTypeJSON, &buf)
 	if err != nil {
 		return err
 	}
@@ -719,15 +1424,52 @@ func (n *OpsGenie) Notify(ctx context.Context, as ...*types.Alert) error {
 	return nil
 }
 
+// priority returns the templated priority if configured. Otherwise the
+// most urgent priority mapped from the severities of the firing alerts
+// is used.
+func (n *OpsGenie) priority(tmpl func(string) string, alerts model.Alerts) string {
+	if n.conf.Priority != "" {
+		return tmpl(n.conf.Priority)
+	}
+	var prio string
+	for _, a := range alerts {
+		if a.Resolved() {
+			continue
+		}
+		p, ok := n.conf.SeverityPriorities[string(a.Labels[n.conf.SeverityLabel])]
+		// Priorities are of the form P1 to P5 and thus sort by urgency.
+		if ok && (prio == "" || p < prio) {
+			prio = p
+		}
+	}
+	return prio
+}
+
+// tags returns the configured tags extended by the tag labels shared by
+// all alerts.
+func (n *OpsGenie) tags(tags string, data *template.Data) string {
+	var res []string
+	if tags != "" {
+		res = append(res, tags)
+	}
+	for _, ln := range n.conf.TagLabels {
+		if v, ok := data.CommonLabels[string(ln)]; ok {
+			res = append(res, fmt.Sprintf("%s:%s", ln, v))
+		}
+	}
+	return strings.Join(res, ",")
+}
+
 // Pushover implements a Notifier for Pushover notifications.
 type Pushover struct {
-	conf *config.PushoverConfig
-	tmpl *template.Template
+	conf   *config.PushoverConfig
+	tmpl   *template.Template
+	client *http.Client
 }
 
 // NewPushover returns a new Pushover notifier.
 func NewPushover(c *config.PushoverConfig, t *template.Template) *Pushover {
-	return &Pushover{conf: c, tmpl: t}
+	return &Pushover{conf: c, tmpl: t, client: http.DefaultClient}
 }
 
 func (*Pushover) name() string { return "pushover" }
@@ -738,7 +1480,7 @@ func (n *Pushover) Notify(ctx context.Context, as ...*types.Alert) error {
 	if !ok {
 		return fmt.Errorf("group key missing")
 	}
-	data := n.tmpl.Data(receiver(ctx), groupLabels(ctx), as...)
+	data := tmplData(ctx, n.tmpl, as...)
 
 	log.With("incident", key).Debugln("notifying Pushover")
 
@@ -770,15 +1512,14 @@ func (n *Pushover) Notify(ctx context.Context, as ...*types.Alert) error {
 	parameters.Add("retry", fmt.Sprintf("%d", int64(time.Duration(n.conf.Retry).Seconds())))
 	parameters.Add("expire", fmt.Sprintf("%d", int64(time.Duration(n.conf.Expire).Seconds())))
 
-	apiURL := "https://api.pushover.net/1/messages.json"
-	u, err := url.Parse(apiURL)
+	u, err := url.Parse(pushoverAPIURL)
 	if err != nil {
 		return err
 	}
 	u.RawQuery = parameters.Encode()
 	log.With("incident", key).Debugf("Pushover URL = %q", u.String())
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, u.String(), "text/plain", nil)
+	resp, err := tracing.Post(ctx, n.client, u.String(), "text/plain", nil)
 	if err != nil {
 		return err
 	}
@@ -794,6 +1535,23 @@ func (n *Pushover) Notify(ctx context.Context, as ...*types.Alert) error {
 	return nil
 }
 
+// tmplData assembles the template data for the alerts from the information
+// the context was populated with along the pipeline.
+func tmplData(ctx context.Context, tmpl *template.Template, alerts ...*types.Alert) *template.Data {
+	data := tmpl.Data(receiver(ctx), groupLabels(ctx), alerts...)
+
+	if r, ok := RouteInfo(ctx); ok {
+		data.Route = r
+	}
+	if l, ok := EscalationLevel(ctx); ok {
+		data.EscalationLevel = l
+	}
+	if groups, ok := Digest(ctx); ok {
+		data.Digest = digestTemplateData(tmpl, receiver(ctx), groups)
+	}
+	return data
+}
+
 func tmplText(tmpl *template.Template, data *template.Data, err *error) func(string) string {
 	return func(name string) (s string) {
 		if *err != nil {
@@ -813,3 +1571,45 @@ func tmplHTML(tmpl *template.Template, data *template.Data, err *error) func(str
 		return s
 	}
 }
+
+// legacyGroupKey returns the group key of earlier versions, the fingerprint
+// of the group labels.
+func legacyGroupKey(ctx context.Context) (uint64, bool) {
+	labels, ok := GroupLabels(ctx)
+	if !ok {
+		return 0, false
+	}
+	return uint64(labels.Fingerprint()), true
+}
+
+// incidentKey returns the identifier of the notification's aggregation
+// group in the group key format for integrations tracking incidents.
+func incidentKey(ctx context.Context, format config.GroupKeyFormat) (interface{}, error) {
+	if format == config.GroupKeyString {
+		key, ok := GroupKey(ctx)
+		if !ok {
+			return nil, fmt.Errorf("group key missing")
+		}
+		return HashGroupKey(key), nil
+	}
+	key, ok := legacyGroupKey(ctx)
+	if !ok {
+		return nil, fmt.Errorf("group labels missing")
+	}
+	return key, nil
+}
+
+// HashGroupKey returns the hash identifying the aggregation group with the
+// given key in notifications, e.g. as PagerDuty incident key or in the
+// values of Slack message buttons.
+func HashGroupKey(groupKey string) string {
+	return hashKey(groupKey)
+}
+
+// hashKey returns a fixed-length digest of a group key for integrations
+// that limit the length of their incident identifiers.
+func hashKey(s string) string {
+	h := sha256.New()
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
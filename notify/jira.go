@@ -0,0 +1,273 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/tracing"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// jiraMaxSummaryLength is the maximum number of characters of the summary
+// of a Jira issue.
+const jiraMaxSummaryLength = 255
+
+// An IssueLinker records the issues created in external ticketing systems
+// for alerts, e.g. on the events the alerts belong to.
+type IssueLinker interface {
+	// LinkIssue links the issue with the given key and URL created by the
+	// named integration to the alerts.
+	LinkIssue(integration, key, url string, alerts ...*types.Alert) error
+}
+
+// Jira implements a Notifier creating a Jira issue for each alert group.
+// The issue is created on the group's first notification, updated on
+// further notifications and transitioned once the group resolved.
+type Jira struct {
+	conf   *config.JiraConfig
+	tmpl   *template.Template
+	issues provider.Threads
+	linker IssueLinker
+	client *http.Client
+}
+
+// NewJira returns a new Jira notification handler. The keys of open issues
+// are stored in issues. Created issues are linked to their alerts through
+// the linker if it is not nil.
+func NewJira(conf *config.JiraConfig, tmpl *template.Template, issues provider.Threads, linker IssueLinker) *Jira {
+	return &Jira{
+		conf:   conf,
+		tmpl:   tmpl,
+		issues: issues,
+		linker: linker,
+		client: http.DefaultClient,
+	}
+}
+
+func (*Jira) name() string { return "jira" }
+
+type jiraIssue struct {
+	Fields jiraFields `json:"fields"`
+}
+
+type jiraFields struct {
+	Project     *jiraRef `json:"project,omitempty"`
+	IssueType   *jiraRef `json:"issuetype,omitempty"`
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	Priority    *jiraRef `json:"priority,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+}
+
+// jiraRef references a Jira entity by its key, name or ID.
+type jiraRef struct {
+	ID   string `json:"id,omitempty"`
+	Key  string `json:"key,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type jiraTransitions struct {
+	Transitions []jiraRef `json:"transitions"`
+}
+
+type jiraTransitionReq struct {
+	Transition jiraRef `json:"transition"`
+}
+
+// Notify implements the Notifier interface.
+func (n *Jira) Notify(ctx context.Context, as ...*types.Alert) error {
+	rcv, ok := Receiver(ctx)
+	if !ok {
+		return fmt.Errorf("receiver missing")
+	}
+	groupKey, ok := GroupKey(ctx)
+	if !ok {
+		return fmt.Errorf("group key missing")
+	}
+	key := fmt.Sprintf("jira/%s/%s/%s", rcv, n.conf.Project, groupKey)
+
+	var (
+		err      error
+		data     = tmplData(ctx, n.tmpl, as...)
+		tmplText = tmplText(n.tmpl, data, &err)
+		fields   = jiraFields{
+			Summary:     truncateRunes(tmplText(n.conf.Summary), jiraMaxSummaryLength),
+			Description: tmplText(n.conf.Description),
+		}
+		priority = tmplText(n.conf.Priority)
+	)
+	if err != nil {
+		return fmt.Errorf("templating error: %s", err)
+	}
+	resolved := data.Status == string(model.AlertResolved)
+
+	issue, err := n.issues.Get(key)
+	if err != nil && err != provider.ErrNotFound {
+		return err
+	}
+
+	if issue != "" {
+		err := n.update(ctx, issue, fields)
+		if err == errJiraIssueNotFound {
+			// The issue was deleted in Jira. Start over with a new one.
+			if err := n.issues.Del(key); err != nil {
+				return err
+			}
+			issue = ""
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if issue == "" {
+		// There is no issue to be resolved.
+		if resolved {
+			return nil
+		}
+		fields.Project = &jiraRef{Key: n.conf.Project}
+		fields.IssueType = &jiraRef{Name: n.conf.IssueType}
+		fields.Labels = n.conf.Labels
+		if priority != "" {
+			fields.Priority = &jiraRef{Name: priority}
+		}
+		if issue, err = n.create(ctx, fields); err != nil {
+			return err
+		}
+		if err := n.issues.Set(key, issue); err != nil {
+			return err
+		}
+		if n.linker != nil {
+			if err := n.linker.LinkIssue(n.name(), issue, n.conf.APIURL+"browse/"+issue, as...); err != nil {
+				log.Warnf("Linking Jira issue %s to events failed: %s", issue, err)
+			}
+		}
+		return nil
+	}
+
+	if !resolved {
+		return nil
+	}
+	if err := n.transition(ctx, issue, n.conf.ResolveTransition); err != nil {
+		return err
+	}
+	// The next notification of the group creates a new issue.
+	return n.issues.Del(key)
+}
+
+// errJiraIssueNotFound is returned if an issue does not exist (anymore).
+var errJiraIssueNotFound = fmt.Errorf("jira issue not found")
+
+// do sends a request with the JSON encoding of v as body to the Jira REST
+// API and decodes the response into res if it is not nil.
+func (n *Jira) do(ctx context.Context, method, path string, v, res interface{}) error {
+	var body io.Reader
+	if v != nil {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(v); err != nil {
+			return err
+		}
+		body = &buf
+	}
+	req, err := http.NewRequest(method, n.conf.APIURL+"rest/api/2/"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentTypeJSON)
+	if v != nil {
+		req.Header.Set("Content-Type", contentTypeJSON)
+	}
+	switch {
+	case n.conf.User != "":
+		req.SetBasicAuth(n.conf.User, string(n.conf.APIToken))
+	case n.conf.APIToken != "":
+		req.Header.Set("Authorization", "Bearer "+string(n.conf.APIToken))
+	}
+
+	resp, err := tracing.Do(ctx, n.client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		io.Copy(ioutil.Discard, resp.Body)
+		return errJiraIssueNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		// Jira explains rejected requests in the error messages of the
+		// response.
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %v: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	if res == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+
+// create creates an issue and returns its key.
+func (n *Jira) create(ctx context.Context, fields jiraFields) (string, error) {
+	var res jiraRef
+	if err := n.do(ctx, "POST", "issue", &jiraIssue{Fields: fields}, &res); err != nil {
+		return "", err
+	}
+	if res.Key == "" {
+		return "", fmt.Errorf("missing key of created Jira issue")
+	}
+	return res.Key, nil
+}
+
+// update sets the summary and description of the issue.
+func (n *Jira) update(ctx context.Context, issue string, fields jiraFields) error {
+	return n.do(ctx, "PUT", "issue/"+issue, &jiraIssue{Fields: fields}, nil)
+}
+
+// transition applies the workflow transition with the given name to the
+// issue.
+func (n *Jira) transition(ctx context.Context, issue, name string) error {
+	var ts jiraTransitions
+	if err := n.do(ctx, "GET", "issue/"+issue+"/transitions", nil, &ts); err != nil {
+		return err
+	}
+	for _, t := range ts.Transitions {
+		if strings.EqualFold(t.Name, name) {
+			return n.do(ctx, "POST", "issue/"+issue+"/transitions", &jiraTransitionReq{Transition: jiraRef{ID: t.ID}}, nil)
+		}
+	}
+	return fmt.Errorf("transition %q not available for Jira issue %s", name, issue)
+}
+
+// truncateRunes returns s truncated to at most n characters.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
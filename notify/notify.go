@@ -23,7 +23,9 @@ import (
 	"github.com/prometheus/common/model"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/template"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -31,6 +33,10 @@ import (
 // to a notification pipeline.
 const MinTimeout = 10 * time.Second
 
+// RepeatIntervalAnnotation is the annotation through which an alert
+// overrides the repeat interval of its route, e.g. "24h".
+const RepeatIntervalAnnotation = "repeat_interval"
+
 // notifyKey defines a custom type with which a context is populated to
 // avoid accidental collisions.
 type notifyKey int
@@ -41,6 +47,10 @@ const (
 	keyGroupLabels
 	keyGroupKey
 	keyNow
+	keyRouteInfo
+	keyEscalationLevel
+	keyEmailHTML
+	keyDigest
 )
 
 // WithReceiver populates a context with a receiver.
@@ -54,8 +64,8 @@ func WithRepeatInterval(ctx context.Context, t time.Duration) context.Context {
 }
 
 // WithGroupKey populates a context with a group key.
-func WithGroupKey(ctx context.Context, fp model.Fingerprint) context.Context {
-	return context.WithValue(ctx, keyGroupKey, fp)
+func WithGroupKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyGroupKey, key)
 }
 
 // WithGroupLabels populates a context with grouping labels.
@@ -68,6 +78,24 @@ func WithNow(ctx context.Context, t time.Time) context.Context {
 	return context.WithValue(ctx, keyNow, t)
 }
 
+// WithRouteInfo populates a context with information about the route
+// that dispatched the alerts.
+func WithRouteInfo(ctx context.Context, r template.Route) context.Context {
+	return context.WithValue(ctx, keyRouteInfo, r)
+}
+
+// WithEscalationLevel populates a context with the escalation level of
+// the alert group.
+func WithEscalationLevel(ctx context.Context, level int) context.Context {
+	return context.WithValue(ctx, keyEscalationLevel, level)
+}
+
+// WithEmailHTML populates a context with the template of the HTML body of
+// email notifications.
+func WithEmailHTML(ctx context.Context, html string) context.Context {
+	return context.WithValue(ctx, keyEmailHTML, html)
+}
+
 func receiver(ctx context.Context) string {
 	recv, ok := Receiver(ctx)
 	if !ok {
@@ -92,8 +120,8 @@ func RepeatInterval(ctx context.Context) (time.Duration, bool) {
 
 // GroupKey extracts a group key from the context. Iff none exists, the
 // second argument is false.
-func GroupKey(ctx context.Context) (model.Fingerprint, bool) {
-	v, ok := ctx.Value(keyGroupKey).(model.Fingerprint)
+func GroupKey(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(keyGroupKey).(string)
 	return v, ok
 }
 
@@ -119,6 +147,27 @@ func Now(ctx context.Context) (time.Time, bool) {
 	return v, ok
 }
 
+// RouteInfo extracts route information from the context. Iff none exists, the
+// second argument is false.
+func RouteInfo(ctx context.Context) (template.Route, bool) {
+	v, ok := ctx.Value(keyRouteInfo).(template.Route)
+	return v, ok
+}
+
+// EscalationLevel extracts the escalation level from the context. Iff none
+// exists, the second argument is false.
+func EscalationLevel(ctx context.Context) (int, bool) {
+	v, ok := ctx.Value(keyEscalationLevel).(int)
+	return v, ok
+}
+
+// EmailHTML extracts the template of the HTML body of email notifications
+// from the context. Iff none exists, the second argument is false.
+func EmailHTML(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(keyEmailHTML).(string)
+	return v, ok
+}
+
 // A Notifier is a type which notifies about alerts under constraints of the
 // given context.
 type Notifier interface {
@@ -163,24 +212,79 @@ func (ns Fanout) Notify(ctx context.Context, alerts ...*types.Alert) error {
 	return nil
 }
 
+// RetryBudgetError is returned by a RetryNotifier that gave up on a
+// notification after its maximum number of attempts.
+type RetryBudgetError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryBudgetError) Error() string {
+	return fmt.Sprintf("retry budget exhausted after %d attempts, last error: %s", e.Attempts, e.Err)
+}
+
+// RetryBudgetExhausted returns true if the error, or one of the errors of a
+// types.MultiError, is a RetryBudgetError.
+func RetryBudgetExhausted(err error) bool {
+	switch e := err.(type) {
+	case *RetryBudgetError:
+		return true
+	case *types.MultiError:
+		for _, err := range e.Errors() {
+			if RetryBudgetExhausted(err) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // RetryNotifier accepts another notifier and retries notifying
 // on error with exponential backoff.
 type RetryNotifier struct {
 	notifier Notifier
+	conf     config.RetryConfig
 }
 
-// Retry wraps the given notifier in a RetryNotifier.
-func Retry(n Notifier) *RetryNotifier {
-	return &RetryNotifier{notifier: n}
+// Retry wraps the given notifier in a RetryNotifier retrying as configured.
+// If the configuration is nil, the defaults apply.
+func Retry(n Notifier, conf *config.RetryConfig) *RetryNotifier {
+	if conf == nil {
+		conf = &config.DefaultRetryConfig
+	}
+	return &RetryNotifier{notifier: n, conf: *conf}
 }
 
-// Notify calls the underlying notifier with exponential backoff until it succeeds.
+// Notify calls the underlying notifier with exponential backoff until it
+// succeeds or the maximum number of attempts is reached.
 // It aborts if the context is canceled or timed out.
 func (n *RetryNotifier) Notify(ctx context.Context, alerts ...*types.Alert) error {
+	// Retries cannot be told apart from the notifier's execution, which
+	// is thus accounted to the stage.
+	obs := observeStage(ctx, StageRetry, len(alerts))
+
+	err := n.retry(obs.ctx, alerts...)
+	obs.done(len(alerts), err)
+
+	return err
+}
+
+func (n *RetryNotifier) backoff() *backoff.ExponentialBackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = time.Duration(n.conf.InitialInterval)
+	b.MaxInterval = time.Duration(n.conf.MaxInterval)
+	// Retry until the context is done rather than for a fixed time.
+	b.MaxElapsedTime = 0
+	b.Reset()
+	return b
+}
+
+func (n *RetryNotifier) retry(ctx context.Context, alerts ...*types.Alert) error {
 	var (
-		i    = 0
-		b    = backoff.NewExponentialBackOff()
-		tick = backoff.NewTicker(b)
+		i       = 0
+		b       = n.backoff()
+		tick    = backoff.NewTicker(b)
+		lastErr error
 	)
 	defer tick.Stop()
 
@@ -189,12 +293,21 @@ func (n *RetryNotifier) Notify(ctx context.Context, alerts ...*types.Alert) erro
 
 		select {
 		case <-tick.C:
-			if err := n.notifier.Notify(ctx, alerts...); err != nil {
-				log.Warnf("Notify attempt %d failed: %s", i, err)
-			} else {
+			if lastErr = n.notifier.Notify(ctx, alerts...); lastErr == nil {
 				return nil
 			}
+			log.Warnf("Notify attempt %d failed: %s", i, lastErr)
+
+			if n.conf.MaxAttempts > 0 && i >= n.conf.MaxAttempts {
+				receiver, _ := Receiver(ctx)
+				numRetryBudgetExhausted.WithLabelValues(receiver).Inc()
+
+				return &RetryBudgetError{Attempts: i, Err: lastErr}
+			}
 		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("%s after %d attempts, last error: %s", ctx.Err(), i-1, lastErr)
+			}
 			return ctx.Err()
 		}
 	}
@@ -214,8 +327,10 @@ func Dedup(notifies provider.Notifies, n Notifier) *DedupingNotifier {
 }
 
 // hasUpdates checks an alert against the last notification that was made
-// about it.
+// about it. The repeat interval is overridden by the alert's annotation.
 func (n *DedupingNotifier) hasUpdate(alert *types.Alert, last *types.NotifyInfo, now time.Time, interval time.Duration) bool {
+	interval = alertRepeatInterval(alert, interval)
+
 	if last != nil {
 		if alert.Resolved() {
 			if last.Resolved {
@@ -236,21 +351,55 @@ func (n *DedupingNotifier) hasUpdate(alert *types.Alert, last *types.NotifyInfo,
 	return true
 }
 
+// alertRepeatInterval returns the repeat interval the alert sets through
+// its annotation or def if it sets none or an invalid one.
+func alertRepeatInterval(alert *types.Alert, def time.Duration) time.Duration {
+	v, ok := alert.Annotations[RepeatIntervalAnnotation]
+	if !ok {
+		return def
+	}
+	d, err := model.ParseDuration(string(v))
+	if err != nil || d == 0 {
+		log.With("alert", alert).Warnf("Ignoring invalid %s annotation %q", RepeatIntervalAnnotation, v)
+		return def
+	}
+	return time.Duration(d)
+}
+
 // Notify implements the Notifier interface.
 func (n *DedupingNotifier) Notify(ctx context.Context, alerts ...*types.Alert) error {
+	obs := observeStage(ctx, StageDedup, len(alerts))
+
+	newNotifies, err := n.updates(ctx, alerts...)
+	if err != nil || newNotifies == nil {
+		obs.done(0, err)
+		return err
+	}
+	obs.done(len(alerts), nil)
+
+	if err := n.notifier.Notify(ctx, alerts...); err != nil {
+		return err
+	}
+
+	return n.notifies.Set(newNotifies...)
+}
+
+// updates returns the NotifyInfos to set once the alerts were notified
+// about. It returns nil if no notification has to be sent.
+func (n *DedupingNotifier) updates(ctx context.Context, alerts ...*types.Alert) ([]*types.NotifyInfo, error) {
 	name, ok := Receiver(ctx)
 	if !ok {
-		return fmt.Errorf("notifier name missing")
+		return nil, fmt.Errorf("notifier name missing")
 	}
 
 	repeatInterval, ok := RepeatInterval(ctx)
 	if !ok {
-		return fmt.Errorf("repeat interval missing")
+		return nil, fmt.Errorf("repeat interval missing")
 	}
 
 	now, ok := Now(ctx)
 	if !ok {
-		return fmt.Errorf("now time missing")
+		return nil, fmt.Errorf("now time missing")
 	}
 
 	var fps []model.Fingerprint
@@ -260,7 +409,7 @@ func (n *DedupingNotifier) Notify(ctx context.Context, alerts ...*types.Alert) e
 
 	notifyInfo, err := n.notifies.Get(name, fps...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// If we have to notify about any of the alerts, we send a notification
@@ -273,7 +422,7 @@ func (n *DedupingNotifier) Notify(ctx context.Context, alerts ...*types.Alert) e
 		}
 	}
 	if !send {
-		return nil
+		return nil, nil
 	}
 
 	var newNotifies []*types.NotifyInfo
@@ -286,12 +435,7 @@ func (n *DedupingNotifier) Notify(ctx context.Context, alerts ...*types.Alert) e
 			Timestamp: now,
 		})
 	}
-
-	if err := n.notifier.Notify(ctx, alerts...); err != nil {
-		return err
-	}
-
-	return n.notifies.Set(newNotifies...)
+	return newNotifies, nil
 }
 
 // Router dispatches the alerts to one of a set of
@@ -332,6 +476,8 @@ func Silence(m types.Muter, n Notifier, mk types.Marker) *SilenceNotifier {
 
 // Notify implements the Notifier interface.
 func (n *SilenceNotifier) Notify(ctx context.Context, alerts ...*types.Alert) error {
+	obs := observeStage(ctx, StageSilence, len(alerts))
+
 	var filtered []*types.Alert
 	for _, a := range alerts {
 		_, ok := n.marker.Silenced(a.Fingerprint())
@@ -344,6 +490,7 @@ func (n *SilenceNotifier) Notify(ctx context.Context, alerts ...*types.Alert) er
 			a.WasSilenced = ok
 		}
 	}
+	obs.done(len(filtered), nil)
 
 	return n.notifier.Notify(ctx, filtered...)
 }
@@ -367,6 +514,8 @@ func Inhibit(m types.Muter, n Notifier, mk types.Marker) *InhibitNotifier {
 
 // Notify implements the Notifier interface.
 func (n *InhibitNotifier) Notify(ctx context.Context, alerts ...*types.Alert) error {
+	obs := observeStage(ctx, StageInhibit, len(alerts))
+
 	var filtered []*types.Alert
 	for _, a := range alerts {
 		ok := n.marker.Inhibited(a.Fingerprint())
@@ -379,6 +528,37 @@ func (n *InhibitNotifier) Notify(ctx context.Context, alerts ...*types.Alert) er
 			a.WasInhibited = ok
 		}
 	}
+	obs.done(len(filtered), nil)
+
+	return n.notifier.Notify(ctx, filtered...)
+}
+
+// FlapNotifier holds back notifications for alerts that are flapping
+// before passing the remaining ones on to the next Notifier.
+type FlapNotifier struct {
+	notifier Notifier
+	muter    types.Muter
+}
+
+// Flap returns a new FlapNotifier.
+func Flap(m types.Muter, n Notifier) *FlapNotifier {
+	return &FlapNotifier{
+		notifier: n,
+		muter:    m,
+	}
+}
+
+// Notify implements the Notifier interface.
+func (n *FlapNotifier) Notify(ctx context.Context, alerts ...*types.Alert) error {
+	obs := observeStage(ctx, StageFlap, len(alerts))
+
+	var filtered []*types.Alert
+	for _, a := range alerts {
+		if !n.muter.Mutes(a.Labels) {
+			filtered = append(filtered, a)
+		}
+	}
+	obs.done(len(filtered), nil)
 
 	return n.notifier.Notify(ctx, filtered...)
 }
@@ -14,15 +14,27 @@
 package notify
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/prometheus/common/model"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/template"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -157,6 +169,56 @@ func TestDedupingNotifierHasUpdate(t *testing.T) {
 			},
 			result: true,
 		},
+		// A firing alert about which we already notified less than the
+		// repeat interval ago but which overrides the repeat interval.
+		{
+			inAlert: &types.Alert{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "a"},
+					Annotations: model.LabelSet{RepeatIntervalAnnotation: "10m"},
+					StartsAt:    now.Add(-10 * time.Minute),
+				},
+			},
+			inNotifyInfo: &types.NotifyInfo{
+				Alert:     model.LabelSet{"alertname": "a"}.Fingerprint(),
+				Resolved:  false,
+				Timestamp: now.Add(-15 * time.Minute),
+			},
+			result: true,
+		},
+		// A firing alert about which we already notified more than the
+		// repeat interval ago but which overrides the repeat interval.
+		{
+			inAlert: &types.Alert{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "a"},
+					Annotations: model.LabelSet{RepeatIntervalAnnotation: "24h"},
+					StartsAt:    now.Add(-10 * time.Minute),
+				},
+			},
+			inNotifyInfo: &types.NotifyInfo{
+				Alert:     model.LabelSet{"alertname": "a"}.Fingerprint(),
+				Resolved:  false,
+				Timestamp: now.Add(-115 * time.Minute),
+			},
+			result: false,
+		},
+		// An invalid override is ignored.
+		{
+			inAlert: &types.Alert{
+				Alert: model.Alert{
+					Labels:      model.LabelSet{"alertname": "a"},
+					Annotations: model.LabelSet{RepeatIntervalAnnotation: "one day"},
+					StartsAt:    now.Add(-10 * time.Minute),
+				},
+			},
+			inNotifyInfo: &types.NotifyInfo{
+				Alert:     model.LabelSet{"alertname": "a"}.Fingerprint(),
+				Resolved:  false,
+				Timestamp: now.Add(-115 * time.Minute),
+			},
+			result: true,
+		},
 	}
 
 	for i, c := range cases {
@@ -178,6 +240,7 @@ func TestDedupingNotifier(t *testing.T) {
 	ctx = WithReceiver(ctx, "name")
 	ctx = WithRepeatInterval(ctx, time.Duration(100*time.Minute))
 	ctx = WithNow(ctx, now)
+	ctx = WithGroupKey(ctx, "name/{}:{}")
 
 	alerts := []*types.Alert{
 		{
@@ -266,6 +329,40 @@ func TestDedupingNotifier(t *testing.T) {
 	}
 }
 
+func TestRetryNotifierBudget(t *testing.T) {
+	var (
+		attempts int
+		n        = Retry(NotifierFunc(func(ctx context.Context, alerts ...*types.Alert) error {
+			attempts++
+			return fmt.Errorf("some error")
+		}), &config.RetryConfig{
+			InitialInterval: model.Duration(time.Millisecond),
+			MaxInterval:     model.Duration(10 * time.Millisecond),
+			MaxAttempts:     3,
+		})
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	)
+	defer cancel()
+
+	err := n.Notify(WithReceiver(ctx, "team-X/webhook/0"))
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts but got %d", attempts)
+	}
+	if e, ok := err.(*RetryBudgetError); !ok || e.Attempts != 3 {
+		t.Fatalf("expected retry budget error after 3 attempts but got %v", err)
+	}
+
+	var me types.MultiError
+	me.Add(fmt.Errorf("other error"))
+	if RetryBudgetExhausted(&me) {
+		t.Errorf("expected other errors not to exhaust the retry budget")
+	}
+	me.Add(err)
+	if !RetryBudgetExhausted(&me) {
+		t.Errorf("expected retry budget error to be found in multi error")
+	}
+}
+
 func TestRoutedNotifier(t *testing.T) {
 	router := Router{
 		"1": &recordNotifier{},
@@ -387,7 +484,7 @@ func TestInhibitNotifier(t *testing.T) {
 
 	// Set the second alert as previously inhibited. It is expected to have
 	// the WasInhibited flag set to true afterwards.
-	marker.SetInhibited(inAlerts[1].Fingerprint(), true)
+	marker.SetInhibited(inAlerts[1].Fingerprint(), &types.InhibitSource{})
 
 	if err := inhibitNotifer.Notify(nil, inAlerts...); err != nil {
 		t.Fatalf("Notifying failed: %s", err)
@@ -405,3 +502,1089 @@ func TestInhibitNotifier(t *testing.T) {
 		t.Fatalf("Muting failed, expected: %v\ngot %v", out, got)
 	}
 }
+
+func TestCostAccount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "costs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "costs.json")
+
+	ca, err := NewCostAccount(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		jul = time.Date(2016, 7, 31, 23, 0, 0, 0, time.UTC)
+		aug = time.Date(2016, 8, 1, 1, 0, 0, 0, time.UTC)
+	)
+	ca.Add("team-A", jul, 0.5)
+	ca.Add("team-A", jul, 0.25)
+	ca.Add("team-B", jul, 1)
+	ca.Add("team-A", aug, 2)
+	ca.Add("team-B", aug, 0)
+
+	exp := map[string]map[string]float64{
+		"2016-07": {"team-A": 0.75, "team-B": 1},
+		"2016-08": {"team-A": 2},
+	}
+	if res := ca.Totals(); !reflect.DeepEqual(res, exp) {
+		t.Fatalf("expected totals %v but got %v", exp, res)
+	}
+
+	// The totals are restored from the file after a restart.
+	ca, err = NewCostAccount(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := ca.Totals(); !reflect.DeepEqual(res, exp) {
+		t.Fatalf("expected restored totals %v but got %v", exp, res)
+	}
+}
+
+func TestCheckerCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// Close a second server immediately to obtain an address without a listener.
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	confs := []*config.Receiver{
+		{
+			Name: "team-A",
+			WebhookConfigs: []*config.WebhookConfig{
+				{URL: srv.URL},
+				{URL: closed.URL},
+			},
+		},
+	}
+
+	c := NewChecker(time.Second)
+	c.Check(confs)
+
+	res := c.Statuses()
+	if len(res) != 1 || res[0].Name != "team-A" || len(res[0].Endpoints) != 2 {
+		t.Fatalf("unexpected check result %v", res)
+	}
+	if es := res[0].Endpoints[0]; !es.Healthy {
+		t.Errorf("expected endpoint %q to be healthy but got error %q", es.Endpoint, es.Error)
+	}
+	if es := res[0].Endpoints[1]; es.Healthy {
+		t.Errorf("expected endpoint %q to be unhealthy", es.Endpoint)
+	}
+}
+
+func TestCheckerHealth(t *testing.T) {
+	var (
+		failing = true
+		method  string
+	)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		if failing {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer webhook.Close()
+
+	var path, auth string
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+	}))
+	defer slack.Close()
+
+	confs := []*config.Receiver{
+		{
+			Name:           "team-A",
+			WebhookConfigs: []*config.WebhookConfig{{URL: webhook.URL + "/hook"}},
+			SlackConfigs: []*config.SlackConfig{{
+				APIURL:   config.Secret(slack.URL + "/api/chat.postMessage"),
+				APIToken: config.Secret("xoxb-token"),
+			}},
+		},
+	}
+
+	c := NewChecker(time.Second)
+	c.Check(confs)
+	res := c.Check(confs)
+
+	if len(res) != 1 || len(res[0].Endpoints) != 2 {
+		t.Fatalf("unexpected check result %v", res)
+	}
+	if res[0].Healthy {
+		t.Errorf("expected receiver to be unhealthy")
+	}
+	if method != "HEAD" {
+		t.Errorf("expected webhook to be sent a HEAD request but got %q", method)
+	}
+	if es := res[0].Endpoints[0]; es.Healthy || es.ConsecutiveFailures != 2 || !es.LastHealthyAt.IsZero() {
+		t.Errorf("expected webhook to have failed twice but got %+v", es)
+	}
+	if path != "/api/auth.test" || auth != "Bearer xoxb-token" {
+		t.Errorf("expected auth.test to be called with the token but got %q with %q", path, auth)
+	}
+	if es := res[0].Endpoints[1]; es.Healthy || !strings.Contains(es.Error, "invalid_auth") {
+		t.Errorf("expected Slack token to be rejected but got %+v", es)
+	}
+
+	failing = false
+	res = c.Check(confs)
+
+	if es := res[0].Endpoints[0]; !es.Healthy || es.ConsecutiveFailures != 0 || es.LastHealthyAt != es.CheckedAt {
+		t.Errorf("expected webhook to be healthy but got %+v", es)
+	}
+}
+
+func TestWebhookNotify(t *testing.T) {
+	var (
+		attempts int
+		method   string
+		header   string
+		body     string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+
+		method, header, body = r.Method, r.Header.Get("X-Receiver"), string(b)
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	w := NewWebhook(&config.WebhookConfig{
+		URL:          srv.URL,
+		Method:       "PUT",
+		Headers:      map[string]string{"X-Receiver": "{{ .Receiver }}"},
+		Body:         `{{ range .Alerts }}{{ .Labels.alertname }} {{ end }}`,
+		MaxRetries:   2,
+		RetryBackoff: config.DefaultWebhookConfig.RetryBackoff / 100,
+	}, tmpl)
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	alerts := []*types.Alert{
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a1"}}},
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a2"}}},
+	}
+
+	if err := w.Notify(ctx, alerts...); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts but got %d", attempts)
+	}
+	if method != "PUT" {
+		t.Errorf("expected method PUT but got %s", method)
+	}
+	if header != "team-X" {
+		t.Errorf("expected templated header %q but got %q", "team-X", header)
+	}
+	if body != "a1 a2 " {
+		t.Errorf("expected templated body %q but got %q", "a1 a2 ", body)
+	}
+
+	// Retries are exhausted after the configured amount.
+	attempts = 0
+	w.conf.MaxRetries = 1
+
+	if err := w.Notify(ctx, alerts...); err == nil {
+		t.Fatalf("expected notification to fail")
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts but got %d", attempts)
+	}
+}
+
+func TestAlertmanagerNotify(t *testing.T) {
+	var (
+		path     string
+		received model.Alerts
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer srv.Close()
+
+	am := NewAlertmanager(&config.AlertmanagerConfig{
+		URL:     srv.URL + "/",
+		MaxHops: 2,
+	})
+
+	alerts := []*types.Alert{
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a1"}}},
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a2", HopsLabel: "1"}}},
+		// Alerts forwarded through the maximum of hops are dropped.
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a3", HopsLabel: "2"}}},
+	}
+
+	if err := am.Notify(context.Background(), alerts...); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+	if path != "/api/v1/alerts" {
+		t.Errorf("expected alerts to be posted to %q but got %q", "/api/v1/alerts", path)
+	}
+
+	expected := []model.LabelSet{
+		{"alertname": "a1", HopsLabel: "1"},
+		{"alertname": "a2", HopsLabel: "2"},
+	}
+	if len(received) != len(expected) {
+		t.Fatalf("expected %d alerts but got %d", len(expected), len(received))
+	}
+	for i, a := range received {
+		if !reflect.DeepEqual(a.Labels, expected[i]) {
+			t.Errorf("expected labels %v but got %v", expected[i], a.Labels)
+		}
+	}
+	// The original alerts must not be modified.
+	if _, ok := alerts[0].Labels[HopsLabel]; ok {
+		t.Errorf("expected hop label not to be set on the original alert")
+	}
+
+	path = ""
+	if err := am.Notify(context.Background(), alerts[2]); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+	if path != "" {
+		t.Errorf("expected no request for dropped alerts")
+	}
+}
+
+type testThreads map[string]string
+
+func (t testThreads) Get(key string) (string, error) {
+	id, ok := t[key]
+	if !ok {
+		return "", provider.ErrNotFound
+	}
+	return id, nil
+}
+
+func (t testThreads) Set(key, id string) error {
+	t[key] = id
+	return nil
+}
+
+func (t testThreads) Del(key string) error {
+	delete(t, key)
+	return nil
+}
+
+func TestSlackThread(t *testing.T) {
+	var (
+		posts    int
+		threadTS []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req slackReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		posts++
+		threadTS = append(threadTS, req.ThreadTS)
+
+		fmt.Fprintf(w, `{"ok":true,"ts":"ts-%d"}`, posts)
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	threads := testThreads{}
+
+	conf := config.DefaultSlackConfig
+	conf.APIURL = config.Secret(srv.URL)
+	conf.APIToken = "token"
+	conf.Channel = "#alerts"
+	conf.Thread = true
+
+	n := NewSlack(&conf, tmpl, threads)
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	ctx = WithGroupKey(ctx, "team-X/{}:{}")
+
+	firing := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a"},
+			StartsAt: time.Now().Add(-time.Minute),
+		},
+	}
+	resolved := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a"},
+			StartsAt: time.Now().Add(-time.Minute),
+			EndsAt:   time.Now().Add(-time.Second),
+		},
+	}
+
+	for _, a := range []*types.Alert{firing, firing, resolved, firing} {
+		if err := n.Notify(ctx, a); err != nil {
+			t.Fatalf("notification failed: %s", err)
+		}
+	}
+
+	// The first message starts a thread which is replied to until the
+	// group resolves.
+	if exp := []string{"", "ts-1", "ts-1", ""}; !reflect.DeepEqual(threadTS, exp) {
+		t.Fatalf("expected thread timestamps %v but got %v", exp, threadTS)
+	}
+	if exp := (testThreads{"team-X/#alerts/team-X/{}:{}": "ts-4"}); !reflect.DeepEqual(threads, exp) {
+		t.Fatalf("expected threads %v but got %v", exp, threads)
+	}
+}
+
+func TestSlackActions(t *testing.T) {
+	var reqs []slackReq
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req slackReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reqs = append(reqs, req)
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	conf := config.DefaultSlackConfig
+	conf.APIURL = config.Secret(srv.URL)
+	conf.Channel = "#alerts"
+	conf.Actions = true
+
+	n := NewSlack(&conf, tmpl, nil)
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	ctx = WithGroupKey(ctx, "team-X/{}:{}")
+
+	firing := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a"},
+			StartsAt: time.Now().Add(-time.Minute),
+		},
+	}
+	resolved := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a"},
+			StartsAt: time.Now().Add(-time.Minute),
+			EndsAt:   time.Now().Add(-time.Second),
+		},
+	}
+	for _, a := range []*types.Alert{firing, resolved} {
+		if err := n.Notify(ctx, a); err != nil {
+			t.Fatalf("notification failed: %s", err)
+		}
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 messages but got %d", len(reqs))
+	}
+
+	att := reqs[0].Attachments[0]
+	if att.CallbackID != SlackCallbackID {
+		t.Fatalf("expected callback ID %q but got %q", SlackCallbackID, att.CallbackID)
+	}
+	var names []string
+	for _, a := range att.Actions {
+		if a.Value != HashGroupKey("team-X/{}:{}") {
+			t.Fatalf("expected button value to be the hashed group key but got %q", a.Value)
+		}
+		names = append(names, a.Name)
+	}
+	if exp := []string{SlackActionSilence, SlackActionAck, SlackActionViewEvent}; !reflect.DeepEqual(names, exp) {
+		t.Fatalf("expected buttons %v but got %v", exp, names)
+	}
+	// Resolved notifications carry no buttons.
+	if att := reqs[1].Attachments[0]; len(att.Actions) != 0 {
+		t.Fatalf("expected no buttons for resolved alerts but got %v", att.Actions)
+	}
+}
+
+func TestTeamsNotify(t *testing.T) {
+	var msg teamsMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	conf := config.DefaultTeamsConfig
+	conf.WebhookURL = config.Secret(srv.URL)
+	conf.MaxAlerts = 1
+
+	n := NewTeams(&conf, tmpl)
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	ctx = WithGroupKey(ctx, "team-X/{}:{}")
+
+	alerts := []*types.Alert{
+		{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "a"},
+				Annotations: model.LabelSet{"summary": "disk full"},
+				StartsAt:    time.Now().Add(-time.Minute),
+			},
+		}, {
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "b"},
+				StartsAt: time.Now().Add(-time.Minute),
+			},
+		},
+	}
+	if err := n.Notify(ctx, alerts...); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("expected a single card but got %d", len(msg.Attachments))
+	}
+	card := msg.Attachments[0].Content
+	if card.Type != "AdaptiveCard" {
+		t.Errorf("expected an adaptive card but got %q", card.Type)
+	}
+	// The title, one alert section, and the summary of the omitted alerts.
+	if len(card.Body) != 3 {
+		t.Fatalf("expected 3 card elements but got %d", len(card.Body))
+	}
+	if c := card.Body[0].Color; c != "attention" {
+		t.Errorf("expected firing color %q but got %q", "attention", c)
+	}
+	section := card.Body[1]
+	if section.Style != "attention" || len(section.Items) != 2 {
+		t.Fatalf("unexpected alert section %+v", section)
+	}
+	if section.Items[0].Text != "a" {
+		t.Errorf("expected alert title %q but got %q", "a", section.Items[0].Text)
+	}
+	if !strings.Contains(section.Items[1].Text, "**summary**: disk full") {
+		t.Errorf("expected annotations in alert text but got %q", section.Items[1].Text)
+	}
+	if txt := card.Body[2].Text; txt != "and 1 more alerts" {
+		t.Errorf("expected omitted alerts to be summarized but got %q", txt)
+	}
+	if len(card.Actions) != 1 || card.Actions[0].URL != "http://am.example.com" {
+		t.Errorf("expected a link to the Alertmanager but got %+v", card.Actions)
+	}
+
+	for _, a := range alerts {
+		a.EndsAt = time.Now().Add(-time.Second)
+	}
+	if err := n.Notify(ctx, alerts...); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+	if c := msg.Attachments[0].Content.Body[0].Color; c != "good" {
+		t.Errorf("expected resolved color %q but got %q", "good", c)
+	}
+}
+
+func TestTelegramNotify(t *testing.T) {
+	var (
+		path string
+		reqs []telegramReq
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+
+		var req telegramReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			fmt.Fprintf(w, `{"ok":false,"description":%q}`, err)
+			return
+		}
+		reqs = append(reqs, req)
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	conf := config.DefaultTelegramConfig
+	conf.APIURL = srv.URL + "/"
+	conf.BotToken = "token"
+	conf.ChatID = -100
+
+	n := NewTelegram(&conf, tmpl)
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	ctx = WithGroupKey(ctx, "team-X/{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{"job": "node-exporter"})
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "disk_full", "job": "node-exporter"},
+			Annotations: model.LabelSet{"summary": "95% used (sda1)."},
+			StartsAt:    time.Now().Add(-time.Minute),
+		},
+	}
+	if err := n.Notify(ctx, alert); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+	if path != "/bottoken/sendMessage" {
+		t.Errorf("expected message to be sent to %q but got %q", "/bottoken/sendMessage", path)
+	}
+	if len(reqs) != 1 {
+		t.Fatalf("expected a single message but got %d", len(reqs))
+	}
+	if reqs[0].ChatID != -100 || reqs[0].ParseMode != "MarkdownV2" {
+		t.Errorf("unexpected request %+v", reqs[0])
+	}
+	exp := "*\\[FIRING:1\\] node\\-exporter*\n\n*disk\\_full* \\(firing\\)\nsummary: 95% used \\(sda1\\)\\.\n"
+	if reqs[0].Text != exp {
+		t.Errorf("expected text %q but got %q", exp, reqs[0].Text)
+	}
+
+	// Texts exceeding the limit are split into multiple messages.
+	reqs = nil
+	conf.Message = strings.Repeat("x", telegramMaxMessageLength) + "\ny"
+	if err := n.Notify(ctx, alert); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 messages but got %d", len(reqs))
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	cases := []struct {
+		in       string
+		limit    int
+		expected []string
+	}{
+		{
+			in:       "abc\ndef",
+			limit:    10,
+			expected: []string{"abc\ndef"},
+		},
+		{
+			in:       "abc\ndef\ngh",
+			limit:    8,
+			expected: []string{"abc\ndef\n", "gh"},
+		},
+		{
+			// Lines exceeding the limit are split within the line.
+			in:       "abcdefgh\nij",
+			limit:    3,
+			expected: []string{"abc", "def", "gh\n", "ij"},
+		},
+		{
+			// Escape sequences are not separated.
+			in:       `ab\.cd`,
+			limit:    3,
+			expected: []string{"ab", `\.c`, "d"},
+		},
+		{
+			in:       `a\\.b`,
+			limit:    3,
+			expected: []string{`a\\`, ".b"},
+		},
+		{
+			in:       "äöü",
+			limit:    2,
+			expected: []string{"äö", "ü"},
+		},
+	}
+
+	for _, c := range cases {
+		if res := splitMessage(c.in, c.limit); !reflect.DeepEqual(res, c.expected) {
+			t.Errorf("expected %q to be split into %q but got %q", c.in, c.expected, res)
+		}
+	}
+}
+
+type testIssueLinker map[string][]*types.Alert
+
+func (l testIssueLinker) LinkIssue(integration, key, url string, alerts ...*types.Alert) error {
+	l[integration+" "+key+" "+url] = alerts
+	return nil
+}
+
+func TestJiraNotify(t *testing.T) {
+	var (
+		reqs    []string
+		created []jiraIssue
+		updated []jiraIssue
+		trans   []string
+		issues  int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "am@example.com" || p != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reqs = append(reqs, r.Method+" "+r.URL.Path)
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/rest/api/2/issue":
+			var req jiraIssue
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			created = append(created, req)
+			issues++
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"id":"1000%d","key":"OPS-%d"}`, issues, issues)
+		case r.Method == "PUT" && r.URL.Path == "/rest/api/2/issue/OPS-404":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == "PUT":
+			var req jiraIssue
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			updated = append(updated, req)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/transitions"):
+			fmt.Fprint(w, `{"transitions":[{"id":"11","name":"In Progress"},{"id":"31","name":"Done"}]}`)
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/transitions"):
+			var req jiraTransitionReq
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			trans = append(trans, req.Transition.ID)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	var (
+		threads = testThreads{}
+		linker  = testIssueLinker{}
+	)
+
+	conf := config.DefaultJiraConfig
+	conf.APIURL = srv.URL + "/"
+	conf.User = "am@example.com"
+	conf.APIToken = "token"
+	conf.Project = "OPS"
+	conf.Priority = `{{ if eq .CommonLabels.severity "critical" }}Highest{{ end }}`
+	conf.Labels = []string{"alertmanager"}
+
+	n := NewJira(&conf, tmpl, threads, linker)
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	ctx = WithGroupKey(ctx, "team-X/{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{"alertname": "disk_full"})
+
+	firing := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "disk_full", "severity": "critical"},
+			StartsAt: time.Now().Add(-time.Minute),
+		},
+	}
+	resolved := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "disk_full", "severity": "critical"},
+			StartsAt: time.Now().Add(-time.Minute),
+			EndsAt:   time.Now().Add(-time.Second),
+		},
+	}
+
+	for _, a := range []*types.Alert{firing, firing, resolved, resolved, firing} {
+		if err := n.Notify(ctx, a); err != nil {
+			t.Fatalf("notification failed: %s", err)
+		}
+	}
+
+	// An issue is created on the first notification, updated on the
+	// second one and transitioned once the group resolved. Notifications
+	// of resolved groups without issue are dropped.
+	exp := []string{
+		"POST /rest/api/2/issue",
+		"PUT /rest/api/2/issue/OPS-1",
+		"PUT /rest/api/2/issue/OPS-1",
+		"GET /rest/api/2/issue/OPS-1/transitions",
+		"POST /rest/api/2/issue/OPS-1/transitions",
+		"POST /rest/api/2/issue",
+	}
+	if !reflect.DeepEqual(reqs, exp) {
+		t.Fatalf("expected requests %v but got %v", exp, reqs)
+	}
+	if !reflect.DeepEqual(trans, []string{"31"}) {
+		t.Errorf("expected transition 31 to be applied but got %v", trans)
+	}
+
+	f := created[0].Fields
+	if f.Project.Key != "OPS" || f.IssueType.Name != "Bug" || f.Priority == nil || f.Priority.Name != "Highest" {
+		t.Errorf("unexpected fields of created issue %+v", f)
+	}
+	if f.Summary != "[FIRING:1] disk_full (critical)" {
+		t.Errorf("unexpected summary %q", f.Summary)
+	}
+	if !reflect.DeepEqual(f.Labels, []string{"alertmanager"}) {
+		t.Errorf("expected labels %v but got %v", conf.Labels, f.Labels)
+	}
+	// Updates leave the fields set on creation untouched.
+	if u := updated[0].Fields; u.Project != nil || u.Priority != nil || u.Labels != nil {
+		t.Errorf("unexpected fields of issue update %+v", u)
+	}
+	if !strings.Contains(updated[1].Fields.Description, "h3. Resolved alerts") {
+		t.Errorf("expected description of resolved alerts but got %q", updated[1].Fields.Description)
+	}
+
+	if exp := (testThreads{"jira/team-X/OPS/team-X/{}:{}": "OPS-2"}); !reflect.DeepEqual(threads, exp) {
+		t.Fatalf("expected issues %v but got %v", exp, threads)
+	}
+	for _, key := range []string{"OPS-1", "OPS-2"} {
+		if _, ok := linker["jira "+key+" "+srv.URL+"/browse/"+key]; !ok {
+			t.Errorf("expected issue %s to be linked but got %v", key, linker)
+		}
+	}
+
+	// Issues deleted in Jira are recreated.
+	threads["jira/team-X/OPS/team-X/{}:{}"] = "OPS-404"
+	if err := n.Notify(ctx, firing); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+	if threads["jira/team-X/OPS/team-X/{}:{}"] != "OPS-3" {
+		t.Fatalf("expected deleted issue to be recreated but got %v", threads)
+	}
+}
+
+func TestSNMPNotify(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	conf := config.DefaultSNMPConfig
+	conf.Target = conn.LocalAddr().String()
+	conf.TrapOID = "1.3.6.1.4.1.8072.9999.1"
+	conf.ResolvedTrapOID = "1.3.6.1.4.1.8072.9999.2"
+	conf.VarBinds = []*config.SNMPVarBind{
+		{OID: "1.3.6.1.4.1.8072.9999.3.1", Value: "{{ .Labels.alertname }}", Type: "string"},
+		{OID: "1.3.6.1.4.1.8072.9999.3.2", Value: "{{ .Annotations.summary }}", Type: "string"},
+	}
+
+	n := NewSNMP(&conf, tmpl)
+
+	ctx := WithReceiver(context.Background(), "noc")
+	ctx = WithGroupKey(ctx, "noc/{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+
+	alerts := []*types.Alert{
+		{
+			Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "disk_full"},
+				Annotations: model.LabelSet{"summary": "95% used"},
+				StartsAt:    time.Now().Add(-time.Minute),
+			},
+		},
+		{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "link_down"},
+				StartsAt: time.Now().Add(-time.Minute),
+				EndsAt:   time.Now().Add(-time.Second),
+			},
+		},
+	}
+	if err := n.Notify(ctx, alerts...); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+
+	// A trap is sent for each alert, identified by the trap OID of its
+	// status.
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 65535)
+	for _, exp := range []struct {
+		trapOID []byte
+		values  []string
+	}{
+		{trapOID: []byte{0x06, 0x0a, 0x2b, 6, 1, 4, 1, 0xbf, 0x08, 0xce, 0x0f, 1}, values: []string{"disk_full", "95% used"}},
+		{trapOID: []byte{0x06, 0x0a, 0x2b, 6, 1, 4, 1, 0xbf, 0x08, 0xce, 0x0f, 2}, values: []string{"link_down"}},
+	} {
+		k, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trap := buf[:k]
+		if !bytes.Contains(trap, []byte("public")) || !bytes.Contains(trap, exp.trapOID) {
+			t.Errorf("unexpected trap %x", trap)
+		}
+		for _, v := range exp.values {
+			if !bytes.Contains(trap, []byte(v)) {
+				t.Errorf("expected trap to contain %q but got %q", v, trap)
+			}
+		}
+	}
+
+	// Integer varbinds must be templated to integers.
+	conf.VarBinds = []*config.SNMPVarBind{
+		{OID: "1.3.6.1.4.1.8072.9999.3.1", Value: "{{ .Labels.alertname }}", Type: "integer"},
+	}
+	if err := NewSNMP(&conf, tmpl).Notify(ctx, alerts...); err == nil {
+		t.Fatalf("expected error for non-integer varbind value")
+	}
+}
+
+func TestOpsGenieNotify(t *testing.T) {
+	var (
+		path string
+		msg  struct {
+			Tags     string `json:"tags"`
+			Priority string `json:"priority"`
+		}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	conf := config.DefaultOpsGenieConfig
+	conf.APIKey = "key"
+	conf.APIHost = srv.URL + "/"
+	conf.Tags = "team-a"
+	conf.TagLabels = []model.LabelName{"job", "severity"}
+	conf.SeverityPriorities = config.DefaultOpsGenieSeverityPriorities
+
+	n := NewOpsGenie(&conf, tmpl)
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	ctx = WithGroupKey(ctx, "team-X/{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+
+	alerts := []*types.Alert{
+		{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "a", "job": "db", "severity": "warning"},
+				StartsAt: time.Now().Add(-time.Minute),
+			},
+		}, {
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": "b", "job": "db", "severity": "critical"},
+				StartsAt: time.Now().Add(-time.Minute),
+			},
+		},
+	}
+	if err := n.Notify(ctx, alerts...); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+	if path != "/v1/json/alert" {
+		t.Errorf("expected alert to be created at %q but got %q", "/v1/json/alert", path)
+	}
+	if msg.Priority != "P1" {
+		t.Errorf("expected priority %q but got %q", "P1", msg.Priority)
+	}
+	// The severity differs across alerts and is not added as a tag.
+	if msg.Tags != "team-a,job:db" {
+		t.Errorf("expected tags %q but got %q", "team-a,job:db", msg.Tags)
+	}
+
+	conf.Priority = "P4"
+	if err := n.Notify(ctx, alerts...); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+	if msg.Priority != "P4" {
+		t.Errorf("expected configured priority %q but got %q", "P4", msg.Priority)
+	}
+
+	for _, a := range alerts {
+		a.EndsAt = time.Now().Add(-time.Second)
+	}
+	if err := n.Notify(ctx, alerts...); err != nil {
+		t.Fatalf("notification failed: %s", err)
+	}
+	if path != "/v1/json/alert/close" {
+		t.Errorf("expected alert to be closed at %q but got %q", "/v1/json/alert/close", path)
+	}
+}
+
+func TestGroupKeyFormats(t *testing.T) {
+	labels := model.LabelSet{"alertname": "a"}
+
+	ctx := WithGroupKey(context.Background(), "team-X/{}:{alertname=\"a\"}")
+	ctx = WithGroupLabels(ctx, labels)
+
+	key, err := incidentKey(ctx, config.GroupKeyLegacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != uint64(labels.Fingerprint()) {
+		t.Errorf("expected legacy incident key %d but got %v", labels.Fingerprint(), key)
+	}
+	key, err = incidentKey(ctx, config.GroupKeyString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != HashGroupKey("team-X/{}:{alertname=\"a\"}") {
+		t.Errorf("expected hashed incident key but got %v", key)
+	}
+
+	for format, exp := range map[config.GroupKeyFormat]string{
+		config.GroupKeyLegacy: fmt.Sprintf(`{"version":"3","groupKey":%d}`, uint64(labels.Fingerprint())),
+		config.GroupKeyString: `{"version":"4","groupKey":"team-X/{}:{alertname=\"a\"}"}`,
+	} {
+		b, err := json.Marshal(newWebhookMessage(ctx, format, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != exp {
+			t.Errorf("expected %s message %s but got %s", format, exp, b)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	rcv := &config.Receiver{
+		Name: "team-X",
+		SlackConfigs: []*config.SlackConfig{{
+			NotifierConfig: config.NotifierConfig{VSendResolved: true},
+			Title:          `{{ .Receiver }}: {{ len .Alerts }} {{ .Status }}`,
+			Text:           `{{ range .Alerts }}{{ .Labels.alertname }} {{ end }}`,
+		}},
+		PushoverConfigs: []*config.PushoverConfig{{
+			Title: `{{ .Status }}`,
+		}},
+		EmailConfigs: []*config.EmailConfig{{
+			NotifierConfig: config.NotifierConfig{VSendResolved: true},
+			HTML:           `{{ template "missing" . }}`,
+		}},
+	}
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	alerts := []*types.Alert{
+		{Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a1"},
+			StartsAt: time.Now().Add(-time.Hour),
+			EndsAt:   time.Now().Add(-time.Minute),
+		}},
+	}
+
+	res := Render(ctx, rcv, tmpl, alerts...)
+
+	if len(res) != 3 {
+		t.Fatalf("expected 3 renderings but got %d", len(res))
+	}
+	byName := map[string]*Rendering{}
+	for _, r := range res {
+		byName[r.Integration] = r
+	}
+
+	slack := byName["slack/0"]
+	if slack == nil || slack.Error != "" || slack.Skipped {
+		t.Fatalf("unexpected slack rendering: %+v", slack)
+	}
+	if s := slack.Fields["title"]; s != "team-X: 1 resolved" {
+		t.Errorf("expected title %q but got %q", "team-X: 1 resolved", s)
+	}
+	if s := slack.Fields["text"]; s != "a1 " {
+		t.Errorf("expected text %q but got %q", "a1 ", s)
+	}
+
+	// Pushover does not send resolved notifications.
+	if po := byName["pushover/0"]; po == nil || !po.Skipped || po.Fields != nil {
+		t.Errorf("expected pushover to be skipped but got %+v", po)
+	}
+
+	if email := byName["email/0"]; email == nil || email.Error == "" || email.Fields != nil {
+		t.Errorf("expected email rendering to fail but got %+v", email)
+	}
+}
+
+func TestRenderSeverities(t *testing.T) {
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+	tmpl.SetSeverities(map[string]template.SeverityStyle{
+		"critical": {Color: "#d00000", Emoji: ":fire:", Priority: 1},
+	})
+
+	rcv := &config.Receiver{
+		Name: "team-X",
+		SlackConfigs: []*config.SlackConfig{{
+			Color:     `{{ .CommonLabels.severity | severityColor }}`,
+			IconEmoji: `{{ .CommonLabels.severity | severityEmoji }}`,
+			Title:     `P{{ severityPriority .CommonLabels.severity }}`,
+		}},
+	}
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	for sev, exp := range map[string]map[string]string{
+		"critical": {"color": "#d00000", "icon_emoji": ":fire:", "title": "P1"},
+		"info":     {"color": "", "icon_emoji": "", "title": "P0"},
+	} {
+		res := Render(ctx, rcv, tmpl, &types.Alert{Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a1", "severity": model.LabelValue(sev)},
+			StartsAt: time.Now().Add(-time.Hour),
+		}})
+		if len(res) != 1 || res[0].Error != "" {
+			t.Fatalf("unexpected renderings: %+v", res)
+		}
+		for k, v := range exp {
+			if s := res[0].Fields[k]; s != v {
+				t.Errorf("expected %s %q for severity %q but got %q", k, v, sev, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/common/log"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// maxPluginOutput is the number of bytes of a failed plugin's standard
+// error output included in the returned error.
+const maxPluginOutput = 1024
+
+// Plugins holds the executables found in the plugins directory. All methods
+// are goroutine-safe.
+type Plugins struct {
+	dir string
+
+	mtx   sync.RWMutex
+	paths map[string]string
+}
+
+// NewPlugins returns new Plugins discovered in the given directory. If the
+// directory is empty, there are no plugins.
+func NewPlugins(dir string) *Plugins {
+	return &Plugins{dir: dir, paths: map[string]string{}}
+}
+
+// Discover rescans the plugins directory for executable files. If one of
+// the receivers uses a plugin that is not found, the previously discovered
+// plugins are kept.
+func (p *Plugins) Discover(confs []*config.Receiver) error {
+	paths := map[string]string{}
+
+	if p.dir != "" {
+		fis, err := ioutil.ReadDir(p.dir)
+		if err != nil {
+			return err
+		}
+		for _, fi := range fis {
+			if !fi.Mode().IsRegular() || fi.Mode().Perm()&0111 == 0 || strings.HasPrefix(fi.Name(), ".") {
+				continue
+			}
+			paths[fi.Name()] = filepath.Join(p.dir, fi.Name())
+		}
+	}
+	for _, rc := range confs {
+		for _, pc := range rc.PluginConfigs {
+			if _, ok := paths[pc.Name]; !ok {
+				return fmt.Errorf("receiver %q uses unknown plugin %q", rc.Name, pc.Name)
+			}
+		}
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.paths = paths
+	return nil
+}
+
+// Names returns the sorted names of the discovered plugins.
+func (p *Plugins) Names() []string {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	res := make([]string, 0, len(p.paths))
+	for name := range p.paths {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Lookup returns the path of the named plugin's executable.
+func (p *Plugins) Lookup(name string) (string, bool) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	path, ok := p.paths[name]
+	return path, ok
+}
+
+// Plugin implements a Notifier running an executable in the plugins
+// directory for each notification.
+type Plugin struct {
+	conf    *config.PluginConfig
+	tmpl    *template.Template
+	plugins *Plugins
+}
+
+// NewPlugin returns a new Plugin running the configured one of the plugins.
+func NewPlugin(conf *config.PluginConfig, tmpl *template.Template, plugins *Plugins) *Plugin {
+	return &Plugin{conf: conf, tmpl: tmpl, plugins: plugins}
+}
+
+func (*Plugin) name() string { return "plugin" }
+
+// PluginMessage defines the JSON object written to the standard input of
+// plugins.
+type PluginMessage struct {
+	WebhookMessage
+
+	// The options of the plugin's configuration.
+	Options map[string]string `json:"options"`
+}
+
+// Notify implements the Notifier interface. The plugin is run with the
+// message on its standard input and must exit with status zero once the
+// notification was sent.
+func (n *Plugin) Notify(ctx context.Context, alerts ...*types.Alert) error {
+	path, ok := n.plugins.Lookup(n.conf.Name)
+	if !ok {
+		return fmt.Errorf("plugin %q not found", n.conf.Name)
+	}
+
+	groupKey, ok := GroupKey(ctx)
+	if !ok {
+		log.Errorf("group key missing")
+	}
+	msg := &PluginMessage{
+		WebhookMessage: WebhookMessage{
+			Version:  "4",
+			Data:     tmplData(ctx, n.tmpl, alerts...),
+			GroupKey: groupKey,
+		},
+		Options: n.conf.Options,
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(path)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stderr = &stderr
+
+	if err := runCommand(ctx, cmd); err != nil {
+		out := stderr.Bytes()
+		if len(out) > maxPluginOutput {
+			out = out[:maxPluginOutput]
+		}
+		if out = bytes.TrimSpace(out); len(out) > 0 {
+			return fmt.Errorf("plugin %q failed: %s: %s", n.conf.Name, err, out)
+		}
+		return fmt.Errorf("plugin %q failed: %s", n.conf.Name, err)
+	}
+	return nil
+}
+
+// runCommand runs the command and kills its process if the context is
+// done before it exits.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestPlugin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "message.json")
+	for name, script := range map[string]string{
+		"record": "#!/bin/sh\ncat > " + out + "\n",
+		"fail":   "#!/bin/sh\necho 'ticket system unavailable' >&2\nexit 3\n",
+		"hang":   "#!/bin/sh\nexec sleep 10\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Files that are not executable are not plugins.
+	if err := ioutil.WriteFile(filepath.Join(dir, "README"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins := NewPlugins(dir)
+	rcvs := []*config.Receiver{{
+		Name:          "team-X",
+		PluginConfigs: []*config.PluginConfig{{Name: "README"}},
+	}}
+	if err := plugins.Discover(rcvs); err == nil {
+		t.Fatalf("expected unknown plugin to be rejected")
+	}
+	rcvs[0].PluginConfigs[0].Name = "record"
+	if err := plugins.Discover(rcvs); err != nil {
+		t.Fatal(err)
+	}
+	if names := plugins.Names(); !reflect.DeepEqual(names, []string{"fail", "hang", "record"}) {
+		t.Fatalf("expected plugins fail, hang, and record but got %v", names)
+	}
+
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.com")
+
+	ctx := WithReceiver(context.Background(), "team-X")
+	ctx = WithGroupKey(ctx, "team-X/{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+	a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "HighLatency"}}}
+
+	n := NewPlugin(&config.PluginConfig{Name: "record", Options: map[string]string{"queue": "OPS"}}, tmpl, plugins)
+	if err := n.Notify(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg PluginMessage
+	if err := json.Unmarshal(b, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.GroupKey != "team-X/{}:{}" || msg.Options["queue"] != "OPS" || len(msg.Alerts) != 1 {
+		t.Errorf("unexpected message %s", b)
+	}
+
+	n = NewPlugin(&config.PluginConfig{Name: "fail"}, tmpl, plugins)
+	if err := n.Notify(ctx, a); err == nil || !strings.Contains(err.Error(), "ticket system unavailable") {
+		t.Errorf("expected error with the plugin's output but got %v", err)
+	}
+
+	// Plugins are killed once the notification times out.
+	tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	n = NewPlugin(&config.PluginConfig{Name: "hang"}, tmpl, plugins)
+	if err := n.Notify(tctx, a); err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("expected deadline to be exceeded but got %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected plugin to be killed but it ran for %s", d)
+	}
+}
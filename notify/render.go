@@ -0,0 +1,294 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Rendering holds the templated fields of a notification to a single
+// integration of a receiver.
+type Rendering struct {
+	// Integration is named like the integration in the receiver's fanout,
+	// e.g. "slack/0".
+	Integration string `json:"integration"`
+	// Fields maps the configuration keys of the templated fields to their
+	// rendered values. Secrets are not rendered.
+	Fields map[string]string `json:"fields,omitempty"`
+	// Skipped is true if the integration would not be notified as none of
+	// the alerts is sent to it.
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Render executes the templates of all integrations of the receiver
+// against the alerts as they would be executed when notifying them under
+// the given context. Template errors are reported per integration.
+func Render(ctx context.Context, rcv *config.Receiver, tmpl *template.Template, alerts ...*types.Alert) []*Rendering {
+	var res []*Rendering
+
+	add := func(name string, i int, c notifierConfig, render func(*template.Data) (map[string]string, error)) {
+		r := &Rendering{Integration: fmt.Sprintf("%s/%d", name, i)}
+		res = append(res, r)
+
+		as := sendable(c, alerts)
+		if len(as) == 0 {
+			r.Skipped = true
+			return
+		}
+		fields, err := render(tmplData(ctx, tmpl, as...))
+		if err != nil {
+			r.Error = err.Error()
+			return
+		}
+		r.Fields = fields
+	}
+
+	for i, c := range rcv.WebhookConfigs {
+		c := c
+		add("webhook", i, c, func(data *template.Data) (map[string]string, error) {
+			var (
+				err    error
+				text   = tmplText(tmpl, data, &err)
+				fields = map[string]string{}
+			)
+			if c.Body != "" {
+				fields["body"] = text(c.Body)
+			} else {
+				b, err := json.Marshal(newWebhookMessage(ctx, c.GroupKeyFormat, data))
+				if err != nil {
+					return nil, err
+				}
+				fields["body"] = string(b)
+			}
+			for k, v := range c.Headers {
+				fields["headers."+k] = text(v)
+			}
+			return fields, err
+		})
+	}
+	for i, c := range rcv.EmailConfigs {
+		c := c
+		add("email", i, c, func(data *template.Data) (map[string]string, error) {
+			var (
+				err  error
+				text = tmplText(tmpl, data, &err)
+				html = tmplHTML(tmpl, data, &err)
+			)
+			body := c.HTML
+			if h, ok := EmailHTML(ctx); ok {
+				body = h
+			}
+			fields := map[string]string{
+				"from": text(c.From),
+				"to":   text(c.To),
+				"html": html(body),
+			}
+			for k, v := range c.Headers {
+				fields["headers."+k] = text(v)
+			}
+			for _, img := range c.Images {
+				fields["images."+img.Name] = text(img.URL)
+			}
+			return fields, err
+		})
+	}
+	for i, c := range rcv.PagerdutyConfigs {
+		c := c
+		add("pagerduty", i, c, func(data *template.Data) (map[string]string, error) {
+			var (
+				err  error
+				text = tmplText(tmpl, data, &err)
+			)
+			fields := map[string]string{
+				"description": text(c.Description),
+				"client":      text(c.Client),
+				"client_url":  text(c.ClientURL),
+			}
+			for k, v := range c.Details {
+				fields["details."+k] = text(v)
+			}
+			return fields, err
+		})
+	}
+	for i, c := range rcv.OpsGenieConfigs {
+		c := c
+		add("opsgenie", i, c, func(data *template.Data) (map[string]string, error) {
+			var (
+				err  error
+				text = tmplText(tmpl, data, &err)
+			)
+			fields := map[string]string{
+				"description": text(c.Description),
+				"source":      text(c.Source),
+				"teams":       text(c.Teams),
+				"tags":        text(c.Tags),
+				"priority":    text(c.Priority),
+			}
+			for k, v := range c.Details {
+				fields["details."+k] = text(v)
+			}
+			return fields, err
+		})
+	}
+	for i, c := range rcv.SlackConfigs {
+		c := c
+		add("slack", i, c, func(data *template.Data) (map[string]string, error) {
+			var (
+				err  error
+				text = tmplText(tmpl, data, &err)
+			)
+			fields := map[string]string{
+				"channel":    text(c.Channel),
+				"username":   text(c.Username),
+				"icon_emoji": text(c.IconEmoji),
+				"color":      text(c.Color),
+				"title":      text(c.Title),
+				"title_link": text(c.TitleLink),
+				"pretext":    text(c.Pretext),
+				"text":       text(c.Text),
+				"fallback":   text(c.Fallback),
+			}
+			return fields, err
+		})
+	}
+	for i, c := range rcv.HipchatConfigs {
+		c := c
+		add("hipchat", i, c, func(data *template.Data) (map[string]string, error) {
+			var (
+				err  error
+				text = tmplText(tmpl, data, &err)
+			)
+			fields := map[string]string{
+				"from":  text(c.From),
+				"color": text(c.Color),
+			}
+			if c.MessageFormat == "html" {
+				fields["message"] = tmplHTML(tmpl, data, &err)(c.Message)
+			} else {
+				fields["message"] = text(c.Message)
+			}
+			return fields, err
+		})
+	}
+	for i, c := range rcv.PushoverConfigs {
+		c := c
+		add("pushover", i, c, func(data *template.Data) (map[string]string, error) {
+			var (
+				err  error
+				text = tmplText(tmpl, data, &err)
+			)
+			fields := map[string]string{
+				"title":    text(c.Title),
+				"message":  text(c.Message),
+				"url":      text(c.URL),
+				"priority": text(c.Priority),
+			}
+			return fields, err
+		})
+	}
+	for i, c := range rcv.TeamsConfigs {
+		c := c
+		add("teams", i, c, func(data *template.Data) (map[string]string, error) {
+			var (
+				err  error
+				text = tmplText(tmpl, data, &err)
+			)
+			fields := map[string]string{
+				"title": text(c.Title),
+				"text":  text(c.Text),
+			}
+			for j, a := range data.Alerts {
+				if err != nil || j == c.MaxAlerts {
+					break
+				}
+				if fields[fmt.Sprintf("alerts.%d.title", j)], err = tmpl.ExecuteTextString(c.AlertTitle, a); err != nil {
+					break
+				}
+				fields[fmt.Sprintf("alerts.%d.text", j)], err = tmpl.ExecuteTextString(c.AlertText, a)
+			}
+			return fields, err
+		})
+	}
+	for i, c := range rcv.TelegramConfigs {
+		c := c
+		add("telegram", i, c, func(data *template.Data) (map[string]string, error) {
+			var (
+				err  error
+				text = tmplText(tmpl, data, &err)
+			)
+			fields := map[string]string{
+				"message": text(c.Message),
+			}
+			return fields, err
+		})
+	}
+	for i, c := range rcv.JiraConfigs {
+		c := c
+		add("jira", i, c, func(data *template.Data) (map[string]string, error) {
+			var (
+				err  error
+				text = tmplText(tmpl, data, &err)
+			)
+			fields := map[string]string{
+				"summary":     truncateRunes(text(c.Summary), jiraMaxSummaryLength),
+				"description": text(c.Description),
+				"priority":    text(c.Priority),
+			}
+			return fields, err
+		})
+	}
+	for i, c := range rcv.SNMPConfigs {
+		c := c
+		add("snmp", i, c, func(data *template.Data) (map[string]string, error) {
+			fields := map[string]string{}
+			for j, a := range data.Alerts {
+				for _, vb := range c.VarBinds {
+					v, err := tmpl.ExecuteTextString(vb.Value, a)
+					if err != nil {
+						return fields, err
+					}
+					fields[fmt.Sprintf("alerts.%d.%s", j, vb.OID)] = v
+				}
+			}
+			return fields, nil
+		})
+	}
+	for i, c := range rcv.AlertmanagerConfigs {
+		c := c
+		add("alertmanager", i, c, func(*template.Data) (map[string]string, error) {
+			b, err := json.Marshal(forwardedAlerts(c.MaxHops, sendable(c, alerts)...))
+			if err != nil {
+				return nil, err
+			}
+			return map[string]string{"body": string(b)}, nil
+		})
+	}
+
+	return res
+}
+
+// RenderText executes the text template against the alerts as the
+// templates of integrations are executed when notifying them under the
+// given context.
+func RenderText(ctx context.Context, tmpl *template.Template, text string, alerts ...*types.Alert) (string, error) {
+	return tmpl.ExecuteTextString(text, tmplData(ctx, tmpl, alerts...))
+}
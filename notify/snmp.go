@@ -0,0 +1,133 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/snmp"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// SNMP implements a Notifier sending an SNMP trap for each alert.
+type SNMP struct {
+	conf *config.SNMPConfig
+	tmpl *template.Template
+
+	sender          *snmp.Sender
+	trapOID         snmp.OID
+	resolvedTrapOID snmp.OID
+	varBinds        []snmp.OID
+	// err is returned on notification if the configuration could not be
+	// applied.
+	err error
+}
+
+// NewSNMP returns a new SNMP trap notification handler.
+func NewSNMP(conf *config.SNMPConfig, tmpl *template.Template) *SNMP {
+	n := &SNMP{conf: conf, tmpl: tmpl}
+	n.err = n.init()
+	return n
+}
+
+func (n *SNMP) init() error {
+	var err error
+	if n.trapOID, err = snmp.ParseOID(n.conf.TrapOID); err != nil {
+		return err
+	}
+	n.resolvedTrapOID = n.trapOID
+	if n.conf.ResolvedTrapOID != "" {
+		if n.resolvedTrapOID, err = snmp.ParseOID(n.conf.ResolvedTrapOID); err != nil {
+			return err
+		}
+	}
+	for _, vb := range n.conf.VarBinds {
+		oid, err := snmp.ParseOID(vb.OID)
+		if err != nil {
+			return err
+		}
+		n.varBinds = append(n.varBinds, oid)
+	}
+
+	version, sec := snmp.V2c, (*snmp.Security)(nil)
+	if n.conf.Version == "v3" {
+		version = snmp.V3
+		sec = &snmp.Security{
+			UserName:     n.conf.User,
+			AuthProtocol: n.conf.AuthProtocol,
+			AuthPassword: string(n.conf.AuthPassword),
+			PrivProtocol: n.conf.PrivProtocol,
+			PrivPassword: string(n.conf.PrivPassword),
+		}
+		if n.conf.EngineID != "" {
+			if sec.EngineID, err = hex.DecodeString(strings.TrimPrefix(n.conf.EngineID, "0x")); err != nil {
+				return fmt.Errorf("invalid engine ID: %s", err)
+			}
+		}
+	}
+	n.sender, err = snmp.NewSender(n.conf.Target, version, string(n.conf.Community), sec)
+	return err
+}
+
+func (*SNMP) name() string { return "snmp" }
+
+// Notify implements the Notifier interface.
+func (n *SNMP) Notify(ctx context.Context, as ...*types.Alert) error {
+	if n.err != nil {
+		return n.err
+	}
+	data := tmplData(ctx, n.tmpl, as...)
+
+	traps := make([]*snmp.Trap, 0, len(data.Alerts))
+	for _, a := range data.Alerts {
+		t, err := n.trap(a)
+		if err != nil {
+			return err
+		}
+		traps = append(traps, t)
+	}
+	return n.sender.Send(ctx, traps...)
+}
+
+// trap returns the trap of the alert.
+func (n *SNMP) trap(a template.Alert) (*snmp.Trap, error) {
+	t := &snmp.Trap{OID: n.trapOID}
+	if a.Status == string(model.AlertResolved) {
+		t.OID = n.resolvedTrapOID
+	}
+	for i, vb := range n.conf.VarBinds {
+		s, err := n.tmpl.ExecuteTextString(vb.Value, a)
+		if err != nil {
+			return nil, fmt.Errorf("templating error: %s", err)
+		}
+		var v interface{} = s
+		if vb.Type == "integer" {
+			x, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid integer value of varbind %s: %s", vb.OID, err)
+			}
+			v = int32(x)
+		}
+		t.VarBinds = append(t.VarBinds, snmp.VarBind{OID: n.varBinds[i], Value: v})
+	}
+	return t, nil
+}
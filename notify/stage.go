@@ -0,0 +1,126 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/tracing"
+)
+
+// Stages of the notification pipeline as exposed in metrics.
+const (
+	StageSilence     = "silence"
+	StageInhibit     = "inhibit"
+	StageFlap        = "flap"
+	StageEnrich      = "enrich"
+	StageDedup       = "dedup"
+	StageRetry       = "retry"
+	StageIntegration = "integration"
+)
+
+var (
+	stageAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notify_stage_alerts_total",
+		Help:      "The total number of alerts entering a notification pipeline stage.",
+	}, []string{"receiver", "stage"})
+
+	stageAlertsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notify_stage_alerts_dropped_total",
+		Help:      "The total number of alerts not passed on by a notification pipeline stage.",
+	}, []string{"receiver", "stage"})
+
+	stageFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notify_stage_failures_total",
+		Help:      "The total number of failed executions of a notification pipeline stage.",
+	}, []string{"receiver", "stage"})
+
+	stageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "alertmanager",
+		Name:      "notify_stage_duration_seconds",
+		Help:      "Time spent in a notification pipeline stage before passing alerts on.",
+		Buckets:   []float64{.001, .01, .1, .5, 1, 5, 10, 30, 60, 300},
+	}, []string{"receiver", "stage"})
+)
+
+func init() {
+	prometheus.Register(stageAlerts)
+	prometheus.Register(stageAlertsDropped)
+	prometheus.Register(stageFailures)
+	prometheus.Register(stageDuration)
+}
+
+// stageObserver records the metrics and the span of a single execution of
+// a pipeline stage.
+type stageObserver struct {
+	receiver string
+	stage    string
+	alerts   int
+	start    time.Time
+
+	// ctx holds the stage's span. Notifiers wrapped by the stage are
+	// called with it so that their spans are children of the stage's.
+	ctx  context.Context
+	span *tracing.Span
+}
+
+// observeStage starts observing the execution of the stage for the given
+// alerts. Stages within a fanout are labeled with the receiver suffixed by
+// the integration.
+func observeStage(ctx context.Context, stage string, alerts int) *stageObserver {
+	var receiver string
+	if ctx != nil {
+		receiver, _ = Receiver(ctx)
+	}
+
+	stageAlerts.WithLabelValues(receiver, stage).Add(float64(alerts))
+
+	ctx, span := tracing.Start(ctx, "notify."+stage,
+		tracing.String("receiver", receiver),
+		tracing.Int("alerts", alerts),
+	)
+	return &stageObserver{
+		receiver: receiver,
+		stage:    stage,
+		alerts:   alerts,
+		start:    time.Now(),
+		ctx:      ctx,
+		span:     span,
+	}
+}
+
+// done records that the stage passed on the given number of alerts or
+// failed with the given error. It must be called before later stages are
+// executed so that they are not accounted to the stage.
+func (o *stageObserver) done(passed int, err error) {
+	stageDuration.WithLabelValues(o.receiver, o.stage).Observe(time.Since(o.start).Seconds())
+
+	if err != nil {
+		stageFailures.WithLabelValues(o.receiver, o.stage).Inc()
+		passed = 0
+	}
+	if dropped := o.alerts - passed; dropped > 0 {
+		stageAlertsDropped.WithLabelValues(o.receiver, o.stage).Add(float64(dropped))
+	}
+
+	o.span.SetAttributes(tracing.Int("alerts_passed", passed))
+	o.span.SetError(err)
+	o.span.End()
+}
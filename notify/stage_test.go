@@ -0,0 +1,59 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+func counterValue(t *testing.T, c *prometheus.CounterVec, lvs ...string) float64 {
+	var m dto.Metric
+	if err := c.WithLabelValues(lvs...).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestStageObserver(t *testing.T) {
+	ctx := WithReceiver(context.Background(), "stage-test")
+
+	obs := observeStage(ctx, StageSilence, 5)
+	obs.done(3, nil)
+
+	obs = observeStage(ctx, StageSilence, 2)
+	obs.done(2, errors.New("failed"))
+
+	if v := counterValue(t, stageAlerts, "stage-test", StageSilence); v != 7 {
+		t.Errorf("expected 7 alerts but got %v", v)
+	}
+	if v := counterValue(t, stageAlertsDropped, "stage-test", StageSilence); v != 4 {
+		t.Errorf("expected 4 dropped alerts but got %v", v)
+	}
+	if v := counterValue(t, stageFailures, "stage-test", StageSilence); v != 1 {
+		t.Errorf("expected 1 failure but got %v", v)
+	}
+
+	var m dto.Metric
+	if err := stageDuration.WithLabelValues("stage-test", StageSilence).(prometheus.Histogram).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if n := m.GetHistogram().GetSampleCount(); n != 2 {
+		t.Errorf("expected 2 observed durations but got %d", n)
+	}
+}
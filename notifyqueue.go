@@ -0,0 +1,280 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var (
+	notifyQueueLength = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "dispatcher_notify_queue_length",
+		Help:      "The number of notifications waiting for a notify worker by receiver and route priority.",
+	}, []string{"receiver", "priority"})
+
+	notifyQueueWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "alertmanager",
+		Name:      "dispatcher_notify_queue_wait_seconds",
+		Help:      "How long notifications waited for a notify worker by route priority.",
+		Buckets:   []float64{.01, .1, 1, 5, 10, 30, 60, 300},
+	}, []string{"priority"})
+
+	notifyQueueFull = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "dispatcher_notify_queue_full_total",
+		Help:      "The total number of flushes of aggregation groups that waited for space in the full notify queue.",
+	})
+
+	notificationsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "dispatcher_notifications_in_flight",
+		Help:      "The number of notifications being sent by notify workers by receiver.",
+	}, []string{"receiver"})
+)
+
+func init() {
+	prometheus.MustRegister(notifyQueueLength)
+	prometheus.MustRegister(notifyQueueWait)
+	prometheus.MustRegister(notifyQueueFull)
+	prometheus.MustRegister(notificationsInFlight)
+}
+
+// notifyJob is a notification of an aggregation group waiting for a notify
+// worker.
+type notifyJob struct {
+	ctx      context.Context
+	alerts   []*types.Alert
+	notify   notifyFunc
+	receiver string
+	priority int
+	// Sequence number keeping jobs of the same priority in order.
+	seq      uint64
+	queuedAt time.Time
+	// Receives the result of the notification.
+	done chan error
+}
+
+// before returns true iff the job is to be sent before the other one.
+func (j *notifyJob) before(o *notifyJob) bool {
+	if j.priority != o.priority {
+		return j.priority > o.priority
+	}
+	return j.seq < o.seq
+}
+
+func (j *notifyJob) labels() prometheus.Labels {
+	return prometheus.Labels{"receiver": j.receiver, "priority": strconv.Itoa(j.priority)}
+}
+
+// A NotifyQueue sends the notifications of aggregation groups through a
+// bounded number of workers. Notifications of groups with a higher route
+// priority are sent first if all workers are busy. The number of
+// notifications sent to a receiver at once may be limited further.
+//
+// Once the queue is full, flushes of aggregation groups wait for space so
+// that alerts keep accumulating in the groups instead.
+type NotifyQueue struct {
+	workers int
+	// Holds a token for every queued notification.
+	slots chan struct{}
+
+	mtx     sync.Mutex
+	cond    *sync.Cond
+	jobs    []*notifyJob
+	seq     uint64
+	stopped bool
+	// Maximum and current number of notifications in flight by receiver.
+	limits map[string]int
+	active map[string]int
+
+	wg sync.WaitGroup
+}
+
+// NewNotifyQueue returns a new NotifyQueue with the given number of
+// workers holding at most size notifications.
+func NewNotifyQueue(workers, size int) *NotifyQueue {
+	q := &NotifyQueue{
+		workers: workers,
+		slots:   make(chan struct{}, size),
+		limits:  map[string]int{},
+		active:  map[string]int{},
+	}
+	q.cond = sync.NewCond(&q.mtx)
+	return q
+}
+
+// SetReceivers sets the limits of notifications sent to each receiver at
+// once. Receivers without a limit are only bounded by the number of
+// workers.
+func (q *NotifyQueue) SetReceivers(rcvs []*config.Receiver) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.limits = map[string]int{}
+	for _, rcv := range rcvs {
+		if rcv.MaxConcurrentNotifications > 0 {
+			q.limits[rcv.Name] = rcv.MaxConcurrentNotifications
+		}
+	}
+	q.cond.Broadcast()
+}
+
+// Run starts the queue's workers.
+func (q *NotifyQueue) Run() {
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+}
+
+// Stop the queue's workers after the notifications in progress finished.
+// Queued notifications fail.
+func (q *NotifyQueue) Stop() {
+	q.mtx.Lock()
+	q.stopped = true
+	for _, j := range q.jobs {
+		j.done <- fmt.Errorf("notify queue stopped")
+		notifyQueueLength.With(j.labels()).Dec()
+		<-q.slots
+	}
+	q.jobs = nil
+	q.cond.Broadcast()
+	q.mtx.Unlock()
+
+	q.wg.Wait()
+}
+
+// Notify queues the notification with the given priority and waits until
+// a worker sent it through nf. If the queue is full, it first waits for
+// space. It returns early if the context is canceled before.
+func (q *NotifyQueue) Notify(ctx context.Context, priority int, nf notifyFunc, alerts ...*types.Alert) error {
+	receiver, _ := notify.Receiver(ctx)
+
+	j := &notifyJob{
+		ctx:      ctx,
+		alerts:   alerts,
+		notify:   nf,
+		receiver: receiver,
+		priority: priority,
+		done:     make(chan error, 1),
+	}
+
+	select {
+	case q.slots <- struct{}{}:
+	default:
+		notifyQueueFull.Inc()
+
+		select {
+		case q.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	q.mtx.Lock()
+	if q.stopped {
+		q.mtx.Unlock()
+		<-q.slots
+		return fmt.Errorf("notify queue stopped")
+	}
+	j.seq = q.seq
+	j.queuedAt = time.Now()
+	q.seq++
+	q.jobs = append(q.jobs, j)
+	notifyQueueLength.With(j.labels()).Inc()
+	q.cond.Broadcast()
+	q.mtx.Unlock()
+
+	select {
+	case err := <-j.done:
+		return err
+	case <-ctx.Done():
+		// The worker skips the job once it finds its context canceled.
+		return ctx.Err()
+	}
+}
+
+// next removes and returns the first job whose receiver is below its
+// limit. It returns nil if there is none. The caller must hold the lock.
+func (q *NotifyQueue) next() *notifyJob {
+	i := -1
+	for k, j := range q.jobs {
+		if l, ok := q.limits[j.receiver]; ok && q.active[j.receiver] >= l {
+			continue
+		}
+		if i < 0 || j.before(q.jobs[i]) {
+			i = k
+		}
+	}
+	if i < 0 {
+		return nil
+	}
+	j := q.jobs[i]
+	q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+	return j
+}
+
+func (q *NotifyQueue) work() {
+	defer q.wg.Done()
+
+	for {
+		q.mtx.Lock()
+		var j *notifyJob
+		for !q.stopped {
+			if j = q.next(); j != nil {
+				break
+			}
+			q.cond.Wait()
+		}
+		if q.stopped {
+			q.mtx.Unlock()
+			return
+		}
+		q.active[j.receiver]++
+		q.mtx.Unlock()
+
+		<-q.slots
+		notifyQueueLength.With(j.labels()).Dec()
+
+		if err := j.ctx.Err(); err != nil {
+			j.done <- err
+		} else {
+			notifyQueueWait.WithLabelValues(strconv.Itoa(j.priority)).Observe(time.Since(j.queuedAt).Seconds())
+			notificationsInFlight.WithLabelValues(j.receiver).Inc()
+
+			j.done <- j.notify(j.ctx, j.alerts...)
+
+			notificationsInFlight.WithLabelValues(j.receiver).Dec()
+		}
+
+		q.mtx.Lock()
+		if q.active[j.receiver]--; q.active[j.receiver] == 0 {
+			delete(q.active, j.receiver)
+		}
+		// Jobs of the receiver may have become eligible.
+		q.cond.Broadcast()
+		q.mtx.Unlock()
+	}
+}
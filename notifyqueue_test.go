@@ -0,0 +1,235 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestNotifyQueuePriority(t *testing.T) {
+	q := NewNotifyQueue(1, 10)
+	q.Run()
+	defer q.Stop()
+
+	var (
+		mtx     sync.Mutex
+		order   []string
+		release = make(chan struct{})
+		started = make(chan struct{})
+	)
+	record := func(name string) notifyFunc {
+		return func(ctx context.Context, alerts ...*types.Alert) error {
+			mtx.Lock()
+			order = append(order, name)
+			mtx.Unlock()
+			return nil
+		}
+	}
+	// Occupy the only worker until all other notifications are queued.
+	go q.Notify(context.Background(), 0, func(ctx context.Context, alerts ...*types.Alert) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	var wg sync.WaitGroup
+	for i, j := range []struct {
+		name     string
+		priority int
+	}{
+		{"low-1", 0},
+		{"critical", 10},
+		{"low-2", 0},
+		{"high", 5},
+	} {
+		wg.Add(1)
+		go func(name string, priority int) {
+			defer wg.Done()
+			if err := q.Notify(context.Background(), priority, record(name)); err != nil {
+				t.Error(err)
+			}
+		}(j.name, j.priority)
+
+		// Wait for the notification to be queued to fix the order of
+		// notifications with the same priority.
+		for {
+			q.mtx.Lock()
+			n := len(q.jobs)
+			q.mtx.Unlock()
+			if n == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	if exp := []string{"critical", "high", "low-1", "low-2"}; !reflect.DeepEqual(order, exp) {
+		t.Fatalf("expected notifications in order %v but got %v", exp, order)
+	}
+}
+
+func TestNotifyQueueCanceled(t *testing.T) {
+	q := NewNotifyQueue(1, 10)
+	q.Run()
+	defer q.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	started := make(chan struct{})
+	go q.Notify(context.Background(), 0, func(ctx context.Context, alerts ...*types.Alert) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	called := false
+	err := q.Notify(ctx, 0, func(ctx context.Context, alerts ...*types.Alert) error {
+		called = true
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline to be exceeded while queued but got %v", err)
+	}
+	if called {
+		t.Fatalf("expected notification not to be sent")
+	}
+}
+
+func TestNotifyQueueReceiverLimit(t *testing.T) {
+	q := NewNotifyQueue(3, 10)
+	q.SetReceivers([]*config.Receiver{
+		{Name: "team-A", MaxConcurrentNotifications: 1},
+	})
+	q.Run()
+	defer q.Stop()
+
+	var (
+		mtx     sync.Mutex
+		active  = map[string]int{}
+		maxSeen = map[string]int{}
+		release = make(chan struct{})
+		started = make(chan string, 3)
+	)
+	nf := func(ctx context.Context, alerts ...*types.Alert) error {
+		receiver, _ := notify.Receiver(ctx)
+
+		mtx.Lock()
+		if active[receiver]++; active[receiver] > maxSeen[receiver] {
+			maxSeen[receiver] = active[receiver]
+		}
+		mtx.Unlock()
+
+		started <- receiver
+		<-release
+
+		mtx.Lock()
+		active[receiver]--
+		mtx.Unlock()
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, receiver := range []string{"team-A", "team-A", "team-B"} {
+		wg.Add(1)
+		go func(receiver string) {
+			defer wg.Done()
+			if err := q.Notify(notify.WithReceiver(context.Background(), receiver), 0, nf); err != nil {
+				t.Error(err)
+			}
+		}(receiver)
+	}
+
+	// Only one notification of team-A and the one of team-B start while
+	// a worker is left idle.
+	got := map[string]int{}
+	for i := 0; i < 2; i++ {
+		got[<-started]++
+	}
+	if got["team-A"] != 1 || got["team-B"] != 1 {
+		t.Fatalf("expected one notification of each receiver to start but got %v", got)
+	}
+	select {
+	case r := <-started:
+		t.Fatalf("expected notification of %s to wait for the receiver's limit", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	wg.Wait()
+
+	if maxSeen["team-A"] != 1 {
+		t.Fatalf("expected at most 1 notification of team-A at once but got %d", maxSeen["team-A"])
+	}
+}
+
+func TestNotifyQueueFull(t *testing.T) {
+	q := NewNotifyQueue(1, 1)
+	q.Run()
+	defer q.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	block := func(ctx context.Context, alerts ...*types.Alert) error {
+		<-release
+		return nil
+	}
+	started := make(chan struct{})
+	go q.Notify(context.Background(), 0, func(ctx context.Context, alerts ...*types.Alert) error {
+		close(started)
+		return block(ctx)
+	})
+	<-started
+
+	// Fill the queue's only slot.
+	go q.Notify(context.Background(), 0, block)
+	for {
+		q.mtx.Lock()
+		n := len(q.jobs)
+		q.mtx.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := q.Notify(ctx, 10, block); err != context.DeadlineExceeded {
+		t.Fatalf("expected flush to wait for space in the full queue but got %v", err)
+	}
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if len(q.jobs) != 1 {
+		t.Fatalf("expected notification not to be queued but got %d queued", len(q.jobs))
+	}
+}
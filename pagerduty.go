@@ -0,0 +1,196 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+)
+
+// Types of PagerDuty webhook events acted upon.
+const (
+	pagerDutyIncidentAcknowledged = "incident.acknowledged"
+	pagerDutyIncidentResolved     = "incident.resolved"
+)
+
+// pagerDutyWebhook is the body of a PagerDuty V3 webhook request.
+//
+// https://developer.pagerduty.com/docs/webhooks/v3-overview/
+type pagerDutyWebhook struct {
+	Event struct {
+		ID        string `json:"id"`
+		EventType string `json:"event_type"`
+		Agent     *struct {
+			Summary string `json:"summary"`
+		} `json:"agent"`
+		Data struct {
+			ID          string `json:"id"`
+			HTMLURL     string `json:"html_url"`
+			IncidentKey string `json:"incident_key"`
+		} `json:"data"`
+	} `json:"event"`
+}
+
+// creator returns the author recorded for acknowledgements and silences
+// made on behalf of the webhook event.
+func (wh *pagerDutyWebhook) creator() string {
+	if a := wh.Event.Agent; a != nil && a.Summary != "" {
+		return a.Summary + " (PagerDuty)"
+	}
+	return "PagerDuty"
+}
+
+// incident returns a reference to the incident for comments.
+func (wh *pagerDutyWebhook) incident() string {
+	if wh.Event.Data.HTMLURL != "" {
+		return wh.Event.Data.HTMLURL
+	}
+	return wh.Event.Data.ID
+}
+
+// verifyPagerDutySignature returns true iff one of the signatures in the
+// X-PagerDuty-Signature header is the HMAC of the body under the secret.
+func verifyPagerDutySignature(secret config.Secret, body []byte, header string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := []byte("v1=" + hex.EncodeToString(mac.Sum(nil)))
+
+	for _, sig := range strings.Split(header, ",") {
+		if hmac.Equal([]byte(strings.TrimSpace(sig)), expected) {
+			return true
+		}
+	}
+	return false
+}
+
+// pagerDutyWebhookResult is the response to a PagerDuty webhook request.
+type pagerDutyWebhookResult struct {
+	Acked     []model.Fingerprint `json:"acked,omitempty"`
+	SilenceID uint64              `json:"silenceId,omitempty"`
+}
+
+func (api *API) pagerDutyWebhook(w http.ResponseWriter, r *http.Request) {
+	api.mtx.RLock()
+	conf := api.pagerDutyWebhookConf
+	api.mtx.RUnlock()
+
+	if conf == nil {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("PagerDuty webhook not configured"),
+		}, nil)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if !verifyPagerDutySignature(conf.Secret, body, r.Header.Get("X-PagerDuty-Signature")) {
+		respondError(w, apiError{
+			typ: errorUnauthorized,
+			err: fmt.Errorf("invalid signature"),
+		}, nil)
+		return
+	}
+
+	var wh pagerDutyWebhook
+	if err := json.Unmarshal(body, &wh); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var res pagerDutyWebhookResult
+
+	key := wh.Event.Data.IncidentKey
+	if key == "" {
+		respond(w, &res)
+		return
+	}
+	switch wh.Event.EventType {
+	case pagerDutyIncidentAcknowledged, pagerDutyIncidentResolved:
+	default:
+		respond(w, &res)
+		return
+	}
+
+	labels, alerts, err := api.dispatcher().GroupByHash(key)
+	if err == provider.ErrNotFound {
+		// The incident may not have been triggered by this Alertmanager or
+		// its group is gone already.
+		log.With("incident", wh.Event.Data.ID).Debug("No alert group for PagerDuty incident")
+		respond(w, &res)
+		return
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var (
+		firing = firingAlerts(alerts)
+		now    = time.Now()
+	)
+	switch wh.Event.EventType {
+	case pagerDutyIncidentAcknowledged:
+		comment := "Acknowledged in PagerDuty incident " + wh.incident()
+		res.Acked, err = api.ackAlerts(firing, wh.creator(), comment, now)
+
+	case pagerDutyIncidentResolved:
+		// Incidents resolved because the group's alerts resolved need no
+		// silence.
+		if len(firing) == 0 {
+			break
+		}
+		// A silence without matchers would mute all alerts.
+		if len(labels) == 0 {
+			log.With("incident", wh.Event.Data.ID).Warn("Not silencing alert group without grouping labels")
+			break
+		}
+		comment := "Resolved in PagerDuty incident " + wh.incident()
+		res.SilenceID, err = api.silenceGroup(labels, time.Duration(conf.SilenceDuration), wh.creator(), comment, now)
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	respond(w, &res)
+}
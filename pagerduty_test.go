@@ -0,0 +1,162 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/provider/boltmem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestPagerDutyWebhook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pagerduty_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	acks, err := boltmem.NewAcks(dir, types.NewMarker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer acks.Close()
+
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "pagerduty",
+			GroupBy:        map[model.LabelName]struct{}{"service": struct{}{}},
+			GroupWait:      time.Hour,
+			GroupInterval:  time.Hour,
+			RepeatInterval: time.Hour,
+		},
+	}
+	d := NewDispatcher(nil, route, nil, nil)
+	defer d.cancel()
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "down", "service": "db"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	d.processAlert(alert, route)
+
+	var key string
+	for _, ag := range d.aggrGroups.byRoute()[route] {
+		key = notify.HashGroupKey(ag.groupKey())
+	}
+
+	silences := provider.NewMemSilences()
+
+	api := NewAPI(nil, silences, nil, acks, nil, nil, nil, nil, "", func() *Dispatcher {
+		return d
+	})
+
+	const secret = "s3cr3t"
+
+	call := func(eventType, incidentKey, signSecret string) (int, *pagerDutyWebhookResult) {
+		body := fmt.Sprintf(`{"event": {
+			"id": "01",
+			"event_type": %q,
+			"agent": {"summary": "Jane Doe"},
+			"data": {"id": "PGR0VU2", "html_url": "https://example.pagerduty.com/incidents/PGR0VU2", "incident_key": %q}
+		}}`, eventType, incidentKey)
+
+		mac := hmac.New(sha256.New, []byte(signSecret))
+		mac.Write([]byte(body))
+
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("X-PagerDuty-Signature", "v1=other, v1="+hex.EncodeToString(mac.Sum(nil)))
+
+		w := httptest.NewRecorder()
+		api.pagerDutyWebhook(w, req)
+
+		var res struct {
+			Data *pagerDutyWebhookResult `json:"data"`
+		}
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, res.Data
+	}
+
+	if code, _ := call(pagerDutyIncidentAcknowledged, key, secret); code != http.StatusNotFound {
+		t.Fatalf("expected unconfigured webhook to be not found but got status %d", code)
+	}
+
+	api.SetPagerdutyWebhook(&config.PagerdutyWebhook{
+		Secret:          secret,
+		SilenceDuration: model.Duration(time.Hour),
+	})
+
+	if code, _ := call(pagerDutyIncidentAcknowledged, key, "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("expected invalid signature to be rejected but got status %d", code)
+	}
+
+	// Incidents of other groups are ignored.
+	code, res := call(pagerDutyIncidentAcknowledged, "unknown", secret)
+	if code != http.StatusOK || len(res.Acked) != 0 {
+		t.Fatalf("expected unknown incident to be ignored but got status %d, %+v", code, res)
+	}
+
+	code, res = call(pagerDutyIncidentAcknowledged, key, secret)
+	if code != http.StatusOK {
+		t.Fatalf("expected acknowledgement to succeed but got status %d", code)
+	}
+	if len(res.Acked) != 1 || res.Acked[0] != alert.Fingerprint() {
+		t.Fatalf("expected alert %v to be acknowledged but got %v", alert.Fingerprint(), res.Acked)
+	}
+	ack, err := acks.Get(alert.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.CreatedBy != "Jane Doe (PagerDuty)" {
+		t.Fatalf("expected acknowledgement by %q but got %q", "Jane Doe (PagerDuty)", ack.CreatedBy)
+	}
+
+	code, res = call(pagerDutyIncidentResolved, key, secret)
+	if code != http.StatusOK || res.SilenceID == 0 {
+		t.Fatalf("expected silence to be created but got status %d, %+v", code, res)
+	}
+	sil, err := silences.Get(res.SilenceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sil.Matchers) != 1 || sil.Matchers[0].Name != "service" || sil.Matchers[0].Value != "db" {
+		t.Fatalf("expected silence matching the group labels but got %v", sil.Matchers)
+	}
+	if d := sil.EndsAt.Sub(sil.StartsAt); d != time.Hour {
+		t.Fatalf("expected silence lasting 1h but got %v", d)
+	}
+}
@@ -17,9 +17,10 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
 	"path/filepath"
-	"sync"
 	"strconv"
+	"sync"
 
 	"github.com/boltdb/bolt"
 	"github.com/prometheus/alertmanager/provider"
@@ -36,7 +37,8 @@ var (
 
 // Alerts gives access to a set of alerts. All methods are goroutine-safe.
 type Alerts struct {
-	db *bolt.DB
+	db    *bolt.DB
+	index *provider.LabelIndex
 
 	mtx       sync.RWMutex
 	listeners map[int]chan *types.Alert
@@ -53,11 +55,24 @@ func NewAlerts(path string) (*Alerts, error) {
 		_, err := tx.CreateBucketIfNotExists(bktAlerts)
 		return err
 	})
-	return &Alerts{
+	if err != nil {
+		return nil, err
+	}
+	a := &Alerts{
 		db:        db,
+		index:     provider.NewLabelIndex(),
 		listeners: map[int]chan *types.Alert{},
 		next:      0,
-	}, err
+	}
+	// Build the label index over the alerts that are already stored.
+	alerts, err := a.getPending()
+	if err != nil {
+		return nil, err
+	}
+	for _, al := range alerts {
+		a.index.Add(al.Labels)
+	}
+	return a, nil
 }
 
 // Close the alert provider.
@@ -149,6 +164,37 @@ func (a *Alerts) getPending() ([]*types.Alert, error) {
 	return alerts, err
 }
 
+// Query returns all alerts whose labels are matched by all of the
+// given matchers. Only alerts selected by the label index are decoded.
+func (a *Alerts) Query(ms types.Matchers) ([]*types.Alert, error) {
+	var alerts []*types.Alert
+
+	err := a.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktAlerts)
+
+		for _, fp := range a.index.Candidates(ms) {
+			k := make([]byte, 8)
+			binary.BigEndian.PutUint64(k, uint64(fp))
+
+			v := b.Get(k)
+			if v == nil {
+				continue
+			}
+			var a types.Alert
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+			if !ms.Match(a.Labels) {
+				continue
+			}
+			a.ID = strconv.FormatUint(uint64(fp), 10)
+			alerts = append(alerts, &a)
+		}
+		return nil
+	})
+	return alerts, err
+}
+
 // Get returns the alert for a given fingerprint.
 func (a *Alerts) Get(fp model.Fingerprint) (*types.Alert, error) {
 	var alert types.Alert
@@ -203,6 +249,7 @@ func (a *Alerts) Put(alerts ...*types.Alert) error {
 			if err := b.Put(fp, ab); err != nil {
 				return fmt.Errorf("writing alert failed: %s", err)
 			}
+			a.index.Add(alert.Labels)
 
 			// Send the update to all subscribers.
 			for _, ch := range a.listeners {
@@ -234,6 +281,18 @@ func NewSilences(path string, mk types.Marker) (*Silences, error) {
 	return &Silences{db: db, mk: mk}, err
 }
 
+// backup calls fn with a consistent snapshot of the database.
+func backup(db *bolt.DB, fn func(int64, io.WriterTo) error) error {
+	return db.View(func(tx *bolt.Tx) error {
+		return fn(tx.Size(), tx)
+	})
+}
+
+// Backup implements the provider.Backuper interface.
+func (s *Silences) Backup(fn func(size int64, snapshot io.WriterTo) error) error {
+	return backup(s.db, fn)
+}
+
 // Close the silences provider.
 func (s *Silences) Close() error {
 	return s.db.Close()
@@ -270,17 +329,13 @@ func (s *Silences) All() ([]*types.Silence, error) {
 		c := b.Cursor()
 
 		for k, v := c.First(); k != nil; k, v = c.Next() {
-			var ms model.Silence
+			var ms types.Silence
 			if err := json.Unmarshal(v, &ms); err != nil {
 				return err
 			}
 			ms.ID = binary.BigEndian.Uint64(k)
 
-			if err := json.Unmarshal(v, &ms); err != nil {
-				return err
-			}
-
-			res = append(res, types.NewSilence(&ms))
+			res = append(res, types.RestoreSilence(&ms))
 		}
 
 		return nil
@@ -308,7 +363,7 @@ func (s *Silences) Set(sil *types.Silence) (uint64, error) {
 		k := make([]byte, 8)
 		binary.BigEndian.PutUint64(k, uid)
 
-		msb, err := json.Marshal(sil.Silence)
+		msb, err := json.Marshal(sil)
 		if err != nil {
 			return err
 		}
@@ -344,18 +399,90 @@ func (s *Silences) Get(uid uint64) (*types.Silence, error) {
 		if v == nil {
 			return provider.ErrNotFound
 		}
-		var ms model.Silence
+		var ms types.Silence
 
 		if err := json.Unmarshal(v, &ms); err != nil {
 			return err
 		}
-		sil = types.NewSilence(&ms)
+		sil = types.RestoreSilence(&ms)
 
 		return nil
 	})
 	return sil, err
 }
 
+// Flags of the first byte of an encoded notification info.
+const (
+	notifyResolved = 1 << iota
+	// notifyHasGroupKey marks entries of earlier versions that stored the
+	// group key. It is skipped when decoding.
+	notifyHasGroupKey
+	notifyHasFailover
+)
+
+// encodeNotifyInfo encodes the resolved state, the optional fallback
+// receiver, and the timestamp of a notification info. Entries written
+// before fallback receivers were stored consist only of the resolved flag
+// and the timestamp.
+func encodeNotifyInfo(n *types.NotifyInfo) ([]byte, error) {
+	var flags byte
+	if n.Resolved {
+		flags |= notifyResolved
+	}
+	v := []byte{flags}
+
+	appendString := func(s string) {
+		lb := make([]byte, binary.MaxVarintLen64)
+		v = append(v, lb[:binary.PutUvarint(lb, uint64(len(s)))]...)
+		v = append(v, s...)
+	}
+	if n.FailoverTo != "" {
+		v[0] |= notifyHasFailover
+		appendString(n.FailoverTo)
+	}
+
+	tsb, err := n.Timestamp.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(v, tsb...), nil
+}
+
+func decodeNotifyInfo(v []byte) (*types.NotifyInfo, error) {
+	if len(v) == 0 {
+		return nil, fmt.Errorf("empty notification info")
+	}
+	ni := &types.NotifyInfo{
+		Resolved: v[0]&notifyResolved != 0,
+	}
+	flags, v := v[0], v[1:]
+
+	readString := func(field string) (string, error) {
+		l, n := binary.Uvarint(v)
+		if n <= 0 || uint64(len(v)-n) < l {
+			return "", fmt.Errorf("invalid %s in notification info", field)
+		}
+		s := string(v[n : n+int(l)])
+		v = v[n+int(l):]
+		return s, nil
+	}
+	var err error
+	if flags&notifyHasGroupKey != 0 {
+		if _, err = readString("group key"); err != nil {
+			return nil, err
+		}
+	}
+	if flags&notifyHasFailover != 0 {
+		if ni.FailoverTo, err = readString("fallback receiver"); err != nil {
+			return nil, err
+		}
+	}
+	if err := ni.Timestamp.UnmarshalBinary(v); err != nil {
+		return nil, err
+	}
+	return ni, nil
+}
+
 // NotificationInfo provides information about pending and successful
 // notifications. All methods are goroutine-safe.
 type NotificationInfo struct {
@@ -375,11 +502,41 @@ func NewNotificationInfo(path string) (*NotificationInfo, error) {
 	return &NotificationInfo{db: db}, err
 }
 
+// Backup implements the provider.Backuper interface.
+func (n *NotificationInfo) Backup(fn func(size int64, snapshot io.WriterTo) error) error {
+	return backup(n.db, fn)
+}
+
 // Close the notification information provider.
 func (n *NotificationInfo) Close() error {
 	return n.db.Close()
 }
 
+// All returns the notification information of all alerts and receivers.
+func (n *NotificationInfo) All() ([]*types.NotifyInfo, error) {
+	var res []*types.NotifyInfo
+
+	err := n.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktNotificationInfo)
+
+		return b.ForEach(func(k, v []byte) error {
+			if len(k) < 8 {
+				return fmt.Errorf("invalid notification info key")
+			}
+			ni, err := decodeNotifyInfo(v)
+			if err != nil {
+				return err
+			}
+			ni.Alert = model.Fingerprint(binary.BigEndian.Uint64(k))
+			ni.Receiver = string(k[8:])
+
+			res = append(res, ni)
+			return nil
+		})
+	})
+	return res, err
+}
+
 // Get notification information for alerts and the given receiver.
 func (n *NotificationInfo) Get(recv string, fps ...model.Fingerprint) ([]*types.NotifyInfo, error) {
 	var res []*types.NotifyInfo
@@ -398,14 +555,13 @@ func (n *NotificationInfo) Get(recv string, fps ...model.Fingerprint) ([]*types.
 				continue
 			}
 
-			ni := &types.NotifyInfo{
-				Alert:    fp,
-				Receiver: recv,
-				Resolved: v[0] == 1,
-			}
-			if err := ni.Timestamp.UnmarshalBinary(v[1:]); err != nil {
+			ni, err := decodeNotifyInfo(v)
+			if err != nil {
 				return err
 			}
+			ni.Alert = fp
+			ni.Receiver = recv
+
 			res = append(res, ni)
 		}
 		return nil
@@ -424,18 +580,10 @@ func (n *NotificationInfo) Set(ns ...*types.NotifyInfo) error {
 			binary.BigEndian.PutUint64(k, uint64(n.Alert))
 			copy(k[8:], []byte(n.Receiver))
 
-			var v []byte
-			if n.Resolved {
-				v = []byte{1}
-			} else {
-				v = []byte{0}
-			}
-			tsb, err := n.Timestamp.MarshalBinary()
+			v, err := encodeNotifyInfo(n)
 			if err != nil {
 				return err
 			}
-			v = append(v, tsb...)
-
 			if err := b.Put(k, v); err != nil {
 				return err
 			}
@@ -1,17 +1,80 @@
 package boltmem
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
 	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/types"
 )
 
-var bktEvents = []byte("events")
+var (
+	bktEvents      = []byte("events")
+	bktEventsIndex = []byte("events_index")
+	bktThreads     = []byte("threads")
+	bktAcks        = []byte("acks")
+	bktMaintenance = []byte("maintenance_windows")
+	bktDependency  = []byte("dependencies")
+	bktDeadLetters = []byte("dead_letters")
+	bktAudit       = []byte("audit")
+	bktHistory     = []byte("alert_history")
+
+	bktAttachments     = []byte("attachments")
+	bktAttachmentBlobs = []byte("attachment_blobs")
+
+	// Keys of events by their creation time.
+	bktEventsCreated = []byte("events_created")
+)
+
+var (
+	eventsStored = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "events_store_events",
+		Help:      "The number of events in the events bucket of the events database.",
+	})
+
+	eventsDBSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "events_store_db_size_bytes",
+		Help:      "The size of the events database file.",
+	})
+
+	eventsOpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "alertmanager",
+		Name:      "events_store_operation_duration_seconds",
+		Help:      "The duration of read and write transactions on the events database.",
+		Buckets:   []float64{.0005, .001, .005, .01, .05, .1, .5, 1, 5},
+	}, []string{"operation"})
+
+	eventsSerializationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "events_store_serialization_errors_total",
+		Help:      "The total number of events that failed to be encoded or decoded.",
+	}, []string{"operation"})
+)
+
+func init() {
+	prometheus.MustRegister(eventsStored)
+	prometheus.MustRegister(eventsDBSize)
+	prometheus.MustRegister(eventsOpDuration)
+	prometheus.MustRegister(eventsSerializationErrors)
+}
 
+// Events stores events in a bolt database. Its size, the duration of
+// transactions, and serialization errors are exported as metrics.
 type Events struct {
 	db *bolt.DB
 }
@@ -22,10 +85,99 @@ func NewEvents(path string) (*Events, error) {
 		return nil, err
 	}
 	err = db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(bktEvents)
+		if _, err := tx.CreateBucketIfNotExists(bktEvents); err != nil {
+			return err
+		}
+		if err := createIndex(tx, bktEventsIndex, indexEvent); err != nil {
+			return err
+		}
+		return createIndex(tx, bktEventsCreated, func(b *bolt.Bucket, k []byte, e *types.Event) error {
+			return b.Put(createdKey(e.CreatedAt, k), nil)
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s := &Events{db: db}
+
+	err = s.view(func(tx *bolt.Tx) error {
+		eventsStored.Set(float64(tx.Bucket(bktEvents).Stats().KeyN))
+		return nil
+	})
+	s.updateSize()
+
+	return s, err
+}
+
+// createIndex creates the index bucket of the given name if it does not
+// exist yet and adds the events stored by previous versions to it.
+func createIndex(tx *bolt.Tx, name []byte, add func(b *bolt.Bucket, k []byte, e *types.Event) error) error {
+	if tx.Bucket(name) != nil {
+		return nil
+	}
+	idx, err := tx.CreateBucket(name)
+	if err != nil {
 		return err
+	}
+	return tx.Bucket(bktEvents).ForEach(func(k, v []byte) error {
+		var e types.Event
+		if err := unmarshalEvent(v, &e); err != nil {
+			return err
+		}
+		return add(idx, k, &e)
 	})
-	return &Events{db: db}, err
+}
+
+// update runs the function in a read-write transaction and updates the
+// metrics of the database.
+func (s *Events) update(fn func(*bolt.Tx) error) error {
+	defer func(start time.Time) {
+		eventsOpDuration.WithLabelValues("write").Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	err := s.db.Update(fn)
+	if err == nil {
+		s.updateSize()
+	}
+	return err
+}
+
+// view runs the function in a read-only transaction.
+func (s *Events) view(fn func(*bolt.Tx) error) error {
+	defer func(start time.Time) {
+		eventsOpDuration.WithLabelValues("read").Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	return s.db.View(fn)
+}
+
+// updateSize sets the database size metric to the size of its file.
+func (s *Events) updateSize() {
+	fi, err := os.Stat(s.db.Path())
+	if err != nil {
+		log.Warnf("Error getting size of events database: %s", err)
+		return
+	}
+	eventsDBSize.Set(float64(fi.Size()))
+}
+
+// marshalEvent encodes the event and counts failures.
+func marshalEvent(e *types.Event) ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		eventsSerializationErrors.WithLabelValues("encode").Inc()
+	}
+	return b, err
+}
+
+// unmarshalEvent decodes the event and counts failures.
+func unmarshalEvent(b []byte, e *types.Event) error {
+	err := json.Unmarshal(b, e)
+	if err != nil {
+		eventsSerializationErrors.WithLabelValues("decode").Inc()
+	}
+	return err
 }
 
 func (s *Events) Set(event *types.Event) (uint64, error) {
@@ -33,7 +185,7 @@ func (s *Events) Set(event *types.Event) (uint64, error) {
 		uid uint64
 		err error
 	)
-	err = s.db.Update(func(tx *bolt.Tx) error {
+	err = s.update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(bktEvents)
 
 		uid, err = b.NextSequence()
@@ -45,26 +197,271 @@ func (s *Events) Set(event *types.Event) (uint64, error) {
 		k := make([]byte, 8)
 		binary.BigEndian.PutUint64(k, uid)
 
-		msb, err := json.Marshal(event)
+		msb, err := marshalEvent(event)
 		if err != nil {
 			return err
 		}
-		return b.Put(k, msb)
+		if err := b.Put(k, msb); err != nil {
+			return err
+		}
+		if err := tx.Bucket(bktEventsCreated).Put(createdKey(event.CreatedAt, k), nil); err != nil {
+			return err
+		}
+		return indexEvent(tx.Bucket(bktEventsIndex), k, event)
 	})
+	if err == nil {
+		eventsStored.Inc()
+	}
 	return uid, err
 }
 
+// Update replaces the stored event with the same ID if its version is
+// unchanged and increments the version.
+func (s *Events) Update(event *types.Event) error {
+	return s.update(func(tx *bolt.Tx) error {
+		var (
+			b   = tx.Bucket(bktEvents)
+			idx = tx.Bucket(bktEventsIndex)
+			k   = make([]byte, 8)
+		)
+		binary.BigEndian.PutUint64(k, event.ID)
+
+		v := b.Get(k)
+		if v == nil {
+			return provider.ErrNotFound
+		}
+		var old types.Event
+		if err := unmarshalEvent(v, &old); err != nil {
+			return err
+		}
+		if old.Version != event.Version {
+			return provider.ErrConflict
+		}
+		if err := unindexEvent(idx, k, &old); err != nil {
+			return err
+		}
+
+		upd := *event
+		upd.Version++
+		if upd.CreatedAt.IsZero() {
+			upd.CreatedAt = old.CreatedAt
+		}
+
+		msb, err := marshalEvent(&upd)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(k, msb); err != nil {
+			return err
+		}
+		if err := indexEvent(idx, k, &upd); err != nil {
+			return err
+		}
+		if !upd.CreatedAt.Equal(old.CreatedAt) {
+			created := tx.Bucket(bktEventsCreated)
+			if err := created.Delete(createdKey(old.CreatedAt, k)); err != nil {
+				return err
+			}
+			if err := created.Put(createdKey(upd.CreatedAt, k), nil); err != nil {
+				return err
+			}
+		}
+		event.Version = upd.Version
+		return nil
+	})
+}
+
+// Delete removes the event with the given ID.
+func (s *Events) Delete(id uint64) error {
+	err := s.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktEvents)
+
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, id)
+
+		v := b.Get(k)
+		if v == nil {
+			return provider.ErrNotFound
+		}
+		var old types.Event
+		if err := unmarshalEvent(v, &old); err != nil {
+			return err
+		}
+		if err := unindexEvent(tx.Bucket(bktEventsIndex), k, &old); err != nil {
+			return err
+		}
+		if err := tx.Bucket(bktEventsCreated).Delete(createdKey(old.CreatedAt, k)); err != nil {
+			return err
+		}
+		return b.Delete(k)
+	})
+	if err == nil {
+		eventsStored.Dec()
+	}
+	return err
+}
+
+// indexEvent adds the event stored under the given key to the full-text
+// index. Index keys consist of a token, a zero byte separator, and the
+// event key.
+func indexEvent(b *bolt.Bucket, k []byte, event *types.Event) error {
+	for tok := range provider.EventTokens(event) {
+		if err := b.Put(indexKey(tok, k), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unindexEvent removes the event stored under the given key from the
+// full-text index.
+func unindexEvent(b *bolt.Bucket, k []byte, event *types.Event) error {
+	for tok := range provider.EventTokens(event) {
+		if err := b.Delete(indexKey(tok, k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indexKey(tok string, k []byte) []byte {
+	ik := make([]byte, 0, len(tok)+1+len(k))
+	ik = append(ik, tok...)
+	ik = append(ik, 0)
+	return append(ik, k...)
+}
+
+// createdKey returns the key of the event stored under the given key in
+// the creation time index. It consists of the creation time in Unix
+// nanoseconds followed by the event key, both big-endian, so that the
+// index is ordered by creation time.
+func createdKey(t time.Time, k []byte) []byte {
+	ck := make([]byte, 8, 8+len(k))
+	binary.BigEndian.PutUint64(ck, unixNano(t))
+	return append(ck, k...)
+}
+
+// unixNano returns the time in Unix nanoseconds. Times before the epoch,
+// including the zero time, are mapped to zero.
+func unixNano(t time.Time) uint64 {
+	if t.Before(time.Unix(0, 0)) {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+// ListByTimeRange returns the events created within the given range in
+// the order of their creation time. Only the events within the range are
+// read from the database.
+func (s *Events) ListByTimeRange(from, to time.Time, limit int) ([]*types.Event, error) {
+	var res []*types.Event
+
+	err := s.view(func(tx *bolt.Tx) error {
+		var (
+			b     = tx.Bucket(bktEvents)
+			c     = tx.Bucket(bktEventsCreated).Cursor()
+			start = make([]byte, 8)
+		)
+		binary.BigEndian.PutUint64(start, unixNano(from))
+
+		for ck, _ := c.Seek(start); ck != nil; ck, _ = c.Next() {
+			if limit > 0 && len(res) >= limit {
+				break
+			}
+			if !to.IsZero() && binary.BigEndian.Uint64(ck[:8]) >= unixNano(to) {
+				break
+			}
+			k := ck[8:]
+
+			v := b.Get(k)
+			if v == nil {
+				continue
+			}
+			var e types.Event
+			if err := unmarshalEvent(v, &e); err != nil {
+				return err
+			}
+			e.ID = binary.BigEndian.Uint64(k)
+			res = append(res, &e)
+		}
+		return nil
+	})
+
+	return res, err
+}
+
+// Search returns all events containing every word of the query in their
+// title, kind, level, creator, labels, or annotations.
+func (s *Events) Search(query string) ([]*types.Event, error) {
+	var res []*types.Event
+
+	toks := provider.Tokenize(query)
+	if len(toks) == 0 {
+		return res, nil
+	}
+
+	err := s.view(func(tx *bolt.Tx) error {
+		var (
+			idx  = tx.Bucket(bktEventsIndex)
+			keys map[string]struct{}
+		)
+		for _, tok := range toks {
+			prefix := append([]byte(tok), 0)
+			matches := map[string]struct{}{}
+
+			c := idx.Cursor()
+			for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				ek := string(k[len(prefix):])
+				if _, ok := keys[ek]; keys == nil || ok {
+					matches[ek] = struct{}{}
+				}
+			}
+			keys = matches
+
+			if len(keys) == 0 {
+				return nil
+			}
+		}
+
+		// Keys are big-endian sequence numbers, sorting them returns
+		// the events in insertion order.
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		b := tx.Bucket(bktEvents)
+
+		for _, k := range sorted {
+			v := b.Get([]byte(k))
+			if v == nil {
+				continue
+			}
+			var e types.Event
+			if err := unmarshalEvent(v, &e); err != nil {
+				return err
+			}
+			e.ID = binary.BigEndian.Uint64([]byte(k))
+			res = append(res, &e)
+		}
+		return nil
+	})
+
+	return res, err
+}
+
 // All returns all existing events.
 func (s *Events) All() ([]*types.Event, error) {
 	var res []*types.Event
 
-	err := s.db.View(func(tx *bolt.Tx) error {
+	err := s.view(func(tx *bolt.Tx) error {
 		b := tx.Bucket(bktEvents)
 		c := b.Cursor()
 
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			var ms types.Event
-			if err := json.Unmarshal(v, &ms); err != nil {
+			if err := unmarshalEvent(v, &ms); err != nil {
 				return err
 			}
 			ms.ID = binary.BigEndian.Uint64(k)
@@ -77,9 +474,34 @@ func (s *Events) All() ([]*types.Event, error) {
 	return res, err
 }
 
+// Children returns the events whose parent is the event with the given
+// ID.
+func (s *Events) Children(id uint64) ([]*types.Event, error) {
+	var res []*types.Event
+
+	err := s.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bktEvents).Cursor()
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var e types.Event
+			if err := unmarshalEvent(v, &e); err != nil {
+				return err
+			}
+			if e.ParentID != id {
+				continue
+			}
+			e.ID = binary.BigEndian.Uint64(k)
+			res = append(res, &e)
+		}
+		return nil
+	})
+
+	return res, err
+}
+
 func (a *Events) Get(id uint64) (*types.Event, error) {
 	var event types.Event
-	err := a.db.View(func(tx *bolt.Tx) error {
+	err := a.view(func(tx *bolt.Tx) error {
 		b := tx.Bucket(bktEvents)
 
 		k := make([]byte, 8)
@@ -90,11 +512,1055 @@ func (a *Events) Get(id uint64) (*types.Event, error) {
 			return provider.ErrNotFound
 		}
 
-		return json.Unmarshal(ab, &event)
+		return unmarshalEvent(ab, &event)
 	})
 	return &event, err
 }
 
+// Backup implements the provider.Backuper interface.
+func (s *Events) Backup(fn func(size int64, snapshot io.WriterTo) error) error {
+	return backup(s.db, fn)
+}
+
 func (s *Events) Close() error {
 	return s.db.Close()
 }
+
+// Threads stores message thread IDs of chat integrations. All methods
+// are goroutine-safe.
+type Threads struct {
+	db *bolt.DB
+}
+
+// NewThreads returns a new thread ID provider.
+func NewThreads(path string) (*Threads, error) {
+	db, err := bolt.Open(filepath.Join(path, "threads.db"), 0666, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bktThreads)
+		return err
+	})
+	return &Threads{db: db}, err
+}
+
+// Get returns the thread ID stored for the key.
+func (t *Threads) Get(key string) (string, error) {
+	var id string
+	err := t.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bktThreads).Get([]byte(key))
+		if v == nil {
+			return provider.ErrNotFound
+		}
+		id = string(v)
+		return nil
+	})
+	return id, err
+}
+
+// Set stores the thread ID for the key.
+func (t *Threads) Set(key, id string) error {
+	return t.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bktThreads).Put([]byte(key), []byte(id))
+	})
+}
+
+// Del removes the thread ID stored for the key.
+func (t *Threads) Del(key string) error {
+	return t.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bktThreads).Delete([]byte(key))
+	})
+}
+
+// Close the thread ID provider.
+func (t *Threads) Close() error {
+	return t.db.Close()
+}
+
+// Acks stores acknowledgements of alerts and mirrors them into a marker.
+// All methods are goroutine-safe.
+type Acks struct {
+	db *bolt.DB
+	mk types.Marker
+}
+
+// NewAcks returns a new acknowledgement provider. All stored
+// acknowledgements are loaded into the marker.
+func NewAcks(path string, mk types.Marker) (*Acks, error) {
+	db, err := bolt.Open(filepath.Join(path, "acks.db"), 0666, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bktAcks)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	a := &Acks{db: db, mk: mk}
+
+	acks, err := a.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, ack := range acks {
+		mk.SetAcked(ack.Alert, ack)
+	}
+	return a, nil
+}
+
+func ackKey(fp model.Fingerprint) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(fp))
+	return k
+}
+
+// All returns all existing acknowledgements.
+func (a *Acks) All() ([]*types.Ack, error) {
+	var res []*types.Ack
+
+	err := a.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bktAcks).ForEach(func(k, v []byte) error {
+			var ack types.Ack
+			if err := json.Unmarshal(v, &ack); err != nil {
+				return err
+			}
+			res = append(res, &ack)
+			return nil
+		})
+	})
+	return res, err
+}
+
+// Get returns the acknowledgement of the alert.
+func (a *Acks) Get(fp model.Fingerprint) (*types.Ack, error) {
+	var ack *types.Ack
+
+	err := a.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bktAcks).Get(ackKey(fp))
+		if v == nil {
+			return provider.ErrNotFound
+		}
+		ack = &types.Ack{}
+		return json.Unmarshal(v, ack)
+	})
+	return ack, err
+}
+
+// Set stores the acknowledgement and marks the alert as acknowledged.
+func (a *Acks) Set(ack *types.Ack) error {
+	err := a.db.Update(func(tx *bolt.Tx) error {
+		b, err := json.Marshal(ack)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bktAcks).Put(ackKey(ack.Alert), b)
+	})
+	if err == nil {
+		a.mk.SetAcked(ack.Alert, ack)
+	}
+	return err
+}
+
+// Del removes the acknowledgement of the alert.
+func (a *Acks) Del(fp model.Fingerprint) error {
+	err := a.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bktAcks).Delete(ackKey(fp))
+	})
+	if err == nil {
+		a.mk.SetAcked(fp)
+	}
+	return err
+}
+
+// Close the acknowledgement provider.
+func (a *Acks) Close() error {
+	return a.db.Close()
+}
+
+// MaintenanceWindows stores planned maintenance windows. All methods are
+// goroutine-safe.
+type MaintenanceWindows struct {
+	db *bolt.DB
+	mk types.Marker
+}
+
+// NewMaintenanceWindows returns a new maintenance window provider marking
+// muted alerts in the marker.
+func NewMaintenanceWindows(path string, mk types.Marker) (*MaintenanceWindows, error) {
+	db, err := bolt.Open(filepath.Join(path, "maintenance.db"), 0666, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bktMaintenance)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MaintenanceWindows{db: db, mk: mk}, nil
+}
+
+func maintenanceKey(id uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, id)
+	return k
+}
+
+// Mutes implements the types.Muter interface. Alerts are muted by the
+// first active window they match at the current time.
+func (m *MaintenanceWindows) Mutes(lset model.LabelSet) bool {
+	mws, err := m.All()
+	if err != nil {
+		log.Errorf("retrieving maintenance windows failed: %s", err)
+		// In doubt, do not mute anything.
+		return false
+	}
+	now := time.Now()
+
+	for _, mw := range mws {
+		if mw.Active(now) && mw.Matches(lset) {
+			m.mk.SetMaintenance(lset.Fingerprint(), mw.ID)
+			return true
+		}
+	}
+	m.mk.SetMaintenance(lset.Fingerprint())
+	return false
+}
+
+// All returns all existing maintenance windows.
+func (m *MaintenanceWindows) All() ([]*types.MaintenanceWindow, error) {
+	var res []*types.MaintenanceWindow
+
+	err := m.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bktMaintenance).ForEach(func(k, v []byte) error {
+			var mw types.MaintenanceWindow
+			if err := json.Unmarshal(v, &mw); err != nil {
+				return err
+			}
+			res = append(res, &mw)
+			return nil
+		})
+	})
+	return res, err
+}
+
+// Get returns the maintenance window with the given ID.
+func (m *MaintenanceWindows) Get(id uint64) (*types.MaintenanceWindow, error) {
+	var mw *types.MaintenanceWindow
+
+	err := m.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bktMaintenance).Get(maintenanceKey(id))
+		if v == nil {
+			return provider.ErrNotFound
+		}
+		mw = &types.MaintenanceWindow{}
+		return json.Unmarshal(v, mw)
+	})
+	return mw, err
+}
+
+// Set creates or replaces the maintenance window.
+func (m *MaintenanceWindows) Set(mw *types.MaintenanceWindow) (uint64, error) {
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktMaintenance)
+
+		if mw.ID == 0 {
+			id, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			mw.ID = id
+		} else if b.Get(maintenanceKey(mw.ID)) == nil {
+			return provider.ErrNotFound
+		}
+
+		v, err := json.Marshal(mw)
+		if err != nil {
+			return err
+		}
+		return b.Put(maintenanceKey(mw.ID), v)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return mw.ID, nil
+}
+
+// Del removes the maintenance window with the given ID.
+func (m *MaintenanceWindows) Del(id uint64) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktMaintenance)
+		if b.Get(maintenanceKey(id)) == nil {
+			return provider.ErrNotFound
+		}
+		return b.Delete(maintenanceKey(id))
+	})
+}
+
+// Backup implements the provider.Backuper interface.
+func (m *MaintenanceWindows) Backup(fn func(size int64, snapshot io.WriterTo) error) error {
+	return backup(m.db, fn)
+}
+
+// Close the maintenance window provider.
+func (m *MaintenanceWindows) Close() error {
+	return m.db.Close()
+}
+
+// Dependencies stores the dependencies between services. All methods are
+// goroutine-safe.
+type Dependencies struct {
+	db *bolt.DB
+}
+
+// NewDependencies returns a new dependency provider.
+func NewDependencies(path string) (*Dependencies, error) {
+	db, err := bolt.Open(filepath.Join(path, "dependencies.db"), 0666, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bktDependency)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Dependencies{db: db}, nil
+}
+
+func dependencyKey(id uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, id)
+	return k
+}
+
+// All returns all existing dependencies ordered by ID.
+func (d *Dependencies) All() ([]*types.Dependency, error) {
+	var res []*types.Dependency
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bktDependency).ForEach(func(k, v []byte) error {
+			var dep types.Dependency
+			if err := json.Unmarshal(v, &dep); err != nil {
+				return err
+			}
+			res = append(res, &dep)
+			return nil
+		})
+	})
+	return res, err
+}
+
+// Get returns the dependency with the given ID.
+func (d *Dependencies) Get(id uint64) (*types.Dependency, error) {
+	var dep *types.Dependency
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bktDependency).Get(dependencyKey(id))
+		if v == nil {
+			return provider.ErrNotFound
+		}
+		dep = &types.Dependency{}
+		return json.Unmarshal(v, dep)
+	})
+	return dep, err
+}
+
+// Set creates or replaces the dependency.
+func (d *Dependencies) Set(dep *types.Dependency) (uint64, error) {
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktDependency)
+
+		if dep.ID == 0 {
+			id, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			dep.ID = id
+		} else if b.Get(dependencyKey(dep.ID)) == nil {
+			return provider.ErrNotFound
+		}
+
+		v, err := json.Marshal(dep)
+		if err != nil {
+			return err
+		}
+		return b.Put(dependencyKey(dep.ID), v)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return dep.ID, nil
+}
+
+// Del removes the dependency with the given ID.
+func (d *Dependencies) Del(id uint64) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktDependency)
+		if b.Get(dependencyKey(id)) == nil {
+			return provider.ErrNotFound
+		}
+		return b.Delete(dependencyKey(id))
+	})
+}
+
+// Backup implements the provider.Backuper interface.
+func (d *Dependencies) Backup(fn func(size int64, snapshot io.WriterTo) error) error {
+	return backup(d.db, fn)
+}
+
+// Close the dependency provider.
+func (d *Dependencies) Close() error {
+	return d.db.Close()
+}
+
+// DeadLetters stores notifications that were given up on. All methods
+// are goroutine-safe.
+type DeadLetters struct {
+	db   *bolt.DB
+	size int
+}
+
+// NewDeadLetters returns a new dead letter provider retaining at most
+// size dead letters.
+func NewDeadLetters(path string, size int) (*DeadLetters, error) {
+	db, err := bolt.Open(filepath.Join(path, "dead_letters.db"), 0666, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bktDeadLetters)
+		return err
+	})
+	return &DeadLetters{db: db, size: size}, err
+}
+
+// All returns all dead letters in the order they were added.
+func (dl *DeadLetters) All() ([]*types.DeadLetter, error) {
+	var res []*types.DeadLetter
+
+	err := dl.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bktDeadLetters).ForEach(func(k, v []byte) error {
+			var l types.DeadLetter
+			if err := json.Unmarshal(v, &l); err != nil {
+				return err
+			}
+			res = append(res, &l)
+			return nil
+		})
+	})
+	return res, err
+}
+
+// Get returns the dead letter with the given ID.
+func (dl *DeadLetters) Get(id uint64) (*types.DeadLetter, error) {
+	var l *types.DeadLetter
+
+	err := dl.db.View(func(tx *bolt.Tx) error {
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, id)
+
+		v := tx.Bucket(bktDeadLetters).Get(k)
+		if v == nil {
+			return provider.ErrNotFound
+		}
+		l = &types.DeadLetter{}
+		return json.Unmarshal(v, l)
+	})
+	return l, err
+}
+
+// Add stores the dead letter and drops the oldest ones exceeding the
+// size limit.
+func (dl *DeadLetters) Add(l *types.DeadLetter) (uint64, error) {
+	var uid uint64
+
+	err := dl.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktDeadLetters)
+
+		var err error
+		if uid, err = b.NextSequence(); err != nil {
+			return err
+		}
+		l.ID = uid
+
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, uid)
+
+		msb, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(k, msb); err != nil {
+			return err
+		}
+
+		// Keys are ordered by insertion, so the oldest dead letters
+		// come first.
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		for len(keys) > dl.size {
+			if err := b.Delete(keys[0]); err != nil {
+				return err
+			}
+			keys = keys[1:]
+		}
+		return nil
+	})
+	return uid, err
+}
+
+// Del removes the dead letter with the given ID.
+func (dl *DeadLetters) Del(id uint64) error {
+	return dl.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktDeadLetters)
+
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, id)
+
+		if b.Get(k) == nil {
+			return provider.ErrNotFound
+		}
+		return b.Delete(k)
+	})
+}
+
+// Collect implements the provider.Collector interface. Dead letters are
+// removed in the order they were given up on.
+func (dl *DeadLetters) Collect(now time.Time, r provider.Retention) (expired, evicted int, err error) {
+	err = dl.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktDeadLetters)
+
+		var entries []retainedEntry
+		err := b.ForEach(func(k, v []byte) error {
+			var l types.DeadLetter
+			if err := json.Unmarshal(v, &l); err != nil {
+				return err
+			}
+			entries = append(entries, newRetainedEntry(k, v, l.Time))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		expired, evicted, err = retain(b, entries, now, r)
+		return err
+	})
+	return expired, evicted, err
+}
+
+// Close the dead letter provider.
+func (dl *DeadLetters) Close() error {
+	return dl.db.Close()
+}
+
+// Collect implements the provider.Collector interface. Entries are removed
+// in the order they were last written, so the notification log of alerts
+// notified about recently is kept.
+func (n *NotificationInfo) Collect(now time.Time, r provider.Retention) (expired, evicted int, err error) {
+	err = n.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktNotificationInfo)
+
+		var entries []retainedEntry
+		err := b.ForEach(func(k, v []byte) error {
+			ni, err := decodeNotifyInfo(v)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, newRetainedEntry(k, v, ni.Timestamp))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		expired, evicted, err = retain(b, entries, now, r)
+		return err
+	})
+	return expired, evicted, err
+}
+
+// retainedEntry is a key of a bucket along with the size and write time
+// of its entry.
+type retainedEntry struct {
+	key  []byte
+	size int64
+	time time.Time
+}
+
+func newRetainedEntry(k, v []byte, t time.Time) retainedEntry {
+	return retainedEntry{
+		// Keys may be invalidated by modifying the bucket.
+		key:  append([]byte{}, k...),
+		size: int64(len(k) + len(v)),
+		time: t,
+	}
+}
+
+// retainedEntries sorts by write time.
+type retainedEntries []retainedEntry
+
+func (es retainedEntries) Len() int           { return len(es) }
+func (es retainedEntries) Swap(i, j int)      { es[i], es[j] = es[j], es[i] }
+func (es retainedEntries) Less(i, j int) bool { return es[i].time.Before(es[j].time) }
+
+// retain deletes the entries of the bucket exceeding the retention, oldest
+// first.
+func retain(b *bolt.Bucket, entries []retainedEntry, now time.Time, r provider.Retention) (expired, evicted int, err error) {
+	sort.Stable(retainedEntries(entries))
+
+	var size int64
+	for _, e := range entries {
+		size += e.size
+	}
+	for _, e := range entries {
+		switch {
+		case r.MaxAge > 0 && e.time.Before(now.Add(-r.MaxAge)):
+			expired++
+		case r.MaxSize > 0 && size > r.MaxSize:
+			evicted++
+		default:
+			// All further entries are newer.
+			return expired, evicted, nil
+		}
+		if err := b.Delete(e.key); err != nil {
+			return expired, evicted, err
+		}
+		size -= e.size
+	}
+	return expired, evicted, nil
+}
+
+// Audit stores the audit log in a dedicated bucket. All methods are
+// goroutine-safe.
+type Audit struct {
+	db *bolt.DB
+}
+
+// NewAudit returns a new audit log provider.
+func NewAudit(path string) (*Audit, error) {
+	db, err := bolt.Open(filepath.Join(path, "audit.db"), 0666, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bktAudit)
+		return err
+	})
+	return &Audit{db: db}, err
+}
+
+// Add appends the entry to the log and sets its ID.
+func (a *Audit) Add(e *types.AuditEntry) (uint64, error) {
+	var uid uint64
+
+	err := a.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktAudit)
+
+		var err error
+		if uid, err = b.NextSequence(); err != nil {
+			return err
+		}
+		e.ID = uid
+
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, uid)
+
+		msb, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return b.Put(k, msb)
+	})
+	return uid, err
+}
+
+// Query returns up to limit entries recorded at or after since, newest
+// first.
+func (a *Audit) Query(since time.Time, limit int) ([]*types.AuditEntry, error) {
+	var res []*types.AuditEntry
+
+	err := a.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bktAudit).Cursor()
+
+		// Keys are ordered by insertion, so the newest entries come last.
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if limit > 0 && len(res) == limit {
+				break
+			}
+			var e types.AuditEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if e.Time.Before(since) {
+				break
+			}
+			res = append(res, &e)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// Backup implements the provider.Backuper interface.
+func (a *Audit) Backup(fn func(size int64, snapshot io.WriterTo) error) error {
+	return backup(a.db, fn)
+}
+
+// Close the audit log provider.
+func (a *Audit) Close() error {
+	return a.db.Close()
+}
+
+// AlertHistory stores the state changes of alerts. Entries are keyed by
+// the alert's fingerprint, the time, and a sequence number, which orders
+// them chronologically per alert. All methods are goroutine-safe.
+type AlertHistory struct {
+	db *bolt.DB
+}
+
+// NewAlertHistory returns a new alert history provider.
+func NewAlertHistory(path string) (*AlertHistory, error) {
+	db, err := bolt.Open(filepath.Join(path, "alert_history.db"), 0666, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bktHistory)
+		return err
+	})
+	return &AlertHistory{db: db}, err
+}
+
+// Add implements the provider.AlertHistory interface.
+func (h *AlertHistory) Add(entries ...*types.AlertHistoryEntry) error {
+	return h.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktHistory)
+
+		for _, e := range entries {
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			k := make([]byte, 24)
+			binary.BigEndian.PutUint64(k, uint64(e.Alert))
+			binary.BigEndian.PutUint64(k[8:], uint64(e.Time.UnixNano()))
+			binary.BigEndian.PutUint64(k[16:], seq)
+
+			msb, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(k, msb); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get implements the provider.AlertHistory interface.
+func (h *AlertHistory) Get(fp model.Fingerprint) ([]*types.AlertHistoryEntry, error) {
+	var res []*types.AlertHistoryEntry
+
+	prefix := make([]byte, 8)
+	binary.BigEndian.PutUint64(prefix, uint64(fp))
+
+	err := h.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bktHistory).Cursor()
+
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var e types.AlertHistoryEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			res = append(res, &e)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// Query implements the provider.AlertHistory interface. Entries are keyed
+// by alert, so all of them are scanned but only those within the range
+// are decoded.
+func (h *AlertHistory) Query(from, to time.Time) ([]*types.AlertHistoryEntry, error) {
+	var (
+		res        []*types.AlertHistoryEntry
+		start, end = uint64(from.UnixNano()), uint64(to.UnixNano())
+	)
+	err := h.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bktHistory).ForEach(func(k, v []byte) error {
+			if t := binary.BigEndian.Uint64(k[8:16]); t < start || t >= end {
+				return nil
+			}
+			var e types.AlertHistoryEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			res = append(res, &e)
+			return nil
+		})
+	})
+	return res, err
+}
+
+// GC implements the provider.AlertHistory interface.
+func (h *AlertHistory) GC(before time.Time) error {
+	cutoff := uint64(before.UnixNano())
+
+	return h.db.Update(func(tx *bolt.Tx) error {
+		var (
+			b   = tx.Bucket(bktHistory)
+			del [][]byte
+		)
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			if binary.BigEndian.Uint64(k[8:16]) < cutoff {
+				// Keys may be invalidated by modifying the bucket.
+				del = append(del, append([]byte{}, k...))
+			}
+		}
+		for _, k := range del {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Backup implements the provider.Backuper interface.
+func (h *AlertHistory) Backup(fn func(size int64, snapshot io.WriterTo) error) error {
+	return backup(h.db, fn)
+}
+
+// Close the alert history provider.
+func (h *AlertHistory) Close() error {
+	return h.db.Close()
+}
+
+// Attachments stores the metadata of files attached to events in a bolt
+// database. Their content is stored in the database as well or, if a
+// directory is given, in a file per attachment. All methods are
+// goroutine-safe.
+type Attachments struct {
+	db *bolt.DB
+	// Directory holding the content of attachments if not empty.
+	dir string
+}
+
+// NewAttachments returns a new attachment provider. If dir is not empty,
+// the content of attachments is stored in files in that directory instead
+// of the database.
+func NewAttachments(path, dir string) (*Attachments, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return nil, err
+		}
+	}
+	db, err := bolt.Open(filepath.Join(path, "attachments.db"), 0666, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bktAttachments); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(bktAttachmentBlobs)
+		return err
+	})
+	return &Attachments{db: db, dir: dir}, err
+}
+
+// attachmentKey orders attachments by event and ID.
+func attachmentKey(eventID, id uint64) []byte {
+	k := make([]byte, 16)
+	binary.BigEndian.PutUint64(k, eventID)
+	binary.BigEndian.PutUint64(k[8:], id)
+	return k
+}
+
+func (s *Attachments) file(eventID, id uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%d-%d", eventID, id))
+}
+
+// List implements the provider.EventAttachments interface.
+func (s *Attachments) List(eventID uint64) ([]*types.Attachment, error) {
+	var res []*types.Attachment
+
+	prefix := make([]byte, 8)
+	binary.BigEndian.PutUint64(prefix, eventID)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bktAttachments).Cursor()
+
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var a types.Attachment
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+			res = append(res, &a)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// Add implements the provider.EventAttachments interface.
+func (s *Attachments) Add(a *types.Attachment, content []byte) (uint64, error) {
+	var file string
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktAttachments)
+
+		uid, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		a.ID = uid
+		k := attachmentKey(a.EventID, uid)
+
+		msb, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(k, msb); err != nil {
+			return err
+		}
+		if s.dir == "" {
+			return tx.Bucket(bktAttachmentBlobs).Put(k, content)
+		}
+
+		// Write the file before committing so that no attachment is
+		// stored without its content.
+		file = s.file(a.EventID, uid)
+		tmp := file + ".tmp"
+		if err := ioutil.WriteFile(tmp, content, 0666); err != nil {
+			return err
+		}
+		return os.Rename(tmp, file)
+	})
+	if err != nil && file != "" {
+		os.Remove(file)
+	}
+	return a.ID, err
+}
+
+// Get implements the provider.EventAttachments interface.
+func (s *Attachments) Get(eventID, id uint64) (*types.Attachment, []byte, error) {
+	var (
+		a       *types.Attachment
+		content []byte
+	)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		k := attachmentKey(eventID, id)
+
+		v := tx.Bucket(bktAttachments).Get(k)
+		if v == nil {
+			return provider.ErrNotFound
+		}
+		a = &types.Attachment{}
+		if err := json.Unmarshal(v, a); err != nil {
+			return err
+		}
+		if s.dir == "" {
+			// Values are only valid during the transaction.
+			content = append([]byte{}, tx.Bucket(bktAttachmentBlobs).Get(k)...)
+			return nil
+		}
+		var err error
+		content, err = ioutil.ReadFile(s.file(eventID, id))
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, content, nil
+}
+
+// Delete implements the provider.EventAttachments interface.
+func (s *Attachments) Delete(eventID, id uint64) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		k := attachmentKey(eventID, id)
+
+		b := tx.Bucket(bktAttachments)
+		if b.Get(k) == nil {
+			return provider.ErrNotFound
+		}
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+		return tx.Bucket(bktAttachmentBlobs).Delete(k)
+	})
+	if err != nil {
+		return err
+	}
+	return s.removeFiles(eventID, id)
+}
+
+// DeleteAll implements the provider.EventAttachments interface.
+func (s *Attachments) DeleteAll(eventID uint64) error {
+	var ids []uint64
+
+	prefix := make([]byte, 8)
+	binary.BigEndian.PutUint64(prefix, eventID)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var (
+			b   = tx.Bucket(bktAttachments)
+			del [][]byte
+		)
+		c := b.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			// Keys may be invalidated by modifying the bucket.
+			del = append(del, append([]byte{}, k...))
+		}
+		for _, k := range del {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+			if err := tx.Bucket(bktAttachmentBlobs).Delete(k); err != nil {
+				return err
+			}
+			ids = append(ids, binary.BigEndian.Uint64(k[8:]))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.removeFiles(eventID, ids...)
+}
+
+// removeFiles removes the content files of the given attachments if they
+// are stored on disk.
+func (s *Attachments) removeFiles(eventID uint64, ids ...uint64) error {
+	if s.dir == "" {
+		return nil
+	}
+	for _, id := range ids {
+		if err := os.Remove(s.file(eventID, id)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Backup implements the provider.Backuper interface. Content stored in
+// files is not included.
+func (s *Attachments) Backup(fn func(size int64, snapshot io.WriterTo) error) error {
+	return backup(s.db, fn)
+}
+
+// Close the attachment provider.
+func (s *Attachments) Close() error {
+	return s.db.Close()
+}
@@ -14,14 +14,22 @@
 package boltmem
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/boltdb/bolt"
 	"github.com/kylelemons/godebug/pretty"
 	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/model"
 )
 
@@ -53,10 +61,11 @@ func TestNotifiesSet(t *testing.T) {
 					Resolved:  false,
 					Timestamp: t0,
 				}, {
-					Alert:     20000,
-					Receiver:  "receiver",
-					Resolved:  true,
-					Timestamp: t0,
+					Alert:      20000,
+					Receiver:   "receiver",
+					Resolved:   true,
+					Timestamp:  t0,
+					FailoverTo: "fallback",
 				}, {
 					Alert:     10000,
 					Receiver:  "receiver",
@@ -76,10 +85,11 @@ func TestNotifiesSet(t *testing.T) {
 							Timestamp: t0,
 						},
 						nil, {
-							Alert:     20000,
-							Receiver:  "receiver",
-							Resolved:  true,
-							Timestamp: t0,
+							Alert:      20000,
+							Receiver:   "receiver",
+							Resolved:   true,
+							Timestamp:  t0,
+							FailoverTo: "fallback",
 						}, {
 							Alert:     10000,
 							Receiver:  "receiver",
@@ -120,6 +130,82 @@ func TestNotifiesSet(t *testing.T) {
 	}
 }
 
+func TestDecodeNotifyInfoLegacy(t *testing.T) {
+	ts := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tsb, err := ts.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Entries written before group keys were stored and entries that
+	// still contain one.
+	for _, v := range [][]byte{
+		append([]byte{notifyResolved}, tsb...),
+		append([]byte{notifyResolved | notifyHasGroupKey, 3, 'k', 'e', 'y'}, tsb...),
+	} {
+		ni, err := decodeNotifyInfo(v)
+		if err != nil {
+			t.Fatalf("Decoding failed: %s", err)
+		}
+		if !ni.Resolved || !ni.Timestamp.Equal(ts) {
+			t.Fatalf("Unexpected notification info %v", ni)
+		}
+	}
+}
+
+func TestNotifiesCollect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notifies_collect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := NewNotificationInfo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	now := time.Now()
+	infos := []*types.NotifyInfo{
+		{Alert: 1, Receiver: "receiver", Timestamp: now.Add(-3 * time.Hour)},
+		{Alert: 2, Receiver: "receiver", Timestamp: now.Add(-time.Hour)},
+		{Alert: 3, Receiver: "receiver", Timestamp: now.Add(-2 * time.Hour)},
+		{Alert: 4, Receiver: "receiver", Timestamp: now},
+	}
+	if err := n.Set(infos...); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+	// All entries have the same size.
+	v, err := encodeNotifyInfo(infos[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := int64(8 + len("receiver") + len(v))
+
+	expired, evicted, err := n.Collect(now, provider.Retention{
+		MaxAge:  150 * time.Minute,
+		MaxSize: 2 * size,
+	})
+	if err != nil {
+		t.Fatalf("Collect failed: %s", err)
+	}
+	if expired != 1 || evicted != 1 {
+		t.Fatalf("Expected 1 expired and 1 evicted entry but got %d and %d", expired, evicted)
+	}
+
+	res, err := n.Get("receiver", 1, 2, 3, 4)
+	if err != nil {
+		t.Fatalf("Retrieval failed: %s", err)
+	}
+	if res[0] != nil || res[1] == nil || res[2] != nil || res[3] == nil {
+		t.Fatalf("Expected the least recently written entries to be removed but got %v", res)
+	}
+
+	// Disabled limits remove nothing.
+	if expired, evicted, err = n.Collect(now.Add(time.Hour), provider.Retention{}); err != nil || expired+evicted != 0 {
+		t.Fatalf("Unexpected removal of %d entries, error %v", expired+evicted, err)
+	}
+}
+
 func TestSilencesSet(t *testing.T) {
 	var (
 		t0 = time.Now()
@@ -319,6 +405,16 @@ func TestSilencesMutes(t *testing.T) {
 			CreatedBy: "user",
 			Comment:   "another test comment",
 		}),
+		types.NewSilence(&model.Silence{
+			Matchers: []*model.Matcher{
+				{Name: "key3", Value: "val3"},
+			},
+			StartsAt:  t0,
+			EndsAt:    t2,
+			CreatedAt: t1,
+			CreatedBy: "user",
+			Comment:   "silence with exclusions",
+		}, &model.Matcher{Name: "severity", Value: "crit.*", IsRegex: true}),
 	}
 
 	dir, err := ioutil.TempDir("", "silences_test")
@@ -385,6 +481,20 @@ func TestSilencesMutes(t *testing.T) {
 			},
 			match: true,
 		},
+		{
+			lset: model.LabelSet{
+				"key3":     "val3",
+				"severity": "warning",
+			},
+			match: true,
+		},
+		{
+			lset: model.LabelSet{
+				"key3":     "val3",
+				"severity": "critical",
+			},
+			match: false,
+		},
 	}
 
 	for i, test := range tests {
@@ -465,6 +575,83 @@ func TestAlertsPut(t *testing.T) {
 	}
 }
 
+func TestAlertsQuery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alerts_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	alerts, err := NewAlerts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	insert := []*types.Alert{
+		{Alert: model.Alert{Labels: model.LabelSet{"job": "db", "severity": "critical"}, StartsAt: now}},
+		{Alert: model.Alert{Labels: model.LabelSet{"job": "db", "severity": "warning"}, StartsAt: now}},
+		{Alert: model.Alert{Labels: model.LabelSet{"job": "web", "severity": "critical"}, StartsAt: now}},
+		{Alert: model.Alert{Labels: model.LabelSet{"job": "db"}, StartsAt: now}},
+	}
+	if err := alerts.Put(insert...); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+	// The index must be rebuilt from the stored alerts on startup.
+	alerts.Close()
+
+	if alerts, err = NewAlerts(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer alerts.Close()
+
+	cases := []struct {
+		matchers types.Matchers
+		expected []model.Fingerprint
+	}{
+		{
+			matchers: types.Matchers{
+				types.NewMatcher("job", "db"),
+				types.NewRegexMatcher("severity", regexp.MustCompile("^(?:crit.*)$")),
+			},
+			expected: []model.Fingerprint{insert[0].Fingerprint()},
+		},
+		{
+			matchers: types.Matchers{types.NewMatcher("job", "db")},
+			expected: []model.Fingerprint{
+				insert[0].Fingerprint(),
+				insert[1].Fingerprint(),
+				insert[3].Fingerprint(),
+			},
+		},
+		{
+			// Matching the empty value selects alerts without the label.
+			matchers: types.Matchers{types.NewMatcher("severity", "")},
+			expected: []model.Fingerprint{insert[3].Fingerprint()},
+		},
+		{
+			matchers: types.Matchers{types.NewMatcher("job", "mail")},
+		},
+	}
+
+	for i, c := range cases {
+		res, err := alerts.Query(c.matchers)
+		if err != nil {
+			t.Fatalf("%d. unexpected error: %s", i, err)
+		}
+		var fps []model.Fingerprint
+		for _, a := range res {
+			fps = append(fps, a.Fingerprint())
+		}
+		sort.Sort(model.Fingerprints(fps))
+		sort.Sort(model.Fingerprints(c.expected))
+
+		if !reflect.DeepEqual(fps, c.expected) {
+			t.Errorf("%d. expected alerts %v but got %v", i, c.expected, fps)
+		}
+	}
+}
+
 func alertsEqual(a1, a2 *types.Alert) bool {
 	if !reflect.DeepEqual(a1.Labels, a2.Labels) {
 		return false
@@ -541,6 +728,9 @@ func notifyInfoEqual(n1, n2 *types.NotifyInfo) bool {
 	if n1.Receiver != n2.Receiver {
 		return false
 	}
+	if n1.FailoverTo != n2.FailoverTo {
+		return false
+	}
 	if !n1.Timestamp.Equal(n2.Timestamp) {
 		return false
 	}
@@ -559,3 +749,827 @@ func notifyInfoListEqual(n1, n2 []*types.NotifyInfo) bool {
 	}
 	return true
 }
+
+func TestEventsSearch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events_search")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := NewEvents(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Close()
+
+	insert := []*types.Event{
+		{
+			Title:  "Database outage",
+			Labels: model.LabelSet{"service": "db"},
+		},
+		{
+			Title:       "Network partition",
+			Annotations: model.LabelSet{"summary": "Database replicas unreachable"},
+		},
+		{
+			Title: "Disk full",
+		},
+	}
+	for _, e := range insert {
+		if _, err := events.Set(e); err != nil {
+			t.Fatalf("Insert failed: %s", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		ids   []uint64
+	}{
+		{query: "database", ids: []uint64{1, 2}},
+		{query: "DATABASE replicas", ids: []uint64{2}},
+		{query: "db", ids: []uint64{1}},
+		{query: "disk", ids: []uint64{3}},
+		{query: "cpu", ids: nil},
+		{query: "  ", ids: nil},
+	}
+
+	search := func() {
+		for _, test := range tests {
+			res, err := events.Search(test.query)
+			if err != nil {
+				t.Fatalf("Search failed: %s", err)
+			}
+			var ids []uint64
+			for _, e := range res {
+				ids = append(ids, e.ID)
+			}
+			if !reflect.DeepEqual(ids, test.ids) {
+				t.Errorf("query %q: expected events %v but got %v", test.query, test.ids, ids)
+			}
+		}
+	}
+	search()
+
+	// The index of events stored before it existed is built on opening.
+	if err := events.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(bktEventsIndex)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	events.Close()
+
+	events, err = NewEvents(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Close()
+
+	search()
+}
+
+func TestEventsUpdateDelete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events_update")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := NewEvents(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Close()
+
+	created := time.Now().UTC().Truncate(time.Second)
+
+	id, err := events.Set(&types.Event{Title: "Database outage", CreatedAt: created})
+	if err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+
+	upd := &types.Event{ID: id, Title: "Network outage"}
+	if err := events.Update(upd); err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	if upd.Version != 1 {
+		t.Fatalf("Expected version 1 after update but got %d", upd.Version)
+	}
+
+	// Updating based on a stale version must fail.
+	stale := &types.Event{ID: id, Title: "Stale", Version: 0}
+	if err := events.Update(stale); err != provider.ErrConflict {
+		t.Fatalf("Expected conflict on stale update but got %v", err)
+	}
+
+	e, err := events.Get(id)
+	if err != nil {
+		t.Fatalf("Retrieval failed: %s", err)
+	}
+	if e.Title != "Network outage" || e.Version != 1 || !e.CreatedAt.Equal(created) {
+		t.Fatalf("Unexpected event after update: %+v", e)
+	}
+
+	// The search index reflects the update.
+	if res, _ := events.Search("database"); len(res) != 0 {
+		t.Errorf("Expected no results for old title but got %v", res)
+	}
+	if res, _ := events.Search("network"); len(res) != 1 {
+		t.Errorf("Expected one result for new title but got %v", res)
+	}
+
+	if err := events.Delete(id); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if _, err := events.Get(id); err != provider.ErrNotFound {
+		t.Fatalf("Expected deleted event not to be found but got %v", err)
+	}
+	if res, _ := events.Search("network"); len(res) != 0 {
+		t.Errorf("Expected no results after delete but got %v", res)
+	}
+	if err := events.Delete(id); err != provider.ErrNotFound {
+		t.Fatalf("Expected not found on repeated delete but got %v", err)
+	}
+	if err := events.Update(&types.Event{ID: id}); err != provider.ErrNotFound {
+		t.Fatalf("Expected not found on update of deleted event but got %v", err)
+	}
+}
+
+func TestEventsMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events_metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	value := func(c prometheus.Collector) *dto.Metric {
+		var m dto.Metric
+		if err := c.(prometheus.Metric).Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		return &m
+	}
+
+	events, err := NewEvents(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reads := value(eventsOpDuration.WithLabelValues("read")).GetHistogram().GetSampleCount()
+
+	var ids []uint64
+	for _, title := range []string{"Database outage", "Network outage"} {
+		id, err := events.Set(&types.Event{Title: title})
+		if err != nil {
+			t.Fatalf("Insert failed: %s", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := events.Delete(ids[0]); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if _, err := events.Get(ids[1]); err != nil {
+		t.Fatalf("Retrieval failed: %s", err)
+	}
+
+	if v := value(eventsStored).GetGauge().GetValue(); v != 1 {
+		t.Errorf("Expected 1 stored event but got %v", v)
+	}
+	fi, err := os.Stat(filepath.Join(dir, "events.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := value(eventsDBSize).GetGauge().GetValue(); v != float64(fi.Size()) {
+		t.Errorf("Expected database size %d but got %v", fi.Size(), v)
+	}
+	if n := value(eventsOpDuration.WithLabelValues("read")).GetHistogram().GetSampleCount(); n != reads+1 {
+		t.Errorf("Expected 1 observed read but got %d", n-reads)
+	}
+
+	// Corrupt the stored event.
+	err = events.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bktEvents).ForEach(func(k, _ []byte) error {
+			return tx.Bucket(bktEvents).Put(k, []byte("{"))
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodeErrs := value(eventsSerializationErrors.WithLabelValues("decode")).GetCounter().GetValue()
+	if _, err := events.Get(ids[1]); err == nil {
+		t.Fatalf("Expected retrieval of corrupted event to fail")
+	}
+	if v := value(eventsSerializationErrors.WithLabelValues("decode")).GetCounter().GetValue(); v != decodeErrs+1 {
+		t.Errorf("Expected 1 decoding error but got %v", v-decodeErrs)
+	}
+
+	// The number of events is restored when the database is opened.
+	events.Close()
+	eventsStored.Set(0)
+
+	if events, err = NewEvents(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer events.Close()
+
+	if v := value(eventsStored).GetGauge().GetValue(); v != 1 {
+		t.Errorf("Expected 1 stored event after reopening but got %v", v)
+	}
+}
+
+func TestEventsChildren(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events_children")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	events, err := NewEvents(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Close()
+
+	parent, err := events.Set(&types.Event{Title: "Major incident"})
+	if err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+	var ids []uint64
+	for _, title := range []string{"Database outage", "Network outage"} {
+		id, err := events.Set(&types.Event{Title: title, ParentID: parent})
+		if err != nil {
+			t.Fatalf("Insert failed: %s", err)
+		}
+		ids = append(ids, id)
+	}
+
+	children, err := events.Children(parent)
+	if err != nil {
+		t.Fatalf("Retrieving children failed: %s", err)
+	}
+	if len(children) != 2 || children[0].ID != ids[0] || children[1].ID != ids[1] {
+		t.Fatalf("Expected both children in insertion order but got %v", children)
+	}
+
+	// Detached events are no children anymore.
+	upd := &types.Event{ID: ids[0], Title: "Database outage"}
+	if err := events.Update(upd); err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	if children, _ := events.Children(parent); len(children) != 1 || children[0].ID != ids[1] {
+		t.Fatalf("Expected only the attached child but got %v", children)
+	}
+}
+
+func TestEventsListByTimeRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events_time_range")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	events, err := NewEvents(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	// Events are inserted out of creation order.
+	for _, offset := range []time.Duration{2, 0, 3, 1} {
+		e := &types.Event{Title: fmt.Sprintf("Outage %d", offset), CreatedAt: now.Add(offset * time.Hour)}
+		if _, err := events.Set(e); err != nil {
+			t.Fatalf("Insert failed: %s", err)
+		}
+	}
+
+	titles := func(from, to time.Time, limit int) []string {
+		res, err := events.ListByTimeRange(from, to, limit)
+		if err != nil {
+			t.Fatalf("Listing events failed: %s", err)
+		}
+		titles := []string{}
+		for _, e := range res {
+			titles = append(titles, e.Title)
+		}
+		return titles
+	}
+
+	cases := []struct {
+		from, to time.Time
+		limit    int
+		expected []string
+	}{
+		{expected: []string{"Outage 0", "Outage 1", "Outage 2", "Outage 3"}},
+		{from: now.Add(time.Hour), expected: []string{"Outage 1", "Outage 2", "Outage 3"}},
+		{from: now.Add(time.Hour), to: now.Add(3 * time.Hour), expected: []string{"Outage 1", "Outage 2"}},
+		{to: now.Add(3 * time.Hour), limit: 1, expected: []string{"Outage 0"}},
+		{from: now.Add(4 * time.Hour), expected: []string{}},
+	}
+	for i, c := range cases {
+		if got := titles(c.from, c.to, c.limit); !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("%d. expected events %q but got %q", i, c.expected, got)
+		}
+	}
+
+	// Updates that change the creation time move the event in the index,
+	// deleted events are removed from it.
+	upd, err := events.Get(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upd.CreatedAt = now.Add(5 * time.Hour)
+	if err := events.Update(upd); err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	if err := events.Delete(4); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if got, expected := titles(time.Time{}, time.Time{}, 0), []string{"Outage 2", "Outage 3", "Outage 0"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected events %q but got %q", expected, got)
+	}
+
+	// The index of events stored before it existed is built on opening.
+	if err := events.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(bktEventsCreated)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	events.Close()
+
+	events, err = NewEvents(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Close()
+
+	if got, expected := titles(time.Time{}, time.Time{}, 0), []string{"Outage 2", "Outage 3", "Outage 0"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected events %q after rebuilding the index but got %q", expected, got)
+	}
+}
+
+func TestAcks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marker := types.NewMarker()
+
+	acks, err := NewAcks(dir, marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ack := &types.Ack{
+		Alert:     1,
+		CreatedBy: "user",
+		Comment:   "investigating",
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if err := acks.Set(ack); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+	if have, ok := marker.Acked(1); !ok || have != ack {
+		t.Fatalf("Expected alert to be marked as acknowledged")
+	}
+	acks.Close()
+
+	// Stored acknowledgements are loaded into the marker on startup.
+	marker = types.NewMarker()
+
+	acks, err = NewAcks(dir, marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer acks.Close()
+
+	if have, ok := marker.Acked(1); !ok || !reflect.DeepEqual(have, ack) {
+		t.Fatalf("Unexpected acknowledgement %v after restart", have)
+	}
+	if have, err := acks.Get(1); err != nil || !reflect.DeepEqual(have, ack) {
+		t.Fatalf("Unexpected acknowledgement %v, error %v", have, err)
+	}
+
+	if err := acks.Del(1); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if _, ok := marker.Acked(1); ok {
+		t.Fatalf("Expected acknowledgement to be removed from marker")
+	}
+	if _, err := acks.Get(1); err != provider.ErrNotFound {
+		t.Fatalf("Expected deleted acknowledgement not to be found but got %v", err)
+	}
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	marker := types.NewMarker()
+
+	m, err := NewMaintenanceWindows(dir, marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	mw := &types.MaintenanceWindow{
+		Matchers: []*model.Matcher{{Name: "service", Value: "db"}},
+		StartsAt: now.Add(time.Hour),
+		EndsAt:   now.Add(2 * time.Hour),
+		Owner:    "user",
+	}
+	id, err := m.Set(mw)
+	if err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+	if have, err := m.Get(id); err != nil || !reflect.DeepEqual(have, mw) {
+		t.Fatalf("Unexpected maintenance window %v, error %v", have, err)
+	}
+
+	lset := model.LabelSet{"alertname": "HighLatency", "service": "db"}
+	if m.Mutes(lset) {
+		t.Fatalf("Expected planned maintenance window not to mute alerts")
+	}
+
+	// Windows are updated in place.
+	mw.StartsAt = now.Add(-time.Hour)
+	if _, err := m.Set(mw); err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	if !m.Mutes(lset) {
+		t.Fatalf("Expected active maintenance window to mute alerts")
+	}
+	if have, ok := marker.Maintenance(lset.Fingerprint()); !ok || have != id {
+		t.Fatalf("Expected alert to be marked with maintenance window %d but got %d", id, have)
+	}
+	if m.Mutes(model.LabelSet{"service": "web"}) {
+		t.Fatalf("Expected alerts of other services not to be muted")
+	}
+
+	if _, err := m.Set(&types.MaintenanceWindow{ID: id + 1}); err != provider.ErrNotFound {
+		t.Fatalf("Expected update of unknown window to fail but got %v", err)
+	}
+
+	if err := m.Del(id); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if m.Mutes(lset) {
+		t.Fatalf("Expected deleted maintenance window not to mute alerts")
+	}
+	if _, ok := marker.Maintenance(lset.Fingerprint()); ok {
+		t.Fatalf("Expected maintenance mark to be removed")
+	}
+	if _, err := m.Get(id); err != provider.ErrNotFound {
+		t.Fatalf("Expected deleted maintenance window not to be found but got %v", err)
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dependencies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d, err := NewDependencies(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	deps := []*types.Dependency{
+		{Service: "app", DependsOn: "db", Equal: model.LabelNames{"cluster"}, CreatedBy: "user", CreatedAt: time.Now().UTC()},
+		{Service: "db", DependsOn: "storage", CreatedBy: "user", CreatedAt: time.Now().UTC()},
+	}
+	for _, dep := range deps {
+		if _, err := d.Set(dep); err != nil {
+			t.Fatalf("Insert failed: %s", err)
+		}
+	}
+	if have, err := d.Get(deps[0].ID); err != nil || !reflect.DeepEqual(have, deps[0]) {
+		t.Fatalf("Unexpected dependency %v, error %v", have, err)
+	}
+	if have, err := d.All(); err != nil || !reflect.DeepEqual(have, deps) {
+		t.Fatalf("Unexpected dependencies %v, error %v", have, err)
+	}
+
+	if _, err := d.Set(&types.Dependency{ID: deps[1].ID + 1}); err != provider.ErrNotFound {
+		t.Fatalf("Expected update of unknown dependency to fail but got %v", err)
+	}
+	if err := d.Del(deps[0].ID); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if _, err := d.Get(deps[0].ID); err != provider.ErrNotFound {
+		t.Fatalf("Expected deleted dependency not to be found but got %v", err)
+	}
+	if err := d.Del(deps[0].ID); err != provider.ErrNotFound {
+		t.Fatalf("Expected deletion of unknown dependency to fail but got %v", err)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dead_letters")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dl, err := NewDeadLetters(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	var letters []*types.DeadLetter
+	for i := 0; i < 3; i++ {
+		l := &types.DeadLetter{
+			Receiver:    fmt.Sprintf("rcv%d", i),
+			GroupKey:    "key",
+			GroupLabels: model.LabelSet{"a": "b"},
+			Alerts: []*types.Alert{{
+				Alert: model.Alert{
+					Labels:   model.LabelSet{"a": "b"},
+					StartsAt: now,
+				},
+			}},
+			Reason: "context deadline exceeded",
+			Time:   now,
+		}
+		id, err := dl.Add(l)
+		if err != nil {
+			t.Fatalf("Insert failed: %s", err)
+		}
+		if id != uint64(i+1) || l.ID != id {
+			t.Fatalf("Unexpected ID %d of dead letter %d", l.ID, i)
+		}
+		letters = append(letters, l)
+	}
+
+	// The oldest dead letter exceeding the limit is dropped.
+	all, err := dl.All()
+	if err != nil {
+		t.Fatalf("Retrieval failed: %s", err)
+	}
+	if !reflect.DeepEqual(all, letters[1:]) {
+		t.Fatalf("Unexpected dead letters\n%v\nexpected\n%v", all, letters[1:])
+	}
+	if _, err := dl.Get(1); err != provider.ErrNotFound {
+		t.Fatalf("Expected dropped dead letter not to be found but got %v", err)
+	}
+	if have, err := dl.Get(3); err != nil || !reflect.DeepEqual(have, letters[2]) {
+		t.Fatalf("Unexpected dead letter %v, error %v", have, err)
+	}
+
+	if err := dl.Del(3); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if err := dl.Del(3); err != provider.ErrNotFound {
+		t.Fatalf("Expected deleted dead letter not to be found but got %v", err)
+	}
+}
+
+func TestDeadLettersCollect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dead_letters_collect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl, err := NewDeadLetters(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 3; i > 0; i-- {
+		if _, err := dl.Add(&types.DeadLetter{Receiver: "rcv", Time: now.Add(-time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatalf("Insert failed: %s", err)
+		}
+	}
+
+	expired, evicted, err := dl.Collect(now, provider.Retention{MaxAge: 150 * time.Minute})
+	if err != nil {
+		t.Fatalf("Collect failed: %s", err)
+	}
+	if expired != 1 || evicted != 0 {
+		t.Fatalf("Expected 1 expired and no evicted dead letter but got %d and %d", expired, evicted)
+	}
+	if _, err := dl.Get(1); err != provider.ErrNotFound {
+		t.Fatalf("Expected expired dead letter not to be found but got %v", err)
+	}
+
+	// A size limit below the size of a single dead letter removes all.
+	expired, evicted, err = dl.Collect(now, provider.Retention{MaxSize: 1})
+	if err != nil {
+		t.Fatalf("Collect failed: %s", err)
+	}
+	if expired != 0 || evicted != 2 {
+		t.Fatalf("Expected 2 evicted dead letters but got %d expired and %d evicted", expired, evicted)
+	}
+	if all, err := dl.All(); err != nil || len(all) != 0 {
+		t.Fatalf("Expected no dead letters but got %v, error %v", all, err)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	audit, err := NewAudit(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+
+	now := time.Now()
+	for i, action := range []string{"add_silence", "ack_event", "reload_config"} {
+		e := &types.AuditEntry{
+			Time:      now.Add(time.Duration(i) * time.Minute),
+			Principal: "alice",
+			Action:    action,
+			Status:    200,
+		}
+		id, err := audit.Add(e)
+		if err != nil {
+			t.Fatalf("Adding entry failed: %s", err)
+		}
+		if id != uint64(i+1) || e.ID != id {
+			t.Fatalf("expected ID %d but got %d", i+1, id)
+		}
+	}
+
+	res, err := audit.Query(time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("Query failed: %s", err)
+	}
+	if len(res) != 3 || res[0].Action != "reload_config" || res[2].Action != "add_silence" {
+		t.Fatalf("expected all entries newest first but got %v", res)
+	}
+
+	res, err = audit.Query(now.Add(time.Minute), 0)
+	if err != nil {
+		t.Fatalf("Query failed: %s", err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("expected 2 entries since the given time but got %d", len(res))
+	}
+
+	res, err = audit.Query(time.Time{}, 1)
+	if err != nil {
+		t.Fatalf("Query failed: %s", err)
+	}
+	if len(res) != 1 || res[0].ID != 3 {
+		t.Fatalf("expected only the newest entry but got %v", res)
+	}
+}
+
+func TestAlertHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alert_history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h, err := NewAlertHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	var (
+		now = time.Now()
+		fp1 = model.Fingerprint(1)
+		fp2 = model.Fingerprint(2)
+	)
+	err = h.Add(
+		&types.AlertHistoryEntry{Alert: fp1, Time: now, State: types.AlertResolved},
+		&types.AlertHistoryEntry{Alert: fp2, Time: now, State: types.AlertFiring},
+		&types.AlertHistoryEntry{Alert: fp1, Time: now.Add(-time.Hour), State: types.AlertFiring},
+		&types.AlertHistoryEntry{Alert: fp1, Time: now.Add(-time.Hour), State: types.AlertSilenced, Silence: 3},
+	)
+	if err != nil {
+		t.Fatalf("Adding entries failed: %s", err)
+	}
+
+	entries, err := h.Get(fp1)
+	if err != nil {
+		t.Fatalf("Retrieval failed: %s", err)
+	}
+	expected := []types.AlertHistoryState{types.AlertFiring, types.AlertSilenced, types.AlertResolved}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries but got %d", len(expected), len(entries))
+	}
+	for i, e := range entries {
+		if e.Alert != fp1 || e.State != expected[i] {
+			t.Fatalf("%d. Expected %q entry of alert %v but got %+v", i, expected[i], fp1, e)
+		}
+	}
+
+	entries, err = h.Query(now.Add(-time.Minute), now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Query failed: %s", err)
+	}
+	if len(entries) != 2 || entries[0].Alert != fp1 || entries[1].Alert != fp2 {
+		t.Fatalf("Expected the entries of both alerts within the range but got %v", entries)
+	}
+	if entries, _ := h.Query(now.Add(-2*time.Hour), now); len(entries) != 2 || entries[0].State != types.AlertFiring {
+		t.Fatalf("Expected the entries before the end of the range but got %v", entries)
+	}
+
+	if err := h.GC(now.Add(-time.Minute)); err != nil {
+		t.Fatalf("GC failed: %s", err)
+	}
+	if entries, _ := h.Get(fp1); len(entries) != 1 || entries[0].State != types.AlertResolved {
+		t.Fatalf("Expected only the resolved entry after GC but got %v", entries)
+	}
+	if entries, _ := h.Get(fp2); len(entries) != 1 {
+		t.Fatalf("Expected entries of other alerts to be kept but got %v", entries)
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "attachments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Content is stored in the database or in files.
+	for i, blobDir := range []string{"", filepath.Join(dir, "blobs")} {
+		path := filepath.Join(dir, fmt.Sprintf("db%d", i))
+		if err := os.MkdirAll(path, 0777); err != nil {
+			t.Fatal(err)
+		}
+		s, err := NewAttachments(path, blobDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+
+		var added []*types.Attachment
+		for j, eid := range []uint64{1, 2, 1} {
+			a := &types.Attachment{
+				EventID:     eid,
+				Filename:    fmt.Sprintf("file%d.txt", j),
+				ContentType: "text/plain",
+				CreatedAt:   now,
+			}
+			if _, err := s.Add(a, []byte(a.Filename)); err != nil {
+				t.Fatal(err)
+			}
+			added = append(added, a)
+		}
+
+		res, err := s.List(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := []*types.Attachment{added[0], added[2]}; !reflect.DeepEqual(res, exp) {
+			t.Fatalf("expected attachments %v but got %v", exp, res)
+		}
+
+		a, content, err := s.Get(2, added[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a, added[1]) || string(content) != "file1.txt" {
+			t.Fatalf("unexpected attachment %v with content %q", a, content)
+		}
+		if _, _, err := s.Get(1, added[1].ID); err != provider.ErrNotFound {
+			t.Fatalf("expected attachment of other event not to be found but got %v", err)
+		}
+
+		if err := s.Delete(1, added[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Delete(1, added[0].ID); err != provider.ErrNotFound {
+			t.Fatalf("expected deleted attachment not to be found but got %v", err)
+		}
+		if err := s.DeleteAll(1); err != nil {
+			t.Fatal(err)
+		}
+		if res, err := s.List(1); err != nil || len(res) != 0 {
+			t.Fatalf("expected no attachments of deleted event but got %v, %v", res, err)
+		}
+		if res, err := s.List(2); err != nil || len(res) != 1 {
+			t.Fatalf("expected attachments of other events to be kept but got %v, %v", res, err)
+		}
+
+		if blobDir != "" {
+			files, err := ioutil.ReadDir(blobDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 1 {
+				t.Fatalf("expected content files of deleted attachments to be removed but got %d files", len(files))
+			}
+		}
+		s.Close()
+	}
+}
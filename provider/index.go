@@ -0,0 +1,105 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"sync"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// LabelIndex is an inverted index from label pairs to the fingerprints
+// of the alerts carrying them. It allows providers to answer matcher
+// queries without decoding every stored alert.
+type LabelIndex struct {
+	mtx      sync.RWMutex
+	all      map[model.Fingerprint]struct{}
+	postings map[model.LabelName]map[model.LabelValue]map[model.Fingerprint]struct{}
+}
+
+// NewLabelIndex returns a new empty LabelIndex.
+func NewLabelIndex() *LabelIndex {
+	return &LabelIndex{
+		all:      map[model.Fingerprint]struct{}{},
+		postings: map[model.LabelName]map[model.LabelValue]map[model.Fingerprint]struct{}{},
+	}
+}
+
+// Add indexes the given label set under its fingerprint.
+func (ix *LabelIndex) Add(lset model.LabelSet) {
+	ix.mtx.Lock()
+	defer ix.mtx.Unlock()
+
+	fp := lset.Fingerprint()
+	if _, ok := ix.all[fp]; ok {
+		return
+	}
+	ix.all[fp] = struct{}{}
+
+	for ln, lv := range lset {
+		vals, ok := ix.postings[ln]
+		if !ok {
+			vals = map[model.LabelValue]map[model.Fingerprint]struct{}{}
+			ix.postings[ln] = vals
+		}
+		fps, ok := vals[lv]
+		if !ok {
+			fps = map[model.Fingerprint]struct{}{}
+			vals[lv] = fps
+		}
+		fps[fp] = struct{}{}
+	}
+}
+
+// Candidates returns the fingerprints of all indexed label sets that may
+// be matched by the matchers. Matchers that also match the empty value
+// select label sets without the label and cannot narrow down the result.
+// Callers must still check the matchers against the returned alerts.
+func (ix *LabelIndex) Candidates(ms types.Matchers) []model.Fingerprint {
+	ix.mtx.RLock()
+	defer ix.mtx.RUnlock()
+
+	var set map[model.Fingerprint]struct{}
+
+	for _, m := range ms {
+		if m.Match(model.LabelSet{}) {
+			continue
+		}
+		cur := map[model.Fingerprint]struct{}{}
+		for lv, fps := range ix.postings[m.Name] {
+			if !m.Match(model.LabelSet{m.Name: lv}) {
+				continue
+			}
+			for fp := range fps {
+				if set == nil {
+					cur[fp] = struct{}{}
+				} else if _, ok := set[fp]; ok {
+					cur[fp] = struct{}{}
+				}
+			}
+		}
+		set = cur
+	}
+	if set == nil {
+		set = ix.all
+	}
+
+	res := make([]model.Fingerprint, 0, len(set))
+	for fp := range set {
+		res = append(res, fp)
+	}
+	return res
+}
@@ -14,7 +14,9 @@
 package provider
 
 import (
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/prometheus/common/model"
 
@@ -26,6 +28,7 @@ type MemData struct {
 	mtx      sync.RWMutex
 	alerts   map[model.Fingerprint]*types.Alert
 	notifies map[string]map[model.Fingerprint]*types.NotifyInfo
+	index    *LabelIndex
 }
 
 // NewMemData contains an empty but initialized MemData instance.
@@ -33,6 +36,7 @@ func NewMemData() *MemData {
 	return &MemData{
 		alerts:   map[model.Fingerprint]*types.Alert{},
 		notifies: map[string]map[model.Fingerprint]*types.NotifyInfo{},
+		index:    NewLabelIndex(),
 	}
 }
 
@@ -127,8 +131,28 @@ func (a *MemAlerts) GetPending() AlertIterator {
 	}
 }
 
-func (a *MemAlerts) getPending() []*types.Alert {
-	// Get fingerprints for all alerts that have pending notifications.
+// Query implements the Alerts interface.
+func (a *MemAlerts) Query(ms types.Matchers) ([]*types.Alert, error) {
+	a.data.mtx.RLock()
+	defer a.data.mtx.RUnlock()
+
+	over := a.resolved()
+
+	var alerts []*types.Alert
+	for _, fp := range a.data.index.Candidates(ms) {
+		if _, ok := over[fp]; ok {
+			continue
+		}
+		if al, ok := a.data.alerts[fp]; ok && ms.Match(al.Labels) {
+			alerts = append(alerts, al)
+		}
+	}
+	return alerts, nil
+}
+
+// resolved returns the fingerprints of all alerts that have been
+// notified about as resolved.
+func (a *MemAlerts) resolved() map[model.Fingerprint]struct{} {
 	over := map[model.Fingerprint]struct{}{}
 	for _, ns := range a.data.notifies {
 		for fp, notify := range ns {
@@ -137,6 +161,12 @@ func (a *MemAlerts) getPending() []*types.Alert {
 			}
 		}
 	}
+	return over
+}
+
+func (a *MemAlerts) getPending() []*types.Alert {
+	// Get fingerprints for all alerts that have pending notifications.
+	over := a.resolved()
 
 	// All alerts that have pending notifications are part of the
 	// new scubscription.
@@ -166,6 +196,7 @@ func (a *MemAlerts) Put(alerts ...*types.Alert) error {
 		}
 
 		a.data.alerts[fp] = alert
+		a.data.index.Add(alert.Labels)
 
 		for _, ch := range a.listeners {
 			ch <- alert
@@ -215,6 +246,20 @@ func (n *MemNotifies) Set(ns ...*types.NotifyInfo) error {
 	return nil
 }
 
+// All implements the Notifies interface.
+func (n *MemNotifies) All() ([]*types.NotifyInfo, error) {
+	n.data.mtx.RLock()
+	defer n.data.mtx.RUnlock()
+
+	var res []*types.NotifyInfo
+	for _, ns := range n.data.notifies {
+		for _, ni := range ns {
+			res = append(res, ni)
+		}
+	}
+	return res, nil
+}
+
 // Get implements the Notifies interface.
 func (n *MemNotifies) Get(dest string, fps ...model.Fingerprint) ([]*types.NotifyInfo, error) {
 	n.data.mtx.RLock()
@@ -237,13 +282,13 @@ func (n *MemNotifies) Get(dest string, fps ...model.Fingerprint) ([]*types.Notif
 // MemSilences implements a Silences provider based on in-memory data.
 type MemSilences struct {
 	mtx      sync.RWMutex
-	silences map[uint64]*model.Silence
+	silences map[uint64]*types.Silence
 }
 
 // NewMemSilences returns a new MemSilences.
 func NewMemSilences() *MemSilences {
 	return &MemSilences{
-		silences: map[uint64]*model.Silence{},
+		silences: map[uint64]*types.Silence{},
 	}
 }
 
@@ -253,7 +298,7 @@ func (s *MemSilences) Mutes(lset model.LabelSet) bool {
 	defer s.mtx.RUnlock()
 
 	for _, sil := range s.silences {
-		if types.NewSilence(sil).Mutes(lset) {
+		if sil.Mutes(lset) {
 			return true
 		}
 	}
@@ -267,7 +312,7 @@ func (s *MemSilences) All() ([]*types.Silence, error) {
 
 	var sils []*types.Silence
 	for _, sil := range s.silences {
-		sils = append(sils, types.NewSilence(sil))
+		sils = append(sils, types.RestoreSilence(sil))
 	}
 	return sils, nil
 }
@@ -285,7 +330,7 @@ func (s *MemSilences) Set(sil *types.Silence) (uint64, error) {
 		}
 	}
 
-	s.silences[sil.ID] = &sil.Silence
+	s.silences[sil.ID] = types.RestoreSilence(sil)
 	return sil.ID, nil
 }
 
@@ -307,5 +352,231 @@ func (s *MemSilences) Get(id uint64) (*types.Silence, error) {
 	if !ok {
 		return nil, ErrNotFound
 	}
-	return types.NewSilence(sil), nil
+	return types.RestoreSilence(sil), nil
+}
+
+// MemEvents implements an Events provider based on in-memory data.
+type MemEvents struct {
+	mtx    sync.RWMutex
+	next   uint64
+	events map[uint64]*types.Event
+}
+
+// NewMemEvents returns a new MemEvents.
+func NewMemEvents() *MemEvents {
+	return &MemEvents{
+		events: map[uint64]*types.Event{},
+	}
+}
+
+// All implements the Events interface.
+func (s *MemEvents) All() ([]*types.Event, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.sorted(func(*types.Event) bool { return true }), nil
+}
+
+// sorted returns copies of the events accepted by the filter in
+// insertion order.
+func (s *MemEvents) sorted(filter func(*types.Event) bool) []*types.Event {
+	var res []*types.Event
+	for _, e := range s.events {
+		if filter(e) {
+			ec := *e
+			res = append(res, &ec)
+		}
+	}
+	sort.Sort(eventsByID(res))
+
+	return res
+}
+
+type eventsByID []*types.Event
+
+func (es eventsByID) Len() int           { return len(es) }
+func (es eventsByID) Swap(i, j int)      { es[i], es[j] = es[j], es[i] }
+func (es eventsByID) Less(i, j int) bool { return es[i].ID < es[j].ID }
+
+// Set implements the Events interface.
+func (s *MemEvents) Set(event *types.Event) (uint64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.next++
+	event.ID = s.next
+
+	ec := *event
+	s.events[event.ID] = &ec
+
+	return event.ID, nil
+}
+
+// Get implements the Events interface.
+func (s *MemEvents) Get(id uint64) (*types.Event, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	e, ok := s.events[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	ec := *e
+	return &ec, nil
+}
+
+// Search implements the Events interface.
+func (s *MemEvents) Search(query string) ([]*types.Event, error) {
+	toks := Tokenize(query)
+	if len(toks) == 0 {
+		return nil, nil
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.sorted(func(e *types.Event) bool {
+		etoks := EventTokens(e)
+		for _, tok := range toks {
+			if _, ok := etoks[tok]; !ok {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
+
+// Update implements the Events interface.
+func (s *MemEvents) Update(event *types.Event) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	old, ok := s.events[event.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if old.Version != event.Version {
+		return ErrConflict
+	}
+	event.Version++
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = old.CreatedAt
+	}
+
+	ec := *event
+	s.events[event.ID] = &ec
+
+	return nil
+}
+
+// Delete implements the Events interface.
+func (s *MemEvents) Delete(id uint64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.events[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.events, id)
+
+	return nil
+}
+
+// Children implements the Events interface.
+func (s *MemEvents) Children(id uint64) ([]*types.Event, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.sorted(func(e *types.Event) bool { return e.ParentID == id }), nil
+}
+
+// ListByTimeRange implements the Events interface.
+func (s *MemEvents) ListByTimeRange(from, to time.Time, limit int) ([]*types.Event, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	res := s.sorted(func(e *types.Event) bool {
+		return !e.CreatedAt.Before(from) && (to.IsZero() || e.CreatedAt.Before(to))
+	})
+	sort.Stable(eventsByCreation(res))
+
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+type eventsByCreation []*types.Event
+
+func (es eventsByCreation) Len() int           { return len(es) }
+func (es eventsByCreation) Swap(i, j int)      { es[i], es[j] = es[j], es[i] }
+func (es eventsByCreation) Less(i, j int) bool { return es[i].CreatedAt.Before(es[j].CreatedAt) }
+
+// MemDeadLetters implements a DeadLetters provider based on in-memory data.
+type MemDeadLetters struct {
+	mtx     sync.RWMutex
+	size    int
+	next    uint64
+	letters []*types.DeadLetter
+}
+
+// NewMemDeadLetters returns a new MemDeadLetters retaining at most size
+// dead letters.
+func NewMemDeadLetters(size int) *MemDeadLetters {
+	return &MemDeadLetters{size: size}
+}
+
+// All implements the DeadLetters interface.
+func (dl *MemDeadLetters) All() ([]*types.DeadLetter, error) {
+	dl.mtx.RLock()
+	defer dl.mtx.RUnlock()
+
+	res := make([]*types.DeadLetter, len(dl.letters))
+	copy(res, dl.letters)
+
+	return res, nil
+}
+
+// Get implements the DeadLetters interface.
+func (dl *MemDeadLetters) Get(id uint64) (*types.DeadLetter, error) {
+	dl.mtx.RLock()
+	defer dl.mtx.RUnlock()
+
+	for _, l := range dl.letters {
+		if l.ID == id {
+			return l, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Add implements the DeadLetters interface. The oldest dead letter is
+// dropped if the limit is exceeded.
+func (dl *MemDeadLetters) Add(l *types.DeadLetter) (uint64, error) {
+	dl.mtx.Lock()
+	defer dl.mtx.Unlock()
+
+	dl.next++
+	l.ID = dl.next
+
+	dl.letters = append(dl.letters, l)
+
+	if len(dl.letters) > dl.size {
+		dl.letters = dl.letters[len(dl.letters)-dl.size:]
+	}
+	return l.ID, nil
+}
+
+// Del implements the DeadLetters interface.
+func (dl *MemDeadLetters) Del(id uint64) error {
+	dl.mtx.Lock()
+	defer dl.mtx.Unlock()
+
+	for i, l := range dl.letters {
+		if l.ID == id {
+			dl.letters = append(dl.letters[:i], dl.letters[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
 }
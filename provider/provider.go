@@ -15,6 +15,10 @@ package provider
 
 import (
 	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/prometheus/common/model"
 
@@ -24,6 +28,8 @@ import (
 var (
 	// ErrNotFound is returned if a provider cannot find a requested item.
 	ErrNotFound = fmt.Errorf("item not found")
+	// ErrConflict is returned if an item was modified since it was read.
+	ErrConflict = fmt.Errorf("item was modified concurrently")
 )
 
 // Iterator provides the functions common to all iterators. To be useful, a
@@ -72,6 +78,14 @@ func (ai alertIterator) Next() <-chan *types.Alert {
 func (ai alertIterator) Err() error { return ai.err }
 func (ai alertIterator) Close()     { close(ai.done) }
 
+// Backuper is implemented by providers that can back up their database
+// while it is in use.
+type Backuper interface {
+	// Backup calls fn with the size and contents of a consistent snapshot
+	// of the database.
+	Backup(fn func(size int64, snapshot io.WriterTo) error) error
+}
+
 // Alerts gives access to a set of alerts. All methods are goroutine-safe.
 type Alerts interface {
 	// Subscribe returns an iterator over active alerts that have not been
@@ -81,6 +95,9 @@ type Alerts interface {
 	// GetPending returns an iterator over all alerts that have
 	// pending notifications.
 	GetPending() AlertIterator
+	// Query returns all alerts with pending notifications whose
+	// labels are matched by all of the given matchers.
+	Query(types.Matchers) ([]*types.Alert, error)
 	// Get returns the alert for a given fingerprint.
 	Get(model.Fingerprint) (*types.Alert, error)
 	// Put adds the given alert to the set.
@@ -107,13 +124,186 @@ type Silences interface {
 // Notifies provides information about pending and successful
 // notifications. All methods are goroutine-safe.
 type Notifies interface {
+	// All returns the notification log of all receivers.
+	All() ([]*types.NotifyInfo, error)
 	Get(dest string, fps ...model.Fingerprint) ([]*types.NotifyInfo, error)
 	// Set several notifies at once. All or none must succeed.
 	Set(ns ...*types.NotifyInfo) error
 }
 
+// Acks gives access to alert acknowledgements. All methods are
+// goroutine-safe.
+type Acks interface {
+	// All returns all existing acknowledgements.
+	All() ([]*types.Ack, error)
+	// Get returns the acknowledgement of the alert or ErrNotFound.
+	Get(model.Fingerprint) (*types.Ack, error)
+	// Set stores an acknowledgement, replacing any previous one of the
+	// same alert.
+	Set(*types.Ack) error
+	// Del removes the acknowledgement of the alert.
+	Del(model.Fingerprint) error
+}
+
+// MaintenanceWindows gives access to planned maintenance windows. All
+// methods are goroutine-safe.
+type MaintenanceWindows interface {
+	// Alerts matching an active window are muted and marked with the
+	// window in the marker.
+	types.Muter
+
+	// All returns all existing maintenance windows.
+	All() ([]*types.MaintenanceWindow, error)
+	// Get returns the maintenance window with the given ID or ErrNotFound.
+	Get(uint64) (*types.MaintenanceWindow, error)
+	// Set creates the maintenance window if its ID is 0 and replaces the
+	// window with its ID otherwise. It returns the window's ID.
+	Set(*types.MaintenanceWindow) (uint64, error)
+	// Del removes the maintenance window with the given ID.
+	Del(uint64) error
+}
+
+// Dependencies gives access to the dependencies between services. All
+// methods are goroutine-safe.
+type Dependencies interface {
+	// All returns all existing dependencies ordered by ID.
+	All() ([]*types.Dependency, error)
+	// Get returns the dependency with the given ID or ErrNotFound.
+	Get(uint64) (*types.Dependency, error)
+	// Set creates the dependency if its ID is 0 and replaces the
+	// dependency with its ID otherwise. It returns the dependency's ID.
+	Set(*types.Dependency) (uint64, error)
+	// Del removes the dependency with the given ID.
+	Del(uint64) error
+}
+
+// DeadLetters stores notifications that were given up on so they can be
+// inspected and sent again. All methods are goroutine-safe.
+type DeadLetters interface {
+	// All returns all dead letters in the order they were added.
+	All() ([]*types.DeadLetter, error)
+	// Get returns the dead letter with the given ID or ErrNotFound.
+	Get(id uint64) (*types.DeadLetter, error)
+	// Add stores a dead letter and sets its ID. Implementations may drop
+	// the oldest dead letters to bound their size.
+	Add(*types.DeadLetter) (uint64, error)
+	// Del removes the dead letter with the given ID.
+	Del(id uint64) error
+}
+
+// Retention limits the entries kept in a store. Zero limits are disabled.
+type Retention struct {
+	// Entries written longer ago are removed.
+	MaxAge time.Duration
+	// The least recently written entries are removed while the total size
+	// of all entries in bytes exceeds the maximum.
+	MaxSize int64
+}
+
+// Collector is implemented by stores whose entries can be removed
+// according to a retention.
+type Collector interface {
+	// Collect removes the entries exceeding the retention at the given
+	// time. It returns the number of entries removed for exceeding the
+	// maximum age and the maximum size.
+	Collect(now time.Time, r Retention) (expired, evicted int, err error)
+}
+
+// AlertHistory stores the state changes of alerts.
+type AlertHistory interface {
+	// Add records the entries.
+	Add(...*types.AlertHistoryEntry) error
+	// Get returns the entries of the alert in chronological order.
+	Get(model.Fingerprint) ([]*types.AlertHistoryEntry, error)
+	// Query returns the entries of all alerts recorded at or after from
+	// and before to, ordered by alert and time.
+	Query(from, to time.Time) ([]*types.AlertHistoryEntry, error)
+	// GC removes all entries older than the given time.
+	GC(before time.Time) error
+}
+
+// Audit stores the audit log of mutating API calls.
+type Audit interface {
+	// Add appends the entry to the log and sets its ID.
+	Add(*types.AuditEntry) (uint64, error)
+	// Query returns up to limit entries recorded at or after since, newest
+	// first. A limit of zero returns all of them.
+	Query(since time.Time, limit int) ([]*types.AuditEntry, error)
+}
+
+// Threads stores the IDs of message threads that chat integrations post
+// follow-up notifications of an alert group to.
+type Threads interface {
+	// Get returns the thread ID stored for the key or ErrNotFound.
+	Get(key string) (string, error)
+	// Set stores the thread ID for the key.
+	Set(key, id string) error
+	// Del removes the thread ID stored for the key.
+	Del(key string) error
+}
+
+// Events gives access to events. All methods are goroutine-safe.
 type Events interface {
 	All() ([]*types.Event, error)
 	Set(*types.Event) (uint64, error)
 	Get(id uint64) (*types.Event, error)
+	// Search returns all events matching every word of the query.
+	Search(query string) ([]*types.Event, error)
+	// Update replaces the event with the same ID. It fails with ErrConflict
+	// if the stored event's version differs from the given one. On success
+	// the event's version is incremented.
+	Update(*types.Event) error
+	// Delete removes the event with the given ID.
+	Delete(id uint64) error
+	// Children returns the events whose parent is the event with the
+	// given ID.
+	Children(id uint64) ([]*types.Event, error)
+	// ListByTimeRange returns the events created at or after from and
+	// before to, ordered by their creation time. A zero from or to leaves
+	// the range open on that side. If limit is positive, at most limit
+	// events are returned.
+	ListByTimeRange(from, to time.Time, limit int) ([]*types.Event, error)
+}
+
+// EventAttachments stores files attached to events.
+type EventAttachments interface {
+	// List returns the attachments of the event in the order they were
+	// added.
+	List(eventID uint64) ([]*types.Attachment, error)
+	// Add stores the attachment with the given content and sets its ID.
+	Add(a *types.Attachment, content []byte) (uint64, error)
+	// Get returns the attachment of the event with the given ID and its
+	// content.
+	Get(eventID, id uint64) (*types.Attachment, []byte, error)
+	// Delete removes the attachment of the event with the given ID.
+	Delete(eventID, id uint64) error
+	// DeleteAll removes all attachments of the event.
+	DeleteAll(eventID uint64) error
+}
+
+// EventTokens returns the set of search tokens of the event's title,
+// severity, assignee, kind, level, creator, labels, and annotations.
+func EventTokens(event *types.Event) map[string]struct{} {
+	texts := []string{event.Title, string(event.Severity), event.Assignee, event.Kind, event.Level, event.Creator}
+	for ln, lv := range event.Labels {
+		texts = append(texts, string(ln), string(lv))
+	}
+	for ln, lv := range event.Annotations {
+		texts = append(texts, string(ln), string(lv))
+	}
+
+	toks := map[string]struct{}{}
+	for _, t := range texts {
+		for _, tok := range Tokenize(t) {
+			toks[tok] = struct{}{}
+		}
+	}
+	return toks
+}
+
+// Tokenize splits a text into lower-cased words of letters and digits.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
 }
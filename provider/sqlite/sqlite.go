@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/prometheus/common/log"
@@ -175,6 +177,21 @@ func (a *Alerts) getPending() ([]*types.Alert, error) {
 	return alerts, nil
 }
 
+// Query implements the Alerts interface.
+func (a *Alerts) Query(ms types.Matchers) ([]*types.Alert, error) {
+	alerts, err := a.getPending()
+	if err != nil {
+		return nil, err
+	}
+	var res []*types.Alert
+	for _, al := range alerts {
+		if ms.Match(al.Labels) {
+			res = append(res, al)
+		}
+	}
+	return res, nil
+}
+
 // Get implements the Alerts interface.
 func (a *Alerts) Get(model.Fingerprint) (*types.Alert, error) {
 	return nil, nil
@@ -335,7 +352,8 @@ CREATE TABLE IF NOT EXISTS notify_info (
 	alert      bigint,
 	receiver   text,
 	resolved   integer,
-	timestamp  timestamp
+	timestamp  timestamp,
+	failover_to text
 );
 CREATE INDEX IF NOT EXISTS notify_done ON notify_info (resolved);
 CREATE UNIQUE INDEX IF NOT EXISTS alert_receiver ON notify_info (alert,receiver);
@@ -357,11 +375,95 @@ func NewNotifies(db *sql.DB) (*Notifies, error) {
 		tx.Rollback()
 		return nil, err
 	}
+	if err := addColumn(tx, "notify_info", "failover_to", "text"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 	tx.Commit()
 
 	return &Notifies{db: db}, nil
 }
 
+// addColumn adds a column to a table created by a previous version if it
+// does not exist yet.
+func addColumn(tx *sql.Tx, table, column, typ string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var (
+			vals = make([]interface{}, len(cols))
+			name sql.NullString
+		)
+		for i, c := range cols {
+			if c == "name" {
+				vals[i] = &name
+			} else {
+				vals[i] = new(interface{})
+			}
+		}
+		if err := rows.Scan(vals...); err != nil {
+			return err
+		}
+		if name.String == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, typ))
+	return err
+}
+
+// All implements the Notifies interface.
+func (n *Notifies) All() ([]*types.NotifyInfo, error) {
+	dbmtx.Lock()
+	defer dbmtx.Unlock()
+
+	rows, err := n.db.Query(`
+		SELECT alert, receiver, resolved, timestamp, failover_to
+		FROM notify_info
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*types.NotifyInfo
+
+	for rows.Next() {
+		var (
+			alertFP    int64
+			failoverTo sql.NullString
+			ni         types.NotifyInfo
+		)
+		if err := rows.Scan(
+			&alertFP,
+			&ni.Receiver,
+			&ni.Resolved,
+			&ni.Timestamp,
+			&failoverTo,
+		); err != nil {
+			return nil, err
+		}
+		ni.Alert = model.Fingerprint(alertFP)
+		ni.FailoverTo = failoverTo.String
+
+		result = append(result, &ni)
+	}
+	return result, rows.Err()
+}
+
 // Get implements the Notifies interface.
 func (n *Notifies) Get(dest string, fps ...model.Fingerprint) ([]*types.NotifyInfo, error) {
 	dbmtx.Lock()
@@ -371,12 +473,15 @@ func (n *Notifies) Get(dest string, fps ...model.Fingerprint) ([]*types.NotifyIn
 
 	for _, fp := range fps {
 		row := n.db.QueryRow(`
-			SELECT alert, receiver, resolved, timestamp
+			SELECT alert, receiver, resolved, timestamp, failover_to
 			FROM notify_info
 			WHERE receiver == $1 AND alert == $2
 		`, dest, int64(fp))
 
-		var alertFP int64
+		var (
+			alertFP    int64
+			failoverTo sql.NullString
+		)
 
 		var ni types.NotifyInfo
 		err := row.Scan(
@@ -384,6 +489,7 @@ func (n *Notifies) Get(dest string, fps ...model.Fingerprint) ([]*types.NotifyIn
 			&ni.Receiver,
 			&ni.Resolved,
 			&ni.Timestamp,
+			&failoverTo,
 		)
 		if err == sql.ErrNoRows {
 			result = append(result, nil)
@@ -394,6 +500,7 @@ func (n *Notifies) Get(dest string, fps ...model.Fingerprint) ([]*types.NotifyIn
 		}
 
 		ni.Alert = model.Fingerprint(alertFP)
+		ni.FailoverTo = failoverTo.String
 
 		result = append(result, &ni)
 	}
@@ -412,8 +519,8 @@ func (n *Notifies) Set(ns ...*types.NotifyInfo) error {
 	}
 
 	insert, err := tx.Prepare(`
-		INSERT INTO notify_info(alert, receiver, resolved, timestamp)
-		VALUES ($1, $2, $3, $4);
+		INSERT INTO notify_info(alert, receiver, resolved, timestamp, failover_to)
+		VALUES ($1, $2, $3, $4, $5);
 	`)
 	if err != nil {
 		tx.Rollback()
@@ -441,6 +548,7 @@ func (n *Notifies) Set(ns ...*types.NotifyInfo) error {
 			ni.Receiver,
 			ni.Resolved,
 			ni.Timestamp,
+			ni.FailoverTo,
 		); err != nil {
 			tx.Rollback()
 			return fmt.Errorf("inserting new notify failed: %s", err)
@@ -484,6 +592,14 @@ func NewSilences(db *sql.DB, mk types.Marker) (*Silences, error) {
 		tx.Rollback()
 		return nil, err
 	}
+	if err := addColumn(tx, "silences", "exclude_matchers", "blob"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := addColumn(tx, "silences", "recurrence", "blob"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 	tx.Commit()
 
 	return &Silences{db: db, marker: mk}, nil
@@ -515,7 +631,7 @@ func (s *Silences) All() ([]*types.Silence, error) {
 	defer dbmtx.Unlock()
 
 	rows, err := s.db.Query(`
-		SELECT id, matchers, starts_at, ends_at, created_at, created_by, comment
+		SELECT id, matchers, exclude_matchers, recurrence, starts_at, ends_at, created_at, created_by, comment
 		FROM silences 
 		ORDER BY starts_at DESC
 	`)
@@ -528,13 +644,17 @@ func (s *Silences) All() ([]*types.Silence, error) {
 
 	for rows.Next() {
 		var (
-			sil      model.Silence
-			matchers []byte
+			sil        model.Silence
+			matchers   []byte
+			excludes   []byte
+			recurrence []byte
 		)
 
 		if err := rows.Scan(
 			&sil.ID,
 			&matchers,
+			&excludes,
+			&recurrence,
 			&sil.StartsAt,
 			&sil.EndsAt,
 			&sil.CreatedAt,
@@ -547,8 +667,18 @@ func (s *Silences) All() ([]*types.Silence, error) {
 		if err := json.Unmarshal(matchers, &sil.Matchers); err != nil {
 			return nil, err
 		}
+		ems, err := unmarshalMatchers(excludes)
+		if err != nil {
+			return nil, err
+		}
+		r, err := unmarshalRecurrence(recurrence)
+		if err != nil {
+			return nil, err
+		}
 
-		silences = append(silences, types.NewSilence(&sil))
+		ts := types.NewSilence(&sil, ems...)
+		ts.Recurrence = r
+		silences = append(silences, ts)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -566,6 +696,14 @@ func (s *Silences) Set(sil *types.Silence) (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
+	emb, err := json.Marshal(sil.ExcludeMatchers)
+	if err != nil {
+		return 0, err
+	}
+	rb, err := json.Marshal(sil.Recurrence)
+	if err != nil {
+		return 0, err
+	}
 
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -573,10 +711,12 @@ func (s *Silences) Set(sil *types.Silence) (uint64, error) {
 	}
 
 	res, err := tx.Exec(`
-		INSERT INTO silences(matchers, starts_at, ends_at, created_at, created_by, comment)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO silences(matchers, exclude_matchers, recurrence, starts_at, ends_at, created_at, created_by, comment)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`,
 		mb,
+		emb,
+		rb,
 		sil.StartsAt,
 		sil.EndsAt,
 		sil.CreatedAt,
@@ -624,18 +764,22 @@ func (s *Silences) Get(sid uint64) (*types.Silence, error) {
 	defer dbmtx.Unlock()
 
 	row := s.db.QueryRow(`
-		SELECT id, matchers, starts_at, ends_at, created_at, created_by, comment
+		SELECT id, matchers, exclude_matchers, recurrence, starts_at, ends_at, created_at, created_by, comment
 		FROM silences
 		WHERE id == $1
 	`, sid)
 
 	var (
-		sil      model.Silence
-		matchers []byte
+		sil        model.Silence
+		matchers   []byte
+		excludes   []byte
+		recurrence []byte
 	)
 	err := row.Scan(
 		&sil.ID,
 		&matchers,
+		&excludes,
+		&recurrence,
 		&sil.StartsAt,
 		&sil.EndsAt,
 		&sil.CreatedAt,
@@ -651,6 +795,500 @@ func (s *Silences) Get(sid uint64) (*types.Silence, error) {
 	if err := json.Unmarshal(matchers, &sil.Matchers); err != nil {
 		return nil, err
 	}
+	ems, err := unmarshalMatchers(excludes)
+	if err != nil {
+		return nil, err
+	}
+	r, err := unmarshalRecurrence(recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := types.NewSilence(&sil, ems...)
+	ts.Recurrence = r
+	return ts, nil
+}
+
+// unmarshalMatchers decodes a JSON list of matchers. Silences stored by
+// previous versions have no exclusion matchers and yield an empty list.
+func unmarshalMatchers(b []byte) ([]*model.Matcher, error) {
+	var ms []*model.Matcher
+	if len(b) == 0 {
+		return ms, nil
+	}
+	err := json.Unmarshal(b, &ms)
+	return ms, err
+}
+
+// unmarshalRecurrence decodes the JSON recurrence of a silence. Silences
+// stored by previous versions have no recurrence and yield nil.
+func unmarshalRecurrence(b []byte) (*types.Recurrence, error) {
+	var r *types.Recurrence
+	if len(b) == 0 {
+		return r, nil
+	}
+	err := json.Unmarshal(b, &r)
+	return r, err
+}
+
+const createEventsTable = `
+CREATE TABLE IF NOT EXISTS events (
+	id           integer PRIMARY KEY AUTOINCREMENT,
+	title        text,
+	kind         text,
+	level        text,
+	is_safe      text,
+	creator      text,
+	alerts       blob,
+	labels       blob,
+	annotations  blob,
+	created_at   timestamp,
+	updated_at   timestamp,
+	closed_at    timestamp,
+	version      integer,
+	parent_id    integer,
+	status       text,
+	status_times blob,
+	severity     text,
+	assignee     text
+);
+CREATE TABLE IF NOT EXISTS events_tokens (
+	token    text,
+	event_id integer
+);
+CREATE INDEX IF NOT EXISTS events_created      ON events (created_at);
+CREATE INDEX IF NOT EXISTS events_tokens_token ON events_tokens (token);
+CREATE INDEX IF NOT EXISTS events_tokens_event ON events_tokens (event_id);
+`
+
+const selectEvents = `
+	SELECT id, title, kind, level, is_safe, creator, alerts, labels, annotations, created_at, updated_at, closed_at, version, parent_id, status, status_times, severity, assignee
+	FROM events
+`
+
+// Events implements the Events provider based on a SQL DB.
+type Events struct {
+	db *sql.DB
+}
+
+// NewEvents returns a new Events based on the provided SQL DB.
+func NewEvents(db *sql.DB) (*Events, error) {
+	dbmtx.Lock()
+	defer dbmtx.Unlock()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(createEventsTable); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := addColumn(tx, "events", "closed_at", "timestamp"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := addColumn(tx, "events", "parent_id", "integer"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := addColumn(tx, "events", "status", "text"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := addColumn(tx, "events", "status_times", "blob"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := addColumn(tx, "events", "severity", "text"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := addColumn(tx, "events", "assignee", "text"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.Exec(`CREATE INDEX IF NOT EXISTS events_parent ON events (parent_id)`); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	tx.Commit()
+
+	return &Events{db: db}, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEvent(row scanner) (*types.Event, error) {
+	var (
+		e                           types.Event
+		alerts, labels, annotations []byte
+		// Events stored by previous versions have no closing time,
+		// parent, status, severity, and assignee.
+		closedAt    *time.Time
+		parentID    *uint64
+		status      *string
+		statusTimes []byte
+		severity    *string
+		assignee    *string
+	)
+	if err := row.Scan(
+		&e.ID,
+		&e.Title,
+		&e.Kind,
+		&e.Level,
+		&e.IsSafe,
+		&e.Creator,
+		&alerts,
+		&labels,
+		&annotations,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+		&closedAt,
+		&e.Version,
+		&parentID,
+		&status,
+		&statusTimes,
+		&severity,
+		&assignee,
+	); err != nil {
+		return nil, err
+	}
+	if closedAt != nil {
+		e.ClosedAt = *closedAt
+	}
+	if parentID != nil {
+		e.ParentID = *parentID
+	}
+	if status != nil {
+		e.Status = types.EventStatus(*status)
+	}
+	if severity != nil {
+		e.Severity = types.EventSeverity(*severity)
+	}
+	if assignee != nil {
+		e.Assignee = *assignee
+	}
+	if len(statusTimes) > 0 {
+		if err := json.Unmarshal(statusTimes, &e.StatusTimes); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(alerts, &e.Alerts); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(labels, &e.Labels); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(annotations, &e.Annotations); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *Events) query(q string, args ...interface{}) ([]*types.Event, error) {
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*types.Event
+
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// All implements the Events interface.
+func (s *Events) All() ([]*types.Event, error) {
+	dbmtx.Lock()
+	defer dbmtx.Unlock()
+
+	return s.query(selectEvents + `ORDER BY id`)
+}
+
+// Get implements the Events interface.
+func (s *Events) Get(id uint64) (*types.Event, error) {
+	dbmtx.Lock()
+	defer dbmtx.Unlock()
+
+	e, err := scanEvent(s.db.QueryRow(selectEvents+`WHERE id == $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, provider.ErrNotFound
+	}
+	return e, err
+}
+
+// Search implements the Events interface.
+func (s *Events) Search(query string) ([]*types.Event, error) {
+	toks := provider.Tokenize(query)
+	if len(toks) == 0 {
+		return nil, nil
+	}
+
+	dbmtx.Lock()
+	defer dbmtx.Unlock()
+
+	var (
+		params []string
+		args   []interface{}
+	)
+	for i, tok := range toks {
+		params = append(params, fmt.Sprintf("$%d", i+1))
+		args = append(args, tok)
+	}
+	args = append(args, len(toks))
+
+	return s.query(selectEvents+fmt.Sprintf(`
+		WHERE id IN (
+			SELECT event_id FROM events_tokens
+			WHERE token IN (%s)
+			GROUP BY event_id
+			HAVING COUNT(DISTINCT token) == $%d
+		)
+		ORDER BY id
+	`, strings.Join(params, ", "), len(args)), args...)
+}
+
+// Children implements the Events interface.
+func (s *Events) Children(id uint64) ([]*types.Event, error) {
+	dbmtx.Lock()
+	defer dbmtx.Unlock()
+
+	return s.query(selectEvents+`WHERE parent_id == $1 ORDER BY id`, id)
+}
+
+// ListByTimeRange implements the Events interface.
+func (s *Events) ListByTimeRange(from, to time.Time, limit int) ([]*types.Event, error) {
+	dbmtx.Lock()
+	defer dbmtx.Unlock()
+
+	var (
+		conds []string
+		args  []interface{}
+	)
+	if !from.IsZero() {
+		args = append(args, from)
+		conds = append(conds, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !to.IsZero() {
+		args = append(args, to)
+		conds = append(conds, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	q := selectEvents
+	if len(conds) > 0 {
+		q += "WHERE " + strings.Join(conds, " AND ")
+	}
+	q += " ORDER BY created_at, id"
+	if limit > 0 {
+		args = append(args, limit)
+		q += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return s.query(q, args...)
+}
+
+// Set implements the Events interface.
+func (s *Events) Set(e *types.Event) (uint64, error) {
+	dbmtx.Lock()
+	defer dbmtx.Unlock()
+
+	alerts, labels, annotations, statusTimes, err := marshalEvent(e)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO events(title, kind, level, is_safe, creator, alerts, labels, annotations, created_at, updated_at, closed_at, version, parent_id, status, status_times, severity, assignee)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	`,
+		e.Title,
+		e.Kind,
+		e.Level,
+		e.IsSafe,
+		e.Creator,
+		alerts,
+		labels,
+		annotations,
+		e.CreatedAt,
+		e.UpdatedAt,
+		e.ClosedAt,
+		e.Version,
+		e.ParentID,
+		e.Status,
+		statusTimes,
+		e.Severity,
+		e.Assignee,
+	)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	e.ID = uint64(id)
 
-	return types.NewSilence(&sil), nil
+	if err := indexEvent(tx, e); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	tx.Commit()
+
+	return e.ID, nil
+}
+
+// Update implements the Events interface.
+func (s *Events) Update(e *types.Event) error {
+	dbmtx.Lock()
+	defer dbmtx.Unlock()
+
+	alerts, labels, annotations, statusTimes, err := marshalEvent(e)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	var (
+		version   uint64
+		createdAt time.Time
+	)
+	err = tx.QueryRow(`SELECT version, created_at FROM events WHERE id == $1`, e.ID).Scan(&version, &createdAt)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return provider.ErrNotFound
+		}
+		return err
+	}
+	if version != e.Version {
+		tx.Rollback()
+		return provider.ErrConflict
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = createdAt
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE events
+		SET title = $1, kind = $2, level = $3, is_safe = $4, creator = $5, alerts = $6,
+			labels = $7, annotations = $8, created_at = $9, updated_at = $10, closed_at = $11,
+			version = $12, parent_id = $13, status = $14, status_times = $15,
+			severity = $16, assignee = $17
+		WHERE id == $18
+	`,
+		e.Title,
+		e.Kind,
+		e.Level,
+		e.IsSafe,
+		e.Creator,
+		alerts,
+		labels,
+		annotations,
+		e.CreatedAt,
+		e.UpdatedAt,
+		e.ClosedAt,
+		version+1,
+		e.ParentID,
+		e.Status,
+		statusTimes,
+		e.Severity,
+		e.Assignee,
+		e.ID,
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM events_tokens WHERE event_id == $1`, e.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := indexEvent(tx, e); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+
+	e.Version = version + 1
+
+	return nil
+}
+
+// Delete implements the Events interface.
+func (s *Events) Delete(id uint64) error {
+	dbmtx.Lock()
+	defer dbmtx.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`DELETE FROM events WHERE id == $1`, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		if err != nil {
+			return err
+		}
+		return provider.ErrNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM events_tokens WHERE event_id == $1`, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+
+	return nil
+}
+
+func marshalEvent(e *types.Event) (alerts, labels, annotations, statusTimes []byte, err error) {
+	if alerts, err = json.Marshal(e.Alerts); err != nil {
+		return
+	}
+	if labels, err = json.Marshal(e.Labels); err != nil {
+		return
+	}
+	if annotations, err = json.Marshal(e.Annotations); err != nil {
+		return
+	}
+	statusTimes, err = json.Marshal(e.StatusTimes)
+	return
+}
+
+// indexEvent adds the search tokens of the event to the index.
+func indexEvent(tx *sql.Tx, e *types.Event) error {
+	for tok := range provider.EventTokens(e) {
+		if _, err := tx.Exec(`
+			INSERT INTO events_tokens(token, event_id) VALUES ($1, $2)
+		`, tok, e.ID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
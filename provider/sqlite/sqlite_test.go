@@ -0,0 +1,190 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite_events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "events.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	events, err := NewEvents(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Now().UTC().Truncate(time.Second)
+
+	insert := []*types.Event{
+		{
+			Title:     "Database outage",
+			Alerts:    []string{"1"},
+			Labels:    model.LabelSet{"service": "db"},
+			CreatedAt: created,
+		},
+		{
+			Title:       "Network partition",
+			Annotations: model.LabelSet{"summary": "Database replicas unreachable"},
+			CreatedAt:   created,
+			ClosedAt:    created.Add(time.Hour),
+			ParentID:    1,
+		},
+	}
+	for _, e := range insert {
+		if _, err := events.Set(e); err != nil {
+			t.Fatalf("Insert failed: %s", err)
+		}
+	}
+
+	all, err := events.All()
+	if err != nil {
+		t.Fatalf("Retrieval failed: %s", err)
+	}
+	if !reflect.DeepEqual(all, insert) {
+		t.Fatalf("Expected events %v but got %v", insert, all)
+	}
+
+	search := func(q string) (ids []uint64) {
+		res, err := events.Search(q)
+		if err != nil {
+			t.Fatalf("Search failed: %s", err)
+		}
+		for _, e := range res {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+	if ids := search("database"); !reflect.DeepEqual(ids, []uint64{1, 2}) {
+		t.Errorf("Unexpected search result %v", ids)
+	}
+	if ids := search("DATABASE replicas"); !reflect.DeepEqual(ids, []uint64{2}) {
+		t.Errorf("Unexpected search result %v", ids)
+	}
+
+	children, err := events.Children(1)
+	if err != nil {
+		t.Fatalf("Retrieving children failed: %s", err)
+	}
+	if len(children) != 1 || children[0].ID != 2 {
+		t.Fatalf("Expected event 2 as only child but got %v", children)
+	}
+
+	upd := &types.Event{ID: 1, Title: "Disk full"}
+	if err := events.Update(upd); err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	if err := events.Update(&types.Event{ID: 1}); err != provider.ErrConflict {
+		t.Fatalf("Expected conflict on stale update but got %v", err)
+	}
+
+	e, err := events.Get(1)
+	if err != nil {
+		t.Fatalf("Retrieval failed: %s", err)
+	}
+	if e.Title != "Disk full" || e.Version != 1 || !e.CreatedAt.Equal(created) {
+		t.Fatalf("Unexpected event after update: %+v", e)
+	}
+	if ids := search("database"); !reflect.DeepEqual(ids, []uint64{2}) {
+		t.Errorf("Unexpected search result after update %v", ids)
+	}
+
+	if err := events.Delete(2); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if _, err := events.Get(2); err != provider.ErrNotFound {
+		t.Fatalf("Expected deleted event not to be found but got %v", err)
+	}
+	if err := events.Delete(2); err != provider.ErrNotFound {
+		t.Fatalf("Expected not found on repeated delete but got %v", err)
+	}
+	if ids := search("database"); ids != nil {
+		t.Errorf("Unexpected search result after delete %v", ids)
+	}
+}
+
+func TestEventsListByTimeRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite_events_time_range")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "events.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	events, err := NewEvents(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	// Events are inserted out of creation order.
+	for _, offset := range []time.Duration{2, 0, 3, 1} {
+		e := &types.Event{Title: fmt.Sprintf("Outage %d", offset), CreatedAt: now.Add(offset * time.Hour)}
+		if _, err := events.Set(e); err != nil {
+			t.Fatalf("Insert failed: %s", err)
+		}
+	}
+
+	cases := []struct {
+		from, to time.Time
+		limit    int
+		expected []string
+	}{
+		{expected: []string{"Outage 0", "Outage 1", "Outage 2", "Outage 3"}},
+		{from: now.Add(time.Hour), expected: []string{"Outage 1", "Outage 2", "Outage 3"}},
+		{from: now.Add(time.Hour), to: now.Add(3 * time.Hour), expected: []string{"Outage 1", "Outage 2"}},
+		{to: now.Add(3 * time.Hour), limit: 1, expected: []string{"Outage 0"}},
+		{from: now.Add(4 * time.Hour), expected: []string{}},
+	}
+	for i, c := range cases {
+		res, err := events.ListByTimeRange(c.from, c.to, c.limit)
+		if err != nil {
+			t.Fatalf("Listing events failed: %s", err)
+		}
+		titles := []string{}
+		for _, e := range res {
+			titles = append(titles, e.Title)
+		}
+		if !reflect.DeepEqual(titles, c.expected) {
+			t.Errorf("%d. expected events %q but got %q", i, c.expected, titles)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// silenceWindow identifies a window of a recurring silence.
+type silenceWindow struct {
+	silence uint64
+	start   int64
+}
+
+// A SilenceScheduler creates the windows of recurring silences as
+// silences shortly before they start.
+type SilenceScheduler struct {
+	silences provider.Silences
+	interval time.Duration
+
+	// End times of windows that were created or found to exist. Windows
+	// are only created once so that they can be deleted like any silence.
+	done  map[silenceWindow]time.Time
+	stopc chan struct{}
+}
+
+// NewSilenceScheduler returns a new SilenceScheduler checking for windows
+// to create at the given interval.
+func NewSilenceScheduler(s provider.Silences, interval time.Duration) *SilenceScheduler {
+	return &SilenceScheduler{
+		silences: s,
+		interval: interval,
+		done:     map[silenceWindow]time.Time{},
+		stopc:    make(chan struct{}),
+	}
+}
+
+// Run the SilenceScheduler's background processing.
+func (s *SilenceScheduler) Run() {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+
+	s.schedule(time.Now())
+
+	for {
+		select {
+		case <-s.stopc:
+			return
+		case <-t.C:
+			s.schedule(time.Now())
+		}
+	}
+}
+
+// Stop the SilenceScheduler's background processing.
+func (s *SilenceScheduler) Stop() {
+	close(s.stopc)
+}
+
+// schedule creates the windows of recurring silences that are in effect at
+// the given time or start before the next check.
+func (s *SilenceScheduler) schedule(now time.Time) {
+	sils, err := s.silences.All()
+	if err != nil {
+		log.Errorf("Retrieving silences failed: %s", err)
+		return
+	}
+	for w, end := range s.done {
+		if end.Before(now) {
+			delete(s.done, w)
+		}
+	}
+
+	for _, sil := range sils {
+		if sil.Recurrence == nil {
+			continue
+		}
+		for _, t := range []time.Time{now, now.Add(s.interval)} {
+			start, end, ok := sil.Window(t)
+			if !ok {
+				continue
+			}
+			w := silenceWindow{silence: sil.ID, start: start.UnixNano()}
+			if _, ok := s.done[w]; ok {
+				continue
+			}
+			// In cluster mode, the window may have been created by another
+			// peer already.
+			if !hasWindow(sils, sil, start, end) {
+				ws := types.NewSilence(&model.Silence{
+					Matchers:  sil.Silence.Matchers,
+					StartsAt:  start,
+					EndsAt:    end,
+					CreatedAt: now,
+					CreatedBy: sil.CreatedBy,
+					Comment:   sil.Comment,
+				}, sil.ExcludeMatchers...)
+
+				if _, err := s.silences.Set(ws); err != nil {
+					log.With("silence", sil.ID).Errorf("Creating window of recurring silence failed: %s", err)
+					continue
+				}
+			}
+			s.done[w] = end
+		}
+	}
+}
+
+// hasWindow returns true iff one of the silences is the window of the
+// recurring silence with the given start and end time.
+func hasWindow(sils []*types.Silence, rec *types.Silence, start, end time.Time) bool {
+	for _, sil := range sils {
+		if sil.Recurrence != nil || !sil.StartsAt.Equal(start) || !sil.EndsAt.Equal(end) {
+			continue
+		}
+		if reflect.DeepEqual(sil.Silence.Matchers, rec.Silence.Matchers) &&
+			reflect.DeepEqual(sil.ExcludeMatchers, rec.ExcludeMatchers) {
+			return true
+		}
+	}
+	return false
+}
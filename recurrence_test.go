@@ -0,0 +1,106 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestSilenceScheduler(t *testing.T) {
+	var (
+		silences = provider.NewMemSilences()
+		now      = time.Date(2024, 1, 6, 3, 0, 0, 0, time.UTC)
+	)
+	rec := types.NewSilence(&model.Silence{
+		Matchers:  []*model.Matcher{{Name: "job", Value: "backup"}},
+		StartsAt:  time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC),
+		EndsAt:    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAt: now,
+		CreatedBy: "jane",
+		Comment:   "Weekly maintenance",
+	})
+	rec.Recurrence = &types.Recurrence{Rule: "FREQ=WEEKLY;BYDAY=SA", Duration: model.Duration(4 * time.Hour)}
+
+	rid, err := silences.Set(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Recurring silences do not mute alerts themselves.
+	if silences.Mutes(model.LabelSet{"job": "backup"}) {
+		t.Fatalf("expected recurring silence not to mute alerts")
+	}
+
+	windows := func() []*types.Silence {
+		sils, err := silences.All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res []*types.Silence
+		for _, sil := range sils {
+			if sil.ID != rid {
+				res = append(res, sil)
+			}
+		}
+		return res
+	}
+
+	s := NewSilenceScheduler(silences, time.Minute)
+	s.schedule(now)
+	s.schedule(now.Add(time.Minute))
+
+	ws := windows()
+	if len(ws) != 1 {
+		t.Fatalf("expected 1 window but got %d", len(ws))
+	}
+	w := ws[0]
+	if !w.StartsAt.Equal(rec.StartsAt) || !w.EndsAt.Equal(rec.StartsAt.Add(4*time.Hour)) {
+		t.Fatalf("expected window from %s to %s but got %s to %s", rec.StartsAt, rec.StartsAt.Add(4*time.Hour), w.StartsAt, w.EndsAt)
+	}
+	if w.Recurrence != nil || w.CreatedBy != "jane" || len(w.Silence.Matchers) != 1 {
+		t.Fatalf("expected window to copy the recurring silence but got %+v", w)
+	}
+
+	// Another scheduler, e.g. on a cluster peer, finds the existing window.
+	NewSilenceScheduler(silences, time.Minute).schedule(now)
+	if n := len(windows()); n != 1 {
+		t.Fatalf("expected window not to be created again but got %d windows", n)
+	}
+
+	// Deleted windows are not created again.
+	if err := silences.Del(w.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.schedule(now.Add(2 * time.Minute))
+	if n := len(windows()); n != 0 {
+		t.Fatalf("expected deleted window not to be created again but got %d windows", n)
+	}
+
+	// The next window is created shortly before it starts.
+	next := time.Date(2024, 1, 13, 2, 0, 0, 0, time.UTC)
+	s.schedule(next.Add(-2 * time.Minute))
+	if n := len(windows()); n != 0 {
+		t.Fatalf("expected no window before the next one is due but got %d", n)
+	}
+	s.schedule(next.Add(-30 * time.Second))
+	if ws := windows(); len(ws) != 1 || !ws[0].StartsAt.Equal(next) {
+		t.Fatalf("expected window starting at %s but got %v", next, ws)
+	}
+}
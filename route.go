@@ -16,6 +16,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand"
+	"sort"
 	"time"
 
 	"github.com/prometheus/common/model"
@@ -24,6 +26,9 @@ import (
 	"github.com/prometheus/alertmanager/types"
 )
 
+// groupByAllToken is the group_by value grouping alerts by all labels.
+const groupByAllToken = "..."
+
 // DefaultRouteOpts are the defaulting routing options which apply
 // to the root route of a routing tree.
 var DefaultRouteOpts = RouteOpts{
@@ -69,6 +74,7 @@ func NewRoute(cr *config.Route, parent *Route) *Route {
 		for _, ln := range cr.GroupBy {
 			opts.GroupBy[ln] = struct{}{}
 		}
+		opts.GroupByAll = cr.GroupByAll
 	}
 	if cr.GroupWait != nil {
 		opts.GroupWait = time.Duration(*cr.GroupWait)
@@ -79,6 +85,24 @@ func NewRoute(cr *config.Route, parent *Route) *Route {
 	if cr.RepeatInterval != nil {
 		opts.RepeatInterval = time.Duration(*cr.RepeatInterval)
 	}
+	if cr.GroupJitter != nil {
+		opts.GroupJitter = time.Duration(*cr.GroupJitter)
+	}
+	if cr.SendResolvedAfter != nil {
+		opts.SendResolvedAfter = time.Duration(*cr.SendResolvedAfter)
+	}
+	if cr.MuteTimeIntervals != nil {
+		opts.MuteTimeIntervals = cr.MuteTimeIntervals
+	}
+	if cr.Escalation != nil {
+		opts.Escalation = cr.Escalation
+	}
+	if cr.EmailHTML != "" {
+		opts.EmailHTML = cr.EmailHTML
+	}
+	if cr.Priority != nil {
+		opts.Priority = *cr.Priority
+	}
 
 	// Build matchers.
 	var matchers types.Matchers
@@ -89,6 +113,12 @@ func NewRoute(cr *config.Route, parent *Route) *Route {
 	for ln, lv := range cr.MatchRE {
 		matchers = append(matchers, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
 	}
+	for ln, lvs := range cr.MatchIn {
+		matchers = append(matchers, types.NewSetMatcher(model.LabelName(ln), lvs))
+	}
+	for ln, lv := range cr.MatchNotRE {
+		matchers = append(matchers, types.NewNegativeRegexMatcher(model.LabelName(ln), lv.Regexp))
+	}
 
 	route := &Route{
 		parent:    parent,
@@ -139,6 +169,40 @@ func (r *Route) Match(lset model.LabelSet) []*Route {
 	return all
 }
 
+// RouteMatch describes a route an alert is routed to.
+type RouteMatch struct {
+	// The path of matchers leading to the route.
+	Route    string         `json:"route"`
+	Matchers types.Matchers `json:"matchers"`
+	// The labels the alert is grouped by on the route.
+	GroupLabels model.LabelSet `json:"groupLabels"`
+	RouteOpts   *RouteOpts     `json:"routeOpts"`
+}
+
+// MatchDetails returns the routes an alert with the given labels is
+// routed to along with their resolved routing options.
+func (r *Route) MatchDetails(lset model.LabelSet) []*RouteMatch {
+	var res []*RouteMatch
+
+	for _, mr := range r.Match(lset) {
+		res = append(res, &RouteMatch{
+			Route:       mr.Key(),
+			Matchers:    mr.SquashMatchers(),
+			GroupLabels: mr.RouteOpts.GroupLabels(lset),
+			RouteOpts:   &mr.RouteOpts,
+		})
+	}
+	return res
+}
+
+// Walk traverses the route tree in depth-first order.
+func (r *Route) Walk(visit func(*Route)) {
+	visit(r)
+	for _, cr := range r.Routes {
+		cr.Walk(visit)
+	}
+}
+
 // SquashMatchers returns the total set of matchers on the path of the tree
 // that have to apply to reach the route.
 func (r *Route) SquashMatchers() types.Matchers {
@@ -155,6 +219,15 @@ func (r *Route) SquashMatchers() types.Matchers {
 	return res
 }
 
+// Key returns a human-readable representation of the path of matchers
+// leading from the root of the routing tree to the route.
+func (r *Route) Key() string {
+	if r.parent == nil {
+		return r.Matchers.String()
+	}
+	return r.parent.Key() + "/" + r.Matchers.String()
+}
+
 // Fingerprint returns a hash of the Route based on its grouping labels,
 // routing options and the total set of matchers necessary to reach this route.
 func (r *Route) Fingerprint() model.Fingerprint {
@@ -163,6 +236,9 @@ func (r *Route) Fingerprint() model.Fingerprint {
 	for ln := range r.RouteOpts.GroupBy {
 		lset[ln] = ""
 	}
+	if r.RouteOpts.GroupByAll {
+		lset[groupByAllToken] = ""
+	}
 
 	return r.SquashMatchers().Fingerprint() ^ lset.Fingerprint()
 }
@@ -175,38 +251,165 @@ type RouteOpts struct {
 
 	// What labels to group alerts by for notifications.
 	GroupBy map[model.LabelName]struct{}
+	// If true, alerts are grouped by all of their labels, so that each
+	// alert has its own aggregation group, and GroupBy is ignored.
+	GroupByAll bool
 
 	// How long to wait to group matching alerts before sending
 	// a notificaiton
 	GroupWait      time.Duration
 	GroupInterval  time.Duration
 	RepeatInterval time.Duration
+
+	// Maximum random delay added to the group wait and group interval
+	// to spread the flushes of groups over time.
+	GroupJitter time.Duration
+
+	// If non-zero, resolved alerts are only notified about if they were
+	// notified as firing before and stayed resolved for this long.
+	SendResolvedAfter time.Duration
+
+	// Time intervals during which no notifications are sent. Alerts
+	// are still aggregated and notified about afterwards.
+	MuteTimeIntervals []*config.TimeInterval
+
+	// Receivers notified instead of the route's receiver if a group
+	// keeps firing, ordered by escalation level.
+	Escalation []*config.EscalationStep
+
+	// Template of the HTML body of email notifications. If empty, the
+	// one of the email config is used.
+	EmailHTML string
+
+	// Notifications of groups with a higher priority are sent first when
+	// the notify workers are saturated.
+	Priority int
 }
 
-func (ro *RouteOpts) String() string {
-	var labels []model.LabelName
+// Muted returns true iff the given time lies within one of the route's
+// mute time intervals.
+func (ro *RouteOpts) Muted(t time.Time) bool {
+	for _, ti := range ro.MuteTimeIntervals {
+		if ti.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// muteHorizon is how far ahead NextMute looks for mute time intervals.
+// It covers a full week regardless of daylight saving time changes.
+const muteHorizon = 8 * 24 * time.Hour
+
+// NextMute returns the start and end of the first period at or after t
+// during which the route is muted. If the route is muted at t, the start
+// lies at or before t. The end is the zero time if the period lasts
+// beyond the horizon. If no period starts within the horizon, false is
+// returned.
+func (ro *RouteOpts) NextMute(t time.Time) (start, end time.Time, ok bool) {
+	if len(ro.MuteTimeIntervals) == 0 {
+		return start, end, false
+	}
+	// Intervals are specified with a granularity of minutes.
+	t = t.Truncate(time.Minute)
+	limit := t.Add(muteHorizon)
+
+	for start = t; !ro.Muted(start); start = start.Add(time.Minute) {
+		if !start.Before(limit) {
+			return time.Time{}, end, false
+		}
+	}
+	for end = start; ro.Muted(end); end = end.Add(time.Minute) {
+		if !end.Before(limit) {
+			return start, time.Time{}, true
+		}
+	}
+	return start, end, true
+}
+
+// Escalated returns the receiver to notify and the escalation level for
+// a group that has been firing without acknowledgement for the given
+// duration. Level zero is the route's own receiver.
+func (ro *RouteOpts) Escalated(firing time.Duration) (string, int) {
+	receiver, level := ro.Receiver, 0
+
+	for i, es := range ro.Escalation {
+		if firing < time.Duration(es.AfterRepeatIntervals)*ro.RepeatInterval {
+			break
+		}
+		receiver, level = es.Receiver, i+1
+	}
+	return receiver, level
+}
+
+// Jitter returns a random duration in [0, GroupJitter).
+func (ro *RouteOpts) Jitter() time.Duration {
+	if ro.GroupJitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ro.GroupJitter)))
+}
+
+// GroupLabels returns the labels of the given label set that determine the
+// aggregation group of an alert with it.
+func (ro *RouteOpts) GroupLabels(lset model.LabelSet) model.LabelSet {
+	if ro.GroupByAll {
+		return lset.Clone()
+	}
+	group := make(model.LabelSet, len(ro.GroupBy))
+	for ln := range ro.GroupBy {
+		if lv, ok := lset[ln]; ok {
+			group[ln] = lv
+		}
+	}
+	return group
+}
+
+// groupByLabels returns the sorted labels alerts are grouped by, or only
+// the special token if they are grouped by all labels.
+func (ro *RouteOpts) groupByLabels() model.LabelNames {
+	if ro.GroupByAll {
+		return model.LabelNames{groupByAllToken}
+	}
+	var labels model.LabelNames
 	for ln := range ro.GroupBy {
 		labels = append(labels, ln)
 	}
+	sort.Sort(labels)
+	return labels
+}
+
+func (ro *RouteOpts) String() string {
+	labels := ro.groupByLabels()
 	return fmt.Sprintf("<RouteOpts send_to:%q group_by:%q timers:%q|%q>", ro.Receiver, labels, ro.GroupWait, ro.GroupInterval)
 }
 
 // MarshalJSON returns a JSON representation of the routing options.
 func (ro *RouteOpts) MarshalJSON() ([]byte, error) {
 	v := struct {
-		Receiver       string           `json:"receiver"`
-		GroupBy        model.LabelNames `json:"groupBy"`
-		GroupWait      time.Duration    `json:"groupWait"`
-		GroupInterval  time.Duration    `json:"groupInterval"`
-		RepeatInterval time.Duration    `json:"repeatInterval"`
+		Receiver          string                   `json:"receiver"`
+		GroupBy           model.LabelNames         `json:"groupBy"`
+		GroupWait         time.Duration            `json:"groupWait"`
+		GroupInterval     time.Duration            `json:"groupInterval"`
+		RepeatInterval    time.Duration            `json:"repeatInterval"`
+		GroupJitter       time.Duration            `json:"groupJitter,omitempty"`
+		SendResolvedAfter time.Duration            `json:"sendResolvedAfter,omitempty"`
+		MuteTimeIntervals []*config.TimeInterval   `json:"muteTimeIntervals,omitempty"`
+		Escalation        []*config.EscalationStep `json:"escalation,omitempty"`
+		EmailHTML         string                   `json:"emailHTML,omitempty"`
+		Priority          int                      `json:"priority,omitempty"`
 	}{
-		Receiver:       ro.Receiver,
-		GroupWait:      ro.GroupWait,
-		GroupInterval:  ro.GroupInterval,
-		RepeatInterval: ro.RepeatInterval,
-	}
-	for ln := range ro.GroupBy {
-		v.GroupBy = append(v.GroupBy, ln)
+		Receiver:          ro.Receiver,
+		GroupBy:           ro.groupByLabels(),
+		GroupWait:         ro.GroupWait,
+		GroupInterval:     ro.GroupInterval,
+		RepeatInterval:    ro.RepeatInterval,
+		GroupJitter:       ro.GroupJitter,
+		SendResolvedAfter: ro.SendResolvedAfter,
+		MuteTimeIntervals: ro.MuteTimeIntervals,
+		Escalation:        ro.Escalation,
+		EmailHTML:         ro.EmailHTML,
+		Priority:          ro.Priority,
 	}
 
 	return json.Marshal(&v)
@@ -14,7 +14,9 @@
 package main
 
 import (
+	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -180,3 +182,411 @@ routes:
 		}
 	}
 }
+
+func TestRouteKey(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+
+routes:
+- match:
+    owner: 'team-A'
+  match_re:
+    env: 'produ.*'
+  receiver: 'notify-A'
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	exp := `{}/{env=~"produ.*", owner="team-A"}`
+	if key := tree.Routes[0].Key(); key != exp {
+		t.Errorf("expected route key %q but got %q", exp, key)
+	}
+}
+
+func TestRouteMatchInAndNotRE(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+
+routes:
+- match_in:
+    region: ['us-west', 'us-east']
+  match_not_re:
+    env: 'staging|dev.*'
+  receiver: 'notify-us'
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	exp := `{}/{env!~"staging|dev.*", region=~"us-east|us-west"}`
+	if key := tree.Routes[0].Key(); key != exp {
+		t.Errorf("expected route key %q but got %q", exp, key)
+	}
+
+	tests := []struct {
+		input    model.LabelSet
+		receiver string
+	}{
+		{input: model.LabelSet{"region": "us-east", "env": "production"}, receiver: "notify-us"},
+		{input: model.LabelSet{"region": "us-west"}, receiver: "notify-us"},
+		{input: model.LabelSet{"region": "us-west", "env": "development"}, receiver: "notify-def"},
+		{input: model.LabelSet{"region": "us-east-1"}, receiver: "notify-def"},
+		{input: model.LabelSet{"env": "production"}, receiver: "notify-def"},
+	}
+	for _, test := range tests {
+		if r := tree.Match(test.input)[0].RouteOpts.Receiver; r != test.receiver {
+			t.Errorf("expected %v to be routed to %q but got %q", test.input, test.receiver, r)
+		}
+	}
+}
+
+func TestRouteMatchDetails(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+group_by: ['alertname']
+
+routes:
+- match:
+    owner: 'team-A'
+  receiver: 'notify-A'
+  group_by: ['alertname', 'cluster']
+  group_wait: 1m
+  continue: true
+- match_re:
+    owner: 'team-.*'
+  receiver: 'notify-teams'
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	res := tree.MatchDetails(model.LabelSet{"alertname": "HighLatency", "owner": "team-A"})
+	if len(res) != 2 {
+		t.Fatalf("expected two matching routes but got %d", len(res))
+	}
+	if exp := `{}/{owner="team-A"}`; res[0].Route != exp {
+		t.Errorf("expected route %q but got %q", exp, res[0].Route)
+	}
+	if res[0].RouteOpts.Receiver != "notify-A" || res[0].RouteOpts.GroupWait != time.Minute {
+		t.Errorf("unexpected routing options %s", res[0].RouteOpts)
+	}
+	// Missing grouping labels are left out.
+	if exp := (model.LabelSet{"alertname": "HighLatency"}); !reflect.DeepEqual(res[0].GroupLabels, exp) {
+		t.Errorf("expected group labels %v but got %v", exp, res[0].GroupLabels)
+	}
+	if exp := `{owner=~"team-.*"}`; res[1].Matchers.String() != exp {
+		t.Errorf("expected matchers %q but got %q", exp, res[1].Matchers.String())
+	}
+	if res[1].RouteOpts.Receiver != "notify-teams" || res[1].RouteOpts.GroupWait != DefaultRouteOpts.GroupWait {
+		t.Errorf("unexpected routing options %s", res[1].RouteOpts)
+	}
+
+	res = tree.MatchDetails(model.LabelSet{"alertname": "HighLatency"})
+	if len(res) != 1 || res[0].RouteOpts.Receiver != "notify-def" {
+		t.Errorf("expected alert to be routed to the root route but got %v", res)
+	}
+}
+
+func TestRouteGroupByAll(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+group_by: ['alertname']
+
+routes:
+- match:
+    owner: 'team-A'
+  receiver: 'notify-A'
+  group_by: ['...']
+  routes:
+  - match:
+      env: 'production'
+    receiver: 'notify-prod'
+  - match:
+      env: 'testing'
+    receiver: 'notify-testing'
+    group_by: ['job']
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	lset := model.LabelSet{"alertname": "HighLatency", "owner": "team-A", "env": "production", "job": "api"}
+	res := tree.MatchDetails(lset)
+	if len(res) != 1 || res[0].RouteOpts.Receiver != "notify-prod" {
+		t.Fatalf("expected alert to be routed to notify-prod but got %v", res)
+	}
+	// Grouping by all labels is inherited.
+	if !reflect.DeepEqual(res[0].GroupLabels, lset) {
+		t.Errorf("expected group labels %v but got %v", lset, res[0].GroupLabels)
+	}
+	lset["env"] = "testing"
+	if res = tree.MatchDetails(lset); res[0].RouteOpts.GroupByAll {
+		t.Errorf("expected group_by to replace grouping by all labels")
+	}
+	if exp := (model.LabelSet{"job": "api"}); !reflect.DeepEqual(res[0].GroupLabels, exp) {
+		t.Errorf("expected group labels %v but got %v", exp, res[0].GroupLabels)
+	}
+
+	if tree.Routes[0].Fingerprint() == NewRoute(&config.Route{Match: ctree.Routes[0].Match}, tree).Fingerprint() {
+		t.Errorf("expected grouping by all labels to change the route fingerprint")
+	}
+	b, err := json.Marshal(&tree.Routes[0].RouteOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"groupBy":["..."]`) {
+		t.Errorf("expected group_by to be rendered as [\"...\"] but got %s", b)
+	}
+
+	for _, in := range []string{
+		"receiver: 'notify-def'\ngroup_by: ['...', 'alertname']",
+		"receiver: 'notify-def'\ngroup_by: ['alert-name']",
+	} {
+		if err := yaml.Unmarshal([]byte(in), &config.Route{}); err == nil {
+			t.Errorf("expected error for invalid group_by in %q", in)
+		}
+	}
+}
+
+func TestRouteGroupJitter(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+group_jitter: 10s
+
+routes:
+- match:
+    owner: 'team-A'
+  receiver: 'notify-A'
+- match:
+    owner: 'team-B'
+  receiver: 'notify-B'
+  group_jitter: 0s
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	if j := tree.Routes[0].RouteOpts.GroupJitter; j != 10*time.Second {
+		t.Fatalf("expected inherited jitter %s but got %s", 10*time.Second, j)
+	}
+	for i := 0; i < 100; i++ {
+		if j := tree.Routes[0].RouteOpts.Jitter(); j < 0 || j >= 10*time.Second {
+			t.Fatalf("expected jitter in [0s, 10s) but got %s", j)
+		}
+	}
+	if j := tree.Routes[1].RouteOpts.Jitter(); j != 0 {
+		t.Errorf("expected no jitter but got %s", j)
+	}
+}
+
+func TestRouteMuteTimeIntervals(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+mute_time_intervals:
+- weekdays: ['saturday:sunday']
+- weekdays: ['monday:friday']
+  times:
+  - start_time: '22:00'
+    end_time: '06:00'
+  location: 'UTC'
+
+routes:
+- match:
+    owner: 'team-A'
+  receiver: 'notify-A'
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	tests := []struct {
+		time  time.Time
+		muted bool
+	}{
+		// Saturday noon.
+		{time.Date(2016, 7, 2, 12, 0, 0, 0, time.UTC), true},
+		// Wednesday noon.
+		{time.Date(2016, 7, 6, 12, 0, 0, 0, time.UTC), false},
+		// Wednesday night.
+		{time.Date(2016, 7, 6, 23, 30, 0, 0, time.UTC), true},
+		// Wednesday early morning.
+		{time.Date(2016, 7, 6, 5, 59, 0, 0, time.UTC), true},
+		{time.Date(2016, 7, 6, 6, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, test := range tests {
+		// Child routes inherit the mute time intervals.
+		for _, r := range []*Route{tree, tree.Routes[0]} {
+			if muted := r.RouteOpts.Muted(test.time); muted != test.muted {
+				t.Errorf("expected muted=%v at %s but got %v", test.muted, test.time, muted)
+			}
+		}
+	}
+}
+
+func TestRouteMuteTimeIntervalsOvernight(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+mute_time_intervals:
+- weekdays: ['monday:friday']
+  times:
+  - start_time: '22:00'
+    end_time: '06:00'
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	// The part after midnight belongs to the weekday the range started on.
+	tests := []struct {
+		time  time.Time
+		muted bool
+	}{
+		// Friday night.
+		{time.Date(2016, 7, 8, 23, 0, 0, 0, time.UTC), true},
+		// Saturday early morning.
+		{time.Date(2016, 7, 9, 2, 0, 0, 0, time.UTC), true},
+		// Sunday night.
+		{time.Date(2016, 7, 10, 23, 0, 0, 0, time.UTC), false},
+		// Monday early morning.
+		{time.Date(2016, 7, 11, 2, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, test := range tests {
+		if muted := tree.RouteOpts.Muted(test.time); muted != test.muted {
+			t.Errorf("expected muted=%v at %s but got %v", test.muted, test.time, muted)
+		}
+	}
+}
+
+func TestTimeRangeEndOfDay(t *testing.T) {
+	var tr config.TimeRange
+	if err := yaml.Unmarshal([]byte("{start_time: '18:00', end_time: '24:00'}"), &tr); err != nil {
+		t.Fatal(err)
+	}
+	ti := &config.TimeInterval{Times: []config.TimeRange{tr}}
+
+	tests := []struct {
+		time     time.Time
+		contains bool
+	}{
+		{time.Date(2016, 7, 6, 17, 59, 0, 0, time.UTC), false},
+		{time.Date(2016, 7, 6, 18, 0, 0, 0, time.UTC), true},
+		{time.Date(2016, 7, 6, 23, 59, 0, 0, time.UTC), true},
+		{time.Date(2016, 7, 7, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, test := range tests {
+		if contains := ti.Contains(test.time); contains != test.contains {
+			t.Errorf("expected contains=%v at %s but got %v", test.contains, test.time, contains)
+		}
+	}
+
+	for _, in := range []string{
+		"{start_time: '24:00', end_time: '06:00'}",
+		"{start_time: '18:00', end_time: '24:01'}",
+	} {
+		var tr config.TimeRange
+		if err := yaml.Unmarshal([]byte(in), &tr); err == nil {
+			t.Errorf("expected error for time range %s but got none", in)
+		}
+	}
+}
+
+func TestRouteNextMute(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+mute_time_intervals:
+- weekdays: ['saturday:sunday']
+- weekdays: ['monday:friday']
+  times:
+  - start_time: '22:00'
+    end_time: '06:00'
+
+routes:
+- match:
+    owner: 'team-A'
+  receiver: 'notify-A'
+  mute_time_intervals:
+  - weekdays: ['monday:sunday']
+- match:
+    owner: 'team-B'
+  receiver: 'notify-B'
+  mute_time_intervals: []
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	date := func(d, h, m int) time.Time {
+		return time.Date(2016, 7, d, h, m, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		route      *Route
+		time       time.Time
+		start, end time.Time
+		ok         bool
+	}{
+		{
+			// Wednesday noon.
+			route: tree,
+			time:  date(6, 12, 0),
+			start: date(6, 22, 0),
+			end:   date(7, 6, 0),
+			ok:    true,
+		},
+		{
+			// Wednesday night.
+			route: tree,
+			time:  date(6, 23, 30),
+			start: date(6, 23, 30),
+			end:   date(7, 6, 0),
+			ok:    true,
+		},
+		{
+			// Friday noon, muted until the end of Sunday as the range
+			// starting on Sunday night does not apply.
+			route: tree,
+			time:  date(8, 12, 0),
+			start: date(8, 22, 0),
+			end:   date(11, 0, 0),
+			ok:    true,
+		},
+		{
+			// Muted beyond the horizon.
+			route: tree.Routes[0],
+			time:  date(6, 12, 0),
+			start: date(6, 12, 0),
+			ok:    true,
+		},
+		{
+			route: tree.Routes[1],
+			time:  date(6, 12, 0),
+			ok:    false,
+		},
+	}
+
+	for i, test := range tests {
+		start, end, ok := test.route.RouteOpts.NextMute(test.time)
+		if ok != test.ok || !start.Equal(test.start) || !end.Equal(test.end) {
+			t.Errorf("%d: expected %s-%s (%v) but got %s-%s (%v)", i, test.start, test.end, test.ok, start, end, ok)
+		}
+	}
+}
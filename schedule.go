@@ -0,0 +1,154 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// RouteSchedule describes when notifications of a route's aggregation
+// groups can be sent next.
+type RouteSchedule struct {
+	Route    string `json:"route"`
+	Receiver string `json:"receiver"`
+	// Muted is true if a mute time interval of the route is active.
+	Muted bool `json:"muted"`
+	// NextMute is the current or next period during which the route is
+	// muted, if any starts within the next week.
+	NextMute *TimeWindow      `json:"nextMute,omitempty"`
+	Groups   []*GroupSchedule `json:"groups"`
+}
+
+// TimeWindow is a period of time. End is nil if the period lasts beyond
+// the time it was computed for.
+type TimeWindow struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// GroupSchedule describes when an aggregation group is evaluated and can
+// notify next.
+type GroupSchedule struct {
+	Labels   model.LabelSet `json:"labels"`
+	GroupKey string         `json:"groupKey"`
+	// Receiver notified next, which differs from the route's receiver if
+	// the group is escalated.
+	Receiver  string    `json:"receiver"`
+	NextFlush time.Time `json:"nextFlush"`
+	// NextRepeat is the earliest flush at which notifications that were
+	// already sent are repeated. It is nil if nothing was sent yet.
+	NextRepeat *time.Time `json:"nextRepeat,omitempty"`
+	// NextNotification is the earliest time a notification can be sent,
+	// taking mute time intervals into account. It is nil if the route is
+	// muted beyond the computed period.
+	NextNotification *time.Time `json:"nextNotification,omitempty"`
+}
+
+// Schedule returns the upcoming notification schedule of all routes and
+// their aggregation groups at the given time. Routes are returned in the
+// order of the routing tree. If matchers are given, only groups holding
+// alerts matched by them are included.
+func (d *Dispatcher) Schedule(now time.Time, ms types.Matchers) []*RouteSchedule {
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	var res []*RouteSchedule
+
+	groups := d.aggrGroups.byRoute()
+
+	d.route.Walk(func(r *Route) {
+		rs := &RouteSchedule{
+			Route:    r.Key(),
+			Receiver: r.RouteOpts.Receiver,
+			Muted:    r.RouteOpts.Muted(now),
+			Groups:   []*GroupSchedule{},
+		}
+		if start, end, ok := r.RouteOpts.NextMute(now); ok {
+			rs.NextMute = &TimeWindow{Start: start}
+			if !end.IsZero() {
+				rs.NextMute.End = &end
+			}
+		}
+
+		for _, ag := range groups[r] {
+			if len(ms) > 0 && !ag.holds(ms) {
+				continue
+			}
+			rs.Groups = append(rs.Groups, ag.schedule(now))
+		}
+		sort.Sort(groupSchedules(rs.Groups))
+
+		res = append(res, rs)
+	})
+
+	return res
+}
+
+type groupSchedules []*GroupSchedule
+
+func (gs groupSchedules) Len() int           { return len(gs) }
+func (gs groupSchedules) Swap(i, j int)      { gs[i], gs[j] = gs[j], gs[i] }
+func (gs groupSchedules) Less(i, j int) bool { return gs[i].Labels.Before(gs[j].Labels) }
+
+// schedule returns the group's schedule at the given time.
+func (ag *aggrGroup) schedule(now time.Time) *GroupSchedule {
+	receiver, _ := ag.escalation(now)
+
+	ag.mtx.RLock()
+	defer ag.mtx.RUnlock()
+
+	gs := &GroupSchedule{
+		Labels:    ag.labels,
+		GroupKey:  ag.groupKey(),
+		Receiver:  receiver,
+		NextFlush: ag.nextFlush,
+	}
+
+	if ag.hasSent && !ag.lastNotified.IsZero() {
+		// Repeats happen at the first flush after the repeat interval
+		// elapsed.
+		next := ag.flushAfter(ag.lastNotified.Add(ag.opts.RepeatInterval))
+		gs.NextRepeat = &next
+	}
+
+	next := ag.nextFlush
+	if ag.opts.Muted(next) {
+		_, end, _ := ag.opts.NextMute(next)
+		if end.IsZero() {
+			return gs
+		}
+		next = ag.flushAfter(end)
+	}
+	gs.NextNotification = &next
+
+	return gs
+}
+
+// flushAfter returns the time of the first scheduled flush at or after t
+// assuming flushes keep happening every group interval. The caller must
+// hold the group's lock.
+func (ag *aggrGroup) flushAfter(t time.Time) time.Time {
+	next := ag.nextFlush
+
+	if iv := ag.opts.GroupInterval; next.Before(t) && iv > 0 {
+		n := (t.Sub(next) + iv - 1) / iv
+		next = next.Add(n * iv)
+	}
+	return next
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestDispatcherSchedule(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+group_interval: 5m
+repeat_interval: 1h
+mute_time_intervals:
+- times:
+  - start_time: '22:00'
+    end_time: '06:00'
+
+routes:
+- match:
+    owner: 'team-A'
+  receiver: 'notify-A'
+  mute_time_intervals: []
+`
+	var ctree config.Route
+	if err := yaml.Unmarshal([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	date := func(d, h, m int) time.Time {
+		return time.Date(2016, 7, d, h, m, 0, 0, time.UTC)
+	}
+	now := date(6, 21, 58)
+
+	ag1 := newAggrGroup(context.Background(), model.LabelSet{"g": "1"}, tree)
+	ag1.nextFlush = now
+	ag1.hasSent = true
+	ag1.lastNotified = date(6, 21, 0)
+
+	// The group's next flush falls into the mute interval.
+	ag2 := newAggrGroup(context.Background(), model.LabelSet{"g": "2"}, tree)
+	ag2.nextFlush = date(6, 22, 12)
+
+	d := NewDispatcher(nil, tree, nil, types.NewMarker())
+	d.aggrGroups.set(tree, ag1)
+	d.aggrGroups.set(tree, ag2)
+
+	sched := d.Schedule(now, nil)
+
+	if len(sched) != 2 {
+		t.Fatalf("expected schedules of 2 routes but got %d", len(sched))
+	}
+	root, child := sched[0], sched[1]
+
+	if root.Receiver != "notify-def" || root.Muted {
+		t.Fatalf("unexpected root schedule %+v", root)
+	}
+	if m := root.NextMute; m == nil || !m.Start.Equal(date(6, 22, 0)) || m.End == nil || !m.End.Equal(date(7, 6, 0)) {
+		t.Fatalf("unexpected next mute %+v", m)
+	}
+	if child.NextMute != nil || len(child.Groups) != 0 {
+		t.Fatalf("unexpected child schedule %+v", child)
+	}
+
+	if len(root.Groups) != 2 {
+		t.Fatalf("expected 2 group schedules but got %d", len(root.Groups))
+	}
+	g1, g2 := root.Groups[0], root.Groups[1]
+
+	if g1.Receiver != "notify-def" || !g1.NextFlush.Equal(now) {
+		t.Errorf("unexpected group schedule %+v", g1)
+	}
+	// Notifications are repeated at the first flush an hour after the
+	// last notification.
+	if g1.NextRepeat == nil || !g1.NextRepeat.Equal(date(6, 22, 3)) {
+		t.Errorf("expected next repeat at %s but got %v", date(6, 22, 3), g1.NextRepeat)
+	}
+	if g1.NextNotification == nil || !g1.NextNotification.Equal(now) {
+		t.Errorf("expected next notification at %s but got %v", now, g1.NextNotification)
+	}
+
+	if g2.NextRepeat != nil {
+		t.Errorf("expected no repeat for group without notifications but got %v", g2.NextRepeat)
+	}
+	// The first flush after the mute interval ended.
+	if g2.NextNotification == nil || !g2.NextNotification.Equal(date(7, 6, 2)) {
+		t.Errorf("expected next notification at %s but got %v", date(7, 6, 2), g2.NextNotification)
+	}
+}
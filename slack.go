@@ -0,0 +1,269 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// slackMaxRequestAge is the maximum age of the timestamp of Slack requests
+// accepted to prevent replays.
+const slackMaxRequestAge = 5 * time.Minute
+
+// slackActionPayload is the payload of a Slack interactive message request.
+//
+// https://api.slack.com/legacy/interactive-messages
+type slackActionPayload struct {
+	Type       string `json:"type"`
+	CallbackID string `json:"callback_id"`
+	Actions    []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"actions"`
+	User struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+// slackActionResponse is a message posted in response to an action.
+type slackActionResponse struct {
+	ResponseType    string `json:"response_type"`
+	ReplaceOriginal bool   `json:"replace_original"`
+	Text            string `json:"text"`
+}
+
+// verifySlackSignature returns true iff the signature is the HMAC of the
+// timestamped body under the signing secret.
+func verifySlackSignature(secret config.Secret, body []byte, timestamp, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := []byte("v0=" + hex.EncodeToString(mac.Sum(nil)))
+
+	return hmac.Equal([]byte(signature), expected)
+}
+
+// verifySlackRequest returns an error if the request's signature is invalid
+// or its timestamp is too far from the given time.
+func verifySlackRequest(secret config.Secret, r *http.Request, body []byte, now time.Time) error {
+	ts := r.Header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid request timestamp %q", ts)
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > slackMaxRequestAge || age < -slackMaxRequestAge {
+		return fmt.Errorf("request timestamp too old")
+	}
+	if !verifySlackSignature(secret, body, ts, r.Header.Get("X-Slack-Signature")) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+func (api *API) slackAction(w http.ResponseWriter, r *http.Request) {
+	api.mtx.RLock()
+	conf := api.slackActionsConf
+	api.mtx.RUnlock()
+
+	if conf == nil {
+		respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("Slack actions not configured"),
+		}, nil)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	now := time.Now()
+
+	if err := verifySlackRequest(conf.SigningSecret, r, body, now); err != nil {
+		respondError(w, apiError{
+			typ: errorUnauthorized,
+			err: err,
+		}, nil)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	var p slackActionPayload
+	if err := json.Unmarshal([]byte(form.Get("payload")), &p); err != nil {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if p.CallbackID != notify.SlackCallbackID || len(p.Actions) != 1 {
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("unknown action"),
+		}, nil)
+		return
+	}
+	action := p.Actions[0]
+
+	labels, alerts, err := api.dispatcher().GroupByHash(action.Value)
+	if err == provider.ErrNotFound {
+		respondSlack(w, false, "The alert group no longer exists.")
+		return
+	}
+	if err != nil {
+		respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var (
+		firing  = firingAlerts(alerts)
+		creator = p.User.Name + " (Slack)"
+	)
+	switch action.Name {
+	case notify.SlackActionSilence:
+		// A silence without matchers would mute all alerts.
+		if len(labels) == 0 {
+			respondSlack(w, false, "Alert groups without grouping labels cannot be silenced.")
+			return
+		}
+		id, err := api.silenceGroup(labels, notify.SlackSilenceDuration, creator, "Silenced from Slack", now)
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+		respondSlack(w, true, fmt.Sprintf("<@%s> silenced the alert group for %s (silence %d).", p.User.ID, model.Duration(notify.SlackSilenceDuration), id))
+
+	case notify.SlackActionAck:
+		fps, err := api.ackAlerts(firing, creator, "Acknowledged from Slack", now)
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+		respondSlack(w, true, fmt.Sprintf("<@%s> acknowledged %d firing alerts.", p.User.ID, len(fps)))
+
+	case notify.SlackActionViewEvent:
+		e, err := api.groupEvent(alerts)
+		if err != nil {
+			respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+		if e == nil {
+			respondSlack(w, false, "There is no event for the alert group.")
+			return
+		}
+		respondSlack(w, false, fmt.Sprintf("<%s|Event %d: %s>", api.eventURL(e.ID), e.ID, e.Title))
+
+	default:
+		respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("unknown action %q", action.Name),
+		}, nil)
+	}
+}
+
+// groupEvent returns the most recently created event containing one of
+// the alerts or nil if there is none.
+func (api *API) groupEvent(alerts []*types.Alert) (*types.Event, error) {
+	events, err := api.events.All()
+	if err != nil {
+		return nil, err
+	}
+	var res *types.Event
+	for _, e := range events {
+		if res != nil && !e.CreatedAt.After(res.CreatedAt) {
+			continue
+		}
+		for _, a := range alerts {
+			if hasEventAlert(e, eventAlertID(a.Fingerprint())) {
+				res = e
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// eventURL returns the link to the event in the web UI.
+func (api *API) eventURL(id uint64) string {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	if api.tmpl == nil || api.tmpl.ExternalURL == nil {
+		return fmt.Sprintf("/#/events/%d", id)
+	}
+	u := *api.tmpl.ExternalURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/"
+	u.Fragment = fmt.Sprintf("/events/%d", id)
+	return u.String()
+}
+
+// respondSlack responds to a Slack action with a message that is visible
+// to the whole channel if public is true and only to the acting user
+// otherwise.
+func respondSlack(w http.ResponseWriter, public bool, text string) {
+	res := &slackActionResponse{
+		ResponseType: "ephemeral",
+		Text:         text,
+	}
+	if public {
+		res.ResponseType = "in_channel"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Errorf("Error encoding Slack response: %s", err)
+	}
+}
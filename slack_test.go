@@ -0,0 +1,174 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/provider/boltmem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestSlackAction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slack_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	acks, err := boltmem.NewAcks(dir, types.NewMarker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer acks.Close()
+
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "slack",
+			GroupBy:        map[model.LabelName]struct{}{"service": struct{}{}},
+			GroupWait:      time.Hour,
+			GroupInterval:  time.Hour,
+			RepeatInterval: time.Hour,
+		},
+	}
+	d := NewDispatcher(nil, route, nil, nil)
+	defer d.cancel()
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "down", "service": "db"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	d.processAlert(alert, route)
+
+	var key string
+	for _, ag := range d.aggrGroups.byRoute()[route] {
+		key = notify.HashGroupKey(ag.groupKey())
+	}
+
+	var (
+		silences = provider.NewMemSilences()
+		events   = provider.NewMemEvents()
+	)
+	eid, err := events.Set(&types.Event{
+		Title:     "Database outage",
+		CreatedAt: time.Now(),
+		Alerts:    []string{eventAlertID(alert.Fingerprint())},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := NewAPI(nil, silences, events, acks, nil, nil, nil, nil, "", func() *Dispatcher {
+		return d
+	})
+
+	const secret = "s3cr3t"
+
+	call := func(action, value string, ts time.Time) (int, *slackActionResponse) {
+		payload := fmt.Sprintf(`{
+			"type": "interactive_message",
+			"callback_id": %q,
+			"actions": [{"name": %q, "type": "button", "value": %q}],
+			"user": {"id": "U01", "name": "jane"}
+		}`, notify.SlackCallbackID, action, value)
+		body := url.Values{"payload": {payload}}.Encode()
+		timestamp := strconv.FormatInt(ts.Unix(), 10)
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+		req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+
+		w := httptest.NewRecorder()
+		api.slackAction(w, req)
+
+		var res slackActionResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, &res
+	}
+
+	if code, _ := call(notify.SlackActionAck, key, time.Now()); code != http.StatusNotFound {
+		t.Fatalf("expected unconfigured actions to be not found but got status %d", code)
+	}
+
+	api.SetSlackActions(&config.SlackActions{SigningSecret: secret})
+
+	if code, _ := call(notify.SlackActionAck, key, time.Now().Add(-time.Hour)); code != http.StatusUnauthorized {
+		t.Fatalf("expected replayed request to be rejected but got status %d", code)
+	}
+
+	code, res := call(notify.SlackActionAck, "unknown", time.Now())
+	if code != http.StatusOK || res.ResponseType != "ephemeral" {
+		t.Fatalf("expected unknown group to be reported to the user but got status %d, %+v", code, res)
+	}
+
+	code, res = call(notify.SlackActionAck, key, time.Now())
+	if code != http.StatusOK || res.ResponseType != "in_channel" {
+		t.Fatalf("expected acknowledgement to be posted but got status %d, %+v", code, res)
+	}
+	ack, err := acks.Get(alert.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.CreatedBy != "jane (Slack)" {
+		t.Fatalf("expected acknowledgement by %q but got %q", "jane (Slack)", ack.CreatedBy)
+	}
+
+	code, res = call(notify.SlackActionSilence, key, time.Now())
+	if code != http.StatusOK {
+		t.Fatalf("expected silencing to succeed but got status %d", code)
+	}
+	sils, err := silences.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sils) != 1 || sils[0].CreatedBy != "jane (Slack)" || sils[0].EndsAt.Sub(sils[0].StartsAt) != time.Hour {
+		t.Fatalf("expected 1h silence by %q but got %v", "jane (Slack)", sils)
+	}
+
+	code, res = call(notify.SlackActionViewEvent, key, time.Now())
+	if code != http.StatusOK {
+		t.Fatalf("expected viewing the event to succeed but got status %d", code)
+	}
+	if exp := fmt.Sprintf("</#/events/%d|Event %d: Database outage>", eid, eid); res.Text != exp {
+		t.Fatalf("expected %q but got %q", exp, res.Text)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BER tags of the types used in SNMP messages.
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagOID         = 0x06
+	tagSequence    = 0x30
+	tagTimeTicks   = 0x43
+	tagTrapV2      = 0xa7
+)
+
+// OID is an object identifier.
+type OID []uint32
+
+// ParseOID parses an object identifier in dotted notation, e.g.
+// 1.3.6.1.4.1.8072. A leading dot is ignored.
+func ParseOID(s string) (OID, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q: at least two arcs required", s)
+	}
+	oid := make(OID, 0, len(parts))
+	for _, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q: %s", s, err)
+		}
+		oid = append(oid, uint32(v))
+	}
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40) {
+		return nil, fmt.Errorf("invalid OID %q: invalid first arcs", s)
+	}
+	return oid, nil
+}
+
+func (o OID) String() string {
+	parts := make([]string, len(o))
+	for i, v := range o {
+		parts[i] = strconv.FormatUint(uint64(v), 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// TimeTicks is a time in hundredths of a second.
+type TimeTicks uint32
+
+// tlv returns the encoding of a value with the given tag and content.
+func tlv(tag byte, content []byte) []byte {
+	b := append([]byte{tag}, encodeLength(len(content))...)
+	return append(b, content...)
+}
+
+// sequence returns the encoding of a sequence of the encoded values.
+func sequence(tag byte, values ...[]byte) []byte {
+	var content []byte
+	for _, v := range values {
+		content = append(content, v...)
+	}
+	return tlv(tag, content)
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// encodeInt returns the shortest two's complement content of v.
+func encodeInt(v int64) []byte {
+	n := 1
+	for i := v; i > 127 || i < -128; i >>= 8 {
+		n++
+	}
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
+
+// encodeUint returns the content of the unsigned value v, which is
+// prefixed with a zero byte if its highest bit is set.
+func encodeUint(v uint64) []byte {
+	var b []byte
+	for ; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	if len(b) == 0 || b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+func encodeOID(o OID) ([]byte, error) {
+	if len(o) < 2 {
+		return nil, fmt.Errorf("invalid OID %s: at least two arcs required", o)
+	}
+	arcs := append([]uint32{o[0]*40 + o[1]}, o[2:]...)
+
+	var b []byte
+	for _, v := range arcs {
+		enc := []byte{byte(v & 0x7f)}
+		for v >>= 7; v > 0; v >>= 7 {
+			enc = append([]byte{0x80 | byte(v&0x7f)}, enc...)
+		}
+		b = append(b, enc...)
+	}
+	return b, nil
+}
+
+// encodeValue returns the encoding of a variable binding's value.
+func encodeValue(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return tlv(tagNull, nil), nil
+	case string:
+		return tlv(tagOctetString, []byte(v)), nil
+	case []byte:
+		return tlv(tagOctetString, v), nil
+	case int:
+		return tlv(tagInteger, encodeInt(int64(v))), nil
+	case int32:
+		return tlv(tagInteger, encodeInt(int64(v))), nil
+	case TimeTicks:
+		return tlv(tagTimeTicks, encodeUint(uint64(v))), nil
+	case OID:
+		b, err := encodeOID(v)
+		if err != nil {
+			return nil, err
+		}
+		return tlv(tagOID, b), nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
@@ -0,0 +1,192 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snmp sends SNMPv2c and SNMPv3 traps.
+package snmp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Version is an SNMP protocol version.
+type Version int
+
+// Supported SNMP versions. The values are the version numbers used in
+// messages.
+const (
+	V2c Version = 1
+	V3  Version = 3
+)
+
+var (
+	// sysUpTimeOID is the OID of sysUpTime.0, the first variable binding
+	// of every trap.
+	sysUpTimeOID = OID{1, 3, 6, 1, 2, 1, 1, 3, 0}
+	// trapOIDOID is the OID of snmpTrapOID.0, the second variable binding
+	// of every trap identifying the notification.
+	trapOIDOID = OID{1, 3, 6, 1, 6, 3, 1, 1, 4, 1, 0}
+
+	// start is the time the process started, from which the uptime sent
+	// with traps and the SNMPv3 engine time are derived.
+	start = time.Now()
+)
+
+// maxMessageSize is the maximum size of messages sent or received.
+const maxMessageSize = 65507
+
+// A VarBind binds a value to an OID. Values are strings, byte slices, int,
+// int32, TimeTicks, OIDs or nil.
+type VarBind struct {
+	OID   OID
+	Value interface{}
+}
+
+// A Trap is an SNMPv2 notification.
+type Trap struct {
+	// OID identifies the notification and is sent as snmpTrapOID.0.
+	OID      OID
+	VarBinds []VarBind
+}
+
+// A Sender sends traps to a single receiver.
+type Sender struct {
+	target    string
+	version   Version
+	community string
+	usm       *usm
+
+	mtx sync.Mutex
+	id  int32
+}
+
+// NewSender returns a new sender of traps to the target host:port. The
+// community authenticates SNMPv2c traps and security configures SNMPv3
+// traps.
+func NewSender(target string, version Version, community string, security *Security) (*Sender, error) {
+	s := &Sender{
+		target:    target,
+		version:   version,
+		community: community,
+	}
+	switch version {
+	case V2c:
+	case V3:
+		if security == nil {
+			return nil, fmt.Errorf("missing security parameters for SNMPv3")
+		}
+		u, err := newUSM(security)
+		if err != nil {
+			return nil, err
+		}
+		s.usm = u
+	default:
+		return nil, fmt.Errorf("unsupported SNMP version %d", version)
+	}
+
+	// Request IDs start randomly so that receivers do not mistake traps of
+	// a restarted sender for duplicates.
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	s.id = int32(binary.BigEndian.Uint32(b[:]) & 0x7fffffff)
+
+	return s, nil
+}
+
+// nextID returns a new request ID.
+func (s *Sender) nextID() int32 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.id = (s.id + 1) & 0x7fffffff
+	return s.id
+}
+
+// Send sends the traps in order through a single socket.
+func (s *Sender) Send(ctx context.Context, traps ...*Trap) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", s.target)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+	for _, t := range traps {
+		b, err := s.encode(t)
+		if err != nil {
+			return err
+		}
+		if len(b) > maxMessageSize {
+			return fmt.Errorf("trap of %d bytes exceeds maximum message size", len(b))
+		}
+		if _, err := conn.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode returns the message of the trap.
+func (s *Sender) encode(t *Trap) ([]byte, error) {
+	pdu, err := s.encodePDU(t)
+	if err != nil {
+		return nil, err
+	}
+	if s.version == V3 {
+		return s.usm.encode(s.nextID(), pdu)
+	}
+	return sequence(tagSequence,
+		tlv(tagInteger, encodeInt(int64(V2c))),
+		tlv(tagOctetString, []byte(s.community)),
+		pdu,
+	), nil
+}
+
+func (s *Sender) encodePDU(t *Trap) ([]byte, error) {
+	vbs := append([]VarBind{
+		{OID: sysUpTimeOID, Value: TimeTicks(time.Since(start) / (10 * time.Millisecond))},
+		{OID: trapOIDOID, Value: t.OID},
+	}, t.VarBinds...)
+
+	var encoded [][]byte
+	for _, vb := range vbs {
+		oid, err := encodeOID(vb.OID)
+		if err != nil {
+			return nil, err
+		}
+		v, err := encodeValue(vb.Value)
+		if err != nil {
+			return nil, fmt.Errorf("variable binding %s: %s", vb.OID, err)
+		}
+		encoded = append(encoded, sequence(tagSequence, tlv(tagOID, oid), v))
+	}
+
+	return sequence(tagTrapV2,
+		tlv(tagInteger, encodeInt(int64(s.nextID()))),
+		// Error status and index.
+		tlv(tagInteger, encodeInt(0)),
+		tlv(tagInteger, encodeInt(0)),
+		sequence(tagSequence, encoded...),
+	), nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"testing"
+)
+
+func TestEncodeValue(t *testing.T) {
+	cases := []struct {
+		in       interface{}
+		expected string
+	}{
+		{in: 0, expected: "020100"},
+		{in: 127, expected: "02017f"},
+		{in: 128, expected: "02020080"},
+		{in: -1, expected: "0201ff"},
+		{in: -129, expected: "0202ff7f"},
+		{in: TimeTicks(0), expected: "430100"},
+		{in: TimeTicks(200), expected: "430200c8"},
+		{in: "ok", expected: "04026f6b"},
+		{in: nil, expected: "0500"},
+		{in: OID{1, 3, 6, 1, 2, 1, 1, 3, 0}, expected: "06082b06010201010300"},
+		{in: OID{1, 3, 6, 1, 4, 1, 2680, 1}, expected: "06082b06010401947801"},
+	}
+	for _, c := range cases {
+		b, err := encodeValue(c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(b); got != c.expected {
+			t.Errorf("expected %v to be encoded as %s but got %s", c.in, c.expected, got)
+		}
+	}
+
+	// Long contents have multi-byte lengths.
+	b, _ := encodeValue(string(make([]byte, 300)))
+	if !bytes.HasPrefix(b, []byte{tagOctetString, 0x82, 0x01, 0x2c}) {
+		t.Errorf("unexpected long encoding prefix %x", b[:4])
+	}
+}
+
+func TestParseOID(t *testing.T) {
+	oid, err := ParseOID(".1.3.6.1.4.1.8072.9999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oid.String() != "1.3.6.1.4.1.8072.9999" {
+		t.Errorf("unexpected OID %s", oid)
+	}
+	for _, s := range []string{"", "1", "1.3.x", "3.1", "1.40", "1..3"} {
+		if _, err := ParseOID(s); err == nil {
+			t.Errorf("expected error for OID %q", s)
+		}
+	}
+}
+
+func TestLocalizeKey(t *testing.T) {
+	// Test vectors of RFC 3414, section A.3.
+	engineID, _ := hex.DecodeString("000000000000000000000002")
+
+	if got := hex.EncodeToString(localizeKey(md5.New, "maplesyrup", engineID)); got != "526f5eed9fcce26f8964c2930787d82b" {
+		t.Errorf("unexpected MD5 key %s", got)
+	}
+	if got := hex.EncodeToString(localizeKey(sha1.New, "maplesyrup", engineID)); got != "6695febc9288e36282235fc7151f128497b38f3f" {
+		t.Errorf("unexpected SHA key %s", got)
+	}
+}
+
+func TestEncodeV2c(t *testing.T) {
+	s, err := NewSender("localhost:162", V2c, "public", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.id = 0
+
+	b, err := s.encode(&Trap{
+		OID:      OID{1, 3, 6, 1, 4, 1, 8072, 9999, 1},
+		VarBinds: []VarBind{{OID: OID{1, 3, 6, 1, 4, 1, 8072, 9999, 2}, Value: "disk_full"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The message starts with the version and community and ends with the
+	// variable binding of the alert.
+	prefix, _ := hex.DecodeString("020101" + "04067075626c6963" + "a7")
+	if !bytes.Equal(b[2:2+len(prefix)], prefix) {
+		t.Errorf("unexpected message header %x", b)
+	}
+	suffix, _ := hex.DecodeString("060a2b06010401bf08ce0f02" + "0409" + hex.EncodeToString([]byte("disk_full")))
+	if !bytes.HasSuffix(b, suffix) {
+		t.Errorf("expected message %x to end with the alert's variable binding", b)
+	}
+	if int(b[1]) != len(b)-2 {
+		t.Errorf("expected message length %d but got %d", len(b)-2, b[1])
+	}
+}
+
+func TestEncodeV3(t *testing.T) {
+	sec := &Security{
+		UserName:     "trapuser",
+		AuthProtocol: AuthSHA,
+		AuthPassword: "authpassword",
+		PrivProtocol: PrivAES,
+		PrivPassword: "privpassword",
+	}
+	s, err := NewSender("localhost:162", V3, "", sec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := "disk_full"
+	b, err := s.encode(&Trap{
+		OID:      OID{1, 3, 6, 1, 4, 1, 8072, 9999, 1},
+		VarBinds: []VarBind{{OID: OID{1, 3, 6, 1, 4, 1, 8072, 9999, 2}, Value: secret}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte(secret)) {
+		t.Errorf("expected the PDU to be encrypted")
+	}
+	if !bytes.Contains(b, DefaultEngineID) {
+		t.Errorf("expected default engine ID in message %x", b)
+	}
+
+	// The message is authenticated by the HMAC over the message with
+	// zeroed authentication parameters, which follow the user name.
+	user := tlv(tagOctetString, []byte("trapuser"))
+	i := bytes.Index(b, user) + len(user)
+	if i < len(user) || b[i] != tagOctetString || b[i+1] != authParamsLen {
+		t.Fatalf("authentication parameters not found in %x", b)
+	}
+	i += 2
+	mac := append([]byte(nil), b[i:i+authParamsLen]...)
+	zeroed := append([]byte(nil), b...)
+	copy(zeroed[i:], make([]byte, authParamsLen))
+
+	h := hmac.New(sha1.New, localizeKey(sha1.New, "authpassword", DefaultEngineID))
+	h.Write(zeroed)
+	if exp := h.Sum(nil)[:authParamsLen]; !bytes.Equal(mac, exp) {
+		t.Errorf("expected HMAC %x but got %x", exp, mac)
+	}
+
+	for _, sec := range []*Security{
+		{},
+		{UserName: "u", PrivProtocol: PrivAES, PrivPassword: "privpassword"},
+		{UserName: "u", AuthProtocol: AuthSHA, AuthPassword: "short"},
+		{UserName: "u", AuthProtocol: "SHA512", AuthPassword: "authpassword"},
+		{UserName: "u", EngineID: []byte{1}},
+	} {
+		if _, err := NewSender("localhost:162", V3, "", sec); err == nil {
+			t.Errorf("expected error for security parameters %+v", sec)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snmp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"sync"
+	"time"
+)
+
+// Authentication and privacy protocols of SNMPv3 users.
+const (
+	AuthMD5 = "MD5"
+	AuthSHA = "SHA"
+	PrivDES = "DES"
+	PrivAES = "AES"
+)
+
+// DefaultEngineID is the SNMPv3 engine ID of senders without a configured
+// one. It is a text engine ID "alertmanager" in the Net-SNMP enterprise.
+var DefaultEngineID = append([]byte{0x80, 0x00, 0x1f, 0x88, 0x04}, "alertmanager"...)
+
+// Security holds the parameters of the SNMPv3 user-based security model.
+// The sender of traps is the authoritative engine, so receivers must know
+// the user with the sender's engine ID.
+type Security struct {
+	EngineID     []byte
+	UserName     string
+	AuthProtocol string
+	AuthPassword string
+	PrivProtocol string
+	PrivPassword string
+}
+
+// Message flags and the security model of SNMPv3 messages.
+const (
+	flagAuth         = 0x01
+	flagPriv         = 0x02
+	securityModelUSM = 3
+	// authParamsLen is the length of the truncated HMAC authenticating a
+	// message.
+	authParamsLen = 12
+)
+
+// usm encodes messages with the user-based security model.
+type usm struct {
+	engineID []byte
+	user     string
+	// The hash function and localized keys used for authentication and
+	// privacy. authHash is nil if messages are not authenticated, privKey
+	// is nil if they are not encrypted.
+	authHash func() hash.Hash
+	authKey  []byte
+	priv     string
+	privKey  []byte
+
+	mtx  sync.Mutex
+	salt uint64
+}
+
+func newUSM(s *Security) (*usm, error) {
+	u := &usm{
+		engineID: s.EngineID,
+		user:     s.UserName,
+		priv:     s.PrivProtocol,
+	}
+	if len(u.engineID) == 0 {
+		u.engineID = DefaultEngineID
+	}
+	if len(u.engineID) < 5 || len(u.engineID) > 32 {
+		return nil, fmt.Errorf("engine ID must have between 5 and 32 bytes")
+	}
+	if u.user == "" {
+		return nil, fmt.Errorf("missing user name")
+	}
+
+	switch s.AuthProtocol {
+	case "":
+		if s.PrivProtocol != "" {
+			return nil, fmt.Errorf("privacy requires authentication")
+		}
+		return u, nil
+	case AuthMD5:
+		u.authHash = md5.New
+	case AuthSHA:
+		u.authHash = sha1.New
+	default:
+		return nil, fmt.Errorf("unknown authentication protocol %q", s.AuthProtocol)
+	}
+	// Shorter passwords are rejected by RFC 3414.
+	if len(s.AuthPassword) < 8 {
+		return nil, fmt.Errorf("authentication password must have at least 8 characters")
+	}
+	u.authKey = localizeKey(u.authHash, s.AuthPassword, u.engineID)
+
+	switch s.PrivProtocol {
+	case "":
+		return u, nil
+	case PrivDES, PrivAES:
+	default:
+		return nil, fmt.Errorf("unknown privacy protocol %q", s.PrivProtocol)
+	}
+	if len(s.PrivPassword) < 8 {
+		return nil, fmt.Errorf("privacy password must have at least 8 characters")
+	}
+	// The privacy key is localized with the authentication hash. DES uses
+	// the first 8 bytes as key and the next 8 bytes as pre-IV, AES-128 the
+	// first 16 bytes as key.
+	u.privKey = localizeKey(u.authHash, s.PrivPassword, u.engineID)[:16]
+
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	u.salt = binary.BigEndian.Uint64(b[:])
+
+	return u, nil
+}
+
+// localizeKey derives the key of the password localized to the engine as
+// specified in RFC 3414, section A.2.
+func localizeKey(newHash func() hash.Hash, password string, engineID []byte) []byte {
+	h := newHash()
+
+	buf := make([]byte, 64)
+	for n, i := 0, 0; n < 1<<20; n += len(buf) {
+		for j := range buf {
+			buf[j] = password[i%len(password)]
+			i++
+		}
+		h.Write(buf)
+	}
+	ku := h.Sum(nil)
+
+	h.Reset()
+	h.Write(ku)
+	h.Write(engineID)
+	h.Write(ku)
+	return h.Sum(nil)
+}
+
+func (u *usm) nextSalt() uint64 {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	u.salt++
+	return u.salt
+}
+
+// encode returns the SNMPv3 message with the given ID and PDU.
+func (u *usm) encode(msgID int32, pdu []byte) ([]byte, error) {
+	var (
+		boots = int32(1)
+		etime = int32(time.Since(start) / time.Second)
+		flags byte
+
+		authParams []byte
+		privParams []byte
+	)
+	data := sequence(tagSequence,
+		tlv(tagOctetString, u.engineID),
+		// The default context.
+		tlv(tagOctetString, nil),
+		pdu,
+	)
+
+	if u.authHash != nil {
+		flags |= flagAuth
+		authParams = make([]byte, authParamsLen)
+	}
+	if u.privKey != nil {
+		flags |= flagPriv
+
+		var (
+			enc []byte
+			err error
+		)
+		enc, privParams, err = u.encrypt(data, boots, etime)
+		if err != nil {
+			return nil, err
+		}
+		data = tlv(tagOctetString, enc)
+	}
+
+	var (
+		version = tlv(tagInteger, encodeInt(int64(V3)))
+		header  = sequence(tagSequence,
+			tlv(tagInteger, encodeInt(int64(msgID))),
+			tlv(tagInteger, encodeInt(maxMessageSize)),
+			tlv(tagOctetString, []byte{flags}),
+			tlv(tagInteger, encodeInt(securityModelUSM)),
+		)
+		privTLV   = tlv(tagOctetString, privParams)
+		secParams = sequence(tagSequence,
+			tlv(tagOctetString, u.engineID),
+			tlv(tagInteger, encodeInt(int64(boots))),
+			tlv(tagInteger, encodeInt(int64(etime))),
+			tlv(tagOctetString, []byte(u.user)),
+			tlv(tagOctetString, authParams),
+			privTLV,
+		)
+		secTLV = tlv(tagOctetString, secParams)
+		msg    = sequence(tagSequence, version, header, secTLV, data)
+	)
+	if u.authHash == nil {
+		return msg, nil
+	}
+
+	// The HMAC is computed over the message with zeroed authentication
+	// parameters, which precede the privacy parameters, and then fills
+	// them in.
+	var (
+		msgHeaderLen = len(msg) - len(version) - len(header) - len(secTLV) - len(data)
+		secStart     = msgHeaderLen + len(version) + len(header) + len(secTLV) - len(secParams)
+		pos          = secStart + len(secParams) - len(privTLV) - authParamsLen
+	)
+	mac := hmac.New(u.authHash, u.authKey)
+	mac.Write(msg)
+	copy(msg[pos:pos+authParamsLen], mac.Sum(nil))
+
+	return msg, nil
+}
+
+// encrypt encrypts the scoped PDU and returns it with the privacy
+// parameters.
+func (u *usm) encrypt(data []byte, boots, etime int32) ([]byte, []byte, error) {
+	salt := make([]byte, 8)
+
+	switch u.priv {
+	case PrivAES:
+		// RFC 3826: the IV consists of the engine boots, the engine time
+		// and the salt.
+		binary.BigEndian.PutUint64(salt, u.nextSalt())
+
+		iv := make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint32(iv, uint32(boots))
+		binary.BigEndian.PutUint32(iv[4:], uint32(etime))
+		copy(iv[8:], salt)
+
+		block, err := aes.NewCipher(u.privKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		enc := make([]byte, len(data))
+		cipher.NewCFBEncrypter(block, iv).XORKeyStream(enc, data)
+		return enc, salt, nil
+
+	case PrivDES:
+		// RFC 3414, section 8.1.1.1: the salt consists of the engine boots
+		// and a local integer, the IV is the pre-IV XOR the salt.
+		binary.BigEndian.PutUint32(salt, uint32(boots))
+		binary.BigEndian.PutUint32(salt[4:], uint32(u.nextSalt()))
+
+		iv := make([]byte, des.BlockSize)
+		for i := range iv {
+			iv[i] = u.privKey[8+i] ^ salt[i]
+		}
+		block, err := des.NewCipher(u.privKey[:8])
+		if err != nil {
+			return nil, nil, err
+		}
+		// The plaintext is padded to a multiple of the block size.
+		padded := make([]byte, (len(data)+des.BlockSize-1)/des.BlockSize*des.BlockSize)
+		copy(padded, data)
+
+		enc := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(enc, padded)
+		return enc, salt, nil
+	}
+	return nil, nil, fmt.Errorf("unknown privacy protocol %q", u.priv)
+}
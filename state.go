@@ -0,0 +1,263 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// stateVersion is the version of the state archive format.
+const stateVersion = 1
+
+// State is the complete operational state of an Alertmanager. It is used
+// to migrate between storage backends and across upgrades.
+type State struct {
+	Version   int                 `json:"version"`
+	CreatedAt time.Time           `json:"createdAt"`
+	Alerts    []*types.Alert      `json:"alerts"`
+	Silences  []*types.Silence    `json:"silences"`
+	Events    []*types.Event      `json:"events"`
+	Notifies  []*types.NotifyInfo `json:"notifies"`
+	Acks      []*types.Ack        `json:"acks"`
+}
+
+// ReadState reads a state archive and verifies its version.
+func ReadState(r io.Reader) (*State, error) {
+	var s State
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version != stateVersion {
+		return nil, fmt.Errorf("unsupported state version %d", s.Version)
+	}
+	return &s, nil
+}
+
+// Write writes the state archive.
+func (s *State) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// Stores are the providers holding the operational state.
+type Stores struct {
+	Alerts   provider.Alerts
+	Silences provider.Silences
+	Events   provider.Events
+	Notifies provider.Notifies
+	Acks     provider.Acks
+}
+
+// Export returns the current state of all stores. If the matcher is not
+// nil, the state only holds the alerts, silences, and events of its tenant
+// and the notification log entries and acknowledgements of its alerts.
+func (s *Stores) Export(m *types.Matcher) (*State, error) {
+	st := &State{
+		Version:   stateVersion,
+		CreatedAt: time.Now(),
+	}
+
+	alerts := s.Alerts.GetPending()
+	defer alerts.Close()
+
+	fps := map[model.Fingerprint]struct{}{}
+	for a := range alerts.Next() {
+		if err := alerts.Err(); err != nil {
+			return nil, err
+		}
+		if m != nil && !m.Match(a.Labels) {
+			continue
+		}
+		st.Alerts = append(st.Alerts, a)
+		fps[a.Fingerprint()] = struct{}{}
+	}
+
+	sils, err := s.Silences.All()
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.Events.All()
+	if err != nil {
+		return nil, err
+	}
+	notifies, err := s.Notifies.All()
+	if err != nil {
+		return nil, err
+	}
+	acks, err := s.Acks.All()
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		st.Silences, st.Events, st.Notifies, st.Acks = sils, events, notifies, acks
+		return st, nil
+	}
+
+	for _, sil := range sils {
+		if ownsSilence(m, sil) {
+			st.Silences = append(st.Silences, sil)
+		}
+	}
+	for _, e := range events {
+		if m.Match(e.Labels) {
+			st.Events = append(st.Events, e)
+		}
+	}
+	for _, ni := range notifies {
+		if _, ok := fps[ni.Alert]; ok {
+			st.Notifies = append(st.Notifies, ni)
+		}
+	}
+	for _, ack := range acks {
+		if _, ok := fps[ack.Alert]; ok {
+			st.Acks = append(st.Acks, ack)
+		}
+	}
+	return st, nil
+}
+
+// Import adds the given state to the stores. Silences and events are
+// assigned new IDs by the stores they are imported into.
+func (s *Stores) Import(st *State) error {
+	if st.Version != stateVersion {
+		return fmt.Errorf("unsupported state version %d", st.Version)
+	}
+	if len(st.Alerts) > 0 {
+		if err := s.Alerts.Put(st.Alerts...); err != nil {
+			return fmt.Errorf("importing alerts failed: %s", err)
+		}
+	}
+	for _, sil := range st.Silences {
+		sil = types.RestoreSilence(sil)
+		sil.ID = 0
+
+		if _, err := s.Silences.Set(sil); err != nil {
+			return fmt.Errorf("importing silence failed: %s", err)
+		}
+	}
+
+	if err := importEvents(s.Events, st.Events); err != nil {
+		return err
+	}
+	if len(st.Notifies) > 0 {
+		if err := s.Notifies.Set(st.Notifies...); err != nil {
+			return fmt.Errorf("importing notification log failed: %s", err)
+		}
+	}
+	for _, ack := range st.Acks {
+		if err := s.Acks.Set(ack); err != nil {
+			return fmt.Errorf("importing acknowledgement failed: %s", err)
+		}
+	}
+	return nil
+}
+
+// importEvents adds the events to the provider with new IDs, keeping the
+// relations between parent and child events.
+func importEvents(ep provider.Events, es []*types.Event) error {
+	// Keep the order of events as IDs are reassigned.
+	events := make([]*types.Event, len(es))
+	copy(events, es)
+	sort.Sort(eventsByID(events))
+
+	var (
+		ids     = map[uint64]uint64{}
+		parents = map[*types.Event]uint64{}
+	)
+	for _, e := range events {
+		old := e.ID
+		if e.ParentID != 0 {
+			parents[e] = e.ParentID
+		}
+		e.ID = 0
+		e.Version = 0
+		e.ParentID = 0
+
+		id, err := ep.Set(e)
+		if err != nil {
+			return fmt.Errorf("importing event failed: %s", err)
+		}
+		ids[old] = id
+	}
+	// Parents are attached once all events have their new IDs.
+	for e, parent := range parents {
+		pid, ok := ids[parent]
+		if !ok {
+			continue
+		}
+		e.ParentID = pid
+
+		if err := ep.Update(e); err != nil {
+			return fmt.Errorf("importing event failed: %s", err)
+		}
+	}
+	return nil
+}
+
+type eventsByID []*types.Event
+
+func (es eventsByID) Len() int           { return len(es) }
+func (es eventsByID) Swap(i, j int)      { es[i], es[j] = es[j], es[i] }
+func (es eventsByID) Less(i, j int) bool { return es[i].ID < es[j].ID }
+
+// runStateCommand exports the state of the stores to the given file or
+// imports it from there. A file name of "-" refers to standard output or
+// input respectively.
+func runStateCommand(cmd string, stores *Stores, filename string) error {
+	switch cmd {
+	case "export-state":
+		st, err := stores.Export(nil)
+		if err != nil {
+			return err
+		}
+		if filename == "-" {
+			return st.Write(os.Stdout)
+		}
+		f, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		if err := st.Write(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+
+	case "import-state":
+		r := io.Reader(os.Stdin)
+		if filename != "-" {
+			f, err := os.Open(filename)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		st, err := ReadState(r)
+		if err != nil {
+			return err
+		}
+		return stores.Import(st)
+	}
+	return fmt.Errorf("unknown command %q", cmd)
+}
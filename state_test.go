@@ -0,0 +1,163 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/provider/boltmem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func newTestStores(t *testing.T, dir string) *Stores {
+	acks, err := boltmem.NewAcks(dir, types.NewMarker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := provider.NewMemData()
+
+	return &Stores{
+		Alerts:   provider.NewMemAlerts(data),
+		Silences: provider.NewMemSilences(),
+		Events:   provider.NewMemEvents(),
+		Notifies: provider.NewMemNotifies(data),
+		Acks:     acks,
+	}
+}
+
+func TestStateExportImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(dir+"/from", 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(dir+"/to", 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		from = newTestStores(t, dir+"/from")
+		to   = newTestStores(t, dir+"/to")
+		now  = time.Now().UTC().Truncate(time.Second)
+	)
+	defer from.Acks.(*boltmem.Acks).Close()
+	defer to.Acks.(*boltmem.Acks).Close()
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "test"},
+			StartsAt: now.Add(-time.Hour),
+			EndsAt:   now.Add(time.Hour),
+		},
+		UpdatedAt: now,
+	}
+	if err := from.Alerts.Put(alert); err != nil {
+		t.Fatal(err)
+	}
+	sil := types.NewSilence(&model.Silence{
+		Matchers:  []*model.Matcher{{Name: "alertname", Value: "test"}},
+		StartsAt:  now,
+		EndsAt:    now.Add(time.Hour),
+		CreatedAt: now,
+		CreatedBy: "user",
+		Comment:   "maintenance",
+	}, &model.Matcher{Name: "severity", Value: "critical"})
+	if _, err := from.Silences.Set(sil); err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range []string{"first", "second"} {
+		if _, err := from.Events.Set(&types.Event{Title: title, CreatedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The parent of the first event is imported after it.
+	if err := from.Events.Update(&types.Event{ID: 1, Title: "first", ParentID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	ni := &types.NotifyInfo{Alert: alert.Fingerprint(), Receiver: "team", Timestamp: now}
+	if err := from.Notifies.Set(ni); err != nil {
+		t.Fatal(err)
+	}
+	ack := &types.Ack{Alert: alert.Fingerprint(), CreatedBy: "user", CreatedAt: now}
+	if err := from.Acks.Set(ack); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := from.Export(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := st.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if st, err = ReadState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := to.Import(st); err != nil {
+		t.Fatal(err)
+	}
+
+	if a, err := to.Alerts.Get(alert.Fingerprint()); err != nil || !a.EndsAt.Equal(alert.EndsAt) {
+		t.Errorf("expected alert to be imported but got %v, %v", a, err)
+	}
+	sils, err := to.Silences.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sils) != 1 || sils[0].Comment != "maintenance" {
+		t.Fatalf("expected silence to be imported but got %v", sils)
+	}
+	if sils[0].Mutes(model.LabelSet{"alertname": "test", "severity": "critical"}) {
+		t.Errorf("expected exclude matchers to be imported")
+	}
+	for i, title := range []string{"first", "second"} {
+		e, err := to.Events.Get(uint64(i + 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Title != title {
+			t.Errorf("expected event %d to be %q but got %q", i+1, title, e.Title)
+		}
+	}
+	if children, err := to.Events.Children(2); err != nil || len(children) != 1 || children[0].Title != "first" {
+		t.Errorf("expected parent of event to be imported but got %v, %v", children, err)
+	}
+	ns, err := to.Notifies.Get("team", alert.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns[0] == nil || !ns[0].Timestamp.Equal(now) {
+		t.Errorf("expected notification log to be imported but got %v", ns[0])
+	}
+	if a, err := to.Acks.Get(alert.Fingerprint()); err != nil || a.CreatedBy != "user" {
+		t.Errorf("expected acknowledgement to be imported but got %v, %v", a, err)
+	}
+
+	// Archives of other versions are rejected.
+	if _, err := ReadState(bytes.NewBufferString(`{"version": 2}`)); err == nil {
+		t.Errorf("expected unsupported version to be rejected")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"github.com/prometheus/alertmanager/provider"
+)
+
+var numEvictedEntries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "storage_evicted_entries_total",
+	Help:      "The total number of entries removed from stores for exceeding their maximum age or size.",
+}, []string{"store", "reason"})
+
+func init() {
+	prometheus.MustRegister(numEvictedEntries)
+}
+
+// gcStore is a store collected by a StorageGC.
+type gcStore struct {
+	name      string
+	collector provider.Collector
+	retention provider.Retention
+}
+
+// StorageGC periodically removes the entries of stores exceeding their
+// retention.
+type StorageGC struct {
+	interval time.Duration
+	stores   []*gcStore
+	stopc    chan struct{}
+}
+
+// NewStorageGC returns a new StorageGC collecting in the given interval.
+func NewStorageGC(interval time.Duration) *StorageGC {
+	return &StorageGC{
+		interval: interval,
+		stopc:    make(chan struct{}),
+	}
+}
+
+// Add the store to be collected with the given retention. Stores without
+// retention limits are not collected. It must be called before Run.
+func (g *StorageGC) Add(name string, c provider.Collector, r provider.Retention) {
+	if r.MaxAge <= 0 && r.MaxSize <= 0 {
+		return
+	}
+	g.stores = append(g.stores, &gcStore{name: name, collector: c, retention: r})
+}
+
+// Run the StorageGC's background processing.
+func (g *StorageGC) Run() {
+	if len(g.stores) == 0 {
+		return
+	}
+	g.collect(time.Now())
+
+	t := time.NewTicker(g.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-g.stopc:
+			return
+		case <-t.C:
+			g.collect(time.Now())
+		}
+	}
+}
+
+// Stop the StorageGC's background processing.
+func (g *StorageGC) Stop() {
+	close(g.stopc)
+}
+
+func (g *StorageGC) collect(now time.Time) {
+	for _, s := range g.stores {
+		expired, evicted, err := s.collector.Collect(now, s.retention)
+		if err != nil {
+			log.With("store", s.name).Errorf("Storage GC failed: %s", err)
+		}
+		numEvictedEntries.WithLabelValues(s.name, "max_age").Add(float64(expired))
+		numEvictedEntries.WithLabelValues(s.name, "max_size").Add(float64(evicted))
+
+		if expired+evicted > 0 {
+			log.With("store", s.name).Debugf("Storage GC removed %d expired and %d evicted entries", expired, evicted)
+		}
+	}
+}
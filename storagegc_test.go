@@ -0,0 +1,65 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/prometheus/alertmanager/provider"
+)
+
+type collectorFunc func(time.Time, provider.Retention) (int, int, error)
+
+func (f collectorFunc) Collect(now time.Time, r provider.Retention) (int, int, error) {
+	return f(now, r)
+}
+
+func TestStorageGC(t *testing.T) {
+	var (
+		now       = time.Now()
+		retention = provider.Retention{MaxAge: time.Hour, MaxSize: 1 << 20}
+		calls     []provider.Retention
+	)
+	g := NewStorageGC(time.Minute)
+	g.Add("gc_test", collectorFunc(func(ts time.Time, r provider.Retention) (int, int, error) {
+		if !ts.Equal(now) {
+			t.Errorf("expected collection at %s but got %s", now, ts)
+		}
+		calls = append(calls, r)
+		return 2, 1, nil
+	}), retention)
+	// Stores without limits are not collected.
+	g.Add("gc_test_unlimited", collectorFunc(func(time.Time, provider.Retention) (int, int, error) {
+		t.Errorf("expected store without retention not to be collected")
+		return 0, 0, nil
+	}), provider.Retention{})
+
+	g.collect(now)
+
+	if len(calls) != 1 || calls[0] != retention {
+		t.Fatalf("expected one collection with %v but got %v", retention, calls)
+	}
+	for reason, exp := range map[string]float64{"max_age": 2, "max_size": 1} {
+		var m dto.Metric
+		if err := numEvictedEntries.WithLabelValues("gc_test", reason).Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		if v := m.GetCounter().GetValue(); v != exp {
+			t.Errorf("expected %v entries evicted by %s but got %v", exp, reason, v)
+		}
+	}
+}
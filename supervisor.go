@@ -0,0 +1,246 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+// SubsystemState is the lifecycle state of a subsystem.
+type SubsystemState string
+
+// Possible subsystem states.
+const (
+	SubsystemStopped SubsystemState = "stopped"
+	SubsystemRunning SubsystemState = "running"
+	SubsystemFailed  SubsystemState = "failed"
+)
+
+// Subsystem is a component whose lifecycle is managed by a Supervisor.
+type Subsystem struct {
+	Name string
+	// Names of the subsystems that must be running before the subsystem
+	// is started and must only be stopped after it.
+	Deps []string
+	// Start brings up the subsystem. It must not block.
+	Start func() error
+	// Stop shuts down the subsystem. It may be nil.
+	Stop func() error
+}
+
+// SubsystemStatus describes the health of a subsystem.
+type SubsystemStatus struct {
+	Name  string         `json:"name"`
+	State SubsystemState `json:"state"`
+	Error string         `json:"error,omitempty"`
+	Since time.Time      `json:"since"`
+}
+
+type subsystem struct {
+	*Subsystem
+	status SubsystemStatus
+	// Whether the subsystem was started and not stopped since. A started
+	// subsystem may have failed afterwards.
+	started bool
+}
+
+// A Supervisor starts and stops subsystems in the order of their
+// dependencies and tracks their health. All methods are goroutine-safe.
+type Supervisor struct {
+	mtx sync.Mutex
+	// Subsystems in an order in which each subsystem follows its
+	// dependencies.
+	order  []*subsystem
+	byName map[string]*subsystem
+	log    log.Logger
+}
+
+// NewSupervisor returns a new Supervisor without subsystems.
+func NewSupervisor() *Supervisor {
+	return &Supervisor{
+		byName: map[string]*subsystem{},
+		log:    log.With("component", "supervisor"),
+	}
+}
+
+// Add registers a subsystem. Its dependencies must have been added before.
+func (s *Supervisor) Add(ss *Subsystem) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.byName[ss.Name]; ok {
+		return fmt.Errorf("subsystem %q already exists", ss.Name)
+	}
+	for _, dep := range ss.Deps {
+		if _, ok := s.byName[dep]; !ok {
+			return fmt.Errorf("unknown dependency %q of subsystem %q", dep, ss.Name)
+		}
+	}
+	sub := &subsystem{
+		Subsystem: ss,
+		status: SubsystemStatus{
+			Name:  ss.Name,
+			State: SubsystemStopped,
+			Since: time.Now(),
+		},
+	}
+	s.order = append(s.order, sub)
+	s.byName[ss.Name] = sub
+
+	return nil
+}
+
+// Start starts all subsystems that are not running in dependency order.
+// If a subsystem fails to start, the subsystems started before are
+// stopped again.
+func (s *Supervisor) Start() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.start(s.order)
+}
+
+// Stop stops all running subsystems in reverse dependency order.
+func (s *Supervisor) Stop() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.stop(s.order)
+}
+
+// Restart stops the named subsystems along with all subsystems depending
+// on them and starts them again.
+func (s *Supervisor) Restart(names ...string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	affected := map[string]bool{}
+	for _, name := range names {
+		if _, ok := s.byName[name]; !ok {
+			return fmt.Errorf("unknown subsystem %q", name)
+		}
+		affected[name] = true
+	}
+
+	var subs []*subsystem
+	for _, sub := range s.order {
+		for _, dep := range sub.Deps {
+			if affected[dep] {
+				affected[sub.Name] = true
+			}
+		}
+		if affected[sub.Name] {
+			subs = append(subs, sub)
+		}
+	}
+
+	s.stop(subs)
+	return s.start(subs)
+}
+
+// Fail marks a running subsystem as failed, e.g. if it terminated
+// unexpectedly.
+func (s *Supervisor) Fail(name string, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	sub, ok := s.byName[name]
+	if !ok || !sub.started {
+		return
+	}
+	s.log.With("subsystem", name).Errorf("Subsystem failed: %s", err)
+	s.setStatus(sub, SubsystemFailed, err)
+}
+
+// Status returns the health of all subsystems in dependency order.
+func (s *Supervisor) Status() []SubsystemStatus {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	res := make([]SubsystemStatus, 0, len(s.order))
+	for _, sub := range s.order {
+		res = append(res, sub.status)
+	}
+	return res
+}
+
+func (s *Supervisor) setStatus(sub *subsystem, state SubsystemState, err error) {
+	sub.status.State = state
+	sub.status.Since = time.Now()
+	sub.status.Error = ""
+
+	if err != nil {
+		sub.status.Error = err.Error()
+	}
+}
+
+// start starts the given subsystems in order. It must be called with the
+// lock held.
+func (s *Supervisor) start(subs []*subsystem) error {
+	var started []*subsystem
+
+	for _, sub := range subs {
+		if sub.started {
+			continue
+		}
+		for _, dep := range sub.Deps {
+			if !s.byName[dep].started {
+				err := fmt.Errorf("dependency %q is not running", dep)
+				s.setStatus(sub, SubsystemFailed, err)
+				s.stop(started)
+				return fmt.Errorf("starting subsystem %q failed: %s", sub.Name, err)
+			}
+		}
+		s.log.With("subsystem", sub.Name).Debugln("Starting subsystem")
+
+		if err := sub.Start(); err != nil {
+			s.setStatus(sub, SubsystemFailed, err)
+			s.stop(started)
+			return fmt.Errorf("starting subsystem %q failed: %s", sub.Name, err)
+		}
+		s.setStatus(sub, SubsystemRunning, nil)
+		sub.started = true
+		started = append(started, sub)
+	}
+	return nil
+}
+
+// stop stops the given subsystems in reverse order. It must be called
+// with the lock held.
+func (s *Supervisor) stop(subs []*subsystem) {
+	for i := len(subs) - 1; i >= 0; i-- {
+		sub := subs[i]
+		if !sub.started {
+			continue
+		}
+		sub.started = false
+
+		s.log.With("subsystem", sub.Name).Debugln("Stopping subsystem")
+
+		var err error
+		if sub.Stop != nil {
+			err = sub.Stop()
+		}
+		if err != nil {
+			s.log.With("subsystem", sub.Name).Errorf("Stopping subsystem failed: %s", err)
+			s.setStatus(sub, SubsystemFailed, err)
+			continue
+		}
+		s.setStatus(sub, SubsystemStopped, nil)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSupervisor(t *testing.T) {
+	var (
+		events  []string
+		failing = map[string]bool{}
+	)
+	sub := func(name string, deps ...string) *Subsystem {
+		return &Subsystem{
+			Name: name,
+			Deps: deps,
+			Start: func() error {
+				if failing[name] {
+					return fmt.Errorf("failed")
+				}
+				events = append(events, "start "+name)
+				return nil
+			},
+			Stop: func() error {
+				events = append(events, "stop "+name)
+				return nil
+			},
+		}
+	}
+
+	s := NewSupervisor()
+	for _, ss := range []*Subsystem{
+		sub("storage"),
+		sub("inhibitor", "storage"),
+		sub("dispatcher", "storage", "inhibitor"),
+		sub("web", "storage"),
+	} {
+		if err := s.Add(ss); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Add(sub("cluster", "unknown")); err == nil {
+		t.Fatalf("expected error for unknown dependency")
+	}
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("start failed: %s", err)
+	}
+	// Restarting a subsystem restarts its dependents as well.
+	if err := s.Restart("inhibitor"); err != nil {
+		t.Fatalf("restart failed: %s", err)
+	}
+	s.Stop()
+
+	expected := []string{
+		"start storage", "start inhibitor", "start dispatcher", "start web",
+		"stop dispatcher", "stop inhibitor", "start inhibitor", "start dispatcher",
+		"stop web", "stop dispatcher", "stop inhibitor", "stop storage",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("unexpected events\n%v\nexpected\n%v", events, expected)
+	}
+
+	// A subsystem failing to start stops the ones started before.
+	events = nil
+	failing["web"] = true
+
+	if err := s.Start(); err == nil {
+		t.Fatalf("expected start to fail")
+	}
+	expected = []string{
+		"start storage", "start inhibitor", "start dispatcher",
+		"stop dispatcher", "stop inhibitor", "stop storage",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("unexpected events\n%v\nexpected\n%v", events, expected)
+	}
+
+	status := s.Status()
+	if st := status[3]; st.Name != "web" || st.State != SubsystemFailed || st.Error != "failed" {
+		t.Fatalf("unexpected status %+v", st)
+	}
+	for _, st := range status[:3] {
+		if st.State != SubsystemStopped {
+			t.Fatalf("unexpected status %+v", st)
+		}
+	}
+}
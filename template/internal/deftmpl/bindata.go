@@ -68,7 +68,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _templateDefaultTmpl = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xec\x1b\x6b\x73\xdb\x36\xf2\x3b\x7f\xc5\x96\x9d\x9b\xc6\x33\x92\x68\x3b\x6d\xa6\x7e\xc8\x37\x8a\x4c\xc7\x9a\x93\x25\x8f\x24\x27\xcd\x74\x3a\x19\x88\x84\x24\x24\x24\xc1\x12\xa0\x65\xb7\xd7\xff\x7e\xbb\x20\xf5\xa0\x45\xd9\x72\x26\x67\xeb\xae\x4e\xfa\x10\x96\xd8\x27\xf6\x05\x10\xfc\xf3\x4f\xf0\xf9\x48\x44\x1c\xec\x4f\x9f\x58\xc0\x13\x1d\xb2\x88\x8d\x79\x62\xc3\x5f\x7f\x35\x68\x7c\x91\x8d\x71\x22\x8f\x7c\x04\x5a\x6b\x51\xae\x7a\x6d\xc2\xc2\xe7\x35\xf7\x46\xf3\x24\x62\x01\x82\x10\xe2\x7c\xef\x98\x79\xea\x9f\x09\xf7\xb8\xb8\xe6\x49\x9d\x26\xf5\xf2\x41\x86\x93\x53\x2f\x92\x57\xe9\xf0\x33\xf7\x34\x91\xfd\x95\x50\xfa\x9a\xe9\x54\xc1\xbf\x41\xcb\xab\x38\x9e\xa1\x8a\x11\xf0\xdf\xe7\x0f\xed\x91\x48\x44\x34\x26\x9c\x43\xc2\x31\x5a\xa8\xda\x99\x81\x22\x6a\xc0\xa3\x65\x8e\xbf\x01\x4d\x7a\x97\xc8\x34\x6e\xb3\x21\x0f\x54\xad\x2f\x13\xcd\xfd\x4b\x26\x12\x55\x7b\xcf\x82\x94\x13\xc3\xcf\x52\x44\x60\x03\x51\x85\x8c\xe5\x58\xc3\x2b\xa2\x55\x6b\xca\x30\x94\x51\x86\xbc\x93\xc3\x96\xe8\xed\x20\xca\x2b\x44\x99\x0a\x3d\x29\x4e\x46\x0b\x84\xf2\x9a\x17\xb9\x77\x58\x88\x0c\x33\x33\x96\x71\x9f\x0b\xbe\x33\xff\xb5\x66\x6d\x7c\xae\xbc\x44\xc4\x5a\xc8\xc8\xbe\xc7\xc6\x9a\xdf\xe8\x6c\x1d\x3f\x05\x42\xe9\x7c\x6a\xc2\xa2\x31\x4a\x86\x83\x4c\xae\x43\x6b\x01\x5c\xb5\x13\x59\xa5\x6a\x0c\x49\xe2\xd3\xa8\x0e\x73\x05\x72\xc1\x32\xe6\x8d\x28\x92\xb8\x4e\x28\x53\x81\xe4\x12\xf8\xeb\xe8\xf6\x65\x9a\x78\xfc\x30\x5b\x4c\x1e\xf1\x84\x69\x99\x64\xee\x67\x95\x18\xaa\x60\x03\x15\x30\xef\x4b\x0d\x47\x2c\x0d\x74\x4d\x0b\x1d\xf0\xdc\x0a\x9a\x87\x71\xc0\x74\xd1\x17\x6b\xeb\x4c\x5e\xa4\x93\x2a\x0a\x81\xb0\x8c\x54\x31\xd0\x36\xa4\x37\x62\x41\x30\x44\xc0\x0a\xbd\x52\xf1\x89\x28\x3a\xce\x43\x13\x03\x11\x7d\xd9\x58\x82\x38\xe1\xe4\x2c\xf6\x66\xb3\x97\xe8\xdf\x6b\x00\x93\x36\x36\x94\x40\x78\x32\xc2\x98\xf9\x2c\x36\x95\x61\x45\xdc\xc2\xc2\x4f\x44\xec\x4d\x98\x5e\x98\x38\x91\xe1\xd7\x2f\xd7\x5d\x6a\x18\xc7\x0a\x51\x36\x77\xa5\x82\x6c\x31\x71\xf3\x53\x7d\x3b\xa7\xb7\x1a\xcf\x8f\x73\xcf\x55\x8a\x5e\x20\x78\xa4\xbf\x5e\xe3\x75\x14\x17\x95\xe0\xeb\x16\x7d\x95\xae\x88\x94\x66\x91\xc7\x55\x09\xdd\x95\x04\x76\x8f\x55\x65\xac\xc6\x3c\x12\xfc\x9b\x19\x75\x85\xa0\x32\x89\xe8\xf1\xea\x17\xc4\xe4\x21\x13\xc1\x82\xe4\xa2\x0a\x3e\x5a\xbe\x22\xa5\x89\x0e\x03\x22\x63\x1d\x7f\x77\xda\x6d\x0e\x3e\x5e\xba\x40\x20\xb8\xbc\x7a\xdb\x6e\x35\xc1\xae\x3a\xce\x87\xd7\x4d\xc7\x39\x1d\x9c\xc2\x2f\xe7\x83\x8b\x36\xec\xd5\x76\x61\x80\x49\x5a\x09\x32\x11\x0b\x1c\xc7\xed\x60\x21\x9a\x68\x1d\x1f\x3a\xce\x74\x3a\xad\x4d\x5f\xd7\x64\x32\x76\x06\x3d\xe7\x86\x68\xed\x11\x72\xfe\xb3\xaa\x97\x30\x6b\xbe\xf6\xed\x13\xe4\x5c\xad\x5a\x7d\x7d\x1b\x70\x60\x28\xad\x61\xe2\xf3\x04\x1b\x01\x1f\x28\xfe\x80\x48\x2b\xa4\x3d\xc6\x7a\x99\x0e\x6b\x9e\x0c\x1d\xd2\x61\x9c\x46\x8e\x21\xc7\xbc\x8c\x5e\xd5\xa8\x56\x9d\x99\x43\xa1\x05\x07\x13\x0e\x17\xad\x01\xb4\x85\xc7\x23\xc5\xe1\x15\x0e\x76\x2c\xab\x29\xe3\xdb\x44\x8c\x27\x58\xb0\xbd\x1d\xd8\xdf\xdd\xfb\x11\x2e\x32\x8a\x96\x75\xc9\x93\x50\x28\x85\x14\x41\x28\x98\xf0\x84\x0f\x6f\x61\x8c\x7c\xb0\x04\x55\x50\x20\xce\x41\x8e\x00\xe3\x3a\x19\xf3\x0a\xb6\x1d\x28\xf4\x2d\x60\xe7\xa1\x10\x41\x0e\x35\x13\x11\x75\x15\x0c\x3c\xe4\x61\xe1\x4c\x3d\x41\x32\x4a\x8e\xf4\x94\x25\x99\x86\x4c\x29\xe9\x09\x94\xd0\x07\x5f\x7a\x69\x88\xf1\x61\x0a\x1d\x8c\x44\x80\xc5\xfd\x95\x46\xa1\xed\x7e\x8e\x61\xef\x18\x26\x3e\x67\x81\x85\x25\x9f\x9e\xcd\x1e\x99\x06\x42\xa6\x1a\x12\xae\x74\x22\x8c\x15\x2a\x20\x22\x2f\x48\x7d\x92\x61\xf6\x38\x10\xa1\xc8\x39\x10\xba\x51\x5c\x59\x48\x14\x0b\x52\xc5\xc8\x59\x81\x50\xfa\x62\x44\xff\xe7\x46\xad\x38\x1d\x62\xe4\x4c\x2a\xe0\x0b\x22\x3d\x4c\x35\x02\x15\x01\x8d\x1d\x2b\xa4\x87\x23\x13\x50\x3c\x08\x2c\xa4\x20\x50\x6e\xa3\xeb\x42\x3a\x33\x87\x44\x8f\xc9\xa0\x3a\x37\x91\x22\xc8\x74\x82\xab\x5a\xd0\x44\x28\x6b\x94\x26\x11\xb2\xe4\x06\xc7\x97\x68\x32\xc3\x91\xbc\x99\x20\x34\x7d\x24\x83\x40\x4e\x49\x35\xcc\xfa\xbe\xc8\x7b\x06\xb3\xc8\x6c\x48\x7d\x93\x37\x5f\x57\x6c\x1e\x50\xd4\x4c\x04\x5a\x80\x78\xb1\xaa\xf9\x23\x35\xc1\xf2\x09\x43\x9e\x1b\x0c\xf9\xa2\x79\xd9\x92\x3a\x09\xb1\xa7\x24\xa3\x05\x0b\x20\xc6\x1e\x84\xf8\xdd\x55\xb3\x86\xfc\xcf\x5d\xe8\x77\xcf\x06\x1f\x1a\x3d\x17\x5a\x7d\xb8\xec\x75\xdf\xb7\x4e\xdd\x53\xb0\x1b\x7d\x1c\xdb\x15\xf8\xd0\x1a\x9c\x77\xaf\x06\x80\x33\x7a\x8d\xce\xe0\x23\x74\xcf\xa0\xd1\xf9\x08\xff\x6a\x75\x4e\x2b\xe0\xfe\x72\xd9\x73\xfb\x7d\xe8\xf6\xac\xd6\xc5\x65\xbb\xe5\x22\xac\xd5\x69\xb6\xaf\x4e\x5b\x9d\x77\xf0\x16\xf1\x3a\x5d\x74\xe1\x16\xfa\x2e\x12\x1d\x74\x81\x18\xe6\xa4\x5a\x6e\x9f\x88\x5d\xb8\xbd\xe6\x39\x0e\x1b\x6f\x5b\xed\xd6\xe0\x63\xc5\x3a\x6b\x0d\x3a\x44\xf3\xac\xdb\x83\x06\x5c\x36\x7a\x83\x56\xf3\xaa\xdd\xe8\x61\x60\xf7\x2e\xbb\x7d\x17\xd9\x9f\x22\xd9\x4e\xab\x73\xd6\x43\x2e\xee\x85\xdb\x19\xd4\x90\x2b\xc2\xc0\x7d\x8f\x03\xe8\x9f\x37\xda\x6d\x62\x65\x35\xae\x50\xfa\x1e\xc9\x07\xcd\xee\xe5\xc7\x5e\xeb\xdd\xf9\x00\xce\xbb\xed\x53\x17\x81\x6f\x5d\x94\xac\xf1\xb6\xed\x66\xac\x50\xa9\x66\xbb\xd1\xba\xa8\xc0\x69\xe3\xa2\xf1\xce\x35\x58\x5d\xa4\xd2\xb3\x68\x5a\x26\x1d\x7c\x38\x77\x09\x44\xfc\x1a\xf8\x4f\x73\xd0\xea\x76\x48\x8d\x66\xb7\x33\xe8\xe1\xb0\x82\x5a\xf6\x06\x73\xd4\x0f\xad\xbe\x5b\x81\x46\xaf\xd5\x27\x83\x9c\xf5\xba\x17\x15\x8b\xcc\x89\x18\x5d\x43\x04\xf1\x3a\x6e\x46\x85\x4c\x0d\x85\x15\xc1\x29\x34\xbe\xea\xbb\x73\x82\x70\xea\x36\xda\x48\xab\x4f\xc8\xa4\xe2\x6c\x72\xcd\xaa\x56\x31\x23\x99\x14\x78\x13\x06\x91\xaa\x97\x24\xb6\xbd\x83\x83\x83\x2c\x9f\xd9\x9b\x4d\x52\x94\xdc\xea\xf6\x48\x46\xba\x3a\x62\xa1\x08\x6e\x0f\xe1\x87\x73\x1e\x5c\x73\xf4\x44\x06\x1d\x9e\xf2\x1f\x2a\x30\x07\xa0\xaa\x09\xba\x1c\xba\x3f\x26\xb7\x2a\x36\x8d\x62\x74\x04\x43\x79\x53\x55\xe2\x0f\x74\xfe\x43\xfc\x9d\x60\x82\xac\x22\xe8\x08\x0c\x51\x7c\x80\x9d\xee\xde\x8f\x31\x02\x42\x4c\x4c\x22\x3a\x84\xdd\x23\xca\xad\x13\xce\xfc\xe7\xe4\x1f\x72\xcd\x80\x9a\xde\xba\x7d\x2d\xf8\x94\xa2\xc8\xa6\xe8\xd5\x98\xf4\xea\xf6\x54\xf8\x7a\x52\xf7\xf9\x35\x06\x64\xd5\x0c\x9e\xcf\x58\xe0\xcc\xc4\xa5\xc5\xac\xf2\xdf\x53\x71\x5d\xb7\x9b\x99\xa8\xd5\xc1\x6d\xcc\x97\x04\xa7\x0e\xc3\xa1\xc5\x3d\x32\x95\x40\x71\x5d\xbf\x1a\x9c\x55\x7f\x7e\x66\xf1\x4d\x87\xfd\x7c\xcb\x7d\x5f\x2f\x72\xec\x18\xe1\x4e\x2c\xeb\xd8\x21\xa7\xa4\x1f\x43\xe9\xdf\x82\x40\x14\x85\x39\x17\x25\xb6\xcd\x40\xdf\xd2\xef\x3c\xa2\x94\x37\xc1\xaa\x6e\x22\xca\xa5\xea\x7e\x31\x6b\xa1\x9f\x54\xc9\xea\x94\x0f\xbf\x08\x64\x64\x1e\x84\x52\x62\x4d\x21\xa4\xac\x36\x08\xa6\xb8\xbf\x98\x44\xbe\x61\xb0\xab\xcc\xff\x9c\x2a\x7d\x88\x15\x27\xe2\x47\xd8\x4a\x50\x65\x42\x92\xbb\xbb\xff\x38\xc2\xa2\x1c\xf1\xea\x1c\x54\x7b\xc3\xc3\x23\x30\x11\x90\x4d\x80\xef\x44\x48\xc1\x82\x1c\x50\x4e\xdc\xc1\x8c\x13\x99\x46\x7e\xd5\x93\x81\x4c\x0e\xe1\xfb\xd1\x1b\xfa\xbb\x6c\x7e\x88\x99\xef\x1b\xa9\xc8\x1b\x86\x63\x33\xb3\x6e\xe7\x33\x6d\xb2\xb7\x66\xc3\xa7\x76\x8f\x25\x95\x36\xd4\xa3\x54\x76\x80\x63\x9d\x3c\x63\x1e\x03\x20\x09\x9e\x38\x93\x5e\xe3\xf6\x00\x89\x04\x55\x74\xb1\x31\x4a\xa2\x65\x5c\x34\xd4\xb5\x79\x80\xd9\x48\xc6\xf6\x09\x06\x98\xbf\x10\x34\xcb\xac\xf6\x9b\xdd\xdd\x27\x0e\x95\x52\xa1\xb1\x8b\xc4\xac\x80\x6c\x87\x81\xf4\xbe\x14\x7c\x3b\x64\x37\xd5\xdc\x49\x50\xd8\xf8\xa6\xf0\xd0\x0b\x38\x4b\x88\xa1\x9e\x14\xe0\xeb\x02\x65\x6e\x1c\x60\xa9\x96\x77\x42\xa2\x60\x2d\x63\x28\x34\x95\x2f\xae\x9f\xda\xad\x8a\xfa\xde\x35\xce\xfd\x4a\xcc\xe4\xa6\x45\x36\xc1\x9c\xaf\x33\x59\x02\xcb\x13\x76\xe3\xf9\xec\xba\xbd\x9b\x8d\x55\xcc\xbc\xd9\xf8\x49\x15\xcd\x1f\x26\xcc\x17\xa9\x3a\x84\xd7\x06\x56\x92\x00\x46\xa3\x42\x16\xcb\xd0\x90\x08\xba\x82\x92\x81\xf0\xe1\x7b\x7e\x40\x7f\x8b\x89\x61\x34\x5a\xb2\xc5\x36\x64\x87\x85\x24\x4f\x97\x25\xde\xac\x0d\xb8\x82\x75\x0d\xca\x34\x2f\x35\x3f\xed\xa2\x91\x4d\x89\xca\xe7\xe3\x86\x4e\xf3\xa4\x6c\xbd\xcc\xbf\xbb\x66\x51\x56\xd7\xcd\x7d\xf3\xd3\xfe\x7e\xb3\xbc\x00\xed\x93\x5f\xdb\x90\xc7\x5b\xc6\x60\x79\xf5\x32\xdc\xf2\x88\x9c\xfd\x59\x1c\xd4\xcf\x4f\xe8\xc1\x1c\x98\xdc\x39\x6b\xcf\xe6\xec\xc0\x1e\x4e\x50\xf3\x03\x0f\xd4\x39\x81\xc5\x61\xf2\x9a\xc3\x7c\x3a\xf7\x00\x58\xe5\x9b\x1f\x2d\xd7\x97\x0f\x96\x61\x55\xbe\xfc\x6c\xa5\xb0\xfa\xf3\x24\x3c\x1f\x27\x2f\x7e\xba\x49\x35\x5b\x78\xcf\x5e\xe6\x3d\xf7\x39\xc7\xd6\x27\xbf\xb5\x66\xdf\x2e\x27\xd8\x76\x57\xc0\xe4\x33\x4b\x26\xf7\xb9\x43\xae\x06\xee\xdc\x12\x3e\xaa\xdb\x9b\x1c\xb3\x3e\xb1\x3f\xcc\xb2\xe6\xd9\xd9\x59\x9e\x7d\x7d\xee\xc9\xc4\x1c\xca\xcd\xf6\x07\x85\x1d\xc1\x3e\xed\x07\x0a\x89\x7b\x28\x03\xbf\x3c\x73\x7b\x69\xa2\x88\x7a\x2c\x45\x06\x98\x77\x14\x22\x32\x44\xf3\xc6\xe2\x4e\x86\xff\x89\x04\x33\xf4\xcc\x29\x2a\x66\xcc\x10\x69\xb2\x58\x68\xa4\xff\x07\x2f\xcd\xfa\xaf\x7f\xfc\x99\xfb\xac\xa4\x60\xaf\xcc\xc8\xc1\xc6\xca\x87\x59\x25\x9f\x03\xe7\xed\x1b\xd6\x97\x6c\x79\x4f\xde\x0b\x3e\xa5\x03\xb8\x07\x5f\x3b\x1c\x3b\xac\xd4\x87\xef\x24\xde\xf2\xf4\x9b\xfd\x29\xad\x20\xf9\xeb\xe0\x1d\xf4\xb8\x92\xaa\xf0\x12\xb2\xff\x9d\x90\x55\x3a\x91\xd1\xf8\xf9\x4c\xfb\xeb\xfa\xfb\x00\xbf\x41\x06\x38\x76\x32\x21\xbf\x81\xd7\x95\x34\x0c\xf9\x93\xd9\x4b\xef\x82\x24\x2f\x7e\xf8\xb7\xf1\xc3\xac\x37\x9d\xbb\xda\xf1\xf0\xf9\x96\x99\x0e\x12\xcb\x6c\xf4\xc0\x6d\x8f\xf5\x57\x32\x9e\x59\x99\xf5\x71\x97\x6b\x55\xa8\x05\x8b\x5b\x27\x59\x25\x78\x76\xcf\x58\x92\x68\x5b\xdc\xe3\x41\x8b\x3e\x78\x85\xe7\x7f\xd4\x59\x96\x3b\xcc\xbb\x77\x8a\x9e\xa9\xa1\x9c\xb5\x5b\x2b\x3d\x25\x76\x6d\x3c\xa1\xee\xaf\xe8\x4e\xd9\xad\x28\x6a\xa2\xb6\x2f\xc7\x7c\x5d\x35\xdd\xb0\xbd\xeb\x71\xec\x41\xaf\xb9\xbf\xa6\xc1\x7b\xe9\x0a\xb7\xa8\x1a\x6f\x9d\x67\xa2\x4c\x93\x2d\x94\x69\xeb\xec\xf4\x98\x08\xbe\xaf\x23\x7e\x09\xac\xff\xcf\x36\x77\x79\xbb\x35\x4b\xc8\x4b\x1b\xae\x19\xe8\x19\xb6\x5c\x73\x69\x5e\xbc\xf1\x6f\xe3\x8d\x2f\x9b\xae\x97\x4d\xd7\xcb\xa6\x6b\xdb\x9d\xe5\x65\xd3\xb5\x35\x2d\xdb\xba\x85\xc2\xd9\xf4\x3e\xee\xe4\x11\xaf\x42\xe7\x28\x0b\xc8\x93\x5f\xc5\x28\xdc\x4d\x5a\xba\x6a\xb2\x58\xe8\x83\x83\x83\xfb\xde\x70\x17\xdf\xec\xae\xbe\x92\xdc\x8e\xa6\x61\x9b\xda\x97\xa7\x6c\x5d\xf6\xd7\xb6\x2e\xa5\x2f\xd1\x1e\x5a\xf2\xa5\xde\xe6\xce\xc5\x86\xe2\x35\xac\xe5\x74\x55\xfc\xea\xf1\xe9\x1c\x62\x7f\x39\x5b\x19\x8d\x36\x4e\x55\xa8\x13\x0c\x6f\x37\x7b\x0f\xb7\x9a\x3b\x56\xee\x3b\xdc\xcd\x0c\xc7\x0e\x86\xf9\x49\xf6\x5f\xab\x98\x26\xb6\xad\xad\x5d\x73\xbf\x2e\x53\x71\x91\xbf\x8e\x1d\xba\xc6\x4a\x10\xba\x0f\x7c\x62\x59\xe5\x9f\x55\xc6\xa9\x9a\x48\xe4\xf8\x0d\xbe\x2a\x5c\x21\x55\xfc\xae\x2c\xff\xa2\x74\x4d\x23\x50\xfa\x09\xa9\xb5\xc1\xe1\x57\x7e\x19\x27\x83\x99\x2f\x35\xef\xff\xe4\x6a\xe5\x5d\x56\x41\x97\x87\x4f\xe8\x72\x7e\x33\xe8\xe6\x1c\x97\xb7\x72\x05\x9e\x1b\x58\x32\x4d\x82\xc7\x7f\xa8\xf5\x9f\x00\x00\x00\xff\xff\x42\x9e\x57\x2f\x33\x3d\x00\x00")
+var _templateDefaultTmpl = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xed\x5c\x7b\x73\xda\xb8\x16\xff\xdf\x9f\x42\xeb\xce\x9d\x6d\x3a\x3c\x92\xf4\x31\x1b\x12\x72\x87\x82\xd3\x30\x97\x40\x06\x48\xbb\x9d\xb6\xb3\x23\x6c\x01\x6a\xfd\x60\x2d\x39\x24\xdb\xee\x77\xbf\xe7\xc8\x06\x5b\x60\x12\x92\xdb\x4d\xe8\x2d\xdb\xed\x2e\x7a\x9d\x97\x8e\x7e\xe7\x48\x96\xfd\xf5\x2b\x71\xd8\x90\xfb\x8c\x98\x7f\xfc\x41\x5d\x16\x4a\x8f\xfa\x74\xc4\x42\x93\xfc\xfd\x77\x0d\xcb\x67\x71\xf9\xeb\x57\xc2\x7c\x07\x2a\x8d\xaf\xab\x86\x5c\x74\x5b\x38\x0a\xda\x4b\xd6\x95\x64\xa1\x4f\x5d\xa8\x82\x9a\xf2\x93\xb2\xea\x27\xfe\x1d\x32\x9b\xf1\x4b\x16\x56\xb1\x53\x37\x29\xc4\x63\x12\xea\x3a\x79\x11\x0d\x3e\x33\x5b\x26\x64\xf9\x90\x94\x1a\x7c\xc4\x84\x84\xf2\x87\x46\xf3\x8d\xd5\xeb\x7f\x22\x48\x2a\xae\x2d\xb5\xd9\x94\x7c\x23\x2e\xf3\xa1\x9d\xf8\x6c\x5a\xc8\x36\x9e\xf0\x90\xfb\xa3\xb4\x7d\xa8\xca\x5a\x97\x2e\x13\x81\x7b\xc9\x9c\xb4\x53\x38\xab\x51\x0a\x90\x51\x18\x44\x13\x81\xd2\xba\x82\xa1\x10\x38\xb8\x27\xa9\x8c\x04\x8c\x91\xc1\xc5\x64\x32\xd3\x07\x84\x65\x7f\xce\x1b\xcd\x98\x1b\x2a\x52\xc1\x31\xca\xb4\x62\x51\xa6\xb9\x19\x62\xad\xde\x20\xb7\x16\x1d\x00\xb3\x52\x2f\x08\x25\x73\xce\x29\x0f\x45\xe9\x2d\x75\x23\x86\x0c\x3f\x07\xdc\x27\x26\x41\xaa\x24\x66\x39\x92\xe4\x29\xd2\x2a\xd5\x03\xcf\x0b\xfc\x78\xf0\x4e\x52\x97\xa1\xb7\x03\x43\x9e\xc2\x90\x29\x97\x63\xbd\x33\x18\xc1\x0b\x2e\x99\xce\xbd\x4d\x3d\x60\x18\xcf\x6d\x1e\xf7\xb9\xe0\x3b\xf3\x5f\xcb\x3f\xf4\xa9\x75\x98\xb0\x43\x3e\x91\x3c\xf0\xcd\x1b\x3c\x40\xb2\x2b\x19\x7b\xd9\x1f\x2e\x17\x33\x4f\x08\xa9\x3f\x02\x11\xa1\x10\x0b\x58\x31\xd2\xca\x65\x83\xa1\x79\x8a\xca\xa2\xa8\x07\x96\xaa\x64\xae\x49\x22\x58\xcc\xbc\xe6\xfb\x01\x4c\x18\xc8\xa4\x91\xcc\x54\xdf\x8f\x6e\x2f\x88\x42\x9b\x55\xe2\x59\x65\x3e\x0b\xa9\x0c\xc2\x78\x71\x18\x39\x86\xd2\x6c\x20\x5c\x6a\x7f\x29\x41\x89\x46\xae\x2c\x49\x2e\x5d\x96\x58\x41\x32\x6f\xe2\x52\xa9\xaf\x94\xd2\x2a\x93\xeb\x74\x22\x81\x0b\xd4\xcb\x23\xa5\xc3\xc0\x9a\xf4\x86\xd4\x75\x07\x50\xb1\x44\x2f\x57\x7c\x24\x0a\x1e\x74\x5b\x47\x97\xfb\x5f\xd6\x96\x60\x12\x32\x74\x16\x73\xbd\xde\x19\xfa\x37\x1a\x40\x81\xda\x9a\x12\x70\x3b\xf0\x61\xf1\x7c\xe6\xeb\xca\xb0\x24\xae\x36\xf1\x63\x3e\xb1\xc7\x54\xa6\x26\x0e\x03\xef\xfe\xd3\xb5\x48\x0d\x16\xb4\x80\x21\xeb\xbb\x92\x26\xdb\x04\xb9\x39\x91\xbc\x9e\xd3\x5b\x5e\xcf\x77\x73\xcf\x65\x8a\xb6\xcb\x99\x2f\xef\xaf\xf1\x2a\x8a\x69\x9c\xba\xdf\xa4\x2f\xd3\xe5\xbe\x90\xd4\xb7\x99\xc8\xa1\xbb\x04\x60\x37\x58\x35\x98\x88\x11\xf3\x39\xfb\x6e\x46\x5d\x22\x28\x14\x10\xdd\x5d\x7d\x4d\x4c\xc9\xa8\x27\xbe\x03\x22\x2d\xd0\xb9\x69\xf1\xea\x5d\x95\xb0\x1a\xe3\x19\xea\xab\x16\x3f\x46\xe3\xf5\x09\xa5\x9c\x6f\x05\xfc\x67\xcf\x32\x78\xff\xec\x59\x45\x07\xfc\x5b\xc1\x5c\x32\x97\x8d\x42\xea\xe5\x2d\xc3\x67\x1f\xff\xa9\x74\xe2\xe3\x5d\xf3\x89\x6f\xc4\xa3\xe1\x17\x27\x98\xfa\x6f\xf7\x51\xb2\x6c\x2c\x54\xac\x50\xb1\x67\xb9\x76\x5f\x1c\x4a\x3e\x3e\xd5\xb4\xd2\x5a\x3f\xee\xac\x15\x65\xe7\x26\x5f\x18\x9e\xb5\xfe\x42\x93\x71\x53\x22\xa2\x4d\xc9\x67\x1e\xd2\x74\x7d\x44\x1e\x90\xb9\xbe\x97\x3b\x6b\x84\x96\x57\x6e\x36\x3b\xd3\xe6\x6b\x87\xec\x42\x8f\xf1\xf3\x12\x49\xe6\x2f\x4e\x96\x8d\x5b\x81\x44\x9f\x75\x5d\xd3\x1c\x6e\xb3\xec\x36\xe5\xd7\xd5\xb2\xdb\xf5\x39\xce\xc7\x65\x2d\xf1\xe1\x2d\x87\xf4\x1b\x1c\xa8\x96\x81\x92\x6f\xeb\xc0\xcc\xa7\x15\x33\xc3\x3c\xca\xdd\xcc\xd4\x64\xf7\x02\x77\x9b\x1a\x9d\xd2\x58\x7a\x2e\x92\x31\x8e\x7e\x69\x74\xea\xfd\xf7\xe7\x16\xc1\x2a\x72\x7e\xf1\xba\xd5\xac\x13\xb3\x58\x2e\xbf\x7b\x5e\x2f\x97\x1b\xfd\x06\xf9\xfd\xb4\x7f\xd6\x22\x7b\xa5\x5d\xd2\x07\x37\x15\x1c\x27\x94\xba\xe5\xb2\xd5\x86\x75\x32\x96\x72\x52\x29\x97\xa7\xd3\x69\x69\xfa\xbc\x14\x84\xa3\x72\xbf\x5b\xbe\x42\x5a\x7b\x38\x38\xf9\x59\x94\x99\x91\x25\x47\x3a\xe6\x31\x70\x2e\x16\x8d\x9e\xbc\x76\x19\xa1\x20\xad\x62\xe2\xb0\x90\xa3\x51\x31\xce\x13\x24\x2d\x80\xf6\x08\x12\xf4\x68\x50\xb2\x03\xaf\x8c\x3a\x8c\x22\xbf\xac\xc8\x51\x3b\xa6\x57\x54\xaa\x15\x67\xe6\x10\x60\xc1\xfe\x98\x91\xb3\x66\x9f\xb4\xb8\xcd\x7c\xd8\xa6\x3c\x85\xc2\x8e\x61\xd4\x83\xc9\x75\xc8\x47\x63\xf0\x0a\x7b\x87\xec\xef\xee\xbd\x20\x67\x31\x45\xc3\x38\x67\xa1\xc7\x85\x00\x8a\x84\x0b\x32\x66\x21\x1b\x5c\xc3\x66\x87\xfa\xb0\x08\x0b\x20\x10\x63\x24\x18\x12\xc8\x1f\xc2\x11\x2b\x00\x30\x81\xd0\xd7\x04\xb0\x49\xc0\x80\x60\x20\x29\xf7\x95\xdf\x12\x1b\x78\x18\xd0\x53\x8e\x81\x8c\x08\x86\x72\x4a\xc3\x58\x43\x2a\x44\x60\x73\x90\xd0\x21\x4e\x60\x47\x1e\xc4\x61\xb5\xd4\x61\x1b\xe6\x02\xf6\x3c\x95\x20\xb4\xd9\x4b\x46\x98\x3b\x8a\x89\xc3\xa8\x6b\x80\x43\x61\xdb\xac\x49\xed\x58\x82\x48\xe2\xce\x4c\x86\x5c\x59\xa1\x00\x5e\x67\xbb\x91\x83\x32\xcc\x9a\x5d\xee\xf1\x84\x03\x0e\x57\x8a\x0b\x03\x88\x42\xe2\x5b\x50\x72\x16\x88\x17\x38\x7c\x88\xff\x67\x4a\xad\x49\x34\x00\x37\x1f\x17\x88\xc3\x91\xf4\x20\x92\x50\x29\xb0\x52\xd9\xb1\x80\x7a\x94\x83\x90\x08\xe6\xba\x06\x50\xe0\x20\xb7\xd2\x35\x95\x4e\xf5\x41\xd1\x27\x68\x50\x99\x98\x48\x60\xcd\x74\x0c\xb3\xaa\x69\xc2\x85\x31\x8c\x42\x1f\x58\x32\x35\xc6\x09\xc0\x64\x8a\x23\x7a\x33\xd6\x60\xf7\x61\xe0\xba\xc1\x14\x55\x83\xec\xd2\xe1\xc9\xde\x44\x4d\x32\x1d\xe0\x46\xcd\x9e\xcf\x2b\xc0\x27\x88\x1a\x8b\x80\x13\x30\x49\x67\x35\x69\x12\x63\x48\xd3\xc9\x80\x25\x06\x03\xbe\x60\x5e\x9a\x51\x27\x44\xf6\x98\xcc\x48\x4e\x5d\x32\x01\x14\x46\x7e\x8b\x6a\x96\x80\xff\xa9\x45\x7a\x9d\x93\xfe\xbb\x5a\xd7\x22\xcd\x1e\x39\xef\x76\xde\x36\x1b\x56\x83\x98\xb5\x1e\x94\xcd\x02\x79\xd7\xec\x9f\x76\x2e\xfa\x04\x7a\x74\x6b\xed\xfe\x7b\xd2\x39\x21\xb5\xf6\x7b\xf2\x9f\x66\xbb\x51\x20\xd6\xef\xe7\x5d\xab\xd7\x23\x9d\xae\xd1\x3c\x3b\x6f\x35\x2d\xa8\x6b\xb6\xeb\xad\x8b\x46\xb3\xfd\x86\xbc\x86\x71\xed\x0e\xb8\x70\x13\x7c\x17\x88\xf6\x3b\x04\x19\x26\xa4\x9a\x56\x0f\x89\x9d\x59\xdd\xfa\x29\x14\x6b\xaf\x9b\xad\x66\xff\x7d\xc1\x38\x69\xf6\xdb\x48\xf3\xa4\xd3\x25\x35\x72\x5e\xeb\xf6\x9b\xf5\x8b\x56\xad\x0b\x0b\xbb\x7b\xde\xe9\x59\xc0\xbe\x01\x64\xdb\xcd\xf6\x49\x17\xb8\x58\x67\x56\xbb\x5f\x02\xae\x50\x47\xac\xb7\x50\x20\xbd\xd3\x5a\xab\x85\xac\x8c\xda\x05\x48\xdf\x45\xf9\x48\xbd\x73\xfe\xbe\xdb\x7c\x73\xda\x27\xa7\x9d\x56\xc3\x82\xca\xd7\x16\x48\x56\x7b\xdd\xb2\x62\x56\xa0\x54\xbd\x55\x6b\x9e\x15\x48\xa3\x76\x56\x7b\x63\xa9\x51\x1d\xa0\xd2\x35\xb0\x5b\x2c\x1d\x79\x77\x6a\x61\x15\xf2\xab\xc1\xbf\xf5\x7e\xb3\xd3\x46\x35\xea\x9d\x76\xbf\x0b\xc5\x02\x68\xd9\xed\xcf\x87\xbe\x6b\xf6\xac\x02\xa9\x75\x9b\x3d\x34\xc8\x49\xb7\x73\x56\x30\xd0\x9c\x30\xa2\xa3\x88\xc0\xb8\xb6\x15\x53\x41\x53\x13\x6d\x46\xa0\x0b\x96\x2f\x7a\xd6\x9c\x20\x69\x58\xb5\x16\xd0\xea\xe1\x60\x54\x71\xd6\xb9\x64\x14\x8b\x80\x48\x0a\x02\xaf\x3c\xd7\x17\xd5\x1c\x60\xdb\x3b\x38\x38\x88\xf1\xcc\x5c\xaf\x93\x40\x70\xab\x9a\xc3\xc0\x97\xc5\x21\xf5\xb8\x7b\x5d\x21\xbf\x9e\x32\x08\x1b\xe0\x89\x94\xb4\x59\xc4\x7e\x2d\x90\x79\x05\xa8\x1a\x82\xcb\x81\xfb\x03\xb8\x15\x61\x73\xca\x87\x87\x64\x10\x5c\x15\x05\xff\x0b\x9c\xbf\x02\xbf\x43\x00\xc8\x22\x54\x1d\x12\x45\x14\x1a\x60\x47\xbd\xf7\x62\x02\x15\x10\xb3\x47\xdc\xaf\x90\xdd\x43\xc4\xd6\x31\xa3\xce\x63\xf2\xf7\x98\xa4\x04\x93\xa1\xaa\x79\x09\x31\x11\x57\x91\x89\xab\x57\x02\xe8\x55\xcd\x29\x77\xe4\xb8\xea\xb0\x4b\x58\x90\x45\x55\x78\x3c\x63\x91\xf2\x4c\x5c\x9c\xcc\x22\xfb\x33\xe2\x97\x55\xb3\x1e\x8b\x5a\xec\x5f\x4f\x58\x46\x70\x4c\x07\xca\x38\xb9\x87\x2a\x12\x08\x26\xab\x17\xfd\x93\xe2\x6f\x8f\x2c\xbe\xda\x06\x3c\xde\x74\xdf\x94\x8b\x1c\x95\x95\x70\xc7\x86\x71\x54\x46\xa7\xc4\x1f\x83\xc0\xb9\x26\x1c\x86\x08\xc0\x5c\x90\xd8\x54\x05\x79\x8d\xbf\x93\x15\x25\xec\x31\x44\x75\xb5\xa2\x2c\x8c\xee\x67\xb3\x3d\xc2\x83\x2a\x59\x9c\xb2\xc1\x17\x0e\x8c\x54\x83\x17\x04\x10\x53\x70\x50\x1c\x1b\x38\x15\xcc\x49\x3b\xa1\x6f\xa8\xd1\x45\xea\x7c\x8e\x84\xac\x40\xc4\xf1\xd9\x21\xa4\x12\x18\x99\x80\xe4\xee\xee\xbf\x0e\x21\x28\xfb\xac\x38\xaf\x2a\xbd\x62\xde\x21\x51\x2b\x20\xee\x40\x7e\xe1\x1e\x2e\x16\xe0\x00\x72\x52\xfb\x0b\x1e\xb7\xfa\x4e\xd1\x0e\xdc\x20\xac\x90\x27\xc3\x57\xf8\x27\x6b\x7e\x32\xa1\x8e\xa3\xa4\x42\x6f\x18\x8c\x54\xcf\xaa\x99\xf4\x34\xd1\xde\x92\x0e\x1e\xda\x3d\x32\x2a\xad\xa9\x47\xae\xec\x84\x1c\xc9\xf0\x11\x71\x8c\x10\x94\xe0\x81\x91\xf4\x12\xf6\x07\x40\xc4\x2d\x82\x8b\x8d\x40\x12\x19\x4c\x74\x43\x5d\xaa\x06\x40\xa3\x60\x62\x1e\xc3\x02\x73\x52\x41\x63\x64\x35\x5f\xed\xee\x9a\x1b\x20\x34\x64\x91\x80\x0a\xc0\x76\xe0\x06\xf6\x17\xcd\xb7\x3d\x7a\x55\x4c\x9c\x04\x84\x9d\x5c\x69\x8d\xb6\xcb\x68\x88\x0c\xe5\x58\xab\x5f\xb5\x50\xe6\xc6\x21\x34\x92\xc1\xc2\x92\xd0\xac\xa5\x0c\x05\xa6\x72\xf8\xe5\x43\xbb\x95\xae\xef\xa2\x71\x6e\x56\x62\x26\x37\x4e\xb2\x5a\xcc\xc9\x3c\xa3\x25\x20\x3c\x41\x36\x9e\xf4\xae\x9a\xbb\x71\x59\x4c\xa8\x3d\x2b\x3f\xa8\xa2\x49\x63\x48\x1d\x1e\x89\x0a\x79\xae\xea\x72\x00\x60\x38\xd4\x50\x2c\x1e\x06\x44\xc0\x15\x60\x67\xcd\x1d\xf2\x84\x1d\xe0\x1f\x1d\x18\x86\xc3\x8c\x2d\x36\x01\x1d\x52\x49\x1e\x0e\x25\x5e\xad\x5c\x70\x9a\x75\xd5\x90\x69\x12\x6a\x5e\xee\x82\x91\x55\x88\x4a\xfa\xc3\x86\x4e\xb2\x30\x6f\xbe\xd4\xdf\x5d\x35\x29\xcb\xf3\x66\xbd\x7a\xb9\xbf\x5f\xcf\x0f\x40\xfb\xe8\xd7\x26\x49\xd6\x5b\xcc\x20\x3b\x7b\xf1\xd8\xfc\x15\x39\xfb\x67\xf1\x19\xe7\x42\x73\x52\x0f\x9b\xb1\xf4\x99\x65\xfa\xa8\x32\xf3\x84\x72\xe1\xfc\x27\xee\xb9\x43\xf6\xa0\x9b\x48\xcf\x47\x96\x78\x27\x0f\x35\x97\x1b\x66\x87\x7e\x3a\xaf\xdc\x53\xa6\x45\x2e\x30\x11\x21\x49\xcf\xf8\x56\x1c\x41\x2e\x73\x25\xd9\xe7\x6a\xd5\xec\x21\x2b\xc9\x11\x7c\x95\x42\xcb\xf5\x69\xc4\x98\x97\xc3\xed\xa2\x5a\x27\xf4\xa6\xae\xbe\x17\xbb\xfa\x4d\x9e\xbc\xf1\x48\xbd\xd2\xec\x9b\xe5\x04\x9b\xee\x0a\x80\x94\x33\xe4\xbb\xc9\x1d\x12\x35\x60\x9b\x19\xb2\x61\xd5\x5c\xe7\x50\xf8\x81\xfd\x61\x06\xf1\x27\x27\x27\x49\xa8\x70\x98\x1d\x84\xea\x04\x71\xb6\x99\xd1\xb6\x2f\xfb\xb8\x79\xd1\xa2\xcc\x20\x70\x9d\xfc\x30\x63\x47\xa1\x40\xea\x93\x80\xc7\x15\xf3\xf4\x87\xfb\x8a\x68\x92\x05\x2d\x84\xa3\x97\x28\x98\xa2\xa7\x8e\x7c\x01\x49\x3d\xa0\x49\x27\x5c\x02\xfd\xbf\x58\x6e\x88\x7a\xfe\xe2\x37\xe6\xd0\x9c\xec\x62\xa9\x47\x52\xad\xac\x5c\x89\xd3\x8e\x79\xe5\x3c\xd7\x84\x60\x18\x4f\xef\xf1\xec\x74\xff\xd6\x67\xb1\x47\x65\x9a\xeb\xc3\x0b\xc0\x9b\x0f\xbf\xd9\x38\xb8\x10\xbf\xf0\x76\x4f\xfc\xe8\x62\xbb\x5e\x1f\x68\xbd\x0a\x19\x06\xfe\xe8\xf1\x4c\xfb\x61\xc5\xdd\xae\x4f\x04\x4b\xf1\x65\xac\xa3\x72\x2c\xe5\xf1\xd1\x20\x7c\xd4\x83\xa8\x3c\x0b\xa6\x89\x4f\x46\x8b\xec\x83\xe6\xd5\xf9\xd0\xca\xf4\x67\x9e\xd7\x3c\xfd\xdf\x32\xb3\x9d\x47\x36\xd8\xaa\xbc\xed\x1e\x60\xb1\x82\x4e\x3e\x8c\x64\x1f\xba\x6e\x91\xe4\x27\x44\x92\x85\x5b\x12\x9f\x48\x4f\x72\xd7\x4d\xee\x83\xfe\x90\xa8\x92\xbd\x01\xb0\x05\x96\xc7\x02\x16\xfd\x7e\xc5\x16\x5a\x7e\x42\x68\x59\xba\x40\xfe\x29\xbd\x63\xf3\x23\x02\x8b\x7e\xd1\x67\x0b\x2d\x0f\x0e\x2d\x39\x17\xc5\xb6\xc0\xf2\x33\x01\x4b\xfe\xcd\xce\x4f\xc9\x55\xc1\x39\x9a\xfc\x93\xfe\xa8\x5d\xfc\x4c\x73\x8d\xad\x1f\xfe\x24\x7e\x18\x43\xfd\x0f\x12\xb8\x6e\x8b\x4b\x0b\xaf\xe8\x6c\x70\x3c\x59\x8e\x05\xe9\xfd\xe8\x38\x12\x3c\xba\x67\x64\x24\xda\x14\xf7\xb8\xd5\xa2\xeb\x5f\x36\xff\xb1\x9c\x25\x7b\xb8\xbe\xf8\x8e\xd9\x23\x9d\xa5\xcf\x4e\x9a\x97\x8e\xd3\x23\x1f\x06\xe3\xc1\xb7\xee\x4e\xf1\x5b\x72\x78\x7e\xbc\x79\x18\x73\xbf\x68\xba\x66\x7a\x77\xcb\xce\x71\x9b\x15\x6e\x50\x34\xde\xc0\xe8\x77\x34\xde\x40\x99\x7e\xe8\x15\x7c\x53\x46\xbc\x5d\x58\xff\xff\xdb\xad\x1b\xce\x71\x1e\x61\xcb\x95\x39\x85\xd9\x7a\xe3\x76\xd3\xb5\xdd\x74\x6d\x37\x5d\xdb\x4d\xd7\x76\xd3\xb5\xdd\x74\xad\x13\x4f\xa1\x37\x5e\x45\x3c\xbe\xc3\x2d\xd0\xf9\x90\xb4\xe6\xc1\xaf\xcc\x6b\xef\x90\x64\x5e\x09\x48\x27\xfa\xe0\xe0\xe0\xa6\x9b\xc8\xfa\xa5\xd6\xe5\xdb\x98\x9b\x72\xc9\x75\x73\xd2\x97\x87\x4c\x5d\xf6\x57\xa6\x2e\xb9\xf7\x07\x6f\x9b\xf2\x4c\x6e\xb3\x70\x01\x5d\x7f\x5d\x26\x0b\x57\xfa\x37\xba\xcc\x87\x55\x5d\xd3\x68\x6d\xa8\x02\x9d\xc8\xe0\x7a\xbd\x2b\x88\xcb\xd8\xb1\x74\xd5\x7b\x11\x19\x8e\xca\xb0\xcc\x8f\xe3\xff\x1a\x3a\x4c\xfc\x20\xef\x41\xc5\x2a\xa6\xf8\x75\x54\xc6\xd7\x0d\xb1\x06\xdf\xdb\x3c\xce\x7c\x9f\x44\xff\x9a\x4f\x24\xc6\x01\x70\xfc\x0e\xdf\x74\x59\x22\xa5\x7f\x67\x28\xf9\xd4\xd8\x8a\x44\x20\xf7\xdb\x62\xc6\x1a\x87\x5f\xc9\x13\xe5\xb8\xae\x72\xf7\x2f\x67\x18\x9a\x2e\xb7\x9f\xd0\x25\xfc\x66\xb5\x95\xfb\x7c\x39\x43\xe7\xb9\x86\x25\xa3\xd0\xbd\xfb\x87\x7b\xfe\x0b\x76\xf8\xdf\x85\xe1\x4f\x00\x00")
 
 func templateDefaultTmplBytes() ([]byte, error) {
 	return bindataRead(
@@ -83,7 +83,7 @@ func templateDefaultTmpl() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "template/default.tmpl", size: 15667, mode: os.FileMode(420), modTime: time.Unix(1461810859, 0)}
+	info := bindataFileInfo{name: "template/default.tmpl", size: 20449, mode: os.FileMode(420), modTime: time.Unix(1792157089, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
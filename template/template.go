@@ -38,6 +38,32 @@ type Template struct {
 	ExternalURL *url.URL
 }
 
+// SeverityStyle defines how notifications present alerts of a severity.
+type SeverityStyle struct {
+	Color    string
+	Emoji    string
+	Priority int
+}
+
+// SetSeverities sets the mapping of severity label values to styles used
+// by the severityColor, severityEmoji, and severityPriority template
+// functions. Severities without a style map to empty values.
+func (t *Template) SetSeverities(styles map[string]SeverityStyle) {
+	funcs := FuncMap{
+		"severityColor": func(severity string) string {
+			return styles[severity].Color
+		},
+		"severityEmoji": func(severity string) string {
+			return styles[severity].Emoji
+		},
+		"severityPriority": func(severity string) int {
+			return styles[severity].Priority
+		},
+	}
+	t.text = t.text.Funcs(tmpltext.FuncMap(funcs))
+	t.html = t.html.Funcs(tmplhtml.FuncMap(funcs))
+}
+
 // FromGlobs calls ParseGlob on all path globs provided and returns the
 // resulting Template.
 func FromGlobs(paths ...string) (*Template, error) {
@@ -80,6 +106,26 @@ func FromGlobs(paths ...string) (*Template, error) {
 	return t, nil
 }
 
+// Extend returns a copy of the template with the given text parsed into
+// it, which adds or replaces template definitions.
+func (t *Template) Extend(text string) (*Template, error) {
+	txt, err := t.text.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if txt, err = txt.New("").Parse(text); err != nil {
+		return nil, err
+	}
+	html, err := t.html.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if html, err = html.New("").Parse(text); err != nil {
+		return nil, err
+	}
+	return &Template{text: txt, html: html, ExternalURL: t.ExternalURL}, nil
+}
+
 // ExecuteTextString needs a meaningful doc comment (TODO(fabxc)).
 func (t *Template) ExecuteTextString(text string, data interface{}) (string, error) {
 	if text == "" {
@@ -130,6 +176,26 @@ var DefaultFuncs = FuncMap{
 	"safeHtml": func(text string) tmplhtml.HTML {
 		return tmplhtml.HTML(text)
 	},
+	"markdownV2": EscapeMarkdownV2,
+	// The severity functions are bound to the configured styles by
+	// SetSeverities.
+	"severityColor":    func(string) string { return "" },
+	"severityEmoji":    func(string) string { return "" },
+	"severityPriority": func(string) int { return 0 },
+}
+
+// markdownV2Replacer escapes all characters reserved in Telegram's
+// MarkdownV2 formatting.
+var markdownV2Replacer = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`,
+	"=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// EscapeMarkdownV2 escapes text so it is displayed literally in a message
+// formatted as Telegram MarkdownV2.
+func EscapeMarkdownV2(text string) string {
+	return markdownV2Replacer.Replace(text)
 }
 
 // Pair is a key/value string pair.
@@ -224,8 +290,66 @@ type Data struct {
 	CommonAnnotations KV `json:"commonAnnotations"`
 
 	ExternalURL string `json:"externalURL"`
+
+	Route Route `json:"route"`
+	// Number of escalation steps of the route that were reached by the
+	// group. Zero if the route's own receiver is notified.
+	EscalationLevel int `json:"escalationLevel"`
+
+	// Alert groups summarized by a digest notification. Empty for
+	// notifications about a single group.
+	Digest DigestGroups `json:"digest,omitempty"`
+}
+
+// Route holds information about the routing node that dispatched a
+// group of alerts to the receiver.
+type Route struct {
+	// Path of matchers from the root of the routing tree to the route.
+	Path           string   `json:"path"`
+	GroupBy        []string `json:"groupBy"`
+	GroupWait      string   `json:"groupWait"`
+	GroupInterval  string   `json:"groupInterval"`
+	RepeatInterval string   `json:"repeatInterval"`
+}
+
+// Statuses of alert groups in digest notifications.
+const (
+	DigestNew      = "new"
+	DigestFiring   = "firing"
+	DigestResolved = "resolved"
+)
+
+// DigestGroup holds an alert group summarized by a digest notification.
+type DigestGroup struct {
+	// Status is "new" for groups firing since the previous digest,
+	// "firing" for groups that were firing in it already, and "resolved".
+	Status      string `json:"status"`
+	GroupLabels KV     `json:"groupLabels"`
+	Alerts      Alerts `json:"alerts"`
 }
 
+// DigestGroups is a list of DigestGroup objects.
+type DigestGroups []DigestGroup
+
+func (gs DigestGroups) withStatus(status string) []DigestGroup {
+	res := []DigestGroup{}
+	for _, g := range gs {
+		if g.Status == status {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+// New returns the groups that started firing since the previous digest.
+func (gs DigestGroups) New() []DigestGroup { return gs.withStatus(DigestNew) }
+
+// Firing returns the groups that are still firing since the previous digest.
+func (gs DigestGroups) Firing() []DigestGroup { return gs.withStatus(DigestFiring) }
+
+// Resolved returns the groups that were resolved.
+func (gs DigestGroups) Resolved() []DigestGroup { return gs.withStatus(DigestResolved) }
+
 // Alert holds one alert for notification templates.
 type Alert struct {
 	Status       string    `json:"status"`
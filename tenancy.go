@@ -0,0 +1,240 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// maxRateLimiters is the number of tenants whose request rate is tracked
+// above which the limiters of idle tenants are dropped.
+const maxRateLimiters = 10000
+
+// Tenants determines the tenant API requests are made for and limits the
+// rate of each tenant's requests. All methods are goroutine-safe.
+type Tenants struct {
+	auth *Authenticator
+
+	mtx      sync.Mutex
+	conf     *config.Tenancy
+	limiters map[string]*rateLimiter
+
+	// now is an indirection for testing.
+	now func() time.Time
+}
+
+// NewTenants returns new Tenants identifying the users of requests with
+// the authenticator. Until it is configured, requests are not scoped.
+func NewTenants(a *Authenticator) *Tenants {
+	return &Tenants{
+		auth:     a,
+		limiters: map[string]*rateLimiter{},
+		now:      time.Now,
+	}
+}
+
+// SetConfig sets the tenant label and rate limits. If the configuration is
+// nil, requests are not scoped.
+func (t *Tenants) SetConfig(conf *config.Tenancy) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.conf = conf
+	t.limiters = map[string]*rateLimiter{}
+}
+
+func (t *Tenants) config() *config.Tenancy {
+	if t == nil {
+		return nil
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	return t.conf
+}
+
+// tenant returns the tenant of the request, which is the tenant of the
+// authenticated user or, for users without a tenant, the one given in the
+// configured header.
+func (t *Tenants) tenant(conf *config.Tenancy, r *http.Request) string {
+	if u, _, _ := t.auth.identify(r); u != nil && u.Tenant != "" {
+		return u.Tenant
+	}
+	if conf.Header != "" {
+		return r.Header.Get(conf.Header)
+	}
+	return ""
+}
+
+// Matcher returns a matcher selecting the alerts of the tenant the request
+// is made for. It is nil if the request is not scoped to a tenant.
+func (t *Tenants) Matcher(r *http.Request) *types.Matcher {
+	conf := t.config()
+	if conf == nil {
+		return nil
+	}
+	if tenant := t.tenant(conf, r); tenant != "" {
+		return types.NewMatcher(conf.Label, tenant)
+	}
+	return nil
+}
+
+// Handler returns a handler calling h for requests within their tenant's
+// rate limit. If authentication is configured, only admins may make
+// requests without a tenant. A nil Tenants allows all requests.
+func (t *Tenants) Handler(name string, h http.HandlerFunc) http.HandlerFunc {
+	if t == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		conf := t.config()
+		if conf == nil || signedEndpoints[name] {
+			h(w, r)
+			return
+		}
+
+		tenant := t.tenant(conf, r)
+		if tenant == "" {
+			if _, role, ok := t.auth.identify(r); ok && role != config.APIRoleAdmin {
+				respondError(w, apiError{
+					typ: errorForbidden,
+					err: fmt.Errorf("tenant required"),
+				}, nil)
+				return
+			}
+		} else if !t.allow(conf, tenant) {
+			respondError(w, apiError{
+				typ: errorTooManyRequests,
+				err: fmt.Errorf("rate limit of tenant %q exceeded", tenant),
+			}, nil)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// allow returns true if the tenant's request rate permits another request.
+func (t *Tenants) allow(conf *config.Tenancy, tenant string) bool {
+	if conf.RateLimit == 0 {
+		return true
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	now := t.now()
+
+	l, ok := t.limiters[tenant]
+	if !ok {
+		if len(t.limiters) >= maxRateLimiters {
+			for k, l := range t.limiters {
+				if l.full(now, conf.RateLimit, conf.RateBurst) {
+					delete(t.limiters, k)
+				}
+			}
+		}
+		l = &rateLimiter{tokens: float64(conf.RateBurst), last: now}
+		t.limiters[tenant] = l
+	}
+	return l.take(now, conf.RateLimit, conf.RateBurst)
+}
+
+// rateLimiter is a token bucket refilled at a constant rate.
+type rateLimiter struct {
+	tokens float64
+	last   time.Time
+}
+
+func (l *rateLimiter) refill(now time.Time, rate float64, burst int) {
+	l.tokens = math.Min(float64(burst), l.tokens+now.Sub(l.last).Seconds()*rate)
+	l.last = now
+}
+
+// take removes a token from the bucket and returns true if one was left.
+func (l *rateLimiter) take(now time.Time, rate float64, burst int) bool {
+	l.refill(now, rate, burst)
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// full returns true if the bucket has been refilled completely.
+func (l *rateLimiter) full(now time.Time, rate float64, burst int) bool {
+	l.refill(now, rate, burst)
+	return l.tokens >= float64(burst)
+}
+
+// ownsSilence returns true if the silence only mutes alerts of the tenant
+// selected by the matcher.
+func ownsSilence(m *types.Matcher, sil *types.Silence) bool {
+	return ownsMatchers(m, sil.Silence.Matchers)
+}
+
+// scopeSilence restricts the silence to the alerts of the tenant selected
+// by the matcher. Silences with other matchers on the tenant label are
+// rejected.
+func scopeSilence(m *types.Matcher, sil *types.Silence) error {
+	ms, err := scopeMatchers(m, sil.Silence.Matchers)
+	if err != nil {
+		return fmt.Errorf("silence %s", err)
+	}
+	sil.Silence.Matchers = ms
+	return nil
+}
+
+// ownsAlerts returns true if there are alerts and all of them are of the
+// tenant selected by the matcher.
+func ownsAlerts(m *types.Matcher, alerts []*types.Alert) bool {
+	for _, a := range alerts {
+		if !m.Match(a.Labels) {
+			return false
+		}
+	}
+	return len(alerts) > 0
+}
+
+// ownsMatchers returns true if the matchers only select alerts of the
+// tenant selected by m.
+func ownsMatchers(m *types.Matcher, ms []*model.Matcher) bool {
+	for _, sm := range ms {
+		if sm.Name == m.Name && !sm.IsRegex && sm.Value == m.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeMatchers restricts the matchers to the alerts of the tenant
+// selected by m. Matchers with others on the tenant label are rejected.
+func scopeMatchers(m *types.Matcher, ms []*model.Matcher) ([]*model.Matcher, error) {
+	if ownsMatchers(m, ms) {
+		return ms, nil
+	}
+	for _, sm := range ms {
+		if sm.Name == m.Name {
+			return nil, fmt.Errorf("must not match alerts of other tenants")
+		}
+	}
+	return append(ms, &model.Matcher{Name: m.Name, Value: m.Value}), nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestTenantsHandler(t *testing.T) {
+	a := NewAuthenticator()
+	a.SetConfig(&config.APIAuth{
+		AnonymousRole: config.APIRoleRead,
+		Users: []*config.APIUser{
+			{Name: "team-a", Role: config.APIRoleSilence, Password: "secret", Tenant: "a"},
+			{Name: "admin", Role: config.APIRoleAdmin, Password: "secret"},
+		},
+	})
+	now := time.Now()
+	tenants := NewTenants(a)
+	tenants.now = func() time.Time { return now }
+	tenants.SetConfig(&config.Tenancy{Label: "tenant", Header: "X-Tenant", RateLimit: 1, RateBurst: 2})
+
+	cases := []struct {
+		prepare func(r *http.Request)
+		status  int
+		tenant  string
+	}{
+		{
+			prepare: func(r *http.Request) { r.SetBasicAuth("team-a", "secret") },
+			status:  http.StatusOK,
+			tenant:  "a",
+		},
+		{
+			// The user's tenant takes precedence over the header.
+			prepare: func(r *http.Request) {
+				r.SetBasicAuth("team-a", "secret")
+				r.Header.Set("X-Tenant", "b")
+			},
+			status: http.StatusOK,
+			tenant: "a",
+		},
+		{
+			// The burst of tenant a is exhausted.
+			prepare: func(r *http.Request) { r.SetBasicAuth("team-a", "secret") },
+			status:  http.StatusTooManyRequests,
+		},
+		{
+			prepare: func(r *http.Request) { r.Header.Set("X-Tenant", "b") },
+			status:  http.StatusOK,
+			tenant:  "b",
+		},
+		{
+			prepare: func(r *http.Request) {},
+			status:  http.StatusForbidden,
+		},
+		{
+			prepare: func(r *http.Request) { r.SetBasicAuth("admin", "secret") },
+			status:  http.StatusOK,
+		},
+	}
+
+	for i, c := range cases {
+		r, err := http.NewRequest("GET", "http://localhost/api/v1/alerts", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.prepare(r)
+
+		var m *types.Matcher
+		w := httptest.NewRecorder()
+		tenants.Handler("list_alerts", func(w http.ResponseWriter, r *http.Request) {
+			m = tenants.Matcher(r)
+		})(w, r)
+
+		if w.Code != c.status {
+			t.Fatalf("%d. expected status %d but got %d", i, c.status, w.Code)
+		}
+		if c.tenant == "" {
+			if m != nil {
+				t.Errorf("%d. expected unscoped request but got %s", i, m)
+			}
+			continue
+		}
+		if m == nil || m.Name != "tenant" || m.Value != c.tenant {
+			t.Errorf("%d. expected request scoped to tenant %q but got %v", i, c.tenant, m)
+		}
+	}
+
+	// Tokens are refilled over time.
+	now = now.Add(time.Second)
+	r, _ := http.NewRequest("GET", "http://localhost/api/v1/alerts", nil)
+	r.SetBasicAuth("team-a", "secret")
+	w := httptest.NewRecorder()
+	tenants.Handler("list_alerts", func(w http.ResponseWriter, r *http.Request) {})(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d after refill but got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestScopeSilence(t *testing.T) {
+	m := types.NewMatcher("tenant", "a")
+
+	sil := types.NewSilence(&model.Silence{
+		Matchers: []*model.Matcher{{Name: "alertname", Value: "HighLatency"}},
+	})
+	if ownsSilence(m, sil) {
+		t.Fatalf("expected silence without tenant matcher not to be owned")
+	}
+	if err := scopeSilence(m, sil); err != nil {
+		t.Fatal(err)
+	}
+	if !ownsSilence(m, sil) {
+		t.Fatalf("expected scoped silence to be owned")
+	}
+	if err := scopeSilence(m, sil); err != nil || len(sil.Silence.Matchers) != 2 {
+		t.Errorf("expected owned silence to be left unchanged but got %v", sil.Silence.Matchers)
+	}
+
+	for _, sm := range []*model.Matcher{
+		{Name: "tenant", Value: "b"},
+		{Name: "tenant", Value: "a|b", IsRegex: true},
+	} {
+		sil := types.NewSilence(&model.Silence{Matchers: []*model.Matcher{sm}})
+		if err := scopeSilence(m, sil); err == nil {
+			t.Errorf("expected silence with matcher %v to be rejected", *sm)
+		}
+	}
+}
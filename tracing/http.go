@@ -0,0 +1,103 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// Do is like ctxhttp.Do but records a client span and propagates the
+// trace context to the server.
+func Do(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx, span := StartKind(ctx, "HTTP "+req.Method, SpanKindClient,
+		String("http.method", req.Method),
+		// The URL is left out as webhook URLs commonly embed credentials.
+		String("http.host", req.URL.Host),
+	)
+	defer span.End()
+
+	Inject(ctx, req.Header)
+
+	resp, err := ctxhttp.Do(ctx, client, req)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	span.SetAttributes(Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetError(fmt.Errorf("unexpected status code %v", resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// Get is like ctxhttp.Get but records a client span and propagates the
+// trace context to the server.
+func Get(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Do(ctx, client, req)
+}
+
+// Post is like ctxhttp.Post but records a client span and propagates the
+// trace context to the server.
+func Post(ctx context.Context, client *http.Client, url string, bodyType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", bodyType)
+	return Do(ctx, client, req)
+}
+
+// statusRecorder records the status code written to a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// InstrumentHandlerFunc records a server span named after the handler
+// for each request, continuing the trace of the client.
+func InstrumentHandlerFunc(name string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if currentTracer() == nil {
+			h(w, r)
+			return
+		}
+		_, span := StartKind(Extract(context.Background(), r.Header), name, SpanKindServer,
+			String("http.method", r.Method),
+			String("http.target", r.URL.Path),
+		)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		h(rec, r)
+
+		span.SetAttributes(Int("http.status_code", rec.code))
+		if rec.code >= 500 {
+			span.SetError(fmt.Errorf("status code %v", rec.code))
+		}
+	}
+}
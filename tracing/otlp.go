@@ -0,0 +1,281 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const (
+	// maxQueuedSpans is the number of finished spans buffered for export.
+	// Further spans are dropped until the queue is drained.
+	maxQueuedSpans = 2048
+	// maxBatchSpans is the maximum number of spans exported at once.
+	maxBatchSpans = 512
+	// exportInterval is the interval in which queued spans are exported.
+	exportInterval = 5 * time.Second
+	// exportTimeout limits the duration of a single export.
+	exportTimeout = 10 * time.Second
+)
+
+var numSpansDropped = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "tracing_spans_dropped_total",
+	Help:      "The total number of finished spans dropped because the export queue was full or the export failed.",
+})
+
+func init() {
+	prometheus.MustRegister(numSpansDropped)
+}
+
+// Tracer records spans and exports them to an OpenTelemetry collector
+// via OTLP/HTTP with JSON encoding.
+type Tracer struct {
+	url         string
+	service     string
+	sampleRatio float64
+
+	client *http.Client
+	rand   *randSource
+	spans  chan *Span
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewTracer returns a new tracer exporting spans of the given service to
+// the OTLP/HTTP traces endpoint at url, e.g.
+// http://otel-collector:4318/v1/traces. Traces started by the Alertmanager
+// are sampled with the given ratio; traces continued from other services
+// are sampled as decided by them.
+func NewTracer(url, service string, sampleRatio float64) *Tracer {
+	return &Tracer{
+		url:         url,
+		service:     service,
+		sampleRatio: sampleRatio,
+		client:      &http.Client{Timeout: exportTimeout},
+		rand:        newRandSource(),
+		spans:       make(chan *Span, maxQueuedSpans),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+func (t *Tracer) newTrace() (TraceID, bool) {
+	var id TraceID
+	t.rand.read(id[:])
+	return id, t.sampleRatio >= 1 || t.rand.float64() < t.sampleRatio
+}
+
+func (t *Tracer) newSpanID() SpanID {
+	var id SpanID
+	t.rand.read(id[:])
+	return id
+}
+
+func (t *Tracer) export(s *Span) {
+	select {
+	case t.spans <- s:
+	default:
+		numSpansDropped.Inc()
+	}
+}
+
+// Run exports finished spans until the tracer is stopped.
+func (t *Tracer) Run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.send(batch); err != nil {
+			log.Warnf("Exporting %d spans failed: %s", len(batch), err)
+			numSpansDropped.Add(float64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case s := <-t.spans:
+			if batch = append(batch, s); len(batch) >= maxBatchSpans {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stop:
+			for {
+				select {
+				case s := <-t.spans:
+					batch = append(batch, s)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Stop exports the remaining spans and stops the tracer.
+func (t *Tracer) Stop() {
+	close(t.stop)
+	<-t.done
+}
+
+func (t *Tracer) send(spans []*Span) error {
+	b, err := json.Marshal(t.request(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Post(t.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// The following types are the OTLP/JSON encoding of an
+// ExportTraceServiceRequest. IDs are hex-encoded and 64 bit integers are
+// encoded as strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              SpanKind       `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            *otlpStatus    `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+)
+
+// otlpStatusError is the status code of failed spans.
+const otlpStatusError = 2
+
+func otlpAttributes(attrs []Attribute) []otlpKeyValue {
+	res := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		kv := otlpKeyValue{Key: a.Key}
+		switch v := a.Value.(type) {
+		case string:
+			kv.Value.StringValue = &v
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			kv.Value.IntValue = &s
+		case int:
+			s := strconv.Itoa(v)
+			kv.Value.IntValue = &s
+		case float64:
+			kv.Value.DoubleValue = &v
+		case bool:
+			kv.Value.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			kv.Value.StringValue = &s
+		}
+		res = append(res, kv)
+	}
+	return res
+}
+
+func (t *Tracer) request(spans []*Span) *otlpRequest {
+	ss := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.mtx.Lock()
+		v := otlpSpan{
+			TraceID:           s.sc.TraceID.String(),
+			SpanID:            s.sc.SpanID.String(),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.attrs),
+		}
+		if s.parent != (SpanID{}) {
+			v.ParentSpanID = s.parent.String()
+		}
+		if s.err != "" {
+			v.Status = &otlpStatus{Code: otlpStatusError, Message: s.err}
+		}
+		s.mtx.Unlock()
+
+		ss = append(ss, v)
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes([]Attribute{String("service.name", t.service)}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/prometheus/alertmanager"},
+				Spans: ss,
+			}},
+		}},
+	}
+}
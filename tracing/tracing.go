@@ -0,0 +1,317 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing records traces of alerts passing through the
+// Alertmanager. Spans follow the OpenTelemetry data model, are propagated
+// to and from other services through W3C trace context headers, and are
+// exported to an OpenTelemetry collector via OTLP/HTTP.
+package tracing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanContext is the part of a span that is propagated to its children,
+// including those in other services.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Sampled is true iff the trace is recorded.
+	Sampled bool
+}
+
+// IsValid returns true iff the span context identifies a span.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// SpanKind is the role of a span in a trace as defined by OpenTelemetry.
+type SpanKind int
+
+// Kinds of spans.
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// Attribute is a key-value pair describing a span. Values are strings,
+// ints, int64s, float64s, or bools.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(k, v string) Attribute { return Attribute{Key: k, Value: v} }
+
+// Int returns an integer attribute.
+func Int(k string, v int) Attribute { return Attribute{Key: k, Value: int64(v)} }
+
+// Bool returns a boolean attribute.
+func Bool(k string, v bool) Attribute { return Attribute{Key: k, Value: v} }
+
+// Span is a timed operation within a trace. The methods of a nil span do
+// nothing so that instrumented code need not check whether tracing is
+// enabled.
+type Span struct {
+	tracer *Tracer
+	name   string
+	kind   SpanKind
+	sc     SpanContext
+	parent SpanID
+	start  time.Time
+
+	mtx   sync.Mutex
+	end   time.Time
+	attrs []Attribute
+	err   string
+	ended bool
+}
+
+// SpanContext returns the span's context.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// IsRecording returns true iff the span is exported once it ends.
+// Attributes that are costly to compute should only be set on recording
+// spans.
+func (s *Span) IsRecording() bool {
+	return s != nil && s.sc.Sampled
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// SetError marks the span as failed with the given error if it is not nil.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.err = err.Error()
+}
+
+// End finishes the span and hands it to the tracer for export. Subsequent
+// calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	if s.ended {
+		s.mtx.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mtx.Unlock()
+
+	if s.sc.Sampled {
+		s.tracer.export(s)
+	}
+}
+
+type tracingKey int
+
+const (
+	keySpan tracingKey = iota
+	keyRemoteSpanContext
+)
+
+// SpanFromContext returns the span of the context or nil if there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(keySpan).(*Span)
+	return s
+}
+
+// SpanContextFromContext returns the context of the current span of the
+// context, which may belong to another service.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.sc
+	}
+	if ctx == nil {
+		return SpanContext{}
+	}
+	sc, _ := ctx.Value(keyRemoteSpanContext).(SpanContext)
+	return sc
+}
+
+// tracer holds the *Tracer spans are recorded with.
+var tracer atomic.Value
+
+// SetTracer sets the tracer spans are recorded with. Tracing is disabled
+// if it is nil.
+func SetTracer(t *Tracer) {
+	tracer.Store(t)
+}
+
+func currentTracer() *Tracer {
+	t, _ := tracer.Load().(*Tracer)
+	return t
+}
+
+// Start starts an internal span as a child of the current span of the
+// context. It returns the context holding the new span, which is nil if
+// tracing is disabled.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return StartKind(ctx, name, SpanKindInternal, attrs...)
+}
+
+// StartKind is like Start for a span of the given kind.
+func StartKind(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	t := currentTracer()
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+	s := &Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+		attrs:  attrs,
+	}
+	if parent.IsValid() {
+		s.sc.TraceID = parent.TraceID
+		s.sc.Sampled = parent.Sampled
+		s.parent = parent.SpanID
+	} else {
+		s.sc.TraceID, s.sc.Sampled = t.newTrace()
+	}
+	s.sc.SpanID = t.newSpanID()
+
+	return context.WithValue(ctx, keySpan, s), s
+}
+
+const traceparentHeader = "Traceparent"
+
+// Inject sets the W3C trace context header of the current span of the
+// context.
+func Inject(ctx context.Context, h http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	flags := 0
+	if sc.Sampled {
+		flags = 1
+	}
+	h.Set(traceparentHeader, fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, flags))
+}
+
+// Extract returns a context whose spans are children of the span of the
+// W3C trace context header if it is set and valid.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	sc, err := parseTraceparent(h.Get(traceparentHeader))
+	if err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, keyRemoteSpanContext, sc)
+}
+
+func parseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, fmt.Errorf("invalid traceparent %q", s)
+	}
+	var flags [1]byte
+	for _, f := range []struct {
+		s   string
+		dst []byte
+	}{
+		{parts[1], sc.TraceID[:]},
+		{parts[2], sc.SpanID[:]},
+		{parts[3], flags[:]},
+	} {
+		if len(f.s) != 2*len(f.dst) {
+			return sc, fmt.Errorf("invalid traceparent %q", s)
+		}
+		if _, err := hex.Decode(f.dst, []byte(f.s)); err != nil {
+			return sc, fmt.Errorf("invalid traceparent %q", s)
+		}
+	}
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("invalid traceparent %q", s)
+	}
+	sc.Sampled = flags[0]&1 == 1
+
+	return sc, nil
+}
+
+// randSource generates IDs and sampling decisions.
+type randSource struct {
+	mtx sync.Mutex
+	rnd *rand.Rand
+}
+
+func newRandSource() *randSource {
+	return &randSource{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (r *randSource) read(b []byte) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.rnd.Read(b)
+}
+
+func (r *randSource) float64() float64 {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.rnd.Float64()
+}
@@ -0,0 +1,146 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestStartDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "op")
+	if span != nil {
+		t.Fatalf("expected no span with tracing disabled but got %v", span)
+	}
+	if span.IsRecording() {
+		t.Fatalf("expected nil span not to be recording")
+	}
+	// Methods of nil spans must not panic.
+	span.SetAttributes(String("k", "v"))
+	span.SetError(fmt.Errorf("failed"))
+	span.End()
+
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		t.Fatalf("expected no span context but got %v", sc)
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	tr := NewTracer("", "test", 1)
+	SetTracer(tr)
+	defer SetTracer(nil)
+
+	h := http.Header{}
+	h.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, span := Start(Extract(context.Background(), h), "op")
+	sc := span.SpanContext()
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || !sc.Sampled {
+		t.Fatalf("expected span to continue the sampled remote trace but got %v", sc)
+	}
+	if !span.IsRecording() {
+		t.Fatalf("expected sampled span to be recording")
+	}
+	if span.parent.String() != "00f067aa0ba902b7" {
+		t.Fatalf("expected remote parent span but got %s", span.parent)
+	}
+
+	out := http.Header{}
+	Inject(ctx, out)
+	if exp := fmt.Sprintf("00-%s-%s-01", sc.TraceID, sc.SpanID); out.Get("traceparent") != exp {
+		t.Fatalf("expected traceparent %q but got %q", exp, out.Get("traceparent"))
+	}
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+	} {
+		if _, err := parseTraceparent(s); err == nil {
+			t.Errorf("expected error for traceparent %q", s)
+		}
+	}
+}
+
+func TestTracerExport(t *testing.T) {
+	var (
+		req     otlpRequest
+		gotHdr  string
+		exports = make(chan struct{}, 1)
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		exports <- struct{}{}
+	}))
+	defer collector.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHdr = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	tr := NewTracer(collector.URL, "alertmanager", 1)
+	SetTracer(tr)
+	defer SetTracer(nil)
+
+	go tr.Run()
+
+	ctx, parent := Start(context.Background(), "flush", String("receiver", "team-X"), Int("alerts", 3))
+	resp, err := Post(ctx, http.DefaultClient, upstream.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	tr.Stop()
+	<-exports
+
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected export request %+v", req)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans but got %d", len(spans))
+	}
+	client, flush := spans[0], spans[1]
+
+	if flush.Name != "flush" || flush.ParentSpanID != "" || flush.Kind != SpanKindInternal {
+		t.Errorf("unexpected root span %+v", flush)
+	}
+	if len(flush.Attributes) != 2 || *flush.Attributes[1].Value.IntValue != "3" {
+		t.Errorf("unexpected attributes %+v", flush.Attributes)
+	}
+	if client.ParentSpanID != flush.SpanID || client.TraceID != flush.TraceID || client.Kind != SpanKindClient {
+		t.Errorf("expected client span to be a child of the root span but got %+v", client)
+	}
+	if client.Status == nil || client.Status.Code != otlpStatusError {
+		t.Errorf("expected client span to have failed but got status %+v", client.Status)
+	}
+	if exp := fmt.Sprintf("00-%s-%s-01", client.TraceID, client.SpanID); gotHdr != exp {
+		t.Errorf("expected traceparent %q to be sent but got %q", exp, gotHdr)
+	}
+}
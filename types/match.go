@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/prometheus/common/model"
@@ -27,11 +28,15 @@ type Matcher struct {
 	Name  model.LabelName
 	Value string
 
-	isRegex bool
-	regex   *regexp.Regexp
+	isRegex    bool
+	isNegative bool
+	regex      *regexp.Regexp
 }
 
 func (m *Matcher) String() string {
+	if m.isNegative {
+		return fmt.Sprintf("<NegativeRegexMatcher %s:%q>", m.Name, m.Value)
+	}
 	if m.isRegex {
 		return fmt.Sprintf("<RegexMatcher %s:%q>", m.Name, m.Value)
 	}
@@ -41,13 +46,15 @@ func (m *Matcher) String() string {
 // MarshalJSON implements json.Marshaler.
 func (m *Matcher) MarshalJSON() ([]byte, error) {
 	v := struct {
-		Name    model.LabelName `json:"name"`
-		Value   string          `json:"value"`
-		IsRegex bool            `json:"isRegex"`
+		Name       model.LabelName `json:"name"`
+		Value      string          `json:"value"`
+		IsRegex    bool            `json:"isRegex"`
+		IsNegative bool            `json:"isNegative,omitempty"`
 	}{
-		Name:    m.Name,
-		Value:   m.Value,
-		IsRegex: m.isRegex,
+		Name:       m.Name,
+		Value:      m.Value,
+		IsRegex:    m.isRegex,
+		IsNegative: m.isNegative,
 	}
 	return json.Marshal(&v)
 }
@@ -57,6 +64,12 @@ func (m *Matcher) IsRegex() bool {
 	return m.isRegex
 }
 
+// IsNegative returns true if the matcher is fulfilled by values not
+// matching its regular expression.
+func (m *Matcher) IsNegative() bool {
+	return m.isNegative
+}
+
 // Match checks whether the label of the matcher has the specified
 // matching value.
 func (m *Matcher) Match(lset model.LabelSet) bool {
@@ -66,7 +79,7 @@ func (m *Matcher) Match(lset model.LabelSet) bool {
 	v := lset[m.Name]
 
 	if m.isRegex {
-		return m.regex.MatchString(string(v))
+		return m.regex.MatchString(string(v)) != m.isNegative
 	}
 	return string(v) == m.Value
 }
@@ -96,6 +109,26 @@ func NewRegexMatcher(name model.LabelName, re *regexp.Regexp) *Matcher {
 	}
 }
 
+// NewNegativeRegexMatcher returns a new matcher that is fulfilled by
+// values not matching the regular expression.
+func NewNegativeRegexMatcher(name model.LabelName, re *regexp.Regexp) *Matcher {
+	m := NewRegexMatcher(name, re)
+	m.isNegative = true
+	return m
+}
+
+// NewSetMatcher returns a new matcher that is fulfilled by any of the
+// given values.
+func NewSetMatcher(name model.LabelName, values []string) *Matcher {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, regexp.QuoteMeta(v))
+	}
+	sort.Strings(quoted)
+
+	return NewRegexMatcher(name, regexp.MustCompile("^(?:"+strings.Join(quoted, "|")+")$"))
+}
+
 // Matchers provides the Match and Fingerprint methods for a slice of Matchers.
 type Matchers []*Matcher
 
@@ -114,8 +147,30 @@ func (ms Matchers) Fingerprint() model.Fingerprint {
 	lset := make(model.LabelSet, 3*len(ms))
 
 	for _, m := range ms {
-		lset[model.LabelName(fmt.Sprintf("%s-%s-%v", m.Name, m.Value, m.isRegex))] = ""
+		k := fmt.Sprintf("%s-%s-%v", m.Name, m.Value, m.isRegex)
+		if m.isNegative {
+			k += "-negative"
+		}
+		lset[model.LabelName(k)] = ""
 	}
 
 	return lset.Fingerprint()
 }
+
+// String returns the matchers in a label selector like representation
+// sorted by label name, e.g. {env="prod", job=~"api.*", region!~"eu-.*"}.
+func (ms Matchers) String() string {
+	strs := make([]string, 0, len(ms))
+	for _, m := range ms {
+		op := "="
+		if m.isNegative {
+			op = "!~"
+		} else if m.isRegex {
+			op = "=~"
+		}
+		strs = append(strs, fmt.Sprintf("%s%s%q", m.Name, op, m.Value))
+	}
+	sort.Strings(strs)
+
+	return "{" + strings.Join(strs, ", ") + "}"
+}
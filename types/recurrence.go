@@ -0,0 +1,207 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// Recurrence makes a silence recur in windows of a fixed duration instead
+// of muting alerts from its start until its end time. The windows start at
+// the occurrences of an iCalendar recurrence rule (RFC 5545) whose first
+// occurrence is the silence's start time.
+type Recurrence struct {
+	// Rule is the recurrence rule, e.g. "FREQ=WEEKLY;BYDAY=SA". The rule
+	// parts FREQ (DAILY or WEEKLY), INTERVAL, and BYDAY are supported.
+	Rule string `json:"rule"`
+	// Duration of each window, encoded like "4h" in JSON.
+	Duration model.Duration `json:"duration"`
+	// Location is the name of the time zone in which occurrences keep the
+	// time of day of the silence's start time. Defaults to UTC.
+	Location string `json:"location,omitempty"`
+}
+
+type recurrenceJSON struct {
+	Rule     string `json:"rule"`
+	Duration string `json:"duration"`
+	Location string `json:"location,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (r *Recurrence) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&recurrenceJSON{
+		Rule:     r.Rule,
+		Duration: r.Duration.String(),
+		Location: r.Location,
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (r *Recurrence) UnmarshalJSON(b []byte) error {
+	var v recurrenceJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	d, err := model.ParseDuration(v.Duration)
+	if err != nil {
+		return err
+	}
+	*r = Recurrence{Rule: v.Rule, Duration: d, Location: v.Location}
+	return nil
+}
+
+// Validate returns an error if the recurrence is invalid.
+func (r *Recurrence) Validate() error {
+	if _, err := parseRecurrenceRule(r.Rule); err != nil {
+		return err
+	}
+	if r.Duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	if _, err := time.LoadLocation(r.Location); err != nil {
+		return fmt.Errorf("invalid location %q: %s", r.Location, err)
+	}
+	return nil
+}
+
+var ruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// recurrenceRule is a parsed recurrence rule.
+type recurrenceRule struct {
+	weekly   bool
+	interval int
+	// Weekdays the rule is restricted to. If empty, daily rules occur on
+	// every day and weekly rules on the weekday of their first occurrence.
+	days map[time.Weekday]bool
+}
+
+func parseRecurrenceRule(s string) (*recurrenceRule, error) {
+	var (
+		r    = &recurrenceRule{interval: 1}
+		freq string
+	)
+	for _, part := range strings.Split(strings.TrimPrefix(s, "RRULE:"), ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		switch k, v := strings.ToUpper(kv[0]), strings.ToUpper(kv[1]); k {
+		case "FREQ":
+			if v != "DAILY" && v != "WEEKLY" {
+				return nil, fmt.Errorf("unsupported frequency %q", v)
+			}
+			freq = v
+		case "INTERVAL":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid interval %q", v)
+			}
+			r.interval = n
+		case "BYDAY":
+			r.days = map[time.Weekday]bool{}
+			for _, d := range strings.Split(v, ",") {
+				wd, ok := ruleWeekdays[d]
+				if !ok {
+					return nil, fmt.Errorf("invalid weekday %q", d)
+				}
+				r.days[wd] = true
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", k)
+		}
+	}
+	if freq == "" {
+		return nil, fmt.Errorf("frequency missing")
+	}
+	r.weekly = freq == "WEEKLY"
+
+	return r, nil
+}
+
+// occurs returns true iff the rule with the given first occurrence occurs
+// on the day of t, which must not be before it.
+func (r *recurrenceRule) occurs(first, t time.Time) bool {
+	days := daysBetween(first, t)
+
+	if !r.weekly {
+		return days%r.interval == 0 && (len(r.days) == 0 || r.days[t.Weekday()])
+	}
+	if len(r.days) == 0 {
+		return t.Weekday() == first.Weekday() && (days/7)%r.interval == 0
+	}
+	// Weeks start on Monday.
+	weeks := (days + (int(first.Weekday())+6)%7) / 7
+	return weeks%r.interval == 0 && r.days[t.Weekday()]
+}
+
+// daysBetween returns the number of calendar days from a to b.
+func daysBetween(a, b time.Time) int {
+	a = time.Date(a.Year(), a.Month(), a.Day(), 12, 0, 0, 0, time.UTC)
+	b = time.Date(b.Year(), b.Month(), b.Day(), 12, 0, 0, 0, time.UTC)
+	return int(b.Sub(a) / (24 * time.Hour))
+}
+
+// Window returns the start and end of the window of a recurring silence
+// that is in effect at the given time. Windows do not extend beyond the
+// silence's end time.
+func (sil *Silence) Window(t time.Time) (start, end time.Time, ok bool) {
+	r := sil.Recurrence
+	if r == nil || t.Before(sil.StartsAt) || !t.Before(sil.EndsAt) {
+		return start, end, false
+	}
+	rule, err := parseRecurrenceRule(r.Rule)
+	if err != nil {
+		return start, end, false
+	}
+	loc, err := time.LoadLocation(r.Location)
+	if err != nil {
+		return start, end, false
+	}
+	var (
+		first = sil.StartsAt.In(loc)
+		d     = time.Duration(r.Duration)
+	)
+	t = t.In(loc)
+
+	// Check the occurrences of all days on which a window still in effect
+	// may have started, latest first.
+	for i := 0; ; i++ {
+		s := time.Date(t.Year(), t.Month(), t.Day()-i, first.Hour(), first.Minute(), first.Second(), first.Nanosecond(), loc)
+		if s.Before(first) || !s.Add(d).After(t) {
+			return start, end, false
+		}
+		if s.After(t) || !rule.occurs(first, s) {
+			continue
+		}
+		end = s.Add(d)
+		if end.After(sil.EndsAt) {
+			end = sil.EndsAt
+		}
+		return s, end, true
+	}
+}
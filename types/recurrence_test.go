@@ -0,0 +1,148 @@
+// Copyright 2016 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+func TestSilenceWindow(t *testing.T) {
+	at := func(s string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	// 2024-01-06 is a Saturday.
+	newSilence := func(rule string, d time.Duration, startsAt, endsAt string) *Silence {
+		return &Silence{
+			Silence: model.Silence{
+				StartsAt: at(startsAt),
+				EndsAt:   at(endsAt),
+			},
+			Recurrence: &Recurrence{Rule: rule, Duration: model.Duration(d)},
+		}
+	}
+
+	cases := []struct {
+		sil        *Silence
+		t          string
+		start, end string
+	}{
+		{
+			sil:   newSilence("FREQ=WEEKLY", 4*time.Hour, "2024-01-06 02:00", "2024-03-01 00:00"),
+			t:     "2024-01-06 03:00",
+			start: "2024-01-06 02:00",
+			end:   "2024-01-06 06:00",
+		}, {
+			sil: newSilence("FREQ=WEEKLY", 4*time.Hour, "2024-01-06 02:00", "2024-03-01 00:00"),
+			t:   "2024-01-06 06:00",
+		}, {
+			sil: newSilence("FREQ=WEEKLY", 4*time.Hour, "2024-01-06 02:00", "2024-03-01 00:00"),
+			t:   "2024-01-06 01:00",
+		}, {
+			sil: newSilence("FREQ=WEEKLY", 4*time.Hour, "2024-01-06 02:00", "2024-03-01 00:00"),
+			t:   "2024-01-10 03:00",
+		}, {
+			sil:   newSilence("FREQ=WEEKLY", 4*time.Hour, "2024-01-06 02:00", "2024-03-01 00:00"),
+			t:     "2024-01-13 05:59",
+			start: "2024-01-13 02:00",
+			end:   "2024-01-13 06:00",
+		}, {
+			// Windows end with the silence.
+			sil:   newSilence("FREQ=WEEKLY", 4*time.Hour, "2024-01-06 02:00", "2024-01-13 04:00"),
+			t:     "2024-01-13 03:00",
+			start: "2024-01-13 02:00",
+			end:   "2024-01-13 04:00",
+		}, {
+			sil:   newSilence("FREQ=WEEKLY;INTERVAL=2;BYDAY=SA,SU", time.Hour, "2024-01-06 02:00", "2024-03-01 00:00"),
+			t:     "2024-01-07 02:30",
+			start: "2024-01-07 02:00",
+			end:   "2024-01-07 03:00",
+		}, {
+			sil: newSilence("FREQ=WEEKLY;INTERVAL=2;BYDAY=SA,SU", time.Hour, "2024-01-06 02:00", "2024-03-01 00:00"),
+			t:   "2024-01-13 02:30",
+		}, {
+			sil:   newSilence("FREQ=WEEKLY;INTERVAL=2;BYDAY=SA,SU", time.Hour, "2024-01-06 02:00", "2024-03-01 00:00"),
+			t:     "2024-01-20 02:30",
+			start: "2024-01-20 02:00",
+			end:   "2024-01-20 03:00",
+		}, {
+			// Windows may span midnight.
+			sil:   newSilence("FREQ=DAILY", 4*time.Hour, "2024-01-06 22:00", "2024-03-01 00:00"),
+			t:     "2024-01-09 01:00",
+			start: "2024-01-08 22:00",
+			end:   "2024-01-09 02:00",
+		}, {
+			sil: newSilence("FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", 4*time.Hour, "2024-01-06 22:00", "2024-03-01 00:00"),
+			t:   "2024-01-07 01:00",
+		},
+	}
+
+	for i, c := range cases {
+		start, end, ok := c.sil.Window(at(c.t))
+		if c.start == "" {
+			if ok {
+				t.Errorf("case %d: expected no window at %s but got %s - %s", i, c.t, start, end)
+			}
+			continue
+		}
+		if !ok || !start.Equal(at(c.start)) || !end.Equal(at(c.end)) {
+			t.Errorf("case %d: expected window %s - %s but got %s - %s (%v)", i, c.start, c.end, start, end, ok)
+		}
+	}
+}
+
+func TestRecurrenceValidate(t *testing.T) {
+	cases := []struct {
+		r   *Recurrence
+		err bool
+	}{
+		{r: &Recurrence{Rule: "FREQ=WEEKLY;BYDAY=SA", Duration: model.Duration(time.Hour)}},
+		{r: &Recurrence{Rule: "RRULE:freq=daily;interval=2", Duration: model.Duration(time.Hour)}},
+		{r: &Recurrence{Rule: "FREQ=MONTHLY", Duration: model.Duration(time.Hour)}, err: true},
+		{r: &Recurrence{Rule: "FREQ=WEEKLY;BYDAY=XX", Duration: model.Duration(time.Hour)}, err: true},
+		{r: &Recurrence{Rule: "FREQ=WEEKLY;COUNT=3", Duration: model.Duration(time.Hour)}, err: true},
+		{r: &Recurrence{Rule: "INTERVAL=2", Duration: model.Duration(time.Hour)}, err: true},
+		{r: &Recurrence{Rule: "FREQ=DAILY"}, err: true},
+		{r: &Recurrence{Rule: "FREQ=DAILY", Duration: model.Duration(time.Hour), Location: "Nowhere/Nowhere"}, err: true},
+	}
+	for i, c := range cases {
+		if err := c.r.Validate(); (err != nil) != c.err {
+			t.Errorf("case %d: expected error %v but got %v", i, c.err, err)
+		}
+	}
+}
+
+func TestRecurrenceJSON(t *testing.T) {
+	var r Recurrence
+	if err := json.Unmarshal([]byte(`{"rule": "FREQ=DAILY", "duration": "90m"}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Duration != model.Duration(90*time.Minute) {
+		t.Fatalf("expected duration 90m but got %s", r.Duration)
+	}
+	b, err := json.Marshal(&r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := `{"rule":"FREQ=DAILY","duration":"90m"}`; string(b) != exp {
+		t.Fatalf("expected %s but got %s", exp, b)
+	}
+}
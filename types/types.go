@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -27,24 +28,56 @@ import (
 // Marker helps to mark alerts as silenced and/or inhibited.
 // All methods are goroutine-safe.
 type Marker interface {
-	SetInhibited(alert model.Fingerprint, b bool)
+	SetInhibited(alert model.Fingerprint, src ...*InhibitSource)
 	SetSilenced(alert model.Fingerprint, sil ...uint64)
+	SetAcked(alert model.Fingerprint, ack ...*Ack)
+	SetFlapping(alert model.Fingerprint, f ...*FlapState)
+	SetMaintenance(alert model.Fingerprint, window ...uint64)
 
 	Silenced(alert model.Fingerprint) (uint64, bool)
 	Inhibited(alert model.Fingerprint) bool
+	InhibitedBy(alert model.Fingerprint) (*InhibitSource, bool)
+	Acked(alert model.Fingerprint) (*Ack, bool)
+	Flapping(alert model.Fingerprint) (*FlapState, bool)
+	Maintenance(alert model.Fingerprint) (uint64, bool)
+}
+
+// FlapState describes an alert that repeatedly changes between firing
+// and resolved.
+type FlapState struct {
+	// Time the alert was first detected to be flapping.
+	Since time.Time `json:"since"`
+	// Number of state changes within the detection window.
+	Transitions int `json:"transitions"`
+}
+
+// InhibitSource identifies the alert that caused the inhibition of
+// another alert.
+type InhibitSource struct {
+	Fingerprint model.Fingerprint `json:"fingerprint"`
+	Labels      model.LabelSet    `json:"labels"`
+	// Dependency is set if the alert is inhibited because its service
+	// depends on the service of the source alert.
+	Dependency *Dependency `json:"dependency,omitempty"`
 }
 
 // NewMarker returns an instance of a Marker implementation.
 func NewMarker() Marker {
 	return &memMarker{
-		inhibited: map[model.Fingerprint]struct{}{},
+		inhibited: map[model.Fingerprint]*InhibitSource{},
 		silenced:  map[model.Fingerprint]uint64{},
+		acked:     map[model.Fingerprint]*Ack{},
+		flapping:  map[model.Fingerprint]*FlapState{},
+		windows:   map[model.Fingerprint]uint64{},
 	}
 }
 
 type memMarker struct {
-	inhibited map[model.Fingerprint]struct{}
+	inhibited map[model.Fingerprint]*InhibitSource
 	silenced  map[model.Fingerprint]uint64
+	acked     map[model.Fingerprint]*Ack
+	flapping  map[model.Fingerprint]*FlapState
+	windows   map[model.Fingerprint]uint64
 
 	mtx sync.RWMutex
 }
@@ -57,6 +90,14 @@ func (m *memMarker) Inhibited(alert model.Fingerprint) bool {
 	return ok
 }
 
+func (m *memMarker) InhibitedBy(alert model.Fingerprint) (*InhibitSource, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	src, ok := m.inhibited[alert]
+	return src, ok
+}
+
 func (m *memMarker) Silenced(alert model.Fingerprint) (uint64, bool) {
 	m.mtx.RLock()
 	defer m.mtx.RUnlock()
@@ -65,14 +106,38 @@ func (m *memMarker) Silenced(alert model.Fingerprint) (uint64, bool) {
 	return sid, ok
 }
 
-func (m *memMarker) SetInhibited(alert model.Fingerprint, b bool) {
+func (m *memMarker) Acked(alert model.Fingerprint) (*Ack, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	ack, ok := m.acked[alert]
+	return ack, ok
+}
+
+func (m *memMarker) Flapping(alert model.Fingerprint) (*FlapState, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	f, ok := m.flapping[alert]
+	return f, ok
+}
+
+func (m *memMarker) Maintenance(alert model.Fingerprint) (uint64, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	id, ok := m.windows[alert]
+	return id, ok
+}
+
+func (m *memMarker) SetInhibited(alert model.Fingerprint, src ...*InhibitSource) {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
-	if !b {
+	if len(src) == 0 {
 		delete(m.inhibited, alert)
 	} else {
-		m.inhibited[alert] = struct{}{}
+		m.inhibited[alert] = src[0]
 	}
 }
 
@@ -87,6 +152,39 @@ func (m *memMarker) SetSilenced(alert model.Fingerprint, sil ...uint64) {
 	}
 }
 
+func (m *memMarker) SetAcked(alert model.Fingerprint, ack ...*Ack) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if len(ack) == 0 {
+		delete(m.acked, alert)
+	} else {
+		m.acked[alert] = ack[0]
+	}
+}
+
+func (m *memMarker) SetFlapping(alert model.Fingerprint, f ...*FlapState) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if len(f) == 0 {
+		delete(m.flapping, alert)
+	} else {
+		m.flapping[alert] = f[0]
+	}
+}
+
+func (m *memMarker) SetMaintenance(alert model.Fingerprint, window ...uint64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if len(window) == 0 {
+		delete(m.windows, alert)
+	} else {
+		m.windows[alert] = window[0]
+	}
+}
+
 // MultiError contains multiple errors and implements the error interface. Its
 // zero value is ready to use. All its methods are goroutine safe.
 type MultiError struct {
@@ -143,7 +241,79 @@ type Alert struct {
 	WasSilenced  bool `json:"-"`
 	WasInhibited bool `json:"-"`
 
-	ID           string `json:"id,omitempty"`
+	ID string `json:"id,omitempty"`
+	// Source is where the alert was last received from.
+	Source *AlertSource `json:"source,omitempty"`
+}
+
+// SourceLabel is the synthetic label routes match the source of alerts on.
+const SourceLabel = "__source__"
+
+// Trust levels of an alert's source, from most to least trusted.
+const (
+	// The alert was sent by an authenticated API user.
+	SourceTrustAuthenticated = "authenticated"
+	// The alert was sent from an address without authentication.
+	SourceTrustAddress = "address"
+	// The source is only known from the generator URL the sender claims.
+	SourceTrustClaimed = "claimed"
+)
+
+// AlertSource identifies the sender of an alert.
+type AlertSource struct {
+	// Client is the authenticated API user that sent the alert.
+	Client string `json:"client,omitempty"`
+	// Address is the IP address the alert was received from.
+	Address string `json:"address,omitempty"`
+	// Domain is the host of the alert's generator URL.
+	Domain string `json:"domain,omitempty"`
+}
+
+// Name returns the most trusted identity of the source, which is the
+// authenticated client, the address, or the generator URL domain.
+func (s *AlertSource) Name() string {
+	if s == nil {
+		return ""
+	}
+	switch {
+	case s.Client != "":
+		return s.Client
+	case s.Address != "":
+		return s.Address
+	}
+	return s.Domain
+}
+
+// Trust returns the trust level of the identity returned by Name. It is
+// empty if the source is unknown.
+func (s *AlertSource) Trust() string {
+	if s == nil {
+		return ""
+	}
+	switch {
+	case s.Client != "":
+		return SourceTrustAuthenticated
+	case s.Address != "":
+		return SourceTrustAddress
+	case s.Domain != "":
+		return SourceTrustClaimed
+	}
+	return ""
+}
+
+// RoutingLabels returns the labels of the alert extended by the synthetic
+// source label. A label of that name sent with the alert is never used so
+// that senders cannot claim another source.
+func (a *Alert) RoutingLabels() model.LabelSet {
+	if _, ok := a.Labels[SourceLabel]; !ok && a.Source == nil {
+		return a.Labels
+	}
+	lset := a.Labels.Clone()
+	delete(lset, SourceLabel)
+	if name := a.Source.Name(); name != "" {
+		lset[SourceLabel] = model.LabelValue(name)
+	}
+	return lset
 }
 
 // AlertSlice is a sortable slice of Alerts.
@@ -210,42 +380,109 @@ func (f MuteFunc) Mutes(lset model.LabelSet) bool { return f(lset) }
 type Silence struct {
 	model.Silence
 
+	// ExcludeMatchers exempt alerts from the silence. An alert matching
+	// all of them is not muted even if it matches the silence's matchers.
+	ExcludeMatchers []*model.Matcher `json:"excludeMatchers,omitempty"`
+	// Recurrence makes the silence recur. Recurring silences do not mute
+	// alerts themselves but have their windows created as silences.
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// Replica is set for silences replicated from another peer of a
+	// cluster.
+	Replica *ReplicaKey `json:"replica,omitempty"`
+
 	// A set of matchers determining if an alert is affected
 	// by the silence.
 	Matchers Matchers `json:"-"`
+	// A set of matchers determining if an alert is exempt from
+	// the silence.
+	excludes Matchers
 
 	// timeFunc provides the time against which to evaluate
 	// the silence.
 	timeFunc func() time.Time
 }
 
-// NewSilence creates a new internal Silence from a public silence object.
-func NewSilence(s *model.Silence) *Silence {
-	sil := &Silence{
-		Silence:  *s,
-		timeFunc: time.Now,
+// NewSilence creates a new internal Silence from a public silence object
+// and optional exclusion matchers.
+func NewSilence(s *model.Silence, excludes ...*model.Matcher) *Silence {
+	return &Silence{
+		Silence:         *s,
+		ExcludeMatchers: excludes,
+		Matchers:        newMatchers(s.Matchers),
+		excludes:        newMatchers(excludes),
+		timeFunc:        time.Now,
 	}
-	for _, m := range s.Matchers {
+}
+
+// RestoreSilence creates a new internal Silence from a silence decoded
+// from its serialized form.
+func RestoreSilence(s *Silence) *Silence {
+	sil := NewSilence(&s.Silence, s.ExcludeMatchers...)
+	sil.Recurrence = s.Recurrence
+	sil.Replica = s.Replica
+	return sil
+}
+
+// ReplicaKey identifies an item replicated across a cluster of
+// Alertmanagers by the peer that created it and the ID it has there.
+type ReplicaKey struct {
+	Origin string `json:"origin"`
+	ID     uint64 `json:"id"`
+}
+
+func newMatchers(ms []*model.Matcher) Matchers {
+	var res Matchers
+	for _, m := range ms {
 		if !m.IsRegex {
-			sil.Matchers = append(sil.Matchers, NewMatcher(m.Name, m.Value))
+			res = append(res, NewMatcher(m.Name, m.Value))
 			continue
 		}
 		rem := NewRegexMatcher(m.Name, regexp.MustCompile("^(?:"+m.Value+")$"))
-		sil.Matchers = append(sil.Matchers, rem)
+		res = append(res, rem)
 	}
-	return sil
+	return res
+}
+
+// Validate returns an error if the silence or one of its exclusion
+// matchers is invalid.
+func (sil *Silence) Validate() error {
+	if err := sil.Silence.Validate(); err != nil {
+		return err
+	}
+	for _, m := range sil.ExcludeMatchers {
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("invalid exclude matcher: %s", err)
+		}
+	}
+	if sil.Recurrence != nil {
+		if err := sil.Recurrence.Validate(); err != nil {
+			return fmt.Errorf("invalid recurrence: %s", err)
+		}
+	}
+	return nil
 }
 
 // Mutes implements the Muter interface.
 func (sil *Silence) Mutes(lset model.LabelSet) bool {
+	if sil.Recurrence != nil {
+		return false
+	}
 	t := sil.timeFunc()
 
 	if t.Before(sil.StartsAt) || t.After(sil.EndsAt) {
 		return false
 	}
+	return sil.Matches(lset)
+}
 
+// Matches returns true iff the label set matches the silence's matchers
+// but not its exclusion matchers, regardless of the silence's time range.
+func (sil *Silence) Matches(lset model.LabelSet) bool {
 	b := sil.Matchers.Match(lset)
 
+	if b && len(sil.excludes) > 0 && sil.excludes.Match(lset) {
+		return false
+	}
 	return b
 }
 
@@ -256,6 +493,9 @@ type NotifyInfo struct {
 	Receiver  string
 	Resolved  bool
 	Timestamp time.Time
+	// FailoverTo is the fallback receiver the notification was delivered
+	// to after the receiver exhausted its retry budget.
+	FailoverTo string
 }
 
 func (n *NotifyInfo) String() string {
@@ -272,13 +512,408 @@ func (n *NotifyInfo) Fingerprint() model.Fingerprint {
 	return fp ^ n.Alert
 }
 
+// Ack is an acknowledgement of a firing alert. Notifications about the
+// alert are suppressed until it resolves or the acknowledgement expires.
+type Ack struct {
+	Alert     model.Fingerprint `json:"alert"`
+	CreatedBy string            `json:"createdBy"`
+	Comment   string            `json:"comment,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	// ExpiresAt is the zero time if the acknowledgement lasts until the
+	// alert resolves.
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+}
+
+// Validate returns an error if the acknowledgement is incomplete.
+func (ack *Ack) Validate() error {
+	if ack.CreatedBy == "" {
+		return fmt.Errorf("creator information missing")
+	}
+	if !ack.ExpiresAt.IsZero() && !ack.ExpiresAt.After(ack.CreatedAt) {
+		return fmt.Errorf("expiry must be after creation")
+	}
+	return nil
+}
+
+// Suppresses returns true iff the acknowledgement suppresses notifications
+// about the alert at the given time. Alerts that resolved or started firing
+// again after the acknowledgement was made are not suppressed.
+func (ack *Ack) Suppresses(a *Alert, now time.Time) bool {
+	if a.Resolved() || a.StartsAt.After(ack.CreatedAt) {
+		return false
+	}
+	return ack.ExpiresAt.IsZero() || now.Before(ack.ExpiresAt)
+}
+
+// MaintenanceWindow is a planned maintenance of a service. Notifications
+// about the service's alerts are suppressed while the window is active.
+type MaintenanceWindow struct {
+	ID uint64 `json:"id"`
+	// Matchers select the alerts of the maintained service.
+	Matchers  []*model.Matcher `json:"matchers"`
+	StartsAt  time.Time        `json:"startsAt"`
+	EndsAt    time.Time        `json:"endsAt"`
+	Owner     string           `json:"owner"`
+	TicketURL string           `json:"ticketURL,omitempty"`
+	Comment   string           `json:"comment,omitempty"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
+// Validate returns an error if the maintenance window is incomplete.
+func (mw *MaintenanceWindow) Validate() error {
+	if len(mw.Matchers) == 0 {
+		return fmt.Errorf("at least one matcher required")
+	}
+	for _, m := range mw.Matchers {
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("invalid matcher: %s", err)
+		}
+	}
+	if mw.Owner == "" {
+		return fmt.Errorf("owner missing")
+	}
+	if mw.StartsAt.IsZero() || mw.EndsAt.IsZero() {
+		return fmt.Errorf("start and end time required")
+	}
+	if !mw.EndsAt.After(mw.StartsAt) {
+		return fmt.Errorf("end time must be after start time")
+	}
+	return nil
+}
+
+// Active returns true iff the window is active at the given time.
+func (mw *MaintenanceWindow) Active(t time.Time) bool {
+	return !t.Before(mw.StartsAt) && t.Before(mw.EndsAt)
+}
+
+// Matches returns true iff the label set belongs to the maintained
+// service, regardless of the window's time range.
+func (mw *MaintenanceWindow) Matches(lset model.LabelSet) bool {
+	return newMatchers(mw.Matchers).Match(lset)
+}
+
+// Dependency declares that a service depends on another one. Alerts of
+// the service are inhibited while the service it depends on has firing
+// alerts.
+type Dependency struct {
+	ID        uint64 `json:"id"`
+	Service   string `json:"service"`
+	DependsOn string `json:"dependsOn"`
+	// Equal holds labels that must have the same values in the alerts of
+	// both services, e.g. their cluster.
+	Equal     model.LabelNames `json:"equal,omitempty"`
+	CreatedBy string           `json:"createdBy"`
+	Comment   string           `json:"comment,omitempty"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
+// Validate returns an error if the dependency is incomplete.
+func (d *Dependency) Validate() error {
+	if d.Service == "" || d.DependsOn == "" {
+		return fmt.Errorf("service and dependsOn required")
+	}
+	if d.Service == d.DependsOn {
+		return fmt.Errorf("service must not depend on itself")
+	}
+	for _, ln := range d.Equal {
+		if !ln.IsValid() {
+			return fmt.Errorf("invalid label name %q", ln)
+		}
+	}
+	if d.CreatedBy == "" {
+		return fmt.Errorf("creator information missing")
+	}
+	return nil
+}
+
+// DeadLetter is a notification that was given up on without being
+// delivered.
+type DeadLetter struct {
+	ID             uint64         `json:"id"`
+	Receiver       string         `json:"receiver"`
+	GroupKey       string         `json:"groupKey"`
+	GroupLabels    model.LabelSet `json:"groupLabels"`
+	RepeatInterval time.Duration  `json:"repeatInterval"`
+	Alerts         []*Alert       `json:"alerts"`
+	// Reason holds the error the notification failed with.
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// AlertHistoryState is a state of an alert recorded in its history.
+type AlertHistoryState string
+
+// States recorded in the history of alerts.
+const (
+	// The alert was received for the first time.
+	AlertCreated     AlertHistoryState = "created"
+	AlertFiring      AlertHistoryState = "firing"
+	AlertResolved    AlertHistoryState = "resolved"
+	AlertSilenced    AlertHistoryState = "silenced"
+	AlertUnsilenced  AlertHistoryState = "unsilenced"
+	AlertInhibited   AlertHistoryState = "inhibited"
+	AlertUninhibited AlertHistoryState = "uninhibited"
+	// A notification about the alert was sent to a receiver.
+	AlertNotified AlertHistoryState = "notified"
+)
+
+// AlertHistoryEntry records an alert entering a state.
+type AlertHistoryEntry struct {
+	Alert model.Fingerprint `json:"alert"`
+	Time  time.Time         `json:"time"`
+	State AlertHistoryState `json:"state"`
+	// Labels of the alert are set for created entries.
+	Labels model.LabelSet `json:"labels,omitempty"`
+	// Silence is set for silenced entries.
+	Silence uint64 `json:"silence,omitempty"`
+	// InhibitedBy is set for inhibited entries.
+	InhibitedBy *InhibitSource `json:"inhibitedBy,omitempty"`
+	// Receiver is set for notified entries.
+	Receiver string `json:"receiver,omitempty"`
+}
+
+// AuditEntry records a call of a mutating API endpoint.
+type AuditEntry struct {
+	ID   uint64    `json:"id"`
+	Time time.Time `json:"time"`
+	// Principal is the authenticated user if known or the client's address.
+	Principal  string `json:"principal"`
+	RemoteAddr string `json:"remoteAddr"`
+	// Action names the API call, e.g. add_silence.
+	Action string `json:"action"`
+	// Target identifies the object acted on if given in the request path.
+	Target string `json:"target,omitempty"`
+	// Status is the HTTP status code of the response.
+	Status int `json:"status"`
+}
+
+// Event is an incident tracked by operators, e.g. an outage that caused
+// a number of alerts.
 type Event struct {
-	ID        uint64         `json:"id"`
-	Title     string         `json:"title"`
-	Kind      string         `json:"kind"`
-	Level     string         `json:"level"`
-	IsSafe    string         `json:"is_safe"`
-	Creator   string         `json:"creator"`
-	Alerts    []string       `json:"alerts"`
-	CreatedAt time.Time      `json:"createdAt"`
+	ID    uint64 `json:"id"`
+	Title string `json:"title"`
+	// Severity of the event's impact. It may be empty.
+	Severity EventSeverity `json:"severity,omitempty"`
+	// Assignee is the person handling the event.
+	Assignee    string         `json:"assignee,omitempty"`
+	Kind        string         `json:"kind"`
+	Level       string         `json:"level"`
+	IsSafe      string         `json:"is_safe"`
+	Creator     string         `json:"creator"`
+	Alerts      []string       `json:"alerts"`
+	Labels      model.LabelSet `json:"labels,omitempty"`
+	Annotations model.LabelSet `json:"annotations,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	// UpdatedAt is the time of the last update. It is zero for events
+	// that were never updated.
+	UpdatedAt time.Time `json:"updatedAt"`
+	// ClosedAt is the time the event was closed. It is zero for open
+	// events.
+	ClosedAt time.Time `json:"closedAt,omitempty"`
+	// Version is incremented on every update of the event.
+	Version uint64 `json:"version"`
+	// ParentID is the ID of the event this event is a child of. It is
+	// zero for top-level events.
+	ParentID uint64 `json:"parentId,omitempty"`
+	// Status is the stage of the event's lifecycle. Events stored by
+	// previous versions have no status, see CurrentStatus.
+	Status EventStatus `json:"status,omitempty"`
+	// StatusTimes holds the time each status of the current lifecycle
+	// was entered.
+	StatusTimes map[EventStatus]time.Time `json:"statusTimes,omitempty"`
+	// Replica is set for events replicated from another peer of a
+	// cluster.
+	Replica *ReplicaKey `json:"replica,omitempty"`
+}
+
+// Attachment is a file attached to an event, e.g. a postmortem document or
+// a screenshot. Its content is stored separately.
+type Attachment struct {
+	ID          uint64 `json:"id"`
+	EventID     uint64 `json:"eventId"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	// SHA256 is the hex-encoded SHA-256 hash of the content.
+	SHA256    string    `json:"sha256"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// EventSeverity is the severity of an event's impact.
+type EventSeverity string
+
+// Possible event severities.
+const (
+	EventCritical EventSeverity = "critical"
+	EventWarning  EventSeverity = "warning"
+	EventInfo     EventSeverity = "info"
+)
+
+// Validate returns an error if the severity is unknown.
+func (s EventSeverity) Validate() error {
+	switch s {
+	case EventCritical, EventWarning, EventInfo:
+		return nil
+	}
+	return fmt.Errorf("unknown event severity %q", s)
+}
+
+// FieldError is a problem with a single field of an object.
+type FieldError struct {
+	// Field is the JSON name of the field, e.g. "labels.team".
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists the problems of the fields of an invalid object.
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Field+": "+fe.Message)
+	}
+	return "invalid " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate returns a ValidationError if the event is malformed. Events
+// require a title and labels and annotations with valid names.
+func (e *Event) Validate() error {
+	var verr ValidationError
+
+	if strings.TrimSpace(e.Title) == "" {
+		verr.add("title", "must not be empty")
+	}
+	if e.Severity != "" {
+		if err := e.Severity.Validate(); err != nil {
+			verr.add("severity", "%s", err)
+		}
+	}
+	if e.Status != "" {
+		if err := e.Status.Validate(); err != nil {
+			verr.add("status", "%s", err)
+		}
+	}
+	for _, f := range []struct {
+		name string
+		lset model.LabelSet
+	}{
+		{"labels", e.Labels},
+		{"annotations", e.Annotations},
+	} {
+		names := make(model.LabelNames, 0, len(f.lset))
+		for ln := range f.lset {
+			names = append(names, ln)
+		}
+		sort.Sort(names)
+
+		for _, ln := range names {
+			if !ln.IsValid() {
+				verr.add(f.name, "invalid name %q", ln)
+			} else if lv := f.lset[ln]; !lv.IsValid() {
+				verr.add(f.name+"."+string(ln), "invalid value %q", lv)
+			}
+		}
+	}
+	for i, a := range e.Alerts {
+		if a == "" {
+			verr.add(fmt.Sprintf("alerts[%d]", i), "must not be empty")
+		}
+	}
+	if !e.CreatedAt.IsZero() {
+		if !e.UpdatedAt.IsZero() && e.UpdatedAt.Before(e.CreatedAt) {
+			verr.add("updatedAt", "must not be before createdAt")
+		}
+		if !e.ClosedAt.IsZero() && e.ClosedAt.Before(e.CreatedAt) {
+			verr.add("closedAt", "must not be before createdAt")
+		}
+	}
+
+	if len(verr) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// EventStatus is a stage of an event's lifecycle.
+type EventStatus string
+
+// Events go through the statuses in the order below. Stages may be
+// skipped and resolved events may be reopened.
+const (
+	EventOpen         EventStatus = "open"
+	EventAcknowledged EventStatus = "acknowledged"
+	EventMitigated    EventStatus = "mitigated"
+	EventResolved     EventStatus = "resolved"
+)
+
+// eventTransitions holds the statuses an event may enter from each
+// status.
+var eventTransitions = map[EventStatus][]EventStatus{
+	EventOpen:         {EventAcknowledged, EventMitigated, EventResolved},
+	EventAcknowledged: {EventMitigated, EventResolved},
+	EventMitigated:    {EventResolved},
+	EventResolved:     {EventOpen},
+}
+
+// Validate returns an error if the status is unknown.
+func (s EventStatus) Validate() error {
+	if _, ok := eventTransitions[s]; !ok {
+		return fmt.Errorf("unknown event status %q", s)
+	}
+	return nil
+}
+
+// CurrentStatus returns the status of the event. Events without a status
+// are resolved if they were closed and open otherwise.
+func (e *Event) CurrentStatus() EventStatus {
+	if e.Status != "" {
+		return e.Status
+	}
+	if !e.ClosedAt.IsZero() {
+		return EventResolved
+	}
+	return EventOpen
+}
+
+// Transition moves the event into the given status at the given time.
+// It fails if the status cannot be entered from the current one.
+// Resolving the event closes it and reopening it starts a new lifecycle.
+func (e *Event) Transition(to EventStatus, at time.Time) error {
+	if err := to.Validate(); err != nil {
+		return err
+	}
+	from := e.CurrentStatus()
+
+	allowed := false
+	for _, s := range eventTransitions[from] {
+		if s == to {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("invalid transition from %s to %s", from, to)
+	}
+
+	switch to {
+	case EventResolved:
+		e.ClosedAt = at
+	case EventOpen:
+		e.ClosedAt = time.Time{}
+		e.StatusTimes = nil
+	}
+	if e.StatusTimes == nil {
+		e.StatusTimes = map[EventStatus]time.Time{}
+	}
+	e.Status = to
+	e.StatusTimes[to] = at
+	e.UpdatedAt = at
+
+	return nil
 }
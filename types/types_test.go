@@ -61,3 +61,136 @@ func TestAlertMerge(t *testing.T) {
 		}
 	}
 }
+
+func TestAlertRoutingLabels(t *testing.T) {
+	cases := []struct {
+		labels   model.LabelSet
+		source   *AlertSource
+		expected model.LabelSet
+		trust    string
+	}{
+		{
+			labels:   model.LabelSet{"alertname": "A"},
+			expected: model.LabelSet{"alertname": "A"},
+		},
+		{
+			labels:   model.LabelSet{"alertname": "A", SourceLabel: "prometheus-a"},
+			expected: model.LabelSet{"alertname": "A"},
+		},
+		{
+			labels:   model.LabelSet{"alertname": "A"},
+			source:   &AlertSource{Client: "prometheus-a", Address: "10.0.0.1", Domain: "prometheus.example.com"},
+			expected: model.LabelSet{"alertname": "A", SourceLabel: "prometheus-a"},
+			trust:    SourceTrustAuthenticated,
+		},
+		{
+			labels:   model.LabelSet{"alertname": "A", SourceLabel: "prometheus-a"},
+			source:   &AlertSource{Address: "10.0.0.1", Domain: "prometheus.example.com"},
+			expected: model.LabelSet{"alertname": "A", SourceLabel: "10.0.0.1"},
+			trust:    SourceTrustAddress,
+		},
+		{
+			labels:   model.LabelSet{"alertname": "A"},
+			source:   &AlertSource{Domain: "prometheus.example.com"},
+			expected: model.LabelSet{"alertname": "A", SourceLabel: "prometheus.example.com"},
+			trust:    SourceTrustClaimed,
+		},
+	}
+
+	for i, c := range cases {
+		a := &Alert{Alert: model.Alert{Labels: c.labels}, Source: c.source}
+		if lset := a.RoutingLabels(); !reflect.DeepEqual(lset, c.expected) {
+			t.Errorf("%d. expected routing labels %v but got %v", i, c.expected, lset)
+		}
+		if trust := a.Source.Trust(); trust != c.trust {
+			t.Errorf("%d. expected trust %q but got %q", i, c.trust, trust)
+		}
+	}
+}
+
+func TestEventTransition(t *testing.T) {
+	var (
+		now = time.Now()
+		e   = &Event{Title: "Database outage"}
+	)
+	if s := e.CurrentStatus(); s != EventOpen {
+		t.Fatalf("expected event without status to be open but got %q", s)
+	}
+
+	steps := []struct {
+		to    EventStatus
+		valid bool
+	}{
+		{EventMitigated, true},
+		{EventAcknowledged, false},
+		{EventOpen, false},
+		{EventResolved, true},
+		{EventMitigated, false},
+		{EventOpen, true},
+		{"unknown", false},
+	}
+	for i, s := range steps {
+		at := now.Add(time.Duration(i) * time.Minute)
+
+		err := e.Transition(s.to, at)
+		if s.valid != (err == nil) {
+			t.Fatalf("%d. expected transition to %q valid %v but got error %v", i, s.to, s.valid, err)
+		}
+		if !s.valid {
+			continue
+		}
+		if e.Status != s.to || !e.StatusTimes[s.to].Equal(at) {
+			t.Fatalf("%d. expected status %q entered at %v but got %q, %v", i, s.to, at, e.Status, e.StatusTimes)
+		}
+		if closed := s.to == EventResolved; closed == e.ClosedAt.IsZero() {
+			t.Fatalf("%d. expected event closed %v but got closing time %v", i, closed, e.ClosedAt)
+		}
+	}
+	// Reopening starts a new lifecycle.
+	if len(e.StatusTimes) != 1 {
+		t.Fatalf("expected only the time of reopening but got %v", e.StatusTimes)
+	}
+
+	// Closed events without status are resolved.
+	legacy := &Event{ClosedAt: now}
+	if s := legacy.CurrentStatus(); s != EventResolved {
+		t.Fatalf("expected closed event without status to be resolved but got %q", s)
+	}
+}
+
+func TestEventValidate(t *testing.T) {
+	now := time.Now()
+
+	valid := &Event{
+		Title:     "Database outage",
+		Severity:  EventCritical,
+		Labels:    model.LabelSet{"team": "db"},
+		CreatedAt: now,
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected event to be valid but got %s", err)
+	}
+
+	invalid := &Event{
+		Title:       " ",
+		Severity:    "fatal",
+		Status:      "closed",
+		Labels:      model.LabelSet{"team": "db", "0team": "db"},
+		Annotations: model.LabelSet{"summary": "\xff"},
+		Alerts:      []string{""},
+		CreatedAt:   now,
+		ClosedAt:    now.Add(-time.Minute),
+	}
+	expected := ValidationError{
+		{Field: "title", Message: "must not be empty"},
+		{Field: "severity", Message: `unknown event severity "fatal"`},
+		{Field: "status", Message: `unknown event status "closed"`},
+		{Field: "labels", Message: `invalid name "0team"`},
+		{Field: "annotations.summary", Message: `invalid value "\xff"`},
+		{Field: "alerts[0]", Message: "must not be empty"},
+		{Field: "closedAt", Message: "must not be before createdAt"},
+	}
+	if err := invalid.Validate(); !reflect.DeepEqual(err, expected) {
+		t.Errorf("expected errors %v but got %v", expected, err)
+	}
+}
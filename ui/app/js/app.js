@@ -457,6 +457,14 @@ angular.module('am').config(
       templateUrl: '/app/partials/events.html',
       controller: 'EventsCtrl'
     }).
+    when('/events/:id', {
+      templateUrl: '/app/partials/event.html',
+      controller: 'EventCtrl'
+    }).
+    when('/events/:id/timeline', {
+      templateUrl: '/app/partials/event-timeline.html',
+      controller: 'EventTimelineCtrl'
+    }).
     otherwise({
       redirectTo: '/alerts'
     });
@@ -14,9 +14,34 @@ function translateEvent(e) {
   e.kind = kinds[e.kind]
   e.level = levels[e.level]
   e.is_safe = e.is_safe ? "是" : "否"
+  e.currentStatus = eventStatus(e)
+  e.statusName = eventStatuses[e.currentStatus]
   return e
 }
 
+var eventStatuses = {
+  "open": "未处理",
+  "acknowledged": "已确认",
+  "mitigated": "已缓解",
+  "resolved": "已解决"
+}
+
+// The statuses an event may enter from each status.
+var eventTransitions = {
+  "open": ["acknowledged", "mitigated", "resolved"],
+  "acknowledged": ["mitigated", "resolved"],
+  "mitigated": ["resolved"],
+  "resolved": ["open"]
+}
+
+// Events without a status are resolved if they were closed.
+function eventStatus(e) {
+  if (e.status) {
+    return e.status
+  }
+  return e.closedAt ? "resolved" : "open"
+}
+
 angular.module('am.services').factory('Event',
   function($resource) {
     return $resource('', {
@@ -30,10 +55,26 @@ angular.module('am.services').factory('Event',
         method: 'POST',
         url: '/api/v1/events'
       },
+      'get': {
+        method: 'GET',
+        url: '/api/v1/event/:id'
+      },
       'alerts': {
         method: 'GET',
         url: '/api/v1/event/:id/alerts'
       },
+      'timeline': {
+        method: 'GET',
+        url: '/api/v1/event/:id/timeline'
+      },
+      'ack': {
+        method: 'POST',
+        url: '/api/v1/event/:id/ack'
+      },
+      'transition': {
+        method: 'POST',
+        url: '/api/v1/event/:id/transition'
+      },
     });
   }
 );
@@ -75,19 +116,35 @@ angular.module('am.controllers').controller('EventFormCtrl',
 );
 
 angular.module('am.controllers').controller('EventsCtrl',
-  function($scope, Event) {
-    $scope.events = [];
-    Event.query({},
-      function(data) {
-        for (i in data.data) {
-          var e = translateEvent(data.data[i]);
-          e.showAlerts = false;
-          $scope.events.push(e);
-        }
-      },
-      function(data) {
-        // console.log(data.data); 
-    });
+  function($scope, $location, Event) {
+    $scope.statuses = eventStatuses;
+    $scope.status = $location.search()['status'] || '';
+
+    $scope.load = function() {
+      var params = {};
+      if ($scope.status) {
+        params.status = $scope.status;
+      }
+      Event.query(params,
+        function(data) {
+          $scope.events = [];
+          for (i in data.data) {
+            var e = translateEvent(data.data[i]);
+            e.showAlerts = false;
+            $scope.events.push(e);
+          }
+        },
+        function(data) {
+          $scope.error = data.data.error;
+      });
+    }
+    $scope.load();
+
+    $scope.filter = function(status) {
+      $scope.status = status;
+      $location.search('status', status || null);
+      $scope.load();
+    }
 
     $scope.showAlerts = function(e) {
       if (e.alertObjs == undefined) {
@@ -105,6 +162,115 @@ angular.module('am.controllers').controller('EventsCtrl',
   }
 );
 
+angular.module('am.controllers').controller('EventCtrl',
+  function($scope, $routeParams, $uibModal, Event) {
+    $scope.load = function() {
+      Event.get({id: $routeParams.id},
+        function(data) {
+          $scope.event = translateEvent(data.data);
+          $scope.transitions = eventTransitions[$scope.event.currentStatus];
+        },
+        function(data) {
+          $scope.error = data.data.error;
+        }
+      );
+      Event.alerts({id: $routeParams.id},
+        function(data) {
+          $scope.alerts = data.data;
+        },
+        function(data) {
+          $scope.error = data.data.error;
+        }
+      );
+    }
+    $scope.load();
+
+    $scope.showAckForm = function() {
+      var modalInstance = $uibModal.open({
+        animation: true,
+        templateUrl: '/app/partials/event-ack.html',
+        controller: 'EventAckCtrl',
+        resolve: {
+          event: function () {
+            return $scope.event;
+          }
+        }
+      });
+      modalInstance.result.then(function() {
+        $scope.load();
+      });
+    }
+
+    $scope.transitionNames = {
+      "open": "Reopen",
+      "acknowledged": "Mark acknowledged",
+      "mitigated": "Mark mitigated",
+      "resolved": "Close"
+    }
+
+    $scope.transition = function(status) {
+      Event.transition({id: $routeParams.id}, {status: status},
+        function(data) {
+          $scope.error = null;
+          $scope.load();
+        },
+        function(data) {
+          $scope.error = data.data.error;
+        }
+      );
+    }
+  }
+);
+
+angular.module('am.controllers').controller('EventAckCtrl',
+  function($scope, $uibModalInstance, Event, event) {
+    $scope.event = event;
+    $scope.ack = {};
+
+    $scope.submit = function() {
+      Event.ack({id: event.id}, $scope.ack,
+        function(data) {
+          $uibModalInstance.close(data.data);
+        },
+        function(data) {
+          $scope.error = data.data.error;
+        }
+      );
+    }
+
+    $scope.cancel = function() {
+      $uibModalInstance.dismiss();
+    }
+  }
+);
+
+angular.module('am.controllers').controller('EventTimelineCtrl',
+  function($scope, $routeParams, Event) {
+    $scope.id = $routeParams.id;
+
+    var kinds = {
+      "created": "创建",
+      "updated": "更新",
+      "closed": "关闭",
+      "alert_started": "告警触发",
+      "alert_resolved": "告警恢复",
+      "acknowledged": "告警确认"
+    }
+
+    Event.timeline({id: $routeParams.id},
+      function(data) {
+        $scope.entries = data.data;
+        for (var i in $scope.entries) {
+          $scope.entries[i].kindName = kinds[$scope.entries[i].kind];
+        }
+      },
+      function(data) {
+        $scope.error = data.data.error;
+      }
+    );
+  }
+);
+
 angular.module('am.directives').directive('alertItem',
   function() {
     return {
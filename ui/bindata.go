@@ -7,7 +7,10 @@
 // ui/app/partials/alert-item.html
 // ui/app/partials/alert.html
 // ui/app/partials/alerts.html
+// ui/app/partials/event-ack.html
 // ui/app/partials/event-form.html
+// ui/app/partials/event-timeline.html
+// ui/app/partials/event.html
 // ui/app/partials/events.html
 // ui/app/partials/route.html
 // ui/app/partials/silence-form.html
@@ -134,7 +137,7 @@ func uiAppIndexHtml() (*asset, error) {
 	return a, nil
 }
 
-var _uiAppJsAppJs = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xdc\x3a\x6d\x6f\xe3\x36\xd2\xdf\xf3\x2b\x98\x22\x80\x24\x34\xab\xb4\xc0\xf3\x29\x6e\x16\x4f\xd0\xcd\xb5\x05\x6e\x6f\x8b\xcd\xf6\x53\x10\x1c\x18\x8b\xb6\x88\xa5\x25\x57\xa4\xec\xa6\xa9\xff\xfb\x0d\xdf\x44\x8a\xa2\xe4\x97\xe4\x50\xe0\x04\xec\xc6\x22\x67\x86\xf3\xce\x19\x52\x49\xcb\x09\xe2\xa2\xa1\x73\x91\xcc\xce\xce\x70\xb5\x6c\x19\x6e\xf2\x55\x5d\xb4\x8c\xa4\x09\x5e\xe5\x05\x6d\xc8\x5c\xd0\x0d\xe1\xc9\x25\x7a\x78\xcc\xf6\x42\x65\xee\x25\x4d\x9a\xba\x15\x24\xb9\x3c\x43\x68\xd1\x56\x30\x56\x57\xe9\x67\x32\x6f\x1b\x0e\xbf\x7e\x26\x6c\x4d\x9a\x0c\xbd\xc0\x2c\x42\x0d\x11\x6d\x53\x99\x17\xf9\xaa\xb9\xba\x46\xc9\x9d\xc2\x97\x0f\x9f\xd7\x6b\x72\xdd\xc1\x00\x94\x24\x0f\x20\x37\x89\x19\xda\x59\x50\x41\x56\x6b\x86\x05\xf9\xad\x61\x30\x8f\xd7\xeb\xab\x35\x6e\x04\xc5\x8c\x5f\x29\xa4\xbc\x14\x2b\xd6\x11\x9e\xd7\xab\x35\x65\x40\xa9\xe3\x92\x30\xb2\x22\x95\xc8\xbc\xc5\xae\xae\xd0\x6f\xa0\x2d\x51\x12\x0b\xdf\x81\xa3\x45\x53\xaf\xd4\x4c\x20\xdd\xa5\x8f\x7d\x5b\x15\x56\x4c\x09\xca\x68\xf5\x95\x56\x4b\xb7\x26\xcf\xd0\xb6\xa4\xf3\x12\x51\x61\xe0\xb8\x93\x54\xe3\x05\xe4\x73\xc3\x48\xc7\xee\xcc\xea\x41\xfd\xdd\xc9\xd7\xdd\xd9\x91\x36\xc3\x8c\x34\xa2\x6f\xb3\x57\x19\x49\xd1\x53\x46\x72\xda\x58\x82\x11\xd6\xc7\x1a\x4e\x11\xd2\x86\x7b\x85\x78\x1c\xf4\x55\xcd\xc9\x1b\x0a\x08\x14\x8f\x15\xc5\x30\xf1\x56\xc2\xfc\xa3\x6e\x56\x6f\x2b\x90\xa4\x7a\xa2\x50\xef\x16\xc0\xcd\xc1\x92\x71\xd2\x6c\xe8\x5c\x67\x97\xa4\x5a\x7e\x26\xbc\x6e\x1b\x30\xcf\x58\xa6\xe9\xe0\xb3\x7c\x81\xe7\xa2\x6e\x9e\xd3\xe4\x3e\x66\xd2\x8b\xc6\x90\x0a\x54\xd1\x8d\xa7\x09\xac\x69\x85\xa6\x05\x48\xf3\xff\xb4\x30\x2c\xbb\x89\xe4\xf7\x96\x34\xcf\x89\xaf\x9e\x15\x11\x65\x2d\xe1\x7f\xba\xfb\xe2\xf9\x74\x6b\x34\x42\xaf\x36\xdf\x5b\x5d\xf0\x81\xfe\x92\x79\x43\x40\x7d\x71\x82\xbf\x7e\xba\x3f\x81\xe2\x92\x88\x53\xf9\xbb\xba\xb6\x22\xfb\x04\x0b\x48\x27\x63\x2c\x7e\xb8\xfb\xe7\xdd\x97\xbb\x63\xc8\x6a\x8d\x66\x87\x79\x81\x67\xd5\xdb\x61\x1e\x3a\xd0\xa6\xaf\x37\x9f\x4a\x35\xfc\x6d\x44\xf8\x49\xe6\x3a\xfe\xb7\x0a\x72\xb5\xd4\x3c\xfc\x0f\x9b\x24\x22\xc1\xbc\xae\x44\x53\x33\xc0\x9b\xac\x5d\x7c\xb0\xcc\x7b\x4b\x93\x7f\xe1\xcd\x8f\xa2\x61\x81\xc0\x2a\x5d\x5e\xa2\x0b\x56\xcf\xb1\x1c\xb1\x82\xeb\x89\x9c\x42\x86\xe4\xe8\x06\x3d\x58\xf1\x2a\xbc\x92\xa9\xf4\xde\xc6\xaf\x95\x4f\x4b\xd7\x0f\x6b\x4f\x4d\x06\xed\x56\x8b\xdd\x47\xf2\x75\x31\x44\xb9\x17\x58\xb4\x83\x75\xf4\xe0\x08\xca\xdd\x06\xaa\x87\x10\x85\xe8\x41\x8d\xf2\x08\xca\xf3\xa4\xe4\x90\x22\xe6\x82\x14\x20\x68\xa7\x19\x29\x79\xe6\x6d\x35\xca\x0f\xe4\x60\x0e\xf4\xd0\xcd\x8d\x53\x59\xbe\xc6\xa2\x4c\xb3\x33\x6b\xc9\x51\x27\x1c\xb7\x8d\xd2\xcb\x98\x75\x02\x93\xf0\xb2\xde\x7e\x20\x02\x53\x26\x0d\xb3\x80\xbd\x8a\xf4\xa5\x11\xf5\x72\xc9\x88\x07\x12\x6e\xa3\x23\xa4\xce\x87\xa3\x46\xa6\x70\xf9\x7b\xb7\x51\x4f\xb1\x10\x80\x4d\xb2\xd1\x87\x3d\x8f\xcf\xc4\xd8\xd1\xb3\x80\x63\x89\xae\xb0\x98\x97\xa0\xe2\x6b\x08\x92\x33\x17\x5d\xd6\x1e\xb0\x9f\xdf\xe1\x79\x69\x74\x9b\xeb\x32\x8c\xe1\x27\xc2\xf8\xa5\xe3\x71\x83\x59\x0b\x61\xf1\x95\x3c\x3b\x6e\x45\x49\x79\xbe\x6e\x79\x99\xba\x58\xd7\x1e\x07\x60\x2e\xce\x15\xea\xb5\xfe\xe3\x46\x29\xff\x4c\x96\xe4\x8f\x6b\xad\x2d\x1b\xf7\xa6\xc4\x05\x17\xee\x8b\x93\x5b\x21\xb2\xbe\x5e\x2f\x80\x35\x1b\x64\xef\xf4\xde\x5b\x24\x1e\xdb\x64\x23\x06\xda\x1d\xd8\x22\xb5\xab\x4e\xa7\xcc\x3d\xde\xca\x0f\x48\x26\x97\xc8\xdb\x33\x60\xbc\xa5\x4f\x1f\xeb\x02\xb3\xc0\xa3\x75\x3e\x07\x13\x56\x2d\x63\x33\x7f\x06\x33\x06\x4d\x02\x81\xea\xb0\x51\x59\x28\x88\xdb\x8b\xad\x54\x13\x34\x66\x16\xc6\xd7\x05\x0c\x6e\xb8\xd3\x06\x5d\x20\x3d\x04\xb1\x7b\x83\xda\xaa\x20\x0b\x5a\x41\xc8\xff\xf5\x57\xe7\x1a\xe4\xf7\x16\x8c\xa3\xa1\x2e\x63\x2c\x64\x7e\x07\xa5\x53\x42\xbf\x47\xf1\x96\xf1\x41\x5d\xaa\xe0\x04\x37\x3e\xc7\xc0\xb0\x86\xee\xe8\x20\x70\x44\x72\x28\xae\x54\x58\xd8\x26\x05\x2e\x53\xd5\xe2\x6e\xb5\x16\xcf\x7e\x04\x2a\x85\x3b\x06\x37\xb8\x41\x90\xd2\xd0\x77\x96\x52\x18\x2b\x0a\x3e\x7f\x02\x4e\xbe\xfa\x41\xf2\xc4\xbe\xfa\x52\x4a\xd1\x55\x8c\x50\x50\xee\x1f\x9f\x16\x72\x3e\x57\xfd\xe9\xa7\xb5\xe0\xb9\x65\x3b\x43\xef\x61\x29\x1f\x11\xc1\xea\xdf\xde\x20\x09\xae\xf7\x82\x1c\x7c\x75\x29\x4a\x69\x9b\x8e\x27\xa7\x62\x17\x2d\x9d\xdd\xad\xcc\x5d\xa2\x66\xe8\xbd\x45\xdd\xf5\xf5\xd1\x90\x05\x6c\xe6\x65\x3c\x21\x79\xee\x9a\xab\x8d\x3d\x7d\xd9\xb9\x18\xee\x10\x0a\x2c\x70\x5f\x80\xd0\x93\x25\x44\x2e\xff\xeb\x18\x93\x0f\xb4\xcd\x10\x47\xbc\x66\x24\x67\xf5\x32\xed\x21\x65\xb3\x21\xb9\x88\xfb\x3b\x98\x91\x84\xb6\x34\xc1\x36\x66\x6c\xfd\x68\x8b\xca\xf4\xaa\x83\xd9\xa5\x71\x1f\xea\x34\x3f\x38\xd2\x1b\x7e\x08\x9d\x41\x3f\x2e\xdb\x8e\x20\xce\x02\x94\x5d\xef\x1d\x9c\x44\x52\xe8\x41\xed\xe2\x41\xdd\x33\x91\x64\xfb\x7c\xe0\x5e\x01\x7f\x32\x60\x4c\x2e\xe9\x74\x04\x18\xcf\x69\x9c\x7c\xa8\x96\x41\x48\x67\x0f\x2e\xa8\x1f\x87\xca\x88\x2f\x35\x4d\x24\xd4\x0e\xb8\xde\x97\x92\xa0\xae\xd2\xd1\x85\xdd\xaa\xe5\x02\x61\xb6\xc5\xcf\x1c\x3d\x11\x58\x00\xe1\xa6\xc1\xcf\x08\x0c\x0e\x73\x4c\xd0\x77\xf5\x5a\x1d\x09\x69\x3c\x9e\x47\x8c\x7c\x6e\xd9\xa2\xfc\x56\x22\x9b\xf4\x17\xb3\xa9\x15\xe4\x41\xfd\x78\xdc\x63\xc1\xde\x5b\x68\x12\xa0\xa2\x88\xf4\xec\xeb\x52\xc5\x31\x41\x4b\x9a\x06\xc4\xed\xc5\x6c\x48\x31\x9b\x4a\x25\x69\x16\x2b\x7e\x5c\x5c\x8d\x04\x62\x24\x04\xcf\xc3\xb1\x59\xac\xe2\x31\x26\xfc\x37\xb6\x68\x2f\xbb\x59\x74\xcb\x57\x80\x8a\x90\xcf\x06\xee\xef\x89\x71\xaa\x0f\x38\xa7\xc5\x23\xf2\x77\xca\xde\x86\x36\x89\x84\xf0\xe8\x6e\xa6\x9b\xf1\x49\xfc\x70\x47\x8d\x27\xcd\x00\x35\x8b\x6a\x0a\xf4\xa8\x7a\x81\xf1\x0a\x54\x46\xf2\x4a\x96\x24\xbf\x54\xd0\x53\xe8\x42\xb2\x2b\x53\x72\x20\x52\x79\xc5\x1e\xae\xe8\x4a\x85\xdc\x35\x12\x8d\x5f\xdc\xf5\x8f\x91\xae\x7a\xe7\x48\xaa\xef\xf0\x4e\x91\x1c\x96\x2b\xa9\x6c\xcf\x22\xf9\xec\xca\x2a\xfd\x70\xfa\xa7\x6c\x69\xd8\xd2\x1b\x93\xcd\x28\xdb\xf4\x0e\xb9\xc0\x96\x15\x95\x0b\x1a\x7d\xb8\xc3\x5f\x94\x86\x91\x68\x7b\xda\xa8\x22\x47\xe2\x29\x2c\x58\x4f\x2b\x1c\x4d\x11\x7a\x50\xe5\x68\x60\x25\xf3\xbe\x4d\x4b\xba\x2c\x19\xfc\x93\x2e\x1d\x4b\x81\x25\x4b\x94\xdb\xba\x7a\x1a\x7c\x6a\x36\x70\x8b\x41\x03\x15\xcc\xff\xcd\x1d\xce\x60\xa1\x37\xef\xe6\xa4\xe3\x83\x72\x7e\x84\xad\x64\x64\x13\x83\xd9\x20\xb1\x99\xe0\xf5\x9b\x64\x2f\x2b\x18\x29\x0c\x54\xfa\x22\x0f\x21\x69\x71\x54\x26\xbe\x20\x2b\x2a\x5c\x83\xa3\x29\x15\x89\xb7\x99\xbd\x59\x62\x8f\x67\xf4\x53\xfa\x2b\x23\xaf\x51\x26\x78\xdb\x6b\xdb\x2b\x7b\xb6\x32\x1e\x26\x5e\x6c\xf8\xf6\x37\x78\x5e\xa5\x68\x66\xea\xa6\x20\x52\x11\xdf\x90\xaa\xe0\xb7\xe2\x9b\x61\x3c\xec\x73\xf4\x83\x3c\x3c\xe2\xda\x23\x5d\xfb\x64\x11\x6e\x1d\xe9\x94\x02\xdc\xd3\x41\x67\x75\xd9\x46\x74\x9d\xa3\x7e\x46\x6a\x67\x8b\x1d\x1e\x04\x0c\xeb\x3f\x18\xcc\xb5\x32\x65\xdb\x4a\xb6\xe8\x03\xb8\x4a\xea\x8f\x07\x15\x98\x9e\x82\x3d\x06\x72\x6c\x0c\xc9\xce\x44\xd1\x8c\x27\xc6\xf0\xba\xa9\x7e\xa5\xfb\xdf\x08\x99\xc9\x5a\xe8\x94\xc8\xf1\xea\x27\x9f\xe9\x18\x45\x9b\x0a\x8e\xa7\xe8\x93\x24\x0c\xaf\x79\xff\x98\xcf\x0c\x0d\x4e\xfa\xdc\x3d\x26\x65\x61\x93\x3b\xc0\xf1\xf0\xe4\x86\x63\x1c\xe3\x07\x67\xac\xa1\x16\x63\x08\xef\x07\xf0\xbb\x3d\x65\xe7\xab\x76\x61\x65\xa4\x63\x93\x8c\x75\x90\xc1\x51\x8d\x3b\x88\x0b\x06\x20\xfa\x5e\xac\x04\x7e\x77\x35\x38\xea\x1a\xa4\x94\x00\x40\x1d\x43\xef\x1e\x67\xe1\x36\x56\x37\x74\x79\xdf\xad\x3e\xb2\x95\x29\x51\x42\x3d\x72\x22\xc6\xcb\xc2\xaa\xde\xfa\xe1\xd6\x45\x94\x9c\x03\x83\x05\x73\x66\x12\x90\xa0\x18\x11\x1f\x29\x63\x94\x13\xd0\x7a\xc1\xd3\xef\x3a\x54\x40\x9b\x98\x35\xb8\xf7\x63\x68\xfe\x44\x7f\xe6\xe7\xba\x6d\x78\x2a\x5f\x96\xf6\x25\x43\xdf\xa2\xff\xcb\xce\xa2\x1a\x0d\xb5\xe4\x29\xd0\x91\x56\xa6\xf2\x66\x5c\x86\x8a\xf4\x01\x01\x88\xd4\x4d\xbd\x8d\x1d\x8d\xf5\x28\x9a\x44\x39\x4e\xaf\xcb\xb0\xf0\x63\x4f\x48\x80\x16\xc2\x3e\x0c\x17\xc5\x47\xed\x3a\xd3\x3b\x57\xe0\x66\xe6\x88\x77\xa4\x42\x80\x44\x14\x21\x4a\xf7\x53\xe5\x6b\x46\xe7\x24\xa5\x97\xe8\xfb\x38\x65\x9d\x34\xa7\xb7\x44\x0d\x13\xb8\xf5\x2b\x2a\x2c\x9b\xa8\x63\x87\x4f\x9d\x4e\xed\xf8\xeb\x36\x12\x3d\x32\xb5\x9d\x1c\x79\x7d\xe7\x5d\x0c\xbd\xe6\xfe\xee\xa4\xfb\x66\xef\xfa\xe9\xc0\x0b\xc8\x89\x74\xac\x88\x4d\xe4\x61\x35\x6f\x05\xd2\x6f\x32\xce\xbd\xca\x68\xd4\x1c\xd6\xb5\xea\x6a\x41\x97\x3d\x6b\xe8\xa1\x59\x08\x29\x8f\x56\x80\xd0\x2f\xd5\xa2\xee\x81\x7b\xe3\x03\x9c\x76\x2d\xe8\x8a\xf4\xc0\xf5\x50\x17\xb2\x7b\xf9\xf4\x1b\xfc\x8e\x4a\x36\x43\x5d\xdb\x39\xa1\x5a\x79\x1b\x0a\xd3\xf2\x73\x8b\xee\xfb\x2c\x78\xb9\xc7\xb2\x21\xfe\xd3\xbc\x1b\xb4\x8f\xb5\xfc\xb0\x48\x0f\xb5\x34\x7f\xaa\x6b\xc1\x45\x83\xd7\x30\xa2\xa0\x82\x6b\x56\x33\xe6\xbe\xea\x30\x03\xde\x07\x2c\x67\x23\x17\xb1\xda\xc8\xa0\xe2\xb4\xef\x9f\x92\xc5\x5f\x9b\x7a\x43\x0b\xf7\xb5\x58\x7f\x54\x9f\xb1\x6d\x4b\x02\xe5\x8f\xbd\x27\x76\xfe\xba\xef\x8b\x22\x1e\x7e\x0b\xe6\x9d\x31\x04\xf7\x36\x3a\x3e\x58\x8d\x8b\x4f\xd5\xbd\x6a\xa0\xfd\xdb\xa9\x5d\xd6\x63\xa4\xbb\xd9\x3d\x88\x15\x0b\x3d\xc1\xcc\xa0\xcb\x39\x8e\x1d\x13\xfc\x07\x31\xa3\x43\x66\x82\x15\x17\x81\xb1\xb5\xcc\xcd\xf1\xd8\x5a\x91\xe3\x9e\xa9\xc5\xf4\xe5\xf4\x70\xb1\x5a\xc0\x36\xb1\x85\xb2\x20\x75\x95\xa8\xf6\xb3\x2f\xb5\x5a\xc5\xbf\x25\xf7\x72\xcd\x7f\x02\x00\x00\xff\xff\x4c\xd3\x22\xbc\xf5\x28\x00\x00")
+var _uiAppJsAppJs = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xdd\x1a\x6b\x6f\xe3\x36\xf2\xbb\x7f\x05\x53\x04\x90\x84\x66\xe5\x16\xb8\x4f\x71\xb3\x68\xd0\xcd\xb5\x05\x6e\x6f\x8b\x75\xfa\x29\x08\x0e\x8c\x45\x5b\xc4\xd2\x92\x2b\x52\x76\xd3\xd4\xff\xfd\x86\x2f\x91\xa2\x1e\x7e\x24\x45\x0f\xb7\x40\x36\x21\x39\x33\x9c\x37\x67\x48\x45\x35\x27\x88\x8b\x8a\x2e\x44\x34\x9b\x4c\x70\xb1\xaa\x19\xae\xd2\x75\x99\xd5\x8c\xc4\x11\x5e\xa7\x19\xad\xc8\x42\xd0\x2d\xe1\xd1\x15\x7a\x78\x4c\x0e\x42\x25\x6e\x10\x47\x55\x59\x0b\x12\x5d\x4d\x10\x5a\xd6\x05\xcc\x95\x45\xfc\x99\x2c\xea\x8a\xc3\x5f\x3f\x11\xb6\x21\x55\x82\x5e\x60\x15\xa1\x8a\x88\xba\x2a\xcc\x40\x0e\x35\x57\xd7\x28\xba\x53\xf8\xf2\x1f\x5f\x94\x1b\x72\xdd\xc0\x00\x94\x24\x0f\x20\x37\x91\x99\xda\x5b\x50\x41\xd6\x1b\x86\x05\xf9\xb5\x62\xb0\x8e\x37\x9b\xe9\x06\x57\x82\x62\xc6\xa7\x0a\x29\xcd\xc5\x9a\x35\x84\x17\xe5\x7a\x43\x19\x50\x6a\xb8\x24\x8c\xac\x49\x21\x12\x6f\xb3\xe9\x14\xfd\x0a\xda\x12\x39\xb1\xf0\x0d\x38\x5a\x56\xe5\x5a\xad\x04\xd2\x5d\xf9\xd8\xb7\x45\x66\xc5\x94\xa0\x8c\x16\x5f\x68\xb1\x72\x7b\xf2\x04\xed\x72\xba\xc8\x11\x15\x06\x8e\x3b\x49\x35\x5e\x40\x3e\x35\x8c\x34\xec\xce\xac\x1e\xd4\xef\xbd\x1c\xee\x27\x27\xda\x0c\x33\x52\x89\xb6\xcd\x5e\x65\x24\x45\x4f\x19\xc9\x69\x63\x05\x46\xd8\x9c\x6a\x38\x45\x48\x1b\xee\x15\xe2\x71\xd0\x57\xb1\x20\x6f\x28\x20\x50\x3c\x55\x14\xc3\xc4\x5b\x09\xf3\xcf\xb2\x5a\xbf\xad\x40\x92\xea\x99\x42\xbd\x5b\x02\x37\x47\x4b\xc6\x49\xb5\xa5\x0b\x9d\x5d\xa2\x62\xf5\x99\xf0\xb2\xae\xc0\x3c\x8f\x87\xe0\x93\x74\x89\x17\xa2\xac\x9e\xe3\x68\xde\x67\xd2\xcb\xca\x90\x0a\x54\xd1\xcc\xc7\x11\xec\x69\x85\xa6\x19\x48\xf3\x3d\xcd\x0c\xcb\x6e\x21\xfa\xad\x26\xd5\x73\xe4\xab\x67\x4d\x44\x5e\x4a\xf8\x1f\xef\xee\x3d\x9f\xae\x8d\x46\xe8\x74\xfb\xad\xd5\x05\xef\xe8\x2f\x5a\x54\x04\xd4\xd7\x4f\xf0\x97\x4f\xf3\x33\x28\xae\x88\x38\x97\xbf\xe9\xb5\x15\xd9\x27\x98\x41\x3a\x19\x62\xf1\xc3\xdd\xbf\xee\xee\xef\x4e\x21\xab\x35\x9a\x1c\xe7\x05\x9e\x55\x6f\xbb\x79\xe8\x48\x9b\xbe\xde\x7c\x2a\xd5\xf0\xb7\x11\xe1\x47\x99\xeb\xf8\xdf\x2a\xc8\x74\xa5\x79\xf8\x3f\x36\x49\x8f\x04\x8b\xb2\x10\x55\xc9\x00\x6f\xb4\x76\xf1\xc1\x12\x6f\x14\x47\xff\xc6\xdb\x1f\x44\xc5\x02\x81\x55\xba\xbc\x42\x97\xac\x5c\x60\x39\x63\x05\xd7\x0b\x29\x85\x0c\xc9\xd1\x0d\x7a\xb0\xe2\x15\x78\x2d\x53\xe9\xdc\xc6\xaf\x95\x4f\x4b\xd7\x0e\x6b\x4f\x4d\x06\xed\x56\x8b\xdd\x46\xf2\x75\xd1\x45\x99\x0b\x2c\xea\xce\x3e\x7a\x72\x00\xe5\x6e\x0b\xd5\x43\x88\x42\xf4\xa4\x46\x79\x04\xe5\x79\x52\x72\x48\x11\x0b\x41\x32\x10\xb4\xd1\x8c\x94\x3c\xf1\x8e\x1a\xe5\x07\x72\x32\x05\x7a\xe8\xe6\xc6\xa9\x2c\xdd\x60\x91\xc7\xc9\xc4\x5a\x72\xd0\x09\x87\x6d\xa3\xf4\x32\x64\x9d\xc0\x24\x3c\x2f\x77\x1f\x88\xc0\x94\x49\xc3\x2c\xe1\xac\x22\x6d\x69\x44\xb9\x5a\x31\xe2\x81\x84\xc7\xe8\x00\xa9\x8b\xee\xac\x91\x29\xdc\x7e\xee\x0e\xea\x31\x16\x02\xb0\x51\x36\xda\xb0\x17\xfd\x2b\x7d\xec\xe8\x55\xc0\xb1\x44\xd7\x58\x2c\x72\x50\xf1\x35\x04\xc9\xc4\x45\x97\xb5\x07\x9c\xe7\x77\x78\x91\x1b\xdd\xa6\xba\x0c\x63\xf8\x89\x30\x7e\xe5\x78\xdc\x62\x56\x43\x58\x7c\x21\xcf\x8e\x5b\x91\x53\x9e\x6e\x6a\x9e\xc7\x2e\xd6\xb5\xc7\x01\x98\x8b\x73\x85\x7a\xad\x7f\xb9\x59\xca\x3f\x93\x15\xf9\xfd\x5a\x6b\xcb\xc6\xbd\x29\x71\xc1\x85\xdb\xe2\xa4\x56\x88\xa4\xad\xd7\x4b\x60\xcd\x06\xd9\x3b\x7d\xf6\x66\x91\xc7\x36\xd9\x8a\x8e\x76\x3b\xb6\x88\xed\xae\xe3\x29\xf3\x80\xb7\xf2\x23\x92\xc9\x15\xf2\xce\x0c\x98\xaf\xe9\xd3\xc7\x32\xc3\x2c\xf0\x68\x9d\xcf\xc1\x84\x45\xcd\xd8\xcc\x5f\xc1\x8c\x41\x93\x40\xa0\x3a\xac\x54\x16\x0a\xe2\xf6\x72\x27\xd5\x04\x8d\x99\x85\xf1\x75\x01\x93\x5b\xee\xb4\x41\x97\x48\x4f\x41\xec\xde\xa0\xba\xc8\xc8\x92\x16\x10\xf2\x7f\xfe\xd9\xb8\x06\xf9\xad\x06\xe3\x68\xa8\xab\x3e\x16\x12\xbf\x83\xd2\x29\xa1\xdd\xa3\x78\xdb\xf8\xa0\x2e\x55\x70\x82\x2b\x9f\x63\x60\x58\x43\x37\x74\x10\x38\x22\x39\x16\x57\x2a\x2c\x6c\x93\x02\x97\x29\x4a\x71\xb7\xde\x88\x67\x3f\x02\x95\xc2\x1d\x83\x5b\x5c\x21\x48\x69\xe8\x1b\x4b\x29\x8c\x15\x05\x9f\x3e\x01\x27\x5f\xfc\x20\x79\x62\x5f\x7c\x29\xa5\xe8\x2a\x46\x28\x28\xf7\xf7\x4f\x4b\xb9\x9e\xaa\xfe\xf4\xd3\x46\xf0\xd4\xb2\x9d\xa0\xf7\xb0\x95\x8f\x88\x60\xf7\xaf\x6f\x90\x04\xd7\x67\x41\x0a\xbe\xba\x12\xb9\xb4\x4d\xc3\x93\x53\xb1\x8b\x96\xc6\xee\x56\xe6\x26\x51\x33\xf4\xde\xa2\xee\xdb\xfa\xa8\xc8\x12\x0e\xf3\xbc\x3f\x21\x79\xee\x9a\xaa\x83\x3d\x7e\xd9\xbb\x18\x6e\x10\x32\x2c\x70\x5b\x80\xd0\x93\x25\x44\x2a\xff\x6b\x18\x33\x6d\x33\xc4\x11\x2f\x19\x49\x59\xb9\x8a\x5b\x48\xc9\xac\x4b\xae\xc7\xfd\x1d\xcc\x40\x42\x5b\x99\x60\x1b\x32\xb6\xd7\xb2\xaa\xf4\xaa\x83\xd9\xa5\x71\x1f\xea\x3c\x3f\x38\xd1\x1b\xbe\x0b\x9d\x21\xcc\xb6\x03\x88\xb3\x00\x65\xdf\x1a\x83\x93\x48\x0a\x2d\xa8\x7d\x7f\x50\xb7\x4c\x24\xd9\xbe\xe8\xb8\x57\xc0\x9f\x0c\x18\x93\x4b\x1a\x1d\x01\xc6\x73\xdc\x4f\x3e\x54\x4b\x27\xa4\x93\x07\x17\xd4\x8f\x5d\x65\xf4\x6f\x35\x4e\x24\xd4\x0e\xb8\xde\x7d\x4e\x50\x53\xe9\xe8\xc2\x6e\x5d\x73\x81\x30\xdb\xe1\x67\x8e\x9e\x08\x6c\x80\x70\x55\xe1\x67\x04\x06\x87\x35\x26\xe8\xbb\x72\xa3\xae\x84\x34\x1e\x4f\x7b\x8c\x7c\x61\xd9\xa2\xfc\x56\x22\x9b\xf4\xd7\x67\x53\x2b\xc8\x83\xfa\xe3\xf1\x80\x05\x5b\xa3\xd0\x24\x40\x45\x11\x69\xd9\xd7\xa5\x8a\x53\x82\x96\x54\x15\x88\xdb\x8a\xd9\x90\x62\x32\x96\x4a\xe2\xa4\xaf\xf8\x71\x71\x35\x10\x88\x3d\x21\x78\x11\xce\xcd\xfa\x2a\x1e\x63\xc2\xff\x60\x8b\xf6\xb2\x9f\xf5\x1e\xf9\x0a\x50\x11\xf2\xd9\xc0\xed\x33\xb1\x9f\xea\x03\x4e\x69\xf6\x88\xfc\x93\xb2\x75\xa0\x8d\x22\x21\x3c\x78\x9a\xe9\x66\x7c\x14\x3f\x3c\x51\xfb\x93\x66\x80\x9a\xcc\x06\x4a\x55\xd5\x0b\x0c\x57\xa0\x32\x92\xd7\xb2\x24\xf9\xb9\x80\x9e\x42\x17\x92\x4d\x99\x92\x02\x91\xc2\x2b\xf6\x70\x41\xd7\x2a\xe4\xae\x91\xa8\xfc\xe2\xae\x7d\x8d\x34\x6d\xdd\x23\xa9\xbe\xc3\xbb\x45\x72\x58\xae\xa4\xb2\x3d\x8b\xe4\xb3\x29\xab\xec\xed\xd5\x1f\xb2\xa5\x61\x2b\x6f\x4e\x36\xa3\x6c\xdb\xba\xe4\x02\x5b\x16\x54\x6e\x68\xf4\xe1\x2e\x7f\x51\x1c\x46\xa2\xed\x69\x7b\x15\x39\x10\x4f\x61\xc1\x7a\x5e\xe1\x68\x8a\xd0\xa3\x2a\x47\x03\x2b\x99\xf7\x6d\x9a\xd3\x55\xce\xe0\x47\xba\x74\x5f\x0a\xcc\x59\xa4\xdc\xd6\xd5\xd3\xe0\x53\xb3\xc9\xc1\x06\xea\x7f\xab\xc3\xf9\xeb\xbb\x39\xe9\xf8\xa0\x9c\x1f\xe0\x28\x19\x38\xc4\x60\x35\x48\x6c\x26\x78\xfd\x26\xd9\xcb\x0a\x46\x0a\x03\x15\xbf\xc8\x4b\x48\x9a\x9d\x94\x89\x2f\xc9\x9a\x0a\xd7\xe0\x68\x4a\x59\xe4\x1d\x66\x6f\x96\xd8\xfb\x33\xfa\x39\xfd\x95\x91\xd7\x28\x13\xbc\xed\xb5\xed\x95\xbd\x5b\x19\x0e\x13\x2f\x36\xba\xad\xb0\x5f\x29\x9a\x95\xb2\xca\x88\x54\xc4\x57\xa4\xc8\xf8\xad\xf8\xaa\x1b\x0f\x87\x1c\xfd\x28\x0f\xef\x71\xed\x81\xae\x7d\xb4\x08\xb7\x8e\x74\x4e\x01\xee\xe9\xa0\xb1\xba\x6c\x23\x9a\xce\x71\xb4\x76\xb6\xd8\xe1\x45\x40\xb7\xfe\x83\xc9\x54\x2b\x53\xb6\xad\x64\x87\x3e\x80\xab\xc4\xfe\x7c\x50\x81\xe9\x25\x38\x63\x20\xc7\xf6\x21\xd9\x95\x5e\x34\xe3\x89\x7d\x78\xcd\x52\xbb\xd2\xfd\x2b\x42\x66\xb4\x16\x3a\x27\x72\xbc\xfa\xc9\x67\xba\x8f\xa2\x4d\x05\xa7\x53\xf4\x49\x12\x86\x37\xbc\x7d\xcd\x67\xa6\x3a\x37\x7d\xee\x1d\x93\xb2\xb0\xc9\xed\xe0\x78\x78\xf2\xc0\x31\x8e\xf1\x9d\x33\x56\x57\x8b\x7d\x08\xef\x3b\xf0\xfb\x03\x65\xe7\xab\x4e\x61\x65\xa4\x53\x93\x8c\x75\x90\xce\x55\x8d\xbb\x88\x0b\x26\x20\xfa\x5e\xac\x04\x7e\x77\xd5\xb9\xea\xea\xa4\x94\x00\x40\x5d\x43\xef\x1f\x67\xe1\x31\x56\x56\x74\x35\x6f\x76\x1f\x38\xca\x94\x28\xa1\x1e\x39\x11\xc3\x65\x61\x51\xee\xfc\x70\x6b\x22\x4a\xae\x81\xc1\x82\x35\x7b\x03\x5d\xee\xa0\x18\x11\x1f\x29\x63\x94\x13\xd0\x7a\xc6\xe3\x6f\x1a\x54\x40\x1b\x59\x35\xb8\xf3\x21\x34\x7f\xa1\xbd\xf2\x53\x59\x57\x3c\x96\x83\x95\x1d\x24\xe8\x6b\xf4\x8f\x64\xd2\xab\xd1\x50\x4b\x9e\x02\x1d\x69\x65\x2a\x6f\xc5\x65\xa8\x9e\x3e\x20\x00\x91\xba\x29\x77\x7d\x57\x63\x2d\x8a\x26\x51\x0e\xd3\x6b\x32\x2c\xfc\x71\x20\x24\x40\x0b\x61\x1f\x86\xb3\xec\xa3\x76\x9d\xf1\x93\x2b\x70\x33\x73\xc5\x3b\x50\x21\x40\x22\xea\x21\x4a\x0f\x53\xe5\x1b\x46\x17\x24\xa6\x57\xe8\xdb\x7e\xca\x3a\x69\x8e\x1f\x89\x1a\x26\x70\xeb\x57\x54\x58\x36\x51\xf7\x5d\x3e\x35\x3a\x7d\x9b\x83\x44\xcf\x8c\x1d\x27\x27\x3e\xdf\x79\x0f\x43\xaf\x79\xbf\x3b\xeb\xbd\xd9\x7b\x7e\x3a\xf2\x01\x72\x24\x1d\x2b\x62\x23\x79\x58\xad\x5b\x81\xf4\x48\xc6\xb9\x57\x19\x0d\x9a\xc3\xba\x56\x59\x2c\xe9\xaa\x65\x0d\x3d\x35\x0b\x21\xe5\xd5\x0a\x10\xfa\xb9\x58\x96\x2d\x70\x6f\xbe\x83\x53\x6f\x04\x5d\x93\x16\xb8\x9e\x9a\x85\x6f\xf1\x83\x7c\xfa\x0d\x7e\x43\x25\x99\xa1\xa6\xed\x1c\x51\xad\x7c\x0d\x85\x65\xf9\xb9\x45\xf3\x7d\x16\x0c\xe6\x58\x36\xc4\x7f\x98\xb1\x41\xfb\x58\xca\x0f\x8b\xf4\x54\x4d\xd3\xa7\xb2\x14\x5c\x54\x78\x03\x33\x0a\x2a\x78\x66\x35\x73\xee\xab\x0e\x33\xe1\x7d\xc0\x32\x19\x78\x88\xd5\x46\x06\x15\xc7\x6d\xff\x94\x2c\xfe\x52\x95\x5b\x9a\xb9\xaf\xc5\xda\xb3\xfa\x8e\x6d\x97\x13\x28\x7f\xec\x3b\xb1\xf3\xd7\x43\x5f\x14\xf1\xf0\x5b\x30\xef\x8e\x21\x78\xb7\xd1\xf1\xc1\x4a\x9c\x7d\x2a\xe6\xaa\x81\xf6\x5f\xa7\xf6\x49\x8b\x91\xe6\x65\xf7\x28\x56\x2c\xf4\x08\x33\x9d\x2e\xe7\x34\x76\x4c\xf0\x1f\xc5\x8c\x0e\x99\x11\x56\x5c\x04\xf6\xed\x65\x5e\x8e\x87\xf6\xea\xb9\xee\x19\xdb\x4c\x3f\x4e\x1f\xd8\x4c\x7d\x72\x72\xc2\x86\x87\xf6\x3b\x62\xbb\xa9\x8c\x58\x46\x0b\x72\xca\xbe\xef\x2c\xd2\x21\x06\xee\x0d\x5c\x97\x91\x52\xc0\xf1\xb8\x83\x72\x28\x76\x15\xb8\x8e\xaf\xfb\x52\x6d\xea\x7f\x1d\xe0\xe5\xd8\xff\x02\x10\xd2\xfb\xe4\xed\x29\x00\x00")
 
 func uiAppJsAppJsBytes() ([]byte, error) {
 	return bindataRead(
@@ -149,12 +152,12 @@ func uiAppJsAppJs() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "ui/app/js/app.js", size: 10485, mode: os.FileMode(436), modTime: time.Unix(1466750110, 0)}
+	info := bindataFileInfo{name: "ui/app/js/app.js", size: 10733, mode: os.FileMode(436), modTime: time.Unix(1792149522, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _uiAppJsExtensionJs = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xa4\x56\x4f\x6b\x24\x45\x14\xbf\xe7\x53\xd4\x0e\x0b\xdd\x03\x63\x0f\x5e\x33\x04\x0d\x1a\x65\x0f\xb2\xc2\xea\x69\x08\x4b\xa5\xfb\xcd\xa4\x4c\x4d\x55\x5b\x55\x3d\x4b\x08\x0d\x22\x82\x20\x08\x1e\xd5\xa3\xac\x7a\x52\x2f\x1e\x02\xfa\x71\xcc\x6a\xbe\x85\xaf\xaa\xba\xba\xab\x7b\xd2\x9b\x38\x1e\x76\x33\xf5\x7e\xaf\xde\xfb\xbd\x7f\xf5\x7a\x55\x89\xdc\x30\x29\x88\x51\x54\x68\x4e\x0d\x9c\x6c\x41\x98\x14\xa6\xe4\xea\x80\x90\x2d\x55\xe4\x82\x89\x42\x93\x23\x77\x26\x64\xb2\xa1\x4c\x18\xfc\x37\x39\x24\x93\xbf\xff\xf8\xfd\xd5\x57\x2f\x27\x33\x8f\xd0\x33\x21\xd5\x86\x72\x8b\xdc\xfc\xf9\xf9\xcd\xf5\x75\x40\x4a\x05\x2f\xa8\x12\x4c\xac\x2d\x76\xfb\xc3\x17\xff\xfc\xf2\xd3\x04\xa1\xfa\xa0\xf1\xc1\x61\x0b\x3c\x72\xc2\xc4\x4a\x5a\xd5\x57\xdf\xfd\x7a\xfb\xd9\xf7\xc1\x8c\xb5\x61\xa5\x7f\x5d\xff\x78\xfb\xe5\xd7\x41\x9a\x2b\x66\x58\xee\xdd\xa2\xf8\xe6\xe5\xcf\xad\x69\xc8\x2c\x79\x34\xeb\x62\x58\xfa\xe3\xa9\x03\x9c\x47\x44\xbc\xe7\x65\x23\xf0\x18\xd3\xcf\x35\x5d\x01\xa2\xdd\xef\xb7\x90\xcc\xb7\xbf\x4d\x88\x8d\xed\x1b\x47\x5e\x81\xa9\x94\x20\x70\x80\xae\xa8\x58\x57\x9c\xaa\x6c\x23\x8b\x8a\x43\x9a\xd0\x4d\xa6\x41\x6d\x59\x0e\x3a\x99\x66\x2b\x9a\x1b\xa9\x2e\xd3\xc4\xe5\x36\xb1\xbc\x43\xde\xd3\xc7\x0a\xb4\xac\x54\xde\x24\xbc\x35\xdb\xca\xd3\x24\x99\x35\x10\x21\xac\x38\x24\xc9\xdb\xac\x48\xdc\xb9\xee\x80\xe4\xd3\x0a\xd4\x65\x72\xd8\x0a\x08\xd9\x80\x39\x97\x56\xff\xfd\x93\x8f\x92\x59\x2b\xae\x14\x47\xd9\x9c\x96\x6c\xbe\x7d\x73\x0e\x96\x91\x4e\x1a\xb4\x0e\x6a\x49\xae\x00\x7b\xe1\x6e\x7b\x1f\x3e\x7d\xf6\xdf\x0d\x52\x0e\x0a\xe5\xfb\x11\x9c\x1f\xb2\x62\xde\x58\xe8\x5b\xae\xa7\x0b\x57\x6c\xfc\x73\x57\x11\x72\x29\x8c\x92\x1c\x6f\xda\x3a\x74\xa7\xa6\x14\xef\x61\xbf\xbe\x63\x14\x1f\x94\x44\xe7\xb2\x84\x19\x79\x5c\xb1\xb3\x0f\x64\x41\xf9\x13\xa1\x0d\x15\x39\x8a\xdc\xad\x19\x61\x02\x5b\x8e\xf2\xe7\x9e\x52\xa8\x9c\xbf\x97\xf5\x41\x6c\xa2\xbe\x20\x56\xd5\xc0\x21\x37\x50\x74\xba\x57\x75\x8c\x43\x27\x71\x93\xe8\x7e\xad\xa4\x22\xe9\x05\x5a\x1d\xa1\x31\x66\x7d\x79\x71\xba\x43\x06\x65\x3e\x8d\xee\xff\xf9\x9c\x60\x8a\xb4\xe4\x38\x0d\x72\x9d\xde\x6d\xc6\x66\x3a\x72\x62\xe4\x7a\xcd\xe1\x99\xd3\x39\xb6\x0a\xe8\xa3\xcd\x24\x0d\x9c\xd8\x8a\x8c\x98\x5b\xd2\x8c\x15\x48\xec\x88\x54\xa2\x80\x15\x13\x50\xdc\x1b\x48\x73\x85\xd0\x85\x27\x4f\x70\x82\xa1\xbd\x54\xa0\xb6\x81\xd7\xde\x5d\xec\x11\xb4\xbf\x11\x87\xee\x67\x24\x8e\x77\x87\x39\x64\x6d\x65\x9f\x9e\x7d\x82\x16\xb3\x0b\xb8\xd4\xaf\x77\x42\x7c\x97\x35\xe6\x83\x2e\x84\x01\x69\x9d\x15\xd4\xd0\x69\x34\x4e\x83\x40\x1c\xbc\x68\xd1\x9d\x66\xce\x72\x2e\x35\xa4\xad\x4a\x7d\xaf\x83\xc0\x44\x29\xec\xc0\x23\xdc\x18\x15\x2c\x1e\xe4\xbd\x6e\xfe\x4e\xbb\xbc\xef\x3b\xb1\x7a\x7c\x5c\x1d\x3e\x98\x45\xff\x1a\x21\xd9\x65\x53\x73\x9f\x5a\xf7\x58\xa6\x57\xf7\x87\xec\x66\x8d\xd9\x59\xb3\x48\x36\x84\xfd\x58\x82\x4b\x46\x6f\x7d\xb6\xda\x4b\x76\x1a\x15\xc1\x2e\x17\x7d\x2e\x5f\x1c\x87\xa6\x58\x51\xec\xdc\x18\xef\xf1\xce\xca\x4a\x9f\xe3\x2a\xee\x14\xea\x07\x17\xeb\x8e\x7a\x78\xfa\x0b\xd2\x3e\x9c\xbd\xa7\xa8\x47\x2b\x58\x85\xce\xa4\x1d\xe1\xa6\x9d\xb1\x95\xf5\xc8\xc4\x86\x0c\xfb\x8e\x4e\xaf\xec\xc2\xc2\x27\xb1\xa8\x67\x51\x90\xa3\xa4\xef\x6f\x63\x9f\xc3\x88\x45\x57\x98\x58\xe9\x7f\xb9\xeb\xe5\x29\xe4\xaa\x9f\xff\x41\xbe\x1e\xc5\xe7\x07\x34\x79\xc1\x14\x0e\x3e\xdb\xba\xaf\x83\xf6\x90\xfa\x25\xf9\xc4\xc0\xa6\xdf\xe1\x83\x4f\x83\x10\x00\x7e\x21\x18\xc5\x72\x83\xdb\xf2\xa4\xdd\x9f\xae\x9a\xf1\x96\x75\x36\x51\xe5\x68\x67\x27\xa3\x9f\xd2\xb6\xec\xc7\x6e\xe1\xd2\xb2\x9c\x97\x54\xd9\xe5\xa0\xfd\xae\x7d\x83\xa1\x46\x76\x6e\x36\xbc\xf9\xdc\xd8\x73\xd5\x1e\x87\xa8\xc6\x66\x77\x30\xb4\x36\x95\xef\x02\x7e\x63\xf2\x68\x44\x76\x77\x4e\xa4\x32\xfa\xfe\xf6\x4d\x3d\xda\x95\x0e\x6a\xf5\x6f\x00\x00\x00\xff\xff\xcb\x88\x3e\x5f\x0f\x0b\x00\x00")
+var _uiAppJsExtensionJs = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xbd\x59\x5b\x6b\x1b\x47\x14\x7e\xf7\xaf\x98\x88\xc0\xae\x40\x59\xd1\x57\x0b\xd3\x9a\x34\x2d\x79\x48\x13\x1a\xf7\x49\x88\x30\xde\x1d\x49\x53\xed\x45\x9d\xdd\x95\x31\x8e\xa0\x2d\x69\x5a\x02\xa1\x09\x85\xde\xd2\x97\xa6\x6e\x02\x85\xa4\xa5\x09\x34\x34\xf9\x39\x95\x63\xff\x8b\x9e\x99\xd9\xd9\x9d\xd9\x8b\x6c\xcb\x4d\x03\x89\xbd\x73\xce\x9c\xf9\xce\x7d\xe6\x64\x98\x86\x6e\x42\xa3\x10\x25\x0c\x87\xb1\x8f\x13\x72\x69\x46\xc2\xc4\x26\x6d\xb4\xb7\x86\xd0\x0c\x33\x34\xa1\xa1\x17\xa3\x0d\xf1\x8d\x50\x2b\xc0\x34\x4c\xe0\x6f\x6b\x1d\xb5\x5e\xbf\x7c\x7e\x70\x67\xbf\xd5\x91\x14\xbc\x1d\x46\x2c\xc0\x3e\xa7\x2c\x5e\x7d\xbe\x78\xf1\x42\x51\xa6\x8c\xec\x60\x16\xd2\x70\xc4\x69\x47\x0f\x6f\x1d\x3e\x79\xd4\x02\xd2\x7c\x2d\x3b\xc3\x27\x33\xe2\x6b\x87\xd0\x70\x18\x71\xd6\x83\x1f\x9e\x1e\x7d\xfa\xa3\x12\xc3\x65\xf0\xd5\x7f\x5e\xfc\x7a\xf4\xe5\x5d\xb5\xea\x32\x9a\x50\x57\x1e\x0b\xcb\x8b\xfd\xc7\xb9\x68\xe2\x70\xf0\x20\x56\xe8\xd0\x97\x9f\x03\x41\x10\x27\x02\x45\x9e\xdc\xcf\x16\x24\x8d\xc6\x37\x62\x3c\x24\x40\x2d\x7e\x7f\x1b\xc0\x7c\xff\x7b\x0b\x71\xdd\xee\x09\xf0\xc4\x71\x53\xc6\xc0\x58\xd7\x13\x9c\xa4\x1c\x3c\x99\xe5\x5f\x60\x40\xc1\x12\x8b\xaf\x0f\x70\x40\x4c\x3a\xe1\x47\x1a\xfb\xf9\xd1\x8c\x24\x29\x0b\x11\x59\x03\xf4\xdc\x2c\xc6\x86\xcc\x3a\xad\x68\x4a\x84\x15\x0e\x7e\xfa\x6d\xb1\x7f\xeb\xf5\xbd\xdb\xc2\x10\x2d\xec\x4e\xc2\x68\xc7\x27\xde\x88\x78\xc2\x03\x7f\xfd\xf9\xfa\xe7\xa7\x87\x4f\xa5\x7b\x5a\x01\x18\x69\x04\xee\xcd\x69\xaf\xbe\x39\x7c\xfc\x8b\xa4\x31\x12\x47\xfe\x2c\x27\xc1\xfa\xe2\xf6\xb3\x16\x07\xd1\xed\xa2\xad\x31\x41\xb1\x82\x80\x43\x89\x09\x05\x78\x17\xc1\x4f\xc2\xd0\x90\x45\x01\x22\xd8\x1d\x67\x5c\x4e\x81\x7c\x8b\xc7\x14\xe5\xe1\x55\x06\xdf\x37\xe1\x76\x74\x7c\x1d\x0d\xd0\xa0\x4e\xb5\xfe\x52\x66\x5d\xd1\x7e\x99\xa8\x69\xda\x97\x58\x06\x99\x9a\x22\xec\x63\xb4\x43\x93\x71\x94\x26\x08\x67\xda\x20\xcc\x08\x52\xbb\x10\x1d\xa2\x64\x4c\x76\xd1\x0e\x81\x55\xd7\x8f\x62\xe2\x39\x6b\x43\x95\x42\xa6\xfb\x85\xbe\xb0\xc1\x56\x41\xd0\xce\x82\x5b\x39\x39\x5b\x16\xb1\xaa\xad\x4a\xb1\x9b\x09\x8f\xb8\x1c\x2e\x0f\x3b\x01\x97\xa3\xc5\xe1\x28\xf5\x31\x73\x82\xc8\x4b\x7d\x62\x5b\x38\x70\x62\xc2\x66\xd4\x25\xb1\xd5\x76\x86\xd8\x4d\x22\xb6\x6b\x5b\x42\x23\x8b\xab\xad\x10\xda\xe7\xb9\xc0\x94\xb9\xa4\x84\x25\x5f\xb7\x2d\xab\x93\x91\x00\xbc\xb7\x8e\xac\x77\xa8\x67\x89\xef\x79\x41\xb0\x3e\x49\x09\xdb\xb5\xd6\xf3\x05\x84\x02\x02\x76\xe3\xfc\xef\x5f\xda\xb2\x3a\xf9\x72\xca\x7c\x58\xeb\xe2\x29\xed\xce\xde\xea\x0a\x03\xc5\x56\x46\x9d\x2b\x36\xcb\x65\x04\x1c\x56\x2f\xef\xda\xd5\xeb\xa7\x17\x38\x22\xc9\x8a\xe8\xba\xeb\x4a\x5f\x5d\x1e\xf6\x09\x83\x73\x56\x17\xd9\xcd\x24\x54\x24\x27\x34\x20\x3e\x0d\xc9\x59\x64\xe7\x32\xaa\xb8\xdd\xc9\xaa\x56\x95\xa8\x61\x7f\x15\x72\x9e\xd5\x67\x92\xad\x89\x31\x8f\x98\xb7\x7b\x22\x27\xe0\x47\x5d\xa8\xbb\x51\x98\xb0\xc8\x07\x7b\xf2\x68\x2f\xbe\xb2\x80\x7f\x0f\x5a\xd0\xc5\x84\xf9\xa5\xc0\x8f\x5d\x48\x9f\x0e\x3a\x9f\xd2\xed\x2b\x91\x87\xfd\xcb\x21\x64\x5f\xe8\xc2\x92\xd8\xd5\x41\x34\x04\x2c\xd8\xbf\x21\x1d\xa5\xf2\x43\xee\x73\x4c\x22\xd4\x32\x73\x41\x67\x8d\x89\x4f\x5c\xa8\x3e\x05\xef\xde\x5c\xa7\x93\x62\x45\x34\x57\xf1\xdb\x30\x62\xc8\x9e\x80\xd4\x06\x18\x4d\xd2\xfb\x93\x41\x05\x0c\xac\x49\x33\x8a\x7f\xa1\xb2\x81\x89\xa0\x8a\x40\x83\x8b\x46\x76\xbd\x18\x6e\x69\xed\x90\x24\x1a\x8d\x7c\x72\x5d\xf0\x6c\x72\x06\x38\x23\xb7\x24\x56\x98\x78\x69\x6b\x40\x85\x1d\xea\x01\xb0\x0d\x94\x86\x1e\x19\x42\x64\x7a\xc7\x2a\x92\x6d\x41\xb8\x27\xc1\x23\x68\xca\x24\xdf\xe4\x01\x77\x42\x96\xee\xed\xad\xa0\xb4\xdc\xa1\xab\x2e\x2b\x91\xae\x6f\x05\x39\x71\x72\xcf\x5e\xdd\xfe\x18\x24\x3a\x13\xb2\x1b\x2f\x3f\x04\xc9\x28\xcb\xc4\x2b\x5e\xa2\x92\x24\x3f\xcc\xc3\x09\x6e\x6b\x39\x55\x52\x44\x90\x7b\x39\xb5\x12\xcc\xb2\x77\xd8\x39\xcb\xfc\xd8\x03\x14\x12\xc6\x20\x02\x37\xe0\x12\x98\x92\xde\x89\x4e\x9f\x67\x3f\xdb\x85\xdd\x57\xcd\xd8\x78\x49\xba\xfa\x91\x8b\xf9\x4a\x96\xa6\xa5\xb4\x8c\x8b\x9b\x91\x71\x53\xea\x55\x99\x80\x25\x17\x06\x4e\xc2\xcc\x1d\xdb\xed\xbe\x25\x89\xd6\x00\xdd\xbc\x89\x2c\xcb\x4c\x03\x3f\xc2\x5e\x7d\x24\xf0\xc4\x9d\x62\x86\x03\x99\xde\xca\x26\x7a\x46\x18\x1d\x9f\xff\x91\xfc\x1a\x1a\x9d\xb1\x6c\x55\x19\x2d\xa2\xcb\xda\x72\x63\x51\x4f\x1b\x7d\x59\x78\x53\x5e\x65\x36\x50\x7f\xd0\xd3\xa8\xa2\xc8\x50\x5e\x64\xf8\x4e\xa7\xba\x5d\x2a\x46\x44\x1c\x18\x8f\x81\x9c\xbf\x4f\x07\xed\x9e\xb1\x03\x34\x18\x47\x3b\x9b\x2a\x23\x86\x18\xd2\xd6\xe4\x30\x40\x39\xd3\x34\x1e\xc3\xe5\x48\x67\x99\xe7\xbf\xcf\x4f\xa5\x65\x16\xb3\x39\x38\xb9\x92\x9b\xb2\xad\x57\x04\xcd\xa5\x76\xa9\xda\x0d\xa9\xcf\xaf\xb1\x9a\xa3\xcb\xce\x2b\x07\x92\xe9\xb3\x4a\x58\xa9\xa0\xea\xa8\x2b\x24\xc4\x56\x98\xfa\x7e\xae\x74\x09\x4c\xb5\x0a\x99\x26\x55\xb0\x48\x81\x48\x5e\x2b\x45\x89\x81\x1a\x14\x37\x94\x5a\x15\x47\xb2\x14\xd9\x7b\xfc\x3e\x07\xbd\xcc\xd3\xcc\xbc\xd4\xd0\xc7\xd5\x1f\xe9\x7f\x0d\x45\xe1\x0b\xc3\xbf\x67\x39\x4e\x86\x69\x0f\x15\x31\x52\x29\x41\x25\x7b\x9d\xd3\xbf\xcf\x5e\x9d\x96\x14\x27\x06\x0f\x05\x72\x4d\xa6\x67\x51\x8c\xeb\x4b\x55\x73\x31\x91\x3e\x82\x1b\xab\x74\x90\x2e\xd5\xf4\xd5\x09\x13\x7f\x49\xfa\x1a\xbe\x53\xcd\xde\x78\xa3\x95\x9f\x6d\x7d\x5d\x72\xe9\xbd\xda\x7b\x23\x79\x5b\x69\x2d\x75\x51\x7c\x16\x23\xe5\xdd\xbb\x26\x58\xdf\xa8\x22\xc7\x56\x22\x11\xb6\xee\x84\xdf\x60\x9b\xfb\x4e\xa0\x37\x7c\xde\x47\x54\xdc\x39\xfc\x7d\x68\x17\x38\x71\x48\x03\x51\x97\xd6\x45\x5b\x2f\x34\x4b\x48\x30\xe5\xc1\xf1\x91\xba\x98\x4f\xbb\xd0\x63\xf8\x0d\x32\x96\xd7\xf3\x0b\x70\xed\x77\xc6\x49\xe0\x6b\xb7\xf8\x22\x2f\x60\x8f\x70\x08\x20\xcd\x73\x43\xfe\xc9\x5e\xab\xeb\x86\xb1\x84\xc4\xf5\x5c\x1b\x64\x97\x93\x5e\x3d\x41\xb5\x40\x6b\xe8\x0e\x6b\xe5\x0a\x60\xd8\xc2\x81\xe3\x53\x3f\x71\xe0\x81\x1e\xda\x35\xc6\xab\xad\xbb\x7a\x9f\x58\xab\xcd\x0a\x3e\xba\x29\x06\x53\xda\xf8\xe5\x43\x22\x7e\x53\xea\x57\xe6\x2f\x57\x30\x9b\x20\x73\xca\xa1\x58\x8d\x59\x8c\xe0\xd3\x06\x1a\x8a\x49\x1f\xca\x5c\xe4\xf7\xba\xd6\x52\x9c\xcb\x1a\x98\x4c\xa0\x82\xb7\x21\x89\xd0\x9e\xdc\xb8\x9e\x35\xae\x95\xb2\x81\x77\xba\x9a\x32\x63\x1a\xfd\x7f\xc8\xb4\x55\xeb\xbd\x1e\xd6\xa7\x78\x3e\x92\x9a\x9a\xaf\xca\xb1\x16\xd3\xaa\x06\xb9\x93\xec\xe6\x68\xe4\x7f\xba\x0d\x71\xb0\xac\x4b\xc0\xbe\xac\x8d\x8b\x4f\xe1\xb4\x42\xe4\xc9\x4c\xda\xf0\x68\xa8\x6b\x12\x6f\xd8\x49\xc6\xc3\x8b\x43\xf1\x1b\x1e\x5e\x15\xc8\x1e\x8d\x03\x1a\xc7\xf6\x7f\xe0\xef\xad\x6c\x78\x72\xd2\x3e\x5f\xd7\xdc\x29\x6f\xed\xa5\x74\xca\x5c\x5b\x1d\xa0\x8b\x89\x35\xc9\x27\xb1\x5f\x3d\x58\xbc\xfc\xbb\xc8\xfa\x74\xea\x29\xd2\xc1\x83\xe7\x07\xdf\xfe\x51\x90\xe4\x60\x50\x6c\xfa\xe2\xd9\xd1\x77\x4f\xb4\xd2\xc3\x5b\xda\x0d\xb0\x0d\x53\x52\xef\xdf\x39\x7c\xf2\xe8\xf0\xf1\xa3\xc5\xd7\xf7\xcb\x6c\xc6\xb0\x57\xf0\x1d\x7c\xf6\x70\xb1\x7f\xb7\xb9\x92\x49\xae\x6c\x98\xac\xfb\x2e\xab\x2b\x99\x05\x97\xb7\xe6\x63\x9f\xa0\xe0\x17\x4a\x1a\xfa\xb2\x78\xba\x70\x53\x8a\xe7\x8b\xb9\xa3\x3e\x18\x25\x0d\xde\x2b\x62\xe4\x9f\xcd\xdf\xe5\xff\x02\xd4\xb3\x0c\xaa\x81\x7a\xea\xe7\x73\xd3\x53\x64\x2d\x0f\xfb\xc6\x28\xf5\x28\x23\x70\xc6\x4c\x8c\x70\xf3\x0f\x5b\x4e\x1e\x2f\x43\xcb\x36\x63\xb3\x34\xbf\x55\x88\xc0\xb7\xa0\x94\x9b\xf0\x1e\x9d\x37\x66\x81\x51\x6f\xca\x42\x26\xb0\x6c\x54\x66\x7b\xe6\xd5\xc0\xb8\x19\x88\x4d\x17\x28\x70\xc8\xab\x81\x8c\x83\x15\x27\x75\x9b\x4a\xab\xa6\xac\x2b\x3f\xf4\xe1\x66\xf4\x2e\x49\x30\xf5\xb5\x47\x66\x75\x64\xa5\xb1\x34\x8e\x6f\x4c\x51\xe7\xaa\xab\xa5\x8a\xf2\x2f\x22\x4c\xed\x83\x21\x1b\x00\x00")
 
 func uiAppJsExtensionJsBytes() ([]byte, error) {
 	return bindataRead(
@@ -169,7 +172,7 @@ func uiAppJsExtensionJs() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "ui/app/js/extension.js", size: 2831, mode: os.FileMode(436), modTime: time.Unix(1466750099, 0)}
+	info := bindataFileInfo{name: "ui/app/js/extension.js", size: 6945, mode: os.FileMode(436), modTime: time.Unix(1792157657, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -234,6 +237,26 @@ func uiAppPartialsAlertsHtml() (*asset, error) {
 	return a, nil
 }
 
+var _uiAppPartialsEventAckHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xa5\x52\x3b\x4e\x03\x31\x10\xad\x93\x53\x0c\x16\x05\x14\xc9\x5e\xc0\xbb\x12\x70\x12\xaf\x3d\x59\xac\xcc\xda\x8b\xed\x4d\x88\xa2\xd4\x9c\x03\x89\x82\x8a\x86\x8e\xf3\xf0\x3b\x06\x5e\x7b\xf3\x91\x10\x15\x95\xc7\x33\xf6\x9b\x37\xf3\x1e\x5f\x58\xd7\x82\xb1\x2b\x41\x5a\x89\x80\x60\x44\x8b\x25\x1b\xb2\x0c\x24\x09\xef\xf3\xc5\xb3\x6a\x3a\xe1\x0b\x8d\xa4\x3c\x06\xd0\xaa\x64\xb8\x42\x13\x66\x42\x2e\x87\xd2\x84\x13\x36\x68\x54\xc5\x7d\x27\xcc\xfe\xa7\x42\x2f\x59\x75\x25\x97\xc6\xae\x09\x55\x83\x20\x88\x60\xa1\x9d\x36\x4d\x0c\xd1\x05\x0f\x76\x01\x09\x09\xb6\xdb\x1c\xcc\xb5\x82\xdd\x6e\xce\x8b\x01\xa9\xe2\xc5\x08\x3c\x4d\x4d\x44\x8d\x54\x7d\x3d\xbe\x7c\xbf\x3c\xbd\xbf\xbd\xc5\x62\x4a\x0c\x25\x67\xd7\xc3\x39\xe1\xd2\x52\xdf\x9a\x14\x4f\xb8\x36\x5d\x1f\x20\x6c\xba\x38\x54\xc0\xfb\xc0\xa0\x23\x21\xf1\xd6\x92\x42\x57\xb2\x03\x12\x03\xd3\xcc\x5a\xab\x90\x4a\x16\x47\x9a\x4b\x87\x71\x1b\xea\x7a\xc3\xc0\xe1\x5d\xaf\x1d\xaa\x8c\x5e\x1c\xe1\x79\x91\x7a\x1e\x79\x7d\x3c\x3d\x7c\xbe\x3e\xff\x9b\x54\x86\xf9\xc5\xc8\xb6\x6d\xdc\x0e\xfb\x8b\x46\x3c\xf7\xf2\x0c\x9c\xb8\xd2\xab\xbd\x0c\x75\x30\xb3\xc6\xd9\xbe\xcb\x4a\xd5\x7d\x08\xd6\x8c\xfd\x3b\xa7\x5b\xe1\x36\xa9\x9b\xd2\x5e\xd4\x51\xa7\x2c\xf9\xfc\x5c\x9b\x64\x8b\x54\x93\xa4\xe5\xb2\x64\xbe\xaf\x5b\x1d\x2e\x2e\x19\xf4\x5d\x87\xee\x54\x5b\x5e\x64\xe0\xdf\x3d\x6a\x1a\x5c\x72\x82\x22\x85\x91\x48\x47\x94\x9b\x74\x3f\x01\xe0\x45\xa4\x5f\x8d\x53\xc4\x7f\xfe\xd6\xae\xa3\xe3\x9c\xb3\x0e\xce\x4a\x30\x3d\xd1\xc1\x9e\xc9\x47\xd9\x4d\xb3\xf4\x22\x4f\x79\xea\xc3\x31\x3d\x38\x2c\x41\xec\x76\xa3\xbb\x0e\x9d\xe2\xf2\xe2\xc8\xd5\xf4\x07\x76\xe3\xe6\x11\x10\x03\x00\x00")
+
+func uiAppPartialsEventAckHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_uiAppPartialsEventAckHtml,
+		"ui/app/partials/event-ack.html",
+	)
+}
+
+func uiAppPartialsEventAckHtml() (*asset, error) {
+	bytes, err := uiAppPartialsEventAckHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "ui/app/partials/event-ack.html", size: 784, mode: os.FileMode(436), modTime: time.Unix(1792149522, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _uiAppPartialsEventFormHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xac\x55\x4f\x4f\x23\x37\x14\x3f\x87\x4f\xe1\x5a\x3d\xc0\x61\x48\xe9\x91\x4e\x46\x6a\x7b\xea\xb5\x7c\x80\xca\x33\xf3\x92\x58\x38\xf6\xd4\xf6\x90\x70\xab\xaa\x56\xd0\x56\xbd\xd3\xf6\x52\x21\x28\x95\xaa\xb2\x42\x42\x2b\x94\xec\xc7\xc9\x04\xf2\x2d\xf6\x8d\x3d\x93\x9d\x40\xb2\x41\xbb\x48\x10\x8d\xed\xdf\x7b\xbf\xe7\xdf\xfb\xe3\xb0\xab\xf4\x80\x48\x75\xc4\x04\x4f\x99\x05\x22\xd9\x00\x3a\xb4\xdc\xa5\x24\x11\xcc\x18\xbf\x30\x34\xda\x6a\x85\x39\x8f\x03\xcb\x62\x03\x16\x57\x8b\x25\xe1\x32\x85\x51\x87\x7e\x46\x49\x1f\x58\xca\x65\xaf\x43\xbf\x62\x06\xc8\x37\xb2\xab\x4a\x3b\x84\x76\x39\x88\x14\xed\x08\x4f\x3b\x14\x8e\x40\xda\x20\xd1\x80\x84\xfe\xbc\x15\x0a\xe8\x81\x4c\xa3\xd0\x64\x4c\xd6\xc4\x29\x98\x84\x46\x5f\x3b\x1c\x61\x44\xc2\x90\x38\xd3\xdd\xb0\x5d\xc2\xa2\xb0\x5d\x59\x6d\x55\x3e\x58\x0c\x22\x9a\xfd\x7d\x32\x3f\x3f\xc3\x33\xb7\xf2\x27\x5a\x0d\xfd\x57\x2b\x4c\x94\xc8\x07\xb2\x5a\xb5\x42\x2e\xb3\xdc\x12\x7b\x9c\xe1\xad\x2d\x8c\x2c\x25\x99\x60\x09\xf4\x95\x48\x41\x77\xa8\x77\x46\x89\xec\x05\x03\x95\x82\xc0\xe0\x77\x2d\xb7\x02\x28\xd1\xf0\x7d\xce\x35\xa4\xb5\xe3\x76\xd3\x73\xd8\x76\x94\xcd\xb8\xee\x6f\x26\xc5\xe9\x7f\xcf\x8d\xcb\x80\x80\xc4\x2e\xf1\x1e\xa2\xce\x4f\x68\x11\xaa\x32\xcb\x95\x24\x98\xc2\x1c\x2f\x41\xa3\x20\xf0\xc6\x41\x10\xb6\xfd\xd9\x3a\xec\x80\x71\x69\xf1\x9f\x46\xf7\x93\xdb\xd9\xaf\x17\x9b\xf0\x2c\x96\x58\x0b\x4c\xd0\xa8\x78\xf3\x63\x71\x77\xb7\x09\x9f\x69\x18\x32\x2d\xb1\x22\x68\x34\x3f\xff\xe9\xe1\xff\x7f\x1e\x5b\x60\x26\x5d\xac\xcf\x16\x71\x7c\xf5\x71\x22\x0a\xac\x20\xf1\xb2\x2a\x72\x57\xe6\xb3\x3f\xae\xe7\x3f\xfc\xb9\x09\x5b\xca\x41\xa3\xe9\xdd\xe5\xfc\xe4\xf7\x4d\xd8\x44\x73\xcb\x93\x52\x6d\x04\x17\x17\x57\x1f\xae\xdd\x7a\x85\xbc\x8e\xc5\xf5\x2f\xc5\xcf\xff\x4e\xc7\xbf\x4d\x27\xaf\x97\xb4\x7d\xe4\x74\xb5\x79\xdd\xae\x49\x1f\x92\xc3\x58\x8d\x68\xb4\xd4\x55\x8b\xed\xa5\x3c\x70\x73\xc0\xba\xd8\xfd\xb3\xb3\x57\xef\x63\x5c\x51\x02\xc5\xe9\x5f\xc5\x64\x3c\x1d\x8f\x5f\xa8\xc5\x17\xfe\x96\x03\x74\xe3\x49\xe9\xe7\xf5\xb9\xfb\xaa\x67\x9c\x0f\x37\x4c\xf9\x51\xad\x4c\x6c\x65\xd0\xd3\x2a\xcf\xea\x69\x17\xe7\xd6\x62\xa2\x7d\x4c\x99\xe6\x03\xa6\x8f\x1d\x7d\xca\x0d\x8b\x05\xa4\x7e\xea\xee\x7e\xca\xa5\x9b\xcc\xee\x2c\x11\x3c\x39\x2c\xcb\xa2\x9c\x87\xdb\x3b\x94\xe4\x59\x06\xba\x9a\x8f\x61\xdb\xfb\xac\x62\x41\xf2\x68\x11\x05\xda\x9a\xbe\x1a\xe2\xad\xb4\x56\x9a\x7c\xd2\x21\x32\x17\x62\x31\xdf\x99\x00\x6d\x89\xfb\x0d\x1c\xa2\x8e\xb2\x39\x8b\xab\x03\x2f\x56\x71\x71\xf3\x70\x7b\x59\x4d\xe1\x25\xc2\xb0\x5d\x3d\x0a\xab\x1e\x88\xbd\xc6\x03\xf1\x65\x49\x67\xaa\xd7\x61\xfd\xf0\xff\x16\x04\x5e\x2e\xf5\xd1\x99\xa7\x83\xbf\x71\x45\x0d\x19\x0a\x81\xd7\x41\x3a\xfc\xc3\xde\x61\xe2\x3b\xd6\xa0\xf1\x48\x63\x8f\x45\xf9\xc2\x09\xc5\xec\xbe\x80\xae\xfd\x02\xc5\xef\x71\x19\x58\x95\xed\xef\x7d\x9e\x8d\x68\x9d\xe8\xd5\x49\x32\x38\xff\x44\xb4\xba\xf2\x89\x6f\x48\x48\x3d\xef\xda\x46\xa8\xcc\x1e\xa1\x89\x03\x94\xb9\xb7\x3a\x87\x66\xca\xad\xea\xf5\x04\x1c\x38\xb8\x13\x6e\x9b\xed\xd0\xa8\xae\xff\xa5\xd4\x37\x72\xdf\x0a\x7d\x4e\xb9\x85\x41\x4d\x29\xb8\xf1\x1b\xd4\x4b\x8a\x72\x95\x8e\xde\x01\xd7\xa6\x13\xeb\x1b\x2b\x32\xda\x7a\x1b\x00\x00\xff\xff\xe8\x71\xdd\x3e\x32\x08\x00\x00")
 
 func uiAppPartialsEventFormHtmlBytes() ([]byte, error) {
@@ -254,7 +277,47 @@ func uiAppPartialsEventFormHtml() (*asset, error) {
 	return a, nil
 }
 
-var _uiAppPartialsEventsHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x8c\x92\x31\x4b\xf3\x40\x18\xc7\xf7\x7e\x8a\x87\x9b\xde\x77\x68\x82\x6e\x96\x4b\xa0\x83\xb3\x8b\xbb\x5c\x93\xb3\x39\x7b\x4d\xc2\xdd\x59\x87\xda\x51\xac\xf8\x01\x2a\xb8\x88\x8b\x82\x20\x08\xd2\x21\xf9\x3a\x49\xa5\xdf\xc2\xcb\x25\x26\x69\xa1\x90\xe9\xb9\x5c\x9e\xff\x2f\x0f\xf9\x3d\xd8\x67\x33\x60\xbe\x83\xe8\x8c\x86\x4a\x22\xb7\x07\x80\x83\x63\xf7\xd4\x3c\x62\x5b\x1f\xcd\x8d\xb0\x4d\x2d\xba\x3d\x4e\xa4\x74\x10\xe1\x54\xa8\xfe\x58\x44\xd7\x31\x82\x70\xdc\x17\x34\xa6\x44\x55\x20\x60\x21\xb4\x88\x07\x93\xfd\x80\x12\x9f\x0a\x68\x30\x1e\x67\xde\xc4\x41\x32\x88\x6e\x86\x45\x9f\xfc\x67\x38\xff\x2b\x8e\x26\xc9\x98\x84\x7f\x28\x3e\xe2\xc8\xdd\xbc\xdc\x6f\x5f\x9f\x06\x30\x9f\x97\xdf\xb4\x14\x53\x9c\xc2\x62\x01\xd8\x2e\x9a\x0f\x27\x7f\xbe\xd2\x7c\xf9\xd1\x4a\x4e\x58\xe8\x77\x0a\x26\x6f\xbb\x41\xae\x0b\xef\x92\xcc\x3f\x1f\xf2\xbb\xf7\x2c\x79\xcc\xd2\x75\x2b\xcf\xe4\x85\x24\x97\x9d\x86\xce\x97\xcf\x79\x9a\x64\x49\xd2\x8a\x7b\x42\xff\xfb\x48\x74\x8f\x6f\x56\xeb\xed\xea\x7b\x9f\x40\xfd\xa1\x82\x5b\xe0\x6c\xca\xd4\x79\x34\x38\x3a\xd9\x07\x62\x5b\x7b\x74\x7b\x8d\xd2\x96\x78\x52\x4b\xb7\x8c\xe1\xb3\xd1\x95\x34\x4a\x0b\x97\xd5\x5e\x58\x8d\xd7\x46\x68\xb9\x0f\x4c\xd1\x69\x3d\x28\x93\xe5\x05\x02\xf3\x52\xc3\x11\xb8\xd8\x6e\x3a\x77\xc6\xa9\x0f\x55\xf9\x0d\x00\x00\xff\xff\xb3\x7f\xea\x05\xd5\x02\x00\x00")
+var _uiAppPartialsEventTimelineHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x8d\x52\x4d\x6f\x83\x30\x0c\xbd\xf7\x57\x78\xd9\x21\x27\x88\xb4\x23\x02\x26\x4d\x9a\xd4\x4b\x77\xda\x1f\x08\x24\x2d\x51\x43\x40\x49\xe8\x84\x3a\xfe\xfb\x9c\xf0\xb1\x56\x9b\xa6\x21\x01\x76\xfc\xec\xf7\x6c\x27\x17\xea\x02\x4a\x14\x44\x5e\xa4\xf1\x89\x57\xad\xd4\xca\x48\x52\xee\x00\xf2\xe6\xa9\x7c\x0d\xc7\x70\xbd\x22\x06\xa6\x09\xde\x97\x78\xce\x30\x16\x21\x96\xc5\x7f\x28\x63\x4e\x89\x6b\xba\x0f\xac\x65\x6d\x67\xe1\xa1\x00\x33\x68\x4d\xa0\xd6\xdc\xb9\x82\x70\x2d\xad\x87\xf8\x4d\x22\x22\x92\x60\xae\xeb\xb9\x59\x41\x4b\x00\x09\xe7\x22\xd3\x94\xb3\x10\x8f\x24\x0c\x59\xca\x5d\xb0\x78\x20\x6b\xac\x3c\x16\xe4\x91\x45\xe9\x8e\xad\x22\x49\x99\x57\x83\xf7\x9d\x01\x3f\xf6\xb2\x20\xbd\x55\x2d\xb7\x23\x01\xd7\x72\xad\x61\xe8\x7b\x69\xcb\x17\x5e\x9f\x73\x36\xe3\xca\x9c\xf1\xb9\xac\xe7\x95\x96\xab\x94\xe8\x24\x47\xcd\xfd\x2a\xd4\x57\x9d\x18\x67\x3b\x78\x36\x88\xb0\xb2\x97\xdc\xa3\x70\x50\x06\x50\x87\x55\xd2\x91\x15\x13\x50\x22\x36\x93\x86\xc9\xc2\x27\x08\xee\x65\x46\x47\x7c\x92\xc3\x21\x11\x02\xf6\xfb\xac\x6d\x33\xe7\x68\x6c\x15\xd1\x77\xa9\x77\xb3\xd1\x95\x86\x58\xeb\xac\x8c\x80\xa2\x00\x5a\xeb\xce\x49\x41\xe1\x19\x28\x06\x93\x46\x9d\x1a\x8d\xaf\xa7\x90\x01\xa5\x71\x16\x5b\xc2\x1b\x47\x01\xdb\x38\x7f\x52\x6d\xce\xba\x91\xef\x75\xa6\x71\x69\xe4\x56\x47\xa0\xeb\x06\x3f\xdf\x95\xc8\x11\x31\xa9\xe6\x95\xd4\x6e\x76\xcc\x1d\xe3\x9f\xf5\x07\xdf\xac\x6b\x5f\x9c\x7f\x26\xd6\xe7\xb4\xee\xda\x16\x07\x4f\xca\x6c\x9e\xce\xcd\xd9\x2f\x45\x6e\x1b\x47\xdb\x2e\xab\x65\xdb\x6e\xd1\x0c\x8b\x2f\x77\xcb\x75\xfb\x02\x17\x7c\x50\xe5\x22\x03\x00\x00")
+
+func uiAppPartialsEventTimelineHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_uiAppPartialsEventTimelineHtml,
+		"ui/app/partials/event-timeline.html",
+	)
+}
+
+func uiAppPartialsEventTimelineHtml() (*asset, error) {
+	bytes, err := uiAppPartialsEventTimelineHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "ui/app/partials/event-timeline.html", size: 802, mode: os.FileMode(436), modTime: time.Unix(1792149522, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _uiAppPartialsEventHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xa5\x55\x41\x6b\xdb\x30\x14\xbe\xf7\x57\x68\xda\x21\x09\xcc\x35\xac\xb7\x60\x1b\x72\xd8\xe8\xa5\x3b\xed\x36\x46\x91\x6d\x25\x16\x96\x65\x23\x29\x29\xa1\x2d\x6c\x87\x42\xb7\xfd\x80\x1c\xf6\x03\x56\x18\x1b\x8c\x8d\x1c\x92\xbf\x93\x74\xf4\x5f\xec\x49\xb6\x93\x78\xd9\x12\x42\x73\x88\xa4\xf7\x9e\xbe\xef\x93\x9f\xde\x93\x17\xb3\x11\x62\xb1\x8f\xe9\x88\x0a\x8d\x83\x23\x84\xbc\xe4\x79\xf0\xc2\xac\xd0\xe5\x25\xb2\xe6\x63\x16\xa3\xeb\xeb\xee\x7a\xad\x99\xe6\x14\x4c\x9e\x0b\xb1\x76\x8b\x74\xed\x68\xd0\xc4\xc0\x51\x49\x7e\x01\x90\x52\xe6\x12\x3d\xf1\x91\x18\x72\x8e\x51\xc4\x89\x52\x3e\x26\x9c\x4a\x8d\xec\xbf\x63\x23\x2c\x29\xec\x55\x05\x11\x75\x50\xe5\x30\x84\x16\xc4\x50\x19\xbf\x25\x71\x81\x25\x38\xaa\xe9\xaa\x1d\x03\x99\x0f\x8b\x1a\x6a\xc3\x1e\x6a\xe1\x58\x1f\x92\x6c\x90\xe8\x2a\x02\x62\x88\x11\x9a\x48\xda\xf7\xf1\x53\xd7\x1e\x4b\xb9\xcd\x03\xbb\x9a\x65\x94\x33\x41\x71\xe0\x85\x43\xad\x73\x81\xf4\xb8\xa0\x3e\x2e\x24\xcb\x88\x1c\x63\xa4\x32\xc2\x39\x1a\x16\x05\x95\xc1\xeb\x2a\xd8\x73\xcb\xd8\xc0\x73\xc9\x8a\xac\xb1\x3d\xe4\x24\x4a\xb1\xa1\x8f\x38\x8b\x52\x1f\x9b\xcf\xd5\x8b\xd2\x97\xb9\xcc\xda\x1d\xeb\x48\x58\x4c\xab\x9c\x1c\x47\x3c\x57\x34\xee\xe9\x26\x1d\xc4\x8b\xfc\x82\xd3\x78\xb0\x66\xdc\xc3\x26\x69\x41\x89\x06\x3a\xc4\xc0\x29\x89\x50\x4c\xb3\x5c\xa8\x4d\x29\x6b\x73\x5b\x75\x9a\x8c\xf0\x71\xd6\xde\x57\x24\xa3\xea\x8d\x7a\x6b\x33\xb3\x49\x5f\x65\xa7\x91\x26\x4d\x42\xb8\x2e\x55\x42\xec\xc2\xe9\x73\x52\xe7\xc2\xd3\x61\x1e\x8f\x57\xe2\xb5\xac\xa7\x66\x11\x07\xbf\x3f\x4e\xef\xdf\xbd\xf7\x5c\x98\x36\xec\xab\x5c\x29\x4d\xf4\x50\x19\x41\x56\xcc\x3a\x0e\xe6\xf2\xff\xb0\x3f\xe6\xcb\xdb\xaf\x3b\x60\x53\x26\xe2\x83\x00\x67\x5f\x76\x03\x72\x18\xf8\x21\x88\xcb\xef\x1f\x96\x37\x77\x8b\xd9\xa7\xc5\x7c\xba\x03\x97\xa9\x73\x45\xfa\x07\x1d\x7e\x79\xfb\x79\x39\x9f\x2d\x66\xb3\x1d\xb0\x91\x84\xcb\x52\x95\xde\x41\xb0\xf7\x93\xe9\xc3\xe4\xd7\x3e\x64\x73\xa3\xd1\x15\x8a\x61\xd6\x6d\x8d\xe1\xe7\x9c\x9d\x39\x71\x8c\x4e\x4f\xbb\x59\xd6\x55\xaa\xb5\x9b\x78\xa3\xcb\x34\x8b\xe4\x2f\x45\x37\x3f\x1f\x26\xdf\xf6\x2b\xaa\x76\x3f\x52\x50\x5d\x60\x6d\x01\x97\xf1\x19\x1a\x11\x3e\xa4\x1d\x53\x6c\xd5\x05\x20\x21\xe5\x0a\x6f\x69\x10\xdb\x57\x77\xe5\xb3\x18\x8f\x66\x26\x42\xe4\x50\x25\xb6\xd8\x9b\x14\x9e\xd2\x32\x17\x83\x86\x8c\xca\xb4\xad\xa7\x6c\xd1\x40\x17\x42\x6d\x38\x89\xce\xb8\x8f\x4b\x81\x57\x08\x5a\x5f\x3a\xee\xb6\xce\xa1\xdd\x88\xb4\x05\x1d\xb3\xec\xd7\xff\xd6\x0d\xb3\xba\xe6\x61\x6a\x1a\x42\xd9\x28\x92\x93\xa0\x67\x1e\x06\x05\x0f\xcb\xc9\xe6\x83\x52\x9f\x8f\x98\x33\xd9\xb7\xa3\x3e\x88\x57\xbe\x24\x4c\xd3\xac\xee\x30\x9c\xa9\xd2\x80\xcb\x50\xd8\x66\xf4\xac\x03\x37\xda\x53\x35\xfc\x01\x69\x9a\x3f\x5e\x07\x07\x00\x00")
+
+func uiAppPartialsEventHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_uiAppPartialsEventHtml,
+		"ui/app/partials/event.html",
+	)
+}
+
+func uiAppPartialsEventHtml() (*asset, error) {
+	bytes, err := uiAppPartialsEventHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "ui/app/partials/event.html", size: 1799, mode: os.FileMode(436), modTime: time.Unix(1792157657, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _uiAppPartialsEventsHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x8d\x54\xdd\x8a\xd3\x40\x18\xbd\xdf\xa7\xf8\x1c\x85\xb4\x60\x1a\xf5\xce\x92\x44\x16\xf4\x76\x15\xf4\x5e\xa6\xcd\xb4\x19\x3b\xf9\x61\x66\x52\x59\x6a\x41\x11\x71\x57\x1f\x60\x05\x6f\xc4\x1b\x17\x04\x41\x90\x5e\xb4\xaf\xd3\x56\xfa\x16\xce\x4f\xd2\x64\x6b\x57\x7b\x93\x9f\x99\xef\x9c\xef\xe4\x9b\x73\xe2\x47\x74\x0c\x34\x0a\x10\x19\x93\x54\x0a\x14\x1e\x01\xf8\xf1\xbd\xf0\x91\x79\xf5\x3d\xf5\x68\x56\xb8\x67\xee\xba\x3a\x1d\xba\x22\xce\x5e\x2a\x08\xe7\x19\x87\x1b\x01\xa4\x05\x63\x08\xfa\x0c\x0b\x11\x20\xcc\x08\x97\x60\xae\xae\xa9\x30\x9c\x0a\x2b\x72\x9c\x56\x45\xe5\xc6\x64\x02\x96\x64\x3a\xf5\x3d\xbd\x6f\x9a\x78\xaa\x4b\x78\x54\xb5\x2b\x11\x43\x9e\x15\x79\x45\xd5\x58\xef\xc9\xd4\x6d\xee\xa9\xdd\x5e\x21\x65\x96\x6a\x9d\x7d\x46\xfb\xa3\x00\x0d\x28\x93\x84\xb7\x1c\xa7\x8d\xf4\x2a\x96\x92\xbb\xf2\x34\x27\x01\x52\x02\x84\xc4\xb2\x10\x10\x04\xe0\x38\xf0\x00\x9c\x9c\xd3\x04\xf3\x53\x07\xba\xe0\xf4\x18\xee\x8f\x1c\xa5\x0e\x81\x48\x30\x63\xe1\xea\xdd\xe5\xe6\xed\xa5\xef\xd9\x16\x7b\x3a\x72\x92\x13\x2c\x03\xd4\x12\xb7\x21\xc5\x09\x69\x03\x4d\xcb\x16\x44\xa0\xbf\x35\x89\x7f\x4b\x12\xff\x55\xa4\xca\x75\x1f\x33\xc1\xa6\xaa\x72\x8a\xd7\x8d\xd3\x9e\x8f\x1d\x5c\x53\xb7\xb1\x81\xd6\xdc\xf0\xc3\xb5\x48\x37\x26\x38\x22\x1c\x6a\x9a\xf2\xe3\xb4\x3f\x8e\x75\x9d\x68\x19\x9e\x76\x7d\x38\x4d\x17\xb0\x1e\x43\xe1\xfa\xcb\xfb\xcd\xd7\x4f\x5d\xd0\x5e\xd0\xb5\x1d\x49\x25\xd3\xdf\x03\xb5\x25\xf6\x23\x7f\xff\x5c\xac\xce\xbe\x37\x90\x23\x9a\x46\x07\x01\xe7\xdf\xae\x02\x99\xba\xb1\x43\x90\xab\x1f\xe7\xca\x02\xcb\xf9\xc7\xe5\x62\xd6\xc0\x53\xf1\x5c\xe0\xc1\x41\xa2\x57\x67\x9f\x57\x8b\xf9\x72\x3e\x6f\xc0\xfb\x5c\xcd\xde\xa4\xe0\x40\xf8\xfa\x62\xb6\xb9\xf8\xb5\xcb\x40\xa2\x63\x09\xaf\x80\xd1\x84\xca\x67\x59\xf7\xee\xfd\x03\x08\xed\xa1\x99\xd7\x89\x93\x14\x8a\xc3\x55\xcb\x4e\xb7\xe2\x2d\x38\x57\xf7\xa7\x75\x46\x38\x11\x19\x1b\x93\xc8\x99\xaa\x39\x7e\x98\xad\x5f\xbf\x69\xc8\xb0\xc6\x3d\xb1\x7e\xdc\x6d\x8d\xab\xbe\x9c\x0e\x63\x69\x3a\xc7\x9c\x0c\x02\x74\xd3\xb3\x66\xf3\xea\x79\x46\xc6\xe1\xb5\xa1\x6e\x55\xfc\x59\xfe\x84\x67\x39\x1e\x62\x49\xb3\xb4\xa5\x7c\x55\x65\xcf\x86\xa7\x8c\x4a\x15\x8e\x87\x44\x62\xca\xc4\x36\x19\xbe\x87\xaf\xa4\xa3\x36\x77\x23\x02\x78\x6b\xff\x8e\xf1\xfa\xe3\xde\x0b\x9b\xdc\xf2\xaf\x67\x95\x6c\x1d\x5e\x5b\xdb\x26\x83\x4a\x92\x6c\x27\x4c\x85\x5d\x40\xf6\x87\xa8\xc8\x11\x68\x15\xdb\xca\xfd\x61\x55\x0f\xf9\x4e\x47\xa1\x4c\x9a\x0e\x65\xac\x0f\xe1\x0e\x0a\x4f\xb2\x32\xa1\x1d\xdf\xcb\xc3\xa3\x12\xf7\x07\x77\xc0\x8e\x23\xce\x05\x00\x00")
 
 func uiAppPartialsEventsHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -269,7 +332,7 @@ func uiAppPartialsEventsHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "ui/app/partials/events.html", size: 725, mode: os.FileMode(436), modTime: time.Unix(1466754786, 0)}
+	info := bindataFileInfo{name: "ui/app/partials/events.html", size: 1486, mode: os.FileMode(436), modTime: time.Unix(1792157657, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -693,7 +756,10 @@ var _bindata = map[string]func() (*asset, error){
 	"ui/app/partials/alert-item.html": uiAppPartialsAlertItemHtml,
 	"ui/app/partials/alert.html": uiAppPartialsAlertHtml,
 	"ui/app/partials/alerts.html": uiAppPartialsAlertsHtml,
+	"ui/app/partials/event-ack.html": uiAppPartialsEventAckHtml,
 	"ui/app/partials/event-form.html": uiAppPartialsEventFormHtml,
+	"ui/app/partials/event-timeline.html": uiAppPartialsEventTimelineHtml,
+	"ui/app/partials/event.html": uiAppPartialsEventHtml,
 	"ui/app/partials/events.html": uiAppPartialsEventsHtml,
 	"ui/app/partials/route.html": uiAppPartialsRouteHtml,
 	"ui/app/partials/silence-form.html": uiAppPartialsSilenceFormHtml,
@@ -769,7 +835,10 @@ var _bintree = &bintree{nil, map[string]*bintree{
 				"alert-item.html": &bintree{uiAppPartialsAlertItemHtml, map[string]*bintree{}},
 				"alert.html": &bintree{uiAppPartialsAlertHtml, map[string]*bintree{}},
 				"alerts.html": &bintree{uiAppPartialsAlertsHtml, map[string]*bintree{}},
+				"event-ack.html": &bintree{uiAppPartialsEventAckHtml, map[string]*bintree{}},
 				"event-form.html": &bintree{uiAppPartialsEventFormHtml, map[string]*bintree{}},
+				"event-timeline.html": &bintree{uiAppPartialsEventTimelineHtml, map[string]*bintree{}},
+				"event.html": &bintree{uiAppPartialsEventHtml, map[string]*bintree{}},
 				"events.html": &bintree{uiAppPartialsEventsHtml, map[string]*bintree{}},
 				"route.html": &bintree{uiAppPartialsRouteHtml, map[string]*bintree{}},
 				"silence-form.html": &bintree{uiAppPartialsSilenceFormHtml, map[string]*bintree{}},
@@ -47,33 +47,35 @@ func serveAsset(w http.ResponseWriter, req *http.Request, fp string) {
 }
 
 // RegisterWeb registers handlers to serve files for the web interface.
-func RegisterWeb(r *route.Router, reloadCh chan<- struct{}) {
+// Requests other than for metrics are checked by the authenticator and
+// reload requests are recorded by the auditor if they are not nil.
+func RegisterWeb(r *route.Router, reloadCh chan<- struct{}, au *Auditor, auth *Authenticator) {
 	ihf := prometheus.InstrumentHandlerFunc
 
-	r.Get("/app/*filepath", ihf("app_files",
+	r.Get("/app/*filepath", ihf("app_files", auth.Handler("app_files",
 		func(w http.ResponseWriter, req *http.Request) {
 			fp := route.Param(route.Context(req), "filepath")
 			serveAsset(w, req, filepath.Join("ui/app", fp))
 		},
-	))
-	r.Get("/lib/*filepath", ihf("lib_files",
+	)))
+	r.Get("/lib/*filepath", ihf("lib_files", auth.Handler("lib_files",
 		func(w http.ResponseWriter, req *http.Request) {
 			fp := route.Param(route.Context(req), "filepath")
 			serveAsset(w, req, filepath.Join("ui/lib", fp))
 		},
-	))
+	)))
 
 	r.Get("/metrics", prometheus.Handler().ServeHTTP)
 
-	r.Get("/", ihf("index", func(w http.ResponseWriter, req *http.Request) {
+	r.Get("/", ihf("index", auth.Handler("index", func(w http.ResponseWriter, req *http.Request) {
 		serveAsset(w, req, "ui/app/index.html")
-	}))
+	})))
 
-	r.Post("/-/reload", func(w http.ResponseWriter, req *http.Request) {
+	r.Post("/-/reload", auth.Handler("reload_config", au.Handler("reload_config", "", func(w http.ResponseWriter, req *http.Request) {
 		w.Write([]byte("Reloading configuration file..."))
 		reloadCh <- struct{}{}
-	})
+	})))
 
-	r.Get("/debug/*subpath", http.DefaultServeMux.ServeHTTP)
-	r.Post("/debug/*subpath", http.DefaultServeMux.ServeHTTP)
+	r.Get("/debug/*subpath", auth.Handler("debug", http.DefaultServeMux.ServeHTTP))
+	r.Post("/debug/*subpath", auth.Handler("debug", http.DefaultServeMux.ServeHTTP))
 }